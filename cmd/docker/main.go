@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
@@ -34,8 +35,10 @@ import (
 	"github.com/docker/go-connections/tlsconfig"
 
 	vicbackends "github.com/vmware/vic/lib/apiservers/engine/backends"
+	"github.com/vmware/vic/lib/apiservers/engine/router/vic"
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/pprof"
+	"github.com/vmware/vic/pkg/certificate"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
 )
@@ -172,13 +175,25 @@ func startServerWithOptions(cli *CliOptions) *apiserver.Server {
 	if !vchConfig.HostCertificate.IsNil() {
 		log.Info("TLS enabled")
 
-		cert, err := vchConfig.HostCertificate.Certificate()
+		monitor, err := vchConfig.HostCertificateMonitor()
 		if err != nil {
 			// This is only viable because we've verified those certificates
 			log.Fatalf("Could not load certificate from config and refusing to run without TLS with a host certificate specified: %s", err)
 		}
 
-		tlsConfig.Certificates = []tls.Certificate{*cert}
+		// the port layer renews the certificate and writes it back to guestinfo - serve it via the
+		// monitor so that renewals take effect here without a restart
+		monitor.AutoRenew = false
+		monitor.Source = hostCertificateSource
+		monitor.Notify = func(s certificate.ExpiryStatus) {
+			if s.State == certificate.CertificateRenewed {
+				kp := monitor.KeyPair()
+				vchConfig.HostCertificate = &config.RawCertificate{Cert: kp.CertPEM, Key: kp.KeyPEM}
+			}
+		}
+		go monitor.Run(context.Background(), certificate.DefaultExpiryCheckInterval)
+
+		tlsConfig.GetCertificate = monitor.GetCertificate
 		serverConfig.TLSConfig = tlsConfig
 
 		// Set options for TLS
@@ -227,17 +242,38 @@ func startServerWithOptions(cli *CliOptions) *apiserver.Server {
 	return api
 }
 
+// hostCertificateSource reads the current host certificate from guestinfo
+func hostCertificateSource() (*certificate.KeyPair, error) {
+	src, err := extraconfig.GuestInfoSource()
+	if err != nil {
+		return nil, err
+	}
+
+	var conf struct {
+		HostCertificate *config.RawCertificate `vic:"0.1" scope:"read-only" key:"cert/HostCertificate"`
+	}
+	extraconfig.Decode(src, &conf)
+
+	if conf.HostCertificate.IsNil() {
+		return nil, nil
+	}
+
+	return certificate.NewKeyPair("", "", conf.HostCertificate.Cert, conf.HostCertificate.Key), nil
+}
+
 func setAPIRoutes(api *apiserver.Server) {
 	imageHandler := &vicbackends.Image{}
 	containerHandler := vicbackends.NewContainerBackend()
 	volumeHandler := &vicbackends.Volume{}
 	networkHandler := &vicbackends.Network{}
 	systemHandler := vicbackends.NewSystemBackend()
+	vicHandler := vicbackends.NewVicBackend()
 
 	api.InitRouter(false,
 		image.NewRouter(imageHandler),
 		container.NewRouter(containerHandler),
 		volume.NewRouter(volumeHandler),
 		network.NewRouter(networkHandler),
-		system.NewRouter(systemHandler))
+		system.NewRouter(systemHandler),
+		vic.NewRouter(vicHandler))
 }
@@ -0,0 +1,165 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/pkg/iso"
+	"github.com/vmware/vic/pkg/version"
+)
+
+// stringList is a flag that may be repeated
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+var (
+	image   string
+	pkg     string
+	bin     string
+	assets  string
+	output  string
+	workDir string
+	debug   bool
+
+	kernelParams stringList
+	caCerts      stringList
+	plugins      stringList
+)
+
+func init() {
+	flag.StringVar(&image, "type", "", "Image to build - bootstrap or appliance")
+	flag.StringVar(&pkg, "p", "", "Staged package to build the image from")
+	flag.StringVar(&bin, "b", "", "Directory holding the built components, and where the image is written by default")
+	flag.StringVar(&assets, "a", "isos", "Directory holding the image assets")
+	flag.StringVar(&output, "o", "", "Output file for the image")
+	flag.StringVar(&workDir, "w", "", "Working directory for unpacking the package - a temporary directory is used by default")
+	flag.BoolVar(&debug, "debug", false, "Build the debug variant of the bootstrap image")
+
+	flag.Var(&kernelParams, "kernel-param", "Append a parameter to the kernel command line (may be repeated)")
+	flag.Var(&caCerts, "ca-cert", "Add the PEM encoded certificate authorities in the file to the system trust store (may be repeated)")
+	flag.Var(&plugins, "plugin", "Install and start the binary as a plugin, in the form name=path (may be repeated)")
+
+	flag.Parse()
+}
+
+// customization builds the image customization from the command line
+func customization() (*iso.Customization, error) {
+	c := &iso.Customization{
+		KernelParams: kernelParams,
+	}
+
+	for _, f := range caCerts {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate %s: %s", f, err)
+		}
+		c.CACerts = append(c.CACerts, data)
+	}
+
+	for _, p := range plugins {
+		parts := strings.SplitN(p, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("plugin %q must be in the form name=path", p)
+		}
+		c.Plugins = append(c.Plugins, iso.Plugin{Name: parts[0], Path: parts[1]})
+	}
+
+	return c, nil
+}
+
+func build() error {
+	src := &iso.Sources{
+		Assets: assets,
+		Bin:    bin,
+	}
+
+	var recipe func(*iso.Bundle) error
+	name := image
+	switch image {
+	case "bootstrap":
+		if debug {
+			name = "bootstrap-debug"
+		}
+		recipe = func(b *iso.Bundle) error { return iso.Bootstrap(b, src, debug) }
+	case "appliance":
+		recipe = func(b *iso.Bundle) error { return iso.Appliance(b, src) }
+	default:
+		return fmt.Errorf("unknown image type %q - must be bootstrap or appliance", image)
+	}
+
+	if output == "" {
+		output = filepath.Join(bin, name+".iso")
+	}
+
+	c, err := customization()
+	if err != nil {
+		return err
+	}
+
+	dir := workDir
+	if dir == "" {
+		if dir, err = ioutil.TempDir("", name); err != nil {
+			return fmt.Errorf("unable to create working directory: %s", err)
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	b, err := iso.Unpack(pkg, dir)
+	if err != nil {
+		return err
+	}
+
+	if err = recipe(b); err != nil {
+		return fmt.Errorf("failed to author %s image: %s", name, err)
+	}
+
+	if err = b.Customize(c); err != nil {
+		return fmt.Errorf("failed to customize %s image: %s", name, err)
+	}
+
+	return b.Generate(output, iso.Init)
+}
+
+func main() {
+	if version.Show() {
+		fmt.Fprintf(os.Stdout, "%s\n", version.String())
+		return
+	}
+
+	if pkg == "" || bin == "" || flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := build(); err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+}
@@ -18,7 +18,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"os/exec"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,6 +30,8 @@ import (
 	"golang.org/x/crypto/ssh"
 
 	"github.com/vmware/vic/cmd/tether/msgs"
+	"github.com/vmware/vic/lib/archive"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/tether"
 	"github.com/vmware/vic/pkg/serial"
 	"github.com/vmware/vic/pkg/trace"
@@ -36,6 +41,9 @@ const (
 	attachChannelType = "attach"
 )
 
+// archiveRoot is the root of the container filesystem as seen by the tether
+var archiveRoot = "/"
+
 // server is the singleton attachServer for the tether - there can be only one
 // as the backchannel line protocol may not provide multiplexing of connections
 var server AttachServer
@@ -366,7 +374,13 @@ func (t *attachServerSSH) run() error {
 		log.Infof("Ready to service attach requests")
 		// Service the incoming channels
 		for attachchan := range chans {
-			// The only channel type we'll support is attach
+			// archive channels are independent of the sessions
+			if attachchan.ChannelType() == msgs.ArchiveChannelType {
+				t.archive(attachchan)
+				continue
+			}
+
+			// The only other channel type we'll support is attach
 			if attachchan.ChannelType() != attachChannelType {
 				detail := fmt.Sprintf("unknown channel type %s", attachchan.ChannelType())
 				attachchan.Reject(ssh.UnknownChannelType, detail)
@@ -384,7 +398,7 @@ func (t *attachServerSSH) run() error {
 			}
 
 			sessionid := string(bytes)
-			session, ok := t.config.Sessions[sessionid]
+			session, ok := t.config.Session(sessionid)
 			if !ok {
 				detail := fmt.Sprintf("session %s is invalid", sessionid)
 				attachchan.Reject(ssh.Prohibited, detail)
@@ -491,6 +505,16 @@ func (t *attachServerSSH) globalMux(reqchan <-chan *ssh.Request) {
 					close(t.askedAndAnswered)
 				})
 			}
+		case msgs.VersionReq:
+			payload = msgs.NewVersionMsg().Marshal()
+		case msgs.StatReq:
+			ok, payload = stat(req.Payload)
+		case msgs.ExecReq:
+			ok, payload = execSession(req.Payload)
+		case msgs.ExecStatusReq:
+			ok, payload = t.execStatus(req.Payload)
+		case msgs.TopReq:
+			ok, payload = top()
 		default:
 			ok = false
 			payload = []byte("unknown global request type: " + req.Type)
@@ -515,6 +539,143 @@ func (t *attachServerSSH) globalMux(reqchan <-chan *ssh.Request) {
 	}
 }
 
+// stat answers a StatReq for the path in payload
+func stat(payload []byte) (bool, []byte) {
+	msg := msgs.StatMsg{}
+	if err := msg.Unmarshal(payload); err != nil {
+		status := msgs.ArchiveStatusMsg{Error: err.Error()}
+		return false, status.Marshal()
+	}
+
+	st, err := archive.Stat(archiveRoot, msg.Path)
+	if err != nil {
+		log.Errorf("stat of %s failed: %s", msg.Path, err)
+		status := msgs.ArchiveStatusMsg{Error: err.Error(), NotExist: os.IsNotExist(err)}
+		return false, status.Marshal()
+	}
+
+	msg.Name = st.Name
+	msg.Size = uint64(st.Size)
+	msg.Mode = uint32(st.Mode)
+	msg.Mtime = uint64(st.Mtime.UnixNano())
+	msg.LinkTarget = st.LinkTarget
+	return true, msg.Marshal()
+}
+
+// execSession answers an ExecReq by launching the described session in the tether
+func execSession(payload []byte) (bool, []byte) {
+	msg := msgs.ExecMsg{}
+	if err := msg.Unmarshal(payload); err != nil {
+		return false, []byte(err.Error())
+	}
+
+	session := &tether.SessionConfig{
+		Common: executor.Common{
+			ID:   msg.ID,
+			Name: msg.ID,
+		},
+		Cmd: exec.Cmd{
+			Path: msg.Path,
+			Args: msg.Args,
+			Env:  msg.Env,
+			Dir:  msg.Dir,
+		},
+		User:     msg.User,
+		Tty:      msg.Tty,
+		Attach:   msg.Attach,
+		RunBlock: msg.Attach,
+	}
+
+	if err := tthr.Exec(session); err != nil {
+		log.Errorf("exec of session %s failed: %s", msg.ID, err)
+		return false, []byte(err.Error())
+	}
+	return true, nil
+}
+
+// execStatus answers an ExecStatusReq with the state of the session whose ID is the payload
+func (t *attachServerSSH) execStatus(payload []byte) (bool, []byte) {
+	id := string(payload)
+	session, ok := t.config.Session(id)
+	if !ok {
+		return false, []byte(fmt.Sprintf("session %s is invalid", id))
+	}
+
+	session.Lock()
+	defer session.Unlock()
+
+	msg := msgs.ExecStatusMsg{
+		Started:    session.Started,
+		Running:    session.Started == "true" && session.StopTime == 0,
+		ExitStatus: session.ExitStatus,
+	}
+	return true, msg.Marshal()
+}
+
+// top answers a TopReq with the processes running in the containerVM
+func top() (bool, []byte) {
+	procs, err := processes()
+	if err != nil {
+		log.Errorf("listing processes failed: %s", err)
+		return false, []byte(err.Error())
+	}
+
+	msg := msgs.TopMsg{Processes: procs}
+	return true, msg.Marshal()
+}
+
+// archive services an archive channel, streaming a tar archive of the requested path out of the
+// container filesystem or extracting one into it. The outcome is reported with an ArchiveStatusReq
+// before the channel is closed.
+func (t *attachServerSSH) archive(newchan ssh.NewChannel) {
+	defer trace.End(trace.Begin("attach server archive handler"))
+
+	msg := msgs.ArchiveMsg{}
+	if err := msg.Unmarshal(newchan.ExtraData()); err != nil {
+		detail := fmt.Sprintf("archive channel requires ArchiveMsg in ExtraData: %s", err)
+		newchan.Reject(ssh.Prohibited, detail)
+		log.Error(detail)
+		return
+	}
+
+	channel, requests, err := newchan.Accept()
+	if err != nil {
+		log.Errorf("could not accept archive channel: %s", err)
+		return
+	}
+
+	go ssh.DiscardRequests(requests)
+
+	go func() {
+		defer channel.Close()
+
+		var err error
+		if msg.Write {
+			log.Infof("Extracting archive to %s", msg.Path)
+			err = archive.Write(archiveRoot, msg.Path, channel, msg.NoOverwriteDirNonDir)
+		} else {
+			log.Infof("Archiving %s", msg.Path)
+			var rc io.ReadCloser
+			if rc, _, err = archive.Read(archiveRoot, msg.Path); err == nil {
+				_, err = io.Copy(channel, rc)
+				rc.Close()
+			}
+			channel.CloseWrite()
+		}
+
+		status := msgs.ArchiveStatusMsg{}
+		if err != nil {
+			log.Errorf("archive of %s failed: %s", msg.Path, err)
+			status.Error = err.Error()
+			status.NotExist = os.IsNotExist(err)
+		}
+
+		if _, err := channel.SendRequest(msgs.ArchiveStatusReq, false, status.Marshal()); err != nil {
+			log.Warnf("Failed to send archive status: %s", err)
+		}
+	}()
+}
+
 func (t *attachServerSSH) channelMux(in <-chan *ssh.Request, session *tether.SessionConfig, cleanup func()) {
 	defer trace.End(trace.Begin("attach server channel request handler"))
 
@@ -32,3 +32,7 @@ func (t *attachServerSSH) Start() error {
 func resizePty(pty uintptr, winSize *msgs.WindowChangeMsg) error {
 	return errors.New("not supported on OSX")
 }
+
+func processes() ([]msgs.ProcessMsg, error) {
+	return nil, errors.New("not supported on OSX")
+}
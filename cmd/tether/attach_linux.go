@@ -15,10 +15,16 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/rand"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"unsafe"
 
@@ -33,6 +39,16 @@ import (
 
 var backchannelMode = os.ModePerm
 
+// procRoot is the procfs that the processes are listed from for TopReq, and passwdPath the
+// user database their owners are named from
+var (
+	procRoot   = "/proc"
+	passwdPath = "/etc/passwd"
+)
+
+// clockTicks is USER_HZ, the unit of the process times in procfs
+const clockTicks = 100
+
 func rawConnectionFromSerial() (net.Conn, error) {
 	log.Info("opening ttyS0 for backchannel")
 	f, err := os.OpenFile(pathPrefix+"/ttyS0", os.O_RDWR|os.O_SYNC|syscall.O_NOCTTY, backchannelMode)
@@ -95,3 +111,249 @@ func resizePty(pty uintptr, winSize *msgs.WindowChangeMsg) error {
 	}
 	return nil
 }
+
+// kthreadd is the pid of the parent of the kernel threads
+const kthreadd = 2
+
+// processes lists the processes of the container from procfs, leaving out the kernel threads and the
+// tether itself as they are part of the containerVM rather than the container
+func processes() ([]msgs.ProcessMsg, error) {
+	defer trace.End(trace.Begin(""))
+
+	boot, err := bootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	uptime, err := uptimeTicks()
+	if err != nil {
+		return nil, err
+	}
+
+	mem, err := memTotal()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	users := userNames()
+	self := os.Getpid()
+
+	var procs []msgs.ProcessMsg
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() || pid == self || pid == kthreadd {
+			continue
+		}
+
+		p, err := process(pid, boot, uptime, mem, users)
+		if err != nil {
+			// the process has most likely exited since the directory was read
+			log.Debugf("skipping process %d: %s", pid, err)
+			continue
+		}
+		if p.PPID == kthreadd {
+			continue
+		}
+		procs = append(procs, *p)
+	}
+
+	return procs, nil
+}
+
+// process describes the process with the given pid from its stat, status and cmdline files. The memory
+// of the process is reported against mem, the memory of the containerVM in KiB.
+func process(pid int, boot, uptime, mem int64, users map[string]string) (*msgs.ProcessMsg, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// the command name is in parentheses and may itself contain spaces and parentheses
+	open := bytes.IndexByte(stat, '(')
+	end := bytes.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("malformed stat %q", stat)
+	}
+	comm := string(stat[open+1 : end])
+
+	// fields start from the state, the third field of stat
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed stat %q", stat)
+	}
+
+	var values [22]int64
+	for _, i := range []int{1, 4, 11, 12, 19, 20, 21} {
+		if values[i], err = strconv.ParseInt(fields[i], 10, 64); err != nil {
+			return nil, fmt.Errorf("malformed stat %q: %s", stat, err)
+		}
+	}
+	ppid, tty, utime, stime, start := values[1], values[4], values[11], values[12], values[19]
+	vsize, rss := values[20], values[21]
+
+	p := &msgs.ProcessMsg{
+		PID:       pid,
+		PPID:      int(ppid),
+		StartTime: boot + start/clockTicks,
+		CPUTime:   (utime + stime) * 1000 / clockTicks,
+		VSZ:       vsize / 1024,
+		RSS:       rss * int64(os.Getpagesize()) / 1024,
+		State:     fields[0],
+		TTY:       ttyName(tty),
+	}
+
+	if elapsed := uptime - start; elapsed > 0 {
+		p.CPU = int((utime + stime) * 100 / elapsed)
+	}
+	if mem > 0 {
+		p.Mem = float64(p.RSS) * 100 / float64(mem)
+	}
+
+	uid, err := processUID(dir)
+	if err != nil {
+		return nil, err
+	}
+	p.User = uid
+	if name, ok := users[uid]; ok {
+		p.User = name
+	}
+
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+	p.Cmd = strings.Join(args, " ")
+	if p.Cmd == "" {
+		// kernel threads and zombies have no command line
+		p.Cmd = "[" + comm + "]"
+	}
+
+	return p, nil
+}
+
+// processUID returns the real user ID from the status file of the process in dir
+func processUID(dir string) (string, error) {
+	f, err := os.Open(filepath.Join(dir, "status"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[0] == "Uid:" {
+			return fields[1], nil
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no Uid in %s/status", dir)
+}
+
+// bootTime returns the time the containerVM booted, in seconds since the epoch
+func bootTime() (int64, error) {
+	stat, err := ioutil.ReadFile(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(stat), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "btime" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no btime in %s/stat", procRoot)
+}
+
+// memTotal returns the memory of the containerVM in KiB
+func memTotal() (int64, error) {
+	meminfo, err := ioutil.ReadFile(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(meminfo), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no MemTotal in %s/meminfo", procRoot)
+}
+
+// uptimeTicks returns the time since the containerVM booted, in clock ticks
+func uptimeTicks() (int64, error) {
+	uptime, err := ioutil.ReadFile(filepath.Join(procRoot, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(uptime))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("malformed uptime %q", uptime)
+	}
+
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(seconds * clockTicks), nil
+}
+
+// userNames maps user IDs onto names from the user database of the container, which may be absent
+func userNames() map[string]string {
+	names := make(map[string]string)
+
+	passwd, err := ioutil.ReadFile(passwdPath)
+	if err != nil {
+		log.Debugf("processes will show user IDs: %s", err)
+		return names
+	}
+
+	for _, line := range strings.Split(string(passwd), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if _, ok := names[fields[2]]; !ok {
+			names[fields[2]] = fields[0]
+		}
+	}
+	return names
+}
+
+// ttyName names the terminal with the given device number as ps does, empty for none
+func ttyName(dev int64) string {
+	if dev == 0 {
+		return ""
+	}
+
+	major := (dev >> 8) & 0xfff
+	minor := (dev & 0xff) | ((dev >> 12) & 0xfff00)
+
+	switch {
+	case major >= 136 && major <= 143:
+		return fmt.Sprintf("pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("tty%d", minor)
+	case major == 4:
+		return fmt.Sprintf("ttyS%d", minor-64)
+	case major == 5 && minor == 1:
+		return "console"
+	default:
+		return fmt.Sprintf("%d:%d", major, minor)
+	}
+}
@@ -24,8 +24,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"testing"
@@ -35,6 +37,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/ssh"
 
+	"github.com/vmware/vic/cmd/tether/msgs"
 	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/portlayer/attach"
 	"github.com/vmware/vic/lib/tether"
@@ -318,6 +321,267 @@ func TestAttachBlock(t *testing.T) {
 	attachCase(t, true)
 }
 
+func TestArchive(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	testServer, _ := server.(*testAttachServer)
+
+	// serve archives from a scratch root rather than the test host
+	root, err := ioutil.TempDir("", "archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	archiveRoot = root
+	defer func() { archiveRoot = "/" }()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "src", "hello"), []byte("hello world\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "dst"), 0755))
+
+	cfg := executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   "archive",
+			Name: "tether_test_executor",
+		},
+
+		Sessions: map[string]*executor.SessionConfig{
+			"archive": {
+				Common: executor.Common{
+					ID:   "archive",
+					Name: "tether_test_session",
+				},
+				Attach: true,
+				Cmd: executor.Cmd{
+					Path: "/bin/sleep",
+					Args: []string{"/bin/sleep", "1"},
+					Env:  []string{},
+					Dir:  "/",
+				},
+			},
+		},
+		Key: genKey(),
+	}
+
+	_, _, conn := StartAttachTether(t, &cfg, mocker)
+	defer conn.Close()
+
+	// wait for updates to occur
+	<-testServer.updated
+
+	containerConfig := &ssh.ClientConfig{
+		User: "daemon",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return nil
+		},
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, "notappliable", containerConfig)
+	assert.NoError(t, err)
+	defer sshConn.Close()
+
+	archiveClient := attach.SSHArchive(ssh.NewClient(sshConn, chans, reqs))
+
+	st, err := archiveClient.Stat("/src/hello")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "hello", st.Name)
+		assert.EqualValues(t, 12, st.Size)
+	}
+
+	_, err = archiveClient.Stat("/missing")
+	assert.True(t, os.IsNotExist(err), "expected not exist error, got %s", err)
+
+	// copy the directory out and back in again
+	data, err := archiveClient.Read("/src")
+	assert.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	_, err = io.Copy(buf, data)
+	assert.NoError(t, err)
+	data.Close()
+
+	assert.NoError(t, archiveClient.Write("/dst", buf, false))
+
+	out, err := ioutil.ReadFile(filepath.Join(root, "dst", "src", "hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world\n", string(out))
+
+	// extraction errors are reported by the tether
+	err = archiveClient.Write("/src/hello", bytes.NewReader(buf.Bytes()), false)
+	assert.Error(t, err)
+}
+
+func TestExec(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	testServer, _ := server.(*testAttachServer)
+
+	cfg := executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   "exec",
+			Name: "tether_test_executor",
+		},
+
+		Sessions: map[string]*executor.SessionConfig{
+			"exec": {
+				Common: executor.Common{
+					ID:   "exec",
+					Name: "tether_test_session",
+				},
+				Attach: true,
+				Cmd: executor.Cmd{
+					Path: "/bin/sleep",
+					Args: []string{"/bin/sleep", "1"},
+					Env:  []string{},
+					Dir:  "/",
+				},
+			},
+		},
+		Key: genKey(),
+	}
+
+	_, _, conn := StartAttachTether(t, &cfg, mocker)
+	defer conn.Close()
+
+	// wait for updates to occur
+	<-testServer.updated
+
+	containerConfig := &ssh.ClientConfig{
+		User: "daemon",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return nil
+		},
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, "notappliable", containerConfig)
+	assert.NoError(t, err)
+	defer sshConn.Close()
+
+	client := ssh.NewClient(sshConn, chans, reqs)
+
+	_, err = attach.SSHls(client)
+	assert.NoError(t, err)
+
+	msg := &msgs.ExecMsg{
+		ID:     "task",
+		Path:   "/bin/echo",
+		Args:   []string{"/bin/echo", "hello, world"},
+		Dir:    "/",
+		Attach: true,
+	}
+	assert.NoError(t, attach.SSHExec(client, msg))
+
+	// the session ID must be unique
+	assert.Error(t, attach.SSHExec(client, msg))
+
+	// the exec session is launched once attached to
+	sshSession, err := attach.SSHAttach(client, msg.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = io.Copy(buf, sshSession.Stdout())
+	assert.NoError(t, err)
+	assert.Equal(t, "hello, world\n", buf.String())
+
+	var status *msgs.ExecStatusMsg
+	for i := 0; i < 50; i++ {
+		status, err = attach.SSHExecStatus(client, msg.ID)
+		if err != nil || !status.Running {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, "true", status.Started)
+		assert.False(t, status.Running)
+		assert.Equal(t, 0, status.ExitStatus)
+	}
+
+	_, err = attach.SSHExecStatus(client, "missing")
+	assert.Error(t, err)
+}
+
+func TestTop(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	testServer, _ := server.(*testAttachServer)
+
+	cfg := executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   "top",
+			Name: "tether_test_executor",
+		},
+
+		Sessions: map[string]*executor.SessionConfig{
+			"top": {
+				Common: executor.Common{
+					ID:   "top",
+					Name: "tether_test_session",
+				},
+				Attach: true,
+				Cmd: executor.Cmd{
+					Path: "/bin/sleep",
+					Args: []string{"/bin/sleep", "1"},
+					Env:  []string{},
+					Dir:  "/",
+				},
+			},
+		},
+		Key: genKey(),
+	}
+
+	_, _, conn := StartAttachTether(t, &cfg, mocker)
+	defer conn.Close()
+
+	// wait for updates to occur
+	<-testServer.updated
+
+	containerConfig := &ssh.ClientConfig{
+		User: "daemon",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return nil
+		},
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, "notappliable", containerConfig)
+	assert.NoError(t, err)
+	defer sshConn.Close()
+
+	client := ssh.NewClient(sshConn, chans, reqs)
+
+	procs, err := attach.SSHTop(client)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the tether runs in the test process, which is left out along with the kernel threads, while
+	// the process that started the test is listed
+	var parent *msgs.ProcessMsg
+	for i := range procs {
+		assert.NotEqual(t, os.Getpid(), procs[i].PID)
+		assert.NotEqual(t, 2, procs[i].PID)
+		assert.NotEqual(t, 2, procs[i].PPID)
+
+		if procs[i].PID == os.Getppid() {
+			parent = &procs[i]
+		}
+	}
+
+	if assert.NotNil(t, parent) {
+		assert.NotEmpty(t, parent.User)
+		assert.NotEmpty(t, parent.Cmd)
+		assert.NotEmpty(t, parent.State)
+		assert.True(t, parent.RSS > 0 && parent.VSZ >= parent.RSS)
+		assert.True(t, parent.Mem > 0 && parent.Mem < 100)
+		assert.True(t, parent.StartTime > 0 && parent.StartTime <= time.Now().Unix())
+	}
+}
+
 //
 /////////////////////////////////////////////////////////////////////////////////////
 
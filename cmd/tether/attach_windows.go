@@ -59,3 +59,7 @@ func (t *attachServerSSH) Start() error {
 func resizePty(pty uintptr, winSize *msgs.WindowChangeMsg) error {
 	return errors.New("not supported on windows")
 }
+
+func processes() ([]msgs.ProcessMsg, error) {
+	return nil, errors.New("not supported on windows")
+}
@@ -15,11 +15,14 @@
 package msgs
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/ssh"
+
+	"github.com/vmware/vic/pkg/version"
 )
 
 // All of the messages passed over the ssh channel/global mux are (or will be)
@@ -142,3 +145,225 @@ func (s *ContainersMsg) Marshal() []byte {
 func (s *ContainersMsg) Unmarshal(payload []byte) error {
 	return ssh.Unmarshal(payload, s)
 }
+
+// VersionMsg
+const VersionReq = "version"
+
+// ProtocolVersion is the revision of the backchannel message set supported by this tether.
+// Tethers that predate version reporting reject VersionReq and are treated as revision 0.
+// Revision 2 adds the archive channel and StatReq, revision 3 adds ExecReq and ExecStatusReq,
+// revision 4 adds TopReq.
+const ProtocolVersion uint32 = 4
+
+type VersionMsg struct {
+	Protocol    uint32
+	Version     string
+	BuildNumber string
+	GitCommit   string
+}
+
+// NewVersionMsg returns the version message describing this binary
+func NewVersionMsg() *VersionMsg {
+	b := version.GetBuild()
+	return &VersionMsg{
+		Protocol:    ProtocolVersion,
+		Version:     b.Version,
+		BuildNumber: b.BuildNumber,
+		GitCommit:   b.GitCommit,
+	}
+}
+
+func (s *VersionMsg) RequestType() string {
+	return VersionReq
+}
+
+func (s *VersionMsg) Marshal() []byte {
+	return ssh.Marshal(*s)
+}
+
+func (s *VersionMsg) Unmarshal(payload []byte) error {
+	return ssh.Unmarshal(payload, s)
+}
+
+// Build returns the reported version as a version.Build
+func (s *VersionMsg) Build() *version.Build {
+	return &version.Build{
+		Version:     s.Version,
+		BuildNumber: s.BuildNumber,
+		GitCommit:   s.GitCommit,
+	}
+}
+
+// ArchiveChannelType is the channel type used to read or write a tar archive of the
+// container filesystem. The channel ExtraData is a marshalled ArchiveMsg.
+const ArchiveChannelType = "archive"
+
+// ArchiveMsg describes the path an archive channel reads from or writes to
+type ArchiveMsg struct {
+	Path string
+	// Write extracts the channel data at Path instead of streaming Path out
+	Write bool
+	// NoOverwriteDirNonDir refuses to replace a directory with a non-directory and vice versa
+	NoOverwriteDirNonDir bool
+}
+
+func (s *ArchiveMsg) RequestType() string {
+	return ArchiveChannelType
+}
+
+func (s *ArchiveMsg) Marshal() []byte {
+	return ssh.Marshal(*s)
+}
+
+func (s *ArchiveMsg) Unmarshal(payload []byte) error {
+	return ssh.Unmarshal(payload, s)
+}
+
+// ArchiveStatusMsg is sent by the tether on an archive channel once the transfer is complete
+const ArchiveStatusReq = "archive-status"
+
+type ArchiveStatusMsg struct {
+	// Error is empty if the transfer succeeded
+	Error string
+	// NotExist is set if the error was caused by a missing path
+	NotExist bool
+}
+
+func (s *ArchiveStatusMsg) RequestType() string {
+	return ArchiveStatusReq
+}
+
+func (s *ArchiveStatusMsg) Marshal() []byte {
+	return ssh.Marshal(*s)
+}
+
+func (s *ArchiveStatusMsg) Unmarshal(payload []byte) error {
+	return ssh.Unmarshal(payload, s)
+}
+
+// StatMsg
+const StatReq = "stat-path"
+
+// StatMsg is sent with only Path set and returned with the remaining fields filled in.
+// A failed request is answered with a marshalled ArchiveStatusMsg.
+type StatMsg struct {
+	Path       string
+	Name       string
+	Size       uint64
+	Mode       uint32
+	Mtime      uint64 // nanoseconds since the epoch
+	LinkTarget string
+}
+
+func (s *StatMsg) RequestType() string {
+	return StatReq
+}
+
+func (s *StatMsg) Marshal() []byte {
+	return ssh.Marshal(*s)
+}
+
+func (s *StatMsg) Unmarshal(payload []byte) error {
+	return ssh.Unmarshal(payload, s)
+}
+
+// ExecMsg
+const ExecReq = "exec"
+
+// ExecMsg describes a session to launch in the running executor, as docker exec does. A failed
+// request is answered with the error text. It is JSON encoded as the ssh encoding of string lists
+// cannot hold arguments containing commas.
+type ExecMsg struct {
+	ID   string
+	Path string
+	Args []string
+	Env  []string
+	Dir  string
+	User string
+	Tty  bool
+	// Attach holds the launch until the session is attached to
+	Attach bool
+}
+
+func (s *ExecMsg) RequestType() string {
+	return ExecReq
+}
+
+func (s *ExecMsg) Marshal() []byte {
+	b, _ := json.Marshal(s)
+	return b
+}
+
+func (s *ExecMsg) Unmarshal(payload []byte) error {
+	return json.Unmarshal(payload, s)
+}
+
+// ExecStatusMsg
+const ExecStatusReq = "exec-status"
+
+// ExecStatusMsg is the reply to an ExecStatusReq, whose payload is the ID of an exec session
+type ExecStatusMsg struct {
+	// Started is "true" once the session has been launched, or the reason it could not be
+	Started    string
+	Running    bool
+	ExitStatus int
+}
+
+func (s *ExecStatusMsg) RequestType() string {
+	return ExecStatusReq
+}
+
+func (s *ExecStatusMsg) Marshal() []byte {
+	b, _ := json.Marshal(s)
+	return b
+}
+
+func (s *ExecStatusMsg) Unmarshal(payload []byte) error {
+	return json.Unmarshal(payload, s)
+}
+
+// TopMsg
+const TopReq = "top"
+
+// ProcessMsg describes a process running in the containerVM
+type ProcessMsg struct {
+	PID  int
+	PPID int
+	// User is the name of the real user of the process, or its ID if the user has no name
+	User string
+	// StartTime is when the process started, in seconds since the epoch
+	StartTime int64
+	// CPUTime is the user and system time used by the process, in milliseconds
+	CPUTime int64
+	// CPU is the percentage of CPU time used by the process since it started
+	CPU int
+	// Mem is the percentage of the containerVM memory resident for the process
+	Mem float64
+	// VSZ and RSS are the virtual and resident memory of the process, in KiB
+	VSZ int64
+	RSS int64
+	// State is the process state code, e.g. R for running and S for sleeping
+	State string
+	// TTY is the controlling terminal of the process, empty if it has none
+	TTY string
+	Cmd string
+}
+
+// TopMsg is the reply to a TopReq, which has no payload, listing the processes in the containerVM.
+// A failed request is answered with the error text.
+type TopMsg struct {
+	Processes []ProcessMsg
+}
+
+func (s *TopMsg) RequestType() string {
+	return TopReq
+}
+
+func (s *TopMsg) Marshal() []byte {
+	b, _ := json.Marshal(s)
+	return b
+}
+
+func (s *TopMsg) Unmarshal(payload []byte) error {
+	return json.Unmarshal(payload, s)
+}
@@ -69,3 +69,85 @@ func TestContainers(t *testing.T) {
 
 	assert.Equal(t, s, out)
 }
+
+func TestVersion(t *testing.T) {
+	s := &VersionMsg{Protocol: ProtocolVersion, Version: "v0.8.0", BuildNumber: "42", GitCommit: "abcdef"}
+
+	assert.Equal(t, s.RequestType(), VersionReq)
+
+	tmp := s.Marshal()
+	out := &VersionMsg{}
+	out.Unmarshal(tmp)
+
+	assert.Equal(t, s, out)
+	assert.Equal(t, "v0.8.0-42-abcdef", out.Build().ShortVersion())
+}
+
+func TestArchive(t *testing.T) {
+	s := &ArchiveMsg{Path: "/etc", Write: true, NoOverwriteDirNonDir: true}
+
+	assert.Equal(t, s.RequestType(), ArchiveChannelType)
+
+	tmp := s.Marshal()
+	out := &ArchiveMsg{}
+	out.Unmarshal(tmp)
+
+	assert.Equal(t, s, out)
+
+	st := &StatMsg{Path: "/etc/hosts", Name: "hosts", Size: 42, Mode: 0644, Mtime: 1475000000000000000}
+
+	assert.Equal(t, st.RequestType(), StatReq)
+
+	tmp = st.Marshal()
+	sout := &StatMsg{}
+	sout.Unmarshal(tmp)
+
+	assert.Equal(t, st, sout)
+}
+
+func TestExec(t *testing.T) {
+	s := &ExecMsg{
+		ID:     "deadbeef",
+		Path:   "/bin/sh",
+		Args:   []string{"/bin/sh", "-c", "echo a,b"},
+		Env:    []string{"PATH=/bin"},
+		Dir:    "/",
+		Tty:    true,
+		Attach: true,
+	}
+
+	assert.Equal(t, s.RequestType(), ExecReq)
+
+	tmp := s.Marshal()
+	out := &ExecMsg{}
+	assert.NoError(t, out.Unmarshal(tmp))
+
+	assert.Equal(t, s, out)
+
+	st := &ExecStatusMsg{Started: "true", ExitStatus: -1}
+
+	assert.Equal(t, st.RequestType(), ExecStatusReq)
+
+	tmp = st.Marshal()
+	sout := &ExecStatusMsg{}
+	assert.NoError(t, sout.Unmarshal(tmp))
+
+	assert.Equal(t, st, sout)
+}
+
+func TestTop(t *testing.T) {
+	s := &TopMsg{
+		Processes: []ProcessMsg{
+			{PID: 1, User: "root", StartTime: 1484000000, CPUTime: 120, Cmd: "/.tether/tether"},
+			{PID: 42, PPID: 1, User: "1000", StartTime: 1484000060, CPUTime: 3500, CPU: 12, TTY: "pts/0", Cmd: "top -b"},
+		},
+	}
+
+	assert.Equal(t, s.RequestType(), TopReq)
+
+	tmp := s.Marshal()
+	out := &TopMsg{}
+	assert.NoError(t, out.Unmarshal(tmp))
+
+	assert.Equal(t, s, out)
+}
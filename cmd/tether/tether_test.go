@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"runtime"
 	"testing"
@@ -116,6 +117,11 @@ func (t *Mocker) ProcessEnv(env []string) []string {
 	return t.Base.ProcessEnv(env)
 }
 
+// Properties returns no vApp properties as there is no OVF environment in tests
+func (t *Mocker) Properties() (map[string]string, error) {
+	return nil, nil
+}
+
 // SetHostname sets both the kernel hostname and /etc/hostname to the specified string
 func (t *Mocker) SetHostname(hostname string, aliases ...string) error {
 	defer trace.End(trace.Begin("mocking hostname to " + hostname))
@@ -147,6 +153,18 @@ func (t *Mocker) MountLabel(ctx context.Context, label, target string) error {
 	return nil
 }
 
+// MountTarget records the mount of the network share at source on target
+func (t *Mocker) MountTarget(ctx context.Context, source url.URL, target string, mode string) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("mocking mounting %s on %s", source.String(), target)))
+
+	if t.Mounts == nil {
+		t.Mounts = make(map[string]string)
+	}
+
+	t.Mounts[source.String()] = target
+	return nil
+}
+
 // Fork triggers vmfork and handles the necessary pre/post OS level operations
 func (t *Mocker) Fork() error {
 	defer trace.End(trace.Begin("mocking fork"))
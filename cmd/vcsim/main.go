@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"log"
 	"runtime"
+	"strings"
 
 	"github.com/vmware/vic/pkg/vsphere/simulator"
 	"github.com/vmware/vic/pkg/vsphere/simulator/esx"
@@ -41,6 +42,15 @@ func main() {
 	cert := flag.String("tlscert", "", "Path to TLS certificate file")
 	key := flag.String("tlskey", "", "Path to TLS key file")
 
+	var faults simulator.Faults
+	var faultMethods string
+	flag.DurationVar(&faults.Latency, "latency", 0, "Latency added to every SOAP request")
+	flag.DurationVar(&faults.Jitter, "jitter", 0, "Random latency of up to this duration added to every SOAP request")
+	flag.Float64Var(&faults.ResetRate, "reset-rate", 0, "Fraction of SOAP requests whose connection is reset, from 0 to 1")
+	flag.Float64Var(&faults.UnavailableRate, "unavailable-rate", 0, "Fraction of SOAP requests answered with 503 Service Unavailable, from 0 to 1")
+	flag.StringVar(&faultMethods, "fault-methods", "", "Comma separated SOAP methods to inject faults into, all methods if empty")
+	flag.Int64Var(&faults.Seed, "fault-seed", 0, "Seed choosing the SOAP requests that fail, for reproducible runs")
+
 	flag.Parse()
 
 	f := flag.Lookup("httptest.serve")
@@ -81,6 +91,13 @@ func main() {
 		}
 	}
 
+	if faultMethods != "" {
+		faults.Methods = strings.Split(faultMethods, ",")
+	}
+	if faults.Latency > 0 || faults.Jitter > 0 || faults.ResetRate > 0 || faults.UnavailableRate > 0 {
+		model.Service.SetFaults(&faults)
+	}
+
 	s := model.Service.NewServer()
 
 	fmt.Printf("GOVC_URL=%s", s.URL)
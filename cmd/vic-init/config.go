@@ -22,4 +22,14 @@ type ExecutorConfig struct {
 type Diagnostics struct {
 	// Should debugging be enabled on whatever component this is and at what level
 	DebugLevel int `vic:"0.1" scope:"read-only" key:"debug"`
+
+	// DebugAccess, if set, opens the appliance up for interactive debugging
+	DebugAccess *DebugAccess `vic:"0.1" scope:"read-only" key:"access"`
+}
+
+type DebugAccess struct {
+	// SSH enables the SSH server
+	SSH bool `vic:"0.1" scope:"read-only" key:"ssh"`
+	// AuthorizedKey is placed in the authorized_keys of root when SSH is enabled
+	AuthorizedKey string `vic:"0.1" scope:"read-only" key:"authorizedkey"`
 }
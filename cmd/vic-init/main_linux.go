@@ -22,6 +22,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/vishvananda/netlink"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/tether"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
@@ -85,10 +86,32 @@ func main() {
 	// create the tether
 	tthr = tether.New(src, sink, &operations{})
 
+	// debug access enabled at runtime is kept across reboots via the config
+	if access := config.Diagnostics.DebugAccess; access != nil && access.SSH {
+		if err = enableSSH(access.AuthorizedKey); err != nil {
+			log.Errorf("Failed to enable SSH for debug access: %s", err)
+		}
+	}
+
 	// register the toolbox extension and configure for appliance
 	toolbox := configureToolbox(tether.NewToolbox())
 	toolbox.PrimaryIP = externalIP
 	tthr.Register("Toolbox", toolbox)
+	tthr.Register("Components", components)
+	tthr.Register("Heartbeat", tether.NewHeartbeat(sink, executor.HeartbeatInterval))
+
+	// the validator reads and records outside of the init prefix
+	vchSrc, err := extraconfig.GuestInfoSource()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	vchSink, err := extraconfig.GuestInfoSink()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	tthr.Register("Validator", newValidator(vchSrc, vchSink))
 
 	err = tthr.Start()
 	if err != nil {
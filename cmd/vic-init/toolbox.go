@@ -24,9 +24,13 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/vmware/vic/lib/tether"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/toolbox"
 )
@@ -42,13 +46,104 @@ func startCommand(r *toolbox.VixMsgStartProgramRequest) (int, error) {
 	switch r.ProgramPath {
 	case "enable-ssh":
 		return -1, enableSSH(r.Arguments)
+	case "disable-ssh":
+		return -1, disableSSH()
 	case "passwd":
 		return -1, passwd(r.Arguments)
+	case "restart-components":
+		return -1, restartComponents(strings.Fields(r.Arguments))
 	default:
 		return -1, fmt.Errorf("unknown command %q", r.ProgramPath)
 	}
 }
 
+// componentStopTimeout is how long restartComponents waits for the components to exit
+const componentStopTimeout = 10 * time.Second
+
+// components tracks the sessions of the appliance so that synthetic commands can act on them
+var components = &sessionTracker{}
+
+// sessionTracker is a tether extension that keeps the sessions from the latest configuration
+type sessionTracker struct {
+	sync.Mutex
+
+	sessions map[string]*tether.SessionConfig
+}
+
+// Start implementation of the tether.Extension interface
+func (s *sessionTracker) Start() error {
+	return nil
+}
+
+// Stop implementation of the tether.Extension interface
+func (s *sessionTracker) Stop() error {
+	return nil
+}
+
+// Reload implementation of the tether.Extension interface
+func (s *sessionTracker) Reload(config *tether.ExecutorConfig) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.sessions = config.Sessions
+	return nil
+}
+
+func (s *sessionTracker) session(id string) *tether.SessionConfig {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.sessions[id]
+}
+
+// restartComponents stops the named sessions and waits for them to exit. The tether relaunches them
+// with the current configuration, so that they pick up settings only read at startup such as the
+// host certificate.
+func restartComponents(ids []string) error {
+	defer trace.End(trace.Begin(strings.Join(ids, ",")))
+
+	var procs []*os.Process
+	for _, id := range ids {
+		session := components.session(id)
+		if session == nil {
+			err := fmt.Errorf("Unknown component %q", id)
+			log.Error(err)
+			return err
+		}
+
+		session.Lock()
+		proc := session.Cmd.Process
+		session.Unlock()
+
+		if proc == nil {
+			log.Infof("Component %s has not launched, nothing to restart", id)
+			continue
+		}
+
+		log.Infof("Stopping component %s (pid: %d) for restart", id, proc.Pid)
+		if err := proc.Signal(syscall.SIGTERM); err != nil {
+			log.Warnf("Failed to signal component %s: %s", id, err)
+			continue
+		}
+		procs = append(procs, proc)
+	}
+
+	// wait for the components to exit so that the caller does not reach the old instances
+	deadline := time.Now().Add(componentStopTimeout)
+	for _, proc := range procs {
+		for proc.Signal(syscall.Signal(0)) == nil {
+			if time.Now().After(deadline) {
+				err := fmt.Errorf("Timed out waiting for pid %d to exit", proc.Pid)
+				log.Error(err)
+				return err
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	return nil
+}
+
 // enableShell changes the root shell from /bin/false to /bin/bash
 func enableShell() error {
 	defer trace.End(trace.Begin(""))
@@ -176,9 +271,38 @@ func enableSSH(key string) error {
 	return startSSH()
 }
 
+// disableSSH stops the sshd server and removes the key placed by enableSSH
+func disableSSH() error {
+	defer trace.End(trace.Begin(""))
+
+	if err := os.Remove("/root/.ssh/authorized_keys"); err != nil && !os.IsNotExist(err) {
+		err := fmt.Errorf("unable to remove authorized_keys: %s", err)
+		log.Error(err)
+		return err
+	}
+
+	// the shell stays enabled if the appliance was started for debugging
+	if debugLevel <= 2 {
+		chsh := exec.Command("/bin/chsh", "-s", "/bin/false", "root")
+		if err := chsh.Start(); err != nil {
+			err := fmt.Errorf("Failed to launch chsh: %s", err)
+			log.Error(err)
+			return err
+		}
+		chsh.Wait()
+	}
+
+	return systemctlSSH("stop")
+}
+
 // startSSH launches the sshd server
 func startSSH() error {
-	c := exec.Command("/usr/bin/systemctl", "start", "sshd")
+	return systemctlSSH("start")
+}
+
+// systemctlSSH applies the action to the sshd service
+func systemctlSSH(action string) error {
+	c := exec.Command("/usr/bin/systemctl", action, "sshd")
 
 	var b bytes.Buffer
 	c.Stdout = &b
@@ -192,7 +316,7 @@ func startSSH() error {
 		// because init is explicitly reaping child processes we cannot use simple
 		// exec commands to gather status
 		_ = c.Wait()
-		log.Info("Attempted to start ssh service:\n %s", b)
+		log.Infof("Attempted to %s ssh service:\n %s", action, b.String())
 	}()
 
 	return nil
@@ -0,0 +1,318 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	vchconfig "github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/tether"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+const (
+	// validationTimeout bounds the checks that reach the vSphere target
+	validationTimeout = 30 * time.Second
+
+	// reachabilityAttempts is how many times the checks that reach the vSphere target are run before the
+	// components are held back, the delay between attempts starting at reachabilityRetryDelay and doubling
+	reachabilityAttempts   = 4
+	reachabilityRetryDelay = 5 * time.Second
+)
+
+// validator is a tether extension that checks the appliance configuration when it is loaded.
+// A problem is recorded in the guestinfo for vic-machine to report, and the components are held back as
+// they would otherwise fail with a less specific error or never finish initializing.
+//
+// Certificates that do not parse stay invalid until the appliance is reconfigured so are only checked
+// once, whereas the vSphere target and datastores may just not be reachable yet; those checks are retried
+// with backoff and run again on each reload until they pass.
+type validator struct {
+	// src and sink access the whole of the appliance configuration rather than the init prefix
+	src  extraconfig.DataSource
+	sink extraconfig.DataSink
+
+	// lookupHost resolves the vSphere target, replaceable for testing
+	lookupHost func(host string) ([]string, error)
+	// retryDelay is the delay before the first retry of the reachability checks, replaceable for testing
+	retryDelay time.Duration
+
+	// ctx is cancelled on Stop to abandon any retries
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	once      sync.Once
+	vchConfig vchconfig.VirtualContainerHostConfigSpec
+	// invalid is the outcome of the configuration checks, which is not expected to change
+	invalid vchconfig.ApplianceValidation
+	// reachable is set once the reachability checks have passed
+	reachable bool
+
+	result vchconfig.ApplianceValidation
+}
+
+func newValidator(src extraconfig.DataSource, sink extraconfig.DataSink) *validator {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &validator{
+		src:        src,
+		sink:       sink,
+		lookupHost: net.LookupHost,
+		retryDelay: reachabilityRetryDelay,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start implementation of the tether.Extension interface
+func (v *validator) Start() error {
+	return nil
+}
+
+// Stop implementation of the tether.Extension interface
+func (v *validator) Stop() error {
+	v.cancel()
+	return nil
+}
+
+// Reload implementation of the tether.Extension interface
+func (v *validator) Reload(conf *tether.ExecutorConfig) error {
+	v.once.Do(func() {
+		extraconfig.Decode(v.src, &v.vchConfig)
+
+		v.invalid = v.validateConfig(&v.vchConfig)
+		if v.invalid.Failed() {
+			v.record(v.invalid)
+		}
+	})
+
+	if !v.invalid.Failed() && !v.reachable {
+		result := v.retryReachable(&v.vchConfig)
+		v.reachable = !result.Failed()
+		v.record(result)
+	}
+
+	if !v.result.Failed() {
+		return nil
+	}
+
+	// record the problem as the launch status of the sessions that have not launched and drop them from
+	// this configuration so that the tether does not launch them
+	status := fmt.Sprintf("invalid configuration: %s", v.result.Message())
+	for id, s := range conf.Sessions {
+		s.Lock()
+		if s.Cmd.Process == nil {
+			s.Started = status
+			extraconfig.EncodeWithPrefix(v.sink, s, fmt.Sprintf("guestinfo.vice..init.sessions|%s", id))
+			delete(conf.Sessions, id)
+		}
+		s.Unlock()
+	}
+
+	return nil
+}
+
+// record logs the outcome of the checks and records it in the guestinfo, replacing any earlier problem
+func (v *validator) record(result vchconfig.ApplianceValidation) {
+	v.result = result
+	if result.Failed() {
+		log.Errorf("Appliance configuration is invalid: %s", result.Message())
+	} else {
+		log.Info("Appliance configuration is valid")
+	}
+
+	// FIXME: we cannot have this embedded knowledge of the extraconfig encoding pattern, but not
+	// currently sure how to expose it neatly via a utility function
+	extraconfig.EncodeWithPrefix(v.sink, result, "guestinfo.vice..validation")
+}
+
+// validate checks that the configuration is coherent and that its target is reachable, returning the
+// first problem found
+func (v *validator) validate(vchConfig *vchconfig.VirtualContainerHostConfigSpec) vchconfig.ApplianceValidation {
+	if result := v.validateConfig(vchConfig); result.Failed() {
+		return result
+	}
+
+	return v.validateReachable(vchConfig)
+}
+
+func invalid(code string, err error) vchconfig.ApplianceValidation {
+	return vchconfig.ApplianceValidation{
+		Code:   code,
+		Detail: err.Error(),
+	}
+}
+
+// validateConfig checks that the certificates parse and that a target is configured
+func (v *validator) validateConfig(vchConfig *vchconfig.VirtualContainerHostConfigSpec) vchconfig.ApplianceValidation {
+	defer trace.End(trace.Begin(""))
+
+	if len(vchConfig.CertificateAuthorities) > 0 {
+		if !x509.NewCertPool().AppendCertsFromPEM(vchConfig.CertificateAuthorities) {
+			return invalid(vchconfig.InvalidCABundle, errors.New("no certificates could be parsed"))
+		}
+	}
+
+	if cert := vchConfig.HostCertificate; cert != nil {
+		if _, err := tls.X509KeyPair(cert.Cert, cert.Key); err != nil {
+			return invalid(vchconfig.InvalidHostCertificate, err)
+		}
+	}
+
+	if vchConfig.ExtensionCert != "" {
+		if _, err := tls.X509KeyPair([]byte(vchConfig.ExtensionCert), []byte(vchConfig.ExtensionKey)); err != nil {
+			return invalid(vchconfig.InvalidExtensionCertificate, err)
+		}
+	}
+
+	if targetHostname(&vchConfig.Target) == "" {
+		return invalid(vchconfig.UnresolvableTarget, errors.New("no vSphere target is configured"))
+	}
+
+	return vchconfig.ApplianceValidation{}
+}
+
+// retryReachable runs the reachability checks until they pass or reachabilityAttempts is reached, as the
+// network or the target may still be coming up when the appliance boots
+func (v *validator) retryReachable(vchConfig *vchconfig.VirtualContainerHostConfigSpec) vchconfig.ApplianceValidation {
+	delay := v.retryDelay
+
+	for attempt := 1; ; attempt++ {
+		result := v.validateReachable(vchConfig)
+		if !result.Failed() || attempt == reachabilityAttempts {
+			return result
+		}
+
+		log.Warnf("Appliance configuration check failed, retrying in %s: %s", delay, result.Message())
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-v.ctx.Done():
+			return result
+		}
+	}
+}
+
+// validateReachable checks that the target resolves and accepts a login and that the image stores are accessible
+func (v *validator) validateReachable(vchConfig *vchconfig.VirtualContainerHostConfigSpec) vchconfig.ApplianceValidation {
+	defer trace.End(trace.Begin(""))
+
+	host := targetHostname(&vchConfig.Target)
+	if net.ParseIP(host) == nil {
+		if _, err := v.lookupHost(host); err != nil {
+			return invalid(vchconfig.UnresolvableTarget, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(v.ctx, validationTimeout)
+	defer cancel()
+
+	sess, err := session.NewSession(sessionConfig(vchConfig)).Connect(ctx)
+	if err != nil {
+		return invalid(vchconfig.UnreachableTarget, err)
+	}
+	defer sess.Logout(ctx)
+
+	for _, store := range vchConfig.ImageStores {
+		if err := datastoreAccessible(ctx, sess, store.Host); err != nil {
+			return invalid(vchconfig.UnreachableDatastore, err)
+		}
+	}
+
+	return vchconfig.ApplianceValidation{}
+}
+
+// targetHostname returns the host of the target without any port or IPv6 brackets
+func targetHostname(target *url.URL) string {
+	host, _, err := net.SplitHostPort(target.Host)
+	if err != nil {
+		host = target.Host
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// sessionConfig returns the configuration for connecting to the vSphere target as the components do
+func sessionConfig(vchConfig *vchconfig.VirtualContainerHostConfigSpec) *session.Config {
+	target := vchConfig.Target
+
+	// If we're in an ESXi environment, then we need
+	// to extract the userid/password from UserPassword
+	if vchConfig.UserPassword != "" {
+		if userinfo, err := url.Parse(fmt.Sprintf("%s://%s@%s", target.Scheme, vchConfig.UserPassword, target.Host)); err == nil {
+			target.User = userinfo.User
+		}
+	}
+
+	return &session.Config{
+		Service:       target.String(),
+		Insecure:      vchConfig.Insecure,
+		Thumbprint:    vchConfig.TargetThumbprint,
+		ExtensionCert: vchConfig.ExtensionCert,
+		ExtensionKey:  vchConfig.ExtensionKey,
+		ExtensionName: vchConfig.ExtensionName,
+	}
+}
+
+// datastoreAccessible checks that the named datastore exists in one of the datacenters and that it
+// is accessible from at least one of the hosts it is mounted on
+func datastoreAccessible(ctx context.Context, sess *session.Session, name string) error {
+	finder := find.NewFinder(sess.Vim25(), false)
+
+	dcs, err := finder.DatacenterList(ctx, "*")
+	if err != nil {
+		return err
+	}
+
+	var ds *object.Datastore
+	for _, dc := range dcs {
+		finder.SetDatacenter(dc)
+		if ds, err = finder.Datastore(ctx, name); err == nil {
+			break
+		}
+	}
+	if ds == nil {
+		return fmt.Errorf("datastore %q was not found", name)
+	}
+
+	var mds mo.Datastore
+	if err = ds.Properties(ctx, ds.Reference(), []string{"host"}, &mds); err != nil {
+		return err
+	}
+
+	for _, mount := range mds.Host {
+		if accessible := mount.MountInfo.Accessible; accessible != nil && *accessible {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("datastore %q is not accessible from any host", name)
+}
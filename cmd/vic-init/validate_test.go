@@ -0,0 +1,208 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vchconfig "github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/tether"
+	"github.com/vmware/vic/pkg/certificate"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	"github.com/vmware/vic/pkg/vsphere/simulator"
+)
+
+func TestValidate(t *testing.T) {
+	model := simulator.ESX()
+	defer model.Remove()
+	require.NoError(t, model.Create())
+
+	server := model.Service.NewServer()
+	defer server.Close()
+
+	ca, _, err := certificate.CreateRootCA("ca.example.com", []string{"VIC"}, 2048)
+	require.NoError(t, err)
+	cert, key, err := certificate.CreateSelfSigned("vch.example.com", []string{"VIC"}, 2048)
+	require.NoError(t, err)
+
+	valid := func() *vchconfig.VirtualContainerHostConfigSpec {
+		conf := &vchconfig.VirtualContainerHostConfigSpec{}
+		conf.CertificateAuthorities = ca.Bytes()
+		conf.HostCertificate = &vchconfig.RawCertificate{Cert: cert.Bytes(), Key: key.Bytes()}
+		conf.Target = *server.URL
+		conf.Insecure = true
+		conf.ImageStores = []url.URL{{Scheme: "ds", Host: "LocalDS_0", Path: "vch"}}
+		return conf
+	}
+
+	tests := []struct {
+		name   string
+		modify func(conf *vchconfig.VirtualContainerHostConfigSpec)
+		code   string
+	}{
+		{"valid", func(*vchconfig.VirtualContainerHostConfigSpec) {}, ""},
+		{"ca", func(conf *vchconfig.VirtualContainerHostConfigSpec) {
+			conf.CertificateAuthorities = []byte("not a certificate")
+		}, vchconfig.InvalidCABundle},
+		{"host certificate", func(conf *vchconfig.VirtualContainerHostConfigSpec) {
+			conf.HostCertificate.Key = ca.Bytes()
+		}, vchconfig.InvalidHostCertificate},
+		{"extension certificate", func(conf *vchconfig.VirtualContainerHostConfigSpec) {
+			conf.ExtensionCert = string(cert.Bytes())
+		}, vchconfig.InvalidExtensionCertificate},
+		{"unresolvable", func(conf *vchconfig.VirtualContainerHostConfigSpec) {
+			conf.Target.Host = "vsphere.invalid:443"
+		}, vchconfig.UnresolvableTarget},
+		{"unreachable", func(conf *vchconfig.VirtualContainerHostConfigSpec) {
+			// nothing listens on the port of a closed server
+			closed := model.Service.NewServer()
+			closed.Close()
+			conf.Target = *closed.URL
+		}, vchconfig.UnreachableTarget},
+		{"datastore", func(conf *vchconfig.VirtualContainerHostConfigSpec) {
+			conf.ImageStores[0].Host = "missing"
+		}, vchconfig.UnreachableDatastore},
+	}
+
+	v := newValidator(nil, nil)
+	v.lookupHost = func(host string) ([]string, error) {
+		if host == "vsphere.invalid" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"127.0.0.1"}, nil
+	}
+
+	for _, test := range tests {
+		conf := valid()
+		test.modify(conf)
+
+		result := v.validate(conf)
+		assert.Equal(t, test.code, result.Code, test.name)
+		assert.Equal(t, test.code != "", result.Failed(), test.name)
+		if test.code != "" {
+			assert.NotEmpty(t, result.Detail, test.name)
+		}
+	}
+}
+
+func TestValidatorReload(t *testing.T) {
+	vch := &vchconfig.VirtualContainerHostConfigSpec{}
+	vch.CertificateAuthorities = []byte("not a certificate")
+
+	src := map[string]string{}
+	extraconfig.Encode(extraconfig.MapSink(src), vch)
+
+	sink := map[string]string{}
+	v := newValidator(extraconfig.MapSource(src), extraconfig.MapSink(sink))
+
+	conf := &tether.ExecutorConfig{
+		Sessions: map[string]*tether.SessionConfig{
+			"vicadmin":   {},
+			"port-layer": {},
+		},
+	}
+
+	require.NoError(t, v.Reload(conf))
+	assert.Empty(t, conf.Sessions, "components should be held back")
+
+	var result vchconfig.VirtualContainerHostConfigSpec
+	extraconfig.Decode(extraconfig.MapSource(sink), &result)
+	assert.Equal(t, vchconfig.InvalidCABundle, result.Validation.Code)
+	assert.Equal(t, "invalid CA bundle: no certificates could be parsed", result.Validation.Message())
+
+	for _, id := range []string{"vicadmin", "port-layer"} {
+		assert.Equal(t, "invalid configuration: invalid CA bundle: no certificates could be parsed",
+			sink["guestinfo.vice..init.sessions|"+id+".started"], id)
+	}
+
+	// the sessions are held back again on later reloads without repeating the checks
+	v.lookupHost = func(host string) ([]string, error) {
+		t.Errorf("unexpected lookup of %s", host)
+		return nil, nil
+	}
+	conf.Sessions["vicadmin"] = &tether.SessionConfig{}
+	require.NoError(t, v.Reload(conf))
+	assert.Empty(t, conf.Sessions)
+}
+
+func TestValidatorReloadUnreachable(t *testing.T) {
+	model := simulator.ESX()
+	defer model.Remove()
+	require.NoError(t, model.Create())
+
+	server := model.Service.NewServer()
+	defer server.Close()
+
+	vch := &vchconfig.VirtualContainerHostConfigSpec{}
+	vch.Target = *server.URL
+	vch.Target.Host = "localhost:" + server.URL.Port()
+	vch.UserPassword = server.URL.User.String()
+	vch.Insecure = true
+
+	src := map[string]string{}
+	extraconfig.Encode(extraconfig.MapSink(src), vch)
+
+	sink := map[string]string{}
+	v := newValidator(extraconfig.MapSource(src), extraconfig.MapSink(sink))
+	v.retryDelay = time.Millisecond
+
+	lookups := 0
+	v.lookupHost = func(host string) ([]string, error) {
+		lookups++
+		return nil, errors.New("no such host")
+	}
+
+	sessions := func() map[string]*tether.SessionConfig {
+		return map[string]*tether.SessionConfig{
+			"vicadmin":   {},
+			"port-layer": {},
+		}
+	}
+
+	conf := &tether.ExecutorConfig{Sessions: sessions()}
+	require.NoError(t, v.Reload(conf))
+	assert.Empty(t, conf.Sessions, "components should be held back")
+	assert.Equal(t, reachabilityAttempts, lookups, "the lookup should be retried")
+
+	var result vchconfig.VirtualContainerHostConfigSpec
+	extraconfig.Decode(extraconfig.MapSource(sink), &result)
+	assert.Equal(t, vchconfig.UnresolvableTarget, result.Validation.Code)
+
+	// the target becoming reachable is noticed on the next reload
+	v.lookupHost = func(host string) ([]string, error) {
+		lookups++
+		return []string{"127.0.0.1"}, nil
+	}
+
+	conf.Sessions = sessions()
+	require.NoError(t, v.Reload(conf))
+	assert.Len(t, conf.Sessions, 2, "components should be launched")
+
+	result = vchconfig.VirtualContainerHostConfigSpec{}
+	extraconfig.Decode(extraconfig.MapSource(sink), &result)
+	assert.False(t, result.Validation.Failed(), "problem should be cleared")
+
+	// and not checked again once passed
+	lookups = 0
+	require.NoError(t, v.Reload(conf))
+	assert.Len(t, conf.Sessions, 2)
+	assert.Zero(t, lookups)
+}
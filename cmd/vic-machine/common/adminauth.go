@@ -0,0 +1,110 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/pkg/ldap"
+)
+
+// adminLDAPNone authenticates vicadmin users against vSphere again
+const adminLDAPNone = "none"
+
+// AdminAuth holds the directory server that vicadmin users are authenticated against
+type AdminAuth struct {
+	// AdminLDAPURL is the ldap:// or ldaps:// URL of the server, nil if none was supplied and
+	// empty to authenticate against vSphere
+	AdminLDAPURL *url.URL
+	// AdminLDAPUserDN is the DN bound as, with %s replaced by the username
+	AdminLDAPUserDN string
+	// AdminLDAPCAs holds the PEM encoded certificate authorities trusted for the server in
+	// addition to the system roots
+	AdminLDAPCAs []byte
+
+	adminLDAPURL string
+	adminLDAPCAs cli.StringSlice
+}
+
+// AdminAuthFlags returns the cli flags for vicadmin authentication
+func (a *AdminAuth) AdminAuthFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:        "admin-ldap-url",
+			Value:       "",
+			Usage:       fmt.Sprintf("ldap:// or ldaps:// URL of an LDAP or Active Directory server that vicadmin users log in against instead of vSphere. Use %q to log in against vSphere", adminLDAPNone),
+			Destination: &a.adminLDAPURL,
+		},
+		cli.StringFlag{
+			Name:        "admin-ldap-user-dn",
+			Value:       "",
+			Usage:       "DN that vicadmin users bind to the LDAP server as, with %s for the username, e.g. \"uid=%s,ou=people,dc=example,dc=com\" or \"%s@example.com\"",
+			Destination: &a.AdminLDAPUserDN,
+		},
+		cli.StringSliceFlag{
+			Name:  "admin-ldap-ca",
+			Value: &a.adminLDAPCAs,
+			Usage: "Specify a list of certificate authority files trusted for the LDAP server",
+		},
+	}
+}
+
+// ProcessAdminAuth parses the LDAP server URL, if one is given, and loads its certificate authorities
+func (a *AdminAuth) ProcessAdminAuth() error {
+	switch a.adminLDAPURL {
+	case "":
+		if a.AdminLDAPUserDN != "" || len(a.adminLDAPCAs) > 0 {
+			return cli.NewExitError("--admin-ldap-user-dn and --admin-ldap-ca require --admin-ldap-url", 1)
+		}
+		return nil
+	case adminLDAPNone:
+		a.AdminLDAPURL = &url.URL{}
+		return nil
+	}
+
+	u, err := url.Parse(a.adminLDAPURL)
+	if err == nil {
+		err = ldap.Validate(u)
+	}
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("%s is an invalid format for LDAP url, e.g. ldaps://dc.example.com: %s", a.adminLDAPURL, err), 1)
+	}
+
+	if !strings.Contains(a.AdminLDAPUserDN, "%s") {
+		return cli.NewExitError("--admin-ldap-user-dn must contain %s for the username", 1)
+	}
+
+	if u.Scheme == ldap.Scheme {
+		log.Warnf("vicadmin passwords are sent to %s unencrypted, use ldaps:// to protect them", u.Host)
+	}
+
+	for _, f := range a.adminLDAPCAs {
+		log.Infof("Loading LDAP CA from %s", f)
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to load LDAP authority from file %s: %s", f, err), 1)
+		}
+		a.AdminLDAPCAs = append(a.AdminLDAPCAs, b...)
+	}
+
+	a.AdminLDAPURL = u
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestProcessAdminAuth(t *testing.T) {
+	a := &AdminAuth{}
+	assert.NoError(t, a.ProcessAdminAuth())
+	assert.Nil(t, a.AdminLDAPURL, "no url supplied")
+
+	a = &AdminAuth{adminLDAPURL: "none"}
+	assert.NoError(t, a.ProcessAdminAuth())
+	if assert.NotNil(t, a.AdminLDAPURL) {
+		assert.Equal(t, "", a.AdminLDAPURL.String(), "vSphere authentication")
+	}
+
+	a = &AdminAuth{adminLDAPURL: "ldaps://dc.example.com", AdminLDAPUserDN: "%s@example.com"}
+	assert.NoError(t, a.ProcessAdminAuth())
+	if assert.NotNil(t, a.AdminLDAPURL) {
+		assert.Equal(t, "dc.example.com", a.AdminLDAPURL.Host)
+	}
+
+	for _, bad := range []*AdminAuth{
+		{AdminLDAPUserDN: "%s@example.com"},
+		{adminLDAPURL: "https://dc.example.com", AdminLDAPUserDN: "%s@example.com"},
+		{adminLDAPURL: "ldaps://dc.example.com"},
+		{adminLDAPURL: "ldaps://dc.example.com", AdminLDAPUserDN: "jdoe@example.com"},
+		{adminLDAPURL: "ldaps://dc.example.com", AdminLDAPUserDN: "%s@example.com", adminLDAPCAs: cli.StringSlice{"missing.pem"}},
+	} {
+		assert.Error(t, bad.ProcessAdminAuth(), "%+v", bad)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/pkg/flags"
+)
+
+// Default size of a new appliance VM
+const (
+	DefaultApplianceCPUs     = 1
+	DefaultApplianceMemoryMB = 2048
+)
+
+// Appliance sizing model for --expected-containers. On top of the default size, the appliance needs
+// a vCPU for every containersPerApplianceCPU containers and memoryMBPerContainerStep of memory for
+// every containerStep containers, for the container state held by the port layer and personality.
+// The port layer inventories every container when it starts, so it is given basePortLayerTimeout
+// plus portLayerTimeoutPerStep for every containerStep containers to launch.
+const (
+	containersPerApplianceCPU = 250
+	containerStep             = 100
+	memoryMBPerContainerStep  = 512
+	basePortLayerTimeout      = 2 * time.Minute
+	portLayerTimeoutPerStep   = 30 * time.Second
+)
+
+// ApplianceSizing is the recommended size of the appliance for a number of containers
+type ApplianceSizing struct {
+	NumCPUs          int
+	MemoryMB         int
+	PortLayerTimeout time.Duration
+}
+
+// RecommendedSizing returns the appliance size recommended by the sizing model for the number of containers
+func RecommendedSizing(containers int) ApplianceSizing {
+	steps := (containers + containerStep - 1) / containerStep
+
+	return ApplianceSizing{
+		NumCPUs:          DefaultApplianceCPUs + containers/containersPerApplianceCPU,
+		MemoryMB:         DefaultApplianceMemoryMB + steps*memoryMBPerContainerStep,
+		PortLayerTimeout: basePortLayerTimeout + time.Duration(steps)*portLayerTimeoutPerStep,
+	}
+}
+
+// ApplianceResources holds the size and resource allocation of the appliance VM. Zero values are
+// left to vSphere on create, and leave the current setting unchanged on configure.
+type ApplianceResources struct {
+	NumCPUs  int
+	MemoryMB int
+
+	// ExpectedContainers, if set, sizes a new appliance with RecommendedSizing
+	ExpectedContainers int
+
+	ApplianceCPUReservationMHz int
+	ApplianceCPULimitMHz       int
+	ApplianceCPUShares         *types.SharesInfo
+
+	ApplianceMemoryReservationMB int
+	ApplianceMemoryLimitMB       int
+	ApplianceMemoryShares        *types.SharesInfo
+}
+
+// ApplianceFlags returns the cli flags for the appliance VM resources. The flags of create are
+// hidden other than --expected-containers, and the size of a new appliance is set by SizeAppliance.
+func (a *ApplianceResources) ApplianceFlags(create bool) []cli.Flag {
+	var sizing []cli.Flag
+	if create {
+		sizing = append(sizing, cli.IntFlag{
+			Name:        "expected-containers",
+			Value:       0,
+			Usage:       "Number of containers the VCH is expected to run, used to size the appliance VM",
+			Destination: &a.ExpectedContainers,
+		})
+	}
+
+	return append(sizing,
+		cli.IntFlag{
+			Name:        "appliance-memory",
+			Value:       0,
+			Usage:       "Memory for the appliance VM, in MB. Does not impact resources allocated per container.",
+			Hidden:      create,
+			Destination: &a.MemoryMB,
+		},
+		cli.IntFlag{
+			Name:        "appliance-memory-reservation",
+			Value:       0,
+			Usage:       "Appliance VM memory reservation in MB",
+			Hidden:      create,
+			Destination: &a.ApplianceMemoryReservationMB,
+		},
+		cli.IntFlag{
+			Name:        "appliance-memory-limit",
+			Value:       0,
+			Usage:       "Appliance VM memory limit in MB (unlimited=0)",
+			Hidden:      create,
+			Destination: &a.ApplianceMemoryLimitMB,
+		},
+		cli.GenericFlag{
+			Name:   "appliance-memory-shares",
+			Value:  flags.NewSharesFlag(&a.ApplianceMemoryShares),
+			Usage:  "Appliance VM memory shares in level or share number, e.g. high, normal, low, or 20480",
+			Hidden: create,
+		},
+		cli.IntFlag{
+			Name:        "appliance-cpu",
+			Value:       0,
+			Usage:       "vCPUs for the appliance VM",
+			Hidden:      create,
+			Destination: &a.NumCPUs,
+		},
+		cli.IntFlag{
+			Name:        "appliance-cpu-reservation",
+			Value:       0,
+			Usage:       "Appliance VM CPU reservation in MHz",
+			Hidden:      create,
+			Destination: &a.ApplianceCPUReservationMHz,
+		},
+		cli.IntFlag{
+			Name:        "appliance-cpu-limit",
+			Value:       0,
+			Usage:       "Appliance VM CPU limit in MHz (unlimited=0)",
+			Hidden:      create,
+			Destination: &a.ApplianceCPULimitMHz,
+		},
+		cli.GenericFlag{
+			Name:   "appliance-cpu-shares",
+			Value:  flags.NewSharesFlag(&a.ApplianceCPUShares),
+			Usage:  "Appliance VM CPU shares in level or share number, e.g. high, normal, low, or 2000",
+			Hidden: create,
+		},
+	)
+}
+
+// ProcessApplianceResources checks the appliance VM resources are not negative
+func (a *ApplianceResources) ProcessApplianceResources() error {
+	for name, v := range map[string]int{
+		"appliance-cpu":                a.NumCPUs,
+		"appliance-memory":             a.MemoryMB,
+		"appliance-cpu-reservation":    a.ApplianceCPUReservationMHz,
+		"appliance-cpu-limit":          a.ApplianceCPULimitMHz,
+		"appliance-memory-reservation": a.ApplianceMemoryReservationMB,
+		"appliance-memory-limit":       a.ApplianceMemoryLimitMB,
+		"expected-containers":          a.ExpectedContainers,
+	} {
+		if v < 0 {
+			return cli.NewExitError(name+" cannot be negative", 1)
+		}
+	}
+	return nil
+}
+
+// SizeAppliance sets the size of a new appliance that was not given explicitly, to the default size or,
+// if ExpectedContainers is set, to the recommended size. A given size smaller than recommended is kept
+// with a warning. It returns the recommended sizing, or nil if ExpectedContainers is not set.
+func (a *ApplianceResources) SizeAppliance() *ApplianceSizing {
+	if a.ExpectedContainers == 0 {
+		if a.NumCPUs == 0 {
+			a.NumCPUs = DefaultApplianceCPUs
+		}
+		if a.MemoryMB == 0 {
+			a.MemoryMB = DefaultApplianceMemoryMB
+		}
+		return nil
+	}
+
+	sizing := RecommendedSizing(a.ExpectedContainers)
+	log.Infof("Recommended appliance size for %d containers: %d vCPUs, %dMB memory", a.ExpectedContainers, sizing.NumCPUs, sizing.MemoryMB)
+
+	if a.NumCPUs == 0 {
+		a.NumCPUs = sizing.NumCPUs
+	} else if a.NumCPUs < sizing.NumCPUs {
+		log.Warnf("appliance-cpu of %d is likely insufficient for %d containers, %d is recommended", a.NumCPUs, a.ExpectedContainers, sizing.NumCPUs)
+	}
+	if a.MemoryMB == 0 {
+		a.MemoryMB = sizing.MemoryMB
+	} else if a.MemoryMB < sizing.MemoryMB {
+		log.Warnf("appliance-memory of %dMB is likely insufficient for %d containers, %dMB is recommended", a.MemoryMB, a.ExpectedContainers, sizing.MemoryMB)
+	}
+
+	return &sizing
+}
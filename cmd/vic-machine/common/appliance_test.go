@@ -0,0 +1,53 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecommendedSizing(t *testing.T) {
+	assert.Equal(t, ApplianceSizing{1, 2048, 2 * time.Minute}, RecommendedSizing(0))
+	assert.Equal(t, ApplianceSizing{1, 2560, 150 * time.Second}, RecommendedSizing(1))
+	assert.Equal(t, ApplianceSizing{1, 2560, 150 * time.Second}, RecommendedSizing(100))
+	assert.Equal(t, ApplianceSizing{2, 3584, 210 * time.Second}, RecommendedSizing(250))
+	assert.Equal(t, ApplianceSizing{5, 7168, 7 * time.Minute}, RecommendedSizing(1000))
+}
+
+func TestSizeAppliance(t *testing.T) {
+	a := &ApplianceResources{}
+	assert.Nil(t, a.SizeAppliance())
+	assert.Equal(t, DefaultApplianceCPUs, a.NumCPUs)
+	assert.Equal(t, DefaultApplianceMemoryMB, a.MemoryMB)
+
+	a = &ApplianceResources{ExpectedContainers: 500}
+	sizing := a.SizeAppliance()
+	require.NotNil(t, sizing)
+	assert.Equal(t, 3, a.NumCPUs)
+	assert.Equal(t, 4608, a.MemoryMB)
+
+	// explicit sizes are kept, even if smaller than recommended
+	a = &ApplianceResources{ExpectedContainers: 500, NumCPUs: 2, MemoryMB: 8192}
+	require.NotNil(t, a.SizeAppliance())
+	assert.Equal(t, 2, a.NumCPUs)
+	assert.Equal(t, 8192, a.MemoryMB)
+
+	a = &ApplianceResources{ExpectedContainers: -1}
+	assert.Error(t, a.ProcessApplianceResources())
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"github.com/urfave/cli"
+)
+
+// Exit codes of the vic-machine commands that act on a target, so that automation can branch on the
+// kind of failure. Failures of any other kind exit with ExitFailure.
+const (
+	// ExitFailure is any failure not covered by a more specific code
+	ExitFailure = 1
+	// ExitValidationFailure means the arguments or the resulting configuration are invalid, and nothing was changed
+	ExitValidationFailure = 2
+	// ExitAuthFailure means the target certificate could not be verified or the credentials were rejected
+	ExitAuthFailure = 3
+	// ExitConflict means the operation conflicts with the state of the target, such as a VCH name in use,
+	// another upgrade in progress or a VCH protected from deletion, and nothing was changed
+	ExitConflict = 4
+	// ExitTimeout means the operation did not complete within --timeout
+	ExitTimeout = 5
+	// ExitPartialFailure means the operation failed after making changes, which need to be checked, such as
+	// some VCHs of a batch failing or a VCH left partly deleted
+	ExitPartialFailure = 6
+)
+
+// authFailure is implemented by errors rejecting the target certificate or the credentials
+type authFailure interface {
+	AuthFailure() bool
+}
+
+// conflict is implemented by errors reporting a conflict with the state of the target
+type conflict interface {
+	Conflict() bool
+}
+
+// Exit returns err as an error that makes vic-machine exit with code. Errors that already carry a code
+// other than ExitFailure keep it, as it is more specific.
+func Exit(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+
+	if e, ok := err.(cli.ExitCoder); ok && e.ExitCode() != ExitFailure {
+		return err
+	}
+	return cli.NewExitError(err.Error(), code)
+}
+
+// TargetExitCode returns ExitAuthFailure if err, from connecting to the target, rejected the target
+// certificate or the credentials, and ExitFailure otherwise
+func TargetExitCode(err error) int {
+	if e, ok := err.(authFailure); ok && e.AuthFailure() {
+		return ExitAuthFailure
+	}
+	return ExitFailure
+}
+
+// TargetError returns err, from connecting to the target, with the code from TargetExitCode
+func TargetError(err error) error {
+	if code := TargetExitCode(err); code != ExitFailure {
+		return Exit(err, code)
+	}
+	return err
+}
+
+// OperationExitCode returns ExitConflict if err, from an operation on the target, reports a conflict
+// with the state of the target, and ExitFailure otherwise
+func OperationExitCode(err error) int {
+	if e, ok := err.(conflict); ok && e.Conflict() {
+		return ExitConflict
+	}
+	return ExitFailure
+}
+
+// OperationError returns err, from an operation on the target, with the code from OperationExitCode
+func OperationError(err error) error {
+	if code := OperationExitCode(err); code != ExitFailure {
+		return Exit(err, code)
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+type conflictError struct{}
+
+func (conflictError) Error() string  { return "Appliance \"vch\" exists" }
+func (conflictError) Conflict() bool { return true }
+
+func exitCode(err error) int {
+	if e, ok := err.(cli.ExitCoder); ok {
+		return e.ExitCode()
+	}
+	return ExitFailure
+}
+
+func TestExit(t *testing.T) {
+	assert.Nil(t, Exit(nil, ExitTimeout))
+
+	err := Exit(errors.New("timed out"), ExitTimeout)
+	assert.Equal(t, ExitTimeout, exitCode(err))
+	assert.Equal(t, "timed out", err.Error())
+
+	// general failures are made more specific, specific codes are kept
+	assert.Equal(t, ExitValidationFailure, exitCode(Exit(cli.NewExitError("bad flag", ExitFailure), ExitValidationFailure)))
+	assert.Equal(t, ExitAuthFailure, exitCode(Exit(cli.NewExitError("bad password", ExitAuthFailure), ExitValidationFailure)))
+}
+
+func TestTargetError(t *testing.T) {
+	err := errors.New("connection refused")
+	assert.Equal(t, err, TargetError(err))
+
+	err = TargetError(&session.LoginError{Host: "vc", Err: errors.New("incorrect user name or password")})
+	assert.Equal(t, ExitAuthFailure, exitCode(err))
+	assert.Equal(t, "Failed to log in to vc: incorrect user name or password", err.Error())
+}
+
+func TestOperationError(t *testing.T) {
+	err := errors.New("task failed")
+	assert.Equal(t, err, OperationError(err))
+
+	err = OperationError(conflictError{})
+	assert.Equal(t, ExitConflict, exitCode(err))
+}
@@ -96,28 +96,12 @@ func (i *Images) CheckImagesFiles(force bool) (map[string]string, error) {
 	imgs[LinuxImageName] = i.BootstrapISO
 
 	for name, img := range imgs {
-		_, err := os.Stat(img)
-		if os.IsNotExist(err) {
-			var dir string
-			dir, err = filepath.Abs(filepath.Dir(os.Args[0]))
-			_, err = os.Stat(filepath.Join(dir, img))
-			if err == nil {
-				img = filepath.Join(dir, img)
-			}
-		}
-
-		if os.IsNotExist(err) {
-			log.Warnf("\t\tUnable to locate %s in the current or installer directory.", img)
-			return nil, err
-		}
-
-		version, err := i.checkImageVersion(img, force)
+		file, version, err := i.checkImageFile(img, force)
 		if err != nil {
-			log.Error(err)
 			return nil, err
 		}
 		versionedName := fmt.Sprintf("%s-%s", version, name)
-		result[versionedName] = img
+		result[versionedName] = file
 		if name == ApplianceImageName {
 			i.ApplianceISO = versionedName
 		} else {
@@ -128,6 +112,55 @@ func (i *Images) CheckImagesFiles(force bool) (map[string]string, error) {
 	return result, nil
 }
 
+// CheckBootstrapImageFile checks the bootstrap image file only, for operations that do not replace the appliance
+func (i *Images) CheckBootstrapImageFile(force bool) (map[string]string, error) {
+	defer trace.End(trace.Begin(""))
+
+	i.OSType = "linux"
+	osImgs, ok := images[i.OSType]
+	if !ok {
+		return nil, fmt.Errorf("Specified OS %q is not known to this installer", i.OSType)
+	}
+
+	if i.BootstrapISO == "" {
+		i.BootstrapISO = osImgs[0]
+	}
+
+	img, version, err := i.checkImageFile(i.BootstrapISO, force)
+	if err != nil {
+		return nil, err
+	}
+	i.BootstrapISO = fmt.Sprintf("%s-%s", version, LinuxImageName)
+
+	return map[string]string{i.BootstrapISO: img}, nil
+}
+
+// checkImageFile locates img in the current or installer directory, and checks its version
+func (i *Images) checkImageFile(img string, force bool) (string, string, error) {
+	_, err := os.Stat(img)
+	if os.IsNotExist(err) {
+		var dir string
+		dir, err = filepath.Abs(filepath.Dir(os.Args[0]))
+		_, err = os.Stat(filepath.Join(dir, img))
+		if err == nil {
+			img = filepath.Join(dir, img)
+		}
+	}
+
+	if os.IsNotExist(err) {
+		log.Warnf("\t\tUnable to locate %s in the current or installer directory.", img)
+		return "", "", err
+	}
+
+	version, err := i.checkImageVersion(img, force)
+	if err != nil {
+		log.Error(err)
+		return "", "", err
+	}
+
+	return img, version, nil
+}
+
 // GetImageVersion will read iso file version from Primary Volume Descriptor, field "Publisher Identifier"
 func (i *Images) GetImageVersion(img string) (string, error) {
 	defer trace.End(trace.Begin(""))
@@ -0,0 +1,81 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/lib/config"
+)
+
+// Maintenance holds the port layer maintenance jobs enabled or disabled for the VCH
+type Maintenance struct {
+	// MaintenanceJobs holds whether each listed job is enabled, keyed by job name, nil if none were supplied
+	MaintenanceJobs map[string]bool
+
+	maintenanceJobs cli.StringSlice
+}
+
+// MaintenanceFlags returns the cli flags for maintenance jobs
+func (m *Maintenance) MaintenanceFlags(hidden bool) []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:   "maintenance-job",
+			Value:  &m.maintenanceJobs,
+			Usage:  fmt.Sprintf("Enable or disable a periodic maintenance job of the VCH, in the form job=true|false, can be specified multiple times. Jobs: %s", strings.Join(config.MaintenanceJobNames, ", ")),
+			Hidden: hidden,
+		},
+	}
+}
+
+// ProcessMaintenance parses the maintenance job flags into MaintenanceJobs
+func (m *Maintenance) ProcessMaintenance() error {
+	for _, entry := range m.maintenanceJobs {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return cli.NewExitError(fmt.Sprintf("Could not parse maintenance job - expected format job=true|false: %s", entry), 1)
+		}
+
+		name := strings.TrimSpace(parts[0])
+		if !isMaintenanceJob(name) {
+			return cli.NewExitError(fmt.Sprintf("Unknown maintenance job %q - expected one of %s", name, strings.Join(config.MaintenanceJobNames, ", ")), 1)
+		}
+
+		enabled, err := strconv.ParseBool(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Could not parse maintenance job %s - expected true or false: %s", name, parts[1]), 1)
+		}
+
+		if m.MaintenanceJobs == nil {
+			m.MaintenanceJobs = make(map[string]bool)
+		}
+		m.MaintenanceJobs[name] = enabled
+	}
+
+	return nil
+}
+
+func isMaintenanceJob(name string) bool {
+	for _, job := range config.MaintenanceJobNames {
+		if job == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config"
+)
+
+func TestProcessMaintenance(t *testing.T) {
+	m := &Maintenance{}
+	require.NoError(t, m.ProcessMaintenance())
+	assert.Nil(t, m.MaintenanceJobs)
+
+	m = &Maintenance{
+		maintenanceJobs: []string{config.ImageCleanupJob + "=false", config.CertificateCheckJob + "= true"},
+	}
+	require.NoError(t, m.ProcessMaintenance())
+	assert.Equal(t, map[string]bool{config.ImageCleanupJob: false, config.CertificateCheckJob: true}, m.MaintenanceJobs)
+}
+
+func TestProcessMaintenanceInvalid(t *testing.T) {
+	for _, entry := range []string{config.ImageCleanupJob, "unknown-job=true", config.ImageCleanupJob + "=maybe"} {
+		m := &Maintenance{maintenanceJobs: []string{entry}}
+		assert.Error(t, m.ProcessMaintenance(), entry)
+	}
+}
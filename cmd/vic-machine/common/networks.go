@@ -0,0 +1,606 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/pkg/ip"
+)
+
+// MTU limits for VCH and container interfaces, the IPv4 minimum and the largest jumbo frame
+// supported by vSphere virtual switches
+const (
+	MinMTU = 68
+	MaxMTU = 9000
+)
+
+// MaxVLAN is the largest 802.1Q VLAN ID that can be tagged in the guest
+const MaxVLAN = 4094
+
+// MaxSearchDomains is the number of search domains the guest resolver honours
+const MaxSearchDomains = 6
+
+// CheckMTU returns an error if mtu is set for the network and out of range
+func CheckMTU(netName string, mtu int) error {
+	if mtu != 0 && (mtu < MinMTU || mtu > MaxMTU) {
+		return fmt.Errorf("Invalid MTU %d for %s network, must be between %d and %d", mtu, netName, MinMTU, MaxMTU)
+	}
+	return nil
+}
+
+// ContainerNetworks holds the vSphere networks that containers can use directly, keyed by the
+// name containers use to refer to them
+type ContainerNetworks struct {
+	MappedNetworks         map[string]string
+	MappedNetworksGateways map[string]net.IPNet
+	MappedNetworksIPRanges map[string][]ip.Range
+	MappedNetworksDNS      map[string][]net.IP
+	MappedNetworksSearch   map[string][]string
+	MappedNetworksMTU      map[string]int
+	MappedNetworksVLAN     map[string]int
+	MappedNetworksIPAM     map[string]string
+	MappedNetworksDHCP     map[string][]net.IP
+	MappedNetworksReserved map[string][]net.IP
+	MappedNetworksFirewall map[string]string
+
+	containerNetworks         cli.StringSlice
+	containerNetworksGateway  cli.StringSlice
+	containerNetworksIPRanges cli.StringSlice
+	containerNetworksDNS      cli.StringSlice
+	containerNetworksSearch   cli.StringSlice
+	containerNetworksMTU      cli.StringSlice
+	containerNetworksVLAN     cli.StringSlice
+	containerNetworksIPAM     cli.StringSlice
+	containerNetworksDHCP     cli.StringSlice
+	containerNetworksReserved cli.StringSlice
+	containerNetworksFirewall cli.StringSlice
+}
+
+// NewContainerNetworks returns an empty set of container networks
+func NewContainerNetworks() ContainerNetworks {
+	return ContainerNetworks{
+		MappedNetworks:         make(map[string]string),
+		MappedNetworksGateways: make(map[string]net.IPNet),
+		MappedNetworksIPRanges: make(map[string][]ip.Range),
+		MappedNetworksDNS:      make(map[string][]net.IP),
+		MappedNetworksSearch:   make(map[string][]string),
+		MappedNetworksMTU:      make(map[string]int),
+		MappedNetworksVLAN:     make(map[string]int),
+		MappedNetworksIPAM:     make(map[string]string),
+		MappedNetworksDHCP:     make(map[string][]net.IP),
+		MappedNetworksReserved: make(map[string][]net.IP),
+		MappedNetworksFirewall: make(map[string]string),
+	}
+}
+
+// ContainerNetworkFlags returns the cli flags for container networks
+func (c *ContainerNetworks) ContainerNetworkFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "container-network, cn",
+			Value: &c.containerNetworks,
+			Usage: "vSphere network list that containers can use directly with labels, e.g. vsphere-net:backend. Defaults to DCHP - see advanced help (-x).",
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-gateway, cng",
+			Value:  &c.containerNetworksGateway,
+			Usage:  "Gateway for the container network's subnet in CONTAINER-NETWORK:SUBNET format, e.g. vsphere-net:172.16.0.0/16.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-ip-range, cnr",
+			Value:  &c.containerNetworksIPRanges,
+			Usage:  "IP range for the container network in CONTAINER-NETWORK:IP-RANGE format, e.g. vsphere-net:172.16.0.0/24, vsphere-net:172.16.0.10-20.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-dns, cnd",
+			Value:  &c.containerNetworksDNS,
+			Usage:  "DNS servers for the container network in CONTAINER-NETWORK:DNS format, e.g. vsphere-net:8.8.8.8. Ignored if no static IP assigned.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-search-domain, cnsd",
+			Value:  &c.containerNetworksSearch,
+			Usage:  "DNS search domain for containers on the container network in CONTAINER-NETWORK:DOMAIN format, e.g. vsphere-net:example.com. May be repeated, domains are searched in the order given.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-mtu, cnm",
+			Value:  &c.containerNetworksMTU,
+			Usage:  "MTU for container interfaces on the container network in CONTAINER-NETWORK:MTU format, e.g. vsphere-net:9000. Defaults to the guest default.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-vlan, cnv",
+			Value:  &c.containerNetworksVLAN,
+			Usage:  "VLAN ID tagged by containers on the container network in CONTAINER-NETWORK:VLAN format, e.g. vsphere-net:42. The vSphere network must trunk the VLAN. Defaults to untagged.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-ipam, cni",
+			Value:  &c.containerNetworksIPAM,
+			Usage:  "How container addresses are managed on the container network in CONTAINER-NETWORK:IPAM format, where IPAM is static, dhcp or dhcp-relay, e.g. vsphere-net:dhcp-relay. Defaults to static if a gateway is set and dhcp otherwise.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-dhcp-server, cnds",
+			Value:  &c.containerNetworksDHCP,
+			Usage:  "DHCP server that container addresses are leased from on a dhcp-relay container network in CONTAINER-NETWORK:DHCP-SERVER format, e.g. vsphere-net:10.0.0.2.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-reserved-ip, cnri",
+			Value:  &c.containerNetworksReserved,
+			Usage:  "IP address held back from the container network's IP ranges, only assigned to containers asking for it, in CONTAINER-NETWORK:IP format, e.g. vsphere-net:172.16.0.10.",
+			Hidden: true,
+		},
+		cli.StringSliceFlag{
+			Name:   "container-network-firewall, cnf",
+			Value:  &c.containerNetworksFirewall,
+			Usage:  "Firewall policy of containers on the container network in CONTAINER-NETWORK:POLICY format, where POLICY is open, outbound, published or closed, e.g. vsphere-net:published. Defaults to open.",
+			Hidden: true,
+		},
+	}
+}
+
+// ProcessContainerNetworks parses the container network flags into the mapped network settings
+func (c *ContainerNetworks) ProcessContainerNetworks() error {
+	gws, err := parseContainerNetworkGateways([]string(c.containerNetworksGateway))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	pools, err := parseContainerNetworkIPRanges([]string(c.containerNetworksIPRanges))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	dns, err := parseContainerNetworkDNS([]string(c.containerNetworksDNS))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	search, err := parseContainerNetworkSearch([]string(c.containerNetworksSearch))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	mtus, err := parseContainerNetworkMTU([]string(c.containerNetworksMTU))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	vlans, err := parseContainerNetworkVLAN([]string(c.containerNetworksVLAN))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	ipams, err := parseContainerNetworkIPAM([]string(c.containerNetworksIPAM))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	dhcp, err := parseContainerNetworkAddrs([]string(c.containerNetworksDHCP), "DHCP server")
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	reserved, err := parseContainerNetworkAddrs([]string(c.containerNetworksReserved), "Reserved IP")
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	firewalls, err := parseContainerNetworkFirewall([]string(c.containerNetworksFirewall))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	// parse container networks
+	for _, cn := range c.containerNetworks {
+		vnet, v, err := splitVnetParam(cn)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		vicnet := vnet
+		if v != "" {
+			vicnet = v
+		}
+
+		c.MappedNetworks[vicnet] = vnet
+		c.MappedNetworksGateways[vicnet] = gws[vnet]
+		c.MappedNetworksIPRanges[vicnet] = pools[vnet]
+		c.MappedNetworksDNS[vicnet] = dns[vnet]
+		c.MappedNetworksSearch[vicnet] = search[vnet]
+		c.MappedNetworksMTU[vicnet] = mtus[vnet]
+		c.MappedNetworksVLAN[vicnet] = vlans[vnet]
+		c.MappedNetworksIPAM[vicnet] = ipams[vnet]
+		c.MappedNetworksDHCP[vicnet] = dhcp[vnet]
+		c.MappedNetworksReserved[vicnet] = reserved[vnet]
+		c.MappedNetworksFirewall[vicnet] = firewalls[vnet]
+
+		delete(gws, vnet)
+		delete(pools, vnet)
+		delete(dns, vnet)
+		delete(search, vnet)
+		delete(mtus, vnet)
+		delete(vlans, vnet)
+		delete(ipams, vnet)
+		delete(dhcp, vnet)
+		delete(reserved, vnet)
+		delete(firewalls, vnet)
+	}
+
+	var hasError bool
+	fmtMsg := "The following container network %s is set, but CONTAINER-NETWORK cannot be found. Please check the --container-network and %s settings"
+	if len(gws) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "gateway", "--container-network-gateway"))
+		for key, value := range gws {
+			mask, _ := value.Mask.Size()
+			log.Errorf("\t%s:%s/%d, %q should be vSphere network name", key, value.IP, mask, key)
+		}
+		hasError = true
+	}
+	if len(pools) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "ip range", "--container-network-ip-range"))
+		for key, value := range pools {
+			log.Errorf("\t%s:%s, %q should be vSphere network name", key, value, key)
+		}
+		hasError = true
+	}
+	if len(dns) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "dns", "--container-network-dns"))
+		for key, value := range dns {
+			log.Errorf("\t%s:%s, %q should be vSphere network name", key, value, key)
+		}
+		hasError = true
+	}
+	if len(search) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "search domain", "--container-network-search-domain"))
+		for key, value := range search {
+			log.Errorf("\t%s:%s, %q should be vSphere network name", key, strings.Join(value, " "), key)
+		}
+		hasError = true
+	}
+	if len(mtus) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "MTU", "--container-network-mtu"))
+		for key, value := range mtus {
+			log.Errorf("\t%s:%d, %q should be vSphere network name", key, value, key)
+		}
+		hasError = true
+	}
+	if len(vlans) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "VLAN", "--container-network-vlan"))
+		for key, value := range vlans {
+			log.Errorf("\t%s:%d, %q should be vSphere network name", key, value, key)
+		}
+		hasError = true
+	}
+	if len(ipams) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "IPAM", "--container-network-ipam"))
+		for key, value := range ipams {
+			log.Errorf("\t%s:%s, %q should be vSphere network name", key, value, key)
+		}
+		hasError = true
+	}
+	if len(dhcp) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "DHCP server", "--container-network-dhcp-server"))
+		for key, value := range dhcp {
+			log.Errorf("\t%s:%s, %q should be vSphere network name", key, value, key)
+		}
+		hasError = true
+	}
+	if len(reserved) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "reserved IP", "--container-network-reserved-ip"))
+		for key, value := range reserved {
+			log.Errorf("\t%s:%s, %q should be vSphere network name", key, value, key)
+		}
+		hasError = true
+	}
+	if len(firewalls) > 0 {
+		log.Error(fmt.Sprintf(fmtMsg, "firewall policy", "--container-network-firewall"))
+		for key, value := range firewalls {
+			log.Errorf("\t%s:%s, %q should be vSphere network name", key, value, key)
+		}
+		hasError = true
+	}
+	if hasError {
+		return cli.NewExitError("Inconsistent container network configuration.", 1)
+	}
+	return nil
+}
+
+type ipNetUnmarshaler struct {
+	ipnet *net.IPNet
+	ip    net.IP
+}
+
+func (m *ipNetUnmarshaler) UnmarshalText(text []byte) error {
+	s := string(text)
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return err
+	}
+
+	m.ipnet = ipnet
+	m.ip = ip
+	return nil
+}
+
+func parseContainerNetworkGateways(cgs []string) (map[string]net.IPNet, error) {
+	gws := make(map[string]net.IPNet)
+	for _, cg := range cgs {
+		m := &ipNetUnmarshaler{}
+		vnet, err := parseVnetParam(cg, m)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := gws[vnet]; ok {
+			return nil, fmt.Errorf("Duplicate gateway specified for container network %s", vnet)
+		}
+
+		gws[vnet] = net.IPNet{IP: m.ip, Mask: m.ipnet.Mask}
+	}
+
+	return gws, nil
+}
+
+func parseContainerNetworkIPRanges(cps []string) (map[string][]ip.Range, error) {
+	pools := make(map[string][]ip.Range)
+	for _, cp := range cps {
+		ipr := &ip.Range{}
+		vnet, err := parseVnetParam(cp, ipr)
+		if err != nil {
+			return nil, err
+		}
+
+		pools[vnet] = append(pools[vnet], *ipr)
+	}
+
+	return pools, nil
+}
+
+func parseContainerNetworkDNS(cds []string) (map[string][]net.IP, error) {
+	dns := make(map[string][]net.IP)
+	for _, cd := range cds {
+		var ip net.IP
+		vnet, err := parseVnetParam(cd, &ip)
+		if err != nil {
+			return nil, err
+		}
+
+		if ip == nil {
+			return nil, fmt.Errorf("DNS IP not specified for container network %s", vnet)
+		}
+
+		dns[vnet] = append(dns[vnet], ip)
+	}
+
+	return dns, nil
+}
+
+func parseContainerNetworkSearch(css []string) (map[string][]string, error) {
+	search := make(map[string][]string)
+	for _, cs := range css {
+		vnet, v, err := splitVnetParam(cs)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing container network parameter %s: %s", cs, err)
+		}
+
+		if v == "" {
+			return nil, fmt.Errorf("Search domain not specified for container network %s", vnet)
+		}
+		if !validDomain(v) {
+			return nil, fmt.Errorf("Invalid search domain %q for container network %s", v, vnet)
+		}
+
+		for _, d := range search[vnet] {
+			if strings.EqualFold(d, v) {
+				return nil, fmt.Errorf("Duplicate search domain %s specified for container network %s", v, vnet)
+			}
+		}
+		if len(search[vnet]) == MaxSearchDomains {
+			return nil, fmt.Errorf("Too many search domains specified for container network %s, at most %d are supported", vnet, MaxSearchDomains)
+		}
+
+		search[vnet] = append(search[vnet], v)
+	}
+
+	return search, nil
+}
+
+// validDomain returns true if d is a syntactically valid DNS domain name, optionally fully qualified
+func validDomain(d string) bool {
+	d = strings.TrimSuffix(d, ".")
+	if d == "" || len(d) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(d, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// parseContainerNetworkAddrs parses CONTAINER-NETWORK:IP parameters, what naming the addresses in errors
+func parseContainerNetworkAddrs(cas []string, what string) (map[string][]net.IP, error) {
+	addrs := make(map[string][]net.IP)
+	for _, ca := range cas {
+		var ip net.IP
+		vnet, err := parseVnetParam(ca, &ip)
+		if err != nil {
+			return nil, err
+		}
+
+		if ip == nil {
+			return nil, fmt.Errorf("%s not specified for container network %s", what, vnet)
+		}
+
+		addrs[vnet] = append(addrs[vnet], ip)
+	}
+
+	return addrs, nil
+}
+
+func parseContainerNetworkIPAM(cis []string) (map[string]string, error) {
+	ipams := make(map[string]string)
+	for _, ci := range cis {
+		vnet, v, err := splitVnetParam(ci)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing container network parameter %s: %s", ci, err)
+		}
+
+		if _, ok := ipams[vnet]; ok {
+			return nil, fmt.Errorf("Duplicate IPAM specified for container network %s", vnet)
+		}
+
+		switch v {
+		case executor.IPAMStatic, executor.IPAMDHCP, executor.IPAMDHCPRelay:
+		default:
+			return nil, fmt.Errorf("Invalid IPAM %q for container network %s, must be one of %s, %s or %s", v, vnet, executor.IPAMStatic, executor.IPAMDHCP, executor.IPAMDHCPRelay)
+		}
+
+		ipams[vnet] = v
+	}
+
+	return ipams, nil
+}
+
+func parseContainerNetworkFirewall(cfs []string) (map[string]string, error) {
+	firewalls := make(map[string]string)
+	for _, cf := range cfs {
+		vnet, v, err := splitVnetParam(cf)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing container network parameter %s: %s", cf, err)
+		}
+
+		if _, ok := firewalls[vnet]; ok {
+			return nil, fmt.Errorf("Duplicate firewall policy specified for container network %s", vnet)
+		}
+
+		if v == "" || !executor.ValidTrustLevel(v) {
+			return nil, fmt.Errorf("Invalid firewall policy %q for container network %s, must be one of %s", v, vnet, strings.Join(executor.TrustLevels, ", "))
+		}
+
+		firewalls[vnet] = v
+	}
+
+	return firewalls, nil
+}
+
+func parseContainerNetworkMTU(cms []string) (map[string]int, error) {
+	mtus := make(map[string]int)
+	for _, cm := range cms {
+		vnet, v, err := splitVnetParam(cm)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing container network parameter %s: %s", cm, err)
+		}
+
+		if _, ok := mtus[vnet]; ok {
+			return nil, fmt.Errorf("Duplicate MTU specified for container network %s", vnet)
+		}
+
+		mtu, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing container network parameter %s: %s", cm, err)
+		}
+		if err = CheckMTU("container network "+vnet, mtu); err != nil {
+			return nil, err
+		}
+
+		mtus[vnet] = mtu
+	}
+
+	return mtus, nil
+}
+
+func parseContainerNetworkVLAN(cvs []string) (map[string]int, error) {
+	vlans := make(map[string]int)
+	for _, cv := range cvs {
+		vnet, v, err := splitVnetParam(cv)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing container network parameter %s: %s", cv, err)
+		}
+
+		if _, ok := vlans[vnet]; ok {
+			return nil, fmt.Errorf("Duplicate VLAN specified for container network %s", vnet)
+		}
+
+		vlan, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing container network parameter %s: %s", cv, err)
+		}
+		if vlan < 1 || vlan > MaxVLAN {
+			return nil, fmt.Errorf("Invalid VLAN %d for container network %s, must be between 1 and %d", vlan, vnet, MaxVLAN)
+		}
+
+		vlans[vnet] = vlan
+	}
+
+	return vlans, nil
+}
+
+func splitVnetParam(p string) (vnet string, value string, err error) {
+	mapped := strings.Split(p, ":")
+	if len(mapped) == 0 || len(mapped) > 2 {
+		err = fmt.Errorf("Invalid value for parameter %s", p)
+		return
+	}
+
+	vnet = mapped[0]
+	if vnet == "" {
+		err = fmt.Errorf("Container network not specified in parameter %s", p)
+		return
+	}
+
+	if len(mapped) > 1 {
+		value = mapped[1]
+	}
+
+	return
+}
+
+func parseVnetParam(p string, m encoding.TextUnmarshaler) (vnet string, err error) {
+	vnet, v, err := splitVnetParam(p)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing container network parameter %s: %s", p, err)
+	}
+
+	if err = m.UnmarshalText([]byte(v)); err != nil {
+		return "", fmt.Errorf("Error parsing container network parameter %s: %s", p, err)
+	}
+
+	return vnet, nil
+}
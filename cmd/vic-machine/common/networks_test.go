@@ -0,0 +1,405 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/vmware/vic/pkg/ip"
+)
+
+func TestParseContainerNetworkGateways(t *testing.T) {
+	var tests = []struct {
+		cgs []string
+		gws map[string]net.IPNet
+		err error
+	}{
+		{[]string{""}, nil, fmt.Errorf("")},
+		{[]string{"foo:"}, nil, fmt.Errorf("")},
+		{[]string{":"}, nil, fmt.Errorf("")},
+		{[]string{":10.10.10.10/24"}, nil, fmt.Errorf("")},
+		{[]string{":foo"}, nil, fmt.Errorf("")},
+		{[]string{"foo:10"}, nil, fmt.Errorf("")},
+		{[]string{"foo:10.10.10.10/24", "foo:10.10.10.2/24"}, nil, fmt.Errorf("")},
+		{
+			[]string{"foo:10.10.10.10/24", "bar:10.10.9.1/16"},
+			map[string]net.IPNet{
+				"foo": {IP: net.ParseIP("10.10.10.10"), Mask: net.CIDRMask(24, 32)},
+				"bar": {IP: net.ParseIP("10.10.9.1"), Mask: net.CIDRMask(16, 32)},
+			},
+			nil,
+		},
+	}
+
+	for _, te := range tests {
+		gws, err := parseContainerNetworkGateways(te.cgs)
+		if te.err != nil {
+			if err == nil {
+				t.Fatalf("parseContainerNetworkGateways(%s) => (%v, nil) want (nil, err)", te.cgs, gws)
+			}
+
+			continue
+		}
+
+		if err != te.err ||
+			gws == nil ||
+			len(gws) != len(te.gws) {
+			t.Fatalf("parseContainerNetworkGateways(%s) => (%v, %s) want (%v, nil)", te.cgs, gws, err, te.gws)
+		}
+
+		for v, g := range gws {
+			if g2, ok := te.gws[v]; !ok {
+				t.Fatalf("parseContainerNetworkGateways(%s) => (%v, %s) want (%v, nil)", te.cgs, gws, err, te.gws)
+			} else if !g2.IP.Equal(g.IP) || bytes.Compare(g2.Mask, g.Mask) != 0 {
+				t.Fatalf("parseContainerNetworkGateways(%s) => (%v, %s) want (%v, nil)", te.cgs, gws, err, te.gws)
+			}
+		}
+	}
+}
+
+func TestParseContainerNetworkIPRanges(t *testing.T) {
+	var tests = []struct {
+		cps  []string
+		iprs map[string][]*ip.Range
+		err  error
+	}{
+		{[]string{""}, nil, fmt.Errorf("")},
+		{[]string{"foo:"}, nil, fmt.Errorf("")},
+		{[]string{":"}, nil, fmt.Errorf("")},
+		{[]string{":10.10.10.10-24"}, nil, fmt.Errorf("")},
+		{[]string{":foo"}, nil, fmt.Errorf("")},
+		{[]string{"foo:10"}, nil, fmt.Errorf("")},
+		{[]string{"foo:10.10.10.10-9"}, nil, fmt.Errorf("")},
+		{[]string{"foo:10.10.10.10-10.10.10.9"}, nil, fmt.Errorf("")},
+		{
+			[]string{"foo:10.10.10.10-24"},
+			map[string][]*ip.Range{"foo": {ip.NewRange(net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24"))}}, nil},
+		{
+			[]string{"foo:10.10.10.10-10.10.10.24"},
+			map[string][]*ip.Range{"foo": {ip.NewRange(net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24"))}},
+			nil,
+		},
+		{
+			[]string{"foo:10.10.10.10-10.10.10.24", "foo:10.10.10.100-10.10.10.105"},
+			map[string][]*ip.Range{
+				"foo": {
+					ip.NewRange(net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24")),
+					ip.NewRange(net.ParseIP("10.10.10.100"), net.ParseIP("10.10.10.105")),
+				},
+			},
+			nil,
+		},
+		{
+			[]string{"foo:10.10.10.10-10.10.10.24", "bar:10.10.9.1-10.10.9.10"},
+			map[string][]*ip.Range{
+				"foo": {ip.NewRange(net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24"))},
+				"bar": {ip.NewRange(net.ParseIP("10.10.9.1"), net.ParseIP("10.10.9.10"))},
+			},
+			nil,
+		},
+	}
+
+	for _, te := range tests {
+		iprs, err := parseContainerNetworkIPRanges(te.cps)
+		if te.err != nil {
+			if err == nil {
+				t.Fatalf("parseContainerNetworkIPRanges(%s) => (%v, nil) want (nil, err)", te.cps, iprs)
+			}
+
+			continue
+		}
+
+		if err != te.err ||
+			len(iprs) != len(te.iprs) {
+			t.Fatalf("parseContainerNetworkIPRanges(%s) => (%v, %s) want (%v, %s)", te.cps, iprs, err, te.iprs, te.err)
+		}
+
+		for v, ipr := range iprs {
+			if ipr2, ok := te.iprs[v]; !ok {
+				t.Fatalf("parseContainerNetworkIPRanges(%s) => (%v, %s) want (%v, %s)", te.cps, iprs, err, te.iprs, te.err)
+			} else {
+				for _, i := range ipr {
+					found := false
+					for _, i2 := range ipr2 {
+						if i.Equal(i2) {
+							found = true
+							break
+						}
+					}
+
+					if !found {
+						t.Fatalf("parseContainerNetworkIPRanges(%s) => (%v, %s) want (%v, %s)", te.cps, iprs, err, te.iprs, te.err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseContainerNetworkDNS(t *testing.T) {
+	var tests = []struct {
+		cds []string
+		dns map[string][]net.IP
+		err error
+	}{
+		{[]string{""}, nil, fmt.Errorf("")},
+		{[]string{"foo:"}, nil, fmt.Errorf("")},
+		{[]string{":"}, nil, fmt.Errorf("")},
+		{[]string{":10.10.10.10"}, nil, fmt.Errorf("")},
+		{[]string{":foo"}, nil, fmt.Errorf("")},
+		{[]string{"foo:10"}, nil, fmt.Errorf("")},
+		{[]string{"foo:10.10.10.109"}, map[string][]net.IP{"foo": {net.ParseIP("10.10.10.109")}}, nil},
+		{[]string{"foo:10.10.10.109", "foo:10.10.10.110", "bar:10.10.9.109", "bar:10.10.9.110"},
+			map[string][]net.IP{
+				"foo": {net.ParseIP("10.10.10.109"), net.ParseIP("10.10.10.110")},
+				"bar": {net.ParseIP("10.10.9.109"), net.ParseIP("10.10.9.110")},
+			},
+			nil,
+		},
+	}
+
+	for _, te := range tests {
+		dns, err := parseContainerNetworkDNS(te.cds)
+		if te.err != nil {
+			if err == nil {
+				t.Fatalf("parseContainerNetworkDNS(%s) => (%v, nil) want (nil, err)", te.cds, dns)
+			}
+
+			continue
+		}
+
+		if err != te.err ||
+			len(dns) != len(te.dns) {
+			t.Fatalf("parseContainerNetworkDNS(%s) => (%v, %s) want (%v, %s)", te.cds, dns, err, te.dns, te.err)
+		}
+
+		for v, d := range dns {
+			if d2, ok := te.dns[v]; !ok {
+				t.Fatalf("parseContainerNetworkDNS(%s) => (%v, %s) want (%v, %s)", te.cds, dns, err, te.dns, te.err)
+			} else {
+				for _, i := range d {
+					found := false
+					for _, i2 := range d2 {
+						if i.Equal(i2) {
+							found = true
+							break
+						}
+					}
+
+					if !found {
+						t.Fatalf("parseContainerNetworkDNS(%s) => (%v, %s) want (%v, %s)", te.cds, dns, err, te.dns, te.err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseContainerNetworkSearch(t *testing.T) {
+	var tests = []struct {
+		css    []string
+		search map[string][]string
+		err    error
+	}{
+		{[]string{"foo"}, nil, fmt.Errorf("")},
+		{[]string{":example.com"}, nil, fmt.Errorf("")},
+		{[]string{"foo:-bad.example.com"}, nil, fmt.Errorf("")},
+		{[]string{"foo:bad..example.com"}, nil, fmt.Errorf("")},
+		{[]string{"foo:bad domain"}, nil, fmt.Errorf("")},
+		{[]string{"foo:example.com", "foo:EXAMPLE.com"}, nil, fmt.Errorf("")},
+		{[]string{"foo:a", "foo:b", "foo:c", "foo:d", "foo:e", "foo:f", "foo:g"}, nil, fmt.Errorf("")},
+		{
+			[]string{"foo:eng.example.com", "bar:example.org.", "foo:example.com"},
+			map[string][]string{"foo": {"eng.example.com", "example.com"}, "bar": {"example.org."}},
+			nil,
+		},
+	}
+
+	for _, te := range tests {
+		search, err := parseContainerNetworkSearch(te.css)
+		if te.err != nil {
+			if err == nil {
+				t.Fatalf("parseContainerNetworkSearch(%s) => (%v, nil) want (nil, err)", te.css, search)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(search, te.search) {
+			t.Fatalf("parseContainerNetworkSearch(%s) => (%v, %s) want (%v, nil)", te.css, search, err, te.search)
+		}
+	}
+}
+
+func TestParseContainerNetworkMTU(t *testing.T) {
+	var tests = []struct {
+		cms  []string
+		mtus map[string]int
+		err  error
+	}{
+		{[]string{"foo"}, nil, fmt.Errorf("")},
+		{[]string{":1500"}, nil, fmt.Errorf("")},
+		{[]string{"foo:jumbo"}, nil, fmt.Errorf("")},
+		{[]string{"foo:9001"}, nil, fmt.Errorf("")},
+		{[]string{"foo:67"}, nil, fmt.Errorf("")},
+		{[]string{"foo:1500", "foo:9000"}, nil, fmt.Errorf("")},
+		{
+			[]string{"foo:9000", "bar:1450"},
+			map[string]int{"foo": 9000, "bar": 1450},
+			nil,
+		},
+	}
+
+	for _, te := range tests {
+		mtus, err := parseContainerNetworkMTU(te.cms)
+		if te.err != nil {
+			if err == nil {
+				t.Fatalf("parseContainerNetworkMTU(%s) => (%v, nil) want (nil, err)", te.cms, mtus)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(mtus, te.mtus) {
+			t.Fatalf("parseContainerNetworkMTU(%s) => (%v, %s) want (%v, nil)", te.cms, mtus, err, te.mtus)
+		}
+	}
+}
+
+func TestParseContainerNetworkVLAN(t *testing.T) {
+	var tests = []struct {
+		cvs   []string
+		vlans map[string]int
+		err   error
+	}{
+		{[]string{"foo"}, nil, fmt.Errorf("")},
+		{[]string{":42"}, nil, fmt.Errorf("")},
+		{[]string{"foo:trunk"}, nil, fmt.Errorf("")},
+		{[]string{"foo:0"}, nil, fmt.Errorf("")},
+		{[]string{"foo:4095"}, nil, fmt.Errorf("")},
+		{[]string{"foo:42", "foo:43"}, nil, fmt.Errorf("")},
+		{
+			[]string{"foo:42", "bar:4094"},
+			map[string]int{"foo": 42, "bar": 4094},
+			nil,
+		},
+	}
+
+	for _, te := range tests {
+		vlans, err := parseContainerNetworkVLAN(te.cvs)
+		if te.err != nil {
+			if err == nil {
+				t.Fatalf("parseContainerNetworkVLAN(%s) => (%v, nil) want (nil, err)", te.cvs, vlans)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(vlans, te.vlans) {
+			t.Fatalf("parseContainerNetworkVLAN(%s) => (%v, %s) want (%v, nil)", te.cvs, vlans, err, te.vlans)
+		}
+	}
+}
+
+func TestParseContainerNetworkIPAM(t *testing.T) {
+	var tests = []struct {
+		cis   []string
+		ipams map[string]string
+		err   error
+	}{
+		{[]string{"foo"}, nil, fmt.Errorf("")},
+		{[]string{":dhcp"}, nil, fmt.Errorf("")},
+		{[]string{"foo:bootp"}, nil, fmt.Errorf("")},
+		{[]string{"foo:dhcp", "foo:static"}, nil, fmt.Errorf("")},
+		{
+			[]string{"foo:dhcp-relay", "bar:static", "baz:dhcp"},
+			map[string]string{"foo": "dhcp-relay", "bar": "static", "baz": "dhcp"},
+			nil,
+		},
+	}
+
+	for _, te := range tests {
+		ipams, err := parseContainerNetworkIPAM(te.cis)
+		if te.err != nil {
+			if err == nil {
+				t.Fatalf("parseContainerNetworkIPAM(%s) => (%v, nil) want (nil, err)", te.cis, ipams)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(ipams, te.ipams) {
+			t.Fatalf("parseContainerNetworkIPAM(%s) => (%v, %s) want (%v, nil)", te.cis, ipams, err, te.ipams)
+		}
+	}
+}
+
+func TestParseContainerNetworkFirewall(t *testing.T) {
+	var tests = []struct {
+		cfs       []string
+		firewalls map[string]string
+		err       error
+	}{
+		{[]string{"foo"}, nil, fmt.Errorf("")},
+		{[]string{":closed"}, nil, fmt.Errorf("")},
+		{[]string{"foo:trusted"}, nil, fmt.Errorf("")},
+		{[]string{"foo:open", "foo:closed"}, nil, fmt.Errorf("")},
+		{
+			[]string{"foo:published", "bar:outbound", "baz:closed", "qux:open"},
+			map[string]string{"foo": "published", "bar": "outbound", "baz": "closed", "qux": "open"},
+			nil,
+		},
+	}
+
+	for _, te := range tests {
+		firewalls, err := parseContainerNetworkFirewall(te.cfs)
+		if te.err != nil {
+			if err == nil {
+				t.Fatalf("parseContainerNetworkFirewall(%s) => (%v, nil) want (nil, err)", te.cfs, firewalls)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(firewalls, te.firewalls) {
+			t.Fatalf("parseContainerNetworkFirewall(%s) => (%v, %s) want (%v, nil)", te.cfs, firewalls, err, te.firewalls)
+		}
+	}
+}
+
+func TestParseContainerNetworkAddrs(t *testing.T) {
+	addrs, err := parseContainerNetworkAddrs([]string{"foo:10.0.0.2", "foo:10.0.0.3", "bar:10.1.0.2"}, "DHCP server")
+	if err != nil {
+		t.Fatalf("parseContainerNetworkAddrs() => (%v, %s) want (addrs, nil)", addrs, err)
+	}
+
+	want := map[string][]net.IP{
+		"foo": {net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")},
+		"bar": {net.ParseIP("10.1.0.2")},
+	}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("parseContainerNetworkAddrs() => (%v, nil) want (%v, nil)", addrs, want)
+	}
+
+	for _, p := range []string{"foo", "foo:bar", ":10.0.0.2"} {
+		if _, err = parseContainerNetworkAddrs([]string{p}, "DHCP server"); err == nil {
+			t.Fatalf("parseContainerNetworkAddrs(%s) => (addrs, nil) want (nil, err)", p)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/pkg/errors"
+)
+
+// Options are the options of a command by name, as given in a batch file or to the vic-machine
+// server rather than on the command line. A value may be a list for options that can be given
+// more than once.
+type Options map[string]interface{}
+
+// FlagNames returns the names of the flag, the first being the primary one
+func FlagNames(f cli.Flag) []string {
+	var names []string
+	for _, name := range strings.Split(f.GetName(), ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
+
+// flagValues returns the values to set a flag to, one per occurrence
+func flagValues(v interface{}) []string {
+	switch v := v.(type) {
+	case *cli.StringSlice:
+		return v.Value()
+	case *cli.IntSlice:
+		var values []string
+		for _, i := range v.Value() {
+			values = append(values, fmt.Sprint(i))
+		}
+		return values
+	case []interface{}:
+		var values []string
+		for _, i := range v {
+			values = append(values, fmt.Sprint(i))
+		}
+		return values
+	case []string:
+		return v
+	case flag.Value:
+		return []string{v.String()}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// Resolve returns the options by the primary names of the flags, failing for options that are
+// not among the flags
+func (o Options) Resolve(flags []cli.Flag) (Options, error) {
+	primary := make(map[string]string)
+	for _, f := range flags {
+		names := FlagNames(f)
+		for _, name := range names {
+			primary[name] = names[0]
+		}
+	}
+
+	resolved := make(Options)
+	for name, value := range o {
+		p, ok := primary[name]
+		if !ok {
+			return nil, errors.Errorf("Unknown option %q", name)
+		}
+		resolved[p] = value
+	}
+	return resolved, nil
+}
+
+// FlagSet applies the flags to a new flag set and sets the options, which must be given by
+// primary name, as if they were given on the command line
+func (o Options) FlagSet(name string, flags []cli.Flag) (*flag.FlagSet, error) {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+
+	for name, value := range o {
+		for _, v := range flagValues(value) {
+			if err := set.Set(name, v); err != nil {
+				return nil, errors.Errorf("Invalid value %q for option %q: %s", v, name, err)
+			}
+		}
+	}
+	return set, nil
+}
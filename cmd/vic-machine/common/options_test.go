@@ -0,0 +1,52 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func TestOptions(t *testing.T) {
+	target := NewTarget()
+	var stores cli.StringSlice
+	flags := append(target.TargetFlags(), cli.StringSliceFlag{
+		Name:  "volume-store, vs",
+		Value: &stores,
+	})
+
+	opts, err := Options{
+		"t":          "10.0.0.1",
+		"user":       "root",
+		"vs":         []interface{}{"ds1/a:default", "ds1/b:other"},
+		"thumbprint": "AA:BB",
+	}.Resolve(flags)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", opts["target"])
+	assert.NotContains(t, opts, "vs")
+
+	_, err = opts.FlagSet("test", flags)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", target.URL.Host+target.URL.Path)
+	assert.Equal(t, "root", target.User)
+	assert.Equal(t, "AA:BB", target.Thumbprint)
+	assert.Equal(t, []string{"ds1/a:default", "ds1/b:other"}, stores.Value())
+
+	_, err = Options{"no-such-option": "x"}.Resolve(flags)
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// Proxies holds the proxies used by the appliance when fetching images
+type Proxies struct {
+	HTTPSProxy *url.URL
+	HTTPProxy  *url.URL
+
+	// NoProxy lists the hosts, domains and CIDRs reached without a proxy, nil if not supplied
+	NoProxy []string
+	// RegistryProxies holds the proxies for specific registries, keyed by registry host
+	RegistryProxies map[string]*url.URL
+
+	httpsProxy      string
+	httpProxy       string
+	noProxy         cli.StringSlice
+	registryProxies cli.StringSlice
+}
+
+// ProxyFlags returns the cli flags for proxies
+func (p *Proxies) ProxyFlags(hidden bool) []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:        "https-proxy, sproxy",
+			Value:       "",
+			Usage:       "An HTTPS proxy for use when fetching images, in the form https://fqdn_or_ip:port",
+			Destination: &p.httpsProxy,
+			Hidden:      hidden,
+		},
+		cli.StringFlag{
+			Name:        "http-proxy, hproxy",
+			Value:       "",
+			Usage:       "An HTTP proxy for use when fetching images, in the form http://fqdn_or_ip:port",
+			Destination: &p.httpProxy,
+			Hidden:      hidden,
+		},
+		cli.StringSliceFlag{
+			Name:   "no-proxy",
+			Value:  &p.noProxy,
+			Usage:  "A host, domain or CIDR to reach without a proxy when fetching images, can be specified multiple times",
+			Hidden: hidden,
+		},
+		cli.StringSliceFlag{
+			Name:   "registry-proxy",
+			Value:  &p.registryProxies,
+			Usage:  "A proxy for one registry in place of --http-proxy and --https-proxy, in the form registry=http(s)://fqdn_or_ip:port, can be specified multiple times",
+			Hidden: hidden,
+		},
+	}
+}
+
+// ProcessProxies parses the proxy flags into HTTPProxy, HTTPSProxy, NoProxy and RegistryProxies
+func (p *Proxies) ProcessProxies() error {
+	var err error
+	if p.httpProxy != "" {
+		p.HTTPProxy, err = url.Parse(p.httpProxy)
+		if err != nil || p.HTTPProxy.Host == "" || p.HTTPProxy.Scheme != "http" {
+			return cli.NewExitError(fmt.Sprintf("Could not parse HTTP proxy - expected format http://fqnd_or_ip:port: %s", p.httpProxy), 1)
+		}
+	}
+
+	if p.httpsProxy != "" {
+		p.HTTPSProxy, err = url.Parse(p.httpsProxy)
+		if err != nil || p.HTTPSProxy.Host == "" || p.HTTPSProxy.Scheme != "https" {
+			return cli.NewExitError(fmt.Sprintf("Could not parse HTTPS proxy - expected format https://fqnd_or_ip:port: %s", p.httpsProxy), 1)
+		}
+	}
+
+	for _, host := range p.noProxy {
+		if host = strings.TrimSpace(host); host != "" {
+			p.NoProxy = append(p.NoProxy, host)
+		}
+	}
+
+	for _, entry := range p.registryProxies {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return cli.NewExitError(fmt.Sprintf("Could not parse registry proxy - expected format registry=http(s)://fqdn_or_ip:port: %s", entry), 1)
+		}
+
+		proxy, err := url.Parse(parts[1])
+		if err != nil || proxy.Host == "" || (proxy.Scheme != "http" && proxy.Scheme != "https") {
+			return cli.NewExitError(fmt.Sprintf("Could not parse proxy for registry %s - expected format http(s)://fqdn_or_ip:port: %s", parts[0], parts[1]), 1)
+		}
+
+		if p.RegistryProxies == nil {
+			p.RegistryProxies = make(map[string]*url.URL)
+		}
+		p.RegistryProxies[parts[0]] = proxy
+	}
+
+	return nil
+}
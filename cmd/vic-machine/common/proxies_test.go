@@ -0,0 +1,45 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessProxies(t *testing.T) {
+	p := &Proxies{
+		httpProxy:       "http://proxy:3128",
+		noProxy:         []string{"localhost", " .corp.example.com ", "10.0.0.0/8"},
+		registryProxies: []string{"registry.example.com=http://registry-proxy:3128", "registry.example.com:5000=https://other:3129"},
+	}
+	require.NoError(t, p.ProcessProxies())
+
+	assert.Equal(t, "proxy:3128", p.HTTPProxy.Host)
+	assert.Nil(t, p.HTTPSProxy)
+	assert.Equal(t, []string{"localhost", ".corp.example.com", "10.0.0.0/8"}, p.NoProxy)
+	require.Len(t, p.RegistryProxies, 2)
+	assert.Equal(t, "http://registry-proxy:3128", p.RegistryProxies["registry.example.com"].String())
+	assert.Equal(t, "https://other:3129", p.RegistryProxies["registry.example.com:5000"].String())
+}
+
+func TestProcessRegistryProxiesInvalid(t *testing.T) {
+	for _, entry := range []string{"registry.example.com", "=http://proxy:3128", "registry.example.com=proxy:3128", "registry.example.com=ftp://proxy"} {
+		p := &Proxies{registryProxies: []string{entry}}
+		assert.Error(t, p.ProcessProxies(), entry)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// Registries holds the registries the appliance treats specially when fetching images
+type Registries struct {
+	// InsecureRegistries are the registries that may be reached over HTTP or without verifying
+	// their certificate
+	InsecureRegistries []url.URL
+	// RegistryCAs holds the PEM encoded certificate authorities trusted for registries in
+	// addition to the system roots
+	RegistryCAs []byte
+
+	insecureRegistries cli.StringSlice
+	registryCAs        cli.StringSlice
+}
+
+// RegistryFlags returns the cli flags for registries. The usage of each flag ends with
+// the given suffix, which describes how the flag changes an existing configuration.
+func (r *Registries) RegistryFlags(suffix string) []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "insecure-registry, dir",
+			Value: &r.insecureRegistries,
+			Usage: "Specify a list of permitted insecure registry server URLs" + suffix,
+		},
+		cli.StringSliceFlag{
+			Name:  "registry-ca, rc",
+			Value: &r.registryCAs,
+			Usage: "Specify a list of certificate authority files trusted for registries" + suffix,
+		},
+	}
+}
+
+// ProcessRegistries parses the insecure registries and loads the registry certificate authorities
+func (r *Registries) ProcessRegistries() error {
+	for _, registry := range r.insecureRegistries {
+		u, err := url.Parse(registry)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("%s is an invalid format for registry url", registry), 1)
+		}
+		r.InsecureRegistries = append(r.InsecureRegistries, *u)
+	}
+
+	for _, f := range r.registryCAs {
+		log.Infof("Loading registry CA from %s", f)
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to load registry authority from file %s: %s", f, err), 1)
+		}
+		r.RegistryCAs = append(r.RegistryCAs, b...)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessRegistries(t *testing.T) {
+	f, err := ioutil.TempFile("", "registry-ca")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	ca := "-----BEGIN CERTIFICATE-----\nca\n-----END CERTIFICATE-----\n"
+	_, err = f.WriteString(ca)
+	require.NoError(t, err)
+	f.Close()
+
+	r := &Registries{
+		insecureRegistries: []string{"registry.example.com:5000"},
+		registryCAs:        []string{f.Name(), f.Name()},
+	}
+	require.NoError(t, r.ProcessRegistries())
+
+	require.Len(t, r.InsecureRegistries, 1)
+	assert.Equal(t, "registry.example.com:5000", r.InsecureRegistries[0].String())
+	assert.Equal(t, ca+ca, string(r.RegistryCAs))
+
+	r = &Registries{registryCAs: []string{f.Name() + ".missing"}}
+	assert.Error(t, r.ProcessRegistries())
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/urfave/cli"
+)
+
+// releaseURLNone disables the release check of a VCH
+const releaseURLNone = "none"
+
+// ReleaseCheck holds the release metadata URL the VCH is checked against for newer versions
+type ReleaseCheck struct {
+	// ReleaseURL is the release metadata URL, nil if none was supplied and empty if the check is disabled
+	ReleaseURL *url.URL
+
+	releaseURL string
+}
+
+// ReleaseCheckFlags returns the cli flags for the release check
+func (r *ReleaseCheck) ReleaseCheckFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:        "release-url",
+			Value:       "",
+			Usage:       fmt.Sprintf("URL of the release metadata, or an admin hosted mirror of it, that the VCH version is checked against for available upgrades. Use %q to disable the check", releaseURLNone),
+			Destination: &r.releaseURL,
+		},
+	}
+}
+
+// ProcessReleaseCheck parses the release metadata URL, if one is given
+func (r *ReleaseCheck) ProcessReleaseCheck() error {
+	switch r.releaseURL {
+	case "":
+		return nil
+	case releaseURLNone:
+		r.ReleaseURL = &url.URL{}
+		return nil
+	}
+
+	u, err := url.Parse(r.releaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return cli.NewExitError(fmt.Sprintf("%s is an invalid format for release url, e.g. https://mirror.example.com/vic/release.json", r.releaseURL), 1)
+	}
+
+	r.ReleaseURL = u
+	return nil
+}
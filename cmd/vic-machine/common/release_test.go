@@ -0,0 +1,44 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessReleaseCheck(t *testing.T) {
+	r := &ReleaseCheck{}
+	assert.NoError(t, r.ProcessReleaseCheck())
+	assert.Nil(t, r.ReleaseURL, "no url supplied")
+
+	r = &ReleaseCheck{releaseURL: "none"}
+	assert.NoError(t, r.ProcessReleaseCheck())
+	if assert.NotNil(t, r.ReleaseURL) {
+		assert.Equal(t, "", r.ReleaseURL.String(), "check disabled")
+	}
+
+	r = &ReleaseCheck{releaseURL: "https://mirror.example.com/vic/release.json"}
+	assert.NoError(t, r.ProcessReleaseCheck())
+	if assert.NotNil(t, r.ReleaseURL) {
+		assert.Equal(t, "mirror.example.com", r.ReleaseURL.Host)
+	}
+
+	for _, bad := range []string{"mirror.example.com/release.json", "ftp://mirror.example.com/release.json", "https://"} {
+		r = &ReleaseCheck{releaseURL: bad}
+		assert.Error(t, r.ProcessReleaseCheck(), bad)
+	}
+}
@@ -16,8 +16,10 @@ package common
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 
@@ -34,6 +36,17 @@ type Target struct {
 	User       string
 	Password   *string
 	Thumbprint string
+
+	// TokenFile holds a SAML bearer token issued by the vCenter SSO service, which is loaded into
+	// Token and used to log in instead of user and password
+	TokenFile string
+	Token     string
+	// Ticket is a session ticket from SessionManager.AcquireCloneTicket, used to log in instead of
+	// user and password
+	Ticket string
+
+	// NonInteractive fails instead of prompting for input that was not supplied
+	NonInteractive bool
 }
 
 func NewTarget() *Target {
@@ -63,6 +76,24 @@ func (t *Target) TargetFlags() []cli.Flag {
 			Destination: &t.Thumbprint,
 			Usage:       "ESX or vCenter host certificate thumbprint",
 		},
+		cli.StringFlag{
+			Name:        "saml-token-file",
+			EnvVar:      "VIC_MACHINE_SAML_TOKEN_FILE",
+			Destination: &t.TokenFile,
+			Usage:       "File containing a SAML bearer token from vCenter SSO, used instead of user and password",
+		},
+		cli.StringFlag{
+			Name:        "session-ticket",
+			EnvVar:      "VIC_MACHINE_SESSION_TICKET",
+			Destination: &t.Ticket,
+			Usage:       "vCenter or ESX session clone ticket, used instead of user and password",
+		},
+		cli.BoolFlag{
+			Name:        "non-interactive",
+			EnvVar:      "VIC_MACHINE_NON_INTERACTIVE",
+			Destination: &t.NonInteractive,
+			Usage:       "Never prompt for input, failing if the password or a confirmation is not supplied",
+		},
 	}
 }
 
@@ -83,6 +114,10 @@ func (t *Target) HasCredentials() error {
 		return cli.NewExitError("--target argument must be specified", 1)
 	}
 
+	if t.TokenFile != "" || t.Ticket != "" {
+		return t.hasDelegatedCredentials()
+	}
+
 	var urlUser string
 	var urlPassword *string
 
@@ -100,11 +135,15 @@ func (t *Target) HasCredentials() error {
 
 	//prompt for passwd if not specified
 	if t.Password == nil && urlPassword == nil {
+		if t.NonInteractive {
+			return cli.NewExitError("vSphere password must be specified, either with --password or as part of --target, with --non-interactive", ExitValidationFailure)
+		}
+
 		log.Print("Please enter ESX or vCenter password: ")
 		b, err := terminal.ReadPassword(int(os.Stdin.Fd()))
 		if err != nil {
 			message := fmt.Sprintf("Failed to read password from stdin: %s", err)
-			cli.NewExitError(message, 1)
+			return cli.NewExitError(message, 1)
 		}
 		sb := string(b)
 		t.Password = &sb
@@ -117,3 +156,25 @@ func (t *Target) HasCredentials() error {
 
 	return nil
 }
+
+// hasDelegatedCredentials checks that only one of a SAML token or session ticket is supplied, loading
+// the token, and removes any password from the target URL as it is not used
+func (t *Target) hasDelegatedCredentials() error {
+	if t.TokenFile != "" && t.Ticket != "" {
+		return cli.NewExitError("Only one of --saml-token-file and --session-ticket can be specified", 1)
+	}
+	if t.Password != nil {
+		return cli.NewExitError("--password cannot be specified with --saml-token-file or --session-ticket", 1)
+	}
+
+	if t.TokenFile != "" {
+		b, err := ioutil.ReadFile(t.TokenFile)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("Failed to read SAML token: %s", err), 1)
+		}
+		t.Token = strings.TrimSpace(string(b))
+	}
+
+	t.URL.User = nil
+	return nil
+}
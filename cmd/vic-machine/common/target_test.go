@@ -15,7 +15,10 @@
 package common
 
 import (
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/urfave/cli"
@@ -27,7 +30,7 @@ func TestFlags(t *testing.T) {
 	target := NewTarget()
 	flags := target.TargetFlags()
 
-	if len(flags) != 4 {
+	if len(flags) != 7 {
 		t.Errorf("Wrong flag numbers")
 	}
 }
@@ -94,3 +97,73 @@ func TestProcess(t *testing.T) {
 		}
 	}
 }
+
+func TestDelegatedCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token.xml")
+	if err = ioutil.WriteFile(tokenFile, []byte("<saml2:Assertion/>\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	target := NewTarget()
+	target.URL, _ = soap.ParseURL("root:pass@127.0.0.1")
+	target.TokenFile = tokenFile
+	if err = target.HasCredentials(); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+	if target.Token != "<saml2:Assertion/>" {
+		t.Errorf("Unexpected token %q", target.Token)
+	}
+	if target.URL.User != nil {
+		t.Errorf("Unexpected credentials in url: %s", target.URL.String())
+	}
+
+	target = NewTarget()
+	target.URL, _ = soap.ParseURL("127.0.0.1")
+	target.Ticket = "ticket"
+	if err = target.HasCredentials(); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+
+	target.TokenFile = tokenFile
+	if err = target.HasCredentials(); err == nil {
+		t.Errorf("Expected error with both token and ticket")
+	}
+
+	passwd := "pass"
+	target = NewTarget()
+	target.URL, _ = soap.ParseURL("127.0.0.1")
+	target.Ticket = "ticket"
+	target.Password = &passwd
+	if err = target.HasCredentials(); err == nil {
+		t.Errorf("Expected error with both password and ticket")
+	}
+
+	target = NewTarget()
+	target.URL, _ = soap.ParseURL("127.0.0.1")
+	target.TokenFile = filepath.Join(dir, "missing.xml")
+	if err = target.HasCredentials(); err == nil {
+		t.Errorf("Expected error with missing token file")
+	}
+}
+
+func TestNonInteractiveCredentials(t *testing.T) {
+	target := NewTarget()
+	target.URL, _ = soap.ParseURL("root@127.0.0.1")
+	target.NonInteractive = true
+
+	err := target.HasCredentials()
+	if e, ok := err.(cli.ExitCoder); !ok || e.ExitCode() != ExitValidationFailure {
+		t.Errorf("Expected validation failure without password, got %#v", err)
+	}
+
+	target.URL, _ = soap.ParseURL("root:pass@127.0.0.1")
+	if err = target.HasCredentials(); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+}
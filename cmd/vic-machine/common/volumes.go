@@ -0,0 +1,82 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// VolumeStores holds the datastore locations for volumes, keyed by volume store label
+type VolumeStores struct {
+	VolumeLocations map[string]string
+	// VolumeStoragePolicies holds the storage policy IDs applied to new volumes, keyed by volume store label
+	VolumeStoragePolicies map[string]string
+
+	volumeStores        cli.StringSlice
+	volumeStorePolicies cli.StringSlice
+}
+
+// VolumeStoreFlags returns the cli flags for volume stores
+func (v *VolumeStores) VolumeStoreFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "volume-store, vs",
+			Value: &v.volumeStores,
+			Usage: "Specify a list of location and label for volume store, e.g. \"datastore/path:label\", \"datastore:label\" or \"nfs://host/export:label\".",
+		},
+		cli.StringSliceFlag{
+			Name:  "volume-store-policy",
+			Value: &v.volumeStorePolicies,
+			Usage: "vSphere storage policy ID applied to new volumes in a volume store, e.g. \"label:policy-id\", may be specified multiple times",
+		},
+	}
+}
+
+// ProcessVolumeStores parses the volume store flags into VolumeLocations
+func (v *VolumeStores) ProcessVolumeStores() error {
+	defer trace.End(trace.Begin(""))
+	v.VolumeLocations = make(map[string]string)
+	for _, arg := range v.volumeStores {
+		// URL locations contain a colon themselves, e.g. nfs://host:port/export:label, so the label
+		// follows the last colon
+		i := strings.Index(arg, ":")
+		if strings.Contains(arg, "://") {
+			i = strings.LastIndex(arg, ":")
+			if i < strings.Index(arg, "://")+len("://") {
+				i = -1
+			}
+		}
+		if i < 0 {
+			return errors.New("Volume store input must be in format datastore/path:label or nfs://host/export:label")
+		}
+		v.VolumeLocations[arg[i+1:]] = arg[:i]
+	}
+
+	v.VolumeStoragePolicies = make(map[string]string)
+	for _, arg := range v.volumeStorePolicies {
+		splitMeta := strings.SplitN(arg, ":", 2)
+		if len(splitMeta) != 2 || splitMeta[0] == "" || splitMeta[1] == "" {
+			return errors.New("Volume store policy input must be in format label:policy-id")
+		}
+		v.VolumeStoragePolicies[splitMeta[0]] = splitMeta[1]
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestProcessVolumeStores(t *testing.T) {
+	v := &VolumeStores{
+		volumeStores: cli.StringSlice{
+			"datastore1/volumes:default",
+			"datastore1:other",
+			"ds://datastore2/volumes:ds",
+			"nfs://fileserver/export:shared",
+			"nfs://10.0.0.5:2049/export?vers=4.1:nfs4",
+		},
+	}
+
+	if assert.NoError(t, v.ProcessVolumeStores()) {
+		assert.Equal(t, map[string]string{
+			"default": "datastore1/volumes",
+			"other":   "datastore1",
+			"ds":      "ds://datastore2/volumes",
+			"shared":  "nfs://fileserver/export",
+			"nfs4":    "nfs://10.0.0.5:2049/export?vers=4.1",
+		}, v.VolumeLocations)
+	}
+
+	for _, arg := range []string{"datastore1/volumes", "nfs://fileserver/export"} {
+		v = &VolumeStores{volumeStores: cli.StringSlice{arg}}
+		assert.Error(t, v.ProcessVolumeStores(), arg)
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configure
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/certificate"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+
+	"golang.org/x/net/context"
+)
+
+// Configure has all input parameters for vic-machine configure command
+type Configure struct {
+	*data.Data
+
+	cert      string
+	key       string
+	clientCAs cli.StringSlice
+	dns       cli.StringSlice
+
+	protect   bool
+	unprotect bool
+	yes       bool
+
+	executor *management.Dispatcher
+}
+
+func NewConfigure() *Configure {
+	configure := &Configure{}
+	configure.Data = data.NewData()
+
+	return configure
+}
+
+// Flags return all cli flags for configure
+func (c *Configure) Flags() []cli.Flag {
+	configure := []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "dns-server",
+			Value: &c.dns,
+			Usage: "DNS server for the client, external, and management networks, replacing those currently configured",
+		},
+		cli.StringFlag{
+			Name:        "key",
+			Value:       "",
+			Usage:       "Virtual Container Host private key file, replacing the current key",
+			Destination: &c.key,
+		},
+		cli.StringFlag{
+			Name:        "cert",
+			Value:       "",
+			Usage:       "Virtual Container Host x509 certificate file, replacing the current certificate",
+			Destination: &c.cert,
+		},
+		cli.StringSliceFlag{
+			Name:  "tls-ca, ca",
+			Usage: "Specify a list of certificate authority files to use for client verification, replacing those currently configured",
+			Value: &c.clientCAs,
+		},
+		cli.BoolFlag{
+			Name:        "protect",
+			Usage:       "Protect the VCH from deletion: vic-machine delete then fails unless --force-protected is given",
+			Destination: &c.protect,
+		},
+		cli.BoolFlag{
+			Name:        "unprotect",
+			Usage:       "Remove the protection of the VCH from deletion",
+			Destination: &c.unprotect,
+		},
+	}
+
+	util := []cli.Flag{
+		cli.BoolFlag{
+			Name:        "yes, y",
+			Usage:       "Apply the changes without asking for confirmation",
+			Destination: &c.yes,
+		},
+		cli.DurationFlag{
+			Name:        "timeout",
+			Value:       3 * time.Minute,
+			Usage:       "Time to wait for configure",
+			Destination: &c.Timeout,
+		},
+	}
+
+	target := c.TargetFlags()
+	id := c.IDFlags()
+	compute := c.ComputeFlags()
+	volumes := c.VolumeStoreFlags()
+	networks := c.ContainerNetworkFlags()
+	registries := c.RegistryFlags(", replacing those currently configured")
+	proxies := c.ProxyFlags(false)
+	appliance := c.ApplianceFlags(false)
+	maintenance := c.MaintenanceFlags(false)
+	release := c.ReleaseCheckFlags()
+	adminAuth := c.AdminAuthFlags()
+	debug := c.DebugFlags()
+
+	// flag arrays are declared, now combined
+	var flags []cli.Flag
+	for _, f := range [][]cli.Flag{target, id, compute, configure, registries, appliance, volumes, networks, proxies, maintenance, release, adminAuth, util, debug} {
+		flags = append(flags, f...)
+	}
+
+	return flags
+}
+
+func (c *Configure) processParams() error {
+	defer trace.End(trace.Begin(""))
+
+	if err := c.HasCredentials(); err != nil {
+		return err
+	}
+
+	if (c.cert == "") != (c.key == "") {
+		return cli.NewExitError("key and cert should be specified at the same time", 1)
+	}
+
+	if c.protect && c.unprotect {
+		return cli.NewExitError("protect and unprotect cannot be specified at the same time", 1)
+	}
+	if c.protect || c.unprotect {
+		c.Protected = &c.protect
+	}
+
+	for _, d := range c.dns {
+		s := net.ParseIP(d)
+		if s == nil {
+			return errors.New("Invalid DNS server specified")
+		}
+		c.Data.DNS = append(c.Data.DNS, s)
+	}
+
+	if err := c.ProcessContainerNetworks(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessVolumeStores(); err != nil {
+		return errors.Errorf("Error occurred while processing volume stores: %s", err)
+	}
+
+	if err := c.ProcessRegistries(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessProxies(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessMaintenance(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessReleaseCheck(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessAdminAuth(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessApplianceResources(); err != nil {
+		return err
+	}
+
+	return c.loadCertificates()
+}
+
+// loadCertificates reads the replacement certificate, key and certificate authorities, if specified
+func (c *Configure) loadCertificates() error {
+	defer trace.End(trace.Begin(""))
+
+	for _, f := range c.clientCAs {
+		log.Infof("Loading CA from %s", f)
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return errors.Errorf("Failed to load authority from file %s: %s", f, err)
+		}
+
+		c.ClientCAs = append(c.ClientCAs, b...)
+	}
+
+	if c.cert == "" {
+		return nil
+	}
+
+	log.Infof("Loading certificate/key pair - private key in %s", c.key)
+	keypair := certificate.NewKeyPair(c.cert, c.key, nil, nil)
+	if err := keypair.LoadCertificate(); err != nil {
+		log.Errorf("Failed to load certificate: %s", err)
+		return err
+	}
+
+	c.KeyPEM = keypair.KeyPEM
+	c.CertPEM = keypair.CertPEM
+	return nil
+}
+
+func (c *Configure) Run(cli *cli.Context) (err error) {
+	if err = c.processParams(); err != nil {
+		return common.Exit(err, common.ExitValidationFailure)
+	}
+
+	if c.Debug.Debug > 0 {
+		log.SetLevel(log.DebugLevel)
+		trace.Logger.Level = log.DebugLevel
+	}
+
+	if len(cli.Args()) > 0 {
+		log.Errorf("Unknown argument: %s", cli.Args()[0])
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
+	}
+
+	log.Infof("### Configuring VCH ####")
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	defer func() {
+		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
+			//context deadline exceeded, replace returned error message
+			err = common.Exit(errors.Errorf("Configure timed out: use --timeout to add more time"), common.ExitTimeout)
+		}
+	}()
+
+	validator, err := validate.NewValidator(ctx, c.Data)
+	if err != nil {
+		log.Errorf("Configure cannot continue - failed to create validator: %s", err)
+		return common.Exit(errors.New("configure failed"), common.TargetExitCode(err))
+	}
+	executor := management.NewDispatcher(validator.Context, validator.Session, nil, c.Force)
+
+	var vch *vm.VirtualMachine
+	if c.Data.ID != "" {
+		vch, err = executor.NewVCHFromID(c.Data.ID)
+	} else {
+		vch, err = executor.NewVCHFromComputePath(c.Data.ComputeResourcePath, c.Data.DisplayName, validator)
+	}
+	if err != nil {
+		log.Errorf("Failed to get Virtual Container Host %s", c.DisplayName)
+		log.Error(err)
+		return errors.New("configure failed")
+	}
+
+	log.Infof("")
+	log.Infof("VCH ID: %s", vch.Reference().String())
+
+	current, err := executor.GetVCHConfig(vch)
+	if err != nil {
+		log.Error("Failed to get Virtual Container Host configuration")
+		log.Error(err)
+		return errors.New("configure failed")
+	}
+	executor.InitDiagnosticLogs(current)
+
+	// decode a second copy of the configuration to apply the changes to
+	requested, err := executor.GetVCHConfig(vch)
+	if err != nil {
+		log.Error("Failed to get Virtual Container Host configuration")
+		log.Error(err)
+		return errors.New("configure failed")
+	}
+
+	if requested, err = validator.ValidateReconfigure(ctx, c.Data, requested); err != nil {
+		log.Error("Configure cannot continue: configuration validation failed")
+		return common.Exit(err, common.ExitValidationFailure)
+	}
+
+	vConfig := validator.AddDeprecatedFields(ctx, requested, c.Data)
+	vConfig.HTTPProxy = c.HTTPProxy
+	vConfig.HTTPSProxy = c.HTTPSProxy
+	vConfig.NoProxy = c.NoProxy
+	vConfig.RollbackTimeout = c.Timeout
+
+	preview, err := executor.PreviewReconfigure(vch, current, requested, vConfig)
+	if err != nil {
+		log.Errorf("Failed to compare the configuration changes: %s", err)
+		return errors.New("configure failed")
+	}
+	if preview.Empty() {
+		log.Infof("No configuration changes to apply")
+		return nil
+	}
+	showPreview(preview)
+
+	if !c.yes {
+		if err = confirm(c.NonInteractive); err != nil {
+			return err
+		}
+	}
+
+	if err = executor.Reconfigure(vch, current, requested, vConfig); err != nil {
+		executor.CollectDiagnosticLogs()
+		return common.OperationError(err)
+	}
+
+	log.Infof("Completed successfully")
+
+	return nil
+}
+
+// maxPreviewValue is the length that values are shortened to in the preview, as certificates are long
+const maxPreviewValue = 64
+
+// showPreview logs the changes that configure is about to make
+func showPreview(preview *management.ReconfigurePreview) {
+	log.Infof("")
+	log.Infof("Configuration changes:")
+	for _, c := range preview.Changes {
+		log.Infof("\t%s: %q => %q", c.Key, shorten(c.Before), shorten(c.After))
+	}
+	if preview.Resize != nil {
+		log.Infof("\tappliance size: %d CPUs, %d MB memory", preview.Resize.NumCPUs, preview.Resize.MemoryMB)
+	}
+	log.Infof("")
+
+	if preview.Restart() {
+		log.Infof("The appliance is restarted to apply the changes")
+		if components := preview.Components(); len(components) > 0 {
+			log.Infof("Components using the changed configuration: %s", strings.Join(components, ", "))
+		}
+	} else {
+		log.Infof("The appliance is resized while running")
+	}
+}
+
+// shorten cuts long values down for display
+func shorten(value string) string {
+	if len(value) <= maxPreviewValue {
+		return value
+	}
+	return value[:maxPreviewValue] + "..."
+}
+
+// confirm asks for confirmation of the changes on the terminal. Without a terminal, or with
+// --non-interactive, the changes must be confirmed with --yes.
+func confirm(nonInteractive bool) error {
+	if nonInteractive || !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return cli.NewExitError("Configure needs confirmation of the changes: specify --yes to apply them without a prompt", common.ExitValidationFailure)
+	}
+
+	log.Print("Apply these changes? [y/N]: ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return errors.Errorf("Failed to read confirmation from stdin: %s", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return cli.NewExitError("Configure cancelled, no changes were made", 1)
+}
@@ -0,0 +1,208 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/errors"
+
+	"golang.org/x/net/context"
+)
+
+// batchShared are the create options that apply to every VCH of a batch and cannot be set per
+// VCH, as the VCHs are created over one session
+var batchShared = []string{
+	"target", "user", "password", "thumbprint", "saml-token-file", "session-ticket", "compute-resource",
+	"force", "timeout", "component-timeout", "debug", "batch", "batch-workers",
+}
+
+// readBatch reads the VCHs of a batch file, each a map of create options to values. A value
+// may be a list for options that can be given more than once. JSON is accepted as YAML.
+func readBatch(file string) ([]map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var vchs []map[string]interface{}
+	if err = yaml.Unmarshal(b, &vchs); err != nil {
+		return nil, errors.Errorf("Failed to parse batch file %s: %s", file, err)
+	}
+	if len(vchs) == 0 {
+		return nil, errors.Errorf("Batch file %s lists no VCHs", file)
+	}
+	return vchs, nil
+}
+
+// batchEntry returns the create command for a VCH of a batch, with the options given on the
+// command line and those of the entry, the latter taking precedence
+func batchEntry(cliContext *cli.Context, entry map[string]interface{}) (*Create, error) {
+	e := NewCreate()
+	flags := e.Flags()
+
+	opts, err := common.Options(entry).Resolve(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := make(map[string]bool)
+	for _, name := range batchShared {
+		shared[name] = true
+	}
+	for name := range opts {
+		if shared[name] {
+			return nil, errors.Errorf("Option %q applies to the whole batch and must be given on the command line", name)
+		}
+	}
+
+	for _, f := range cliContext.Command.Flags {
+		names := common.FlagNames(f)
+		if _, ok := opts[names[0]]; ok {
+			continue
+		}
+		for _, name := range names {
+			if cliContext.IsSet(name) {
+				opts[names[0]] = cliContext.Generic(name)
+				break
+			}
+		}
+	}
+
+	if _, err = opts.FlagSet("create", flags); err != nil {
+		return nil, err
+	}
+	if err = e.processParams(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// runBatch creates the VCHs of the batch file concurrently. All of them are validated before
+// any is created, and --timeout bounds each round of --batch-workers creations.
+func (c *Create) runBatch(cliContext *cli.Context) (err error) {
+	if len(cliContext.Args()) > 0 {
+		log.Errorf("Unknown argument: %s", cliContext.Args()[0])
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
+	}
+
+	entries, err := readBatch(c.batchFile)
+	if err != nil {
+		return common.Exit(err, common.ExitValidationFailure)
+	}
+	if c.batchWorkers < 1 {
+		return cli.NewExitError("--batch-workers must be at least 1", common.ExitValidationFailure)
+	}
+
+	var creates []*Create
+	for i, entry := range entries {
+		e, err := batchEntry(cliContext, entry)
+		if err != nil {
+			return common.Exit(errors.Errorf("VCH %d of batch %s: %s", i+1, c.batchFile, err), common.ExitValidationFailure)
+		}
+		creates = append(creates, e)
+	}
+
+	log.Infof("### Installing %d VCHs ####", len(creates))
+
+	rounds := (len(creates) + c.batchWorkers - 1) / c.batchWorkers
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout*time.Duration(rounds))
+	defer cancel()
+	defer func() {
+		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
+			//context deadline exceeded, replace returned error message
+			err = common.Exit(errors.Errorf("Create timed out: use --timeout to add more time"), common.ExitTimeout)
+		}
+	}()
+
+	validator, err := validate.NewValidator(ctx, creates[0].Data)
+	if err != nil {
+		log.Error("Create cannot continue: failed to create validator")
+		return common.TargetError(err)
+	}
+
+	vchs := make([]*management.BatchVCH, len(creates))
+	for i, e := range creates {
+		var images map[string]string
+		if e.applianceOVA == "" {
+			if images, err = e.CheckImagesFiles(e.Force); err != nil {
+				return common.Exit(err, common.ExitValidationFailure)
+			}
+		}
+
+		validator.ClearIssues()
+		vchConfig, err := validator.Validate(ctx, e.Data)
+		if err != nil {
+			log.Errorf("Create cannot continue: configuration validation failed for VCH %q", e.DisplayName)
+			return common.Exit(err, common.ExitValidationFailure)
+		}
+
+		vConfig, err := e.installerSettings(ctx, validator, vchConfig, images)
+		if err != nil {
+			return err
+		}
+
+		vchs[i] = &management.BatchVCH{
+			Conf:       vchConfig,
+			Settings:   vConfig,
+			ClientCert: e.clientCert,
+		}
+	}
+
+	log.Info("")
+
+	executor := management.NewDispatcher(ctx, validator.Session, vchs[0].Conf, c.Force)
+	executor.ComponentTimeouts = creates[0].componentTimeouts
+	results := executor.CreateVCHs(vchs, c.batchWorkers)
+
+	var failed []string
+	for i, res := range results {
+		if res.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", res.Name, res.Err))
+			continue
+		}
+
+		e := creates[i]
+		log.Infof("")
+		log.Infof("VCH %q:", res.Name)
+		res.Dispatcher.ShowVCH(vchs[i].Conf, e.key, e.cert, e.cacert, e.envFile)
+	}
+
+	if len(failed) > 0 {
+		executor.CollectDiagnosticLogs()
+		log.Errorf("")
+		for _, f := range failed {
+			log.Errorf("Failed to create VCH %s", f)
+		}
+		err = errors.Errorf("%d of %d VCHs failed to be created", len(failed), len(results))
+		if len(failed) < len(results) {
+			return common.Exit(err, common.ExitPartialFailure)
+		}
+		return err
+	}
+
+	log.Infof("Installer completed successfully")
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+// commandContext returns the context of a create command run with the args
+func commandContext(t *testing.T, args ...string) *cli.Context {
+	c := NewCreate()
+	flags := c.Flags()
+
+	set := flag.NewFlagSet("create", flag.ContinueOnError)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	require.NoError(t, set.Parse(args))
+
+	ctx := cli.NewContext(nil, set, nil)
+	ctx.Command = cli.Command{Name: "create", Flags: flags}
+	return ctx
+}
+
+func writeBatch(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "batch")
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	return f.Name()
+}
+
+func TestReadBatch(t *testing.T) {
+	for _, content := range []string{
+		"- name: vch1\n  image-store: ds1\n- name: vch2\n  volume-store:\n  - ds1/a:default\n  - ds1/b:other\n",
+		`[{"name": "vch1", "image-store": "ds1"}, {"name": "vch2", "volume-store": ["ds1/a:default", "ds1/b:other"]}]`,
+	} {
+		file := writeBatch(t, content)
+		defer os.Remove(file)
+
+		vchs, err := readBatch(file)
+		require.NoError(t, err, content)
+		require.Len(t, vchs, 2)
+		assert.Equal(t, "vch1", vchs[0]["name"])
+		assert.Equal(t, []interface{}{"ds1/a:default", "ds1/b:other"}, vchs[1]["volume-store"])
+	}
+
+	file := writeBatch(t, "[]")
+	defer os.Remove(file)
+
+	_, err := readBatch(file)
+	assert.Error(t, err, "an empty batch is an error")
+}
+
+func TestBatchEntry(t *testing.T) {
+	ctx := commandContext(t, "-t", "10.0.0.1", "--user", "root", "--password", "secret", "--no-tls",
+		"-i", "ds1", "--volume-store", "ds1/volumes:default")
+
+	e, err := batchEntry(ctx, map[string]interface{}{
+		"name":           "vch1",
+		"i":              "ds2",
+		"bridge-network": "br1",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "vch1", e.DisplayName)
+	assert.Equal(t, "10.0.0.1", e.URL.Host+e.URL.Path)
+	assert.Equal(t, "secret", *e.Password)
+	assert.True(t, e.noTLS)
+	assert.Equal(t, "br1", e.BridgeNetworkName)
+
+	// values of the entry replace those from the command line
+	assert.Equal(t, []string{"ds2"}, e.ImageDatastorePaths)
+	assert.Equal(t, "ds1/volumes", e.VolumeLocations["default"])
+}
+
+func TestBatchEntryInvalid(t *testing.T) {
+	ctx := commandContext(t, "-t", "10.0.0.1", "--user", "root", "--password", "secret", "--no-tls")
+
+	for _, entry := range []map[string]interface{}{
+		{"name": "vch1", "target": "10.0.0.2"},
+		{"name": "vch1", "r": "/dc/host/cluster"},
+		{"name": "vch1", "no-such-option": "x"},
+		{"name": "vch1", "debug": "not-a-number"},
+	} {
+		_, err := batchEntry(ctx, entry)
+		assert.Error(t, err, "entry %v", entry)
+	}
+}
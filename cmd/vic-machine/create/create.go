@@ -17,7 +17,7 @@ package create
 import (
 	"bytes"
 	"crypto/tls"
-	"encoding"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -32,14 +32,22 @@ import (
 
 	"github.com/urfave/cli"
 
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/lib/install/telemetry"
 	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/lib/spec"
 	"github.com/vmware/vic/pkg/certificate"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/flags"
 	"github.com/vmware/vic/pkg/ip"
 	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/compute"
+	"github.com/vmware/vic/pkg/vsphere/datastore"
+	"github.com/vmware/vic/pkg/vsphere/disk"
 
 	"golang.org/x/net/context"
 )
@@ -80,9 +88,10 @@ type Create struct {
 
 	envFile string
 
-	cname   string
-	org     cli.StringSlice
-	keySize int
+	cname       string
+	serverNames cli.StringSlice
+	org         cli.StringSlice
+	keySize     int
 
 	noTLS           bool
 	noTLSverify     bool
@@ -90,30 +99,53 @@ type Create struct {
 
 	clientCAs cli.StringSlice
 
-	containerNetworks         cli.StringSlice
-	containerNetworksGateway  cli.StringSlice
-	containerNetworksIPRanges cli.StringSlice
-	containerNetworksDNS      cli.StringSlice
-	volumeStores              cli.StringSlice
-	insecureRegistries        cli.StringSlice
-	dns                       cli.StringSlice
-	clientNetworkName         string
-	clientNetworkGateway      string
-	clientNetworkIP           string
-	externalNetworkName       string
-	externalNetworkGateway    string
-	externalNetworkIP         string
-	managementNetworkName     string
-	managementNetworkGateway  string
-	managementNetworkIP       string
+	certExpiryWarnings cli.StringSlice
+
+	imageStores              cli.StringSlice
+	prefetchImages           cli.StringSlice
+	externalVMs              cli.StringSlice
+	dns                      cli.StringSlice
+	clientNetworkName        string
+	clientNetworkGateway     string
+	clientNetworkIP          string
+	clientNetworkDNS         cli.StringSlice
+	clientNetworkMTU         int
+	clientNetworkMAC         string
+	clientNetworkClientID    string
+	externalNetworkName      string
+	externalNetworkGateway   string
+	externalNetworkIP        string
+	externalNetworkDNS       cli.StringSlice
+	externalNetworkMTU       int
+	managementNetworkName    string
+	managementNetworkGateway string
+	managementNetworkIP      string
+	managementNetworkDNS     cli.StringSlice
+	managementNetworkMTU     int
 
 	memoryReservLimits string
 	cpuReservLimits    string
 
 	BridgeIPRange string
+	ipamWebhook   string
 
-	httpsProxy string
-	httpProxy  string
+	applianceOVA string
+
+	firewall string
+
+	containerLogDriver string
+	containerLogOpts   cli.StringSlice
+
+	componentTimeoutArgs cli.StringSlice
+	componentTimeouts    map[string]time.Duration
+
+	batchFile    string
+	batchWorkers int
+
+	dryRun  bool
+	protect bool
+
+	telemetryEndpoint string
 
 	executor *management.Dispatcher
 }
@@ -129,11 +161,23 @@ func NewCreate() *Create {
 func (c *Create) Flags() []cli.Flag {
 	create := []cli.Flag{
 		// images
+		cli.StringSliceFlag{
+			Name:  "image-store, i",
+			Value: &c.imageStores,
+			Usage: "Image datastore path in format \"datastore/path\", may be specified multiple times",
+		},
 		cli.StringFlag{
-			Name:        "image-store, i",
+			Name:        "image-store-placement",
+			Value:       datastore.PlacementPolicies[0],
+			Usage:       fmt.Sprintf("Placement policy when there are multiple image stores: %s", strings.Join(datastore.PlacementPolicies, ", ")),
+			Destination: &c.ImageStorePlacement,
+			Hidden:      true,
+		},
+		cli.StringFlag{
+			Name:        "image-store-policy",
 			Value:       "",
-			Usage:       "Image datastore path in format \"datastore/path\"",
-			Destination: &c.ImageDatastorePath,
+			Usage:       "vSphere storage policy ID applied to the base image and image layer disks, e.g. a vSAN policy",
+			Destination: &c.ImageStoragePolicy,
 		},
 		cli.StringFlag{
 			Name:        "base-image-size",
@@ -142,6 +186,25 @@ func (c *Create) Flags() []cli.Flag {
 			Destination: &c.ScratchSize,
 			Hidden:      true,
 		},
+		cli.StringFlag{
+			Name:        "disk-provisioning",
+			Value:       "",
+			Usage:       fmt.Sprintf("Provisioning of the base image and volume disks: %s. Defaults to thin", strings.Join(disk.ProvisioningTypes, ", ")),
+			Destination: &c.DiskProvisioning,
+		},
+		cli.StringFlag{
+			Name:        "storage-policy",
+			Value:       "",
+			Usage:       "vSphere storage policy ID applied to the appliance, containerVMs and their scratch disks, e.g. a VM encryption policy",
+			Destination: &c.StoragePolicy,
+		},
+		cli.StringFlag{
+			Name:        "appliance-ova",
+			Value:       "",
+			Usage:       "Signed OVA to import the appliance and bootstrap images from, for targets the images cannot be uploaded to",
+			Destination: &c.applianceOVA,
+			Hidden:      true,
+		},
 
 		// container disk
 		cli.StringFlag{
@@ -152,13 +215,6 @@ func (c *Create) Flags() []cli.Flag {
 			Hidden:      true,
 		},
 
-		// volume
-		cli.StringSliceFlag{
-			Name:  "volume-store, vs",
-			Value: &c.volumeStores,
-			Usage: "Specify a list of location and label for volume store, e.g. \"datastore/path:label\" or \"datastore:label\".",
-		},
-
 		// bridge
 		cli.StringFlag{
 			Name:        "bridge-network, b",
@@ -166,6 +222,24 @@ func (c *Create) Flags() []cli.Flag {
 			Usage:       "The bridge network port group name (private port group for containers). Defaults to the Virtual Container Host name",
 			Destination: &c.BridgeNetworkName,
 		},
+		cli.StringFlag{
+			Name:        "bridge-network-dvs",
+			Value:       "",
+			Usage:       "Distributed switch to create the bridge network port group on in vCenter, which is removed again on delete. The port group must not exist yet",
+			Destination: &c.BridgeNetworkDVS,
+		},
+		cli.IntFlag{
+			Name:        "bridge-network-vlan",
+			Value:       0,
+			Usage:       "VLAN ID of the bridge network port group created with --bridge-network-dvs, 0 for none",
+			Destination: &c.BridgeNetworkVLAN,
+		},
+		cli.StringFlag{
+			Name:        "bridge-network-port-binding",
+			Value:       management.PortBindingStatic,
+			Usage:       fmt.Sprintf("Port binding of the bridge network port group created with --bridge-network-dvs: %s or %s", management.PortBindingStatic, management.PortBindingEphemeral),
+			Destination: &c.BridgeNetworkPortBinding,
+		},
 		cli.StringFlag{
 			Name:        "bridge-network-range, bnr",
 			Value:       "172.16.0.0/12",
@@ -173,6 +247,20 @@ func (c *Create) Flags() []cli.Flag {
 			Destination: &c.BridgeIPRange,
 			Hidden:      true,
 		},
+		cli.IntFlag{
+			Name:        "bridge-network-mtu",
+			Value:       0,
+			Usage:       "MTU for interfaces on the bridge network. Defaults to the guest default",
+			Destination: &c.BridgeNetworkMTU,
+			Hidden:      true,
+		},
+		cli.StringFlag{
+			Name:        "ipam-webhook",
+			Value:       "",
+			Usage:       "URL of an external IPAM service that assigns container addresses on networks with an IP pool",
+			Destination: &c.ipamWebhook,
+			Hidden:      true,
+		},
 
 		// client
 		cli.StringFlag{
@@ -195,6 +283,33 @@ func (c *Create) Flags() []cli.Flag {
 			Destination: &c.clientNetworkIP,
 			Hidden:      true,
 		},
+		cli.StringSliceFlag{
+			Name:   "client-network-dns",
+			Value:  &c.clientNetworkDNS,
+			Usage:  "DNS server for the VCH on the client network when using a static IP, overriding --dns-server",
+			Hidden: true,
+		},
+		cli.IntFlag{
+			Name:        "client-network-mtu",
+			Value:       0,
+			Usage:       "MTU for the VCH on the client network. Defaults to the guest default",
+			Destination: &c.clientNetworkMTU,
+			Hidden:      true,
+		},
+		cli.StringFlag{
+			Name:        "client-network-mac",
+			Value:       "",
+			Usage:       "Static MAC address for the VCH on the client network, between 00:50:56:00:00:00 and 00:50:56:3f:ff:ff",
+			Destination: &c.clientNetworkMAC,
+			Hidden:      true,
+		},
+		cli.StringFlag{
+			Name:        "client-network-dhcp-client-id",
+			Value:       "",
+			Usage:       "DHCP client identifier for the VCH on the client network, for DHCP reservations keyed on it",
+			Destination: &c.clientNetworkClientID,
+			Hidden:      true,
+		},
 
 		// external
 		cli.StringFlag{
@@ -217,6 +332,19 @@ func (c *Create) Flags() []cli.Flag {
 			Destination: &c.externalNetworkIP,
 			Hidden:      true,
 		},
+		cli.StringSliceFlag{
+			Name:   "external-network-dns",
+			Value:  &c.externalNetworkDNS,
+			Usage:  "DNS server for the VCH on the external network when using a static IP, overriding --dns-server",
+			Hidden: true,
+		},
+		cli.IntFlag{
+			Name:        "external-network-mtu",
+			Value:       0,
+			Usage:       "MTU for the VCH on the external network. Defaults to the guest default",
+			Destination: &c.externalNetworkMTU,
+			Hidden:      true,
+		},
 
 		// management
 		cli.StringFlag{
@@ -239,6 +367,19 @@ func (c *Create) Flags() []cli.Flag {
 			Destination: &c.managementNetworkIP,
 			Hidden:      true,
 		},
+		cli.StringSliceFlag{
+			Name:   "management-network-dns",
+			Value:  &c.managementNetworkDNS,
+			Usage:  "DNS server for the VCH on the management network when using a static IP, overriding --dns-server",
+			Hidden: true,
+		},
+		cli.IntFlag{
+			Name:        "management-network-mtu",
+			Value:       0,
+			Usage:       "MTU for the VCH on the management network. Defaults to the guest default",
+			Destination: &c.managementNetworkMTU,
+			Hidden:      true,
+		},
 
 		// general DNS
 		cli.StringSliceFlag{
@@ -248,31 +389,6 @@ func (c *Create) Flags() []cli.Flag {
 			Hidden: true,
 		},
 
-		// container networks - mapped from vSphere
-		cli.StringSliceFlag{
-			Name:  "container-network, cn",
-			Value: &c.containerNetworks,
-			Usage: "vSphere network list that containers can use directly with labels, e.g. vsphere-net:backend. Defaults to DCHP - see advanced help (-x).",
-		},
-		cli.StringSliceFlag{
-			Name:   "container-network-gateway, cng",
-			Value:  &c.containerNetworksGateway,
-			Usage:  "Gateway for the container network's subnet in CONTAINER-NETWORK:SUBNET format, e.g. vsphere-net:172.16.0.0/16.",
-			Hidden: true,
-		},
-		cli.StringSliceFlag{
-			Name:   "container-network-ip-range, cnr",
-			Value:  &c.containerNetworksIPRanges,
-			Usage:  "IP range for the container network in CONTAINER-NETWORK:IP-RANGE format, e.g. vsphere-net:172.16.0.0/24, vsphere-net:172.16.0.10-20.",
-			Hidden: true,
-		},
-		cli.StringSliceFlag{
-			Name:   "container-network-dns, cnd",
-			Value:  &c.containerNetworksDNS,
-			Usage:  "DNS servers for the container network in CONTAINER-NETWORK:DNS format, e.g. vsphere-net:8.8.8.8. Ignored if no static IP assigned.",
-			Hidden: true,
-		},
-
 		// memory
 		cli.IntFlag{
 			Name:        "memory, mem",
@@ -293,13 +409,6 @@ func (c *Create) Flags() []cli.Flag {
 			Usage:  "VCH resource pool memory shares in level or share number, e.g. high, normal, low, or 163840",
 			Hidden: true,
 		},
-		cli.IntFlag{
-			Name:        "appliance-memory",
-			Value:       2048,
-			Usage:       "Memory for the appliance VM, in MB. Does not impact resources allocated per container.",
-			Hidden:      true,
-			Destination: &c.MemoryMB,
-		},
 
 		// cpu
 		cli.IntFlag{
@@ -321,12 +430,102 @@ func (c *Create) Flags() []cli.Flag {
 			Usage:  "VCH VCH resource pool vCPUs shares, in level or share number, e.g. high, normal, low, or 4000",
 			Hidden: true,
 		},
-		cli.IntFlag{
-			Name:        "appliance-cpu",
-			Value:       1,
-			Usage:       "vCPUs for the appliance VM",
+
+		// placement
+		cli.StringFlag{
+			Name:        "folder",
+			Value:       "",
+			Usage:       "VM folder to create the VCH in, relative to the datacenter VM folder, e.g. vic/production. Missing folders are created",
+			Destination: &c.VMFolder,
+		},
+		cli.StringFlag{
+			Name:        "host, appliance-host",
+			Value:       "",
+			Usage:       "Host of the compute resource to create the appliance VM on, instead of leaving placement to DRS",
+			Destination: &c.ApplianceHost,
+		},
+		cli.StringFlag{
+			Name:        "appliance-host-group",
+			Value:       "",
+			Usage:       "DRS host group of the cluster to keep the appliance VM on",
+			Destination: &c.ApplianceHostGroup,
+			Hidden:      true,
+		},
+		cli.StringFlag{
+			Name:        "container-host-group",
+			Value:       "",
+			Usage:       "DRS host group of the cluster to keep containerVMs on",
+			Destination: &c.ContainerHostGroup,
 			Hidden:      true,
-			Destination: &c.NumCPUs,
+		},
+		cli.BoolFlag{
+			Name:        "host-group-mandatory",
+			Usage:       "VMs must run on their host group, rather than DRS preferring it",
+			Destination: &c.HostGroupMandatory,
+			Hidden:      true,
+		},
+		cli.BoolFlag{
+			Name:        "container-anti-affinity",
+			Usage:       fmt.Sprintf("Keep containerVMs with the same %q label on different hosts", config.AntiAffinityLabel),
+			Destination: &c.ContainerAntiAffinity,
+			Hidden:      true,
+		},
+		cli.StringFlag{
+			Name:        "appliance-restart-priority",
+			Value:       "",
+			Usage:       fmt.Sprintf("vSphere HA restart priority of the appliance after a host failure (%s)", strings.Join(compute.RestartPriorities, ", ")),
+			Destination: &c.ApplianceHA.RestartPriority,
+		},
+		cli.StringFlag{
+			Name:        "appliance-isolation-response",
+			Value:       "",
+			Usage:       fmt.Sprintf("vSphere HA response when the appliance host is isolated (%s)", strings.Join(compute.IsolationResponses, ", ")),
+			Destination: &c.ApplianceHA.IsolationResponse,
+		},
+		cli.StringFlag{
+			Name:        "appliance-monitoring",
+			Value:       "",
+			Usage:       fmt.Sprintf("vSphere HA VM monitoring sensitivity for the appliance (%s)", strings.Join(compute.MonitoringSensitivities(), ", ")),
+			Destination: &c.ApplianceHA.Monitoring,
+		},
+		cli.StringFlag{
+			Name:        "container-vm-profile",
+			Value:       "",
+			Usage:       fmt.Sprintf("Virtual hardware profile of containerVMs (%s), density removes devices they do not use", strings.Join(spec.Profiles, ", ")),
+			Destination: &c.ContainerVMProfile,
+		},
+		cli.StringFlag{
+			Name:        "serial-concentrator",
+			Value:       "",
+			Usage:       fmt.Sprintf("Virtual serial port concentrator that containerVMs connect to the appliance through, %q or a telnet:// URI, for more attachable containers per host", config.SerialConcentratorAppliance),
+			Destination: &c.SerialConcentrator,
+		},
+		cli.BoolFlag{
+			Name:        "container-crash-logs",
+			Usage:       "Write the kernel console of containerVMs to a log in their datastore folder, to diagnose kernel panics",
+			Destination: &c.ContainerCrashLogs,
+		},
+		cli.StringFlag{
+			Name:        "container-log-driver",
+			Value:       "",
+			Usage:       fmt.Sprintf("Log driver that the output of containers is forwarded to when they are not given one: %s", strings.Join(executor.LogDrivers, ", ")),
+			Destination: &c.containerLogDriver,
+		},
+		cli.StringSliceFlag{
+			Name:  "container-log-opt",
+			Value: &c.containerLogOpts,
+			Usage: "Option of --container-log-driver in format key=value, as for docker --log-opt, may be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "external-vm",
+			Value: &c.externalVMs,
+			Usage: "Inventory path of an existing VM to list as a read-only external container, can be specified multiple times",
+		},
+		cli.StringFlag{
+			Name:        "firewall",
+			Value:       "",
+			Usage:       fmt.Sprintf("Set to %q to enable the host firewall rulesets needed for serial-over-LAN where they are disabled, instead of failing", firewallAllow),
+			Destination: &c.firewall,
 		},
 
 		// TLS
@@ -336,6 +535,11 @@ func (c *Create) Flags() []cli.Flag {
 			Usage:       "Common Name to use in generated CA certificate when requiring client certificate authentication",
 			Destination: &c.cname,
 		},
+		cli.StringSliceFlag{
+			Name:  "tls-server-name",
+			Value: &c.serverNames,
+			Usage: "Additional FQDN, IP address or wildcard name (*.yourdomain.com) to include in generated server certificates",
+		},
 		cli.StringSliceFlag{
 			Name:   "organization",
 			Usage:  "A list of identifiers to record in the generated certificates. Defaults to VCH name and IP/FQND if provided.",
@@ -380,30 +584,38 @@ func (c *Create) Flags() []cli.Flag {
 			Destination: &c.keySize,
 			Hidden:      true,
 		},
-
-		// registries
 		cli.StringSliceFlag{
-			Name:  "insecure-registry, dir",
-			Value: &c.insecureRegistries,
-			Usage: "Specify a list of permitted insecure registry server URLs",
+			Name:   "certificate-expiry-warning",
+			Usage:  "Time remaining before host certificate expiry at which to warn, e.g. 720h. Defaults to 30, 7 and 1 days",
+			Value:  &c.certExpiryWarnings,
+			Hidden: true,
 		},
-
-		// proxies
-		cli.StringFlag{
-			Name:        "https-proxy, sproxy",
-			Value:       "",
-			Usage:       "An HTTPS proxy for use when fetching images, in the form https://fqdn_or_ip:port",
-			Destination: &c.httpsProxy,
+		cli.BoolFlag{
+			Name:        "certificate-auto-renew",
+			Usage:       "Renew a self-signed host certificate automatically before it expires",
+			Destination: &c.CertAutoRenew,
 			Hidden:      true,
 		},
-
-		cli.StringFlag{
-			Name:        "http-proxy, hproxy",
-			Value:       "",
-			Usage:       "An HTTP proxy for use when fetching images, in the form http://fqdn_or_ip:port",
-			Destination: &c.httpProxy,
+		cli.DurationFlag{
+			Name:        "certificate-renewal-overlap",
+			Value:       certificate.DefaultRenewalOverlap,
+			Usage:       "How long before expiry a self-signed host certificate is renewed",
+			Destination: &c.CertRenewalOverlap,
 			Hidden:      true,
 		},
+
+		// images
+		cli.StringSliceFlag{
+			Name:  "prefetch-image",
+			Value: &c.prefetchImages,
+			Usage: "Image to pull into the image store once the VCH is up, can be specified multiple times",
+		},
+		cli.DurationFlag{
+			Name:        "prefetch-interval",
+			Value:       0,
+			Usage:       "How often prefetched images are pulled again to pick up new tags, 0 to pull once",
+			Destination: &c.PrefetchInterval,
+		},
 	}
 
 	util := []cli.Flag{
@@ -426,6 +638,53 @@ func (c *Create) Flags() []cli.Flag {
 			Usage:       "Time to wait for create",
 			Destination: &c.Timeout,
 		},
+		cli.DurationFlag{
+			Name:        "docker-api-timeout",
+			Value:       0,
+			Usage:       "Time to wait for the docker API of the VCH to respond once it is deployed, within --timeout. 0 waits until --timeout",
+			Destination: &c.DockerAPITimeout,
+		},
+		cli.DurationFlag{
+			Name:        "docker-api-attempt-timeout",
+			Value:       10 * time.Second,
+			Usage:       "Time to wait for each request to the docker API of the VCH while waiting for it to respond. 0 for no limit",
+			Destination: &c.DockerAPIAttemptTimeout,
+		},
+		cli.BoolFlag{
+			Name:        "protect",
+			Usage:       "Protect the VCH from deletion: vic-machine delete then fails unless --force-protected is given",
+			Destination: &c.protect,
+		},
+		cli.StringFlag{
+			Name:        "telemetry-endpoint",
+			Value:       "",
+			Usage:       "URL that the outcome of the install, the vSphere version and the features used are reported to, without names or addresses. Nothing is reported if not given",
+			EnvVar:      "VIC_MACHINE_TELEMETRY_ENDPOINT",
+			Destination: &c.telemetryEndpoint,
+		},
+		cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Validate the configuration and show what create would do as JSON, without changing anything",
+			Destination: &c.dryRun,
+		},
+		cli.StringFlag{
+			Name:        "batch",
+			Value:       "",
+			Usage:       "YAML or JSON file listing VCHs to create concurrently, each as a map of create options to values",
+			Destination: &c.batchFile,
+		},
+		cli.IntFlag{
+			Name:        "batch-workers",
+			Value:       4,
+			Usage:       "Number of VCHs of a batch to create at a time",
+			Destination: &c.batchWorkers,
+		},
+		cli.StringSliceFlag{
+			Name:   "component-timeout",
+			Value:  &c.componentTimeoutArgs,
+			Usage:  "Time to wait for an appliance component to launch, in the form component=duration, e.g. port-layer=2m",
+			Hidden: true,
+		},
 	}
 
 	help := []cli.Flag{
@@ -439,12 +698,20 @@ func (c *Create) Flags() []cli.Flag {
 
 	target := c.TargetFlags()
 	compute := c.ComputeFlags()
+	volumes := c.VolumeStoreFlags()
+	networks := c.ContainerNetworkFlags()
+	registries := c.RegistryFlags("")
+	proxies := c.ProxyFlags(true)
+	appliance := c.ApplianceFlags(true)
 	iso := c.ImageFlags(true)
+	maintenance := c.MaintenanceFlags(true)
+	release := c.ReleaseCheckFlags()
+	adminAuth := c.AdminAuthFlags()
 	debug := c.DebugFlags()
 
 	// flag arrays are declared, now combined
 	var flags []cli.Flag
-	for _, f := range [][]cli.Flag{target, compute, create, iso, util, debug, help} {
+	for _, f := range [][]cli.Flag{target, compute, create, registries, appliance, volumes, networks, proxies, iso, maintenance, release, adminAuth, util, debug, help} {
 		flags = append(flags, f...)
 	}
 
@@ -473,11 +740,13 @@ func (c *Create) processParams() error {
 		c.BridgeNetworkName = c.DisplayName
 	}
 
+	c.Protected = &c.protect
+
 	if len(c.DisplayName) > MaxDisplayNameLen {
 		return cli.NewExitError(fmt.Sprintf("Display name %s exceeds the permitted 31 characters limit. Please use a shorter -name parameter", c.DisplayName), 1)
 	}
 
-	if err := c.processContainerNetworks(); err != nil {
+	if err := c.ProcessContainerNetworks(); err != nil {
 		return err
 	}
 
@@ -485,18 +754,26 @@ func (c *Create) processParams() error {
 		return err
 	}
 
+	if err := c.processIPAMWebhook(); err != nil {
+		return err
+	}
+
 	if err := c.processNetwork(&c.Data.ClientNetwork, "client", c.clientNetworkName,
-		c.clientNetworkIP, c.clientNetworkGateway); err != nil {
+		c.clientNetworkIP, c.clientNetworkGateway, c.clientNetworkDNS, c.clientNetworkMTU); err != nil {
+		return err
+	}
+
+	if err := c.processClientIdentity(); err != nil {
 		return err
 	}
 
 	if err := c.processNetwork(&c.Data.ExternalNetwork, "external", c.externalNetworkName,
-		c.externalNetworkIP, c.externalNetworkGateway); err != nil {
+		c.externalNetworkIP, c.externalNetworkGateway, c.externalNetworkDNS, c.externalNetworkMTU); err != nil {
 		return err
 	}
 
 	if err := c.processNetwork(&c.Data.ManagementNetwork, "management", c.managementNetworkName,
-		c.managementNetworkIP, c.managementNetworkGateway); err != nil {
+		c.managementNetworkIP, c.managementNetworkGateway, c.managementNetworkDNS, c.managementNetworkMTU); err != nil {
 		return err
 	}
 
@@ -504,23 +781,93 @@ func (c *Create) processParams() error {
 		return err
 	}
 
+	if err := c.processComponentTimeouts(); err != nil {
+		return err
+	}
+
+	switch c.firewall {
+	case "":
+	case firewallAllow:
+		c.AllowFirewall = true
+	default:
+		return cli.NewExitError(fmt.Sprintf("--firewall must be %q if specified, not %q", firewallAllow, c.firewall), 1)
+	}
+
+	if err := c.ApplianceHA.Validate(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if err := spec.ValidateProfile(c.ContainerVMProfile); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if err := c.processFolder(); err != nil {
+		return err
+	}
+
+	if err := c.processContainerLog(); err != nil {
+		return err
+	}
+
+	if err := c.processSerialConcentrator(); err != nil {
+		return err
+	}
+
+	if _, err := disk.ParseProvisioning(c.DiskProvisioning); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if err := c.processApplianceOVA(); err != nil {
+		return err
+	}
+
 	// must come after client network processing as it checks for static IP on that interface
 	if err := c.processCertificates(); err != nil {
 		return err
 	}
 
-	if err := c.processVolumeStores(); err != nil {
+	if err := c.ProcessVolumeStores(); err != nil {
 		return errors.Errorf("Error occurred while processing volume stores: %s", err)
 	}
 
-	if err := c.processInsecureRegistries(); err != nil {
+	if err := c.processImageStores(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessRegistries(); err != nil {
+		return err
+	}
+
+	c.processPrefetchImages()
+	c.ExternalVMs = c.externalVMs
+
+	if err := c.ProcessProxies(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessMaintenance(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessReleaseCheck(); err != nil {
+		return err
+	}
+
+	if err := c.ProcessAdminAuth(); err != nil {
 		return err
 	}
 
-	if err := c.processProxies(); err != nil {
+	if err := c.ProcessApplianceResources(); err != nil {
 		return err
 	}
 
+	// the port layer takes longer to launch with more containers to inventory
+	if sizing := c.SizeAppliance(); sizing != nil {
+		if _, ok := c.componentTimeouts["port-layer"]; !ok {
+			c.componentTimeouts["port-layer"] = sizing.PortLayerTimeout
+		}
+	}
+
 	return nil
 }
 
@@ -561,6 +908,14 @@ func (c *Create) processCertificates() error {
 	c.KeyPEM = keypair.KeyPEM
 	c.CertPEM = keypair.CertPEM
 
+	for _, w := range c.certExpiryWarnings {
+		d, err := time.ParseDuration(w)
+		if err != nil || d <= 0 {
+			return cli.NewExitError(fmt.Sprintf("Invalid certificate expiry warning %q: must be a positive duration", w), 1)
+		}
+		c.CertExpiryThresholds = append(c.CertExpiryThresholds, d)
+	}
+
 	// do we have key, cert, and --no-tlsverify
 	if c.noTLSverify || len(cas) == 0 {
 		log.Warnf("Configuring without TLS verify - client authentication disabled")
@@ -579,86 +934,57 @@ func (c *Create) processBridgeNetwork() error {
 	if err != nil {
 		return cli.NewExitError(fmt.Sprintf("Error parsing bridge network ip range: %s. Range must be in CIDR format, e.g., 172.16.0.0/12", err), 1)
 	}
-	return nil
-}
 
-func (c *Create) processContainerNetworks() error {
-	gws, err := parseContainerNetworkGateways([]string(c.containerNetworksGateway))
-	if err != nil {
+	if err = common.CheckMTU("bridge", c.BridgeNetworkMTU); err != nil {
 		return cli.NewExitError(err.Error(), 1)
 	}
 
-	pools, err := parseContainerNetworkIPRanges([]string(c.containerNetworksIPRanges))
-	if err != nil {
-		return cli.NewExitError(err.Error(), 1)
+	if c.BridgeNetworkVLAN < 0 || c.BridgeNetworkVLAN > 4094 {
+		return cli.NewExitError(fmt.Sprintf("Bridge network VLAN ID %d must be between 0 and 4094", c.BridgeNetworkVLAN), 1)
 	}
-
-	dns, err := parseContainerNetworkDNS([]string(c.containerNetworksDNS))
-	if err != nil {
-		return cli.NewExitError(err.Error(), 1)
+	switch c.BridgeNetworkPortBinding {
+	case "", management.PortBindingStatic, management.PortBindingEphemeral:
+	default:
+		return cli.NewExitError(fmt.Sprintf("--bridge-network-port-binding must be %q or %q", management.PortBindingStatic, management.PortBindingEphemeral), 1)
 	}
-
-	// parse container networks
-	for _, cn := range c.containerNetworks {
-		vnet, v, err := splitVnetParam(cn)
-		if err != nil {
-			return cli.NewExitError(err.Error(), 1)
-		}
-
-		vicnet := vnet
-		if v != "" {
-			vicnet = v
-		}
-
-		c.MappedNetworks[vicnet] = vnet
-		c.MappedNetworksGateways[vicnet] = gws[vnet]
-		c.MappedNetworksIPRanges[vicnet] = pools[vnet]
-		c.MappedNetworksDNS[vicnet] = dns[vnet]
-
-		delete(gws, vnet)
-		delete(pools, vnet)
-		delete(dns, vnet)
+	if c.BridgeNetworkDVS == "" && c.BridgeNetworkVLAN != 0 {
+		return cli.NewExitError("--bridge-network-vlan can only be given with --bridge-network-dvs", 1)
 	}
+	return nil
+}
 
-	var hasError bool
-	fmtMsg := "The following container network %s is set, but CONTAINER-NETWORK cannot be found. Please check the --container-network and %s settings"
-	if len(gws) > 0 {
-		log.Error(fmt.Sprintf(fmtMsg, "gateway", "--container-network-gateway"))
-		for key, value := range gws {
-			mask, _ := value.Mask.Size()
-			log.Errorf("\t%s:%s/%d, %q should be vSphere network name", key, value.IP, mask, key)
-		}
-		hasError = true
-	}
-	if len(pools) > 0 {
-		log.Error(fmt.Sprintf(fmtMsg, "ip range", "--container-network-ip-range"))
-		for key, value := range pools {
-			log.Errorf("\t%s:%s, %q should be vSphere network name", key, value, key)
-		}
-		hasError = true
-	}
-	if len(dns) > 0 {
-		log.Errorf(fmt.Sprintf(fmtMsg, "dns", "--container-network-dns"))
-		for key, value := range dns {
-			log.Errorf("\t%s:%s, %q should be vSphere network name", key, value, key)
-		}
-		hasError = true
+// processIPAMWebhook parses the URL of the external IPAM service, if one is given
+func (c *Create) processIPAMWebhook() error {
+	if c.ipamWebhook == "" {
+		return nil
 	}
-	if hasError {
-		return cli.NewExitError("Inconsistent container network configuration.", 1)
+
+	u, err := url.Parse(c.ipamWebhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return cli.NewExitError(fmt.Sprintf("%s is an invalid format for IPAM webhook url, e.g. https://ipam.example.com/vic", c.ipamWebhook), 1)
 	}
+
+	c.IPAMWebhook = u
 	return nil
 }
 
 // processNetwork parses network args if present
-func (c *Create) processNetwork(network *data.NetworkConfig, netName, pgName, staticIP, gateway string) error {
+func (c *Create) processNetwork(network *data.NetworkConfig, netName, pgName, staticIP, gateway string, dns []string, mtu int) error {
 	network.Name = pgName
 
+	if err := common.CheckMTU(netName, mtu); err != nil {
+		return err
+	}
+	network.MTU = mtu
+
 	var err error
 
 	i := staticIP != ""
 	g := gateway != ""
 	if !i && !g {
+		if len(dns) > 0 {
+			log.Warnf("Specified DNS servers for the %s network are ignored as static IP is not set. VCH will use DNS servers provided by DHCP.", netName)
+		}
 		return nil
 	}
 	if i != g {
@@ -667,17 +993,39 @@ func (c *Create) processNetwork(network *data.NetworkConfig, netName, pgName, st
 
 	defer func(net *data.NetworkConfig) {
 		if err == nil {
-			log.Debugf("%s network: IP %q gateway %q", netName, net.IP, net.Gateway)
+			log.Debugf("%s network: IP %q gateway %q DNS %s", netName, net.IP, net.Gateway, net.Nameservers)
 		}
 	}(network)
 
+	for _, d := range dns {
+		s := net.ParseIP(d)
+		if s == nil {
+			err = fmt.Errorf("Invalid %s network DNS server: %s", netName, d)
+			return err
+		}
+		network.Nameservers = append(network.Nameservers, s)
+	}
+
 	network.Gateway, err = ip.ParseIPandMask(gateway)
 	if err != nil {
 		return fmt.Errorf("Invalid %s network gateway: %s", netName, err)
 	}
+	// IPv6 static assignment is only supported for the client network
+	if ip.IsIPv6(network.Gateway.IP) && netName != "client" {
+		err = fmt.Errorf("Invalid %s network gateway: %s is not an IPv4 address", netName, gateway)
+		return err
+	}
 
 	network.IP, err = ip.ParseIPandMask(staticIP)
 	if err == nil {
+		if !ip.SameFamily(network.IP.IP, network.Gateway.IP) {
+			err = fmt.Errorf("Invalid %s network address: %s and gateway %s are not of the same address family", netName, staticIP, gateway)
+			return err
+		}
+		if !network.Gateway.Contains(network.IP.IP) {
+			err = fmt.Errorf("Invalid %s network address: %s is not in the network specified by gateway %s", netName, staticIP, gateway)
+			return err
+		}
 		return nil
 	}
 
@@ -708,6 +1056,135 @@ func (c *Create) processNetwork(network *data.NetworkConfig, netName, pgName, st
 	return fmt.Errorf("Invalid %s network address: %s does not resolve to a gateway compatible IP", netName, staticIP)
 }
 
+// firewallAllow is the --firewall value that opens the host firewalls for serial-over-LAN
+const firewallAllow = "allow"
+
+// vSphere only accepts static MAC addresses in the range reserved for manual assignment
+var staticMACPrefix = net.HardwareAddr{0x00, 0x50, 0x56}
+
+const staticMACMaxByte = 0x3f
+
+// processClientIdentity checks the static MAC address and DHCP client identifier of the client network
+func (c *Create) processClientIdentity() error {
+	if c.clientNetworkMAC != "" {
+		mac, err := net.ParseMAC(c.clientNetworkMAC)
+		if err != nil || len(mac) != 6 {
+			return cli.NewExitError(fmt.Sprintf("Invalid client network MAC address %q", c.clientNetworkMAC), 1)
+		}
+		if !bytes.Equal(mac[:3], staticMACPrefix) || mac[3] > staticMACMaxByte {
+			return cli.NewExitError(fmt.Sprintf("Client network MAC address %s is not in the range 00:50:56:00:00:00 to 00:50:56:3f:ff:ff reserved for static addresses", mac), 1)
+		}
+		c.Data.ClientNetwork.MAC = mac.String()
+	}
+
+	if c.clientNetworkClientID != "" {
+		if !c.Data.ClientNetwork.Empty() {
+			log.Warn("The client network DHCP client identifier is ignored as a static IP is set")
+		}
+		c.Data.ClientNetwork.DHCPClientID = c.clientNetworkClientID
+	}
+	return nil
+}
+
+// processComponentTimeouts parses the per-component launch timeouts
+func (c *Create) processComponentTimeouts() error {
+	c.componentTimeouts = make(map[string]time.Duration)
+
+	for _, t := range c.componentTimeoutArgs {
+		parts := strings.SplitN(t, "=", 2)
+		if len(parts) != 2 {
+			return cli.NewExitError(fmt.Sprintf("Component timeout %q must be in the form component=duration", t), 1)
+		}
+
+		known := false
+		for _, name := range management.ApplianceComponents {
+			known = known || name == parts[0]
+		}
+		if !known {
+			return cli.NewExitError(fmt.Sprintf("Unknown appliance component %q, must be one of %s", parts[0], strings.Join(management.ApplianceComponents, ", ")), 1)
+		}
+
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil || timeout <= 0 {
+			return cli.NewExitError(fmt.Sprintf("Invalid timeout %q for component %s", parts[1], parts[0]), 1)
+		}
+		c.componentTimeouts[parts[0]] = timeout
+	}
+
+	return nil
+}
+
+// processFolder normalizes the VM folder path, which is relative to the datacenter VM folder
+func (c *Create) processFolder() error {
+	if c.VMFolder == "" {
+		return nil
+	}
+
+	// cleaning a rooted path also removes any attempt to step above the datacenter VM folder
+	folder := strings.Trim(path.Clean("/"+c.VMFolder), "/")
+	if folder == "" {
+		return cli.NewExitError(fmt.Sprintf("Invalid folder %q", c.VMFolder), 1)
+	}
+
+	c.VMFolder = folder
+	return nil
+}
+
+// processContainerLog parses the log driver that container output is forwarded to by default
+func (c *Create) processContainerLog() error {
+	if c.containerLogDriver == "" {
+		if len(c.containerLogOpts) > 0 {
+			return cli.NewExitError("container-log-opt needs container-log-driver", 1)
+		}
+		return nil
+	}
+
+	cfg, err := executor.ParseLogConfig(c.containerLogDriver, c.containerLogOpts)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Invalid container-log-opt: %s", err), 1)
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Invalid container-log-driver: %s", err), 1)
+	}
+
+	c.ContainerLogConfig = cfg
+	return nil
+}
+
+// processSerialConcentrator checks the virtual serial port concentrator is the appliance or a telnet URI
+func (c *Create) processSerialConcentrator() error {
+	if c.SerialConcentrator == "" || c.SerialConcentrator == config.SerialConcentratorAppliance {
+		return nil
+	}
+
+	u, err := url.Parse(c.SerialConcentrator)
+	if err != nil || (u.Scheme != "telnet" && u.Scheme != "telnets") || u.Host == "" {
+		return cli.NewExitError(fmt.Sprintf("serial-concentrator must be %q or a telnet:// or telnets:// URI with a host, not %q", config.SerialConcentratorAppliance, c.SerialConcentrator), 1)
+	}
+	return nil
+}
+
+// processApplianceOVA checks the appliance OVA, which replaces the appliance and bootstrap ISOs
+func (c *Create) processApplianceOVA() error {
+	if c.applianceOVA == "" {
+		return nil
+	}
+
+	if c.ApplianceISO != "" || c.BootstrapISO != "" {
+		return cli.NewExitError("appliance-ova cannot be specified with appliance-iso or bootstrap-iso", 1)
+	}
+
+	if _, err := os.Stat(c.applianceOVA); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to read appliance OVA: %s", err), 1)
+	}
+
+	// the names of the images within the OVA
+	c.ApplianceISO = common.ApplianceImageName
+	c.BootstrapISO = common.LinuxImageName
+	return nil
+}
+
 // processDNSServers parses DNS servers used for client, external, mgmt networks
 func (c *Create) processDNSServers() error {
 	if len(c.dns) == 0 {
@@ -733,49 +1210,17 @@ func (c *Create) processDNSServers() error {
 	return nil
 }
 
-func (c *Create) processVolumeStores() error {
-	defer trace.End(trace.Begin(""))
-	c.VolumeLocations = make(map[string]string)
-	for _, arg := range c.volumeStores {
-		splitMeta := strings.SplitN(arg, ":", 2)
-		if len(splitMeta) != 2 {
-			return errors.New("Volume store input must be in format datastore/path:label")
-		}
-		c.VolumeLocations[splitMeta[1]] = splitMeta[0]
+func (c *Create) processImageStores() error {
+	if err := datastore.ValidPlacementPolicy(c.ImageStorePlacement); err != nil {
+		return cli.NewExitError(fmt.Sprintf("Invalid image store placement: %s", err), 1)
 	}
 
+	c.ImageDatastorePaths = c.imageStores
 	return nil
 }
 
-func (c *Create) processInsecureRegistries() error {
-	for _, registry := range c.insecureRegistries {
-		url, err := url.Parse(registry)
-		if err != nil {
-			return cli.NewExitError(fmt.Sprintf("%s is an invalid format for registry url", registry), 1)
-		}
-		c.InsecureRegistries = append(c.InsecureRegistries, *url)
-	}
-
-	return nil
-}
-
-func (c *Create) processProxies() error {
-	var err error
-	if c.httpProxy != "" {
-		c.HTTPProxy, err = url.Parse(c.httpProxy)
-		if err != nil || c.HTTPProxy.Host == "" || c.HTTPProxy.Scheme != "http" {
-			return cli.NewExitError(fmt.Sprintf("Could not parse HTTP proxy - expected format http://fqnd_or_ip:port: %s", c.httpProxy), 1)
-		}
-	}
-
-	if c.httpsProxy != "" {
-		c.HTTPSProxy, err = url.Parse(c.httpsProxy)
-		if err != nil || c.HTTPSProxy.Host == "" || c.HTTPSProxy.Scheme != "https" {
-			return cli.NewExitError(fmt.Sprintf("Could not parse HTTPS proxy - expected format https://fqnd_or_ip:port: %s", c.httpsProxy), 1)
-		}
-	}
-
-	return nil
+func (c *Create) processPrefetchImages() {
+	c.PrefetchImages = c.prefetchImages
 }
 
 func (c *Create) loadCertificates() ([]byte, *certificate.KeyPair, error) {
@@ -811,6 +1256,25 @@ func (c *Create) loadCertificates() ([]byte, *certificate.KeyPair, error) {
 	return certs, keypair, nil
 }
 
+// certificateNames returns the names, in addition to the common name, to include as subjectAltNames
+// in generated server certificates - the static client network address and any --tls-server-name values
+func (c *Create) certificateNames() ([]string, error) {
+	var names []string
+
+	if !ip.Empty(c.Data.ClientNetwork.IP) {
+		names = append(names, c.Data.ClientNetwork.IP.IP.String())
+	}
+
+	for _, n := range c.serverNames {
+		if net.ParseIP(n) == nil && strings.Contains(strings.TrimPrefix(n, "*."), "*") {
+			return nil, fmt.Errorf("Invalid TLS server name %q: wildcards are only permitted as the leftmost label, e.g. *.yourdomain.com", n)
+		}
+		names = append(names, n)
+	}
+
+	return names, nil
+}
+
 func (c *Create) generateCertificates(ca bool) ([]byte, *certificate.KeyPair, error) {
 	defer trace.End(trace.Begin(""))
 
@@ -835,10 +1299,15 @@ func (c *Create) generateCertificates(ca bool) ([]byte, *certificate.KeyPair, er
 	cakey := fmt.Sprintf("./%s/ca-key.pem", c.DisplayName)
 	c.cacert = fmt.Sprintf("./%s/ca.pem", c.DisplayName)
 
+	alternates, err := c.certificateNames()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if !ca {
 		log.Infof("Generating self-signed certificate/key pair - private key in %s", c.key)
 		keypair := certificate.NewKeyPair(c.key, c.cert, nil, nil)
-		err := keypair.CreateSelfSigned(c.cname, nil, c.keySize)
+		err := keypair.CreateSelfSigned(c.cname, nil, c.keySize, alternates...)
 		if err != nil {
 			log.Errorf("Failed to generate self-signed certificate: %s", err)
 			return nil, nil, err
@@ -848,15 +1317,20 @@ func (c *Create) generateCertificates(ca bool) ([]byte, *certificate.KeyPair, er
 	}
 
 	// if we've not got a specific CommonName but do have a static IP then go with that.
-	if c.cname == "" && c.clientNetworkIP != "" {
-		c.cname = c.clientNetworkIP
+	if c.cname == "" && !ip.Empty(c.Data.ClientNetwork.IP) {
+		c.cname = c.Data.ClientNetwork.IP.IP.String()
 		log.Infof("Using client-network-ip as cname for server certificates - use --tls-cname to override: %s", c.cname)
 	}
+	if c.cname == "" && len(c.serverNames) > 0 {
+		c.cname = c.serverNames[0]
+		log.Infof("Using tls-server-name as cname for server certificates - use --tls-cname to override: %s", c.cname)
+	}
 
 	if c.cname == "" {
 		log.Error("Common Name must be provided when generating certificates for client authentication:")
 		log.Info("  --tls-cname=<FQDN or static IP> # for the appliance VM")
 		log.Info("  --tls-cname=<*.yourdomain.com>  # if DNS has entries in that form for DHCP addresses (less secure)")
+		log.Info("  --tls-server-name=<FQDN or IP>  # for each additional name the appliance VM is reached by")
 		log.Info("  --no-tlsverify                  # disables client authentication (anyone can connect to the VCH)")
 		log.Info("  --no-tls                        # disables TLS entirely")
 		log.Info("")
@@ -889,7 +1363,7 @@ func (c *Create) generateCertificates(ca bool) ([]byte, *certificate.KeyPair, er
 	// Server certificates
 	log.Infof("Generating server certificate/key pair - private key in %s", skey)
 	skp := certificate.NewKeyPair(scert, skey, nil, nil)
-	err = skp.CreateServerCertificate(c.cname, c.org, c.keySize, cakp)
+	err = skp.CreateServerCertificate(c.cname, c.org, c.keySize, cakp, alternates...)
 	if err != nil {
 		log.Errorf("Failed to generate server certificates: %s", err)
 		return nil, nil, err
@@ -932,6 +1406,34 @@ func (c *Create) generateCertificates(ca bool) ([]byte, *certificate.KeyPair, er
 	return cakp.CertPEM, skp, nil
 }
 
+// installerSettings returns the settings for creating the validated VCH that are not part of
+// its configuration, adding the remaining options to the configuration
+func (c *Create) installerSettings(ctx context.Context, validator *validate.Validator, vchConfig *config.VirtualContainerHostConfigSpec, images map[string]string) (*data.InstallerData, error) {
+	vConfig := validator.AddDeprecatedFields(ctx, vchConfig, c.Data)
+	vConfig.ImageFiles = images
+	vConfig.ApplianceOVA = c.applianceOVA
+	vConfig.ApplianceISO = path.Base(c.ApplianceISO)
+	vConfig.BootstrapISO = path.Base(c.BootstrapISO)
+
+	vConfig.HTTPProxy = c.HTTPProxy
+	vConfig.HTTPSProxy = c.HTTPSProxy
+	vConfig.NoProxy = c.NoProxy
+	vConfig.RollbackTimeout = c.Timeout
+
+	vchConfig.RegistryProxies = c.Data.RegistryProxies
+
+	if validator.Session.IsVC() { // create certificates for VCH extension
+		certbuffer, keybuffer, err := certificate.CreateSelfSigned("", []string{"VMware Inc."}, 2048)
+		if err != nil {
+			return nil, errors.Errorf("Failed to create certificate for VIC vSphere extension: %s", err)
+		}
+		vchConfig.ExtensionCert = certbuffer.String()
+		vchConfig.ExtensionKey = keybuffer.String()
+	}
+
+	return vConfig, nil
+}
+
 func (c *Create) Run(cliContext *cli.Context) (err error) {
 
 	if c.advancedOptions {
@@ -943,18 +1445,36 @@ func (c *Create) Run(cliContext *cli.Context) (err error) {
 		log.SetLevel(log.DebugLevel)
 		trace.Logger.Level = log.DebugLevel
 	}
+	if c.batchFile != "" {
+		return c.runBatch(cliContext)
+	}
 	if err = c.processParams(); err != nil {
-		return err
+		return common.Exit(err, common.ExitValidationFailure)
+	}
+
+	// a dry run is not reported, as nothing is installed
+	var reporter *telemetry.Reporter
+	if !c.dryRun {
+		if reporter, err = telemetry.NewReporter(c.telemetryEndpoint, "create"); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
 	}
+	reporter.Features(c.Data)
+	defer func() {
+		reporter.Send(err)
+	}()
 
 	var images map[string]string
-	if images, err = c.CheckImagesFiles(c.Force); err != nil {
-		return err
+	reporter.Stage("images")
+	if c.applianceOVA == "" {
+		if images, err = c.CheckImagesFiles(c.Force); err != nil {
+			return common.Exit(err, common.ExitValidationFailure)
+		}
 	}
 
 	if len(cliContext.Args()) > 0 {
 		log.Errorf("Unknown argument: %s", cliContext.Args()[0])
-		return errors.New("invalid CLI arguments")
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
 	}
 
 	log.Infof("### Installing VCH ####")
@@ -964,56 +1484,53 @@ func (c *Create) Run(cliContext *cli.Context) (err error) {
 	defer func() {
 		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
 			//context deadline exceeded, replace returned error message
-			err = errors.Errorf("Create timed out: use --timeout to add more time")
+			err = common.Exit(errors.Errorf("Create timed out: use --timeout to add more time"), common.ExitTimeout)
 		}
 	}()
 
+	reporter.Stage("validation")
 	validator, err := validate.NewValidator(ctx, c.Data)
 	if err != nil {
 		log.Error("Create cannot continue: failed to create validator")
-		return err
+		return common.TargetError(err)
 	}
+	reporter.Environment(validator.Session)
 
 	vchConfig, err := validator.Validate(ctx, c.Data)
 	if err != nil {
 		log.Error("Create cannot continue: configuration validation failed")
-		return err
+		return common.Exit(err, common.ExitValidationFailure)
 	}
 
-	vConfig := validator.AddDeprecatedFields(ctx, vchConfig, c.Data)
-	vConfig.ImageFiles = images
-	vConfig.ApplianceISO = path.Base(c.ApplianceISO)
-	vConfig.BootstrapISO = path.Base(c.BootstrapISO)
-
-	vConfig.HTTPProxy = c.HTTPProxy
-	vConfig.HTTPSProxy = c.HTTPSProxy
-
-	vchConfig.InsecureRegistries = c.Data.InsecureRegistries
-
-	if validator.Session.IsVC() { // create certificates for VCH extension
-		var certbuffer, keybuffer bytes.Buffer
-		if certbuffer, keybuffer, err = certificate.CreateSelfSigned("", []string{"VMware Inc."}, 2048); err != nil {
-			return errors.Errorf("Failed to create certificate for VIC vSphere extension: %s", err)
-		}
-		vchConfig.ExtensionCert = certbuffer.String()
-		vchConfig.ExtensionKey = keybuffer.String()
+	vConfig, err := c.installerSettings(ctx, validator, vchConfig, images)
+	if err != nil {
+		return err
 	}
 
 	// separate initial validation from dispatch of creation task
 	log.Info("")
 
 	executor := management.NewDispatcher(ctx, validator.Session, vchConfig, c.Force)
+	if c.dryRun {
+		return c.showPlan(cliContext, executor, vchConfig, vConfig)
+	}
+
+	executor.ComponentTimeouts = c.componentTimeouts
+	executor.DockerAPITimeout = c.DockerAPITimeout
+	executor.DockerAPIAttemptTimeout = c.DockerAPIAttemptTimeout
+	reporter.Stage("create")
 	if err = executor.CreateVCH(vchConfig, vConfig); err != nil {
 
 		executor.CollectDiagnosticLogs()
-		return err
+		return common.OperationError(err)
 	}
 
-	// check the docker endpoint is responsive
+	// check the docker endpoint is responsive, the VCH exists if it is not
+	reporter.Stage("docker-api")
 	if err = executor.CheckDockerAPI(vchConfig, c.clientCert); err != nil {
 
 		executor.CollectDiagnosticLogs()
-		return err
+		return common.Exit(err, common.ExitPartialFailure)
 	}
 
 	log.Infof("Initialization of appliance successful")
@@ -1023,105 +1540,20 @@ func (c *Create) Run(cliContext *cli.Context) (err error) {
 	return nil
 }
 
-type ipNetUnmarshaler struct {
-	ipnet *net.IPNet
-	ip    net.IP
-}
-
-func (m *ipNetUnmarshaler) UnmarshalText(text []byte) error {
-	s := string(text)
-	ip, ipnet, err := net.ParseCIDR(s)
+// showPlan writes what creating the VCH would do to the application writer as JSON
+func (c *Create) showPlan(cliContext *cli.Context, executor *management.Dispatcher, conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+	plan, err := executor.PlanVCH(conf, settings)
 	if err != nil {
+		log.Error("Create dry run failed")
 		return err
 	}
 
-	m.ipnet = ipnet
-	m.ip = ip
-	return nil
-}
-
-func parseContainerNetworkGateways(cgs []string) (map[string]net.IPNet, error) {
-	gws := make(map[string]net.IPNet)
-	for _, cg := range cgs {
-		m := &ipNetUnmarshaler{}
-		vnet, err := parseVnetParam(cg, m)
-		if err != nil {
-			return nil, err
-		}
-
-		if _, ok := gws[vnet]; ok {
-			return nil, fmt.Errorf("Duplicate gateway specified for container network %s", vnet)
-		}
-
-		gws[vnet] = net.IPNet{IP: m.ip, Mask: m.ipnet.Mask}
-	}
-
-	return gws, nil
-}
-
-func parseContainerNetworkIPRanges(cps []string) (map[string][]ip.Range, error) {
-	pools := make(map[string][]ip.Range)
-	for _, cp := range cps {
-		ipr := &ip.Range{}
-		vnet, err := parseVnetParam(cp, ipr)
-		if err != nil {
-			return nil, err
-		}
-
-		pools[vnet] = append(pools[vnet], *ipr)
-	}
-
-	return pools, nil
-}
-
-func parseContainerNetworkDNS(cds []string) (map[string][]net.IP, error) {
-	dns := make(map[string][]net.IP)
-	for _, cd := range cds {
-		var ip net.IP
-		vnet, err := parseVnetParam(cd, &ip)
-		if err != nil {
-			return nil, err
-		}
-
-		if ip == nil {
-			return nil, fmt.Errorf("DNS IP not specified for container network %s", vnet)
-		}
-
-		dns[vnet] = append(dns[vnet], ip)
-	}
-
-	return dns, nil
-}
-
-func splitVnetParam(p string) (vnet string, value string, err error) {
-	mapped := strings.Split(p, ":")
-	if len(mapped) == 0 || len(mapped) > 2 {
-		err = fmt.Errorf("Invalid value for parameter %s", p)
-		return
-	}
-
-	vnet = mapped[0]
-	if vnet == "" {
-		err = fmt.Errorf("Container network not specified in parameter %s", p)
-		return
-	}
-
-	if len(mapped) > 1 {
-		value = mapped[1]
-	}
-
-	return
-}
-
-func parseVnetParam(p string, m encoding.TextUnmarshaler) (vnet string, err error) {
-	vnet, v, err := splitVnetParam(p)
+	out, err := json.MarshalIndent(plan, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("Error parsing container network parameter %s: %s", p, err)
-	}
-
-	if err = m.UnmarshalText([]byte(v)); err != nil {
-		return "", fmt.Errorf("Error parsing container network parameter %s: %s", p, err)
+		return err
 	}
+	fmt.Fprintf(cliContext.App.Writer, "%s\n", out)
 
-	return vnet, nil
+	log.Infof("Dry run completed successfully, nothing was created")
+	return nil
 }
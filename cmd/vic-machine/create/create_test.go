@@ -15,197 +15,163 @@
 package create
 
 import (
-	"bytes"
-	"fmt"
 	"net"
 	"testing"
+	"time"
 
-	"github.com/vmware/vic/pkg/ip"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/install/data"
 )
 
-func TestParseContainerNetworkGateways(t *testing.T) {
-	var tests = []struct {
-		cgs []string
-		gws map[string]net.IPNet
-		err error
-	}{
-		{[]string{""}, nil, fmt.Errorf("")},
-		{[]string{"foo:"}, nil, fmt.Errorf("")},
-		{[]string{":"}, nil, fmt.Errorf("")},
-		{[]string{":10.10.10.10/24"}, nil, fmt.Errorf("")},
-		{[]string{":foo"}, nil, fmt.Errorf("")},
-		{[]string{"foo:10"}, nil, fmt.Errorf("")},
-		{[]string{"foo:10.10.10.10/24", "foo:10.10.10.2/24"}, nil, fmt.Errorf("")},
-		{
-			[]string{"foo:10.10.10.10/24", "bar:10.10.9.1/16"},
-			map[string]net.IPNet{
-				"foo": {IP: net.ParseIP("10.10.10.10"), Mask: net.CIDRMask(24, 32)},
-				"bar": {IP: net.ParseIP("10.10.9.1"), Mask: net.CIDRMask(16, 32)},
-			},
-			nil,
-		},
+func TestProcessNetwork(t *testing.T) {
+	c := NewCreate()
+
+	var network data.NetworkConfig
+	err := c.processNetwork(&network, "client", "pg", "10.0.0.2/24", "10.0.0.1/24", []string{"10.0.0.53", "10.0.1.53"}, 0)
+	if !assert.NoError(t, err) {
+		return
 	}
 
-	for _, te := range tests {
-		gws, err := parseContainerNetworkGateways(te.cgs)
-		if te.err != nil {
-			if err == nil {
-				t.Fatalf("parseContainerNetworkGateways(%s) => (%v, nil) want (nil, err)", te.cgs, gws)
-			}
+	assert.Equal(t, "pg", network.Name)
+	assert.Equal(t, "10.0.0.2/24", network.IP.String())
+	assert.Equal(t, "10.0.0.1/24", network.Gateway.String())
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.53"), net.ParseIP("10.0.1.53")}, network.Nameservers)
+
+	// IPv6 is supported on the client network
+	network = data.NetworkConfig{}
+	err = c.processNetwork(&network, "client", "pg", "fd00::2/64", "fd00::1/64", []string{"fd00::53"}, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "fd00::2/64", network.IP.String())
+		assert.Equal(t, []net.IP{net.ParseIP("fd00::53")}, network.Nameservers)
+	}
 
-			continue
-		}
+	// DHCP
+	network = data.NetworkConfig{}
+	assert.NoError(t, c.processNetwork(&network, "client", "pg", "", "", []string{"10.0.0.53"}, 0))
+	assert.True(t, network.Empty())
+	assert.Empty(t, network.Nameservers)
 
-		if err != te.err ||
-			gws == nil ||
-			len(gws) != len(te.gws) {
-			t.Fatalf("parseContainerNetworkGateways(%s) => (%v, %s) want (%v, nil)", te.cgs, gws, err, te.gws)
-		}
+	// the MTU applies with DHCP as well
+	network = data.NetworkConfig{}
+	assert.NoError(t, c.processNetwork(&network, "external", "pg", "", "", nil, 9000))
+	assert.Equal(t, 9000, network.MTU)
+}
 
-		for v, g := range gws {
-			if g2, ok := te.gws[v]; !ok {
-				t.Fatalf("parseContainerNetworkGateways(%s) => (%v, %s) want (%v, nil)", te.cgs, gws, err, te.gws)
-			} else if !g2.IP.Equal(g.IP) || bytes.Compare(g2.Mask, g.Mask) != 0 {
-				t.Fatalf("parseContainerNetworkGateways(%s) => (%v, %s) want (%v, nil)", te.cgs, gws, err, te.gws)
-			}
-		}
+func TestProcessNetworkInvalid(t *testing.T) {
+	c := NewCreate()
+
+	tests := []struct {
+		name    string
+		ip      string
+		gateway string
+		dns     []string
+		mtu     int
+	}{
+		{"client", "10.0.0.2/24", "", nil, 0},
+		{"client", "", "10.0.0.1/24", nil, 0},
+		{"client", "10.0.0.2/24", "10.0.0.1/24", []string{"not-an-ip"}, 0},
+		{"client", "10.0.1.2/24", "10.0.0.1/24", nil, 0},
+		{"client", "fd00::2/64", "10.0.0.1/24", nil, 0},
+		{"client", "10.0.0.2/24", "fd00::1/64", nil, 0},
+		{"external", "fd00::2/64", "fd00::1/64", nil, 0},
+		{"management", "fd00::2/64", "fd00::1/64", nil, 0},
+		{"client", "", "", nil, 9001},
+		{"management", "", "", nil, 12},
+	}
+
+	for _, test := range tests {
+		var network data.NetworkConfig
+		err := c.processNetwork(&network, test.name, "pg", test.ip, test.gateway, test.dns, test.mtu)
+		assert.Error(t, err, "Expected error for %s network IP %q gateway %q DNS %s MTU %d", test.name, test.ip, test.gateway, test.dns, test.mtu)
 	}
 }
 
-func TestParseContainerNetworkIPRanges(t *testing.T) {
-	var tests = []struct {
-		cps  []string
-		iprs map[string][]*ip.Range
-		err  error
-	}{
-		{[]string{""}, nil, fmt.Errorf("")},
-		{[]string{"foo:"}, nil, fmt.Errorf("")},
-		{[]string{":"}, nil, fmt.Errorf("")},
-		{[]string{":10.10.10.10-24"}, nil, fmt.Errorf("")},
-		{[]string{":foo"}, nil, fmt.Errorf("")},
-		{[]string{"foo:10"}, nil, fmt.Errorf("")},
-		{[]string{"foo:10.10.10.10-9"}, nil, fmt.Errorf("")},
-		{[]string{"foo:10.10.10.10-10.10.10.9"}, nil, fmt.Errorf("")},
-		{
-			[]string{"foo:10.10.10.10-24"},
-			map[string][]*ip.Range{"foo": {ip.NewRange(net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24"))}}, nil},
-		{
-			[]string{"foo:10.10.10.10-10.10.10.24"},
-			map[string][]*ip.Range{"foo": {ip.NewRange(net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24"))}},
-			nil,
-		},
-		{
-			[]string{"foo:10.10.10.10-10.10.10.24", "foo:10.10.10.100-10.10.10.105"},
-			map[string][]*ip.Range{
-				"foo": {
-					ip.NewRange(net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24")),
-					ip.NewRange(net.ParseIP("10.10.10.100"), net.ParseIP("10.10.10.105")),
-				},
-			},
-			nil,
-		},
-		{
-			[]string{"foo:10.10.10.10-10.10.10.24", "bar:10.10.9.1-10.10.9.10"},
-			map[string][]*ip.Range{
-				"foo": {ip.NewRange(net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24"))},
-				"bar": {ip.NewRange(net.ParseIP("10.10.9.1"), net.ParseIP("10.10.9.10"))},
-			},
-			nil,
-		},
+func TestProcessComponentTimeouts(t *testing.T) {
+	c := NewCreate()
+	c.componentTimeoutArgs = []string{"port-layer=2m", "vicadmin=30s"}
+
+	if assert.NoError(t, c.processComponentTimeouts()) {
+		assert.Equal(t, map[string]time.Duration{"port-layer": 2 * time.Minute, "vicadmin": 30 * time.Second}, c.componentTimeouts)
 	}
 
-	for _, te := range tests {
-		iprs, err := parseContainerNetworkIPRanges(te.cps)
-		if te.err != nil {
-			if err == nil {
-				t.Fatalf("parseContainerNetworkIPRanges(%s) => (%v, nil) want (nil, err)", te.cps, iprs)
-			}
+	for _, arg := range []string{"port-layer", "unknown=1m", "vicadmin=soon", "vicadmin=-1s"} {
+		c.componentTimeoutArgs = []string{arg}
+		assert.Error(t, c.processComponentTimeouts(), arg)
+	}
+}
 
-			continue
+func TestProcessFolder(t *testing.T) {
+	c := NewCreate()
+
+	for arg, folder := range map[string]string{
+		"":                 "",
+		"vic":              "vic",
+		"/vic/production/": "vic/production",
+		"vic//production":  "vic/production",
+		"../vic":           "vic",
+	} {
+		c.VMFolder = arg
+		if assert.NoError(t, c.processFolder(), arg) {
+			assert.Equal(t, folder, c.VMFolder, arg)
 		}
+	}
 
-		if err != te.err ||
-			len(iprs) != len(te.iprs) {
-			t.Fatalf("parseContainerNetworkIPRanges(%s) => (%v, %s) want (%v, %s)", te.cps, iprs, err, te.iprs, te.err)
-		}
+	for _, arg := range []string{"/", "..", "vic/../.."} {
+		c.VMFolder = arg
+		assert.Error(t, c.processFolder(), arg)
+	}
+}
 
-		for v, ipr := range iprs {
-			if ipr2, ok := te.iprs[v]; !ok {
-				t.Fatalf("parseContainerNetworkIPRanges(%s) => (%v, %s) want (%v, %s)", te.cps, iprs, err, te.iprs, te.err)
-			} else {
-				for _, i := range ipr {
-					found := false
-					for _, i2 := range ipr2 {
-						if i.Equal(i2) {
-							found = true
-							break
-						}
-					}
-
-					if !found {
-						t.Fatalf("parseContainerNetworkIPRanges(%s) => (%v, %s) want (%v, %s)", te.cps, iprs, err, te.iprs, te.err)
-					}
-				}
-			}
-		}
+func TestProcessContainerLog(t *testing.T) {
+	c := NewCreate()
+	assert.NoError(t, c.processContainerLog())
+	assert.Equal(t, "", c.ContainerLogConfig.Type)
+
+	c.containerLogDriver = "fluentd"
+	c.containerLogOpts = []string{"fluentd-address=10.0.0.1:24224", "tag=vch"}
+	if assert.NoError(t, c.processContainerLog()) {
+		assert.Equal(t, "fluentd", c.ContainerLogConfig.Type)
+		assert.Equal(t, "vch", c.ContainerLogConfig.Config["tag"])
+	}
+
+	for driver, opts := range map[string][]string{
+		"":        {"tag=vch"},
+		"syslog":  {"syslog-address=udp://10.0.0.1"},
+		"gelf":    {"gelf-address"},
+		"fluentd": nil,
+	} {
+		c.containerLogDriver = driver
+		c.containerLogOpts = opts
+		assert.Error(t, c.processContainerLog(), driver)
 	}
 }
 
-func TestParseContainerNetworkDNS(t *testing.T) {
-	var tests = []struct {
-		cds []string
-		dns map[string][]net.IP
-		err error
-	}{
-		{[]string{""}, nil, fmt.Errorf("")},
-		{[]string{"foo:"}, nil, fmt.Errorf("")},
-		{[]string{":"}, nil, fmt.Errorf("")},
-		{[]string{":10.10.10.10"}, nil, fmt.Errorf("")},
-		{[]string{":foo"}, nil, fmt.Errorf("")},
-		{[]string{"foo:10"}, nil, fmt.Errorf("")},
-		{[]string{"foo:10.10.10.109"}, map[string][]net.IP{"foo": {net.ParseIP("10.10.10.109")}}, nil},
-		{[]string{"foo:10.10.10.109", "foo:10.10.10.110", "bar:10.10.9.109", "bar:10.10.9.110"},
-			map[string][]net.IP{
-				"foo": {net.ParseIP("10.10.10.109"), net.ParseIP("10.10.10.110")},
-				"bar": {net.ParseIP("10.10.9.109"), net.ParseIP("10.10.9.110")},
-			},
-			nil,
-		},
+func TestProcessSerialConcentrator(t *testing.T) {
+	c := NewCreate()
+	for _, vspc := range []string{"", "appliance", "telnet://vspc.example.com:13370", "telnets://10.0.0.1:13371"} {
+		c.SerialConcentrator = vspc
+		assert.NoError(t, c.processSerialConcentrator(), vspc)
 	}
 
-	for _, te := range tests {
-		dns, err := parseContainerNetworkDNS(te.cds)
-		if te.err != nil {
-			if err == nil {
-				t.Fatalf("parseContainerNetworkDNS(%s) => (%v, nil) want (nil, err)", te.cds, dns)
-			}
+	for _, vspc := range []string{"vspc.example.com:13370", "tcp://10.0.0.1:13370", "telnet://", "Appliance"} {
+		c.SerialConcentrator = vspc
+		assert.Error(t, c.processSerialConcentrator(), vspc)
+	}
+}
 
-			continue
-		}
+func TestProcessClientIdentity(t *testing.T) {
+	c := NewCreate()
+	c.clientNetworkMAC = "00-50-56-3F-00-01"
+	c.clientNetworkClientID = "vch-01"
 
-		if err != te.err ||
-			len(dns) != len(te.dns) {
-			t.Fatalf("parseContainerNetworkDNS(%s) => (%v, %s) want (%v, %s)", te.cds, dns, err, te.dns, te.err)
-		}
+	if assert.NoError(t, c.processClientIdentity()) {
+		assert.Equal(t, "00:50:56:3f:00:01", c.Data.ClientNetwork.MAC)
+		assert.Equal(t, "vch-01", c.Data.ClientNetwork.DHCPClientID)
+	}
 
-		for v, d := range dns {
-			if d2, ok := te.dns[v]; !ok {
-				t.Fatalf("parseContainerNetworkDNS(%s) => (%v, %s) want (%v, %s)", te.cds, dns, err, te.dns, te.err)
-			} else {
-				for _, i := range d {
-					found := false
-					for _, i2 := range d2 {
-						if i.Equal(i2) {
-							found = true
-							break
-						}
-					}
-
-					if !found {
-						t.Fatalf("parseContainerNetworkDNS(%s) => (%v, %s) want (%v, %s)", te.cds, dns, err, te.dns, te.err)
-					}
-				}
-			}
-		}
+	for _, mac := range []string{"not-a-mac", "00:50:56:40:00:01", "00:0c:29:00:00:01", "00:50:56:00:00:00:00:01"} {
+		c.clientNetworkMAC = mac
+		assert.Error(t, c.processClientIdentity(), mac)
 	}
 }
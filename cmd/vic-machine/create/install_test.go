@@ -17,6 +17,7 @@ package create
 import (
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"testing"
 
@@ -57,3 +58,17 @@ func TestGenKey(t *testing.T) {
 	assert.NotEmpty(t, kp.CertPEM, "Expected certificate to contain data")
 	assert.NotEmpty(t, kp.CertPEM, "Expected key to contain data")
 }
+
+func TestCertificateNames(t *testing.T) {
+	c := NewCreate()
+	c.Data.ClientNetwork.IP = net.IPNet{IP: net.ParseIP("10.0.0.2"), Mask: net.CIDRMask(24, 32)}
+	c.serverNames = []string{"vch.example.com", "*.example.com", "fd00::2"}
+
+	names, err := c.certificateNames()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2", "vch.example.com", "*.example.com", "fd00::2"}, names)
+
+	c.serverNames = []string{"vch.*.example.com"}
+	_, err = c.certificateNames()
+	assert.Error(t, err, "Expected wildcard outside the leftmost label to be rejected")
+}
@@ -21,6 +21,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/urfave/cli"
+	"github.com/vmware/vic/cmd/vic-machine/common"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/lib/install/management"
 	"github.com/vmware/vic/lib/install/validate"
@@ -41,6 +42,7 @@ type Debug struct {
 	enableSSH     bool
 	password      string
 	authorizedKey string
+	rollback      bool
 }
 
 func NewDebug() *Debug {
@@ -72,6 +74,11 @@ func (d *Debug) Flags() []cli.Flag {
 			Usage:       "Password to set for root user (non-persistent over reboots)",
 			Destination: &d.password,
 		},
+		cli.BoolFlag{
+			Name:        "rollback",
+			Usage:       "Disable SSH within appliance VM and remove the authorized key",
+			Destination: &d.rollback,
+		},
 	}
 
 	util := []cli.Flag{
@@ -107,10 +114,9 @@ func (d *Debug) processParams() error {
 	return nil
 }
 
-func (d *Debug) Run(cli *cli.Context) error {
-	var err error
+func (d *Debug) Run(cli *cli.Context) (err error) {
 	if err = d.processParams(); err != nil {
-		return err
+		return common.Exit(err, common.ExitValidationFailure)
 	}
 
 	if d.Debug.Debug > 0 {
@@ -120,18 +126,24 @@ func (d *Debug) Run(cli *cli.Context) error {
 
 	if len(cli.Args()) > 0 {
 		log.Errorf("Unknown argument: %s", cli.Args()[0])
-		return errors.New("invalid CLI arguments")
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
 	}
 
 	log.Infof("### Configuring VCH for debug ####")
 
 	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout)
 	defer cancel()
+	defer func() {
+		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
+			//context deadline exceeded, replace returned error message
+			err = common.Exit(errors.Errorf("Debug timed out: use --timeout to add more time"), common.ExitTimeout)
+		}
+	}()
 
 	validator, err := validate.NewValidator(ctx, d.Data)
 	if err != nil {
 		log.Errorf("Debug cannot continue - failed to create validator: %s", err)
-		return errors.New("Debug failed")
+		return common.Exit(errors.New("Debug failed"), common.TargetExitCode(err))
 	}
 	executor := management.NewDispatcher(validator.Context, validator.Session, nil, d.Force)
 
@@ -164,6 +176,17 @@ func (d *Debug) Run(cli *cli.Context) error {
 	log.Infof("Installer version: %s", installerVer.ShortVersion())
 	log.Infof("VCH version: %s", vchConfig.Version.ShortVersion())
 
+	if d.rollback {
+		if err = executor.DisableDebugVCH(vch, vchConfig); err != nil {
+			executor.CollectDiagnosticLogs()
+			log.Errorf("%s", err)
+			return errors.New("Debug rollback failed")
+		}
+
+		log.Infof("Completed successfully")
+		return nil
+	}
+
 	// load the key file if set
 	var key []byte
 	if d.authorizedKey != "" {
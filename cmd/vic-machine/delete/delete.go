@@ -15,12 +15,14 @@
 package delete
 
 import (
+	"fmt"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/urfave/cli"
 
+	"github.com/vmware/vic/cmd/vic-machine/common"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/lib/install/management"
 	"github.com/vmware/vic/lib/install/validate"
@@ -35,6 +37,9 @@ import (
 type Uninstall struct {
 	*data.Data
 
+	volumeAction   string
+	forceProtected bool
+
 	executor *management.Dispatcher
 }
 
@@ -52,12 +57,23 @@ func (d *Uninstall) Flags() []cli.Flag {
 			Usage:       "Force the deletion",
 			Destination: &d.Force,
 		},
+		cli.BoolFlag{
+			Name:        "force-protected",
+			Usage:       "Delete the VCH even if it is protected from deletion",
+			Destination: &d.forceProtected,
+		},
 		cli.DurationFlag{
 			Name:        "timeout",
 			Value:       3 * time.Minute,
 			Usage:       "Time to wait for delete",
 			Destination: &d.Timeout,
 		},
+		cli.StringFlag{
+			Name:        "volume-action",
+			Value:       "",
+			Usage:       "What to do with the volume stores: \"preserve\" them for a new VCH, or \"delete\" them. Removed only with --force if not given",
+			Destination: &d.volumeAction,
+		},
 	}
 
 	target := d.TargetFlags()
@@ -81,12 +97,18 @@ func (d *Uninstall) processParams() error {
 		return err
 	}
 
+	switch management.VolumeAction(d.volumeAction) {
+	case management.VolumesDefault, management.VolumesPreserve, management.VolumesDelete:
+	default:
+		return cli.NewExitError(fmt.Sprintf("--volume-action must be %q or %q", management.VolumesPreserve, management.VolumesDelete), 1)
+	}
+
 	return nil
 }
 
 func (d *Uninstall) Run(cli *cli.Context) (err error) {
 	if err = d.processParams(); err != nil {
-		return err
+		return common.Exit(err, common.ExitValidationFailure)
 	}
 
 	if d.Debug.Debug > 0 {
@@ -96,7 +118,7 @@ func (d *Uninstall) Run(cli *cli.Context) (err error) {
 
 	if len(cli.Args()) > 0 {
 		log.Errorf("Unknown argument: %s", cli.Args()[0])
-		return errors.New("invalid CLI arguments")
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
 	}
 
 	log.Infof("### Removing VCH ####")
@@ -106,14 +128,14 @@ func (d *Uninstall) Run(cli *cli.Context) (err error) {
 	defer func() {
 		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
 			//context deadline exceeded, replace returned error message
-			err = errors.Errorf("Delete timed out: use --timeout to add more time")
+			err = common.Exit(errors.Errorf("Delete timed out: use --timeout to add more time"), common.ExitTimeout)
 		}
 	}()
 
 	validator, err := validate.NewValidator(ctx, d.Data)
 	if err != nil {
 		log.Errorf("Delete cannot continue - failed to create validator: %s", err)
-		return errors.New("delete failed")
+		return common.Exit(errors.New("delete failed"), common.TargetExitCode(err))
 	}
 	executor := management.NewDispatcher(validator.Context, validator.Session, nil, d.Force)
 
@@ -140,10 +162,16 @@ func (d *Uninstall) Run(cli *cli.Context) (err error) {
 	}
 	executor.InitDiagnosticLogs(vchConfig)
 
-	if err = executor.DeleteVCH(vchConfig); err != nil {
+	if vchConfig.Protected && !d.forceProtected {
+		log.Errorf("VCH %s is protected from deletion: specify --force-protected to delete it, or remove the protection with vic-machine configure --unprotect", vchConfig.Name)
+		return common.Exit(errors.New("delete failed"), common.ExitConflict)
+	}
+
+	if err = executor.DeleteVCH(vchConfig, management.VolumeAction(d.volumeAction)); err != nil {
 		executor.CollectDiagnosticLogs()
 		log.Errorf("%s", err)
-		return errors.New("delete failed")
+		// components may have been removed before the failure
+		return common.Exit(errors.New("delete failed"), common.ExitPartialFailure)
 	}
 
 	log.Infof("Completed successfully")
@@ -15,12 +15,17 @@
 package inspect
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/urfave/cli"
 
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/lib/install/management"
 	"github.com/vmware/vic/lib/install/validate"
@@ -32,10 +37,21 @@ import (
 	"golang.org/x/net/context"
 )
 
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
 // Inspect has all input parameters for vic-machine inspect command
 type Inspect struct {
 	*data.Data
 
+	// Output selects the format of the report - text or json
+	Output string
+
+	// Health limits the report to the health of the appliance components
+	Health bool
+
 	executor *management.Dispatcher
 }
 
@@ -54,6 +70,17 @@ func (i *Inspect) Flags() []cli.Flag {
 			Usage:       "Time to wait for upgrade",
 			Destination: &i.Timeout,
 		},
+		cli.StringFlag{
+			Name:        "output",
+			Value:       outputText,
+			Usage:       "Format of the report: text, or json for consumption by automation",
+			Destination: &i.Output,
+		},
+		cli.BoolFlag{
+			Name:        "health",
+			Usage:       "Report only the health of the appliance components, failing if any are degraded",
+			Destination: &i.Health,
+		},
 	}
 
 	target := i.TargetFlags()
@@ -77,34 +104,48 @@ func (i *Inspect) processParams() error {
 		return err
 	}
 
+	switch i.Output {
+	case outputText, outputJSON:
+	default:
+		return cli.NewExitError(fmt.Sprintf("--output must be %q or %q", outputText, outputJSON), 1)
+	}
+
 	return nil
 }
 
-func (i *Inspect) Run(cli *cli.Context) error {
-	var err error
+func (i *Inspect) Run(cli *cli.Context) (err error) {
 	if err = i.processParams(); err != nil {
-		return err
+		return common.Exit(err, common.ExitValidationFailure)
 	}
 
 	if i.Debug.Debug > 0 {
 		log.SetLevel(log.DebugLevel)
 		trace.Logger.Level = log.DebugLevel
+	} else if i.Output == outputJSON {
+		// progress messages share the output stream with the report
+		log.SetLevel(log.ErrorLevel)
 	}
 
 	if len(cli.Args()) > 0 {
 		log.Errorf("Unknown argument: %s", cli.Args()[0])
-		return errors.New("invalid CLI arguments")
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
 	}
 
 	log.Infof("### Inspecting VCH ####")
 
 	ctx, cancel := context.WithTimeout(context.Background(), i.Timeout)
 	defer cancel()
+	defer func() {
+		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
+			//context deadline exceeded, replace returned error message
+			err = common.Exit(errors.Errorf("Inspect timed out: use --timeout to add more time"), common.ExitTimeout)
+		}
+	}()
 
 	validator, err := validate.NewValidator(ctx, i.Data)
 	if err != nil {
 		log.Errorf("Inspect cannot continue - failed to create validator: %s", err)
-		return errors.New("inspect failed")
+		return common.Exit(errors.New("inspect failed"), common.TargetExitCode(err))
 	}
 	executor := management.NewDispatcher(validator.Context, validator.Session, nil, i.Force)
 
@@ -132,13 +173,45 @@ func (i *Inspect) Run(cli *cli.Context) error {
 	executor.InitDiagnosticLogs(vchConfig)
 
 	installerVer := version.GetBuild()
+	if i.Health {
+		return i.health(cli, executor, vch, vchConfig)
+	}
+
+	upgradeStatus, upgradeErr := i.upgradeStatus(ctx, vch, installerVer, vchConfig.Version)
+
+	if i.Output == outputJSON {
+		report, err := executor.InspectionReport(vch, vchConfig)
+		if err != nil {
+			executor.CollectDiagnosticLogs()
+			log.Errorf("%s", err)
+			return errors.New("inspect failed")
+		}
+
+		report.InstallerVersion = installerVer.ShortVersion()
+		report.UpgradeStatus = strings.Join(upgradeStatus, ". ")
+		if upgradeErr != nil {
+			report.UpgradeStatus = upgradeErr.Error()
+		}
+
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.App.Writer, "%s\n", out)
+		return nil
+	}
 
 	log.Info("")
 	log.Infof("Installer version: %s", installerVer.ShortVersion())
 	log.Infof("VCH version: %s", vchConfig.Version.ShortVersion())
 	log.Info("")
 	log.Info("VCH upgrade status:")
-	i.upgradeStatusMessage(ctx, vch, installerVer, vchConfig.Version)
+	if upgradeErr != nil {
+		log.Error(upgradeErr)
+	}
+	for _, msg := range upgradeStatus {
+		log.Info(msg)
+	}
 
 	if err = executor.InspectVCH(vch, vchConfig); err != nil {
 		executor.CollectDiagnosticLogs()
@@ -151,46 +224,69 @@ func (i *Inspect) Run(cli *cli.Context) error {
 	return nil
 }
 
-// upgradeStatusMessage generates a user facing status string about upgrade progress and status
-func (i *Inspect) upgradeStatusMessage(ctx context.Context, vch *vm.VirtualMachine, installerVer *version.Build, vchVer *version.Build) {
+// health reports the health of the appliance components, failing if any are degraded
+func (i *Inspect) health(cli *cli.Context, executor *management.Dispatcher, vch *vm.VirtualMachine, vchConfig *config.VirtualContainerHostConfigSpec) error {
+	components, err := executor.ComponentHealth(vch, vchConfig)
+	if err != nil {
+		executor.CollectDiagnosticLogs()
+		log.Errorf("%s", err)
+		return errors.New("inspect failed")
+	}
+
+	if i.Output == outputJSON {
+		out, err := json.MarshalIndent(components, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cli.App.Writer, "%s\n", out)
+
+		for _, c := range components {
+			if c.Health == management.HealthDegraded {
+				return errors.New("VCH is degraded")
+			}
+		}
+		return nil
+	}
+
+	if err = management.ShowHealth(components); err != nil {
+		log.Errorf("%s", err)
+		return errors.New("VCH is degraded")
+	}
+
+	log.Infof("Completed successfully")
+	return nil
+}
+
+// upgradeStatus generates user facing status messages about upgrade progress and status
+func (i *Inspect) upgradeStatus(ctx context.Context, vch *vm.VirtualMachine, installerVer *version.Build, vchVer *version.Build) ([]string, error) {
 	if sameVer := installerVer.Equal(vchVer); sameVer {
-		log.Info("Installer has same version as VCH")
-		log.Info("No upgrade available with this installer version")
-		return
+		return []string{"Installer has same version as VCH", "No upgrade available with this installer version"}, nil
 	}
 
 	upgrading, _, err := vch.UpgradeInProgress(ctx, management.UpgradePrefix)
 	if err != nil {
-		log.Errorf("Unable to determine if upgrade is in progress: %s", err)
-		return
+		return nil, fmt.Errorf("Unable to determine if upgrade is in progress: %s", err)
 	}
 	if upgrading {
-		log.Info("Upgrade in progress")
-		return
+		return []string{"Upgrade in progress"}, nil
 	}
 
 	canUpgrade, err := installerVer.IsNewer(vchVer)
 	if err != nil {
-		log.Errorf("Unable to determine if upgrade is availabile: %s", err)
-		return
+		return nil, fmt.Errorf("Unable to determine if upgrade is availabile: %s", err)
 	}
 	if canUpgrade {
-		log.Info("Upgrade available")
-		return
+		return []string{"Upgrade available"}, nil
 	}
 
 	oldInstaller, err := installerVer.IsOlder(vchVer)
 	if err != nil {
-		log.Errorf("Unable to determine if upgrade is available: %s", err)
-		return
+		return nil, fmt.Errorf("Unable to determine if upgrade is available: %s", err)
 	}
 	if oldInstaller {
-		log.Info("Installer has older version than VCH")
-		log.Info("No upgrade available with this installer version")
-		return
+		return []string{"Installer has older version than VCH", "No upgrade available with this installer version"}, nil
 	}
 
 	// can't get here
-	log.Warn("Invalid upgrade status")
-	return
+	return []string{"Invalid upgrade status"}, nil
 }
@@ -15,8 +15,6 @@
 package list
 
 import (
-	"fmt"
-	"path"
 	"text/tabwriter"
 	"text/template"
 	"time"
@@ -25,13 +23,13 @@ import (
 
 	"github.com/urfave/cli"
 
+	"github.com/vmware/vic/cmd/vic-machine/common"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/lib/install/management"
 	"github.com/vmware/vic/lib/install/validate"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/version"
-	"github.com/vmware/vic/pkg/vsphere/vm"
 
 	"golang.org/x/net/context"
 )
@@ -41,12 +39,15 @@ type items struct {
 	Path          string
 	Name          string
 	Version       string
+	Address       string
+	DockerHost    string
 	UpgradeStatus string
+	ReleaseStatus string
 }
 
 // templ is parsed by text/template package
 const templ = `{{range .}}
-{{.ID}}	{{.Path}}	{{.Name}}	{{.Version}}	{{.UpgradeStatus}}{{end}}
+{{.ID}}	{{.Path}}	{{.Name}}	{{.Version}}	{{.Address}}	{{.DockerHost}}	{{.UpgradeStatus}}	{{.ReleaseStatus}}{{end}}
 `
 
 // List has all input parameters for vic-machine ls command
@@ -98,28 +99,13 @@ func (l *List) processParams() error {
 	return nil
 }
 
-func (l *List) prettyPrint(cli *cli.Context, ctx context.Context, vchs []*vm.VirtualMachine, executor *management.Dispatcher) {
+func (l *List) prettyPrint(cli *cli.Context, vchs []management.VCHListing) {
 	data := []items{
-		{"ID", "PATH", "NAME", "VERSION", "UPGRADE STATUS"},
+		{"ID", "PATH", "NAME", "VERSION", "ADDRESS", "DOCKER HOST", "UPGRADE STATUS", "RELEASE STATUS"},
 	}
-	installerVer := version.GetBuild()
 	for _, vch := range vchs {
-
-		vchConfig, err := executor.GetVCHConfig(vch)
-		var version string
-		if err != nil {
-			log.Error("Failed to get Virtual Container Host configuration")
-			log.Error(err)
-			version = "unknown"
-		} else {
-			version = vchConfig.Version.ShortVersion()
-		}
-
-		parentPath := path.Dir(path.Dir(vch.InventoryPath))
-		name := path.Base(vch.InventoryPath)
-		upgradeStatus := l.upgradeStatusMessage(ctx, vch, installerVer, vchConfig.Version)
 		data = append(data,
-			items{vch.Reference().Value, parentPath, name, version, upgradeStatus})
+			items{vch.ID, vch.Path, vch.Name, vch.Version, vch.Address, vch.DockerHost, vch.UpgradeStatus, vch.ReleaseStatus})
 	}
 	t := template.New("vic-machine ls")
 	t, _ = t.Parse(templ)
@@ -132,7 +118,7 @@ func (l *List) prettyPrint(cli *cli.Context, ctx context.Context, vchs []*vm.Vir
 
 func (l *List) Run(cli *cli.Context) (err error) {
 	if err = l.processParams(); err != nil {
-		return err
+		return common.Exit(err, common.ExitValidationFailure)
 	}
 
 	if l.Debug.Debug > 0 {
@@ -142,7 +128,7 @@ func (l *List) Run(cli *cli.Context) (err error) {
 
 	if len(cli.Args()) > 0 {
 		log.Errorf("Unknown argument: %s", cli.Args()[0])
-		return errors.New("invalid CLI arguments")
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
 	}
 
 	log.Infof("### Listing VCHs ####")
@@ -152,7 +138,7 @@ func (l *List) Run(cli *cli.Context) (err error) {
 	defer func() {
 		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
 			//context deadline exceeded, replace returned error message
-			err = errors.Errorf("List timed out: use --timeout to add more time")
+			err = common.Exit(errors.Errorf("List timed out: use --timeout to add more time"), common.ExitTimeout)
 		}
 	}()
 
@@ -164,7 +150,7 @@ func (l *List) Run(cli *cli.Context) (err error) {
 	}
 	if err != nil {
 		log.Errorf("List cannot continue - failed to create validator: %s", err)
-		return errors.New("list failed")
+		return common.Exit(errors.New("list failed"), common.TargetExitCode(err))
 	}
 
 	_, err = validator.ValidateTarget(ctx, l.Data)
@@ -178,44 +164,10 @@ func (l *List) Run(cli *cli.Context) (err error) {
 		return err
 	}
 	executor := management.NewDispatcher(validator.Context, validator.Session, nil, false)
-	vchs, err := executor.SearchVCHs(validator.ResourcePoolPath)
+	vchs, err := executor.ListVCHs(validator.ResourcePoolPath, version.GetBuild())
 	if err != nil {
 		log.Errorf("List cannot continue - failed to search VCHs in %s: %s", validator.ResourcePoolPath, err)
 	}
-	l.prettyPrint(cli, ctx, vchs, executor)
+	l.prettyPrint(cli, vchs)
 	return nil
 }
-
-// upgradeStatusMessage generates a user facing status string about upgrade progress and status
-func (l *List) upgradeStatusMessage(ctx context.Context, vch *vm.VirtualMachine, installerVer *version.Build, vchVer *version.Build) string {
-	if sameVer := installerVer.Equal(vchVer); sameVer {
-		return "Up to date"
-	}
-
-	upgrading, _, err := vch.UpgradeInProgress(ctx, management.UpgradePrefix)
-	if err != nil {
-		return fmt.Sprintf("Unknown: %s", err)
-	}
-	if upgrading {
-		return "Upgrade in progress"
-	}
-
-	canUpgrade, err := installerVer.IsNewer(vchVer)
-	if err != nil {
-		return fmt.Sprintf("Unknown: %s", err)
-	}
-	if canUpgrade {
-		return fmt.Sprintf("Upgradeable to %s", installerVer.ShortVersion())
-	}
-
-	oldInstaller, err := installerVer.IsOlder(vchVer)
-	if err != nil {
-		return fmt.Sprintf("Unknown: %s", err)
-	}
-	if oldInstaller {
-		return fmt.Sprintf("VCH has newer version")
-	}
-
-	// can't get here
-	return "Invalid upgrade status"
-}
@@ -24,12 +24,16 @@ import (
 
 	"github.com/urfave/cli"
 
+	"github.com/vmware/vic/cmd/vic-machine/configure"
 	"github.com/vmware/vic/cmd/vic-machine/create"
 	"github.com/vmware/vic/cmd/vic-machine/debug"
 	uninstall "github.com/vmware/vic/cmd/vic-machine/delete"
 	"github.com/vmware/vic/cmd/vic-machine/inspect"
 	"github.com/vmware/vic/cmd/vic-machine/list"
+	"github.com/vmware/vic/cmd/vic-machine/server"
+	"github.com/vmware/vic/cmd/vic-machine/update"
 	"github.com/vmware/vic/cmd/vic-machine/upgrade"
+	"github.com/vmware/vic/cmd/vic-machine/verify"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/version"
 )
@@ -51,6 +55,10 @@ func main() {
 	list := list.NewList()
 	upgrade := upgrade.NewUpgrade()
 	debug := debug.NewDebug()
+	configure := configure.NewConfigure()
+	server := server.NewServer()
+	verify := verify.NewVerify()
+	update := update.NewUpdate()
 	app.Commands = []cli.Command{
 		{
 			Name:   "create",
@@ -82,6 +90,30 @@ func main() {
 			Action: upgrade.Run,
 			Flags:  upgrade.Flags(),
 		},
+		{
+			Name:  "update",
+			Usage: "Update components of a VCH without upgrading it",
+			Subcommands: []cli.Command{
+				{
+					Name:   "iso",
+					Usage:  "Replace the bootstrap ISO used by containerVMs",
+					Action: update.RunISO,
+					Flags:  update.ISOFlags(),
+				},
+			},
+		},
+		{
+			Name:   "configure",
+			Usage:  "Change the configuration of a VCH",
+			Action: configure.Run,
+			Flags:  configure.Flags(),
+		},
+		{
+			Name:   "server",
+			Usage:  "Serve a REST API to manage VCHs",
+			Action: server.Run,
+			Flags:  server.Flags(),
+		},
 		{
 			Name:   "version",
 			Usage:  "Show VIC version information",
@@ -93,6 +125,12 @@ func main() {
 			Action: debug.Run,
 			Flags:  debug.Flags(),
 		},
+		{
+			Name:   "verify-images",
+			Usage:  "Verify the image layers of a VCH, and quarantine or repair damaged ones",
+			Action: verify.Run,
+			Flags:  verify.Flags(),
+		},
 	}
 
 	app.Version = version.GetBuild().ShortVersion()
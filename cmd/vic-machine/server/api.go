@@ -0,0 +1,279 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/gorilla/mux"
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/lib/client"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/version"
+)
+
+// PathPrefix is the path of version 1 of the vic-machine server API
+const PathPrefix = "/v1"
+
+// command is a vic-machine command the server can run as a job
+type command interface {
+	Flags() []cli.Flag
+	Run(*cli.Context) error
+}
+
+// operation is an operation on VCHs the server runs as a job
+type operation struct {
+	command func() command
+	// byID is set for operations on an existing VCH, identified by the request path
+	byID bool
+}
+
+// reserved are the options requests cannot set. The target is that of the server and the VCH
+// that of the request path, while the rest concern the server process rather than a VCH, or name
+// files that would be read from the server rather than the client.
+var reserved = []string{
+	"target", "user", "password", "thumbprint", "id", "yes", "non-interactive",
+	"debug", "batch", "batch-workers", "extended-help",
+	"cert", "key", "tls-ca", "registry-ca", "admin-ldap-ca", "saml-token-file",
+	"appliance-iso", "bootstrap-iso", "appliance-ova",
+}
+
+// vchClient is how the server queries VCHs outside of jobs
+type vchClient interface {
+	List() ([]client.VCH, error)
+	Inspect(id string) (*management.Inspection, error)
+	VolumeStores(id string) ([]management.VolumeStore, error)
+	Close() error
+}
+
+// api serves the vic-machine server API
+type api struct {
+	token string
+
+	// target holds the vSphere target options given to every operation
+	target     common.Options
+	operations map[string]operation
+
+	// connect returns a client for the target, searching for VCHs in the compute resource
+	connect func(computePath string) (vchClient, error)
+
+	jobs *jobQueue
+}
+
+func newAPI(token string, target common.Options, operations map[string]operation, connect func(string) (vchClient, error)) *api {
+	return &api{
+		token:      token,
+		target:     target,
+		operations: operations,
+		connect:    connect,
+		jobs:       newJobQueue(),
+	}
+}
+
+// handler returns the handler of the API, requiring the token of the server on every request
+func (a *api) handler() http.Handler {
+	m := mux.NewRouter()
+	r := m.PathPrefix(PathPrefix).Subrouter()
+
+	r.HandleFunc("/version", a.getVersion).Methods("GET")
+
+	r.HandleFunc("/vchs", a.listVCHs).Methods("GET")
+	r.HandleFunc("/vchs", a.operate("create")).Methods("POST")
+	r.HandleFunc("/vchs/{id}", a.inspectVCH).Methods("GET")
+	r.HandleFunc("/vchs/{id}/volume-stores", a.volumeStores).Methods("GET")
+	r.HandleFunc("/vchs/{id}", a.operate("delete")).Methods("DELETE")
+	r.HandleFunc("/vchs/{id}/configure", a.operate("configure")).Methods("POST")
+	r.HandleFunc("/vchs/{id}/upgrade", a.operate("upgrade")).Methods("POST")
+	r.HandleFunc("/vchs/{id}/verify-images", a.operate("verify-images")).Methods("POST")
+
+	r.HandleFunc("/jobs", a.listJobs).Methods("GET")
+	r.HandleFunc("/jobs/{id}", a.getJob).Methods("GET")
+
+	return a.authenticate(m)
+}
+
+func (a *api) authenticate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(a.token)) != 1 {
+			log.Warnf("Rejected unauthenticated %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="vic-machine"`)
+			writeError(w, http.StatusUnauthorized, errors.New("A valid token is required"))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (a *api) getVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version.GetBuild())
+}
+
+// listVCHs lists the VCHs in the compute resource given as the compute-resource query
+// parameter, or on the whole target if it is not given. Queries connect on their own and so run
+// alongside jobs.
+func (a *api) listVCHs(w http.ResponseWriter, r *http.Request) {
+	c, err := a.connect(r.URL.Query().Get("compute-resource"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	defer c.Close()
+
+	vchs, err := c.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, vchs)
+}
+
+func (a *api) inspectVCH(w http.ResponseWriter, r *http.Request) {
+	c, err := a.connect("")
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	defer c.Close()
+
+	report, err := c.Inspect(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (a *api) volumeStores(w http.ResponseWriter, r *http.Request) {
+	c, err := a.connect("")
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	defer c.Close()
+
+	stores, err := c.VolumeStores(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stores)
+}
+
+// operate returns the handler queueing the operation as a job. The body of the request is a
+// JSON object of the options of the vic-machine command of the operation, as given on the
+// command line, and may be empty for operations on an existing VCH.
+func (a *api) operate(name string) http.HandlerFunc {
+	op := a.operations[name]
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string]interface{})
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&body); err != nil && (err != io.EOF || !op.byID) {
+			writeError(w, http.StatusBadRequest, errors.Errorf("Request body must be a JSON object of %s options: %s", name, err))
+			return
+		}
+
+		cmd := op.command()
+		flags := cmd.Flags()
+
+		opts, err := common.Options(body).Resolve(flags)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		for _, name := range reserved {
+			if _, ok := opts[name]; ok {
+				writeError(w, http.StatusBadRequest, errors.Errorf("Option %q cannot be set by requests", name))
+				return
+			}
+		}
+
+		for k, v := range a.target {
+			opts[k] = v
+		}
+		// requests cannot answer prompts, the request itself is the confirmation
+		for _, f := range flags {
+			switch f.GetName() {
+			case "yes", "non-interactive":
+				opts[f.GetName()] = true
+			}
+		}
+		var vch string
+		if op.byID {
+			vch = mux.Vars(r)["id"]
+			opts["id"] = vch
+		} else if n, ok := opts["name"]; ok {
+			vch = fmt.Sprint(n)
+		}
+
+		set, err := opts.FlagSet(name, flags)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		job, err := a.jobs.add(name, vch, func() error {
+			return cmd.Run(cli.NewContext(nil, set, nil))
+		})
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+
+		w.Header().Set("Location", PathPrefix+"/jobs/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+	}
+}
+
+func (a *api) listJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.jobs.list())
+}
+
+func (a *api) getJob(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	job, ok := a.jobs.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.Errorf("No such job: %s", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Failed to write response: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"message": err.Error()})
+}
@@ -0,0 +1,269 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/lib/client"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/pkg/errors"
+)
+
+const testToken = "secret"
+
+// testCommand records the options it is run with
+type testCommand struct {
+	common.Target
+	common.Debug
+	common.VCHID
+
+	name string
+	fail bool
+
+	ran chan *testCommand
+}
+
+func (c *testCommand) Flags() []cli.Flag {
+	flags := append(c.TargetFlags(), c.IDFlags()...)
+	flags = append(flags, c.DebugFlags()...)
+	return append(flags,
+		cli.StringFlag{Name: "name, n", Destination: &c.name},
+		cli.BoolFlag{Name: "fail", Destination: &c.fail},
+		cli.StringFlag{Name: "tls-ca, ca"},
+	)
+}
+
+func (c *testCommand) Run(*cli.Context) error {
+	log.Infof("Running for %s", c.name)
+	c.ran <- c
+	if c.fail {
+		return common.Exit(errors.New("failed as asked"), common.ExitConflict)
+	}
+	return nil
+}
+
+type testClient struct{}
+
+func (testClient) List() ([]client.VCH, error) {
+	return []client.VCH{{ID: "vm-1", Name: "vch1"}}, nil
+}
+
+func (testClient) Inspect(id string) (*management.Inspection, error) {
+	if id != "vm-1" {
+		return nil, errors.Errorf("no VCH %s", id)
+	}
+	return &management.Inspection{ID: id, Name: "vch1"}, nil
+}
+
+func (testClient) VolumeStores(id string) ([]management.VolumeStore, error) {
+	if id != "vm-1" {
+		return nil, errors.Errorf("no VCH %s", id)
+	}
+	return []management.VolumeStore{{Name: "default", Datastore: "datastore1", Volumes: 2}}, nil
+}
+
+func (testClient) Close() error {
+	return nil
+}
+
+func testServer(t *testing.T) (*httptest.Server, chan *testCommand) {
+	ran := make(chan *testCommand, 1)
+	newCommand := func() command { return &testCommand{ran: ran} }
+
+	a := newAPI(testToken,
+		common.Options{"target": "10.0.0.1/dc1", "user": "root", "password": "pass"},
+		map[string]operation{
+			"create": {command: newCommand},
+			"delete": {command: newCommand, byID: true},
+		},
+		func(string) (vchClient, error) { return testClient{}, nil },
+	)
+	return httptest.NewServer(a.handler()), ran
+}
+
+func request(t *testing.T, s *httptest.Server, method, path, body string, out interface{}) *http.Response {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, s.URL+PathPrefix+path, r)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+// waitJob waits for the job to finish and returns its final state
+func waitJob(t *testing.T, s *httptest.Server, id string) Job {
+	for i := 0; i < 100; i++ {
+		var job Job
+		resp := request(t, s, "GET", "/jobs/"+id, "", &job)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		if job.Finished != nil {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestAuthentication(t *testing.T) {
+	s, _ := testServer(t)
+	defer s.Close()
+
+	for _, auth := range []string{"", "Bearer wrong", testToken} {
+		req, err := http.NewRequest("GET", s.URL+PathPrefix+"/version", nil)
+		require.NoError(t, err)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "authorization %q", auth)
+	}
+
+	resp := request(t, s, "GET", "/version", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCreateJob(t *testing.T) {
+	s, ran := testServer(t)
+	defer s.Close()
+
+	var job Job
+	resp := request(t, s, "POST", "/vchs", `{"n": "vch1"}`, &job)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, PathPrefix+"/jobs/"+job.ID, resp.Header.Get("Location"))
+	assert.Equal(t, "create", job.Operation)
+	assert.Equal(t, "vch1", job.VCH)
+
+	cmd := <-ran
+	assert.Equal(t, "vch1", cmd.name)
+	assert.Equal(t, "10.0.0.1/dc1", cmd.URL.Host+cmd.URL.Path)
+	assert.Equal(t, "root", cmd.User)
+	assert.Equal(t, "pass", *cmd.Password)
+
+	job = waitJob(t, s, job.ID)
+	assert.Equal(t, JobSucceeded, job.State)
+	assert.Contains(t, job.Log, "Running for vch1")
+
+	var jobs []Job
+	request(t, s, "GET", "/jobs", "", &jobs)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, job.ID, jobs[0].ID)
+	assert.Empty(t, jobs[0].Log, "logs are only reported for a single job")
+}
+
+func TestDeleteJob(t *testing.T) {
+	s, ran := testServer(t)
+	defer s.Close()
+
+	var job Job
+	resp := request(t, s, "DELETE", "/vchs/vm-1", `{"fail": true}`, &job)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "vm-1", job.VCH)
+
+	cmd := <-ran
+	assert.Equal(t, "vm-1", cmd.ID)
+	assert.True(t, cmd.NonInteractive, "requests cannot answer prompts")
+
+	job = waitJob(t, s, job.ID)
+	assert.Equal(t, JobFailed, job.State)
+	assert.Equal(t, "failed as asked", job.Error)
+	assert.Equal(t, common.ExitConflict, job.ExitCode)
+
+	// the body may be left out for operations on an existing VCH
+	resp = request(t, s, "DELETE", "/vchs/vm-1", "", &job)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	<-ran
+	waitJob(t, s, job.ID)
+}
+
+func TestInvalidOperation(t *testing.T) {
+	s, _ := testServer(t)
+	defer s.Close()
+
+	for _, body := range []string{
+		"",
+		"[]",
+		`{"no-such-option": 1}`,
+		`{"name": "vch1", "target": "10.0.0.2"}`,
+		`{"name": "vch1", "v": 1}`,
+		`{"name": "vch1", "fail": "maybe"}`,
+		`{"name": "vch1", "ca": "/etc/shadow"}`,
+	} {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		resp := request(t, s, "POST", "/vchs", body, &msg)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "body %q", body)
+		assert.NotEmpty(t, msg.Message)
+	}
+
+	resp := request(t, s, "GET", "/jobs/nonexistent", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestQueryVCHs(t *testing.T) {
+	s, _ := testServer(t)
+	defer s.Close()
+
+	var vchs []client.VCH
+	resp := request(t, s, "GET", "/vchs", "", &vchs)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, vchs, 1)
+	assert.Equal(t, "vm-1", vchs[0].ID)
+
+	var report management.Inspection
+	resp = request(t, s, "GET", "/vchs/vm-1", "", &report)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "vch1", report.Name)
+
+	resp = request(t, s, "GET", "/vchs/vm-2", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	var stores []management.VolumeStore
+	resp = request(t, s, "GET", "/vchs/vm-1/volume-stores", "", &stores)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, stores, 1)
+	assert.Equal(t, 2, stores[0].Volumes)
+
+	resp = request(t, s, "GET", "/vchs/vm-2/volume-stores", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
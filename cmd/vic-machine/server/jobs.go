@@ -0,0 +1,225 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/uid"
+)
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+const (
+	// maxQueuedJobs is the number of jobs that can wait to run before new ones are refused
+	maxQueuedJobs = 64
+	// maxFinishedJobs is the number of finished jobs kept, the oldest being dropped first
+	maxFinishedJobs = 256
+)
+
+var errQueueFull = errors.New("Too many jobs are queued, try again later")
+
+// Job is the state of an operation on a VCH run by the server
+type Job struct {
+	ID        string `json:"id"`
+	Operation string `json:"operation"`
+	// VCH is the ID of the VCH operated on, or the name of the VCH to create
+	VCH   string `json:"vch"`
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+	// ExitCode is the vic-machine exit code of a failed job
+	ExitCode int `json:"exit_code,omitempty"`
+
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	// Log is the output of the operation, only reported for a single job
+	Log string `json:"log,omitempty"`
+}
+
+// job is a queued or running operation, or the record of a finished one
+type job struct {
+	status Job
+	run    func() error
+	log    bytes.Buffer
+}
+
+// jobQueue runs jobs one at a time, in the order they are added. The vic-machine commands jobs
+// run set the log level and other process-wide state, and the log of a job is all output while
+// it runs, so jobs cannot overlap.
+type jobQueue struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+
+	pending chan *job
+}
+
+func newJobQueue() *jobQueue {
+	q := &jobQueue{
+		jobs:    make(map[string]*job),
+		pending: make(chan *job, maxQueuedJobs),
+	}
+	go q.work()
+	return q
+}
+
+// add queues run as a job for the operation on the VCH
+func (q *jobQueue) add(operation, vch string, run func() error) (Job, error) {
+	j := &job{
+		status: Job{
+			ID:        uid.New().Truncate().String(),
+			Operation: operation,
+			VCH:       vch,
+			State:     JobQueued,
+			Created:   time.Now().UTC(),
+		},
+		run: run,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case q.pending <- j:
+	default:
+		return Job{}, errQueueFull
+	}
+	q.jobs[j.status.ID] = j
+	q.prune()
+
+	return j.status, nil
+}
+
+// get returns the job with the ID, including its log
+func (q *jobQueue) get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	status := j.status
+	status.Log = j.log.String()
+	return status, true
+}
+
+// list returns all jobs, oldest first
+func (q *jobQueue) list() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		jobs = append(jobs, j.status)
+	}
+	sort.Sort(byCreation(jobs))
+	return jobs
+}
+
+// prune drops the oldest finished jobs beyond maxFinishedJobs, and must be called with mu held
+func (q *jobQueue) prune() {
+	var finished []Job
+	for _, j := range q.jobs {
+		if j.status.Finished != nil {
+			finished = append(finished, j.status)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+
+	sort.Sort(byCreation(finished))
+	for _, status := range finished[:len(finished)-maxFinishedJobs] {
+		delete(q.jobs, status.ID)
+	}
+}
+
+func (q *jobQueue) work() {
+	for j := range q.pending {
+		q.runJob(j)
+	}
+}
+
+func (q *jobQueue) runJob(j *job) {
+	q.mu.Lock()
+	started := time.Now().UTC()
+	j.status.State = JobRunning
+	j.status.Started = &started
+	q.mu.Unlock()
+
+	// no other job runs, so all output until the job is done is the job's
+	out := log.StandardLogger().Out
+	log.SetOutput(io.MultiWriter(out, &lockedWriter{mu: &q.mu, w: &j.log}))
+	log.Infof("Running %s job %s for VCH %q", j.status.Operation, j.status.ID, j.status.VCH)
+
+	err := j.run()
+
+	log.SetOutput(out)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	finished := time.Now().UTC()
+	j.status.Finished = &finished
+	if err != nil {
+		j.status.State = JobFailed
+		j.status.Error = err.Error()
+		j.status.ExitCode = common.ExitFailure
+		if e, ok := err.(cli.ExitCoder); ok {
+			j.status.ExitCode = e.ExitCode()
+		}
+		log.Errorf("%s job %s failed: %s", j.status.Operation, j.status.ID, err)
+		return
+	}
+	j.status.State = JobSucceeded
+	log.Infof("%s job %s succeeded", j.status.Operation, j.status.ID)
+}
+
+// lockedWriter writes to w with mu held, so that it can be read as it is written
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
+
+type byCreation []Job
+
+func (b byCreation) Len() int           { return len(b) }
+func (b byCreation) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byCreation) Less(i, j int) bool { return b[i].Created.Before(b[j].Created) }
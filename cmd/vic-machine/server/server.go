@@ -0,0 +1,277 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/cmd/vic-machine/configure"
+	"github.com/vmware/vic/cmd/vic-machine/create"
+	uninstall "github.com/vmware/vic/cmd/vic-machine/delete"
+	"github.com/vmware/vic/cmd/vic-machine/upgrade"
+	"github.com/vmware/vic/cmd/vic-machine/verify"
+	"github.com/vmware/vic/lib/client"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/certificate"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+
+	"golang.org/x/net/context"
+)
+
+// requestTimeout bounds the vSphere operations of requests answered without a job
+const requestTimeout = 3 * time.Minute
+
+// Server has all input parameters for vic-machine server command
+type Server struct {
+	*data.Data
+
+	addr      string
+	token     string
+	tokenFile string
+	cert      string
+	key       string
+
+	// targetURL is the target without credentials, as connecting modifies Data.URL
+	targetURL *url.URL
+}
+
+func NewServer() *Server {
+	server := &Server{}
+	server.Data = data.NewData()
+
+	return server
+}
+
+// Flags return all cli flags for server
+func (s *Server) Flags() []cli.Flag {
+	util := []cli.Flag{
+		cli.StringFlag{
+			Name:        "addr",
+			Value:       ":8443",
+			Usage:       "Address to serve the API on",
+			Destination: &s.addr,
+		},
+		cli.StringFlag{
+			Name:        "token",
+			Value:       "",
+			Usage:       "Token clients authenticate with, generated at startup if not given",
+			EnvVar:      "VIC_MACHINE_SERVER_TOKEN",
+			Destination: &s.token,
+		},
+		cli.StringFlag{
+			Name:        "token-file",
+			Value:       "",
+			Usage:       "File to write a generated token to, readable only by the user. The token is printed to standard output if not given",
+			Destination: &s.tokenFile,
+		},
+		cli.StringFlag{
+			Name:        "tls-server-cert",
+			Value:       "",
+			Usage:       "Certificate of the API, a self-signed one is generated if not given",
+			Destination: &s.cert,
+		},
+		cli.StringFlag{
+			Name:        "tls-server-key",
+			Value:       "",
+			Usage:       "Private key of the API certificate",
+			Destination: &s.key,
+		},
+		cli.BoolFlag{
+			Name:        "force, f",
+			Usage:       "Force connection to the target (ignores certificate verification)",
+			Destination: &s.Force,
+		},
+	}
+
+	target := s.TargetFlags()
+	debug := s.DebugFlags()
+
+	// flag arrays are declared, now combined
+	var flags []cli.Flag
+	for _, f := range [][]cli.Flag{target, util, debug} {
+		flags = append(flags, f...)
+	}
+
+	return flags
+}
+
+func (s *Server) processParams() error {
+	defer trace.End(trace.Begin(""))
+
+	if err := s.HasCredentials(); err != nil {
+		return err
+	}
+
+	if (s.cert == "") != (s.key == "") {
+		return cli.NewExitError("tls-server-cert and tls-server-key should be specified at the same time", 1)
+	}
+
+	if s.token == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return errors.Errorf("Failed to generate token: %s", err)
+		}
+		s.token = hex.EncodeToString(b)
+
+		// the token is kept out of the log, which is written to a file readable by others
+		if s.tokenFile != "" {
+			if err := ioutil.WriteFile(s.tokenFile, []byte(s.token+"\n"), 0600); err != nil {
+				return errors.Errorf("Failed to write token to %s: %s", s.tokenFile, err)
+			}
+			log.Infof("Clients must authenticate with the token written to %s", s.tokenFile)
+		} else {
+			log.Info("Clients must authenticate with the token printed to standard output")
+			fmt.Fprintln(os.Stdout, s.token)
+		}
+	}
+
+	u := *s.URL
+	u.User = nil
+	s.targetURL = &u
+
+	return nil
+}
+
+// target returns the target with the compute resource to search for VCHs in
+func (s *Server) target(computePath string) *data.Data {
+	u := *s.targetURL
+
+	target := data.NewData()
+	target.Target = &common.Target{
+		URL:        &u,
+		User:       s.User,
+		Password:   s.Password,
+		Thumbprint: s.Thumbprint,
+	}
+	target.ComputeResourcePath = computePath
+	target.Force = s.Force
+
+	return target
+}
+
+// connect returns a client for the target, with the VCHs of the compute resource
+func (s *Server) connect(computePath string) (vchClient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+
+	c, err := client.New(ctx, s.target(computePath))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &requestClient{Client: c, cancel: cancel}, nil
+}
+
+// requestClient is a client bound to the timeout of a request
+type requestClient struct {
+	*client.Client
+	cancel context.CancelFunc
+}
+
+func (c *requestClient) Close() error {
+	defer c.cancel()
+	return c.Client.Close()
+}
+
+// certificate returns the certificate of the API
+func (s *Server) certificate() (*tls.Certificate, error) {
+	kp := certificate.NewKeyPair(s.cert, s.key, nil, nil)
+	if s.cert != "" {
+		if err := kp.LoadCertificate(); err != nil {
+			return nil, errors.Errorf("Failed to load API certificate: %s", err)
+		}
+		return kp.Certificate()
+	}
+
+	log.Warn("Using a self-signed certificate for the API, give --tls-server-cert and --tls-server-key to have clients verify the server")
+	if err := kp.CreateSelfSigned("", []string{"VMware Inc."}, 2048); err != nil {
+		return nil, errors.Errorf("Failed to create API certificate: %s", err)
+	}
+	return kp.Certificate()
+}
+
+func (s *Server) Run(cli *cli.Context) error {
+	var err error
+	if err = s.processParams(); err != nil {
+		return err
+	}
+
+	if s.Debug.Debug > 0 {
+		log.SetLevel(log.DebugLevel)
+		trace.Logger.Level = log.DebugLevel
+	}
+
+	if len(cli.Args()) > 0 {
+		log.Errorf("Unknown argument: %s", cli.Args()[0])
+		return errors.New("invalid CLI arguments")
+	}
+
+	// check the target before serving, pinning its thumbprint for all later connections
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	target := s.target("")
+	c, err := client.New(ctx, target)
+	if err != nil {
+		log.Error("Server cannot start: failed to connect to target")
+		return err
+	}
+	s.Thumbprint = target.Thumbprint
+	c.Close()
+
+	cert, err := s.certificate()
+	if err != nil {
+		return err
+	}
+
+	opts := common.Options{
+		"target":     s.targetURL.String(),
+		"user":       s.User,
+		"password":   *s.Password,
+		"thumbprint": s.Thumbprint,
+	}
+	operations := map[string]operation{
+		"create":        {command: func() command { return create.NewCreate() }},
+		"delete":        {command: func() command { return uninstall.NewUninstall() }, byID: true},
+		"configure":     {command: func() command { return configure.NewConfigure() }, byID: true},
+		"upgrade":       {command: func() command { return upgrade.NewUpgrade() }, byID: true},
+		"verify-images": {command: func() command { return verify.NewVerify() }, byID: true},
+	}
+
+	server := &http.Server{
+		Addr:    s.addr,
+		Handler: newAPI(s.token, opts, operations, s.connect).handler(),
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{*cert},
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+
+	log.Infof("### Serving the vic-machine API for %s on %s ####", s.targetURL, s.addr)
+	return server.ListenAndServeTLS("", "")
+}
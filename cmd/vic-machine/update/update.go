@@ -0,0 +1,182 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"path"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/urfave/cli"
+
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+
+	"golang.org/x/net/context"
+)
+
+// Update has all input parameters for vic-machine update commands
+type Update struct {
+	*data.Data
+
+	executor *management.Dispatcher
+}
+
+func NewUpdate() *Update {
+	update := &Update{}
+	update.Data = data.NewData()
+
+	return update
+}
+
+// ISOFlags return all cli flags for update iso
+func (u *Update) ISOFlags() []cli.Flag {
+	util := []cli.Flag{
+		cli.BoolFlag{
+			Name:        "force, f",
+			Usage:       "Force the update (ignores version checks)",
+			Destination: &u.Force,
+		},
+		cli.DurationFlag{
+			Name:        "timeout",
+			Value:       3 * time.Minute,
+			Usage:       "Time to wait for update",
+			Destination: &u.Timeout,
+		},
+	}
+
+	iso := []cli.Flag{
+		cli.StringFlag{
+			Name:        "bootstrap-iso, bi",
+			Value:       "",
+			Usage:       "The bootstrap iso",
+			Destination: &u.BootstrapISO,
+		},
+	}
+
+	target := u.TargetFlags()
+	id := u.IDFlags()
+	compute := u.ComputeFlags()
+	debug := u.DebugFlags()
+
+	// flag arrays are declared, now combined
+	var flags []cli.Flag
+	for _, f := range [][]cli.Flag{target, id, compute, iso, util, debug} {
+		flags = append(flags, f...)
+	}
+
+	return flags
+}
+
+func (u *Update) processParams() error {
+	defer trace.End(trace.Begin(""))
+
+	if err := u.HasCredentials(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RunISO replaces the bootstrap image of containerVMs, without upgrading the VCH
+func (u *Update) RunISO(cli *cli.Context) (err error) {
+	if err = u.processParams(); err != nil {
+		return common.Exit(err, common.ExitValidationFailure)
+	}
+
+	if u.Debug.Debug > 0 {
+		log.SetLevel(log.DebugLevel)
+		trace.Logger.Level = log.DebugLevel
+	}
+
+	if len(cli.Args()) > 0 {
+		log.Errorf("Unknown argument: %s", cli.Args()[0])
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
+	}
+
+	var images map[string]string
+	if images, err = u.CheckBootstrapImageFile(u.Force); err != nil {
+		return common.Exit(err, common.ExitValidationFailure)
+	}
+
+	log.Infof("### Updating VCH bootstrap image ####")
+
+	ctx, cancel := context.WithTimeout(context.Background(), u.Timeout)
+	defer cancel()
+	defer func() {
+		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
+			//context deadline exceeded, replace returned error message
+			err = common.Exit(errors.Errorf("Update timed out: use --timeout to add more time"), common.ExitTimeout)
+		}
+	}()
+
+	validator, err := validate.NewValidator(ctx, u.Data)
+	if err != nil {
+		log.Errorf("Update cannot continue - failed to create validator: %s", err)
+		return common.Exit(errors.New("update failed"), common.TargetExitCode(err))
+	}
+	executor := management.NewDispatcher(validator.Context, validator.Session, nil, u.Force)
+
+	var vch *vm.VirtualMachine
+	if u.Data.ID != "" {
+		vch, err = executor.NewVCHFromID(u.Data.ID)
+	} else {
+		vch, err = executor.NewVCHFromComputePath(u.Data.ComputeResourcePath, u.Data.DisplayName, validator)
+	}
+	if err != nil {
+		log.Errorf("Failed to get Virtual Container Host %s", u.DisplayName)
+		log.Error(err)
+		return errors.New("update failed")
+	}
+
+	log.Infof("")
+	log.Infof("VCH ID: %s", vch.Reference().String())
+
+	current, err := executor.GetVCHConfig(vch)
+	if err != nil {
+		log.Error("Failed to get Virtual Container Host configuration")
+		log.Error(err)
+		return errors.New("update failed")
+	}
+	executor.InitDiagnosticLogs(current)
+
+	// decode a second copy of the configuration to apply the changes to
+	requested, err := executor.GetVCHConfig(vch)
+	if err != nil {
+		log.Error("Failed to get Virtual Container Host configuration")
+		log.Error(err)
+		return errors.New("update failed")
+	}
+
+	vConfig := validator.AddDeprecatedFields(ctx, requested, u.Data)
+	vConfig.ImageFiles = images
+	vConfig.BootstrapISO = path.Base(u.BootstrapISO)
+	vConfig.RollbackTimeout = u.Timeout
+
+	if err = executor.UpdateBootstrapImage(vch, current, requested, vConfig); err != nil {
+		executor.CollectDiagnosticLogs()
+		return common.OperationError(err)
+	}
+
+	log.Infof("Completed successfully")
+
+	return nil
+}
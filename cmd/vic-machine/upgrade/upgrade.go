@@ -22,6 +22,7 @@ import (
 
 	"github.com/urfave/cli"
 
+	"github.com/vmware/vic/cmd/vic-machine/common"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/lib/install/management"
 	"github.com/vmware/vic/lib/install/validate"
@@ -60,6 +61,18 @@ func (u *Upgrade) Flags() []cli.Flag {
 			Usage:       "Time to wait for upgrade",
 			Destination: &u.Timeout,
 		},
+		cli.DurationFlag{
+			Name:        "docker-api-timeout",
+			Value:       0,
+			Usage:       "Time to wait for the docker API of the VCH to respond once it is upgraded, within --timeout. 0 waits until --timeout",
+			Destination: &u.DockerAPITimeout,
+		},
+		cli.DurationFlag{
+			Name:        "docker-api-attempt-timeout",
+			Value:       10 * time.Second,
+			Usage:       "Time to wait for each request to the docker API of the VCH while waiting for it to respond. 0 for no limit",
+			Destination: &u.DockerAPIAttemptTimeout,
+		},
 	}
 
 	target := u.TargetFlags()
@@ -87,10 +100,9 @@ func (u *Upgrade) processParams() error {
 	return nil
 }
 
-func (u *Upgrade) Run(cli *cli.Context) error {
-	var err error
+func (u *Upgrade) Run(cli *cli.Context) (err error) {
 	if err = u.processParams(); err != nil {
-		return err
+		return common.Exit(err, common.ExitValidationFailure)
 	}
 
 	if u.Debug.Debug > 0 {
@@ -100,25 +112,33 @@ func (u *Upgrade) Run(cli *cli.Context) error {
 
 	if len(cli.Args()) > 0 {
 		log.Errorf("Unknown argument: %s", cli.Args()[0])
-		return errors.New("invalid CLI arguments")
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
 	}
 
 	var images map[string]string
 	if images, err = u.CheckImagesFiles(u.Force); err != nil {
-		return err
+		return common.Exit(err, common.ExitValidationFailure)
 	}
 
 	log.Infof("### Upgrading VCH ####")
 
 	ctx, cancel := context.WithTimeout(context.Background(), u.Timeout)
 	defer cancel()
+	defer func() {
+		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
+			//context deadline exceeded, replace returned error message
+			err = common.Exit(errors.Errorf("Upgrade timed out: use --timeout to add more time"), common.ExitTimeout)
+		}
+	}()
 
 	validator, err := validate.NewValidator(ctx, u.Data)
 	if err != nil {
 		log.Errorf("Upgrade cannot continue - failed to create validator: %s", err)
-		return errors.New("upgrade failed")
+		return common.Exit(errors.New("upgrade failed"), common.TargetExitCode(err))
 	}
 	executor := management.NewDispatcher(validator.Context, validator.Session, nil, u.Force)
+	executor.DockerAPITimeout = u.DockerAPITimeout
+	executor.DockerAPIAttemptTimeout = u.DockerAPIAttemptTimeout
 
 	var vch *vm.VirtualMachine
 	if u.Data.ID != "" {
@@ -161,14 +181,7 @@ func (u *Upgrade) Run(cli *cli.Context) error {
 		if err == nil {
 			err = errors.New("upgrade failed")
 		}
-		return err
-	}
-
-	// check the docker endpoint is responsive
-	if err = executor.CheckDockerAPI(vchConfig, nil); err != nil {
-
-		executor.CollectDiagnosticLogs()
-		return err
+		return common.OperationError(err)
 	}
 
 	log.Infof("Completed successfully")
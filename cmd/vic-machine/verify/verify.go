@@ -0,0 +1,166 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/urfave/cli"
+	"github.com/vmware/vic/cmd/vic-machine/common"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+
+	"golang.org/x/net/context"
+)
+
+// Verify has all input parameters for vic-machine verify-images command
+type Verify struct {
+	*data.Data
+
+	// Action is what is done with damaged layers - report, quarantine or repair
+	Action string
+
+	executor *management.Dispatcher
+}
+
+func NewVerify() *Verify {
+	v := &Verify{}
+	v.Data = data.NewData()
+	return v
+}
+
+// Flags return all cli flags for verify-images
+func (v *Verify) Flags() []cli.Flag {
+	util := []cli.Flag{
+		cli.StringFlag{
+			Name:        "action",
+			Value:       management.ImageActionReport,
+			Usage:       fmt.Sprintf("What to do with damaged image layers: %s. Quarantine and repair restart the appliance", strings.Join(management.ImageActions, ", ")),
+			Destination: &v.Action,
+		},
+		cli.DurationFlag{
+			Name:        "timeout",
+			Value:       10 * time.Minute,
+			Usage:       "Time to wait for verification to complete",
+			Destination: &v.Timeout,
+		},
+	}
+
+	target := v.TargetFlags()
+	id := v.IDFlags()
+	compute := v.ComputeFlags()
+	debug := v.DebugFlags()
+
+	// flag arrays are declared, now combined
+	var flags []cli.Flag
+	for _, f := range [][]cli.Flag{target, id, compute, util, debug} {
+		flags = append(flags, f...)
+	}
+
+	return flags
+}
+
+func (v *Verify) processParams() error {
+	defer trace.End(trace.Begin(""))
+
+	if err := v.HasCredentials(); err != nil {
+		return err
+	}
+
+	if err := management.ValidImageAction(v.Action); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func (v *Verify) Run(cli *cli.Context) (err error) {
+	if err = v.processParams(); err != nil {
+		return common.Exit(err, common.ExitValidationFailure)
+	}
+
+	if v.Debug.Debug > 0 {
+		log.SetLevel(log.DebugLevel)
+		trace.Logger.Level = log.DebugLevel
+	}
+
+	if len(cli.Args()) > 0 {
+		log.Errorf("Unknown argument: %s", cli.Args()[0])
+		return common.Exit(errors.New("invalid CLI arguments"), common.ExitValidationFailure)
+	}
+
+	log.Infof("### Verifying VCH image stores ####")
+
+	ctx, cancel := context.WithTimeout(context.Background(), v.Timeout)
+	defer cancel()
+	defer func() {
+		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
+			//context deadline exceeded, replace returned error message
+			err = common.Exit(errors.Errorf("Verify timed out: use --timeout to add more time"), common.ExitTimeout)
+		}
+	}()
+
+	validator, err := validate.NewValidator(ctx, v.Data)
+	if err != nil {
+		log.Errorf("Verify cannot continue - failed to create validator: %s", err)
+		return common.Exit(errors.New("verify failed"), common.TargetExitCode(err))
+	}
+	executor := management.NewDispatcher(validator.Context, validator.Session, nil, v.Force)
+
+	var vch *vm.VirtualMachine
+	if v.Data.ID != "" {
+		vch, err = executor.NewVCHFromID(v.Data.ID)
+	} else {
+		vch, err = executor.NewVCHFromComputePath(v.Data.ComputeResourcePath, v.Data.DisplayName, validator)
+	}
+	if err != nil {
+		log.Errorf("Failed to get Virtual Container Host %s", v.DisplayName)
+		log.Error(err)
+		return errors.New("verify failed")
+	}
+
+	log.Infof("")
+	log.Infof("VCH ID: %s", vch.Reference().String())
+
+	vchConfig, err := executor.GetVCHConfig(vch)
+	if err != nil {
+		log.Error("Failed to get Virtual Container Host configuration")
+		log.Error(err)
+		return errors.New("verify failed")
+	}
+	executor.InitDiagnosticLogs(vchConfig)
+
+	reports, err := executor.VerifyImageStores(vch, vchConfig, v.Action)
+	if showErr := management.ShowImageStores(reports, v.Action); err == nil {
+		err = showErr
+	}
+	if err != nil {
+		executor.CollectDiagnosticLogs()
+		log.Errorf("%s", err)
+		return errors.New("verify failed")
+	}
+
+	log.Infof("Completed successfully")
+
+	return nil
+}
@@ -108,6 +108,8 @@ func configureReaders() map[string]entryReader {
 		"block":   "/debug/pprof/block?debug=1",
 		"heap":    "/debug/pprof/heap?debug=1",
 		"profile": "/debug/pprof/profile",
+		// sampled trace latencies
+		"metrics": "/metrics",
 	}
 
 	pprofSources := map[string]string{
@@ -38,6 +38,16 @@ type server struct {
 	l    net.Listener
 	addr string
 	mux  *http.ServeMux
+
+	// auth checks the credentials of users, nil to serve without authentication
+	auth     vicadmin.Authenticator
+	sessions *vicadmin.Sessions
+}
+
+// loginPage is the data of the login template
+type loginPage struct {
+	Provider string
+	Error    string
 }
 
 type format int
@@ -116,14 +126,20 @@ func (s *server) serve() error {
 	defer trace.End(trace.Begin(""))
 
 	s.mux = http.NewServeMux()
+	if s.sessions == nil {
+		s.sessions = vicadmin.NewSessions(vicadmin.DefaultSessionTimeout)
+	}
+
+	s.mux.HandleFunc("/authentication", s.login)
+	s.mux.HandleFunc("/logout", s.logout)
 
 	// tar of appliance system logs
-	s.mux.HandleFunc("/logs.tar.gz", s.tarDefaultLogs)
-	s.mux.HandleFunc("/logs.zip", s.zipDefaultLogs)
+	s.mux.HandleFunc("/logs.tar.gz", s.authenticated(s.tarDefaultLogs))
+	s.mux.HandleFunc("/logs.zip", s.authenticated(s.zipDefaultLogs))
 
 	// tar of appliance system logs + container logs
-	s.mux.HandleFunc("/container-logs.tar.gz", s.tarContainerLogs)
-	s.mux.HandleFunc("/container-logs.zip", s.zipContainerLogs)
+	s.mux.HandleFunc("/container-logs.tar.gz", s.authenticated(s.tarContainerLogs))
+	s.mux.HandleFunc("/container-logs.zip", s.authenticated(s.zipContainerLogs))
 
 	s.mux.Handle("/css/", http.StripPrefix("/css/", http.FileServer(http.Dir("css/"))))
 	s.mux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("images/"))))
@@ -134,17 +150,17 @@ func (s *server) serve() error {
 		p := path
 
 		// get single log file (no tail)
-		s.mux.HandleFunc("/logs/"+name, func(w http.ResponseWriter, r *http.Request) {
+		s.mux.HandleFunc("/logs/"+name, s.authenticated(func(w http.ResponseWriter, r *http.Request) {
 			http.ServeFile(w, r, p)
-		})
+		}))
 
 		// get single log file (with tail)
-		s.mux.HandleFunc("/logs/tail/"+name, func(w http.ResponseWriter, r *http.Request) {
+		s.mux.HandleFunc("/logs/tail/"+name, s.authenticated(func(w http.ResponseWriter, r *http.Request) {
 			s.tailFiles(w, r, []string{p})
-		})
+		}))
 	}
 
-	s.mux.HandleFunc("/", s.index)
+	s.mux.HandleFunc("/", s.authenticated(s.index))
 	server := &http.Server{
 		Handler: s.mux,
 	}
@@ -166,6 +182,106 @@ func (s *server) stop() error {
 	return nil
 }
 
+// authenticated wraps handler so that it is only served to logged in users. Users log in with the
+// form at /authentication, which starts a session, or with basic auth credentials on each request
+// for command line clients. A verified client certificate also authenticates the request.
+func (s *server) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if s.auth == nil || s.sessions.Get(req) != nil {
+			handler(res, req)
+			return
+		}
+
+		if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+			handler(res, req)
+			return
+		}
+
+		if username, password, ok := req.BasicAuth(); ok {
+			err := s.auth.Authenticate(context.Background(), username, password)
+			if err == nil {
+				handler(res, req)
+				return
+			}
+
+			log.Warnf("Authentication of %s failed: %s", username, err)
+			res.Header().Set("WWW-Authenticate", `Basic realm="vicadmin"`)
+			http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		// the dashboard is opened in a browser, the other pages may be fetched by scripts
+		if req.URL.Path == "/" {
+			http.Redirect(res, req, "/authentication", http.StatusSeeOther)
+			return
+		}
+
+		res.Header().Set("WWW-Authenticate", `Basic realm="vicadmin"`)
+		http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+}
+
+// login serves the login form, and starts a session for the user on a valid submission of it
+func (s *server) login(res http.ResponseWriter, req *http.Request) {
+	defer trace.End(trace.Begin(""))
+
+	if s.auth == nil {
+		http.Redirect(res, req, "/", http.StatusSeeOther)
+		return
+	}
+
+	page := loginPage{Provider: "vSphere"}
+	if _, ok := s.auth.(*vicadmin.LDAPAuthenticator); ok {
+		page.Provider = "directory"
+	}
+
+	if req.Method == http.MethodPost {
+		username := req.FormValue("username")
+
+		err := s.auth.Authenticate(context.Background(), username, req.FormValue("password"))
+		if err == nil {
+			cookie, err := s.sessions.Create(username)
+			if err != nil {
+				log.Errorf("Unable to create session: %s", err)
+				http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			cookie.Secure = req.TLS != nil
+
+			log.Infof("User %s logged in", username)
+			http.SetCookie(res, cookie)
+			http.Redirect(res, req, "/", http.StatusSeeOther)
+			return
+		}
+
+		log.Warnf("Authentication of %s failed: %s", username, err)
+		page.Error = "Authentication failed"
+		if err == vicadmin.ErrInvalidCredentials {
+			page.Error = err.Error()
+		}
+		res.WriteHeader(http.StatusUnauthorized)
+	}
+
+	tmpl, err := template.ParseFiles("login.html")
+	if err == nil {
+		err = tmpl.ExecuteTemplate(res, "login.html", page)
+	}
+	if err != nil {
+		log.Errorf("Error parsing template: %s", err)
+	}
+}
+
+// logout ends the session of the user
+func (s *server) logout(res http.ResponseWriter, req *http.Request) {
+	defer trace.End(trace.Begin(""))
+
+	cookie := s.sessions.Delete(req)
+	cookie.Secure = req.TLS != nil
+
+	http.SetCookie(res, cookie)
+	http.Redirect(res, req, "/authentication", http.StatusSeeOther)
+}
+
 func (s *server) bundleContainerLogs(res http.ResponseWriter, req *http.Request, f format) {
 	defer trace.End(trace.Begin(""))
 
@@ -40,6 +40,7 @@ import (
 	vchconfig "github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/guest"
 	"github.com/vmware/vic/lib/pprof"
+	"github.com/vmware/vic/lib/vicadmin"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/version"
 	"github.com/vmware/vic/pkg/vsphere/compute"
@@ -72,6 +73,8 @@ var (
 		session.Config
 		addr string
 		tls  bool
+
+		sessionTimeout time.Duration
 	}
 
 	resources vchconfig.Resources
@@ -107,6 +110,7 @@ func init() {
 	// }
 
 	flag.StringVar(&config.addr, "l", "client.localhost:2378", "Listen address")
+	flag.DurationVar(&config.sessionTimeout, "session-timeout", vicadmin.DefaultSessionTimeout, "How long a login lasts")
 
 	// TODO: This should all be pulled from the config
 	flag.StringVar(&config.DatacenterPath, "dc", "", "Path of the datacenter")
@@ -469,11 +473,18 @@ func main() {
 		log.Warn("Listening on all networks because of debug level")
 	}
 
+	auth, err := vicadmin.NewAuthenticator(&vchConfig)
+	if err != nil {
+		log.Fatalf("Unable to configure authentication: %s", err)
+	}
+
 	s := &server{
-		addr: config.addr,
+		addr:     config.addr,
+		auth:     auth,
+		sessions: vicadmin.NewSessions(config.sessionTimeout),
 	}
 
-	err := s.listen()
+	err = s.listen()
 	if err != nil {
 		log.Fatal(err)
 	}
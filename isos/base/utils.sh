@@ -199,64 +199,6 @@ ensure_apt_packages() {
     fi
 }
 
-# build an ISO from the specified bundle directory.
-# 1: bundle base directory
-# 2: output file for ISO image - stdio:/dev/fd/1 can be used for stdout
-# 3: init binary to use
-generate_iso() {
-    [ -n "$3" ] || {
-        echo "Init binary must be specified to generate_iso" 1>&2
-        return 1
-    }
-
-    ensure_apt_packages cpio xorriso || {
-        echo "cpio and xorriso packages must be installed for ISO authoring: $?" 1>&2
-        return 1
-    }
-
-    out=$(readlink -f $2)
-    # subshell to avoid changing directory for invoker in failure cases
-    (
-        # operate relative to the package
-        cd $1
-
-        test -r bootfs/boot/isolinux/isolinux.bin -a -w bootfs/boot/isolinux/isolinux.cfg || {
-            echo "isolinux files must exist in $1/boot/isolinux: $?" 1>&2
-            return 2
-        }
-
-        # ensure the target init exists
-        test -x rootfs/$3 || {
-            echo "Specified init ($3) does not exist or is not executable: $?" 1>&2
-            return 3
-        }
-        # set the init binary in isolinux.cfg
-        sed -i -e "s|^#\(\s*append rdinit\)=_INIT_BINARY_|\1=$3|" bootfs/boot/isolinux/isolinux.cfg || {
-            echo "Unable to update rdinit entry in isolinux.cfg: $?" 1>&2
-            return 4
-        }
-
-        # create the initramfs archive - subshell to avoid changing directory
-        echo "Constructing initramfs archive"
-        ( cd rootfs && find | cpio -o -H newc | gzip --fast ) > bootfs/boot/core.gz || {
-            echo "Failed to package root filesystem from $1/rootfs: $?" 1>&2
-            return 5
-        }
-
-        # deleting the file first seems to be necessary in some cases
-        rm -f "$out"
-
-        # generate the ISO and write it to $ISOOUT
-        xorriso -dev "$out" -options_from_file xorriso-options.cfg || {
-            echo "Failed to generate ISO file from package: $?" 1>&2
-            return 6
-        }
-    )
-
-    return
-}
-
-
 # Support use of yum cached packages with installroot
 # This has been written to use getopts to:
 # a. allow the cache to be optional
@@ -100,10 +100,12 @@ fi
 # List stable packages here
 #   iproute2  # for ip
 #   libtirpc  # due to a previous package reliance on rpc
+#   iptables  # for container network firewall policies, carried into the container by bootstrap
 #
 yum_cached -c $cache -u -p $PKGDIR install \
     haveged \
     systemd \
+    iptables \
     -y --nogpgcheck
 
 # https://www.freedesktop.org/wiki/Software/systemd/InitrdInterface/
@@ -16,6 +16,7 @@ package backends
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/url"
@@ -39,6 +40,7 @@ import (
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/imagec"
 	"github.com/vmware/vic/pkg/errors"
+	urlfetcher "github.com/vmware/vic/pkg/fetcher"
 	"github.com/vmware/vic/pkg/vsphere/sys"
 )
 
@@ -254,10 +256,41 @@ func InsecureRegistries() []string {
 	return registries
 }
 
+// RegistryCertPool returns the certificate authorities trusted for registries: the system roots and
+// those in the VCH configuration
+func RegistryCertPool() *x509.CertPool {
+	if vchConfig == nil || len(vchConfig.RegistryCertificateAuthorities) == 0 {
+		return nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		log.Warnf("Unable to load system root certificates: %s", err)
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(vchConfig.RegistryCertificateAuthorities) {
+		log.Warnf("Unable to load registry certificate authorities")
+	}
+	return pool
+}
+
+// RegistryProxies returns the proxies used for registries in place of the proxies from the environment
+func RegistryProxies() urlfetcher.RegistryProxies {
+	if vchConfig == nil {
+		return nil
+	}
+	return urlfetcher.RegistryProxies(vchConfig.RegistryProxies)
+}
+
 // syncContainerCache runs once at startup to populate the container cache
 func syncContainerCache() error {
 	log.Debugf("Updating container cache")
 
+	// the saved port mappings keep the host ports that were picked at random
+	if err := loadPortMappings(); err != nil {
+		log.Errorf("Failed to load port mappings: %s", err)
+	}
+
 	backend := NewContainerBackend()
 	client := backend.containerProxy.Client()
 
@@ -277,6 +310,8 @@ func syncContainerCache() error {
 			errs = append(errs, err.Error())
 		}
 	}
+	prunePortMappings()
+
 	if len(errs) > 0 {
 		return errors.Errorf("Failed to set port mapping: %s", strings.Join(errs, "\n"))
 	}
@@ -294,6 +329,11 @@ func setPortMapping(info *models.ContainerInfo, backend *Container, container *c
 		return nil
 	}
 
+	restored, err := restorePortMappings(container.ContainerID)
+	if restored || err != nil {
+		return err
+	}
+
 	log.Debugf("Set port mapping for container %q, portmapping %+v", container.Name, container.HostConfig.PortBindings)
 	client := backend.containerProxy.Client()
 	endpointsOK, err := client.Scopes.GetContainerEndpoints(
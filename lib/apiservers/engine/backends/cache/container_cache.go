@@ -31,6 +31,9 @@ type CCache struct {
 	idIndex          *truncindex.TruncIndex
 	containersByID   map[string]*container.VicContainer
 	containersByName map[string]*container.VicContainer
+
+	// the exec instances of the containers, removed with their container
+	execsByID map[string]*container.VicExec
 }
 
 var containerCache *CCache
@@ -40,6 +43,7 @@ func init() {
 		idIndex:          truncindex.NewTruncIndex([]string{}),
 		containersByID:   make(map[string]*container.VicContainer),
 		containersByName: make(map[string]*container.VicContainer),
+		execsByID:        make(map[string]*container.VicExec),
 	}
 }
 
@@ -95,7 +99,62 @@ func (cc *CCache) DeleteContainer(nameOrID string) {
 	delete(cc.containersByID, container.ContainerID)
 	delete(cc.containersByName, container.Name)
 
+	for id, exec := range cc.execsByID {
+		if exec.ContainerID == container.ContainerID {
+			delete(cc.execsByID, id)
+		}
+	}
+
 	if err := cc.idIndex.Delete(container.ContainerID); err != nil {
 		log.Warnf("Error deleting ID from index: %s", err)
 	}
 }
+
+// AddExec records an exec instance of a container
+func (cc *CCache) AddExec(exec *container.VicExec) {
+	cc.m.Lock()
+	defer cc.m.Unlock()
+
+	cc.execsByID[exec.ID] = exec
+}
+
+// GetExec returns a copy of the exec instance with the given ID, or nil if there is none
+func (cc *CCache) GetExec(id string) *container.VicExec {
+	cc.m.RLock()
+	defer cc.m.RUnlock()
+
+	exec, exist := cc.execsByID[id]
+	if !exist {
+		return nil
+	}
+
+	copied := *exec
+	return &copied
+}
+
+// StartExec marks the exec instance with the given ID as started, returning false if it does not
+// exist or has already been started
+func (cc *CCache) StartExec(id string) bool {
+	cc.m.Lock()
+	defer cc.m.Unlock()
+
+	exec, exist := cc.execsByID[id]
+	if !exist || exec.Started {
+		return false
+	}
+
+	exec.Started = true
+	exec.Running = true
+	return true
+}
+
+// UpdateExec records the state of the process of the exec instance with the given ID
+func (cc *CCache) UpdateExec(id string, running bool, exitCode int) {
+	cc.m.Lock()
+	defer cc.m.Unlock()
+
+	if exec, exist := cc.execsByID[id]; exist {
+		exec.Running = running
+		exec.ExitCode = exitCode
+	}
+}
@@ -15,6 +15,7 @@
 package backends
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -33,6 +34,8 @@ import (
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/namesgenerator"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/pkg/term"
 	"github.com/docker/docker/pkg/version"
 	"github.com/docker/docker/reference"
 	"github.com/docker/engine-api/types"
@@ -47,10 +50,13 @@ import (
 	"github.com/vmware/vic/lib/apiservers/engine/backends/cache"
 	viccontainer "github.com/vmware/vic/lib/apiservers/engine/backends/container"
 	"github.com/vmware/vic/lib/apiservers/engine/backends/portmap"
+	"github.com/vmware/vic/lib/apiservers/engine/backends/translate"
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/containers"
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/interaction"
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/scopes"
 	"github.com/vmware/vic/lib/apiservers/portlayer/models"
+	vchconfig "github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/metadata"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/uid"
@@ -69,6 +75,12 @@ const (
 	MinCPUs = 1
 	// DefaultCPUs - the default number of container VM CPUs
 	DefaultCPUs = 2
+
+	// the exit code of an exec process that could not be launched, as docker reports it
+	execLaunchFailed = 126
+	// how long the exit of an exec process is waited for once its streams have closed
+	execStatusAttempts = 50
+	execStatusInterval = 100 * time.Millisecond
 )
 
 var (
@@ -82,7 +94,7 @@ var (
 	portMapper portmap.PortMapper
 
 	cbpLock         sync.Mutex
-	containerByPort map[string]string // port:containerID
+	containerByPort map[string]string // port/proto:containerID
 
 	ctx = context.TODO()
 )
@@ -116,10 +128,6 @@ type Container struct {
 	containerProxy VicContainerProxy
 }
 
-const (
-	defaultEnvPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
-)
-
 func (c *Container) Handle(id, name string) (string, error) {
 	resp, err := c.containerProxy.Client().Containers.Get(containers.NewGetParamsWithContext(ctx).WithID(id))
 	if err != nil {
@@ -147,32 +155,189 @@ func NewContainerBackend() *Container {
 
 // ContainerExecCreate sets up an exec in a running container.
 func (c *Container) ContainerExecCreate(config *types.ExecConfig) (string, error) {
-	return "", fmt.Errorf("%s does not implement container.ContainerExecCreate", ProductName())
+	defer trace.End(trace.Begin(config.Container))
+
+	// Look up the container name in the metadata cache to get long ID
+	vc := cache.ContainerCache().GetContainer(config.Container)
+	if vc == nil {
+		return "", NotFoundError(config.Container)
+	}
+
+	if err := externalError(vc, config.Container); err != nil {
+		return "", err
+	}
+
+	running, err := c.containerProxy.IsRunning(vc)
+	if err != nil {
+		return "", err
+	}
+	if !running {
+		return "", derr.NewRequestConflictError(fmt.Errorf("Container %s is not running", config.Container))
+	}
+
+	exec := &viccontainer.VicExec{
+		ID:          stringid.GenerateRandomID(),
+		ContainerID: vc.ContainerID,
+		Config:      *config,
+	}
+	cache.ContainerCache().AddExec(exec)
+
+	return exec.ID, nil
 }
 
 // ContainerExecInspect returns low-level information about the exec
 // command. An error is returned if the exec cannot be found.
 func (c *Container) ContainerExecInspect(id string) (*backend.ExecInspect, error) {
-	return nil, fmt.Errorf("%s does not implement container.ContainerExecInspect", ProductName())
+	defer trace.End(trace.Begin(id))
+
+	exec := cache.ContainerCache().GetExec(id)
+	if exec == nil {
+		return nil, ExecNotFoundError(id)
+	}
+
+	// refresh the state of a detached exec, which is not followed to its exit
+	if exec.Started && exec.Running {
+		if vc := cache.ContainerCache().GetContainer(exec.ContainerID); vc != nil {
+			c.updateExec(vc, id)
+			exec = cache.ContainerCache().GetExec(id)
+		}
+	}
+
+	inspect := &backend.ExecInspect{
+		ID:      exec.ID,
+		Running: exec.Running,
+		ProcessConfig: &backend.ExecProcessConfig{
+			Tty:        exec.Config.Tty,
+			Entrypoint: exec.Config.Cmd[0],
+			Arguments:  exec.Config.Cmd[1:],
+			Privileged: &exec.Config.Privileged,
+			User:       exec.Config.User,
+		},
+		OpenStdin:   exec.Config.AttachStdin,
+		OpenStdout:  exec.Config.AttachStdout,
+		OpenStderr:  exec.Config.AttachStderr,
+		ContainerID: exec.ContainerID,
+	}
+
+	if exec.Started && !exec.Running {
+		exitCode := exec.ExitCode
+		inspect.ExitCode = &exitCode
+	}
+
+	return inspect, nil
 }
 
 // ContainerExecResize changes the size of the TTY of the process
 // running in the exec with the given name to the given height and
 // width.
 func (c *Container) ContainerExecResize(name string, height, width int) error {
-	return fmt.Errorf("%s does not implement container.ContainerExecResize", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	exec := cache.ContainerCache().GetExec(name)
+	if exec == nil {
+		return ExecNotFoundError(name)
+	}
+
+	vc := cache.ContainerCache().GetContainer(exec.ContainerID)
+	if vc == nil {
+		return NotFoundError(exec.ContainerID)
+	}
+
+	return c.containerProxy.Resize(execContainer(vc, exec), int32(height), int32(width))
 }
 
 // ContainerExecStart starts a previously set up exec instance. The
 // std streams are set up.
 func (c *Container) ContainerExecStart(name string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error {
-	return fmt.Errorf("%s does not implement container.ContainerExecStart", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	exec := cache.ContainerCache().GetExec(name)
+	if exec == nil {
+		return ExecNotFoundError(name)
+	}
+
+	vc := cache.ContainerCache().GetContainer(exec.ContainerID)
+	if vc == nil {
+		return NotFoundError(exec.ContainerID)
+	}
+
+	if !cache.ContainerCache().StartExec(name) {
+		return derr.NewRequestConflictError(fmt.Errorf("Exec %s has already been started", name))
+	}
+
+	ca := &backend.ContainerAttachConfig{
+		UseStdin:  exec.Config.AttachStdin && stdin != nil,
+		UseStdout: exec.Config.AttachStdout && stdout != nil,
+		// a tty merges stderr into stdout
+		UseStderr: exec.Config.AttachStderr && stderr != nil && !exec.Config.Tty,
+	}
+	if exec.Config.DetachKeys != "" {
+		keys, err := term.ToBytes(exec.Config.DetachKeys)
+		if err != nil {
+			return BadRequestError(fmt.Sprintf("Invalid escape keys (%s) provided", exec.Config.DetachKeys))
+		}
+		ca.DetachKeys = keys
+	}
+
+	attach := ca.UseStdin || ca.UseStdout || ca.UseStderr
+	if err := c.containerProxy.LaunchTask(vc, exec, attach); err != nil {
+		cache.ContainerCache().UpdateExec(name, false, execLaunchFailed)
+		return err
+	}
+
+	if !attach {
+		return nil
+	}
+
+	err := c.containerProxy.AttachStreams(context.Background(), execContainer(vc, exec), stdin, stdout, stderr, ca)
+
+	// the streams close when the process exits, after which its exit code is available
+	for i := 0; i < execStatusAttempts && c.updateExec(vc, name); i++ {
+		time.Sleep(execStatusInterval)
+	}
+
+	return err
 }
 
 // ExecExists looks up the exec instance and returns a bool if it exists or not.
 // It will also return the error produced by `getConfig`
 func (c *Container) ExecExists(name string) (bool, error) {
-	return false, fmt.Errorf("%s does not implement container.ExecExists", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	if cache.ContainerCache().GetExec(name) == nil {
+		return false, ExecNotFoundError(name)
+	}
+	return true, nil
+}
+
+// updateExec records the state of the process of the exec instance reported by the portlayer,
+// returning whether it is still running
+func (c *Container) updateExec(vc *viccontainer.VicContainer, id string) bool {
+	running, exitCode, err := c.containerProxy.TaskStatus(vc, id)
+	if err != nil {
+		log.Errorf("Failed to get the state of exec %s: %s", id, err)
+
+		// a process that could not be launched will not run
+		if !IsNotFoundError(err) {
+			cache.ContainerCache().UpdateExec(id, false, execLaunchFailed)
+		}
+		return false
+	}
+
+	cache.ContainerCache().UpdateExec(id, running, exitCode)
+	return running
+}
+
+// execContainer returns a copy of the container that addresses the streams of the exec instance
+func execContainer(vc *viccontainer.VicContainer, exec *viccontainer.VicExec) *viccontainer.VicContainer {
+	config := *vc.Config
+	config.Tty = exec.Config.Tty
+	config.StdinOnce = true
+
+	ec := *vc
+	ec.ContainerID = exec.ID
+	ec.Config = &config
+	return &ec
 }
 
 // docker's container.copyBackend
@@ -181,13 +346,39 @@ func (c *Container) ExecExists(name string) (bool, error) {
 // specified path in the container identified by the given name. Returns a
 // tar archive of the resource and whether it was a directory or a single file.
 func (c *Container) ContainerArchivePath(name string, path string) (content io.ReadCloser, stat *types.ContainerPathStat, err error) {
-	return nil, nil, fmt.Errorf("%s does not implement container.ContainerArchivePath", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	vc := cache.ContainerCache().GetContainer(name)
+	if vc == nil {
+		return nil, nil, NotFoundError(name)
+	}
+
+	if err = externalError(vc, name); err != nil {
+		return nil, nil, err
+	}
+
+	// the stat reports a missing path before the archive is streamed
+	stat, err = c.containerProxy.StatPath(vc.ContainerID, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.containerProxy.ArchivePath(vc.ContainerID, path, pw))
+	}()
+
+	return pr, stat, nil
 }
 
 // ContainerCopy performs a deprecated operation of archiving the resource at
 // the specified path in the container identified by the given name.
 func (c *Container) ContainerCopy(name string, res string) (io.ReadCloser, error) {
-	return nil, fmt.Errorf("%s does not implement container.ContainerCopy", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	// the archive entries are named after the base of res, as with the archive API
+	content, _, err := c.ContainerArchivePath(name, res)
+	return content, err
 }
 
 // ContainerExport writes the contents of the container to the given
@@ -203,13 +394,35 @@ func (c *Container) ContainerExport(name string, out io.Writer) error {
 // be an error if unpacking the given content would cause an existing directory
 // to be replaced with a non-directory and vice versa.
 func (c *Container) ContainerExtractToDir(name, path string, noOverwriteDirNonDir bool, content io.Reader) error {
-	return fmt.Errorf("%s does not implement container.ContainerExtractToDir", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	vc := cache.ContainerCache().GetContainer(name)
+	if vc == nil {
+		return NotFoundError(name)
+	}
+
+	if err := externalError(vc, name); err != nil {
+		return err
+	}
+
+	return c.containerProxy.ExtractToDir(vc.ContainerID, path, noOverwriteDirNonDir, content)
 }
 
 // ContainerStatPath stats the filesystem resource at the specified path in the
 // container identified by the given name.
 func (c *Container) ContainerStatPath(name string, path string) (stat *types.ContainerPathStat, err error) {
-	return nil, fmt.Errorf("%s does not implement container.ContainerStatPath", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	vc := cache.ContainerCache().GetContainer(name)
+	if vc == nil {
+		return nil, NotFoundError(name)
+	}
+
+	if err = externalError(vc, name); err != nil {
+		return nil, err
+	}
+
+	return c.containerProxy.StatPath(vc.ContainerID, path)
 }
 
 // docker's container.stateBackend
@@ -229,6 +442,13 @@ func (c *Container) ContainerCreate(config types.ContainerCreateConfig) (types.C
 
 	// get the image from the cache
 	image, err := cache.ImageCache().Get(config.Config.Image)
+	if err != nil {
+		// creates in a burst, as compose makes, may reference an image that a batch
+		// pull or prefetch is still pulling
+		if pulling, perr := imagePulls.Wait(context.Background(), config.Config.Image); pulling && perr == nil {
+			image, err = cache.ImageCache().Get(config.Config.Image)
+		}
+	}
 	if err != nil {
 		// if no image found then error thrown and a pull
 		// will be initiated by the docker client
@@ -236,7 +456,14 @@ func (c *Container) ContainerCreate(config types.ContainerCreateConfig) (types.C
 		return types.ContainerCreateResponse{}, derr.NewRequestNotFoundError(err)
 	}
 
-	setCreateConfigOptions(config.Config, image.Config)
+	// fill in the container config with what the image provides
+	translation, err := translate.Apply(config.Config, image)
+	if err != nil {
+		return types.ContainerCreateResponse{}, InternalServerError(err.Error())
+	}
+	for _, w := range translation.Warnings {
+		log.Warnf("ContainerCreate: %s", w)
+	}
 
 	log.Debugf("config.Config = %+v", config.Config)
 	if err = validateCreateConfig(&config); err != nil {
@@ -249,6 +476,7 @@ func (c *Container) ContainerCreate(config types.ContainerCreateConfig) (types.C
 	if err != nil {
 		return types.ContainerCreateResponse{}, err
 	}
+	container.Annotations = translation.Annotations
 
 	// Create an actualized container in the VIC port layer
 	id, err := c.containerCreate(container, config)
@@ -280,9 +508,7 @@ func (c *Container) containerCreate(vc *viccontainer.VicContainer, config types.
 		return "", InternalServerError("Failed to create container")
 	}
 
-	imageID := vc.ImageID
-
-	id, h, err := c.containerProxy.CreateContainerHandle(imageID, config)
+	id, h, err := c.containerProxy.CreateContainerHandle(vc, config)
 	if err != nil {
 		return "", err
 	}
@@ -328,6 +554,10 @@ func (c *Container) ContainerKill(name string, sig uint64) error {
 		return NotFoundError(name)
 	}
 
+	if err := externalError(vc, name); err != nil {
+		return err
+	}
+
 	err := c.containerProxy.Signal(vc, sig)
 
 	return err
@@ -356,6 +586,10 @@ func (c *Container) ContainerResize(name string, height, width int) error {
 		return NotFoundError(name)
 	}
 
+	if err := externalError(vc, name); err != nil {
+		return err
+	}
+
 	// Call the port layer to resize
 	plHeight := int32(height)
 	plWidth := int32(width)
@@ -378,6 +612,10 @@ func (c *Container) ContainerRestart(name string, seconds int) error {
 		return NotFoundError(name)
 	}
 
+	if err := externalError(vc, name); err != nil {
+		return err
+	}
+
 	err := c.containerProxy.Stop(vc, name, seconds, false)
 	if err != nil {
 		return InternalServerError(fmt.Sprintf("Stop failed with: %s", err))
@@ -396,6 +634,12 @@ func (c *Container) ContainerRestart(name string, seconds int) error {
 // fails. If the remove succeeds, the container name is released, and
 // network links are removed.
 func (c *Container) ContainerRm(name string, config *types.ContainerRmConfig) error {
+	return c.containerRm(name, config, false)
+}
+
+// containerRm removes the container, refusing if it carries the protected label unless
+// forceProtected is set
+func (c *Container) containerRm(name string, config *types.ContainerRmConfig, forceProtected bool) error {
 	defer trace.End(trace.Begin(name))
 
 	// Look up the container name in the metadata cache to get long ID
@@ -405,23 +649,35 @@ func (c *Container) ContainerRm(name string, config *types.ContainerRmConfig) er
 	}
 	id := vc.ContainerID
 
+	if err := externalError(vc, name); err != nil {
+		return err
+	}
+
+	if vc.Config != nil && !forceProtected {
+		if protected, _ := translate.Protected(vc.Config); protected {
+			return derr.NewRequestConflictError(fmt.Errorf("Container %s is protected from removal by the %s label: remove it through the VIC API with force_protected", name, vchconfig.ProtectedLabel))
+		}
+	}
+
 	// Get the portlayer Client API
 	client := c.containerProxy.Client()
 
-	// TODO: Pass this RemoveVolume flag to somewhere
-	_ = &config.RemoveVolume
-
 	// Use the force and stop the container first
 	if config.ForceRemove {
 		c.containerProxy.Stop(vc, name, 0, true)
 	}
 
-	//call the remove directly on the name. No need for using a handle.
-	_, err := client.Containers.ContainerRemove(containers.NewContainerRemoveParamsWithContext(ctx).WithID(id))
+	// call the remove directly on the name. No need for using a handle. With -v the portlayer also
+	// removes the anonymous volumes of the container, named volumes are kept.
+	_, err := client.Containers.ContainerRemove(containers.NewContainerRemoveParamsWithContext(ctx).WithID(id).WithV(&config.RemoveVolume))
 	if err != nil {
 		switch err := err.(type) {
 		case *containers.ContainerRemoveNotFound:
 			cache.ContainerCache().DeleteContainer(id)
+			// the port layer has already removed it on exit
+			if vc.HostConfig != nil && vc.HostConfig.AutoRemove {
+				return nil
+			}
 			return NotFoundError(name)
 		case *containers.ContainerRemoveDefault:
 			return InternalServerError(err.Payload.Message)
@@ -436,6 +692,15 @@ func (c *Container) ContainerRm(name string, config *types.ContainerRmConfig) er
 	return nil
 }
 
+// externalError returns a conflict error if the container is an existing VM listed as an external
+// container, which can only be inspected
+func externalError(vc *viccontainer.VicContainer, name string) error {
+	if !vc.External {
+		return nil
+	}
+	return derr.NewRequestConflictError(fmt.Errorf("Container %s is an external VM and cannot be changed through %s", name, ProductName()))
+}
+
 // cleanupPortBindings gets port bindings for the container and
 // unmaps ports if the cVM that previously bound them isn't powered on
 func (c *Container) cleanupPortBindings(vc *viccontainer.VicContainer) error {
@@ -444,7 +709,7 @@ func (c *Container) cleanupPortBindings(vc *viccontainer.VicContainer) error {
 			hPort := hostPort.HostPort
 
 			cbpLock.Lock()
-			mappedCtr, mapped := containerByPort[hPort]
+			mappedCtr, mapped := containerByPort[mappedPortKey(hPort, ctrPort.Proto())]
 			cbpLock.Unlock()
 			if !mapped {
 				continue
@@ -496,6 +761,10 @@ func (c *Container) containerStart(name string, hostConfig *containertypes.HostC
 	}
 	id := vc.ContainerID
 
+	if err = externalError(vc, name); err != nil {
+		return err
+	}
+
 	// handle legacy hostConfig
 	if hostConfig != nil {
 		// hostConfig exist for backwards compatibility.  TODO: Figure out which parameters we
@@ -611,6 +880,16 @@ type portMapping struct {
 	portProto   nat.Port
 }
 
+// key identifies the host port of the mapping, which can be mapped once for each protocol
+func (p *portMapping) key() string {
+	return mappedPortKey(p.strHostPort, p.portProto.Proto())
+}
+
+// mappedPortKey is the key of a published host port in containerByPort and the saved mappings
+func mappedPortKey(hostPort, proto string) string {
+	return hostPort + "/" + proto
+}
+
 // unrollPortMap processes config for mapping/unmapping ports e.g. from hostconfig.PortBindings
 func unrollPortMap(portMap nat.PortMap) ([]*portMapping, error) {
 	var portMaps []*portMapping
@@ -623,7 +902,8 @@ func unrollPortMap(portMap nat.PortMap) ([]*portMapping, error) {
 		}
 
 		// iterate over all the ports in pb []nat.PortBinding
-		for _, p := range pb {
+		for j := range pb {
+			p := &pb[j]
 			var hostPort int
 			var hPort string
 			if p.HostPort == "" {
@@ -633,7 +913,7 @@ func unrollPortMap(portMap nat.PortMap) ([]*portMapping, error) {
 					log.Errorf("could not find available port on host")
 					return nil, err
 				}
-				// update the hostconfig
+				// update the hostconfig, so that the same port is unmapped later
 				p.HostPort = strconv.Itoa(hostPort)
 
 			} else {
@@ -677,14 +957,24 @@ func MapPorts(hostconfig *containertypes.HostConfig, endpoint *models.EndpointCo
 
 	cbpLock.Lock()
 	defer cbpLock.Unlock()
+	defer savePortMappings()
 	for _, p := range portMap {
-		if err = portMapper.MapPort(nil, p.intHostPort, p.portProto.Proto(), containerIP.String(), p.portProto.Int(), externalIfaceName, bridgeIfaceName); err != nil {
+		b := portmap.Binding{
+			Port:      p.intHostPort,
+			Proto:     p.portProto.Proto(),
+			DestIP:    containerIP.String(),
+			DestPort:  p.portProto.Int(),
+			SrcIface:  externalIfaceName,
+			DestIface: bridgeIfaceName,
+		}
+		if err = b.Map(portMapper); err != nil {
 			return err
 		}
 
 		// update mapped ports
-		containerByPort[p.strHostPort] = containerID
-		log.Debugf("mapped port %s for container %s", p.strHostPort, containerID)
+		containerByPort[p.key()] = containerID
+		savedPortMappings[p.key()] = savedPortMapping{ContainerID: containerID, Binding: b}
+		log.Debugf("mapped port %s for container %s", p.key(), containerID)
 	}
 	return nil
 }
@@ -704,11 +994,12 @@ func UnmapPorts(hostconfig *containertypes.HostConfig) error {
 
 	cbpLock.Lock()
 	defer cbpLock.Unlock()
+	defer savePortMappings()
 	for _, p := range portMap {
 		// check if we should actually unmap based on current mappings
-		_, mapped := containerByPort[p.strHostPort]
+		_, mapped := containerByPort[p.key()]
 		if !mapped {
-			log.Debugf("skipping already unmapped %s", p.strHostPort)
+			log.Debugf("skipping already unmapped %s", p.key())
 			continue
 		}
 
@@ -717,8 +1008,9 @@ func UnmapPorts(hostconfig *containertypes.HostConfig) error {
 		}
 
 		// update mapped ports
-		delete(containerByPort, p.strHostPort)
-		log.Debugf("unmapped port %s", p.strHostPort)
+		delete(containerByPort, p.key())
+		delete(savedPortMappings, p.key())
+		log.Debugf("unmapped port %s", p.key())
 	}
 	return nil
 }
@@ -789,7 +1081,19 @@ func (c *Container) ContainerStop(name string, seconds int) error {
 		return NotFoundError(name)
 	}
 
-	return c.containerProxy.Stop(vc, name, seconds, true)
+	if err := externalError(vc, name); err != nil {
+		return err
+	}
+
+	if err := c.containerProxy.Stop(vc, name, seconds, true); err != nil {
+		return err
+	}
+
+	// the port layer removes auto-remove containers that exit, but not those it stops itself
+	if vc.HostConfig != nil && vc.HostConfig.AutoRemove {
+		return c.containerRm(name, &types.ContainerRmConfig{}, false)
+	}
+	return nil
 }
 
 // ContainerUnpause unpauses a container
@@ -816,6 +1120,10 @@ func (c *Container) ContainerWait(name string, timeout time.Duration) (int, erro
 		return -1, NotFoundError(name)
 	}
 
+	if err := externalError(vc, name); err != nil {
+		return -1, err
+	}
+
 	processExitCode, processStatus, containerState, err := c.containerProxy.Wait(vc, timeout)
 	if err != nil {
 		return -1, err
@@ -953,6 +1261,10 @@ func (c *Container) ContainerLogs(name string, config *backend.ContainerLogsConf
 	if vc == nil {
 		return NotFoundError(name)
 	}
+
+	if err := externalError(vc, name); err != nil {
+		return err
+	}
 	name = vc.ContainerID
 
 	tailLines, since, err := c.validateContainerLogsConfig(vc, config)
@@ -981,16 +1293,116 @@ func (c *Container) ContainerLogs(name string, config *backend.ContainerLogsConf
 // ContainerStats writes information about the container to the stream
 // given in the config object.
 func (c *Container) ContainerStats(name string, config *backend.ContainerStatsConfig) error {
-	return fmt.Errorf("%s does not implement container.ContainerStats", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	vc := cache.ContainerCache().GetContainer(name)
+	if vc == nil {
+		return NotFoundError(name)
+	}
+
+	running, err := c.containerProxy.IsRunning(vc)
+	if err != nil {
+		return err
+	}
+
+	// a container that is not running has empty stats, as docker reports them
+	if !running && !config.Stream {
+		return json.NewEncoder(config.OutStream).Encode(&types.Stats{})
+	}
+
+	out := config.OutStream
+	if config.Stream {
+		wf := ioutils.NewWriteFlusher(out)
+		defer wf.Close()
+		wf.Flush()
+		out = wf
+	}
+
+	if !running {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-config.Stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// the portlayer streams the VM metrics, which are translated as they arrive
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	go func() {
+		pw.CloseWithError(c.containerProxy.StreamContainerStats(ctx, vc.ContainerID, config.Stream, pw))
+	}()
+
+	dec := json.NewDecoder(pr)
+	enc := json.NewEncoder(out)
+
+	var pre types.CPUStats
+	for {
+		var m models.ContainerStats
+		if err := dec.Decode(&m); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		stats := translate.Stats(&m)
+		stats.PreCPUStats = pre
+		pre = stats.CPUStats
+
+		if err := enc.Encode(stats); err != nil {
+			return nil
+		}
+
+		if !config.Stream {
+			return nil
+		}
+	}
 }
 
 // ContainerTop lists the processes running inside of the given
-// container by calling ps with the given args, or with the flags
-// "-ef" if no args are given.  An error is returned if the container
-// is not found, or is not running, or if there are any problems
-// running ps, or parsing the output.
+// container in the columns of ps with the given args, or with the flags
+// "-ef" if no args are given. The processes are listed by the tether in
+// the container VM. An error is returned if the container is not found,
+// or is not running, or if the args are not supported.
 func (c *Container) ContainerTop(name string, psArgs string) (*types.ContainerProcessList, error) {
-	return nil, fmt.Errorf("%s does not implement container.ContainerTop", ProductName())
+	defer trace.End(trace.Begin(name))
+
+	vc := cache.ContainerCache().GetContainer(name)
+	if vc == nil {
+		return nil, NotFoundError(name)
+	}
+
+	if err := externalError(vc, name); err != nil {
+		return nil, err
+	}
+
+	running, err := c.containerProxy.IsRunning(vc)
+	if err != nil {
+		return nil, err
+	}
+	if !running {
+		return nil, derr.NewRequestConflictError(fmt.Errorf("Container %s is not running", name))
+	}
+
+	procs, err := c.containerProxy.Top(vc)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := translate.Top(procs, psArgs, time.Now())
+	if err != nil {
+		return nil, derr.NewBadRequestError(err)
+	}
+	return list, nil
 }
 
 // Containers returns the list of containers to show given the user's filtering.
@@ -1087,6 +1499,10 @@ func (c *Container) ContainerAttach(name string, ca *backend.ContainerAttachConf
 	}
 	id := vc.ContainerID
 
+	if err := externalError(vc, name); err != nil {
+		return err
+	}
+
 	client := c.containerProxy.Client()
 	handle, err := c.Handle(id, name)
 	if err != nil {
@@ -1199,96 +1615,15 @@ func createInternalVicContainer(image *metadata.ImageConfig, config *types.Conta
 	return container, nil
 }
 
-// SetConfigOptions is a place to add necessary container configuration
-// values that were not explicitly supplied by the user
-func setCreateConfigOptions(config, imageConfig *containertypes.Config) {
-	// Overwrite or append the image's config from the CLI with the metadata from the image's
-	// layer metadata where appropriate
-	if len(config.Cmd) == 0 {
-		config.Cmd = imageConfig.Cmd
-	}
-	if config.WorkingDir == "" {
-		config.WorkingDir = imageConfig.WorkingDir
-	}
-	if len(config.Entrypoint) == 0 {
-		config.Entrypoint = imageConfig.Entrypoint
-	}
-
-	if config.Volumes == nil {
-		config.Volumes = imageConfig.Volumes
-	} else {
-		for k, v := range imageConfig.Volumes {
-			//NOTE: the value of the map is an empty struct.
-			//      we also do not care about duplicates.
-			//      This Volumes map is really a Set.
-			config.Volumes[k] = v
-		}
-	}
-
-	// set up environment
-	setEnvFromImageConfig(config, imageConfig)
-}
-
-func setEnvFromImageConfig(config, imageConfig *containertypes.Config) {
-	// Set PATH in ENV if needed
-	setPathFromImageConfig(config, imageConfig)
-
-	containerEnv := make(map[string]string, len(config.Env))
-	for _, env := range config.Env {
-		kv := strings.SplitN(env, "=", 2)
-		var val string
-		if len(kv) == 2 {
-			val = kv[1]
-		}
-		containerEnv[kv[0]] = val
-	}
-
-	// Set TERM to xterm if tty is set, unless user supplied a different TERM
-	if config.Tty {
-		if _, ok := containerEnv["TERM"]; !ok {
-			config.Env = append(config.Env, "TERM=xterm")
-		}
-	}
-
-	// add remaining environment variables from the image config to the container
-	// config, taking care not to overwrite anything
-	for _, imageEnv := range imageConfig.Env {
-		key := strings.SplitN(imageEnv, "=", 2)[0]
-		// is environment variable already set in container config?
-		if _, ok := containerEnv[key]; !ok {
-			// no? let's copy it from the image config
-			config.Env = append(config.Env, imageEnv)
-		}
-	}
-}
-
-func setPathFromImageConfig(config, imageConfig *containertypes.Config) {
-	// check if user supplied PATH environment variable at creation time
-	for _, v := range config.Env {
-		if strings.HasPrefix(v, "PATH=") {
-			// a PATH is set, bail
-			return
-		}
-	}
-
-	// check to see if the image this container is created from supplies a PATH
-	for _, v := range imageConfig.Env {
-		if strings.HasPrefix(v, "PATH=") {
-			// a PATH was found, add it to the config
-			config.Env = append(config.Env, v)
-			return
-		}
-	}
-
-	// no PATH set, use the default
-	config.Env = append(config.Env, fmt.Sprintf("PATH=%s", defaultEnvPath))
-}
-
 // validateCreateConfig() checks the parameters for ContainerCreate().
 // It may "fix up" the config param passed into ConntainerCreate() if needed.
 func validateCreateConfig(config *types.ContainerCreateConfig) error {
 	defer trace.End(trace.Begin("Container.validateCreateConfig"))
 
+	if _, err := translate.Protected(config.Config); err != nil {
+		return derr.NewBadRequestError(err)
+	}
+
 	// process cpucount here
 	var cpuCount int64 = DefaultCPUs
 
@@ -1375,6 +1710,12 @@ func validateCreateConfig(config *types.ContainerCreateConfig) error {
 		}
 	}
 
+	// the appliance forwards the container output to the log driver, which must be one it supports
+	logConfig := executor.LogConfig{Type: config.HostConfig.LogConfig.Type, Config: config.HostConfig.LogConfig.Config}
+	if err := logConfig.Validate(); err != nil {
+		return BadRequestError(err.Error())
+	}
+
 	// TODO(jzt): users other than root are not currently supported
 	// We should check for USER in config.Config.Env once we support Dockerfiles.
 	if config.Config.User != "" && config.Config.User != "root" {
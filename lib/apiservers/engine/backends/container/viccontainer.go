@@ -15,6 +15,7 @@
 package container
 
 import (
+	"github.com/docker/engine-api/types"
 	containertypes "github.com/docker/engine-api/types/container"
 )
 
@@ -25,6 +26,8 @@ type VicContainer struct {
 	ContainerID string
 	Config      *containertypes.Config //Working copy of config (with overrides from container create)
 	HostConfig  *containertypes.HostConfig
+	Annotations map[string]string // Container annotations derived from the image, e.g. its healthcheck
+	External    bool              // Whether this is an existing VM listed as a read-only external container
 }
 
 // NewVicContainer returns a reference to a new VicContainer
@@ -33,3 +36,17 @@ func NewVicContainer() *VicContainer {
 		Config: &containertypes.Config{},
 	}
 }
+
+// VicExec is an additional process created in a running container, as docker exec does
+type VicExec struct {
+	ID          string
+	ContainerID string
+	Config      types.ExecConfig
+
+	// an exec may only be started once
+	Started bool
+
+	// the state of the process as last reported by the portlayer
+	Running  bool
+	ExitCode int
+}
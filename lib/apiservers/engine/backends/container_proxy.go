@@ -36,8 +36,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -59,12 +62,12 @@ import (
 	"github.com/docker/engine-api/types"
 	"github.com/docker/engine-api/types/container"
 	dnetwork "github.com/docker/engine-api/types/network"
-	"github.com/docker/engine-api/types/strslice"
 	"github.com/docker/go-connections/nat"
 
 	"github.com/vmware/vic/lib/apiservers/engine/backends/cache"
 	viccontainer "github.com/vmware/vic/lib/apiservers/engine/backends/container"
 	epoint "github.com/vmware/vic/lib/apiservers/engine/backends/endpoint"
+	"github.com/vmware/vic/lib/apiservers/engine/backends/translate"
 	"github.com/vmware/vic/lib/apiservers/portlayer/client"
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/containers"
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/interaction"
@@ -72,6 +75,7 @@ import (
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/scopes"
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/storage"
 	"github.com/vmware/vic/lib/apiservers/portlayer/models"
+	vchconfig "github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/metadata"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/sys"
@@ -79,13 +83,22 @@ import (
 
 // VicContainerProxy interface
 type VicContainerProxy interface {
-	CreateContainerHandle(imageID string, config types.ContainerCreateConfig) (string, string, error)
+	CreateContainerHandle(vc *viccontainer.VicContainer, config types.ContainerCreateConfig) (string, string, error)
 	AddContainerToScope(handle string, config types.ContainerCreateConfig) (string, error)
 	AddVolumesToContainer(handle string, config types.ContainerCreateConfig) (string, error)
 	AddLoggingToContainer(handle string, config types.ContainerCreateConfig) (string, error)
 	AddInteractionToContainer(handle string, config types.ContainerCreateConfig) (string, error)
 	CommitContainerHandle(handle, containerID string, waitTime int32) error
 	StreamContainerLogs(name string, out io.Writer, started chan struct{}, showTimestamps bool, followLogs bool, since int64, tailLines int64) error
+	StreamContainerStats(ctx context.Context, name string, stream bool, out io.Writer) error
+
+	LaunchTask(vc *viccontainer.VicContainer, exec *viccontainer.VicExec, attach bool) error
+	TaskStatus(vc *viccontainer.VicContainer, execID string) (running bool, exitCode int, err error)
+	Top(vc *viccontainer.VicContainer) ([]translate.Process, error)
+
+	StatPath(name, path string) (*types.ContainerPathStat, error)
+	ArchivePath(name, path string, out io.Writer) error
+	ExtractToDir(name, path string, noOverwriteDirNonDir bool, content io.Reader) error
 
 	Stop(vc *viccontainer.VicContainer, name string, seconds int, unbound bool) error
 	IsRunning(vc *viccontainer.VicContainer) (bool, error)
@@ -106,9 +119,10 @@ type ContainerProxy struct {
 }
 
 type volumeFields struct {
-	ID    string
-	Dest  string
-	Flags string
+	ID        string
+	Dest      string
+	Flags     string
+	Anonymous bool
 }
 
 const (
@@ -122,6 +136,7 @@ const (
 	forceLogType                         = "json-file" //Use in inspect to allow docker logs to work
 	annotationKeyLabels                  = "docker.labels"
 	killWaitForExit        time.Duration = 2 * time.Second
+	attachReplayKB         int64         = 64               //recent output replayed to attaches that request logs, all the portlayer keeps
 	killWaitBeforeForce    time.Duration = 10 * time.Second //Time to wait for signal to take effect before attempting force using Stop()
 	ShortIDLen                           = 12
 
@@ -167,13 +182,14 @@ func (c *ContainerProxy) Client() *client.PortLayer {
 //
 // returns:
 //	(containerID, containerHandle, error)
-func (c *ContainerProxy) CreateContainerHandle(imageID string, config types.ContainerCreateConfig) (string, string, error) {
-	defer trace.End(trace.Begin(imageID))
+func (c *ContainerProxy) CreateContainerHandle(vc *viccontainer.VicContainer, config types.ContainerCreateConfig) (string, string, error) {
+	defer trace.End(trace.Begin(vc.ImageID))
 
 	if c.client == nil {
 		return "", "", InternalServerError("ContainerProxy.CreateContainerHandle failed to create a portlayer client")
 	}
 
+	imageID := vc.ImageID
 	if imageID == "" {
 		return "", "", NotFoundError("No image specified")
 	}
@@ -184,7 +200,11 @@ func (c *ContainerProxy) CreateContainerHandle(imageID string, config types.Cont
 		return "", "", InternalServerError("ContainerProxy.CreateContainerHandle got unexpected error getting VCH UUID")
 	}
 
-	plCreateParams := dockerContainerCreateParamsToPortlayer(config, imageID, host)
+	plCreateParams, err := dockerContainerCreateParamsToPortlayer(config, vc.Annotations, imageID, host)
+	if err != nil {
+		return "", "", BadRequestError(err.Error())
+	}
+
 	createResults, err := c.client.Containers.Create(plCreateParams)
 	if err != nil {
 		if _, ok := err.(*containers.CreateNotFound); ok {
@@ -311,6 +331,9 @@ func (c *ContainerProxy) AddVolumesToContainer(handle string, config types.Conta
 		flags := make(map[string]string)
 		//NOTE: for now we are passing the flags directly through. This is NOT SAFE and only a stop gap.
 		flags["Mode"] = fields.Flags
+		if fields.Anonymous {
+			flags["Anonymous"] = "true"
+		}
 		joinParams := storage.NewVolumeJoinParamsWithContext(ctx).WithJoinArgs(&models.VolumeJoinConfig{
 			Flags:     flags,
 			Handle:    handle,
@@ -415,7 +438,7 @@ func (c *ContainerProxy) CommitContainerHandle(handle, containerID string, waitT
 		case *containers.CommitNotFound:
 			return NotFoundError(containerID)
 		case *containers.CommitConflict:
-			return ConflictError(err.Error())
+			return ConflictError(err.Payload.Message)
 		case *containers.CommitDefault:
 			return InternalServerError(err.Payload.Message)
 		default:
@@ -462,6 +485,240 @@ func (c *ContainerProxy) StreamContainerLogs(name string, out io.Writer, started
 	return nil
 }
 
+// StreamContainerStats writes the stream of container VM metrics from the portlayer to out, until
+// ctx is done or the container stops. Only the first metrics are written if stream is false.
+func (c *ContainerProxy) StreamContainerStats(ctx context.Context, name string, stream bool, out io.Writer) error {
+	defer trace.End(trace.Begin(name))
+
+	plClient, transport := c.createNewAttachClientWithTimeouts(attachConnectTimeout, 0, attachAttemptTimeout)
+	defer transport.Close()
+
+	params := containers.NewGetContainerStatsParamsWithContext(ctx).
+		WithID(name).
+		WithStream(&stream)
+	_, err := plClient.Containers.GetContainerStats(params, out)
+	if err != nil {
+		switch err := err.(type) {
+		case *containers.GetContainerStatsNotFound:
+			return NotFoundError(fmt.Sprintf("No such container: %s", name))
+		case *containers.GetContainerStatsConflict:
+			// the container stopped
+			return nil
+		case *containers.GetContainerStatsInternalServerError:
+			return InternalServerError(err.Payload.Message)
+		default:
+			if ctx.Err() != nil || strings.Contains(err.Error(), swaggerSubstringEOF) {
+				return nil
+			}
+			return InternalServerError(fmt.Sprintf("Unknown error from the port layer: %s", err))
+		}
+	}
+
+	return nil
+}
+
+// StatPath returns the stat of the path in the filesystem of the container
+func (c *ContainerProxy) StatPath(name, path string) (*types.ContainerPathStat, error) {
+	defer trace.End(trace.Begin(name))
+
+	if c.client == nil {
+		return nil, InternalServerError("ContainerProxy.StatPath failed to get a portlayer client")
+	}
+
+	resp, err := c.client.Interaction.StatContainerPath(interaction.NewStatContainerPathParamsWithContext(ctx).WithID(name).WithPath(path))
+	if err != nil {
+		switch err := err.(type) {
+		case *interaction.StatContainerPathNotFound:
+			return nil, NotFoundError(err.Payload.Message)
+		case *interaction.StatContainerPathConflict:
+			return nil, ConflictError(err.Payload.Message)
+		case *interaction.StatContainerPathInternalServerError:
+			return nil, InternalServerError(err.Payload.Message)
+		default:
+			return nil, InternalServerError(err.Error())
+		}
+	}
+
+	st := resp.Payload
+	return &types.ContainerPathStat{
+		Name:       st.Name,
+		Size:       st.Size,
+		Mode:       os.FileMode(st.Mode),
+		Mtime:      time.Unix(0, st.Mtime),
+		LinkTarget: st.LinkTarget,
+	}, nil
+}
+
+// ArchivePath writes a tar archive of the path in the filesystem of the container to out
+func (c *ContainerProxy) ArchivePath(name, path string, out io.Writer) error {
+	defer trace.End(trace.Begin(name))
+
+	// the archive may take a while to stream so there is no response timeout
+	plClient, transport := c.createNewAttachClientWithTimeouts(attachConnectTimeout, 0, attachAttemptTimeout)
+	defer transport.Close()
+
+	_, err := plClient.Interaction.GetContainerArchive(interaction.NewGetContainerArchiveParamsWithContext(ctx).WithID(name).WithPath(path), out)
+	if err != nil {
+		switch err := err.(type) {
+		case *interaction.GetContainerArchiveNotFound:
+			return NotFoundError(err.Payload.Message)
+		case *interaction.GetContainerArchiveConflict:
+			return ConflictError(err.Payload.Message)
+		case *interaction.GetContainerArchiveInternalServerError:
+			return InternalServerError(err.Payload.Message)
+		default:
+			if strings.Contains(err.Error(), swaggerSubstringEOF) {
+				return nil
+			}
+			return InternalServerError(err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ExtractToDir extracts the tar archive content to the directory path in the filesystem of the container
+func (c *ContainerProxy) ExtractToDir(name, path string, noOverwriteDirNonDir bool, content io.Reader) error {
+	defer trace.End(trace.Begin(name))
+
+	plClient, transport := c.createNewAttachClientWithTimeouts(attachConnectTimeout, 0, attachAttemptTimeout)
+	defer transport.Close()
+
+	params := interaction.NewPutContainerArchiveParamsWithContext(ctx).
+		WithID(name).
+		WithPath(path).
+		WithNoOverwriteDirNonDir(&noOverwriteDirNonDir).
+		WithArchive(ioutil.NopCloser(content))
+
+	_, err := plClient.Interaction.PutContainerArchive(params)
+	if err != nil {
+		switch err := err.(type) {
+		case *interaction.PutContainerArchiveBadRequest:
+			return BadRequestError(err.Payload.Message)
+		case *interaction.PutContainerArchiveNotFound:
+			return NotFoundError(err.Payload.Message)
+		case *interaction.PutContainerArchiveConflict:
+			return ConflictError(err.Payload.Message)
+		case *interaction.PutContainerArchiveInternalServerError:
+			return InternalServerError(err.Payload.Message)
+		default:
+			return InternalServerError(err.Error())
+		}
+	}
+
+	return nil
+}
+
+// LaunchTask launches the process of the exec instance in the running container. A process that
+// is attached to is not started until its streams are connected with AttachStreams.
+func (c *ContainerProxy) LaunchTask(vc *viccontainer.VicContainer, exec *viccontainer.VicExec, attach bool) error {
+	defer trace.End(trace.Begin(exec.ID))
+
+	if c.client == nil {
+		return InternalServerError("ContainerProxy.LaunchTask failed to get a portlayer client")
+	}
+
+	config := &models.TaskLaunchConfig{
+		ID:         exec.ID,
+		Cmd:        exec.Config.Cmd,
+		Env:        vc.Config.Env,
+		WorkingDir: swag.String(vc.Config.WorkingDir),
+		User:       swag.String(exec.Config.User),
+		Tty:        swag.Bool(exec.Config.Tty),
+		Attach:     swag.Bool(attach),
+	}
+
+	_, err := c.client.Interaction.TaskLaunch(interaction.NewTaskLaunchParamsWithContext(ctx).WithID(vc.ContainerID).WithConfig(config))
+	if err != nil {
+		switch err := err.(type) {
+		case *interaction.TaskLaunchNotFound:
+			return ConflictError(err.Payload.Message)
+		case *interaction.TaskLaunchNotImplemented:
+			return derr.NewErrorWithStatusCode(fmt.Errorf("Container %s does not support exec, it must be recreated to do so", vc.ContainerID),
+				http.StatusNotImplemented)
+		case *interaction.TaskLaunchInternalServerError:
+			return InternalServerError(err.Payload.Message)
+		default:
+			return InternalServerError(err.Error())
+		}
+	}
+
+	return nil
+}
+
+// TaskStatus returns whether the process of the exec instance is running and its exit code once
+// it has exited. An error is returned if the process could not be launched.
+func (c *ContainerProxy) TaskStatus(vc *viccontainer.VicContainer, execID string) (bool, int, error) {
+	defer trace.End(trace.Begin(execID))
+
+	if c.client == nil {
+		return false, 0, InternalServerError("ContainerProxy.TaskStatus failed to get a portlayer client")
+	}
+
+	resp, err := c.client.Interaction.TaskInspect(interaction.NewTaskInspectParamsWithContext(ctx).WithID(vc.ContainerID).WithTaskID(execID))
+	if err != nil {
+		switch err := err.(type) {
+		case *interaction.TaskInspectNotFound:
+			return false, 0, NotFoundError(err.Payload.Message)
+		case *interaction.TaskInspectInternalServerError:
+			return false, 0, InternalServerError(err.Payload.Message)
+		default:
+			return false, 0, InternalServerError(err.Error())
+		}
+	}
+
+	info := resp.Payload
+	if started := swag.StringValue(info.Started); started != "" && started != "true" {
+		return false, 0, InternalServerError(started)
+	}
+
+	return swag.BoolValue(info.Running), int(swag.Int32Value(info.ExitCode)), nil
+}
+
+// Top returns the processes running in the container
+func (c *ContainerProxy) Top(vc *viccontainer.VicContainer) ([]translate.Process, error) {
+	defer trace.End(trace.Begin(vc.ContainerID))
+
+	if c.client == nil {
+		return nil, InternalServerError("ContainerProxy.Top failed to get a portlayer client")
+	}
+
+	resp, err := c.client.Interaction.ContainerTop(interaction.NewContainerTopParamsWithContext(ctx).WithID(vc.ContainerID))
+	if err != nil {
+		switch err := err.(type) {
+		case *interaction.ContainerTopNotFound:
+			return nil, ConflictError(err.Payload.Message)
+		case *interaction.ContainerTopNotImplemented:
+			return nil, derr.NewErrorWithStatusCode(fmt.Errorf("Container %s does not support top, it must be recreated to do so", vc.ContainerID),
+				http.StatusNotImplemented)
+		case *interaction.ContainerTopInternalServerError:
+			return nil, InternalServerError(err.Payload.Message)
+		default:
+			return nil, InternalServerError(err.Error())
+		}
+	}
+
+	procs := make([]translate.Process, len(resp.Payload))
+	for i, p := range resp.Payload {
+		procs[i] = translate.Process{
+			PID:       p.Pid,
+			PPID:      p.Ppid,
+			User:      p.User,
+			StartTime: time.Unix(p.StartTime, 0),
+			CPUTime:   time.Duration(p.CPUTime) * time.Millisecond,
+			CPU:       int(p.CPU),
+			Mem:       p.Mem,
+			VSZ:       p.Vsz,
+			RSS:       p.Rss,
+			State:     p.State,
+			TTY:       p.Tty,
+			Cmd:       p.Cmd,
+		}
+	}
+
+	return procs, nil
+}
+
 // Stop will stop (shutdown) a VIC container.
 //
 // returns
@@ -708,6 +965,13 @@ func (c *ContainerProxy) AttachStreams(ctx context.Context, vc *viccontainer.Vic
 	var wg sync.WaitGroup
 	errors := make(chan error, 3)
 
+	// an attach that asks for logs, e.g. to reattach after a network interruption, is sent the recent
+	// output kept by the portlayer before the live output
+	var replay *int64
+	if ca.Logs {
+		replay = swag.Int64(attachReplayKB)
+	}
+
 	// For stdin, we only have a timeout for connection.  There can be a long duration before
 	// the first entry so there is no timeout for response.
 	plClient, transport := createNewAttachClientWithTimeouts(attachConnectTimeout, 0, attachAttemptTimeout)
@@ -740,7 +1004,7 @@ func (c *ContainerProxy) AttachStreams(ctx context.Context, vc *viccontainer.Vic
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := copyStdOut(ctx, plClient, attachAttemptTimeout, vc, clStdout)
+			err := copyStdOut(ctx, plClient, attachAttemptTimeout, vc, clStdout, replay)
 			if err != nil {
 				log.Errorf("container attach: stdout (%s): %s", vc.ContainerID, err.Error())
 			} else {
@@ -763,7 +1027,7 @@ func (c *ContainerProxy) AttachStreams(ctx context.Context, vc *viccontainer.Vic
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := copyStdErr(ctx, plClient, vc, clStderr)
+			err := copyStdErr(ctx, plClient, vc, clStderr, replay)
 			if err != nil {
 				log.Errorf("container attach: stderr (%s): %s", vc.ContainerID, err.Error())
 			} else {
@@ -816,7 +1080,7 @@ func (c *ContainerProxy) AttachStreams(ctx context.Context, vc *viccontainer.Vic
 // Utility Functions
 //----------
 
-func dockerContainerCreateParamsToPortlayer(cc types.ContainerCreateConfig, layerID string, imageStore string) *containers.CreateParams {
+func dockerContainerCreateParamsToPortlayer(cc types.ContainerCreateConfig, annotations map[string]string, layerID string, imageStore string) (*containers.CreateParams, error) {
 	config := &models.ContainerCreateConfig{}
 
 	config.NumCpus = &cc.HostConfig.CPUCount
@@ -828,30 +1092,21 @@ func dockerContainerCreateParamsToPortlayer(cc types.ContainerCreateConfig, laye
 	// Repo Requested
 	config.RepoName = swag.String(cc.Config.Image)
 
-	var path string
-	var args []string
-
-	// Expand cmd into entrypoint and args
-	cmd := strslice.StrSlice(cc.Config.Cmd)
-	if len(cc.Config.Entrypoint) != 0 {
-		path, args = cc.Config.Entrypoint[0], append(cc.Config.Entrypoint[1:], cmd...)
-	} else {
-		path, args = cmd[0], cmd[1:]
+	// the session the container config translates to
+	session, err := translate.Session("", cc.Config)
+	if err != nil {
+		return nil, err
 	}
 
 	//copy friendly name
 	config.Name = swag.String(cc.Name)
 
-	// copy the path
-	config.Path = swag.String(path)
-
-	// copy the args
-	config.Args = make([]string, len(args))
-	copy(config.Args, args)
+	// copy the path and args
+	config.Path = swag.String(session.Cmd.Path)
+	config.Args = session.Cmd.Args[1:]
 
 	// copy the env array
-	config.Env = make([]string, len(cc.Config.Env))
-	copy(config.Env, cc.Config.Env)
+	config.Env = session.Cmd.Env
 
 	// image store
 	config.ImageStore = &models.ImageStore{Name: imageStore}
@@ -860,23 +1115,49 @@ func dockerContainerCreateParamsToPortlayer(cc types.ContainerCreateConfig, laye
 	config.NetworkDisabled = swag.Bool(cc.Config.NetworkDisabled)
 
 	// working dir
-	config.WorkingDir = swag.String(cc.Config.WorkingDir)
+	config.WorkingDir = swag.String(session.Cmd.Dir)
 
 	// attach
-	config.Attach = swag.Bool(cc.Config.AttachStdin || cc.Config.AttachStdout || cc.Config.AttachStderr)
+	config.Attach = swag.Bool(session.Attach)
 
 	// tty
-	config.Tty = swag.Bool(cc.Config.Tty)
+	config.Tty = swag.Bool(session.Tty)
 
 	// container stop signal
-	config.StopSignal = swag.String(cc.Config.StopSignal)
+	config.StopSignal = swag.String(session.StopSignal)
+
+	// how long the container process may run each time it is started
+	if session.Deadline > 0 {
+		config.Deadline = swag.Int64(session.Deadline)
+	}
+
+	// log driver that the appliance forwards the container output to
+	if cc.HostConfig.LogConfig.Type != "" {
+		config.LogConfig = &models.LogConfig{
+			Type:   swag.String(cc.HostConfig.LogConfig.Type),
+			Config: cc.HostConfig.LogConfig.Config,
+		}
+	}
+
+	// remove the container once it exits, whether or not the client is still connected
+	config.AutoRemove = swag.Bool(cc.HostConfig.AutoRemove)
 
 	// Stuff the Docker labels into VIC container annotations
 	annotationsFromLabels(config, cc.Config.Labels)
 
+	// along with those derived from the image
+	if len(annotations) > 0 {
+		if config.Annotations == nil {
+			config.Annotations = make(map[string]string)
+		}
+		for k, v := range annotations {
+			config.Annotations[k] = v
+		}
+	}
+
 	log.Debugf("dockerContainerCreateParamsToPortlayer = %+v", config)
 
-	return containers.NewCreateParamsWithContext(ctx).WithCreateConfig(config)
+	return containers.NewCreateParamsWithContext(ctx).WithCreateConfig(config), nil
 }
 
 func toModelsNetworkConfig(cc types.ContainerCreateConfig) *models.NetworkConfig {
@@ -961,6 +1242,7 @@ func processVolumeParam(volString string) (volumeFields, error) {
 		fields.ID = VolumeID.String()
 		fields.Dest = volumeStrings[0]
 		fields.Flags = "rw"
+		fields.Anonymous = true
 	case 2:
 		fields.ID = volumeStrings[0]
 		fields.Dest = volumeStrings[1]
@@ -1040,6 +1322,9 @@ func ContainerInfoToDockerContainerInspect(vc *viccontainer.VicContainer, info *
 		if info.ProcessConfig.ExitCode != nil {
 			containerState.ExitCode = int(*info.ProcessConfig.ExitCode)
 		}
+		if info.ProcessConfig.OOMKilled != nil {
+			containerState.OOMKilled = *info.ProcessConfig.OOMKilled
+		}
 		if info.ProcessConfig.ErrorMsg != nil {
 			containerState.Error = *info.ProcessConfig.ErrorMsg
 		}
@@ -1292,6 +1577,25 @@ func containerConfigFromContainerInfo(vc *viccontainer.VicContainer, info *model
 	// Pull labels from the annotation
 	labelsFromAnnotations(&container, info.ContainerConfig.Annotations)
 
+	// mark external containers so they can be told apart, e.g. with a label filter on docker ps
+	if info.ContainerConfig.Annotations[vchconfig.ExternalAnnotation] == "true" {
+		if container.Labels == nil {
+			container.Labels = make(map[string]string)
+		}
+		container.Labels[vchconfig.ExternalLabel] = "true"
+	}
+
+	// report the tether version so that containers booted from an older bootstrap image can be found
+	if info.ContainerConfig.TetherVersion != nil {
+		if container.Labels == nil {
+			container.Labels = make(map[string]string)
+		}
+		container.Labels[vchconfig.TetherVersionLabel] = *info.ContainerConfig.TetherVersion
+		if info.ContainerConfig.TetherOutdated != nil {
+			container.Labels[vchconfig.TetherOutdatedLabel] = strconv.FormatBool(*info.ContainerConfig.TetherOutdated)
+		}
+	}
+
 	return &container
 }
 
@@ -1435,6 +1739,7 @@ func ContainerInfoToVicContainer(info models.ContainerInfo) *viccontainer.VicCon
 	tempVC.HostConfig = &container.HostConfig{}
 	vc.Config = containerConfigFromContainerInfo(tempVC, &info)
 	vc.HostConfig = hostConfigFromContainerInfo(tempVC, &info, PortLayerName())
+	vc.External = info.ContainerConfig.Annotations[vchconfig.ExternalAnnotation] == "true"
 	return vc
 }
 
@@ -1575,7 +1880,7 @@ func copyStdIn(ctx context.Context, pl *client.PortLayer, vc *viccontainer.VicCo
 	return err
 }
 
-func copyStdOut(ctx context.Context, pl *client.PortLayer, attemptTimeout time.Duration, vc *viccontainer.VicContainer, clStdout io.Writer) error {
+func copyStdOut(ctx context.Context, pl *client.PortLayer, attemptTimeout time.Duration, vc *viccontainer.VicContainer, clStdout io.Writer, replay *int64) error {
 	id := vc.ContainerID
 	//Calculate how much time to let portlayer attempt
 	plAttemptTimeout := attemptTimeout - attachPLAttemptDiff //assumes personality deadline longer than portlayer's deadline
@@ -1585,7 +1890,7 @@ func copyStdOut(ctx context.Context, pl *client.PortLayer, attemptTimeout time.D
 	log.Debugf("* stdout personality deadline: %s", time.Now().Add(attemptTimeout).Format(time.UnixDate))
 
 	log.Debugf("* stdout attach start %s", time.Now().Format(time.UnixDate))
-	getStdoutParams := interaction.NewContainerGetStdoutParamsWithContext(ctx).WithID(id).WithDeadline(&swaggerDeadline)
+	getStdoutParams := interaction.NewContainerGetStdoutParamsWithContext(ctx).WithID(id).WithDeadline(&swaggerDeadline).WithReplay(replay)
 	_, err := pl.Interaction.ContainerGetStdout(getStdoutParams, clStdout)
 	log.Debugf("* stdout attach end %s", time.Now().Format(time.UnixDate))
 	if err != nil {
@@ -1599,9 +1904,9 @@ func copyStdOut(ctx context.Context, pl *client.PortLayer, attemptTimeout time.D
 	return nil
 }
 
-func copyStdErr(ctx context.Context, pl *client.PortLayer, vc *viccontainer.VicContainer, clStderr io.Writer) error {
+func copyStdErr(ctx context.Context, pl *client.PortLayer, vc *viccontainer.VicContainer, clStderr io.Writer, replay *int64) error {
 	name := vc.ContainerID
-	getStderrParams := interaction.NewContainerGetStderrParamsWithContext(ctx).WithID(name)
+	getStderrParams := interaction.NewContainerGetStderrParamsWithContext(ctx).WithID(name).WithReplay(replay)
 
 	_, err := pl.Interaction.ContainerGetStderr(getStderrParams, clStderr)
 	if err != nil {
@@ -37,6 +37,7 @@ import (
 
 	"github.com/vmware/vic/lib/apiservers/engine/backends/cache"
 	viccontainer "github.com/vmware/vic/lib/apiservers/engine/backends/container"
+	"github.com/vmware/vic/lib/apiservers/engine/backends/translate"
 	plclient "github.com/vmware/vic/lib/apiservers/portlayer/client"
 	plscopes "github.com/vmware/vic/lib/apiservers/portlayer/client/scopes"
 	plmodels "github.com/vmware/vic/lib/apiservers/portlayer/models"
@@ -202,7 +203,7 @@ func (m *MockContainerProxy) Handle(id, name string) (string, error) {
 	return "", nil
 }
 
-func (m *MockContainerProxy) CreateContainerHandle(imageID string, config types.ContainerCreateConfig) (string, string, error) {
+func (m *MockContainerProxy) CreateContainerHandle(vc *viccontainer.VicContainer, config types.ContainerCreateConfig) (string, string, error) {
 	respIdx := m.mockRespIndices[0]
 
 	if respIdx >= len(m.mockCreateHandleData) {
@@ -265,6 +266,10 @@ func (m *MockContainerProxy) Client() *plclient.PortLayer {
 	return nil
 }
 
+func (m *MockContainerProxy) StreamContainerStats(ctx context.Context, name string, stream bool, out io.Writer) error {
+	return nil
+}
+
 func (m *MockContainerProxy) StreamContainerLogs(name string, out io.Writer, started chan struct{}, showTimestamps bool, followLogs bool, since int64, tailLines int64) error {
 	var lineCount int64 = 10
 
@@ -284,6 +289,30 @@ func (m *MockContainerProxy) StreamContainerLogs(name string, out io.Writer, sta
 	return nil
 }
 
+func (m *MockContainerProxy) LaunchTask(vc *viccontainer.VicContainer, exec *viccontainer.VicExec, attach bool) error {
+	return nil
+}
+
+func (m *MockContainerProxy) TaskStatus(vc *viccontainer.VicContainer, execID string) (bool, int, error) {
+	return false, 0, nil
+}
+
+func (m *MockContainerProxy) Top(vc *viccontainer.VicContainer) ([]translate.Process, error) {
+	return nil, nil
+}
+
+func (m *MockContainerProxy) StatPath(name, path string) (*types.ContainerPathStat, error) {
+	return nil, nil
+}
+
+func (m *MockContainerProxy) ArchivePath(name, path string, out io.Writer) error {
+	return nil
+}
+
+func (m *MockContainerProxy) ExtractToDir(name, path string, noOverwriteDirNonDir bool, content io.Reader) error {
+	return nil
+}
+
 func (m *MockContainerProxy) Stop(vc *viccontainer.VicContainer, name string, seconds int, unbound bool) error {
 	return nil
 }
@@ -664,3 +693,30 @@ func TestPortInformation(t *testing.T) {
 	ports = portInformation(mockContainerInfo, ips)
 	assert.Equal(t, len(ports), 2, "Expected 2 port binding, found %d", len(ports))
 }
+
+func TestUnrollPortMap(t *testing.T) {
+	tcp, _ := nat.NewPort("tcp", "53")
+	udp, _ := nat.NewPort("udp", "53")
+	web, _ := nat.NewPort("tcp", "80")
+
+	portMap := nat.PortMap{
+		tcp: []nat.PortBinding{{HostPort: "5353"}},
+		udp: []nat.PortBinding{{HostPort: "5353"}},
+		web: []nat.PortBinding{{}},
+	}
+
+	mappings, err := unrollPortMap(portMap)
+	assert.NoError(t, err)
+	assert.Len(t, mappings, 3)
+
+	keys := make(map[string]bool)
+	for _, m := range mappings {
+		keys[m.key()] = true
+	}
+	assert.True(t, keys["5353/tcp"])
+	assert.True(t, keys["5353/udp"])
+
+	// the random host port is recorded so that the same port is unmapped
+	assert.NotEmpty(t, portMap[web][0].HostPort)
+	assert.True(t, keys[portMap[web][0].HostPort+"/tcp"])
+}
@@ -58,6 +58,11 @@ func VolumeNotFoundError(msg string) error {
 	return derr.NewErrorWithStatusCode(fmt.Errorf("No such volume: %s", msg), http.StatusNotFound)
 }
 
+// ExecNotFoundError returns a 404 docker error when an exec instance is not found.
+func ExecNotFoundError(id string) error {
+	return derr.NewRequestNotFoundError(fmt.Errorf("No such exec instance '%s' found in daemon", id))
+}
+
 func ResourceNotFoundError(cid, res string) error {
 	return derr.NewRequestNotFoundError(fmt.Errorf("No such %s for container: %s", res, cid))
 }
@@ -209,6 +209,8 @@ func (i *Image) PullImage(ctx context.Context, ref reference.Named, metaHeaders
 		Reference:   ref.String(),
 		Timeout:     imagec.DefaultHTTPTimeout,
 		Outstream:   outStream,
+		RegistryCAs: RegistryCertPool(),
+		Proxies:     RegistryProxies(),
 	}
 
 	if authConfig != nil {
@@ -17,6 +17,8 @@ package backends
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 
 	"net/http"
@@ -31,6 +33,7 @@ import (
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/containers"
 	"github.com/vmware/vic/lib/apiservers/portlayer/client/scopes"
 	"github.com/vmware/vic/lib/apiservers/portlayer/models"
+	"github.com/vmware/vic/lib/config/executor"
 )
 
 type Network struct {
@@ -107,7 +110,24 @@ func (n *Network) GetAllNetworks() []libnetwork.Network {
 
 func (n *Network) CreateNetwork(name, driver string, ipam apinet.IPAM, options map[string]string, labels map[string]string, internal bool, enableIPv6 bool) (libnetwork.Network, error) {
 	if len(ipam.Config) > 1 {
-		return nil, fmt.Errorf("at most one ipam config supported")
+		return nil, derr.NewBadRequestError(fmt.Errorf("at most one ipam config supported"))
+	}
+
+	if driver == "" {
+		driver = "bridge"
+	}
+
+	// external networks are those of the VCH configuration, so only bridge networks can be created
+	if driver != "bridge" {
+		return nil, derr.NewBadRequestError(fmt.Errorf("network driver %s is not supported, only bridge networks can be created", driver))
+	}
+
+	if internal {
+		return nil, derr.NewBadRequestError(fmt.Errorf("internal networks are not supported"))
+	}
+
+	if enableIPv6 {
+		return nil, derr.NewBadRequestError(fmt.Errorf("IPv6 networks are not supported"))
 	}
 
 	var gateway, subnet *string
@@ -128,8 +148,9 @@ func (n *Network) CreateNetwork(name, driver string, ipam apinet.IPAM, options m
 		}
 	}
 
-	if driver == "" {
-		driver = "bridge"
+	firewall, err := firewallPolicy(options)
+	if err != nil {
+		return nil, derr.NewBadRequestError(err)
 	}
 
 	cfg := &models.ScopeConfig{
@@ -138,6 +159,7 @@ func (n *Network) CreateNetwork(name, driver string, ipam apinet.IPAM, options m
 		ScopeType: driver,
 		Subnet:    subnet,
 		IPAM:      pools,
+		Firewall:  firewall,
 	}
 
 	created, err := PortLayerClient().Scopes.CreateScope(scopes.NewCreateScopeParamsWithContext(ctx).WithConfig(cfg))
@@ -147,7 +169,7 @@ func (n *Network) CreateNetwork(name, driver string, ipam apinet.IPAM, options m
 			return nil, derr.NewErrorWithStatusCode(fmt.Errorf("network %s already exists", name), http.StatusConflict)
 
 		case *scopes.CreateScopeDefault:
-			return nil, derr.NewErrorWithStatusCode(fmt.Errorf(err.Payload.Message), http.StatusInternalServerError)
+			return nil, derr.NewErrorWithStatusCode(fmt.Errorf(err.Payload.Message), err.Code())
 
 		default:
 			return nil, derr.NewErrorWithStatusCode(err, http.StatusInternalServerError)
@@ -160,6 +182,9 @@ func (n *Network) CreateNetwork(name, driver string, ipam apinet.IPAM, options m
 func (n *Network) ConnectContainerToNetwork(containerName, networkName string, endpointConfig *apinet.EndpointSettings) error {
 	vc := cache.ContainerCache().GetContainer(containerName)
 	if vc != nil {
+		if err := externalError(vc, containerName); err != nil {
+			return err
+		}
 		containerName = vc.ContainerID
 	}
 
@@ -289,6 +314,9 @@ func (n *Network) DeleteNetwork(name string) error {
 		case *scopes.DeleteScopeNotFound:
 			return derr.NewRequestNotFoundError(fmt.Errorf("network %s not found", name))
 
+		case *scopes.DeleteScopeForbidden:
+			return derr.NewErrorWithStatusCode(fmt.Errorf("error while removing network %s: %s", name, err.Payload.Message), http.StatusForbidden)
+
 		case *scopes.DeleteScopeInternalServerError:
 			return derr.NewErrorWithStatusCode(fmt.Errorf(err.Payload.Message), http.StatusInternalServerError)
 
@@ -404,35 +432,104 @@ func (n *network) IpamConfig() (string, map[string]string, []*libnetwork.IpamCon
 	return "", make(map[string]string), confs, nil
 }
 
+// IpamInfo reports the subnet of the network as its address pool, the IP ranges containers are
+// assigned addresses from being reported by IpamConfig
 func (n *network) IpamInfo() ([]*libnetwork.IpamInfo, []*libnetwork.IpamInfo) {
 	n.Lock()
 	defer n.Unlock()
 
-	var infos []*libnetwork.IpamInfo
-	for _, i := range n.cfg.IPAM {
-		_, pool, err := net.ParseCIDR(i)
-		if err != nil {
+	if n.cfg.Subnet == nil || *n.cfg.Subnet == "" {
+		return nil, nil
+	}
+
+	_, pool, err := net.ParseCIDR(*n.cfg.Subnet)
+	if err != nil {
+		return nil, nil
+	}
+
+	info := &libnetwork.IpamInfo{
+		Meta: make(map[string]string),
+	}
+
+	info.Pool = pool
+	if n.cfg.Gateway != nil && *n.cfg.Gateway != "" {
+		info.Gateway = &net.IPNet{IP: net.ParseIP(*n.cfg.Gateway), Mask: pool.Mask}
+	}
+
+	info.AuxAddresses = make(map[string]*net.IPNet)
+	return []*libnetwork.IpamInfo{info}, nil
+}
+
+// driver options describing the vSphere network backing a network
+const (
+	backingNetworkOption = "com.vmware.vic.backing.network"
+	backingSwitchOption  = "com.vmware.vic.backing.switch"
+	backingVLANOption    = "com.vmware.vic.backing.vlan"
+	backingMorefOption   = "com.vmware.vic.backing.moref"
+)
+
+// driver options holding the comma separated firewall rules of a network
+const (
+	firewallIngressOption = "com.vmware.vic.firewall.ingress"
+	firewallEgressOption  = "com.vmware.vic.firewall.egress"
+)
+
+// firewallPolicy returns the firewall rules given in the driver options of docker network create,
+// or nil if there are none
+func firewallPolicy(options map[string]string) (*models.FirewallPolicy, error) {
+	p := &models.FirewallPolicy{}
+	for opt, rules := range map[string]*[]string{firewallIngressOption: &p.Ingress, firewallEgressOption: &p.Egress} {
+		v, ok := options[opt]
+		if !ok {
 			continue
 		}
 
-		info := &libnetwork.IpamInfo{
-			Meta: make(map[string]string),
+		parsed, err := executor.ParseFirewallRules(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s option: %s", opt, err)
 		}
 
-		info.Pool = pool
-		if n.cfg.Gateway != nil {
-			info.Gateway = &net.IPNet{IP: net.ParseIP(*n.cfg.Gateway), Mask: net.CIDRMask(32, 32)}
+		for _, r := range parsed {
+			*rules = append(*rules, r.String())
 		}
-
-		info.AuxAddresses = make(map[string]*net.IPNet)
-		infos = append(infos, info)
 	}
 
-	return infos, nil
+	if len(p.Ingress) == 0 && len(p.Egress) == 0 {
+		return nil, nil
+	}
+	return p, nil
 }
 
+// DriverOptions reports the firewall rules of the network and the vSphere network backing it, so
+// that docker networks can be correlated with vSphere networking
 func (n *network) DriverOptions() map[string]string {
-	return make(map[string]string)
+	opts := make(map[string]string)
+
+	if f := n.cfg.Firewall; f != nil {
+		if len(f.Ingress) > 0 {
+			opts[firewallIngressOption] = strings.Join(f.Ingress, ", ")
+		}
+		if len(f.Egress) > 0 {
+			opts[firewallEgressOption] = strings.Join(f.Egress, ", ")
+		}
+	}
+
+	b := n.cfg.Backing
+	if b == nil {
+		return opts
+	}
+
+	opts[backingMorefOption] = b.Moref
+	if b.Name != nil && *b.Name != "" {
+		opts[backingNetworkOption] = *b.Name
+	}
+	if b.Switch != nil && *b.Switch != "" {
+		opts[backingSwitchOption] = *b.Switch
+	}
+	if b.VlanID != nil {
+		opts[backingVLANOption] = strconv.Itoa(int(*b.VlanID))
+	}
+	return opts
 }
 
 func (n *network) Scope() string {
@@ -0,0 +1,135 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backends
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/lib/apiservers/engine/backends/kv"
+	"github.com/vmware/vic/lib/apiservers/engine/backends/portmap"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// portMappingsKey is the k/v store key holding the published ports, which are
+// mapped again when the appliance reboots or the personality restarts
+const portMappingsKey = "portmappings"
+
+// savedPortMapping is a published port as saved in the k/v store
+type savedPortMapping struct {
+	ContainerID string          `json:"container"`
+	Binding     portmap.Binding `json:"binding"`
+}
+
+// savedPortMappings holds the published ports by host port, guarded by cbpLock
+var savedPortMappings = make(map[string]savedPortMapping)
+
+// loadPortMappings reads the published ports from the k/v store
+func loadPortMappings() error {
+	defer trace.End(trace.Begin(""))
+
+	val, err := kv.Get(PortLayerClient(), portMappingsKey)
+	if err != nil && err != kv.ErrKeyNotFound {
+		return err
+	}
+
+	cbpLock.Lock()
+	defer cbpLock.Unlock()
+
+	savedPortMappings = make(map[string]savedPortMapping)
+	if val == "" {
+		return nil
+	}
+
+	if err = json.Unmarshal([]byte(val), &savedPortMappings); err != nil {
+		return fmt.Errorf("Failed to unmarshal port mappings: %s", err)
+	}
+
+	// mappings saved before they were keyed by protocol as well as host port
+	for port, m := range savedPortMappings {
+		if !strings.Contains(port, "/") {
+			delete(savedPortMappings, port)
+			savedPortMappings[mappedPortKey(port, m.Binding.Proto)] = m
+		}
+	}
+	return nil
+}
+
+// savePortMappings writes the published ports to the k/v store. The caller must hold cbpLock.
+func savePortMappings() {
+	b, err := json.Marshal(savedPortMappings)
+	if err != nil {
+		log.Errorf("Unable to marshal port mappings: %s", err)
+		return
+	}
+
+	// the mapping is in place either way, it just won't survive a restart
+	if err = kv.Put(PortLayerClient(), portMappingsKey, string(b)); err != nil {
+		log.Errorf("Unable to save port mappings: %s", err)
+	}
+}
+
+// restorePortMappings maps the saved ports of the container again, checking that
+// the rules are in place afterwards. It returns false if none were saved.
+func restorePortMappings(containerID string) (bool, error) {
+	defer trace.End(trace.Begin(containerID))
+
+	cbpLock.Lock()
+	defer cbpLock.Unlock()
+
+	restored := false
+	for port, m := range savedPortMappings {
+		if m.ContainerID != containerID {
+			continue
+		}
+		restored = true
+
+		if err := m.Binding.Map(portMapper); err != nil {
+			return true, fmt.Errorf("Failed to restore port mapping %s for container %s: %s", port, containerID, err)
+		}
+
+		if err := portMapper.Verify(m.Binding.IP, m.Binding.Port, m.Binding.Proto); err != nil {
+			return true, fmt.Errorf("Port mapping %s for container %s failed self-test: %s", port, containerID, err)
+		}
+
+		containerByPort[port] = containerID
+		log.Infof("Restored port mapping %s for container %s", port, containerID)
+	}
+
+	return restored, nil
+}
+
+// prunePortMappings drops the saved ports that were not mapped again, which belong
+// to containers that are gone or no longer running
+func prunePortMappings() {
+	cbpLock.Lock()
+	defer cbpLock.Unlock()
+
+	pruned := false
+	for port, m := range savedPortMappings {
+		if containerByPort[port] != m.ContainerID {
+			log.Debugf("Dropping saved port mapping %s for container %s", port, m.ContainerID)
+			delete(savedPortMappings, port)
+			pruned = true
+		}
+	}
+
+	if pruned {
+		savePortMappings()
+	}
+}
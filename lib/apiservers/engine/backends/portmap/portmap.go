@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 
 	log "github.com/Sirupsen/logrus"
@@ -31,14 +32,41 @@ const (
 	Unmap
 )
 
+// check tests whether a rule is present
+const check iptables.Action = "-C"
+
+// iptablesRaw runs iptables, replaced in tests
+var iptablesRaw = iptables.Raw
+
 type PortMapper interface {
 	MapPort(ip net.IP, port int, proto string, destIP string, destPort int, srcIface, destIface string) error
 	UnmapPort(ip net.IP, port int, proto string, destPort int, srcIface, destIface string) error
+
+	// Verify checks that the rules for a mapped port are present in iptables
+	Verify(ip net.IP, port int, proto string) error
+}
+
+// Binding describes a port mapping, so that it can be saved and mapped again
+// once the rules are lost, e.g. after the appliance reboots
+type Binding struct {
+	IP        net.IP `json:"ip,omitempty"`
+	Port      int    `json:"port"`
+	Proto     string `json:"proto"`
+	DestIP    string `json:"dest_ip"`
+	DestPort  int    `json:"dest_port"`
+	SrcIface  string `json:"src_iface"`
+	DestIface string `json:"dest_iface"`
+}
+
+// Map maps the port described by the binding
+func (b *Binding) Map(p PortMapper) error {
+	return p.MapPort(b.IP, b.Port, b.Proto, b.DestIP, b.DestPort, b.SrcIface, b.DestIface)
 }
 
 type bindKey struct {
-	ip   string
-	port int
+	ip    string
+	port  int
+	proto string
 }
 
 type portMapper struct {
@@ -57,11 +85,22 @@ func (p *portMapper) isPortAvailable(proto string, ip net.IP, port int) bool {
 		addr = ip.String()
 	}
 
-	if _, ok := p.bindings[bindKey{addr, port}]; ok {
+	if _, ok := p.bindings[bindKey{addr, port, proto}]; ok {
 		return false
 	}
 
-	c, err := net.Dial(proto, net.JoinHostPort(addr, strconv.Itoa(port)))
+	hostPort := net.JoinHostPort(addr, strconv.Itoa(port))
+	if proto == "udp" {
+		// dialing udp succeeds whether or not anything is listening, so check that the port can be bound instead
+		c, err := net.ListenPacket(proto, hostPort)
+		if err != nil {
+			return false
+		}
+		c.Close()
+		return true
+	}
+
+	c, err := net.Dial(proto, hostPort)
 	defer func() {
 		if c != nil {
 			c.Close()
@@ -112,10 +151,36 @@ func (p *portMapper) UnmapPort(ip net.IP, port int, proto string, destPort int,
 	return p.forward(iptables.Delete, ip, port, proto, "", destPort, srcIface, destIface)
 }
 
+func (p *portMapper) Verify(ip net.IP, port int, proto string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	ipStr := ""
+	if ip != nil && !ip.IsUnspecified() {
+		ipStr = ip.String()
+	}
+
+	args, ok := p.bindings[bindKey{ipStr, port, proto}]
+	if !ok {
+		return fmt.Errorf("port %d/%s is not mapped", port, proto)
+	}
+
+	var missing []string
+	for _, cmd := range args {
+		if err := iptablesRunAndCheck(check, cmd); err != nil {
+			missing = append(missing, strings.Join(cmd, " "))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("iptables rules missing for port %d: %s", port, missing)
+	}
+	return nil
+}
+
 // iptablesRunAndCheck runs an iptables command with the provided args
 func iptablesRunAndCheck(action iptables.Action, args []string) error {
 	args = append([]string{string(action)}, args...)
-	if output, err := iptables.Raw(args...); err != nil {
+	if output, err := iptablesRaw(args...); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return iptables.ChainError{Chain: "FORWARD", Output: output}
@@ -123,6 +188,16 @@ func iptablesRunAndCheck(action iptables.Action, args []string) error {
 	return nil
 }
 
+// iptablesAppend appends the rule unless it is already present, as it is when
+// the ports of a running container are mapped again after a restart
+func iptablesAppend(args []string) error {
+	if err := iptablesRunAndCheck(check, args); err == nil {
+		log.Debugf("iptables rule already present: %s", args)
+		return nil
+	}
+	return iptablesRunAndCheck(iptables.Append, args)
+}
+
 // iptablesDelete takes the saved args from the Append operation
 // and uses them to delete the previously added rules
 func iptablesDelete(args [][]string) error {
@@ -152,7 +227,7 @@ func (p *portMapper) forward(action iptables.Action, ip net.IP, port int, proto,
 		ipStr = ip.String()
 	}
 
-	key := bindKey{ip: ipStr, port: port}
+	key := bindKey{ip: ipStr, port: port, proto: proto}
 	switch action {
 	case iptables.Delete:
 		// lookup commands to reverse
@@ -160,10 +235,10 @@ func (p *portMapper) forward(action iptables.Action, ip net.IP, port int, proto,
 			if err := iptablesDelete(args); err != nil {
 				return err
 			}
-			delete(p.bindings, bindKey{ipStr, port})
+			delete(p.bindings, key)
 			return nil
 		}
-		return fmt.Errorf("Failed to find unmap data for %s:%d", ipStr, port)
+		return fmt.Errorf("Failed to find unmap data for %s:%d/%s", ipStr, port, proto)
 
 	case iptables.Append:
 		var savedArgs [][]string
@@ -175,7 +250,7 @@ func (p *portMapper) forward(action iptables.Action, ip net.IP, port int, proto,
 			"--dport", strconv.Itoa(port),
 			"-j", "DNAT",
 			"--to-destination", net.JoinHostPort(destAddr, strconv.Itoa(destPort))}
-		if err := iptablesRunAndCheck(action, args); err != nil {
+		if err := iptablesAppend(args); err != nil {
 			return err
 		}
 		savedArgs = append(savedArgs, args)
@@ -190,7 +265,7 @@ func (p *portMapper) forward(action iptables.Action, ip net.IP, port int, proto,
 			"--to-destination", net.JoinHostPort(destAddr, strconv.Itoa(destPort)),
 			"-m", "addrtype",
 			"--dst-type", "LOCAL"}
-		if err := iptablesRunAndCheck(action, args); err != nil {
+		if err := iptablesAppend(args); err != nil {
 			return err
 		}
 		savedArgs = append(savedArgs, args)
@@ -203,7 +278,7 @@ func (p *portMapper) forward(action iptables.Action, ip net.IP, port int, proto,
 			"-d", destAddr,
 			"--dport", strconv.Itoa(destPort),
 			"-j", "ACCEPT"}
-		if err := iptablesRunAndCheck(action, args); err != nil {
+		if err := iptablesAppend(args); err != nil {
 			return err
 		}
 		savedArgs = append(savedArgs, args)
@@ -214,7 +289,7 @@ func (p *portMapper) forward(action iptables.Action, ip net.IP, port int, proto,
 			"-d", destAddr,
 			"--dport", strconv.Itoa(destPort),
 			"-j", "MASQUERADE"}
-		if err := iptablesRunAndCheck(action, args); err != nil {
+		if err := iptablesAppend(args); err != nil {
 			return err
 		}
 		savedArgs = append(savedArgs, args)
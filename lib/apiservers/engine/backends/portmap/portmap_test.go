@@ -0,0 +1,151 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portmap
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIptables keeps the rules in memory
+type fakeIptables struct {
+	rules   map[string]int
+	appends int
+}
+
+func (f *fakeIptables) raw(args ...string) ([]byte, error) {
+	rule := strings.Join(args[1:], " ")
+
+	switch args[0] {
+	case "-A":
+		f.rules[rule]++
+		f.appends++
+	case "-D":
+		if f.rules[rule] == 0 {
+			return nil, errors.New("no such rule")
+		}
+		f.rules[rule]--
+	case "-C":
+		if f.rules[rule] == 0 {
+			return nil, errors.New("no such rule")
+		}
+	}
+	return nil, nil
+}
+
+func withFakeIptables() *fakeIptables {
+	f := &fakeIptables{rules: make(map[string]int)}
+	iptablesRaw = f.raw
+	return f
+}
+
+// freePort returns a port nothing is listening on
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestMapVerifyUnmap(t *testing.T) {
+	f := withFakeIptables()
+
+	b := &Binding{
+		Port:      freePort(t),
+		Proto:     "tcp",
+		DestIP:    "172.16.0.2",
+		DestPort:  80,
+		SrcIface:  "external",
+		DestIface: "bridge",
+	}
+
+	p := NewPortMapper()
+	assert.Error(t, p.Verify(nil, b.Port, b.Proto), "port not mapped")
+
+	require.NoError(t, b.Map(p))
+	assert.Equal(t, 4, f.appends)
+	assert.NoError(t, p.Verify(nil, b.Port, b.Proto))
+	assert.Error(t, b.Map(p), "port already mapped")
+
+	// rules lost, e.g. flushed by hand
+	for k := range f.rules {
+		delete(f.rules, k)
+		break
+	}
+	assert.Error(t, p.Verify(nil, b.Port, b.Proto))
+}
+
+func TestMapAfterRestart(t *testing.T) {
+	f := withFakeIptables()
+
+	b := &Binding{
+		Port:      freePort(t),
+		Proto:     "tcp",
+		DestIP:    "172.16.0.2",
+		DestPort:  80,
+		SrcIface:  "external",
+		DestIface: "bridge",
+	}
+
+	require.NoError(t, b.Map(NewPortMapper()))
+
+	// a new port mapper, as after a restart, adopts the rules still present
+	p := NewPortMapper()
+	require.NoError(t, b.Map(p))
+	assert.Equal(t, 4, f.appends)
+	for _, n := range f.rules {
+		assert.Equal(t, 1, n)
+	}
+	assert.NoError(t, p.Verify(nil, b.Port, b.Proto))
+
+	require.NoError(t, p.UnmapPort(nil, b.Port, b.Proto, b.DestPort, b.SrcIface, b.DestIface))
+	for _, n := range f.rules {
+		assert.Equal(t, 0, n)
+	}
+}
+
+func TestMapTCPAndUDP(t *testing.T) {
+	f := withFakeIptables()
+
+	port := freePort(t)
+	tcp := &Binding{
+		Port:      port,
+		Proto:     "tcp",
+		DestIP:    "172.16.0.2",
+		DestPort:  53,
+		SrcIface:  "external",
+		DestIface: "bridge",
+	}
+	udp := *tcp
+	udp.Proto = "udp"
+
+	p := NewPortMapper()
+	require.NoError(t, tcp.Map(p))
+	require.NoError(t, udp.Map(p), "the same host port can be mapped for each protocol")
+	assert.Equal(t, 8, f.appends)
+	assert.NoError(t, p.Verify(nil, port, "tcp"))
+	assert.NoError(t, p.Verify(nil, port, "udp"))
+	assert.Error(t, udp.Map(p), "port already mapped")
+
+	require.NoError(t, p.UnmapPort(nil, port, "udp", udp.DestPort, udp.SrcIface, udp.DestIface))
+	assert.Error(t, p.Verify(nil, port, "udp"))
+	assert.NoError(t, p.Verify(nil, port, "tcp"))
+}
@@ -0,0 +1,228 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prefetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/reference"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/pkg/dio"
+)
+
+// Batch pulls images concurrently, writing the progress of all of them to one stream. An image
+// that is already being pulled is not pulled again, the pull in progress is joined instead.
+type Batch struct {
+	pull Puller
+
+	// slots limits the number of pulls that run at once
+	slots chan struct{}
+
+	mu      sync.Mutex
+	pulling map[string]*pullOp
+}
+
+// pullOp is a pull in progress, with the progress streams of everything waiting for it
+type pullOp struct {
+	done chan struct{}
+	err  error
+	out  dio.DynamicMultiWriter
+}
+
+// NewBatch returns a Batch that runs at most concurrency pulls at once
+func NewBatch(pull Puller, concurrency int) *Batch {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &Batch{
+		pull:    pull,
+		slots:   make(chan struct{}, concurrency),
+		pulling: make(map[string]*pullOp),
+	}
+}
+
+// Pull pulls the images and waits for all of them, writing the docker JSON progress messages of the
+// pulls to out followed by a status message with the image as ID. The references are checked before
+// anything is pulled and an image named more than once is pulled once. The returned error names
+// the images that failed.
+func (b *Batch) Pull(ctx context.Context, images []string, out io.Writer) error {
+	refs, err := ParseReferences(images)
+	if err != nil {
+		return err
+	}
+
+	w := &syncWriter{w: out}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if seen[ref.String()] {
+			continue
+		}
+		seen[ref.String()] = true
+
+		wg.Add(1)
+		go func(ref reference.Named) {
+			defer wg.Done()
+
+			name := ref.String()
+			status := "Pull complete"
+			if err := b.Fetch(ctx, ref, w); err != nil {
+				log.Warnf("Failed to pull image %s: %s", name, err)
+				status = fmt.Sprintf("Pull failed: %s", err)
+
+				mu.Lock()
+				failed = append(failed, name)
+				mu.Unlock()
+			}
+
+			w.message(jsonmessage.JSONMessage{ID: name, Status: status})
+		}(ref)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to pull %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Fetch pulls the image, or waits for the pull of it already in progress, writing the progress to out.
+// It has the signature of a Puller, so that other pulls can share the pulls of the batch.
+func (b *Batch) Fetch(ctx context.Context, ref reference.Named, out io.Writer) error {
+	name := ref.String()
+
+	b.mu.Lock()
+	op, ok := b.pulling[name]
+	if !ok {
+		op = &pullOp{
+			done: make(chan struct{}),
+			out:  dio.MultiWriter(out),
+		}
+		b.pulling[name] = op
+
+		go b.run(ref, op)
+	} else {
+		op.out.Add(out)
+	}
+	b.mu.Unlock()
+
+	defer op.out.Remove(out)
+
+	select {
+	case <-op.done:
+		return op.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait waits for the pull of the image if one is in progress. It returns false without waiting
+// if the image is not being pulled.
+func (b *Batch) Wait(ctx context.Context, image string) (bool, error) {
+	ref, err := reference.ParseNamed(image)
+	if err != nil {
+		// image IDs are not pulled
+		return false, nil
+	}
+
+	b.mu.Lock()
+	op, ok := b.pulling[reference.WithDefaultTag(ref).String()]
+	b.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	select {
+	case <-op.done:
+		return true, op.err
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// run pulls the image once a slot is free. The pull is not tied to the context of whoever
+// started it, as others may be waiting for it.
+func (b *Batch) run(ref reference.Named, op *pullOp) {
+	name := ref.String()
+
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		writeMessage(op.out, jsonmessage.JSONMessage{ID: name, Status: "Waiting"})
+		b.slots <- struct{}{}
+	}
+
+	log.Infof("Pulling image %s", name)
+	op.err = b.pull(context.Background(), ref, op.out)
+
+	<-b.slots
+
+	b.mu.Lock()
+	delete(b.pulling, name)
+	b.mu.Unlock()
+
+	close(op.done)
+}
+
+// ParseReferences parses the image references, adding the default tag to those without one
+func ParseReferences(images []string) ([]reference.Named, error) {
+	refs := make([]reference.Named, 0, len(images))
+	for _, image := range images {
+		ref, err := reference.ParseNamed(image)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image reference %q: %s", image, err)
+		}
+		refs = append(refs, reference.WithDefaultTag(ref))
+	}
+	return refs, nil
+}
+
+func writeMessage(w io.Writer, msg jsonmessage.JSONMessage) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	w.Write(append(b, '\r', '\n'))
+}
+
+// syncWriter serializes the writes of concurrent pulls to a stream, so their messages are not interleaved
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.w.Write(b)
+}
+
+func (s *syncWriter) message(msg jsonmessage.JSONMessage) {
+	writeMessage(s, msg)
+}
@@ -0,0 +1,220 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prefetch pulls images into the image store ahead of use, so that the first
+// container created from a commonly used image does not wait for the pull.
+package prefetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/reference"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/apiservers/engine/router/vic"
+)
+
+// Puller pulls an image into the image store, writing docker JSON progress messages to out
+type Puller func(ctx context.Context, ref reference.Named, out io.Writer) error
+
+// Prefetcher pulls the requested images one at a time and keeps the status of each
+type Prefetcher struct {
+	mu sync.Mutex
+
+	pull Puller
+
+	// images are the normalized references in the order they were first requested
+	images  []string
+	status  map[string]*vic.PrefetchStatus
+	pending []reference.Named
+
+	wake chan struct{}
+}
+
+func New(pull Puller) *Prefetcher {
+	return &Prefetcher{
+		pull:   pull,
+		status: make(map[string]*vic.PrefetchStatus),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Start pulls the queued images until ctx is done
+func (p *Prefetcher) Start(ctx context.Context) {
+	go func() {
+		for {
+			ref := p.next()
+			if ref == nil {
+				select {
+				case <-p.wake:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			p.fetch(ctx, ref)
+		}
+	}()
+}
+
+// Schedule queues the images now and again each interval until ctx is done, refreshing
+// tags that have moved since. The images are queued only once if interval is zero.
+func (p *Prefetcher) Schedule(ctx context.Context, images []string, interval time.Duration) error {
+	if _, err := p.Add(images); err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		return nil
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// the references were validated by the first Add
+				p.Add(images)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Add queues the images for pulling and returns the status of all requested images.
+// Images that are already queued or being pulled are not queued again.
+func (p *Prefetcher) Add(images []string) ([]vic.PrefetchStatus, error) {
+	refs, err := ParseReferences(images)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	for _, ref := range refs {
+		name := ref.String()
+
+		s, ok := p.status[name]
+		if !ok {
+			s = &vic.PrefetchStatus{Image: name}
+			p.status[name] = s
+			p.images = append(p.images, name)
+		}
+
+		if s.State == vic.PrefetchQueued || s.State == vic.PrefetchPulling {
+			continue
+		}
+
+		s.State = vic.PrefetchQueued
+		s.Progress = ""
+		s.Error = ""
+		p.pending = append(p.pending, ref)
+	}
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+
+	return p.Status(), nil
+}
+
+// Status returns the status of all requested images, in the order they were first requested
+func (p *Prefetcher) Status() []vic.PrefetchStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := make([]vic.PrefetchStatus, 0, len(p.images))
+	for _, name := range p.images {
+		status = append(status, *p.status[name])
+	}
+	return status
+}
+
+func (p *Prefetcher) next() reference.Named {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.pending) == 0 {
+		return nil
+	}
+
+	ref := p.pending[0]
+	p.pending = p.pending[1:]
+	p.status[ref.String()].State = vic.PrefetchPulling
+	return ref
+}
+
+func (p *Prefetcher) fetch(ctx context.Context, ref reference.Named) {
+	name := ref.String()
+	log.Infof("Prefetching image %s", name)
+
+	err := p.pull(ctx, ref, &progress{p: p, image: name})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.status[name]
+	if err != nil {
+		log.Warnf("Failed to prefetch image %s: %s", name, err)
+		s.State = vic.PrefetchFailed
+		s.Error = err.Error()
+		return
+	}
+
+	now := time.Now().UTC()
+	s.State = vic.PrefetchComplete
+	s.Completed = &now
+}
+
+// progress records the last message of the docker JSON stream of a pull as its progress
+type progress struct {
+	p     *Prefetcher
+	image string
+}
+
+func (w *progress) Write(b []byte) (int, error) {
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		var msg jsonmessage.JSONMessage
+		if err := json.Unmarshal(line, &msg); err != nil || msg.Status == "" {
+			continue
+		}
+
+		text := msg.Status
+		if msg.ID != "" {
+			text = msg.ID + ": " + text
+		}
+		if msg.ProgressMessage != "" {
+			text += " " + msg.ProgressMessage
+		}
+
+		w.p.mu.Lock()
+		w.p.status[w.image].Progress = text
+		w.p.mu.Unlock()
+	}
+
+	return len(b), nil
+}
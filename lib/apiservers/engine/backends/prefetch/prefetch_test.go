@@ -0,0 +1,210 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prefetch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/reference"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/apiservers/engine/router/vic"
+)
+
+// waitFor polls the status until all images are no longer queued or being pulled
+func waitFor(t *testing.T, p *Prefetcher) []vic.PrefetchStatus {
+	for i := 0; i < 100; i++ {
+		done := true
+		status := p.Status()
+		for _, s := range status {
+			if s.State == vic.PrefetchQueued || s.State == vic.PrefetchPulling {
+				done = false
+			}
+		}
+		if done {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("prefetch did not complete: %#v", p.Status())
+	return nil
+}
+
+func TestPrefetch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pulled []string
+	p := New(func(ctx context.Context, ref reference.Named, out io.Writer) error {
+		pulled = append(pulled, ref.String())
+		if ref.Name() == "missing" {
+			return errors.New("not found")
+		}
+		fmt.Fprintf(out, "{\"status\":\"Pull complete\",\"id\":\"%s\"}\r\n", "8ddc19f16526")
+		return nil
+	})
+
+	status, err := p.Add([]string{"busybox", "nginx:1.11", "missing"})
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	assert.Equal(t, "busybox:latest", status[0].Image)
+	assert.Equal(t, vic.PrefetchQueued, status[0].State)
+
+	p.Start(ctx)
+	status = waitFor(t, p)
+
+	assert.Equal(t, []string{"busybox:latest", "nginx:1.11", "missing:latest"}, pulled)
+
+	assert.Equal(t, vic.PrefetchComplete, status[1].State)
+	assert.Equal(t, "8ddc19f16526: Pull complete", status[1].Progress)
+	assert.NotNil(t, status[1].Completed)
+
+	assert.Equal(t, vic.PrefetchFailed, status[2].State)
+	assert.Equal(t, "not found", status[2].Error)
+
+	// requesting an image again pulls it again, keeping its place in the status
+	status, err = p.Add([]string{"missing"})
+	require.NoError(t, err)
+	assert.Equal(t, vic.PrefetchQueued, status[2].State)
+	assert.Empty(t, status[2].Error)
+
+	waitFor(t, p)
+	assert.Len(t, pulled, 4)
+}
+
+func TestPrefetchInvalidReference(t *testing.T) {
+	p := New(nil)
+
+	_, err := p.Add([]string{"busybox", "Invalid:Reference:"})
+	assert.Error(t, err)
+
+	// nothing is queued when any reference is invalid
+	assert.Empty(t, p.Status())
+}
+
+func TestPrefetchSchedule(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pulls := make(chan string, 10)
+	p := New(func(ctx context.Context, ref reference.Named, out io.Writer) error {
+		pulls <- ref.String()
+		return nil
+	})
+	p.Start(ctx)
+
+	require.NoError(t, p.Schedule(ctx, []string{"busybox"}, 20*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case image := <-pulls:
+			assert.Equal(t, "busybox:latest", image)
+		case <-time.After(time.Second):
+			t.Fatalf("image was not pulled again")
+		}
+	}
+}
+
+func TestBatchPull(t *testing.T) {
+	var mu sync.Mutex
+	pulls := make(map[string]int)
+
+	b := NewBatch(func(ctx context.Context, ref reference.Named, out io.Writer) error {
+		mu.Lock()
+		pulls[ref.String()]++
+		mu.Unlock()
+
+		if ref.Name() == "missing" {
+			return errors.New("not found")
+		}
+		fmt.Fprintf(out, "{\"status\":\"Pull complete\",\"id\":\"%s\"}\r\n", "8ddc19f16526")
+		return nil
+	}, 2)
+
+	var out bytes.Buffer
+	err := b.Pull(context.Background(), []string{"busybox", "nginx:1.11", "busybox:latest", "missing"}, &out)
+	assert.EqualError(t, err, "failed to pull missing:latest")
+
+	// an image named twice is pulled once
+	assert.Equal(t, map[string]int{"busybox:latest": 1, "nginx:1.11": 1, "missing:latest": 1}, pulls)
+
+	assert.Contains(t, out.String(), `{"status":"Pull complete","id":"busybox:latest"}`)
+	assert.Contains(t, out.String(), `{"status":"Pull failed: not found","id":"missing:latest"}`)
+	assert.Equal(t, 2, bytes.Count(out.Bytes(), []byte("8ddc19f16526")))
+}
+
+func TestBatchPullInvalidReference(t *testing.T) {
+	b := NewBatch(nil, 1)
+
+	var out bytes.Buffer
+	err := b.Pull(context.Background(), []string{"busybox", "Invalid:Reference:"}, &out)
+	assert.Error(t, err)
+	assert.Empty(t, out.String())
+}
+
+func TestBatchJoin(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pulls := 0
+
+	b := NewBatch(func(ctx context.Context, ref reference.Named, out io.Writer) error {
+		pulls++
+		close(started)
+		<-release
+		return nil
+	}, 1)
+
+	ok, err := b.Wait(context.Background(), "busybox")
+	assert.False(t, ok)
+	assert.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- b.Pull(context.Background(), []string{"busybox"}, ioutil.Discard)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// a create waits for the pull in progress
+	ok, err = b.Wait(ctx, "busybox:latest")
+	assert.True(t, ok)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// and a prefetch of the same image joins it rather than pulling again
+	ref, err := reference.ParseNamed("busybox")
+	require.NoError(t, err)
+	assert.Equal(t, context.DeadlineExceeded, b.Fetch(ctx, reference.WithDefaultTag(ref), ioutil.Discard))
+
+	// waiters that give up do not affect the pull
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 1, pulls)
+
+	ok, err = b.Wait(context.Background(), "busybox")
+	assert.False(t, ok)
+	assert.NoError(t, err)
+}
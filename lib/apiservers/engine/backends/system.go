@@ -53,6 +53,9 @@ import (
 
 type System struct {
 	systemProxy VicSystemProxy
+
+	// releases caches the latest release published at the release URL of the VCH
+	releases version.ReleaseChecker
 }
 
 const (
@@ -61,8 +64,10 @@ const (
 	systemOS           = " VMware OS"
 	systemOSVersion    = " VMware OS version"
 	systemProductName  = " VMware Product"
+	systemRelease      = " VIC release"
 	volumeStoresID     = "VolumeStores"
 	loginTimeout       = 20 * time.Second
+	releaseTimeout     = 5 * time.Second
 )
 
 func NewSystemBackend() *System {
@@ -188,6 +193,16 @@ func (s *System) SystemInfo() (*types.Info, error) {
 		}
 	}
 
+	// Report whether a newer release is available, if the VCH has a release URL
+	if releaseURL := VchConfig().ReleaseURL; releaseURL.Host != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		status := s.releases.Status(ctx, &releaseURL, version.GetBuild())
+		cancel()
+
+		customInfo := [2]string{systemRelease, status}
+		info.SystemStatus = append(info.SystemStatus, customInfo)
+	}
+
 	return info, nil
 }
 
@@ -250,6 +265,8 @@ func (s *System) AuthenticateToRegistry(ctx context.Context, authConfig *types.A
 		Timeout:  loginTimeout,
 		Username: authConfig.Username,
 		Password: authConfig.Password,
+		RootCAs:  RegistryCertPool(),
+		Proxies:  RegistryProxies(),
 	})
 
 	// Only look at V2 registries
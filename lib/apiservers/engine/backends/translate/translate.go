@@ -0,0 +1,428 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package translate maps image metadata onto the configuration of a container created from
+// that image, and that configuration onto the executor session that runs it. It also maps
+// the metrics of container VMs onto docker stats, and their processes onto docker top.
+package translate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/engine-api/types"
+	containertypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/vmware/vic/lib/apiservers/portlayer/models"
+	vchconfig "github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/metadata"
+)
+
+const (
+	// DefaultEnvPath is the PATH used if neither the container nor the image provide one
+	DefaultEnvPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+	// HealthcheckAnnotation is the annotation holding the image healthcheck, base64 encoded JSON
+	HealthcheckAnnotation = "docker.healthcheck"
+)
+
+// Result holds what the image contributes to the container beyond its config
+type Result struct {
+	// Annotations to store with the container
+	Annotations map[string]string
+	// Warnings about image config that has no effect
+	Warnings []string
+}
+
+// Apply merges the image config into the container config. Values supplied for the container take
+// precedence over those from the image; maps and the environment are merged.
+func Apply(config *containertypes.Config, image *metadata.ImageConfig) (*Result, error) {
+	res := &Result{
+		Annotations: make(map[string]string),
+	}
+
+	var ic containertypes.Config
+	if image != nil && image.Config != nil {
+		ic = *image.Config
+	}
+
+	if len(config.Cmd) == 0 {
+		config.Cmd = ic.Cmd
+	}
+	if len(config.Entrypoint) == 0 {
+		config.Entrypoint = ic.Entrypoint
+	}
+	if config.WorkingDir == "" {
+		config.WorkingDir = ic.WorkingDir
+	}
+	if config.User == "" {
+		config.User = ic.User
+	}
+	if config.StopSignal == "" {
+		config.StopSignal = ic.StopSignal
+	}
+
+	config.Env = Env(config.Env, ic.Env, config.Tty)
+
+	// Volumes and ExposedPorts are sets, so duplicates are of no concern
+	if len(ic.Volumes) > 0 {
+		if config.Volumes == nil {
+			config.Volumes = make(map[string]struct{}, len(ic.Volumes))
+		}
+		for k, v := range ic.Volumes {
+			config.Volumes[k] = v
+		}
+	}
+
+	if len(ic.ExposedPorts) > 0 {
+		if config.ExposedPorts == nil {
+			config.ExposedPorts = make(map[nat.Port]struct{}, len(ic.ExposedPorts))
+		}
+		for k, v := range ic.ExposedPorts {
+			config.ExposedPorts[k] = v
+		}
+	}
+
+	if len(ic.Labels) > 0 {
+		if config.Labels == nil {
+			config.Labels = make(map[string]string, len(ic.Labels))
+		}
+		for k, v := range ic.Labels {
+			if _, ok := config.Labels[k]; !ok {
+				config.Labels[k] = v
+			}
+		}
+	}
+
+	// the port layer keeps containers of the same service apart, if enabled for the VCH
+	if service := config.Labels[vchconfig.AntiAffinityLabel]; service != "" {
+		res.Annotations[vchconfig.AntiAffinityAnnotation] = service
+	}
+
+	// ONBUILD triggers only apply when the image is used as the base of a build
+	if len(ic.OnBuild) > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("Ignoring %d ONBUILD trigger(s) defined by the image", len(ic.OnBuild)))
+	}
+
+	if image != nil && image.Healthcheck != nil && !disabled(image.Healthcheck) {
+		b, err := json.Marshal(image.Healthcheck)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode healthcheck: %s", err)
+		}
+		res.Annotations[HealthcheckAnnotation] = base64.StdEncoding.EncodeToString(b)
+	}
+
+	return res, nil
+}
+
+// Env merges the image environment into the container environment without overriding any
+// container variables, adding a PATH if neither provides one and a TERM for a tty
+func Env(env, imageEnv []string, tty bool) []string {
+	merged := make([]string, len(env), len(env)+len(imageEnv)+2)
+	copy(merged, env)
+
+	set := make(map[string]bool, len(env))
+	for _, e := range env {
+		set[strings.SplitN(e, "=", 2)[0]] = true
+	}
+
+	for _, e := range imageEnv {
+		key := strings.SplitN(e, "=", 2)[0]
+		if !set[key] {
+			merged = append(merged, e)
+			set[key] = true
+		}
+	}
+
+	if !set["PATH"] {
+		merged = append(merged, "PATH="+DefaultEnvPath)
+	}
+
+	if tty && !set["TERM"] {
+		merged = append(merged, "TERM=xterm")
+	}
+
+	return merged
+}
+
+// Session returns the executor session for the container config, which must already have had
+// the image config applied
+func Session(id string, config *containertypes.Config) (*executor.SessionConfig, error) {
+	cmd := append(append([]string{}, config.Entrypoint...), config.Cmd...)
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("no command specified")
+	}
+
+	session := &executor.SessionConfig{
+		Common: executor.Common{
+			ID: id,
+		},
+		Cmd: executor.Cmd{
+			Path: cmd[0],
+			Args: cmd,
+			Env:  append([]string{}, config.Env...),
+			Dir:  config.WorkingDir,
+		},
+		Tty:        config.Tty,
+		Attach:     config.AttachStdin || config.AttachStdout || config.AttachStderr,
+		StopSignal: config.StopSignal,
+		// docker creates a working directory that is missing from the image
+		CreateDir: true,
+	}
+
+	if d := config.Labels[vchconfig.DeadlineLabel]; d != "" {
+		deadline, err := time.ParseDuration(d)
+		if err != nil || deadline < time.Second {
+			return nil, fmt.Errorf("invalid %s label %q: must be a duration of at least 1s, such as 90m", vchconfig.DeadlineLabel, d)
+		}
+		// rounded up so that a deadline is never shortened
+		session.Deadline = int64((deadline + time.Second - 1) / time.Second)
+	}
+
+	// user may be in the form user:group
+	if config.User != "" {
+		parts := strings.SplitN(config.User, ":", 2)
+		session.User = parts[0]
+		if len(parts) == 2 {
+			session.Group = parts[1]
+		}
+	}
+
+	return session, nil
+}
+
+// Protected returns whether the container config carries a true protected label, which stops the
+// container being removed without force_protected
+func Protected(config *containertypes.Config) (bool, error) {
+	p, ok := config.Labels[vchconfig.ProtectedLabel]
+	if !ok {
+		return false, nil
+	}
+
+	protected, err := strconv.ParseBool(p)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s label %q: must be true or false", vchconfig.ProtectedLabel, p)
+	}
+	return protected, nil
+}
+
+// Stats converts the metrics of a container VM into docker stats, leaving PreCPUStats to the caller. The
+// virtual NICs are reported as eth0, eth1, ... and the virtual disks as block devices 8:0, 8:16, ...
+// in the order the port layer lists them, which is that of their device instances.
+func Stats(m *models.ContainerStats) *types.StatsJSON {
+	s := &types.StatsJSON{
+		Networks: make(map[string]types.NetworkStats),
+	}
+
+	if m.SampleTime != 0 {
+		s.Read = time.Unix(0, m.SampleTime)
+	}
+
+	if m.Memory != nil {
+		s.MemoryStats = types.MemoryStats{
+			Usage:    uint64(m.Memory.Consumed),
+			MaxUsage: uint64(m.Memory.MaxConsumed),
+			Limit:    uint64(m.Memory.Provisioned),
+		}
+	}
+
+	// docker computes the CPU percentage against the system time of all CPUs
+	if m.CPU != nil {
+		cpu := &s.CPUStats
+		for _, u := range m.CPU.Usage {
+			cpu.CPUUsage.PercpuUsage = append(cpu.CPUUsage.PercpuUsage, uint64(u))
+			cpu.CPUUsage.TotalUsage += uint64(u)
+		}
+		cpu.SystemUsage = uint64(m.CPU.Elapsed) * uint64(len(m.CPU.Usage))
+	}
+
+	for i, n := range m.Networks {
+		s.Networks[fmt.Sprintf("eth%d", i)] = types.NetworkStats{
+			RxBytes:   uint64(n.RxBytes),
+			RxPackets: uint64(n.RxPackets),
+			RxDropped: uint64(n.RxDropped),
+			TxBytes:   uint64(n.TxBytes),
+			TxPackets: uint64(n.TxPackets),
+			TxDropped: uint64(n.TxDropped),
+		}
+	}
+
+	blkio := &s.BlkioStats
+	for i, d := range m.Disks {
+		minor := uint64(16 * i)
+		blkio.IoServiceBytesRecursive = append(blkio.IoServiceBytesRecursive,
+			types.BlkioStatEntry{Major: 8, Minor: minor, Op: "Read", Value: uint64(d.ReadBytes)},
+			types.BlkioStatEntry{Major: 8, Minor: minor, Op: "Write", Value: uint64(d.WriteBytes)},
+		)
+		blkio.IoServicedRecursive = append(blkio.IoServicedRecursive,
+			types.BlkioStatEntry{Major: 8, Minor: minor, Op: "Read", Value: uint64(d.Reads)},
+			types.BlkioStatEntry{Major: 8, Minor: minor, Op: "Write", Value: uint64(d.Writes)},
+		)
+	}
+
+	return s
+}
+
+// Process describes a process running in a container VM
+type Process struct {
+	PID  int64
+	PPID int64
+	// User is the name of the user running the process, or its ID
+	User string
+	// StartTime is when the process started
+	StartTime time.Time
+	// CPUTime is the user and system time used by the process
+	CPUTime time.Duration
+	// CPU is the percentage of CPU time used by the process since it started
+	CPU int
+	// Mem is the percentage of the container VM memory resident for the process
+	Mem float64
+	// VSZ and RSS are the virtual and resident memory of the process, in KiB
+	VSZ int64
+	RSS int64
+	// State is the process state code
+	State string
+	// TTY is the controlling terminal of the process, empty if it has none
+	TTY string
+	Cmd string
+}
+
+// Top formats the processes of a container VM as ps does with the given arguments, which select
+// the full format with f, or the user format of BSD ps with u, as in aux. All processes are listed,
+// as they are all in the container, so -e and -A, and a and x without a dash, are accepted as well.
+// Other arguments are refused as the output of ps is not parsed.
+func Top(procs []Process, psArgs string, now time.Time) (*types.ContainerProcessList, error) {
+	unsupported := fmt.Errorf("unsupported ps arguments %q, only -e, -A, -f and aux are supported", psArgs)
+
+	full := psArgs == ""
+	user := false
+	for _, arg := range strings.Fields(psArgs) {
+		bsd := !strings.HasPrefix(arg, "-")
+		for _, c := range strings.TrimPrefix(arg, "-") {
+			switch {
+			case c == 'e' || c == 'A':
+			case c == 'f':
+				full = true
+			case bsd && (c == 'a' || c == 'x'):
+			case bsd && c == 'u':
+				user = true
+			default:
+				return nil, unsupported
+			}
+		}
+	}
+
+	sorted := append([]Process(nil), procs...)
+	sort.Sort(byPID(sorted))
+
+	list := &types.ContainerProcessList{
+		Titles: []string{"PID", "TTY", "TIME", "CMD"},
+	}
+	switch {
+	case user:
+		list.Titles = []string{"USER", "PID", "%CPU", "%MEM", "VSZ", "RSS", "TTY", "STAT", "START", "TIME", "COMMAND"}
+	case full:
+		list.Titles = []string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"}
+	}
+
+	for _, p := range sorted {
+		tty := p.TTY
+		if tty == "" {
+			tty = "?"
+		}
+
+		pid := strconv.FormatInt(p.PID, 10)
+		start := startTime(p.StartTime.In(now.Location()), now)
+
+		switch {
+		case user:
+			list.Processes = append(list.Processes, []string{
+				p.User,
+				pid,
+				fmt.Sprintf("%.1f", float64(p.CPU)),
+				fmt.Sprintf("%.1f", p.Mem),
+				strconv.FormatInt(p.VSZ, 10),
+				strconv.FormatInt(p.RSS, 10),
+				tty,
+				p.State,
+				start,
+				bsdCPUTime(p.CPUTime),
+				p.Cmd,
+			})
+		case full:
+			list.Processes = append(list.Processes, []string{
+				p.User,
+				pid,
+				strconv.FormatInt(p.PPID, 10),
+				strconv.Itoa(p.CPU),
+				start,
+				tty,
+				cpuTime(p.CPUTime),
+				p.Cmd,
+			})
+		default:
+			list.Processes = append(list.Processes, []string{pid, tty, cpuTime(p.CPUTime), p.Cmd})
+		}
+	}
+
+	return list, nil
+}
+
+type byPID []Process
+
+func (p byPID) Len() int           { return len(p) }
+func (p byPID) Less(i, j int) bool { return p[i].PID < p[j].PID }
+func (p byPID) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// startTime formats the STIME of ps: the time for processes started today, otherwise the date
+// for those started this year and the year for the rest
+func startTime(t, now time.Time) string {
+	switch {
+	case t.YearDay() == now.YearDay() && t.Year() == now.Year():
+		return t.Format("15:04")
+	case t.Year() == now.Year():
+		return t.Format("Jan02")
+	default:
+		return t.Format("2006")
+	}
+}
+
+// cpuTime formats the TIME of ps as [DD-]HH:MM:SS
+func cpuTime(d time.Duration) string {
+	s := int64(d / time.Second)
+	days, hours, minutes, seconds := s/86400, s/3600%24, s/60%60, s%60
+
+	if days > 0 {
+		return fmt.Sprintf("%d-%02d:%02d:%02d", days, hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+}
+
+// bsdCPUTime formats the TIME of BSD ps as MM:SS, with the minutes unbounded
+func bsdCPUTime(d time.Duration) string {
+	s := int64(d / time.Second)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// disabled returns true if the healthcheck turns off one inherited from the base image
+func disabled(h *metadata.HealthConfig) bool {
+	return len(h.Test) > 0 && h.Test[0] == "NONE"
+}
@@ -0,0 +1,394 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	docker "github.com/docker/docker/image"
+	containertypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/apiservers/portlayer/models"
+	vchconfig "github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/metadata"
+)
+
+var defaultPath = "PATH=" + DefaultEnvPath
+
+func image(config *containertypes.Config) *metadata.ImageConfig {
+	return &metadata.ImageConfig{
+		V1Image: docker.V1Image{Config: config},
+	}
+}
+
+func TestApply(t *testing.T) {
+	var tests = []struct {
+		name      string
+		config    containertypes.Config
+		image     *metadata.ImageConfig
+		expected  containertypes.Config
+		warnings  int
+		annotated bool
+		service   string
+	}{
+		{
+			name:     "no image",
+			config:   containertypes.Config{Cmd: []string{"/bin/sh"}},
+			expected: containertypes.Config{Cmd: []string{"/bin/sh"}, Env: []string{defaultPath}},
+		},
+		{
+			name: "defaults from image",
+			image: image(&containertypes.Config{
+				Cmd:        []string{"nginx"},
+				Entrypoint: []string{"/entrypoint.sh"},
+				WorkingDir: "/srv",
+				User:       "www-data",
+				StopSignal: "SIGQUIT",
+			}),
+			expected: containertypes.Config{
+				Cmd:        []string{"nginx"},
+				Entrypoint: []string{"/entrypoint.sh"},
+				WorkingDir: "/srv",
+				User:       "www-data",
+				StopSignal: "SIGQUIT",
+				Env:        []string{defaultPath},
+			},
+		},
+		{
+			name: "container overrides image",
+			config: containertypes.Config{
+				Cmd:        []string{"ls"},
+				Entrypoint: []string{"/bin/busybox"},
+				WorkingDir: "/tmp",
+				User:       "root",
+				StopSignal: "SIGKILL",
+			},
+			image: image(&containertypes.Config{
+				Cmd:        []string{"nginx"},
+				Entrypoint: []string{"/entrypoint.sh"},
+				WorkingDir: "/srv",
+				User:       "www-data",
+				StopSignal: "SIGQUIT",
+			}),
+			expected: containertypes.Config{
+				Cmd:        []string{"ls"},
+				Entrypoint: []string{"/bin/busybox"},
+				WorkingDir: "/tmp",
+				User:       "root",
+				StopSignal: "SIGKILL",
+				Env:        []string{defaultPath},
+			},
+		},
+		{
+			name: "env merge",
+			config: containertypes.Config{
+				Env: []string{"FOO=container", "TERM=vt100"},
+				Tty: true,
+			},
+			image: image(&containertypes.Config{
+				Env: []string{"PATH=/opt/bin", "FOO=image", "BAR=image"},
+			}),
+			expected: containertypes.Config{
+				Env: []string{"FOO=container", "TERM=vt100", "PATH=/opt/bin", "BAR=image"},
+				Tty: true,
+			},
+		},
+		{
+			name: "sets are merged",
+			config: containertypes.Config{
+				Volumes:      map[string]struct{}{"/data": {}},
+				ExposedPorts: map[nat.Port]struct{}{"8080/tcp": {}},
+				Labels:       map[string]string{"owner": "container"},
+			},
+			image: image(&containertypes.Config{
+				Volumes:      map[string]struct{}{"/data": {}, "/logs": {}},
+				ExposedPorts: map[nat.Port]struct{}{"80/tcp": {}, "53/udp": {}},
+				Labels:       map[string]string{"owner": "image", "version": "1.0"},
+			}),
+			expected: containertypes.Config{
+				Volumes:      map[string]struct{}{"/data": {}, "/logs": {}},
+				ExposedPorts: map[nat.Port]struct{}{"8080/tcp": {}, "80/tcp": {}, "53/udp": {}},
+				Labels:       map[string]string{"owner": "container", "version": "1.0"},
+				Env:          []string{defaultPath},
+			},
+		},
+		{
+			name:   "onbuild ignored",
+			config: containertypes.Config{},
+			image: image(&containertypes.Config{
+				OnBuild: []string{"ADD . /app", "RUN make"},
+			}),
+			expected: containertypes.Config{Env: []string{defaultPath}},
+			warnings: 1,
+		},
+		{
+			name: "healthcheck",
+			image: &metadata.ImageConfig{
+				V1Image:     docker.V1Image{Config: &containertypes.Config{}},
+				Healthcheck: &metadata.HealthConfig{Test: []string{"CMD-SHELL", "true"}, Interval: time.Second},
+			},
+			expected:  containertypes.Config{Env: []string{defaultPath}},
+			annotated: true,
+		},
+		{
+			name: "anti-affinity from image label",
+			image: image(&containertypes.Config{
+				Labels: map[string]string{vchconfig.AntiAffinityLabel: "web"},
+			}),
+			expected: containertypes.Config{
+				Labels: map[string]string{vchconfig.AntiAffinityLabel: "web"},
+				Env:    []string{defaultPath},
+			},
+			service: "web",
+		},
+		{
+			name: "healthcheck disabled",
+			image: &metadata.ImageConfig{
+				V1Image:     docker.V1Image{Config: &containertypes.Config{}},
+				Healthcheck: &metadata.HealthConfig{Test: []string{"NONE"}},
+			},
+			expected: containertypes.Config{Env: []string{defaultPath}},
+		},
+	}
+
+	for _, te := range tests {
+		config := te.config
+		res, err := Apply(&config, te.image)
+		require.NoError(t, err, te.name)
+
+		assert.Equal(t, te.expected, config, te.name)
+		assert.Len(t, res.Warnings, te.warnings, te.name)
+		assert.Equal(t, te.service, res.Annotations[vchconfig.AntiAffinityAnnotation], te.name)
+
+		encoded, ok := res.Annotations[HealthcheckAnnotation]
+		assert.Equal(t, te.annotated, ok, te.name)
+		if !ok {
+			continue
+		}
+
+		b, err := base64.StdEncoding.DecodeString(encoded)
+		require.NoError(t, err, te.name)
+
+		var health metadata.HealthConfig
+		require.NoError(t, json.Unmarshal(b, &health), te.name)
+		assert.Equal(t, *te.image.Healthcheck, health, te.name)
+	}
+}
+
+func TestSession(t *testing.T) {
+	var tests = []struct {
+		config containertypes.Config
+		path   string
+		args   []string
+		user   string
+		group  string
+	}{
+		{
+			config: containertypes.Config{Cmd: []string{"/bin/ls", "-l"}},
+			path:   "/bin/ls",
+			args:   []string{"/bin/ls", "-l"},
+		},
+		{
+			config: containertypes.Config{Entrypoint: []string{"/entrypoint.sh", "-v"}, Cmd: []string{"run"}, User: "nobody"},
+			path:   "/entrypoint.sh",
+			args:   []string{"/entrypoint.sh", "-v", "run"},
+			user:   "nobody",
+		},
+		{
+			config: containertypes.Config{Entrypoint: []string{"/entrypoint.sh"}, User: "1000:100"},
+			path:   "/entrypoint.sh",
+			args:   []string{"/entrypoint.sh"},
+			user:   "1000",
+			group:  "100",
+		},
+	}
+
+	for i, te := range tests {
+		te.config.Env = []string{"A=b"}
+		te.config.WorkingDir = "/"
+		te.config.AttachStdout = true
+		te.config.StopSignal = "SIGINT"
+
+		session, err := Session("id", &te.config)
+		require.NoError(t, err, "test %d", i)
+
+		assert.Equal(t, "id", session.ID, "test %d", i)
+		assert.Equal(t, te.path, session.Cmd.Path, "test %d", i)
+		assert.Equal(t, te.args, session.Cmd.Args, "test %d", i)
+		assert.Equal(t, []string{"A=b"}, session.Cmd.Env, "test %d", i)
+		assert.Equal(t, "/", session.Cmd.Dir, "test %d", i)
+		assert.True(t, session.CreateDir, "test %d", i)
+		assert.Equal(t, te.user, session.User, "test %d", i)
+		assert.Equal(t, te.group, session.Group, "test %d", i)
+		assert.True(t, session.Attach, "test %d", i)
+		assert.Equal(t, "SIGINT", session.StopSignal, "test %d", i)
+	}
+
+	_, err := Session("id", &containertypes.Config{})
+	assert.Error(t, err)
+}
+
+func TestSessionDeadline(t *testing.T) {
+	config := containertypes.Config{Cmd: []string{"/bin/batch"}}
+
+	session, err := Session("id", &config)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, session.Deadline)
+
+	for label, deadline := range map[string]int64{"90m": 5400, "1s": 1, "1500ms": 2} {
+		config.Labels = map[string]string{vchconfig.DeadlineLabel: label}
+
+		session, err = Session("id", &config)
+		if assert.NoError(t, err, label) {
+			assert.Equal(t, deadline, session.Deadline, label)
+		}
+	}
+
+	for _, label := range []string{"90", "-1h", "500ms", "forever"} {
+		config.Labels = map[string]string{vchconfig.DeadlineLabel: label}
+
+		_, err = Session("id", &config)
+		assert.Error(t, err, label)
+	}
+}
+
+func TestProtected(t *testing.T) {
+	config := containertypes.Config{}
+
+	protected, err := Protected(&config)
+	require.NoError(t, err)
+	assert.False(t, protected)
+
+	for label, expected := range map[string]bool{"true": true, "1": true, "false": false, "0": false} {
+		config.Labels = map[string]string{vchconfig.ProtectedLabel: label}
+
+		protected, err = Protected(&config)
+		if assert.NoError(t, err, label) {
+			assert.Equal(t, expected, protected, label)
+		}
+	}
+
+	config.Labels = map[string]string{vchconfig.ProtectedLabel: "yes please"}
+	_, err = Protected(&config)
+	assert.Error(t, err)
+}
+
+func TestStats(t *testing.T) {
+	now := time.Unix(1492000000, 500)
+	m := &models.ContainerStats{
+		SampleTime: now.UnixNano(),
+		CPU: &models.CPUStats{
+			Usage:   []int64{int64(10 * time.Second), int64(5 * time.Second)},
+			Elapsed: int64(20 * time.Second),
+		},
+		Memory: &models.MemoryStats{
+			Consumed:    256 << 20,
+			MaxConsumed: 300 << 20,
+			Provisioned: 2048 << 20,
+		},
+		Networks: []*models.NetworkStats{
+			{Instance: "4000", RxBytes: 100, TxBytes: 50, RxPackets: 2},
+			{Instance: "4001", RxBytes: 10},
+		},
+		Disks: []*models.DiskStats{
+			{Instance: "scsi0:0", ReadBytes: 4096, Reads: 1, WriteBytes: 8192, Writes: 2},
+		},
+	}
+
+	s := Stats(m)
+	assert.True(t, now.Equal(s.Read))
+
+	// 15s of CPU time over 20s of two CPUs is 75% of a CPU, as docker computes it
+	cpu := s.CPUStats
+	assert.Equal(t, uint64(15*time.Second), cpu.CPUUsage.TotalUsage)
+	assert.Equal(t, uint64(40*time.Second), cpu.SystemUsage)
+	percent := float64(cpu.CPUUsage.TotalUsage) / float64(cpu.SystemUsage) * float64(len(cpu.CPUUsage.PercpuUsage)) * 100
+	assert.Equal(t, 75.0, percent)
+
+	assert.Equal(t, uint64(256<<20), s.MemoryStats.Usage)
+	assert.Equal(t, uint64(300<<20), s.MemoryStats.MaxUsage)
+	assert.Equal(t, uint64(2048<<20), s.MemoryStats.Limit)
+
+	require.Len(t, s.Networks, 2)
+	assert.Equal(t, uint64(100), s.Networks["eth0"].RxBytes)
+	assert.Equal(t, uint64(50), s.Networks["eth0"].TxBytes)
+	assert.Equal(t, uint64(10), s.Networks["eth1"].RxBytes)
+
+	require.Len(t, s.BlkioStats.IoServiceBytesRecursive, 2)
+	assert.Equal(t, "Read", s.BlkioStats.IoServiceBytesRecursive[0].Op)
+	assert.Equal(t, uint64(4096), s.BlkioStats.IoServiceBytesRecursive[0].Value)
+	assert.Equal(t, uint64(8192), s.BlkioStats.IoServiceBytesRecursive[1].Value)
+	assert.Equal(t, uint64(2), s.BlkioStats.IoServicedRecursive[1].Value)
+
+	// metrics before the first sample are empty
+	s = Stats(&models.ContainerStats{})
+	assert.True(t, s.Read.IsZero())
+	assert.Empty(t, s.CPUStats.CPUUsage.PercpuUsage)
+	assert.Empty(t, s.Networks)
+}
+
+func TestTop(t *testing.T) {
+	now := time.Date(2017, time.March, 14, 15, 30, 0, 0, time.UTC)
+	procs := []Process{
+		{PID: 42, PPID: 1, User: "1000", StartTime: now.Add(-time.Hour), CPUTime: 26*time.Hour + 3*time.Minute + 4*time.Second, CPU: 12, Mem: 1.25, VSZ: 4096, RSS: 1024, State: "R", TTY: "pts/0", Cmd: "top -b"},
+		{PID: 1, User: "root", StartTime: time.Date(2017, time.January, 2, 9, 0, 0, 0, time.UTC), CPUTime: 1500 * time.Millisecond, VSZ: 1536, RSS: 512, State: "S", Cmd: "/bin/sh"},
+		{PID: 7, PPID: 1, User: "root", StartTime: time.Date(2016, time.December, 31, 9, 0, 0, 0, time.UTC), State: "Z", Cmd: "[sh]"},
+	}
+
+	for _, args := range []string{"", "-ef", "-e -f", "fA"} {
+		list, err := Top(procs, args, now)
+		if !assert.NoError(t, err, args) {
+			continue
+		}
+
+		assert.Equal(t, []string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"}, list.Titles)
+		assert.Equal(t, [][]string{
+			{"root", "1", "0", "0", "Jan02", "?", "00:00:01", "/bin/sh"},
+			{"root", "7", "1", "0", "2016", "?", "00:00:00", "[sh]"},
+			{"1000", "42", "1", "12", "14:30", "pts/0", "1-02:03:04", "top -b"},
+		}, list.Processes, args)
+	}
+
+	list, err := Top(procs, "-e", now)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"PID", "TTY", "TIME", "CMD"}, list.Titles)
+		assert.Equal(t, []string{"42", "pts/0", "1-02:03:04", "top -b"}, list.Processes[2])
+	}
+
+	for _, args := range []string{"aux", "ux", "-e u"} {
+		list, err = Top(procs, args, now)
+		if !assert.NoError(t, err, args) {
+			continue
+		}
+
+		assert.Equal(t, []string{"USER", "PID", "%CPU", "%MEM", "VSZ", "RSS", "TTY", "STAT", "START", "TIME", "COMMAND"}, list.Titles)
+		assert.Equal(t, [][]string{
+			{"root", "1", "0.0", "0.0", "1536", "512", "?", "S", "Jan02", "0:01", "/bin/sh"},
+			{"root", "7", "0.0", "0.0", "0", "0", "?", "Z", "2016", "0:00", "[sh]"},
+			{"1000", "42", "12.0", "1.2", "4096", "1024", "pts/0", "R", "14:30", "1563:04", "top -b"},
+		}, list.Processes, args)
+	}
+
+	for _, args := range []string{"-aux", "-u", "aux -o pid", "l"} {
+		_, err = Top(procs, args, now)
+		assert.Error(t, err, args)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backends
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	derr "github.com/docker/docker/errors"
+	"github.com/docker/docker/reference"
+	"github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/apiservers/engine/backends/cache"
+	"github.com/vmware/vic/lib/apiservers/engine/backends/prefetch"
+	"github.com/vmware/vic/lib/apiservers/engine/router/vic"
+	"github.com/vmware/vic/lib/apiservers/portlayer/client/containers"
+	"github.com/vmware/vic/lib/apiservers/portlayer/client/scopes"
+	"github.com/vmware/vic/lib/apiservers/portlayer/models"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// Vic implements the VIC extension API
+type Vic struct {
+	systemProxy VicSystemProxy
+	containers  *Container
+	prefetcher  *prefetch.Prefetcher
+}
+
+func NewVicBackend() *Vic {
+	v := &Vic{
+		systemProxy: &SystemProxy{},
+		containers:  NewContainerBackend(),
+		prefetcher:  prefetch.New(imagePulls.Fetch),
+	}
+
+	ctx := context.Background()
+	v.prefetcher.Start(ctx)
+
+	// images named at create time are pulled as soon as the VCH is up
+	if conf := VchConfig(); conf != nil && len(conf.PrefetchImages) > 0 {
+		if err := v.prefetcher.Schedule(ctx, conf.PrefetchImages, conf.PrefetchInterval); err != nil {
+			log.Errorf("Failed to schedule image prefetch: %s", err)
+		}
+	}
+
+	return v
+}
+
+// imagePullConcurrency is the number of images pulled at once by batch pulls and prefetches
+const imagePullConcurrency = 4
+
+// imagePulls are the pulls shared by batch pulls, prefetches and container creates, so that an
+// image is pulled once however many of them need it
+var imagePulls = prefetch.NewBatch(pullImage, imagePullConcurrency)
+
+// pullImage pulls an image anonymously, as docker pull does
+func pullImage(ctx context.Context, ref reference.Named, out io.Writer) error {
+	return (&Image{}).PullImage(ctx, ref, nil, nil, out)
+}
+
+func (v *Vic) VCHInfo() (*vic.VCHInfo, error) {
+	defer trace.End(trace.Begin(""))
+
+	conf := VchConfig()
+	if conf == nil {
+		return nil, derr.NewErrorWithStatusCode(fmt.Errorf("VCH configuration is not available"), http.StatusInternalServerError)
+	}
+
+	return &vic.VCHInfo{
+		Name:    conf.Name,
+		ID:      conf.ExecutorConfig.ID,
+		Version: ProductVersion(),
+	}, nil
+}
+
+func (v *Vic) Capacity() (*vic.Capacity, error) {
+	defer trace.End(trace.Begin(""))
+
+	running, paused, stopped, err := v.systemProxy.ContainerCount()
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := &vic.Capacity{
+		Containers: running + paused + stopped,
+		Running:    running,
+	}
+
+	vchInfo, err := v.systemProxy.VCHInfo()
+	if err != nil {
+		return nil, err
+	}
+	if vchInfo.CPUMhz != nil {
+		capacity.CPUMhz = *vchInfo.CPUMhz
+	}
+	if vchInfo.Memory != nil {
+		capacity.MemoryMB = *vchInfo.Memory
+	}
+
+	return capacity, nil
+}
+
+func (v *Vic) ContainerAdopt(req *vic.AdoptRequest) (*vic.AdoptResponse, error) {
+	return nil, notImplementedError("adopt")
+}
+
+// ContainerRegisterExternal lists an existing VM as a read-only external container
+func (v *Vic) ContainerRegisterExternal(req *vic.ExternalRequest) (*vic.ExternalResponse, error) {
+	defer trace.End(trace.Begin(req.VM))
+
+	if req.VM == "" {
+		return nil, derr.NewBadRequestError(fmt.Errorf("no VM given"))
+	}
+
+	ok, err := PortLayerClient().Containers.RegisterExternal(containers.NewRegisterExternalParamsWithContext(ctx).
+		WithConfig(&models.ExternalVMConfig{VM: req.VM}))
+	if err != nil {
+		switch err := err.(type) {
+		case *containers.RegisterExternalNotFound:
+			return nil, derr.NewRequestNotFoundError(fmt.Errorf(err.Payload.Message))
+
+		case *containers.RegisterExternalConflict:
+			return nil, derr.NewRequestConflictError(fmt.Errorf(err.Payload.Message))
+
+		case *containers.RegisterExternalInternalServerError:
+			return nil, derr.NewErrorWithStatusCode(fmt.Errorf(err.Payload.Message), http.StatusInternalServerError)
+
+		default:
+			return nil, derr.NewErrorWithStatusCode(err, http.StatusInternalServerError)
+		}
+	}
+
+	// the container is listed by docker ps and found by name without waiting for the cache to be synced
+	vc := ContainerInfoToVicContainer(*ok.Payload)
+	cache.ContainerCache().AddContainer(vc)
+
+	return &vic.ExternalResponse{ID: vc.ContainerID}, nil
+}
+
+func (v *Vic) ContainerCheckpoint(name string, req *vic.CheckpointRequest) error {
+	return notImplementedError("checkpoint")
+}
+
+func (v *Vic) ContainerConsoleTicket(name string) (*vic.ConsoleTicket, error) {
+	return nil, notImplementedError("console ticket")
+}
+
+// ContainerRemove removes the container as docker rm does, and also removes protected containers
+// if the options say to
+func (v *Vic) ContainerRemove(name string, opts *vic.RemoveOptions) error {
+	config := &types.ContainerRmConfig{
+		ForceRemove:  opts.Force,
+		RemoveVolume: opts.RemoveVolumes,
+	}
+	return v.containers.containerRm(name, config, opts.ForceProtected)
+}
+
+// notImplementedError returns a 501 error for an operation of the extension API that the port
+// layer does not support yet
+func notImplementedError(op string) error {
+	return derr.NewErrorWithStatusCode(fmt.Errorf("%s is not supported by this VCH", op), http.StatusNotImplemented)
+}
+
+func (v *Vic) ImagePrefetch(req *vic.PrefetchRequest) ([]vic.PrefetchStatus, error) {
+	defer trace.End(trace.Begin(""))
+
+	status, err := v.prefetcher.Add(req.Images)
+	if err != nil {
+		return nil, derr.NewBadRequestError(err)
+	}
+	return status, nil
+}
+
+func (v *Vic) ImagePrefetchStatus() ([]vic.PrefetchStatus, error) {
+	return v.prefetcher.Status(), nil
+}
+
+func (v *Vic) ImagesPull(req *vic.PullRequest, out io.Writer) error {
+	defer trace.End(trace.Begin(""))
+
+	if _, err := prefetch.ParseReferences(req.Images); err != nil {
+		return derr.NewBadRequestError(err)
+	}
+	return imagePulls.Pull(context.Background(), req.Images, out)
+}
+
+func (v *Vic) NetworksPrune() (*vic.NetworksPruneReport, error) {
+	defer trace.End(trace.Begin(""))
+
+	ok, err := PortLayerClient().Scopes.PruneScopes(scopes.NewPruneScopesParamsWithContext(ctx))
+	if err != nil {
+		return nil, derr.NewErrorWithStatusCode(err, http.StatusInternalServerError)
+	}
+
+	return &vic.NetworksPruneReport{NetworksDeleted: ok.Payload}, nil
+}
+
+// NetworkFirewall returns the firewall rules of a network
+func (v *Vic) NetworkFirewall(name string) (*vic.FirewallPolicy, error) {
+	defer trace.End(trace.Begin(name))
+
+	ok, err := PortLayerClient().Scopes.GetFirewall(scopes.NewGetFirewallParamsWithContext(ctx).WithIDName(name))
+	if err != nil {
+		switch err := err.(type) {
+		case *scopes.GetFirewallNotFound:
+			return nil, derr.NewRequestNotFoundError(fmt.Errorf("network %s not found", name))
+
+		case *scopes.GetFirewallInternalServerError:
+			return nil, derr.NewErrorWithStatusCode(fmt.Errorf(err.Payload.Message), http.StatusInternalServerError)
+
+		default:
+			return nil, derr.NewErrorWithStatusCode(err, http.StatusInternalServerError)
+		}
+	}
+
+	return &vic.FirewallPolicy{Ingress: ok.Payload.Ingress, Egress: ok.Payload.Egress}, nil
+}
+
+// SetNetworkFirewall replaces the firewall rules of a network, which apply to containers from their next start
+func (v *Vic) SetNetworkFirewall(name string, policy *vic.FirewallPolicy) (*vic.FirewallPolicy, error) {
+	defer trace.End(trace.Begin(name))
+
+	p := &models.FirewallPolicy{Ingress: policy.Ingress, Egress: policy.Egress}
+	ok, err := PortLayerClient().Scopes.SetFirewall(scopes.NewSetFirewallParamsWithContext(ctx).WithIDName(name).WithPolicy(p))
+	if err != nil {
+		switch err := err.(type) {
+		case *scopes.SetFirewallBadRequest:
+			return nil, derr.NewBadRequestError(fmt.Errorf(err.Payload.Message))
+
+		case *scopes.SetFirewallNotFound:
+			return nil, derr.NewRequestNotFoundError(fmt.Errorf("network %s not found", name))
+
+		case *scopes.SetFirewallInternalServerError:
+			return nil, derr.NewErrorWithStatusCode(fmt.Errorf(err.Payload.Message), http.StatusInternalServerError)
+
+		default:
+			return nil, derr.NewErrorWithStatusCode(err, http.StatusInternalServerError)
+		}
+	}
+
+	return &vic.FirewallPolicy{Ingress: ok.Payload.Ingress, Egress: ok.Payload.Egress}, nil
+}
@@ -24,6 +24,7 @@ import (
 
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/docker/engine-api/types"
 	"github.com/docker/go-units"
@@ -38,19 +39,38 @@ import (
 const (
 	OptsVolumeStoreKey     string = "VolumeStore"
 	OptsCapacityKey        string = "Capacity"
+	OptsStoragePolicyKey   string = "StoragePolicy"
 	dockerMetadataModelKey string = "DockerMetaData"
+
+	// storagePolicyMetadataKey is the portlayer volume metadata key for the storage policy of the volume
+	storagePolicyMetadataKey string = "StoragePolicy"
 )
 
 //Validation pattern for Volume Names
 var volumeNameRegex = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_.-]*$")
 
+// volumeOpts are the driver options accepted by docker volume create
+var volumeOpts = []string{OptsVolumeStoreKey, OptsCapacityKey, OptsStoragePolicyKey}
+
 func NewVolumeModel(volume *models.VolumeResponse, labels map[string]string) *types.Volume {
-	return &types.Volume{
+	model := &types.Volume{
 		Driver:     volume.Driver,
 		Name:       volume.Name,
 		Labels:     labels,
 		Mountpoint: volume.Label,
 	}
+
+	status := make(map[string]interface{})
+	if volume.Store != "" {
+		status[OptsVolumeStoreKey] = volume.Store
+	}
+	if policy := volume.Metadata[storagePolicyMetadataKey]; policy != "" {
+		status[OptsStoragePolicyKey] = policy
+	}
+	if len(status) > 0 {
+		model.Status = status
+	}
+	return model
 }
 
 // Volume which defines the docker personalities view of a Volume
@@ -161,6 +181,11 @@ func (v *Volume) volumeCreate(name, driverName string, volumeData, labels map[st
 func (v *Volume) VolumeCreate(name, driverName string, volumeData, labels map[string]string) (*types.Volume, error) {
 	defer trace.End(trace.Begin("Volume.VolumeCreate"))
 
+	volumeData, err := normalizeDriverOpts(volumeData)
+	if err != nil {
+		return nil, derr.NewBadRequestError(err)
+	}
+
 	result, err := v.volumeCreate(name, driverName, volumeData, labels)
 	if err != nil {
 		switch err := err.(type) {
@@ -284,6 +309,30 @@ func newVolumeCreateReq(name, driverName string, volumeData, labels map[string]s
 	return req, nil
 }
 
+// normalizeDriverOpts returns the driver options given to docker volume create with their names in
+// canonical form, so that -o capacity=10G is the same as -o Capacity=10G, rejecting unknown options
+func normalizeDriverOpts(opts map[string]string) (map[string]string, error) {
+	normalized := make(map[string]string, len(opts))
+	for k, v := range opts {
+		key := ""
+		for _, opt := range volumeOpts {
+			if strings.EqualFold(k, opt) {
+				key = opt
+				break
+			}
+		}
+		if key == "" {
+			return nil, fmt.Errorf("unknown volume option %q, expected one of %s", k, strings.Join(volumeOpts, ", "))
+		}
+
+		if _, ok := normalized[key]; ok {
+			return nil, fmt.Errorf("volume option %s given more than once", key)
+		}
+		normalized[key] = v
+	}
+	return normalized, nil
+}
+
 // volumeStore returns the value of the optional volume store param specified in the CLI.
 func volumeStore(args map[string]string) string {
 	storeName, ok := args[OptsVolumeStoreKey]
@@ -297,6 +346,17 @@ func validateDriverArgs(args map[string]string, req *models.VolumeRequest) error
 	// volumestore name validation
 	req.Store = volumeStore(args)
 
+	// the storage policy, overriding that of the volume store, is passed to the portlayer in the metadata
+	if policy, ok := args[OptsStoragePolicyKey]; ok {
+		if policy == "" {
+			return fmt.Errorf("Invalid storage policy: must not be empty")
+		}
+		if req.Metadata == nil {
+			req.Metadata = make(map[string]string)
+		}
+		req.Metadata[storagePolicyMetadataKey] = policy
+	}
+
 	// capacity validation
 	capstr, ok := args[OptsCapacityKey]
 	if !ok {
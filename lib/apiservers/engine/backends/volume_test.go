@@ -28,6 +28,7 @@ func TestFillDockerVolume(t *testing.T) {
 		Driver: "vsphere",
 		Name:   "Test Volume",
 		Label:  "Test Label",
+		Store:  "default",
 	}
 	testLabels := make(map[string]string)
 	testLabels["TestMeta"] = "custom info about my volume"
@@ -38,6 +39,7 @@ func TestFillDockerVolume(t *testing.T) {
 	assert.Equal(t, "Test Volume", dockerVolume.Name)
 	assert.Equal(t, "Test Label", dockerVolume.Mountpoint)
 	assert.Equal(t, "custom info about my volume", dockerVolume.Labels["TestMeta"])
+	assert.Equal(t, "default", dockerVolume.Status[OptsVolumeStoreKey])
 }
 
 func TestTranslatVolumeRequestModel(t *testing.T) {
@@ -130,6 +132,35 @@ func TestValidateDriverArgs(t *testing.T) {
 	if !assert.Equal(t, "default", testModel.Store) || !assert.Equal(t, int64(12), testModel.Capacity) || !assert.NoError(t, err) {
 		return
 	}
+
+	testMap[OptsStoragePolicyKey] = "aa6d5a82-1c88-45da-85d3-3d74b91a5bad"
+	err = validateDriverArgs(testMap, &testModel)
+	if !assert.NoError(t, err) || !assert.Equal(t, "aa6d5a82-1c88-45da-85d3-3d74b91a5bad", testModel.Metadata[storagePolicyMetadataKey]) {
+		return
+	}
+
+	//This is a negative test case. We want an error
+	testMap[OptsStoragePolicyKey] = ""
+	err = validateDriverArgs(testMap, &testModel)
+	assert.Error(t, err)
+}
+
+func TestNormalizeDriverOpts(t *testing.T) {
+	opts, err := normalizeDriverOpts(map[string]string{"capacity": "10G", "VOLUMESTORE": "fast", "StoragePolicy": "gold"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{OptsCapacityKey: "10G", OptsVolumeStoreKey: "fast", OptsStoragePolicyKey: "gold"}, opts)
+	}
+
+	opts, err = normalizeDriverOpts(nil)
+	if assert.NoError(t, err) {
+		assert.Empty(t, opts)
+	}
+
+	_, err = normalizeDriverOpts(map[string]string{"size": "10G"})
+	assert.Error(t, err, "unknown option")
+
+	_, err = normalizeDriverOpts(map[string]string{"capacity": "10G", "Capacity": "20G"})
+	assert.Error(t, err, "option given twice")
 }
 
 func TestExtractDockerMetadata(t *testing.T) {
@@ -0,0 +1,35 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vic
+
+import "io"
+
+// Backend is the methods that need to be implemented to provide
+// the VIC specific functionality
+type Backend interface {
+	VCHInfo() (*VCHInfo, error)
+	Capacity() (*Capacity, error)
+	ContainerAdopt(req *AdoptRequest) (*AdoptResponse, error)
+	ContainerCheckpoint(name string, req *CheckpointRequest) error
+	ContainerRegisterExternal(req *ExternalRequest) (*ExternalResponse, error)
+	ContainerConsoleTicket(name string) (*ConsoleTicket, error)
+	ContainerRemove(name string, opts *RemoveOptions) error
+	ImagePrefetch(req *PrefetchRequest) ([]PrefetchStatus, error)
+	ImagePrefetchStatus() ([]PrefetchStatus, error)
+	ImagesPull(req *PullRequest, out io.Writer) error
+	NetworksPrune() (*NetworksPruneReport, error)
+	NetworkFirewall(name string) (*FirewallPolicy, error)
+	SetNetworkFirewall(name string, policy *FirewallPolicy) (*FirewallPolicy, error)
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vic
+
+import "time"
+
+// VCHInfo describes the Virtual Container Host serving the API
+type VCHInfo struct {
+	Name       string `json:"name"`
+	ID         string `json:"id"`
+	Version    string `json:"version"`
+	APIVersion string `json:"api_version"`
+}
+
+// Capacity describes the resources available to containers and their use
+type Capacity struct {
+	CPUMhz     int64 `json:"cpu_mhz"`
+	MemoryMB   int64 `json:"memory_mb"`
+	Containers int   `json:"containers"`
+	Running    int   `json:"running"`
+}
+
+// AdoptRequest asks for an existing VM to be managed as a container
+type AdoptRequest struct {
+	// VM is the managed object reference or inventory path of the VM
+	VM string `json:"vm"`
+	// Name is the container name, generated if empty
+	Name string `json:"name,omitempty"`
+}
+
+// AdoptResponse holds the ID of the adopted container
+type AdoptResponse struct {
+	ID string `json:"id"`
+}
+
+// ExternalRequest asks for an existing VM to be listed as a read-only external container
+type ExternalRequest struct {
+	// VM is the managed object reference or inventory path of the VM
+	VM string `json:"vm"`
+}
+
+// ExternalResponse holds the ID of the external container
+type ExternalResponse struct {
+	ID string `json:"id"`
+}
+
+// CheckpointRequest asks for a snapshot of a container VM
+type CheckpointRequest struct {
+	Name string `json:"name"`
+	// Memory includes the memory of a running container
+	Memory bool `json:"memory,omitempty"`
+}
+
+// ConsoleTicket grants access to the console of a container VM
+type ConsoleTicket struct {
+	Ticket        string `json:"ticket"`
+	Host          string `json:"host"`
+	Port          int    `json:"port"`
+	SSLThumbprint string `json:"ssl_thumbprint,omitempty"`
+}
+
+// RemoveOptions are the options of docker rm, with the override for protected containers
+type RemoveOptions struct {
+	Force         bool
+	RemoveVolumes bool
+	// ForceProtected removes the container even if its protected label is true
+	ForceProtected bool
+}
+
+// Image prefetch states
+const (
+	PrefetchQueued   = "queued"
+	PrefetchPulling  = "pulling"
+	PrefetchComplete = "complete"
+	PrefetchFailed   = "failed"
+)
+
+// PrefetchRequest asks for images to be pulled into the image store ahead of use
+type PrefetchRequest struct {
+	Images []string `json:"images"`
+}
+
+// PrefetchStatus reports the progress of pulling an image ahead of use
+type PrefetchStatus struct {
+	Image string `json:"image"`
+	State string `json:"state"`
+	// Progress is the last progress message of the pull
+	Progress string `json:"progress,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Completed is when the image was last pulled successfully
+	Completed *time.Time `json:"completed,omitempty"`
+}
+
+// PullRequest asks for images to be pulled concurrently, with the progress of all of them in one stream
+type PullRequest struct {
+	Images []string `json:"images"`
+}
+
+// NetworksPruneReport lists the networks removed by a prune, as docker network prune does
+type NetworksPruneReport struct {
+	NetworksDeleted []string `json:"NetworksDeleted"`
+}
+
+// FirewallPolicy holds the firewall rules of a network, matched in order ahead of the trust level of the
+// network. A rule has the form "ACTION [PROTOCOL [REMOTE [PORTS]]]", for example "allow tcp 10.0.0.0/8 22".
+type FirewallPolicy struct {
+	Ingress []string `json:"ingress"`
+	Egress  []string `json:"egress"`
+}
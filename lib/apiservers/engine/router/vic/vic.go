@@ -0,0 +1,67 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vic
+
+import "github.com/docker/docker/api/server/router"
+
+// APIVersion is the version of the VIC extension API. It changes only when
+// an existing route or type changes incompatibly.
+const APIVersion = "v1"
+
+// PathPrefix is the path under which the VIC extension API is served
+const PathPrefix = "/vic/" + APIVersion
+
+// vicRouter is a router for the operations specific to VIC, which have no
+// counterpart in the docker API
+type vicRouter struct {
+	backend Backend
+	routes  []router.Route
+}
+
+// NewRouter initializes a new VIC router
+func NewRouter(b Backend) router.Router {
+	r := &vicRouter{
+		backend: b,
+	}
+	r.initRoutes()
+	return r
+}
+
+// Routes returns the available routes of the VIC extension API
+func (r *vicRouter) Routes() []router.Route {
+	return r.routes
+}
+
+func (r *vicRouter) initRoutes() {
+	r.routes = []router.Route{
+		// GET
+		router.NewGetRoute(PathPrefix+"/info", r.getInfo),
+		router.NewGetRoute(PathPrefix+"/capacity", r.getCapacity),
+		router.NewGetRoute(PathPrefix+"/images/prefetch", r.getImagesPrefetch),
+		router.NewGetRoute(PathPrefix+"/networks/{name:.*}/firewall", r.getNetworksFirewall),
+		// POST
+		router.NewPostRoute(PathPrefix+"/containers/adopt", r.postContainersAdopt),
+		router.NewPostRoute(PathPrefix+"/containers/external", r.postContainersExternal),
+		router.NewPostRoute(PathPrefix+"/containers/{name:.*}/checkpoint", r.postContainersCheckpoint),
+		router.NewPostRoute(PathPrefix+"/containers/{name:.*}/console", r.postContainersConsole),
+		router.NewPostRoute(PathPrefix+"/images/prefetch", r.postImagesPrefetch),
+		router.NewPostRoute(PathPrefix+"/images/pull", r.postImagesPull),
+		router.NewPostRoute(PathPrefix+"/networks/prune", r.postNetworksPrune),
+		// PUT
+		router.NewPutRoute(PathPrefix+"/networks/{name:.*}/firewall", r.putNetworksFirewall),
+		// DELETE
+		router.NewDeleteRoute(PathPrefix+"/containers/{name:.*}", r.deleteContainers),
+	}
+}
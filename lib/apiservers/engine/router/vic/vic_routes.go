@@ -0,0 +1,205 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vic
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/streamformatter"
+	"golang.org/x/net/context"
+)
+
+func (v *vicRouter) getInfo(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	info, err := v.backend.VCHInfo()
+	if err != nil {
+		return err
+	}
+	info.APIVersion = APIVersion
+	return httputils.WriteJSON(w, http.StatusOK, info)
+}
+
+func (v *vicRouter) getCapacity(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	capacity, err := v.backend.Capacity()
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, capacity)
+}
+
+func (v *vicRouter) postContainersAdopt(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+
+	var req AdoptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	res, err := v.backend.ContainerAdopt(&req)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusCreated, res)
+}
+
+func (v *vicRouter) postContainersExternal(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+
+	var req ExternalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	res, err := v.backend.ContainerRegisterExternal(&req)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusCreated, res)
+}
+
+func (v *vicRouter) postContainersCheckpoint(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+
+	var req CheckpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	if err := v.backend.ContainerCheckpoint(vars["name"], &req); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (v *vicRouter) postContainersConsole(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	ticket, err := v.backend.ContainerConsoleTicket(vars["name"])
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, ticket)
+}
+
+func (v *vicRouter) deleteContainers(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	opts := &RemoveOptions{
+		Force:          httputils.BoolValue(r, "force"),
+		RemoveVolumes:  httputils.BoolValue(r, "v"),
+		ForceProtected: httputils.BoolValue(r, "force_protected"),
+	}
+
+	if err := v.backend.ContainerRemove(vars["name"], opts); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (v *vicRouter) getImagesPrefetch(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	status, err := v.backend.ImagePrefetchStatus()
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, status)
+}
+
+func (v *vicRouter) postImagesPrefetch(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+
+	var req PrefetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	// the pulls continue in the background, their progress is reported by GET
+	status, err := v.backend.ImagePrefetch(&req)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusAccepted, status)
+}
+
+func (v *vicRouter) postImagesPull(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+
+	var req PullRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	output := ioutils.NewWriteFlusher(w)
+	defer output.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// errors before the progress starts are returned as the status, as docker pull does
+	if err := v.backend.ImagesPull(&req, output); err != nil {
+		if !output.Flushed() {
+			return err
+		}
+		sf := streamformatter.NewJSONStreamFormatter()
+		output.Write(sf.FormatError(err))
+	}
+	return nil
+}
+
+func (v *vicRouter) postNetworksPrune(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	report, err := v.backend.NetworksPrune()
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
+func (v *vicRouter) getNetworksFirewall(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	policy, err := v.backend.NetworkFirewall(vars["name"])
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, policy)
+}
+
+func (v *vicRouter) putNetworksFirewall(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.CheckForJSON(r); err != nil {
+		return err
+	}
+
+	var policy FirewallPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		return err
+	}
+
+	// the rules apply to containers from their next start
+	res, err := v.backend.SetNetworkFirewall(vars["name"], &policy)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, res)
+}
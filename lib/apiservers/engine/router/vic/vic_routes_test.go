@@ -0,0 +1,314 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type mockBackend struct {
+	checkpointed map[string]*CheckpointRequest
+	removed      map[string]*RemoveOptions
+	prefetched   []string
+	pulled       []string
+	firewalls    map[string]*FirewallPolicy
+}
+
+func (m *mockBackend) VCHInfo() (*VCHInfo, error) {
+	return &VCHInfo{Name: "vch", ID: "VirtualMachine:vm-1", Version: "v0.8.0"}, nil
+}
+
+func (m *mockBackend) Capacity() (*Capacity, error) {
+	return &Capacity{CPUMhz: 2000, MemoryMB: 4096, Containers: 3, Running: 1}, nil
+}
+
+func (m *mockBackend) ContainerAdopt(req *AdoptRequest) (*AdoptResponse, error) {
+	return &AdoptResponse{ID: "adopted-" + req.VM}, nil
+}
+
+func (m *mockBackend) ContainerRegisterExternal(req *ExternalRequest) (*ExternalResponse, error) {
+	if req.VM == "" {
+		return nil, errors.New("no VM given")
+	}
+	return &ExternalResponse{ID: "external-" + req.VM}, nil
+}
+
+func (m *mockBackend) ContainerCheckpoint(name string, req *CheckpointRequest) error {
+	m.checkpointed[name] = req
+	return nil
+}
+
+func (m *mockBackend) ContainerConsoleTicket(name string) (*ConsoleTicket, error) {
+	return nil, errors.New("no console for " + name)
+}
+
+func (m *mockBackend) ContainerRemove(name string, opts *RemoveOptions) error {
+	m.removed[name] = opts
+	return nil
+}
+
+func (m *mockBackend) ImagePrefetch(req *PrefetchRequest) ([]PrefetchStatus, error) {
+	m.prefetched = append(m.prefetched, req.Images...)
+	return m.ImagePrefetchStatus()
+}
+
+func (m *mockBackend) ImagePrefetchStatus() ([]PrefetchStatus, error) {
+	var status []PrefetchStatus
+	for _, image := range m.prefetched {
+		status = append(status, PrefetchStatus{Image: image, State: PrefetchQueued})
+	}
+	return status, nil
+}
+
+func (m *mockBackend) ImagesPull(req *PullRequest, out io.Writer) error {
+	for _, image := range req.Images {
+		if image == "invalid:" {
+			return errors.New("invalid image reference")
+		}
+	}
+
+	for _, image := range req.Images {
+		m.pulled = append(m.pulled, image)
+		fmt.Fprintf(out, "{\"status\":\"Pull complete\",\"id\":\"%s\"}\r\n", image)
+	}
+	return errors.New("failed to pull missing")
+}
+
+func (m *mockBackend) NetworksPrune() (*NetworksPruneReport, error) {
+	return &NetworksPruneReport{NetworksDeleted: []string{"unused"}}, nil
+}
+
+func (m *mockBackend) NetworkFirewall(name string) (*FirewallPolicy, error) {
+	policy, ok := m.firewalls[name]
+	if !ok {
+		return nil, errors.New("no such network " + name)
+	}
+	return policy, nil
+}
+
+func (m *mockBackend) SetNetworkFirewall(name string, policy *FirewallPolicy) (*FirewallPolicy, error) {
+	if _, ok := m.firewalls[name]; !ok {
+		return nil, errors.New("no such network " + name)
+	}
+	m.firewalls[name] = policy
+	return policy, nil
+}
+
+// handler returns the handler of the route with the method and path
+func handler(t *testing.T, b Backend, method, path string) httputils.APIFunc {
+	for _, r := range NewRouter(b).Routes() {
+		if r.Method() == method && r.Path() == path {
+			return r.Handler()
+		}
+	}
+
+	t.Fatalf("no route for %s %s", method, path)
+	return nil
+}
+
+func TestGetInfo(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/vic/v1/info", nil)
+
+	err := handler(t, &mockBackend{}, "GET", PathPrefix+"/info")(context.Background(), w, r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var info VCHInfo
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&info))
+	assert.Equal(t, "vch", info.Name)
+	assert.Equal(t, APIVersion, info.APIVersion)
+}
+
+func TestPostContainersAdopt(t *testing.T) {
+	h := handler(t, &mockBackend{}, "POST", PathPrefix+"/containers/adopt")
+
+	// the body must be JSON
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/vic/v1/containers/adopt", strings.NewReader(`{"vm": "vm-42"}`))
+	assert.Error(t, h(context.Background(), w, r, nil))
+
+	r, _ = http.NewRequest("POST", "/vic/v1/containers/adopt", strings.NewReader(`{"vm": "vm-42"}`))
+	r.Header.Set("Content-Type", "application/json")
+	require.NoError(t, h(context.Background(), w, r, nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var res AdoptResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	assert.Equal(t, "adopted-vm-42", res.ID)
+}
+
+func TestPostContainersExternal(t *testing.T) {
+	h := handler(t, &mockBackend{}, "POST", PathPrefix+"/containers/external")
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/vic/v1/containers/external", strings.NewReader(`{"vm": "/dc1/vm/legacy-db"}`))
+	r.Header.Set("Content-Type", "application/json")
+	require.NoError(t, h(context.Background(), w, r, nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var res ExternalResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	assert.Equal(t, "external-/dc1/vm/legacy-db", res.ID)
+
+	r, _ = http.NewRequest("POST", "/vic/v1/containers/external", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	assert.EqualError(t, h(context.Background(), httptest.NewRecorder(), r, nil), "no VM given")
+}
+
+func TestPostContainersCheckpoint(t *testing.T) {
+	b := &mockBackend{checkpointed: make(map[string]*CheckpointRequest)}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/vic/v1/containers/web/checkpoint", strings.NewReader(`{"name": "before-upgrade", "memory": true}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	err := handler(t, b, "POST", PathPrefix+"/containers/{name:.*}/checkpoint")(context.Background(), w, r, map[string]string{"name": "web"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, &CheckpointRequest{Name: "before-upgrade", Memory: true}, b.checkpointed["web"])
+}
+
+func TestPostContainersConsoleError(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/vic/v1/containers/web/console", nil)
+
+	err := handler(t, &mockBackend{}, "POST", PathPrefix+"/containers/{name:.*}/console")(context.Background(), w, r, map[string]string{"name": "web"})
+	assert.EqualError(t, err, "no console for web")
+}
+
+func TestDeleteContainers(t *testing.T) {
+	b := &mockBackend{removed: make(map[string]*RemoveOptions)}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("DELETE", "/vic/v1/containers/web?force=1&force_protected=true", nil)
+
+	err := handler(t, b, "DELETE", PathPrefix+"/containers/{name:.*}")(context.Background(), w, r, map[string]string{"name": "web"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, &RemoveOptions{Force: true, ForceProtected: true}, b.removed["web"])
+}
+
+func TestImagesPrefetch(t *testing.T) {
+	b := &mockBackend{}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/vic/v1/images/prefetch", strings.NewReader(`{"images": ["busybox", "nginx:1.11"]}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	err := handler(t, b, "POST", PathPrefix+"/images/prefetch")(context.Background(), w, r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, []string{"busybox", "nginx:1.11"}, b.prefetched)
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/vic/v1/images/prefetch", nil)
+
+	err = handler(t, b, "GET", PathPrefix+"/images/prefetch")(context.Background(), w, r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var status []PrefetchStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	require.Len(t, status, 2)
+	assert.Equal(t, "nginx:1.11", status[1].Image)
+	assert.Equal(t, PrefetchQueued, status[1].State)
+}
+
+func TestImagesPull(t *testing.T) {
+	b := &mockBackend{}
+	h := handler(t, b, "POST", PathPrefix+"/images/pull")
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/vic/v1/images/pull", strings.NewReader(`{"images": ["busybox", "missing"]}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	// failures after the progress starts are reported in the stream
+	require.NoError(t, h(context.Background(), w, r, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, []string{"busybox", "missing"}, b.pulled)
+
+	dec := json.NewDecoder(w.Body)
+	var msgs []map[string]interface{}
+	for dec.More() {
+		var msg map[string]interface{}
+		require.NoError(t, dec.Decode(&msg))
+		msgs = append(msgs, msg)
+	}
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "busybox", msgs[0]["id"])
+	assert.Equal(t, "failed to pull missing", msgs[2]["error"])
+
+	// and before as the error of the request
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/vic/v1/images/pull", strings.NewReader(`{"images": ["invalid:"]}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	assert.EqualError(t, h(context.Background(), w, r, nil), "invalid image reference")
+}
+
+func TestPostNetworksPrune(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/vic/v1/networks/prune", nil)
+
+	err := handler(t, &mockBackend{}, "POST", PathPrefix+"/networks/prune")(context.Background(), w, r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report NetworksPruneReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, []string{"unused"}, report.NetworksDeleted)
+}
+
+func TestNetworksFirewall(t *testing.T) {
+	b := &mockBackend{firewalls: map[string]*FirewallPolicy{"web": {}}}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("PUT", "/vic/v1/networks/web/firewall", strings.NewReader(`{"ingress": ["allow tcp any 80"], "egress": ["deny any 10.0.0.0/8"]}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	err := handler(t, b, "PUT", PathPrefix+"/networks/{name:.*}/firewall")(context.Background(), w, r, map[string]string{"name": "web"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/vic/v1/networks/web/firewall", nil)
+
+	err = handler(t, b, "GET", PathPrefix+"/networks/{name:.*}/firewall")(context.Background(), w, r, map[string]string{"name": "web"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var policy FirewallPolicy
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&policy))
+	assert.Equal(t, []string{"allow tcp any 80"}, policy.Ingress)
+	assert.Equal(t, []string{"deny any 10.0.0.0/8"}, policy.Egress)
+
+	r, _ = http.NewRequest("GET", "/vic/v1/networks/db/firewall", nil)
+	err = handler(t, b, "GET", PathPrefix+"/networks/{name:.*}/firewall")(context.Background(), w, r, map[string]string{"name": "db"})
+	assert.EqualError(t, err, "no such network db")
+}
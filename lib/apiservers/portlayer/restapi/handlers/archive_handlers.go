@@ -0,0 +1,234 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"golang.org/x/net/context"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+
+	"github.com/go-swagger/go-swagger/httpkit"
+	middleware "github.com/go-swagger/go-swagger/httpkit/middleware"
+
+	"github.com/vmware/vic/lib/apiservers/portlayer/models"
+	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations/interaction"
+	"github.com/vmware/vic/lib/archive"
+	"github.com/vmware/vic/lib/portlayer/attach"
+	"github.com/vmware/vic/lib/portlayer/exec"
+	vsphereSpl "github.com/vmware/vic/lib/portlayer/storage/vsphere"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// localArchive serves archives of a container filesystem mounted in the appliance
+type localArchive struct {
+	root string
+}
+
+func (l *localArchive) Stat(path string) (*types.ContainerPathStat, error) {
+	return archive.Stat(l.root, path)
+}
+
+func (l *localArchive) Read(path string) (io.ReadCloser, error) {
+	rc, _, err := archive.Read(l.root, path)
+	return rc, err
+}
+
+func (l *localArchive) Write(path string, content io.Reader, noOverwriteDirNonDir bool) error {
+	return archive.Write(l.root, path, content, noOverwriteDirNonDir)
+}
+
+// containerArchive returns the archive interface for the filesystem of the container, served by the
+// tether if the container is running and from its layer disk otherwise. The returned function releases
+// the filesystem once the archive interface is no longer in use. On error the HTTP status to return is
+// provided.
+func (i *InteractionHandlersImpl) containerArchive(id, path string, write bool) (attach.ArchiveInteraction, func(), int, error) {
+	container := exec.Containers.Container(id)
+	if container == nil {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("container %s not found", id)
+	}
+
+	info := container.Info()
+	if info.State() == exec.StateRunning {
+		ai, err := i.attachServer.Archive(context.Background(), id, interactionTimeout)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+		return ai, func() {}, http.StatusOK, nil
+	}
+
+	// the volumes of a stopped container are not attached with its layer disk
+	if mount := vsphereSpl.VolumePath(info, path); mount != "" {
+		return nil, nil, http.StatusConflict, fmt.Errorf("%s is on the volume mounted at %s, which is only accessible while container %s is running", path, mount, id)
+	}
+
+	if i.containerStore == nil {
+		return nil, nil, http.StatusConflict, fmt.Errorf("container %s is not running", id)
+	}
+
+	op := trace.NewOperation(context.Background(), "archive(%s)", id)
+	root, cleanup, err := i.containerStore.Mount(op, info, write)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+
+	return &localArchive{root: root}, cleanup, http.StatusOK, nil
+}
+
+// archiveStatus returns the HTTP status for an archive error
+func archiveStatus(err error) int {
+	switch {
+	case os.IsNotExist(err):
+		return http.StatusNotFound
+	case err == archive.ErrExtractPointNotDirectory:
+		return http.StatusBadRequest
+	default:
+		if perr, ok := err.(*os.PathError); ok && perr.Err.Error() == archive.ErrExtractPointNotDirectory.Error() {
+			// reported by the tether
+			return http.StatusBadRequest
+		}
+		return http.StatusInternalServerError
+	}
+}
+
+// StatContainerPathHandler returns the stat of a path in the container filesystem
+func (i *InteractionHandlersImpl) StatContainerPathHandler(params interaction.StatContainerPathParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.ID))
+
+	ai, cleanup, code, err := i.containerArchive(params.ID, params.Path, false)
+	if err == nil {
+		defer cleanup()
+
+		var st *types.ContainerPathStat
+		if st, err = ai.Stat(params.Path); err == nil {
+			return interaction.NewStatContainerPathOK().WithPayload(&models.ContainerPathStat{
+				Name:       st.Name,
+				Size:       st.Size,
+				Mode:       int64(st.Mode),
+				Mtime:      st.Mtime.UnixNano(),
+				LinkTarget: st.LinkTarget,
+			})
+		}
+		code = archiveStatus(err)
+	}
+
+	log.Errorf("%s", err.Error())
+	e := &models.Error{Message: err.Error()}
+
+	switch code {
+	case http.StatusNotFound:
+		return interaction.NewStatContainerPathNotFound().WithPayload(e)
+	case http.StatusConflict:
+		return interaction.NewStatContainerPathConflict().WithPayload(e)
+	default:
+		return interaction.NewStatContainerPathInternalServerError().WithPayload(e)
+	}
+}
+
+// GetContainerArchiveHandler returns a tar archive of a path in the container filesystem
+func (i *InteractionHandlersImpl) GetContainerArchiveHandler(params interaction.GetContainerArchiveParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.ID))
+
+	ai, cleanup, code, err := i.containerArchive(params.ID, params.Path, false)
+	if err == nil {
+		// a missing path would only show once the archive is streamed
+		if _, err = ai.Stat(params.Path); err == nil {
+			var data io.ReadCloser
+			if data, err = ai.Read(params.Path); err == nil {
+				return NewContainerArchiveHandler(params.ID, data, cleanup)
+			}
+		}
+		cleanup()
+		code = archiveStatus(err)
+	}
+
+	log.Errorf("%s", err.Error())
+	e := &models.Error{Message: err.Error()}
+
+	switch code {
+	case http.StatusNotFound:
+		return interaction.NewGetContainerArchiveNotFound().WithPayload(e)
+	case http.StatusConflict:
+		return interaction.NewGetContainerArchiveConflict().WithPayload(e)
+	default:
+		return interaction.NewGetContainerArchiveInternalServerError().WithPayload(e)
+	}
+}
+
+// PutContainerArchiveHandler extracts a tar archive to a directory in the container filesystem
+func (i *InteractionHandlersImpl) PutContainerArchiveHandler(params interaction.PutContainerArchiveParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.ID))
+
+	noOverwriteDirNonDir := false
+	if params.NoOverwriteDirNonDir != nil {
+		noOverwriteDirNonDir = *params.NoOverwriteDirNonDir
+	}
+
+	ai, cleanup, code, err := i.containerArchive(params.ID, params.Path, true)
+	if err == nil {
+		err = ai.Write(params.Path, params.Archive, noOverwriteDirNonDir)
+		cleanup()
+		if err == nil {
+			return interaction.NewPutContainerArchiveOK()
+		}
+		code = archiveStatus(err)
+	}
+
+	log.Errorf("%s", err.Error())
+	e := &models.Error{Message: err.Error()}
+
+	switch code {
+	case http.StatusBadRequest:
+		return interaction.NewPutContainerArchiveBadRequest().WithPayload(e)
+	case http.StatusNotFound:
+		return interaction.NewPutContainerArchiveNotFound().WithPayload(e)
+	case http.StatusConflict:
+		return interaction.NewPutContainerArchiveConflict().WithPayload(e)
+	default:
+		return interaction.NewPutContainerArchiveInternalServerError().WithPayload(e)
+	}
+}
+
+// ContainerArchiveHandler is the custom return handler for container archives
+type ContainerArchiveHandler struct {
+	containerID string
+	data        io.ReadCloser
+	cleanup     func()
+}
+
+// NewContainerArchiveHandler creates a ContainerArchiveHandler streaming data, calling cleanup once done
+func NewContainerArchiveHandler(id string, data io.ReadCloser, cleanup func()) *ContainerArchiveHandler {
+	return &ContainerArchiveHandler{containerID: id, data: data, cleanup: cleanup}
+}
+
+// WriteResponse to the client
+func (c *ContainerArchiveHandler) WriteResponse(rw http.ResponseWriter, producer httpkit.Producer) {
+	defer c.cleanup()
+	defer c.data.Close()
+
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(rw, c.data); err != nil {
+		log.Errorf("Error copying archive for container %s: %s", c.containerID, err)
+	} else {
+		log.Debugf("Finished copying archive for container %s", c.containerID)
+	}
+}
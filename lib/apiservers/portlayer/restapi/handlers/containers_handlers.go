@@ -18,11 +18,14 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/go-swagger/go-swagger/httpkit"
 	middleware "github.com/go-swagger/go-swagger/httpkit/middleware"
 	"golang.org/x/net/context"
 
@@ -30,11 +33,16 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/vmware/govmomi/vim25/types"
+
 	"github.com/vmware/vic/lib/apiservers/portlayer/models"
 	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations"
 	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations/containers"
 	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/config/executor/validation"
 	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/metrics"
+	"github.com/vmware/vic/lib/portlayer/store"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/uid"
 	"github.com/vmware/vic/pkg/version"
@@ -60,6 +68,8 @@ func (handler *ContainersHandlersImpl) Configure(api *operations.PortLayerAPI, h
 	api.ContainersContainerSignalHandler = containers.ContainerSignalHandlerFunc(handler.ContainerSignalHandler)
 	api.ContainersGetContainerLogsHandler = containers.GetContainerLogsHandlerFunc(handler.GetContainerLogsHandler)
 	api.ContainersContainerWaitHandler = containers.ContainerWaitHandlerFunc(handler.ContainerWaitHandler)
+	api.ContainersGetContainerStatsHandler = containers.GetContainerStatsHandlerFunc(handler.GetContainerStatsHandler)
+	api.ContainersRegisterExternalHandler = containers.RegisterExternalHandlerFunc(handler.RegisterExternalHandler)
 
 	handler.handlerCtx = handlerCtx
 }
@@ -106,6 +116,8 @@ func (handler *ContainersHandlersImpl) CreateHandler(params containers.CreatePar
 				},
 				Tty:    *params.CreateConfig.Tty,
 				Attach: *params.CreateConfig.Attach,
+				// a working directory missing from the image is created on start
+				CreateDir: true,
 				Cmd: executor.Cmd{
 					Env:  params.CreateConfig.Env,
 					Dir:  *params.CreateConfig.WorkingDir,
@@ -127,6 +139,21 @@ func (handler *ContainersHandlersImpl) CreateHandler(params containers.CreatePar
 		}
 	}
 
+	if params.CreateConfig.Deadline != nil {
+		m.Sessions[id].Deadline = *params.CreateConfig.Deadline
+	}
+
+	if params.CreateConfig.LogConfig != nil && params.CreateConfig.LogConfig.Type != nil {
+		m.LogConfig = executor.LogConfig{
+			Type:   *params.CreateConfig.LogConfig.Type,
+			Config: params.CreateConfig.LogConfig.Config,
+		}
+	}
+
+	if params.CreateConfig.AutoRemove != nil {
+		m.AutoRemove = *params.CreateConfig.AutoRemove
+	}
+
 	log.Infof("CreateHandler Metadata: %#v", m)
 
 	// Create the executor.ExecutorCreateConfig
@@ -230,8 +257,10 @@ func (handler *ContainersHandlersImpl) CommitHandler(params containers.CommitPar
 	if err := h.Commit(context.Background(), handler.handlerCtx.Session, params.WaitTime); err != nil {
 		log.Errorf("CommitHandler error on handle(%s) for %s: %#v", h.String(), h.ExecConfig.ID, err)
 		switch err := err.(type) {
-		case exec.ConcurrentAccessError:
+		case exec.ConcurrentAccessError, exec.ExternalError, store.NameReservedError:
 			return containers.NewCommitConflict().WithPayload(&models.Error{Message: err.Error()})
+		case *validation.Error:
+			return containers.NewCommitDefault(http.StatusBadRequest).WithPayload(&models.Error{Message: err.Error()})
 		default:
 			return containers.NewCommitDefault(http.StatusServiceUnavailable).WithPayload(&models.Error{Message: err.Error()})
 		}
@@ -256,12 +285,13 @@ func (handler *ContainersHandlersImpl) RemoveContainerHandler(params containers.
 	}
 
 	// NOTE: this should allowing batching of operations, as with Create, Start, Stop, et al
-	err := container.Remove(context.Background(), handler.handlerCtx.Session)
+	volumes := params.V != nil && *params.V
+	err := container.Remove(context.Background(), handler.handlerCtx.Session, volumes)
 	if err != nil {
 		switch err := err.(type) {
 		case exec.NotFoundError:
 			return containers.NewContainerRemoveNotFound()
-		case exec.RemovePowerError:
+		case exec.RemovePowerError, exec.ExternalError:
 			return containers.NewContainerRemoveConflict().WithPayload(&models.Error{Message: err.Error()})
 		default:
 			return containers.NewContainerRemoveInternalServerError()
@@ -359,6 +389,47 @@ func (handler *ContainersHandlersImpl) GetContainerLogsHandler(params containers
 	return NewContainerOutputHandler("logs").WithPayload(detachableOut, params.ID)
 }
 
+// GetContainerStatsHandler streams the performance metrics of a running container VM
+func (handler *ContainersHandlersImpl) GetContainerStatsHandler(params containers.GetContainerStatsParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.ID))
+
+	c := exec.Containers.Container(uid.Parse(params.ID).String())
+	if c == nil {
+		return containers.NewGetContainerStatsNotFound().WithPayload(&models.Error{
+			Message: fmt.Sprintf("container %s not found", params.ID),
+		})
+	}
+
+	if c.CurrentState() != exec.StateRunning {
+		return containers.NewGetContainerStatsConflict().WithPayload(&models.Error{
+			Message: fmt.Sprintf("container %s is not running", params.ID),
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := c.Metrics(ctx)
+	if err != nil {
+		cancel()
+		return containers.NewGetContainerStatsInternalServerError().WithPayload(&models.Error{Message: err.Error()})
+	}
+
+	// the stream ends when the container stops
+	go func() {
+		select {
+		case <-c.WaitForState(exec.StateStopped):
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return &containerStatsResponder{
+		id:      params.ID,
+		metrics: ch,
+		cancel:  cancel,
+		stream:  params.Stream == nil || *params.Stream,
+	}
+}
+
 func (handler *ContainersHandlersImpl) ContainerWaitHandler(params containers.ContainerWaitParams) middleware.Responder {
 	defer trace.End(trace.Begin(fmt.Sprintf("%s:%d", params.ID, params.Timeout)))
 
@@ -395,6 +466,38 @@ func (handler *ContainersHandlersImpl) ContainerWaitHandler(params containers.Co
 	}
 }
 
+// RegisterExternalHandler lists an existing VM, given by managed object reference or inventory path, as a
+// read-only external container
+func (handler *ContainersHandlersImpl) RegisterExternalHandler(params containers.RegisterExternalParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.Config.VM))
+
+	ctx := context.Background()
+	sess := handler.handlerCtx.Session
+
+	var ref types.ManagedObjectReference
+	if !ref.FromString(params.Config.VM) || ref.Type != "VirtualMachine" {
+		vm, err := sess.Finder.VirtualMachine(ctx, params.Config.VM)
+		if err != nil {
+			return containers.NewRegisterExternalNotFound().WithPayload(&models.Error{Message: err.Error()})
+		}
+		ref = vm.Reference()
+	}
+
+	c, err := exec.RegisterExternal(ctx, sess, ref)
+	if err != nil {
+		switch err.(type) {
+		case exec.NotFoundError:
+			return containers.NewRegisterExternalNotFound().WithPayload(&models.Error{Message: fmt.Sprintf("VM %s not found", params.Config.VM)})
+		case exec.ExternalVMError:
+			return containers.NewRegisterExternalConflict().WithPayload(&models.Error{Message: err.Error()})
+		default:
+			return containers.NewRegisterExternalInternalServerError().WithPayload(&models.Error{Message: err.Error()})
+		}
+	}
+
+	return containers.NewRegisterExternalOK().WithPayload(convertContainerToContainerInfo(c.Info()))
+}
+
 // utility function to convert from a Container type to the API Model ContainerInfo (which should prob be called ContainerDetail)
 func convertContainerToContainerInfo(container *exec.ContainerInfo) *models.ContainerInfo {
 	defer trace.End(trace.Begin(container.ExecConfig.ID))
@@ -428,6 +531,13 @@ func convertContainerToContainerInfo(container *exec.ContainerInfo) *models.Cont
 
 	info.ContainerConfig.StorageSize = &container.VMUnsharedDisk
 
+	if container.TetherVersion != nil {
+		tetherVersion := container.TetherVersion.ShortVersion()
+		info.ContainerConfig.TetherVersion = &tetherVersion
+		outdated := container.TetherOutdated()
+		info.ContainerConfig.TetherOutdated = &outdated
+	}
+
 	if container.ExecConfig.Annotations != nil && len(container.ExecConfig.Annotations) > 0 {
 		info.ContainerConfig.Annotations = make(map[string]string)
 
@@ -448,6 +558,9 @@ func convertContainerToContainerInfo(container *exec.ContainerInfo) *models.Cont
 	exitcode := int32(container.ExecConfig.Sessions[ccid].ExitStatus)
 	info.ProcessConfig.ExitCode = &exitcode
 
+	oomKilled := container.ExecConfig.Sessions[ccid].OOMKilled
+	info.ProcessConfig.OOMKilled = &oomKilled
+
 	startTime := container.ExecConfig.Sessions[ccid].StartTime
 	info.ProcessConfig.StartTime = &startTime
 
@@ -504,3 +617,97 @@ func convertContainerToContainerInfo(container *exec.ContainerInfo) *models.Cont
 
 	return info
 }
+
+// containerStatsResponder writes the metrics of a container as a stream of JSON objects
+type containerStatsResponder struct {
+	id      string
+	metrics <-chan *metrics.VMMetrics
+	cancel  context.CancelFunc
+	stream  bool
+}
+
+// WriteResponse writes the metrics until the subscription ends or the client goes away
+func (r *containerStatsResponder) WriteResponse(rw http.ResponseWriter, producer httpkit.Producer) {
+	defer r.cancel()
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(rw)
+	for m := range r.metrics {
+		if err := enc.Encode(toContainerStats(m)); err != nil {
+			log.Debugf("Error writing stats stream for container %s: %s", r.id, err)
+			return
+		}
+
+		if f, ok := rw.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if !r.stream {
+			return
+		}
+	}
+	log.Debugf("Finished writing stats stream for container %s", r.id)
+}
+
+// toContainerStats converts the metrics of a container VM to the API model, listing the virtual NICs and
+// disks in the order of their device instances
+func toContainerStats(m *metrics.VMMetrics) *models.ContainerStats {
+	s := &models.ContainerStats{
+		CPU: &models.CPUStats{
+			Elapsed: int64(m.CPU.Elapsed),
+		},
+		Memory: &models.MemoryStats{
+			Consumed:    int64(m.Memory.Consumed),
+			MaxConsumed: int64(m.Memory.MaxConsumed),
+			Provisioned: int64(m.Memory.Provisioned),
+		},
+	}
+
+	if !m.SampleTime.IsZero() {
+		s.SampleTime = m.SampleTime.UnixNano()
+	}
+
+	for _, u := range m.CPU.Usage {
+		s.CPU.Usage = append(s.CPU.Usage, int64(u))
+	}
+
+	nics := make([]string, 0, len(m.Networks))
+	for k := range m.Networks {
+		nics = append(nics, k)
+	}
+	sort.Strings(nics)
+
+	for _, instance := range nics {
+		n := m.Networks[instance]
+		s.Networks = append(s.Networks, &models.NetworkStats{
+			Instance:  instance,
+			RxBytes:   int64(n.RxBytes),
+			RxPackets: int64(n.RxPackets),
+			RxDropped: int64(n.RxDropped),
+			TxBytes:   int64(n.TxBytes),
+			TxPackets: int64(n.TxPackets),
+			TxDropped: int64(n.TxDropped),
+		})
+	}
+
+	disks := make([]string, 0, len(m.Disks))
+	for k := range m.Disks {
+		disks = append(disks, k)
+	}
+	sort.Strings(disks)
+
+	for _, instance := range disks {
+		d := m.Disks[instance]
+		s.Disks = append(s.Disks, &models.DiskStats{
+			Instance:   instance,
+			ReadBytes:  int64(d.ReadBytes),
+			Reads:      int64(d.Reads),
+			WriteBytes: int64(d.WriteBytes),
+			Writes:     int64(d.Writes),
+		})
+	}
+
+	return s
+}
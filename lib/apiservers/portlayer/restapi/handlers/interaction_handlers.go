@@ -28,18 +28,25 @@ import (
 	"github.com/go-swagger/go-swagger/httpkit"
 	middleware "github.com/go-swagger/go-swagger/httpkit/middleware"
 
+	"github.com/vmware/vic/cmd/tether/msgs"
 	"github.com/vmware/vic/lib/apiservers/portlayer/models"
 	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations"
 	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations/interaction"
+	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/portlayer/attach"
 	"github.com/vmware/vic/lib/portlayer/constants"
+	"github.com/vmware/vic/lib/portlayer/event/events"
 	"github.com/vmware/vic/lib/portlayer/exec"
+	vsphereSpl "github.com/vmware/vic/lib/portlayer/storage/vsphere"
 	"github.com/vmware/vic/pkg/trace"
 )
 
 // InteractionHandlersImpl is the receiver for all of the interaction handler methods
 type InteractionHandlersImpl struct {
 	attachServer *attach.Server
+
+	// gives access to the filesystems of stopped containers, nil if unavailable
+	containerStore *vsphereSpl.ContainerStore
 }
 
 const (
@@ -47,7 +54,7 @@ const (
 	attachStdinInitString               = "v1c#>"
 )
 
-func (i *InteractionHandlersImpl) Configure(api *operations.PortLayerAPI, _ *HandlerContext) {
+func (i *InteractionHandlersImpl) Configure(api *operations.PortLayerAPI, handlerCtx *HandlerContext) {
 
 	api.InteractionInteractionJoinHandler = interaction.InteractionJoinHandlerFunc(i.JoinHandler)
 	api.InteractionInteractionBindHandler = interaction.InteractionBindHandlerFunc(i.BindHandler)
@@ -60,7 +67,39 @@ func (i *InteractionHandlersImpl) Configure(api *operations.PortLayerAPI, _ *Han
 
 	api.InteractionContainerCloseStdinHandler = interaction.ContainerCloseStdinHandlerFunc(i.ContainerCloseStdinHandler)
 
+	api.InteractionStatContainerPathHandler = interaction.StatContainerPathHandlerFunc(i.StatContainerPathHandler)
+	api.InteractionGetContainerArchiveHandler = interaction.GetContainerArchiveHandlerFunc(i.GetContainerArchiveHandler)
+	api.InteractionPutContainerArchiveHandler = interaction.PutContainerArchiveHandlerFunc(i.PutContainerArchiveHandler)
+
+	api.InteractionTaskLaunchHandler = interaction.TaskLaunchHandlerFunc(i.TaskLaunchHandler)
+	api.InteractionTaskInspectHandler = interaction.TaskInspectHandlerFunc(i.TaskInspectHandler)
+	api.InteractionContainerTopHandler = interaction.ContainerTopHandlerFunc(i.ContainerTopHandler)
+
+	if handlerCtx != nil && handlerCtx.Session != nil {
+		op := trace.NewOperation(context.Background(), "configure container store")
+
+		var err error
+		if i.containerStore, err = vsphereSpl.NewContainerStore(op, handlerCtx.Session); err != nil {
+			log.Errorf("Files of stopped containers will not be accessible: %s", err)
+		}
+	}
+
 	i.attachServer = attach.NewAttachServer(constants.ManagementHostName, 0)
+	i.attachServer.SetConnectHandler(func(id string, v *msgs.VersionMsg) {
+		exec.TetherConnected(id, v.Build())
+	})
+	if exec.Config.SerialConcentrator == config.SerialConcentratorAppliance {
+		i.attachServer.EnableConcentrator(constants.SerialConcentratorPort)
+	}
+
+	// the output recorded for replay is kept until the container is removed
+	if em := exec.Config.EventManager; em != nil {
+		em.Subscribe(events.NewEventType(events.ContainerEvent{}).Topic(), "interaction", func(ie events.Event) {
+			if ie.String() == events.ContainerRemoved {
+				i.attachServer.Forget(ie.Reference())
+			}
+		})
+	}
 
 	if err := i.attachServer.Start(false); err != nil {
 		log.Fatalf("Attach server unable to start: %s", err)
@@ -285,7 +324,7 @@ func (i *InteractionHandlersImpl) ContainerGetStdoutHandler(params interaction.C
 
 	return NewContainerOutputHandler("stdout").WithPayload(
 		NewFlushingReader(
-			session.Stdout(),
+			i.attachServer.Output(params.ID, "stdout", session.Stdout(), replayBytes(params.Replay)),
 		),
 		params.ID,
 	)
@@ -328,12 +367,23 @@ func (i *InteractionHandlersImpl) ContainerGetStderrHandler(params interaction.C
 
 	return NewContainerOutputHandler("stderr").WithPayload(
 		NewFlushingReader(
-			session.Stderr(),
+			i.attachServer.Output(params.ID, "stderr", session.Stderr(), replayBytes(params.Replay)),
 		),
 		params.ID,
 	)
 }
 
+// replayBytes converts the kilobytes of output requested for replay to bytes
+func replayBytes(kb *int64) int {
+	if kb == nil || *kb <= 0 {
+		return 0
+	}
+	if *kb > attach.ReplaySize/1024 {
+		return attach.ReplaySize
+	}
+	return int(*kb * 1024)
+}
+
 // GenericFlusher is a custom reader to allow us to detach cleanly during an io.Copy
 type GenericFlusher interface {
 	Flush()
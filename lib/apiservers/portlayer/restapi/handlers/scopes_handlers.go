@@ -26,6 +26,7 @@ import (
 	"github.com/vmware/vic/lib/apiservers/portlayer/models"
 	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations"
 	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations/scopes"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/portlayer/exec"
 	"github.com/vmware/vic/lib/portlayer/network"
 	"github.com/vmware/vic/pkg/ip"
@@ -43,6 +44,7 @@ type ScopesHandlersImpl struct {
 func (handler *ScopesHandlersImpl) Configure(api *operations.PortLayerAPI, handlerCtx *HandlerContext) {
 	api.ScopesCreateScopeHandler = scopes.CreateScopeHandlerFunc(handler.ScopesCreate)
 	api.ScopesDeleteScopeHandler = scopes.DeleteScopeHandlerFunc(handler.ScopesDelete)
+	api.ScopesPruneScopesHandler = scopes.PruneScopesHandlerFunc(handler.ScopesPrune)
 	api.ScopesListAllHandler = scopes.ListAllHandlerFunc(handler.ScopesListAll)
 	api.ScopesListHandler = scopes.ListHandlerFunc(handler.ScopesList)
 	api.ScopesGetContainerEndpointsHandler = scopes.GetContainerEndpointsHandlerFunc(handler.ScopesGetContainerEndpoints)
@@ -50,6 +52,11 @@ func (handler *ScopesHandlersImpl) Configure(api *operations.PortLayerAPI, handl
 	api.ScopesRemoveContainerHandler = scopes.RemoveContainerHandlerFunc(handler.ScopesRemoveContainer)
 	api.ScopesBindContainerHandler = scopes.BindContainerHandlerFunc(handler.ScopesBindContainer)
 	api.ScopesUnbindContainerHandler = scopes.UnbindContainerHandlerFunc(handler.ScopesUnbindContainer)
+	api.ScopesListReservationsHandler = scopes.ListReservationsHandlerFunc(handler.ScopesListReservations)
+	api.ScopesReserveIPHandler = scopes.ReserveIPHandlerFunc(handler.ScopesReserveIP)
+	api.ScopesReleaseIPHandler = scopes.ReleaseIPHandlerFunc(handler.ScopesReleaseIP)
+	api.ScopesGetFirewallHandler = scopes.GetFirewallHandlerFunc(handler.ScopesGetFirewall)
+	api.ScopesSetFirewallHandler = scopes.SetFirewallHandlerFunc(handler.ScopesSetFirewall)
 
 	handler.netCtx = network.DefaultContext
 	handler.handlerCtx = handlerCtx
@@ -82,6 +89,46 @@ func parseScopeConfig(cfg *models.ScopeConfig) (subnet *net.IPNet, gateway net.I
 	return
 }
 
+// parseFirewallPolicy returns the ingress and egress rules of a firewall policy
+func parseFirewallPolicy(p *models.FirewallPolicy) (ingress, egress []executor.FirewallRule, err error) {
+	if p == nil {
+		return nil, nil, nil
+	}
+
+	for _, r := range p.Ingress {
+		rule, err := executor.ParseFirewallRule(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		ingress = append(ingress, rule)
+	}
+
+	for _, r := range p.Egress {
+		rule, err := executor.ParseFirewallRule(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		egress = append(egress, rule)
+	}
+
+	return ingress, egress, nil
+}
+
+func toFirewallPolicy(ingress, egress []executor.FirewallRule) *models.FirewallPolicy {
+	p := &models.FirewallPolicy{
+		Ingress: make([]string, len(ingress)),
+		Egress:  make([]string, len(egress)),
+	}
+	for i, r := range ingress {
+		p.Ingress[i] = r.String()
+	}
+	for i, r := range egress {
+		p.Egress[i] = r.String()
+	}
+
+	return p
+}
+
 func (handler *ScopesHandlersImpl) listScopes(idName string) ([]*models.ScopeConfig, error) {
 	defer trace.End(trace.Begin(idName))
 	scs, err := handler.netCtx.Scopes(context.Background(), &idName)
@@ -92,6 +139,13 @@ func (handler *ScopesHandlersImpl) listScopes(idName string) ([]*models.ScopeCon
 	cfgs := make([]*models.ScopeConfig, len(scs))
 	for i, s := range scs {
 		cfgs[i] = toScopeConfig(s)
+
+		b, err := s.Backing(context.Background())
+		if err != nil {
+			log.Warnf("Unable to look up the vSphere network backing scope %s: %s", s.Name(), err)
+			continue
+		}
+		cfgs[i].Backing = toNetworkBacking(b)
 	}
 
 	return cfgs, nil
@@ -111,7 +165,12 @@ func (handler *ScopesHandlersImpl) ScopesCreate(params scopes.CreateScopeParams)
 
 	subnet, gateway, dns, err := parseScopeConfig(cfg)
 	if err != nil {
-		return scopes.NewCreateScopeDefault(http.StatusServiceUnavailable).WithPayload(errorPayload(err))
+		return scopes.NewCreateScopeDefault(http.StatusBadRequest).WithPayload(errorPayload(err))
+	}
+
+	ingress, egress, err := parseFirewallPolicy(cfg.Firewall)
+	if err != nil {
+		return scopes.NewCreateScopeDefault(http.StatusBadRequest).WithPayload(errorPayload(err))
 	}
 
 	s, err := handler.netCtx.NewScope(context.Background(), cfg.ScopeType, cfg.Name, subnet, gateway, dns, cfg.IPAM)
@@ -123,6 +182,15 @@ func (handler *ScopesHandlersImpl) ScopesCreate(params scopes.CreateScopeParams)
 		return scopes.NewCreateScopeDefault(http.StatusServiceUnavailable).WithPayload(errorPayload(err))
 	}
 
+	if len(ingress) > 0 || len(egress) > 0 {
+		if err = handler.netCtx.SetFirewall(context.Background(), s.Name(), ingress, egress); err != nil {
+			if derr := handler.netCtx.DeleteScope(context.Background(), s.Name()); derr != nil {
+				log.Warnf("Unable to remove scope %s after failing to set its firewall rules: %s", s.Name(), derr)
+			}
+			return scopes.NewCreateScopeDefault(http.StatusServiceUnavailable).WithPayload(errorPayload(err))
+		}
+	}
+
 	return scopes.NewCreateScopeCreated().WithPayload(toScopeConfig(s))
 }
 
@@ -134,6 +202,9 @@ func (handler *ScopesHandlersImpl) ScopesDelete(params scopes.DeleteScopeParams)
 		case network.ResourceNotFoundError:
 			return scopes.NewDeleteScopeNotFound().WithPayload(errorPayload(err))
 
+		case network.ForbiddenError:
+			return scopes.NewDeleteScopeForbidden().WithPayload(errorPayload(err))
+
 		default:
 			return scopes.NewDeleteScopeInternalServerError().WithPayload(errorPayload(err))
 		}
@@ -142,6 +213,17 @@ func (handler *ScopesHandlersImpl) ScopesDelete(params scopes.DeleteScopeParams)
 	return scopes.NewDeleteScopeOK()
 }
 
+func (handler *ScopesHandlersImpl) ScopesPrune() middleware.Responder {
+	defer trace.End(trace.Begin(""))
+
+	pruned, err := handler.netCtx.PruneScopes(context.Background())
+	if err != nil {
+		return scopes.NewPruneScopesDefault(http.StatusInternalServerError).WithPayload(errorPayload(err))
+	}
+
+	return scopes.NewPruneScopesOK().WithPayload(pruned)
+}
+
 func (handler *ScopesHandlersImpl) ScopesListAll() middleware.Responder {
 	defer trace.End(trace.Begin(""))
 
@@ -347,9 +429,26 @@ func toScopeConfig(scope *network.Scope) *models.ScopeConfig {
 		sc.Endpoints[i] = toEndpointConfig(e)
 	}
 
+	if ingress, egress := scope.Firewall(); len(ingress) > 0 || len(egress) > 0 {
+		sc.Firewall = toFirewallPolicy(ingress, egress)
+	}
+
 	return sc
 }
 
+func toNetworkBacking(b *network.Backing) *models.NetworkBacking {
+	if b == nil {
+		return nil
+	}
+
+	return &models.NetworkBacking{
+		Name:   &b.Name,
+		Switch: &b.Switch,
+		VlanID: &b.VLAN,
+		Moref:  b.Moref,
+	}
+}
+
 func toEndpointConfig(e *network.Endpoint) *models.EndpointConfig {
 	addr := ""
 	if !ip.IsUnspecifiedIP(e.IP()) {
@@ -371,3 +470,100 @@ func toEndpointConfig(e *network.Endpoint) *models.EndpointConfig {
 		Ports:     ecports,
 	}
 }
+
+func (handler *ScopesHandlersImpl) ScopesListReservations(params scopes.ListReservationsParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.IDName))
+
+	addrs, err := handler.netCtx.Reservations(params.IDName)
+	if err != nil {
+		if _, ok := err.(network.ResourceNotFoundError); ok {
+			return scopes.NewListReservationsNotFound().WithPayload(errorPayload(err))
+		}
+
+		return scopes.NewListReservationsInternalServerError().WithPayload(errorPayload(err))
+	}
+
+	res := make([]string, len(addrs))
+	for i, a := range addrs {
+		res[i] = a.String()
+	}
+
+	return scopes.NewListReservationsOK().WithPayload(res)
+}
+
+func (handler *ScopesHandlersImpl) ScopesReserveIP(params scopes.ReserveIPParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.IDName))
+
+	addr := net.ParseIP(params.Reservation.IP)
+	if addr == nil {
+		return scopes.NewReserveIPInternalServerError().WithPayload(errorPayload(fmt.Errorf("invalid ip address %q", params.Reservation.IP)))
+	}
+
+	if err := handler.netCtx.ReserveIP(context.Background(), params.IDName, addr); err != nil {
+		switch err := err.(type) {
+		case network.ResourceNotFoundError:
+			return scopes.NewReserveIPNotFound().WithPayload(errorPayload(err))
+
+		case network.DuplicateResourceError:
+			return scopes.NewReserveIPConflict().WithPayload(errorPayload(err))
+
+		default:
+			return scopes.NewReserveIPInternalServerError().WithPayload(errorPayload(err))
+		}
+	}
+
+	return scopes.NewReserveIPCreated().WithPayload(&models.IPReservation{IP: addr.String()})
+}
+
+func (handler *ScopesHandlersImpl) ScopesReleaseIP(params scopes.ReleaseIPParams) middleware.Responder {
+	defer trace.End(trace.Begin(fmt.Sprintf("%s %s", params.IDName, params.IP)))
+
+	addr := net.ParseIP(params.IP)
+	if addr == nil {
+		return scopes.NewReleaseIPNotFound().WithPayload(errorPayload(fmt.Errorf("invalid ip address %q", params.IP)))
+	}
+
+	if err := handler.netCtx.ReleaseIP(context.Background(), params.IDName, addr); err != nil {
+		if _, ok := err.(network.ResourceNotFoundError); ok {
+			return scopes.NewReleaseIPNotFound().WithPayload(errorPayload(err))
+		}
+
+		return scopes.NewReleaseIPInternalServerError().WithPayload(errorPayload(err))
+	}
+
+	return scopes.NewReleaseIPOK()
+}
+
+func (handler *ScopesHandlersImpl) ScopesGetFirewall(params scopes.GetFirewallParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.IDName))
+
+	ingress, egress, err := handler.netCtx.Firewall(params.IDName)
+	if err != nil {
+		if _, ok := err.(network.ResourceNotFoundError); ok {
+			return scopes.NewGetFirewallNotFound().WithPayload(errorPayload(err))
+		}
+
+		return scopes.NewGetFirewallInternalServerError().WithPayload(errorPayload(err))
+	}
+
+	return scopes.NewGetFirewallOK().WithPayload(toFirewallPolicy(ingress, egress))
+}
+
+func (handler *ScopesHandlersImpl) ScopesSetFirewall(params scopes.SetFirewallParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.IDName))
+
+	ingress, egress, err := parseFirewallPolicy(params.Policy)
+	if err != nil {
+		return scopes.NewSetFirewallBadRequest().WithPayload(errorPayload(err))
+	}
+
+	if err = handler.netCtx.SetFirewall(context.Background(), params.IDName, ingress, egress); err != nil {
+		if _, ok := err.(network.ResourceNotFoundError); ok {
+			return scopes.NewSetFirewallNotFound().WithPayload(errorPayload(err))
+		}
+
+		return scopes.NewSetFirewallInternalServerError().WithPayload(errorPayload(err))
+	}
+
+	return scopes.NewSetFirewallOK().WithPayload(toFirewallPolicy(ingress, egress))
+}
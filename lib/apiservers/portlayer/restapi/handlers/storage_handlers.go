@@ -17,7 +17,9 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/go-swagger/go-swagger/httpkit/middleware"
@@ -26,18 +28,26 @@ import (
 	"github.com/vmware/vic/lib/apiservers/portlayer/models"
 	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations"
 	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations/storage"
+	"github.com/vmware/vic/lib/config"
 
+	"github.com/vmware/vic/pkg/nfs"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/datastore"
 
+	"github.com/vmware/vic/lib/portlayer"
 	epl "github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/scheduler"
 	spl "github.com/vmware/vic/lib/portlayer/storage"
+	nfsSpl "github.com/vmware/vic/lib/portlayer/storage/nfs"
 	vsphereSpl "github.com/vmware/vic/lib/portlayer/storage/vsphere"
 	"github.com/vmware/vic/lib/portlayer/util"
 
 	"golang.org/x/net/context"
 )
 
+// imageCleanupInterval is how often inconsistent images are removed from the image stores
+const imageCleanupInterval = time.Hour
+
 // StorageHandlersImpl is the receiver for all of the storage handler methods
 type StorageHandlersImpl struct {
 	imageCache  *spl.NameLookupCache
@@ -51,16 +61,14 @@ func (h *StorageHandlersImpl) Configure(api *operations.PortLayerAPI, handlerCtx
 	ctx := context.Background()
 	op := trace.NewOperation(ctx, "configure")
 
-	if len(spl.Config.ImageStores) == 0 {
-		log.Panicf("No image stores provided; unable to instantiate storage layer")
-	}
-	imageStoreURL := spl.Config.ImageStores[0]
-	// TODO: support multiple image stores. Right now we only support the first one
-	if len(spl.Config.ImageStores) > 1 {
-		log.Warningf("Multiple image stores found. Multiple image stores are not yet supported. Using [%s] %s", imageStoreURL.Host, imageStoreURL.Path)
+	// the image store is chosen from those configured when the port layer is initialized
+	imageStoreURL := epl.Config.ImageStore
+	if imageStoreURL == nil {
+		log.Panicf("No image store chosen; unable to instantiate storage layer")
 	}
+	log.Infof("Using image store [%s] %s", imageStoreURL.Host, imageStoreURL.Path)
 
-	ds, err := vsphereSpl.NewImageStore(op, handlerCtx.Session, &imageStoreURL)
+	ds, err := vsphereSpl.NewImageStore(op, handlerCtx.Session, imageStoreURL)
 	if err != nil {
 		log.Panicf("Cannot instantiate storage layer: %s", err)
 	}
@@ -77,9 +85,26 @@ func (h *StorageHandlersImpl) Configure(api *operations.PortLayerAPI, handlerCtx
 		log.Panicf("Cannot instantiate the volume store: %s", err)
 	}
 
+	// Volume stores on NFS exports are shared between containerVMs and are mounted
+	// by the tether rather than attached as disks.
+	nfsVolumeStore := nfsSpl.NewVolumeStore(op, &nfsSpl.KernelMounter{}, nfsSpl.DefaultMountRoot)
+
+	dsLocations := make(map[string]*url.URL)
+	for volStoreName, location := range spl.Config.VolumeLocations {
+		if !nfs.IsExport(location) {
+			dsLocations[volStoreName] = location
+			continue
+		}
+
+		log.Infof("Adding volume store %s (%s)", volStoreName, location)
+		if _, err = nfsVolumeStore.AddStore(op, location, volStoreName); err != nil {
+			log.Errorf("volume addition error %s", err)
+		}
+	}
+
 	// Get the datastores for volumes.
 	// Each volume store name maps to a datastore + path, which can be referred to by the name.
-	dstores, err := datastore.GetDatastores(context.TODO(), handlerCtx.Session, spl.Config.VolumeLocations)
+	dstores, err := datastore.GetDatastores(context.TODO(), handlerCtx.Session, dsLocations)
 	if err != nil {
 		log.Panicf("Cannot find datastores: %s", err)
 	}
@@ -93,11 +118,26 @@ func (h *StorageHandlersImpl) Configure(api *operations.PortLayerAPI, handlerCtx
 		}
 	}
 
-	h.volumeCache, err = spl.NewVolumeLookupCache(op, vsVolumeStore)
+	h.volumeCache, err = spl.NewVolumeLookupCache(op, spl.NewVolumeMux(vsVolumeStore, nfsVolumeStore))
 	if err != nil {
 		log.Panicf("Cannot instantiate the Volume Lookup cache: %s", err)
 	}
 
+	// the anonymous volumes of auto-removed containers are destroyed through the cache
+	epl.Config.VolumeDestroyer = h.volumeCache.VolumeDestroy
+
+	// images left inconsistent by interrupted pulls are removed periodically, not only on restart
+	err = portlayer.Maintenance.Register(scheduler.Job{
+		Name:     config.ImageCleanupJob,
+		Interval: imageCleanupInterval,
+		Run: func(ctx context.Context) error {
+			return ds.Cleanup(trace.NewOperation(ctx, "image cleanup"))
+		},
+	})
+	if err != nil {
+		log.Errorf("Unable to register maintenance job: %s", err)
+	}
+
 	api.StorageCreateImageStoreHandler = storage.CreateImageStoreHandlerFunc(h.CreateImageStore)
 	api.StorageGetImageHandler = storage.GetImageHandlerFunc(h.GetImage)
 	api.StorageGetImageTarHandler = storage.GetImageTarHandlerFunc(h.GetImageTar)
@@ -453,7 +493,12 @@ func (h *StorageHandlersImpl) VolumeJoin(params storage.VolumeJoinParams) middle
 		})
 	}
 
-	actualHandle, err = vsphereSpl.VolumeJoin(op, actualHandle, volume, params.JoinArgs.MountPath, params.JoinArgs.Flags)
+	join := vsphereSpl.VolumeJoin
+	if _, ok := volume.Device.(*nfsSpl.Export); ok {
+		join = nfsSpl.VolumeJoin
+	}
+
+	actualHandle, err = join(op, actualHandle, volume, params.JoinArgs.MountPath, params.JoinArgs.Flags)
 	if err != nil {
 		log.Errorf("Volumes: StorageHandler : %#v", err)
 
@@ -0,0 +1,167 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	log "github.com/Sirupsen/logrus"
+
+	middleware "github.com/go-swagger/go-swagger/httpkit/middleware"
+
+	"github.com/vmware/vic/cmd/tether/msgs"
+	"github.com/vmware/vic/lib/apiservers/portlayer/models"
+	"github.com/vmware/vic/lib/apiservers/portlayer/restapi/operations/interaction"
+	"github.com/vmware/vic/lib/portlayer/attach"
+	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// runningContainer returns an error if the container with the given ID is not running
+func runningContainer(id string) error {
+	container := exec.Containers.Container(id)
+	if container == nil {
+		return fmt.Errorf("container %s not found", id)
+	}
+
+	if container.Info().State() != exec.StateRunning {
+		return fmt.Errorf("container %s is not running", id)
+	}
+
+	return nil
+}
+
+// TaskLaunchHandler launches a task in a running container
+func (i *InteractionHandlersImpl) TaskLaunchHandler(params interaction.TaskLaunchParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.ID))
+
+	if err := runningContainer(params.ID); err != nil {
+		log.Errorf("%s", err.Error())
+		return interaction.NewTaskLaunchNotFound().WithPayload(&models.Error{Message: err.Error()})
+	}
+
+	config := params.Config
+	if len(config.Cmd) == 0 {
+		err := fmt.Errorf("no command specified for task %s", config.ID)
+		log.Errorf("%s", err.Error())
+		return interaction.NewTaskLaunchInternalServerError().WithPayload(&models.Error{Message: err.Error()})
+	}
+
+	msg := &msgs.ExecMsg{
+		ID:   config.ID,
+		Path: config.Cmd[0],
+		Args: config.Cmd,
+		Env:  config.Env,
+	}
+	if config.WorkingDir != nil {
+		msg.Dir = *config.WorkingDir
+	}
+	if config.User != nil {
+		msg.User = *config.User
+	}
+	if config.Tty != nil {
+		msg.Tty = *config.Tty
+	}
+	if config.Attach != nil {
+		msg.Attach = *config.Attach
+	}
+
+	if err := i.attachServer.Exec(context.Background(), params.ID, msg, interactionTimeout); err != nil {
+		log.Errorf("%s", err.Error())
+		e := &models.Error{Message: err.Error()}
+
+		if err == attach.ErrExecUnsupported {
+			return interaction.NewTaskLaunchNotImplemented().WithPayload(e)
+		}
+		return interaction.NewTaskLaunchInternalServerError().WithPayload(e)
+	}
+
+	return interaction.NewTaskLaunchCreated().WithPayload(&models.TaskInfo{ID: config.ID})
+}
+
+// TaskInspectHandler returns the state of a task launched in a running container. The streams of
+// a task are released once it is seen to have exited.
+func (i *InteractionHandlersImpl) TaskInspectHandler(params interaction.TaskInspectParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.TaskID))
+
+	if err := runningContainer(params.ID); err != nil {
+		log.Errorf("%s", err.Error())
+		return interaction.NewTaskInspectNotFound().WithPayload(&models.Error{Message: err.Error()})
+	}
+
+	status, err := i.attachServer.ExecStatus(context.Background(), params.ID, params.TaskID)
+	if err != nil {
+		log.Errorf("%s", err.Error())
+		return interaction.NewTaskInspectInternalServerError().WithPayload(&models.Error{Message: err.Error()})
+	}
+
+	if status.Started != "" && !status.Running {
+		if err = i.attachServer.Remove(params.TaskID); err != nil {
+			log.Warnf("Failed to remove connection of task %s: %s", params.TaskID, err)
+		}
+		i.attachServer.Forget(params.TaskID)
+	}
+
+	exitCode := int32(status.ExitStatus)
+	return interaction.NewTaskInspectOK().WithPayload(&models.TaskInfo{
+		ID:       params.TaskID,
+		Running:  &status.Running,
+		ExitCode: &exitCode,
+		Started:  &status.Started,
+	})
+}
+
+// ContainerTopHandler lists the processes running in a running container
+func (i *InteractionHandlersImpl) ContainerTopHandler(params interaction.ContainerTopParams) middleware.Responder {
+	defer trace.End(trace.Begin(params.ID))
+
+	if err := runningContainer(params.ID); err != nil {
+		log.Errorf("%s", err.Error())
+		return interaction.NewContainerTopNotFound().WithPayload(&models.Error{Message: err.Error()})
+	}
+
+	procs, err := i.attachServer.Top(context.Background(), params.ID)
+	if err != nil {
+		log.Errorf("%s", err.Error())
+		e := &models.Error{Message: err.Error()}
+
+		if err == attach.ErrTopUnsupported {
+			return interaction.NewContainerTopNotImplemented().WithPayload(e)
+		}
+		return interaction.NewContainerTopInternalServerError().WithPayload(e)
+	}
+
+	payload := make([]*models.ProcessInfo, len(procs))
+	for n, p := range procs {
+		payload[n] = &models.ProcessInfo{
+			Pid:       int64(p.PID),
+			Ppid:      int64(p.PPID),
+			User:      p.User,
+			StartTime: p.StartTime,
+			CPUTime:   p.CPUTime,
+			CPU:       int32(p.CPU),
+			Mem:       p.Mem,
+			Vsz:       p.VSZ,
+			Rss:       p.RSS,
+			State:     p.State,
+			Tty:       p.TTY,
+			Cmd:       p.Cmd,
+		}
+	}
+
+	return interaction.NewContainerTopOK().WithPayload(payload)
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive copies files in and out of a container filesystem as tar archives, for
+// docker cp. The filesystem is either the root of a running containerVM, served by the tether,
+// or the disk of a powered off containerVM mounted in the appliance.
+package archive
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	dockerarchive "github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/engine-api/types"
+)
+
+// ErrExtractPointNotDirectory is returned when an archive is extracted to a path that is not a directory
+var ErrExtractPointNotDirectory = errors.New("extraction point is not a directory")
+
+// resourcePath returns the path of the container path p below root, with symlinks followed
+// in the scope of root
+func resourcePath(root, p string) (string, error) {
+	return symlink.FollowSymlinkInScope(filepath.Join(root, filepath.Clean(string(filepath.Separator)+p)), root)
+}
+
+// resolvePath returns the path of the container path p below root with all but the last
+// element resolved, and the absolute container path
+func resolvePath(root, p string) (resolvedPath, absPath string, err error) {
+	absPath = dockerarchive.PreserveTrailingDotOrSeparator(filepath.Join(string(filepath.Separator), p), p)
+
+	dirPath, basePath := filepath.Split(absPath)
+	resolvedDirPath, err := resourcePath(root, dirPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	return resolvedDirPath + string(filepath.Separator) + basePath, absPath, nil
+}
+
+func stat(root, resolvedPath, absPath string) (*types.ContainerPathStat, error) {
+	lstat, err := os.Lstat(resolvedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var linkTarget string
+	if lstat.Mode()&os.ModeSymlink != 0 {
+		// fully evaluate the symlink in the scope of the container root
+		hostPath, err := resourcePath(root, absPath)
+		if err != nil {
+			return nil, err
+		}
+
+		linkTarget, err = filepath.Rel(root, hostPath)
+		if err != nil {
+			return nil, err
+		}
+		linkTarget = filepath.Join(string(filepath.Separator), linkTarget)
+	}
+
+	return &types.ContainerPathStat{
+		Name:       filepath.Base(absPath),
+		Size:       lstat.Size(),
+		Mode:       lstat.Mode(),
+		Mtime:      lstat.ModTime(),
+		LinkTarget: linkTarget,
+	}, nil
+}
+
+// Stat returns the stat of the path p of the container filesystem at root
+func Stat(root, p string) (*types.ContainerPathStat, error) {
+	resolvedPath, absPath, err := resolvePath(root, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return stat(root, resolvedPath, absPath)
+}
+
+// Read returns a tar archive of the path p of the container filesystem at root, and its stat.
+// The archive entries start with the base name of p, even if p is a symlink.
+func Read(root, p string) (io.ReadCloser, *types.ContainerPathStat, error) {
+	resolvedPath, absPath, err := resolvePath(root, p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	st, err := stat(root, resolvedPath, absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := dockerarchive.TarResourceRebase(resolvedPath, filepath.Base(absPath))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, st, nil
+}
+
+// Write extracts the tar archive content to the directory p of the container filesystem at
+// root. If noOverwriteDirNonDir is set, directories are not replaced by other files, and other
+// files are not replaced by directories.
+func Write(root, p string, content io.Reader, noOverwriteDirNonDir bool) error {
+	// the last element is resolved too, so that an archive can be extracted to a symlink to a directory
+	absPath := dockerarchive.PreserveTrailingDotOrSeparator(filepath.Join(string(filepath.Separator), p), p)
+	resolvedPath, err := resourcePath(root, absPath)
+	if err != nil {
+		return err
+	}
+
+	st, err := os.Lstat(resolvedPath)
+	if err != nil {
+		return err
+	}
+
+	if !st.IsDir() {
+		return ErrExtractPointNotDirectory
+	}
+
+	return dockerarchive.Untar(content, resolvedPath, &dockerarchive.TarOptions{NoOverwriteDirNonDir: noOverwriteDirNonDir})
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entries returns the sorted names of the entries of the tar archive r
+func entries(t *testing.T, r io.Reader) []string {
+	var names []string
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReadWrite(t *testing.T) {
+	root, err := ioutil.TempDir("", "archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc", "app"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "etc", "app", "app.conf"), []byte("debug=true\n"), 0644))
+	require.NoError(t, os.Symlink("/etc/app", filepath.Join(root, "config")))
+	// links out of the container filesystem resolve within it
+	require.NoError(t, os.Symlink("/../../etc", filepath.Join(root, "escape")))
+
+	st, err := Stat(root, "/etc/app/app.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "app.conf", st.Name)
+	assert.EqualValues(t, 11, st.Size)
+
+	st, err = Stat(root, "/config")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/app", st.LinkTarget)
+
+	st, err = Stat(root, "/escape")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc", st.LinkTarget)
+
+	_, err = Stat(root, "/missing")
+	assert.True(t, os.IsNotExist(err))
+
+	data, st, err := Read(root, "/etc/app")
+	require.NoError(t, err)
+	assert.True(t, st.Mode.IsDir())
+	assert.Equal(t, []string{"app/", "app/app.conf"}, entries(t, data))
+	data.Close()
+
+	// the entries of a symlinked directory are named after the link
+	data, _, err = Read(root, "/config/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config/", "config/app.conf"}, entries(t, data))
+	data.Close()
+
+	// write the archive of a file to another directory, through a symlink
+	data, _, err = Read(root, "/etc/app/app.conf")
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	_, err = io.Copy(buf, data)
+	require.NoError(t, err)
+	data.Close()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "tmp"), 0755))
+	require.NoError(t, Write(root, "/tmp", bytes.NewReader(buf.Bytes()), false))
+	b, err := ioutil.ReadFile(filepath.Join(root, "tmp", "app.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "debug=true\n", string(b))
+
+	assert.Equal(t, ErrExtractPointNotDirectory, Write(root, "/etc/app/app.conf", bytes.NewReader(buf.Bytes()), false))
+
+	// a directory is not replaced by a file with noOverwriteDirNonDir
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dir", "app.conf"), 0755))
+	assert.Error(t, Write(root, "/dir", bytes.NewReader(buf.Bytes()), true))
+}
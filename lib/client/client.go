@@ -0,0 +1,306 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client lets other programs manage Virtual Container Hosts and call the VIC
+// extension API without shelling out to vic-machine. Client wraps the operations of
+// vic-machine for a vSphere target, and Extension calls the /vic API of a running VCH.
+package client
+
+import (
+	"bytes"
+	"path"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/certificate"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/version"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+
+	"golang.org/x/net/context"
+)
+
+// VCH identifies a Virtual Container Host
+type VCH struct {
+	// ID is the managed object reference of the appliance VM
+	ID string `json:"id"`
+	// Path is the inventory path of the resource pool or vApp containing the VCH
+	Path    string `json:"path"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Client manages the VCHs of a vSphere target. The context it is created with bounds
+// all of its operations.
+type Client struct {
+	ctx context.Context
+
+	target    *data.Data
+	validator *validate.Validator
+
+	// Progress, if set, receives the progress events of create, delete, upgrade and configure
+	Progress management.ProgressFunc
+}
+
+// New connects to the target, which holds the vSphere URL and credentials and the compute
+// resource VCHs are searched for in
+func New(ctx context.Context, target *data.Data) (*Client, error) {
+	defer trace.End(trace.Begin(""))
+
+	if err := target.HasCredentials(); err != nil {
+		return nil, err
+	}
+
+	var v *validate.Validator
+	var err error
+	if target.ComputeResourcePath == "" {
+		v, err = validate.CreateNoDCCheck(ctx, target)
+	} else {
+		v, err = validate.NewValidator(ctx, target)
+	}
+	if err != nil {
+		return nil, errors.Errorf("Failed to connect to %s: %s", target.URL, err)
+	}
+
+	return &Client{
+		ctx:       ctx,
+		target:    target,
+		validator: v,
+	}, nil
+}
+
+// Close ends the vSphere session of the client
+func (c *Client) Close() error {
+	return c.validator.Session.Logout(c.ctx)
+}
+
+func (c *Client) dispatcher(conf *config.VirtualContainerHostConfigSpec) *management.Dispatcher {
+	d := management.NewDispatcher(c.validator.Context, c.validator.Session, conf, c.target.Force)
+	d.Progress = c.Progress
+	return d
+}
+
+// vch finds the VCH by ID, or by display name in the compute resource of the target if
+// id is empty, and returns it with its configuration. Diagnostic logs of the VCH are
+// collected by the dispatcher from then on.
+func (c *Client) vch(d *management.Dispatcher, id string) (*vm.VirtualMachine, *config.VirtualContainerHostConfigSpec, error) {
+	var vch *vm.VirtualMachine
+	var err error
+	if id != "" {
+		vch, err = d.NewVCHFromID(id)
+	} else {
+		vch, err = d.NewVCHFromComputePath(c.target.ComputeResourcePath, c.target.DisplayName, c.validator)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conf, err := d.GetVCHConfig(vch)
+	if err != nil {
+		return nil, nil, errors.Errorf("Failed to get configuration of VCH %s: %s", vch.Reference().Value, err)
+	}
+	d.InitDiagnosticLogs(conf)
+
+	return vch, conf, nil
+}
+
+// List returns the VCHs in the compute resource of the target, or in all of it if unset
+func (c *Client) List() ([]VCH, error) {
+	defer trace.End(trace.Begin(""))
+
+	if _, err := c.validator.ValidateTarget(c.ctx, c.target); err != nil {
+		return nil, err
+	}
+	if _, err := c.validator.ValidateCompute(c.ctx, c.target); err != nil {
+		return nil, err
+	}
+
+	d := c.dispatcher(nil)
+	vchs, err := d.SearchVCHs(c.validator.ResourcePoolPath)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]VCH, 0, len(vchs))
+	for _, vch := range vchs {
+		v := VCH{
+			ID:      vch.Reference().Value,
+			Path:    path.Dir(path.Dir(vch.InventoryPath)),
+			Name:    path.Base(vch.InventoryPath),
+			Version: "unknown",
+		}
+		if conf, err := d.GetVCHConfig(vch); err == nil {
+			v.Version = conf.Version.ShortVersion()
+		} else {
+			log.Warnf("Failed to get configuration of VCH %s: %s", v.ID, err)
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// Inspect returns the state of the VCH with the ID, or with the display name of the target if
+// id is empty
+func (c *Client) Inspect(id string) (*management.Inspection, error) {
+	defer trace.End(trace.Begin(id))
+
+	d := c.dispatcher(nil)
+	vch, conf, err := c.vch(d, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := d.InspectionReport(vch, conf)
+	if err != nil {
+		return nil, err
+	}
+	report.InstallerVersion = version.GetBuild().ShortVersion()
+	return report, nil
+}
+
+// VolumeStores returns the volume stores of the VCH with the ID, or with the display name of the
+// target if id is empty, with their capacity and usage
+func (c *Client) VolumeStores(id string) ([]management.VolumeStore, error) {
+	defer trace.End(trace.Begin(id))
+
+	d := c.dispatcher(nil)
+	_, conf, err := c.vch(d, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.VolumeStores(conf), nil
+}
+
+// Create creates a VCH as vic-machine create does, returning it once the docker API is up.
+// The spec holds the configuration of the VCH, including its TLS certificates if any, and
+// the appliance and bootstrap ISOs to upload.
+func (c *Client) Create(spec *data.Data) (*VCH, error) {
+	defer trace.End(trace.Begin(spec.DisplayName))
+
+	if spec.Target == nil {
+		spec.Target = c.target.Target
+	}
+
+	images, err := spec.CheckImagesFiles(spec.Force)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := validate.NewValidator(c.ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := v.Validate(c.ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	conf.RegistryProxies = spec.RegistryProxies
+
+	settings := v.AddDeprecatedFields(c.ctx, conf, spec)
+	settings.ImageFiles = images
+	settings.ApplianceISO = path.Base(spec.ApplianceISO)
+	settings.BootstrapISO = path.Base(spec.BootstrapISO)
+	settings.HTTPProxy = spec.HTTPProxy
+	settings.HTTPSProxy = spec.HTTPSProxy
+	settings.NoProxy = spec.NoProxy
+
+	if v.Session.IsVC() {
+		var cert, key bytes.Buffer
+		if cert, key, err = certificate.CreateSelfSigned("", []string{"VMware Inc."}, 2048); err != nil {
+			return nil, errors.Errorf("Failed to create certificate for VIC vSphere extension: %s", err)
+		}
+		conf.ExtensionCert = cert.String()
+		conf.ExtensionKey = key.String()
+	}
+
+	d := management.NewDispatcher(v.Context, v.Session, conf, spec.Force)
+	if err = d.CreateVCH(conf, settings); err != nil {
+		d.CollectDiagnosticLogs()
+		return nil, err
+	}
+	if err = d.CheckDockerAPI(conf, nil); err != nil {
+		d.CollectDiagnosticLogs()
+		return nil, err
+	}
+
+	moref := new(types.ManagedObjectReference)
+	moref.FromString(conf.ID)
+
+	return &VCH{
+		ID:      moref.Value,
+		Path:    settings.ResourcePoolPath,
+		Name:    conf.Name,
+		Version: conf.Version.ShortVersion(),
+	}, nil
+}
+
+// Configure applies the changes to the VCH with the ID, as vic-machine configure does. Only
+// the settings that vic-machine configure accepts are taken from changes.
+func (c *Client) Configure(id string, changes *data.Data) error {
+	defer trace.End(trace.Begin(id))
+
+	d := c.dispatcher(nil)
+	vch, current, err := c.vch(d, id)
+	if err != nil {
+		return err
+	}
+
+	// a second copy, as the requested changes are applied in place
+	requested, err := d.GetVCHConfig(vch)
+	if err != nil {
+		return err
+	}
+	if requested, err = c.validator.ValidateReconfigure(c.ctx, changes, requested); err != nil {
+		return err
+	}
+
+	settings := c.validator.AddDeprecatedFields(c.ctx, requested, changes)
+	settings.HTTPProxy = changes.HTTPProxy
+	settings.HTTPSProxy = changes.HTTPSProxy
+	settings.NoProxy = changes.NoProxy
+	settings.RollbackTimeout = changes.Timeout
+
+	if err = d.Reconfigure(vch, current, requested, settings); err != nil {
+		d.CollectDiagnosticLogs()
+		return err
+	}
+	return nil
+}
+
+// Delete removes the VCH with the ID, as vic-machine delete does, removing or preserving its
+// volume stores as volumes says
+func (c *Client) Delete(id string, volumes management.VolumeAction) error {
+	defer trace.End(trace.Begin(id))
+
+	d := c.dispatcher(nil)
+	_, conf, err := c.vch(d, id)
+	if err != nil {
+		return err
+	}
+
+	if err = d.DeleteVCH(conf, volumes); err != nil {
+		d.CollectDiagnosticLogs()
+		return err
+	}
+	return nil
+}
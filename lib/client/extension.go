@@ -0,0 +1,227 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+
+	"github.com/vmware/vic/lib/apiservers/engine/router/vic"
+)
+
+// Extension calls the VIC extension API served by the docker personality of a VCH
+type Extension struct {
+	base   *url.URL
+	client *http.Client
+}
+
+// APIError is returned for requests the VCH rejected
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+// NewExtension returns a client for the VCH with the docker API at endpoint, e.g.
+// https://10.0.0.1:2376. The http client carries the TLS configuration needed to
+// reach it, and http.DefaultClient is used if it is nil.
+func NewExtension(endpoint *url.URL, client *http.Client) *Extension {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	base := *endpoint
+	base.Path = vic.PathPrefix
+
+	return &Extension{
+		base:   &base,
+		client: client,
+	}
+}
+
+// Info returns information about the VCH
+func (e *Extension) Info(ctx context.Context) (*vic.VCHInfo, error) {
+	info := &vic.VCHInfo{}
+	if err := e.do(ctx, "GET", "/info", nil, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Capacity returns the resources available to containers and their use
+func (e *Extension) Capacity(ctx context.Context) (*vic.Capacity, error) {
+	capacity := &vic.Capacity{}
+	if err := e.do(ctx, "GET", "/capacity", nil, capacity); err != nil {
+		return nil, err
+	}
+	return capacity, nil
+}
+
+// Adopt manages an existing VM as a container, returning the container ID
+func (e *Extension) Adopt(ctx context.Context, req *vic.AdoptRequest) (string, error) {
+	resp := &vic.AdoptResponse{}
+	if err := e.do(ctx, "POST", "/containers/adopt", req, resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// RegisterExternal lists an existing VM, given by managed object reference or inventory path, as a read-only
+// external container, returning the container ID
+func (e *Extension) RegisterExternal(ctx context.Context, vm string) (string, error) {
+	resp := &vic.ExternalResponse{}
+	if err := e.do(ctx, "POST", "/containers/external", &vic.ExternalRequest{VM: vm}, resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// Checkpoint snapshots the container VM
+func (e *Extension) Checkpoint(ctx context.Context, name string, req *vic.CheckpointRequest) error {
+	return e.do(ctx, "POST", "/containers/"+name+"/checkpoint", req, nil)
+}
+
+// ConsoleTicket acquires a ticket for the console of the container VM
+func (e *Extension) ConsoleTicket(ctx context.Context, name string) (*vic.ConsoleTicket, error) {
+	ticket := &vic.ConsoleTicket{}
+	if err := e.do(ctx, "POST", "/containers/"+name+"/console", nil, ticket); err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// Prefetch queues the images to be pulled into the image store and returns the status of
+// all images requested so far
+func (e *Extension) Prefetch(ctx context.Context, images ...string) ([]vic.PrefetchStatus, error) {
+	var status []vic.PrefetchStatus
+	if err := e.do(ctx, "POST", "/images/prefetch", &vic.PrefetchRequest{Images: images}, &status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// PrefetchStatus returns the status of all images requested for prefetch
+func (e *Extension) PrefetchStatus(ctx context.Context) ([]vic.PrefetchStatus, error) {
+	var status []vic.PrefetchStatus
+	if err := e.do(ctx, "GET", "/images/prefetch", nil, &status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Pull pulls the images concurrently, writing their combined progress to out as docker pull does
+func (e *Extension) Pull(ctx context.Context, out io.Writer, images ...string) error {
+	resp, err := e.request(ctx, "POST", "/images/pull", &vic.PullRequest{Images: images})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// failures once the progress has started are reported in the stream
+	return jsonmessage.DisplayJSONMessagesStream(resp.Body, out, 0, false, nil)
+}
+
+// NetworkFirewall returns the firewall rules of a network
+func (e *Extension) NetworkFirewall(ctx context.Context, name string) (*vic.FirewallPolicy, error) {
+	policy := &vic.FirewallPolicy{}
+	if err := e.do(ctx, "GET", "/networks/"+name+"/firewall", nil, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// SetNetworkFirewall replaces the firewall rules of a network, which apply to containers from their
+// next start, and returns the rules as the VCH stored them
+func (e *Extension) SetNetworkFirewall(ctx context.Context, name string, policy *vic.FirewallPolicy) (*vic.FirewallPolicy, error) {
+	res := &vic.FirewallPolicy{}
+	if err := e.do(ctx, "PUT", "/networks/"+name+"/firewall", policy, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// do sends in as the JSON body of the request, if not nil, and decodes the response into out,
+// if not nil
+func (e *Extension) do(ctx context.Context, method, path string, in, out interface{}) error {
+	resp, err := e.request(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// request sends in as the JSON body of the request, if not nil, and returns the response if its
+// status is a success
+func (e *Extension) request(ctx context.Context, method, path string, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, e.base.String()+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+
+		// errors are plain text, or {"message": "..."} from newer servers
+		b, _ := ioutil.ReadAll(resp.Body)
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &msg) == nil && msg.Message != "" {
+			apiErr.Message = msg.Message
+		} else if text := strings.TrimSpace(string(b)); text != "" {
+			apiErr.Message = text
+		} else {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return nil, apiErr
+	}
+
+	return resp, nil
+}
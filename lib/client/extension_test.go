@@ -0,0 +1,242 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	netcontext "golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/apiservers/engine/router/vic"
+)
+
+type mockBackend struct {
+	checkpointed map[string]*vic.CheckpointRequest
+	prefetched   []string
+	firewalls    map[string]*vic.FirewallPolicy
+}
+
+func (m *mockBackend) VCHInfo() (*vic.VCHInfo, error) {
+	return &vic.VCHInfo{Name: "vch", ID: "VirtualMachine:vm-1", Version: "v0.8.0"}, nil
+}
+
+func (m *mockBackend) Capacity() (*vic.Capacity, error) {
+	return &vic.Capacity{CPUMhz: 2000, MemoryMB: 4096, Containers: 3, Running: 1}, nil
+}
+
+func (m *mockBackend) ContainerAdopt(req *vic.AdoptRequest) (*vic.AdoptResponse, error) {
+	return &vic.AdoptResponse{ID: "adopted-" + req.VM}, nil
+}
+
+func (m *mockBackend) ContainerRegisterExternal(req *vic.ExternalRequest) (*vic.ExternalResponse, error) {
+	return &vic.ExternalResponse{ID: "external-" + req.VM}, nil
+}
+
+func (m *mockBackend) ContainerCheckpoint(name string, req *vic.CheckpointRequest) error {
+	m.checkpointed[name] = req
+	return nil
+}
+
+func (m *mockBackend) ContainerConsoleTicket(name string) (*vic.ConsoleTicket, error) {
+	return nil, errors.New("No such container: " + name)
+}
+
+func (m *mockBackend) ContainerRemove(name string, opts *vic.RemoveOptions) error {
+	return nil
+}
+
+func (m *mockBackend) ImagePrefetch(req *vic.PrefetchRequest) ([]vic.PrefetchStatus, error) {
+	m.prefetched = append(m.prefetched, req.Images...)
+	return m.ImagePrefetchStatus()
+}
+
+func (m *mockBackend) ImagePrefetchStatus() ([]vic.PrefetchStatus, error) {
+	var status []vic.PrefetchStatus
+	for _, image := range m.prefetched {
+		status = append(status, vic.PrefetchStatus{Image: image, State: vic.PrefetchQueued})
+	}
+	return status, nil
+}
+
+func (m *mockBackend) ImagesPull(req *vic.PullRequest, out io.Writer) error {
+	sf := streamformatter.NewJSONStreamFormatter()
+	for _, image := range req.Images {
+		if image == "broken" {
+			return errors.New("manifest for broken not found")
+		}
+		out.Write(sf.FormatStatus(image, "Pull complete"))
+	}
+	return nil
+}
+
+func (m *mockBackend) NetworksPrune() (*vic.NetworksPruneReport, error) {
+	return &vic.NetworksPruneReport{}, nil
+}
+
+func (m *mockBackend) NetworkFirewall(name string) (*vic.FirewallPolicy, error) {
+	policy, ok := m.firewalls[name]
+	if !ok {
+		return nil, errors.New("network " + name + " not found")
+	}
+	return policy, nil
+}
+
+func (m *mockBackend) SetNetworkFirewall(name string, policy *vic.FirewallPolicy) (*vic.FirewallPolicy, error) {
+	if _, ok := m.firewalls[name]; !ok {
+		return nil, errors.New("network " + name + " not found")
+	}
+	m.firewalls[name] = policy
+	return policy, nil
+}
+
+// server serves the routes of the VIC extension API as the personality does
+func server(b vic.Backend) *httptest.Server {
+	m := mux.NewRouter()
+	for _, r := range vic.NewRouter(b).Routes() {
+		h := r.Handler()
+		m.Path(r.Path()).Methods(r.Method()).HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if err := h(netcontext.Background(), w, req, mux.Vars(req)); err != nil {
+				httputils.WriteError(w, err)
+			}
+		})
+	}
+	return httptest.NewServer(m)
+}
+
+func extension(t *testing.T, s *httptest.Server) *Extension {
+	u, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	return NewExtension(u, nil)
+}
+
+func TestExtension(t *testing.T) {
+	b := &mockBackend{checkpointed: make(map[string]*vic.CheckpointRequest)}
+	s := server(b)
+	defer s.Close()
+
+	e := extension(t, s)
+	ctx := context.Background()
+
+	info, err := e.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "vch", info.Name)
+	assert.Equal(t, vic.APIVersion, info.APIVersion)
+
+	capacity, err := e.Capacity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &vic.Capacity{CPUMhz: 2000, MemoryMB: 4096, Containers: 3, Running: 1}, capacity)
+
+	id, err := e.Adopt(ctx, &vic.AdoptRequest{VM: "vm-42"})
+	require.NoError(t, err)
+	assert.Equal(t, "adopted-vm-42", id)
+
+	id, err = e.RegisterExternal(ctx, "vm-43")
+	require.NoError(t, err)
+	assert.Equal(t, "external-vm-43", id)
+
+	req := &vic.CheckpointRequest{Name: "before-upgrade", Memory: true}
+	require.NoError(t, e.Checkpoint(ctx, "web", req))
+	assert.Equal(t, req, b.checkpointed["web"])
+
+	status, err := e.Prefetch(ctx, "busybox", "nginx")
+	require.NoError(t, err)
+	assert.Len(t, status, 2)
+
+	status, err = e.PrefetchStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []vic.PrefetchStatus{{Image: "busybox", State: vic.PrefetchQueued}, {Image: "nginx", State: vic.PrefetchQueued}}, status)
+}
+
+func TestExtensionError(t *testing.T) {
+	s := server(&mockBackend{})
+	defer s.Close()
+
+	_, err := extension(t, s).ConsoleTicket(context.Background(), "web")
+	require.Error(t, err)
+
+	apiErr, ok := err.(*APIError)
+	require.True(t, ok, "expected an APIError, got %T", err)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "No such container: web", apiErr.Message)
+}
+
+func TestExtensionFirewall(t *testing.T) {
+	b := &mockBackend{firewalls: map[string]*vic.FirewallPolicy{"bridge": {}}}
+	s := server(b)
+	defer s.Close()
+
+	e := extension(t, s)
+	ctx := context.Background()
+
+	policy := &vic.FirewallPolicy{
+		Ingress: []string{"allow tcp 10.0.0.0/8 22", "deny"},
+		Egress:  []string{"allow udp any 53"},
+	}
+	res, err := e.SetNetworkFirewall(ctx, "bridge", policy)
+	require.NoError(t, err)
+	assert.Equal(t, policy, res)
+	assert.Equal(t, policy, b.firewalls["bridge"])
+
+	res, err = e.NetworkFirewall(ctx, "bridge")
+	require.NoError(t, err)
+	assert.Equal(t, policy, res)
+
+	_, err = e.NetworkFirewall(ctx, "missing")
+	require.Error(t, err)
+	apiErr, ok := err.(*APIError)
+	require.True(t, ok, "expected an APIError, got %T", err)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	_, err = e.SetNetworkFirewall(ctx, "missing", policy)
+	require.Error(t, err)
+}
+
+func TestExtensionPull(t *testing.T) {
+	s := server(&mockBackend{})
+	defer s.Close()
+
+	e := extension(t, s)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	require.NoError(t, e.Pull(ctx, &out, "busybox", "nginx"))
+	assert.Equal(t, "busybox: Pull complete\nnginx: Pull complete\n", out.String())
+
+	// a failure before any progress is the status of the request
+	out.Reset()
+	err := e.Pull(ctx, &out, "broken")
+	require.Error(t, err)
+	_, ok := err.(*APIError)
+	assert.True(t, ok, "expected an APIError, got %T", err)
+
+	// a failure after the progress has started is reported in the stream
+	out.Reset()
+	err = e.Pull(ctx, &out, "busybox", "broken")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "manifest for broken not found")
+	assert.Equal(t, "busybox: Pull complete\n", out.String())
+}
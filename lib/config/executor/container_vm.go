@@ -29,6 +29,9 @@ const (
 	KILLED
 )
 
+// HeartbeatInterval is how often components that publish a heartbeat refresh it
+const HeartbeatInterval = 30 * time.Second
+
 // Common data between managed entities, across execution environments
 type Common struct {
 	// A reference to the components hosting execution environment, if any
@@ -55,6 +58,18 @@ type Diagnostics struct {
 	// ExitLogs is a best effort record of the time of process death and the cause for
 	// restartable entities
 	ExitLogs []ExitLog `vic:"0.1" scope:"read-write" key:"exitlogs"`
+	// Heartbeat is updated periodically while the entity is running, for those that publish one
+	Heartbeat time.Time `vic:"0.1" scope:"read-write" key:"heartbeat"`
+	// DebugAccess, if set, opens the entity up for interactive debugging
+	DebugAccess *DebugAccess `vic:"0.1" scope:"read-only" key:"access"`
+}
+
+// DebugAccess controls interactive access for debugging
+type DebugAccess struct {
+	// SSH enables the SSH server
+	SSH bool `vic:"0.1" scope:"read-only" key:"ssh"`
+	// AuthorizedKey is placed in the authorized_keys of root when SSH is enabled
+	AuthorizedKey string `vic:"0.1" scope:"read-only" key:"authorizedkey"`
 }
 
 // ExitLog records some basic diagnostics about anomalous exit for restartable entities
@@ -79,6 +94,19 @@ type MountSpec struct {
 	// Freeform mode string, which could translate directly to mount options
 	// We may want to turn this into a more structured form eventually
 	Mode string `vic:"0.1" scope:"read-only" key:"mode"`
+
+	// Anonymous is set for volumes created for this executor alone, which are destroyed along with it
+	// when it is removed on exit
+	Anonymous bool `vic:"0.1" scope:"read-only" key:"anonymous"`
+}
+
+// LogConfig selects a log driver and its options, as given by docker --log-driver and --log-opt
+type LogConfig struct {
+	// Type is the name of the log driver, empty for none
+	Type string `vic:"0.1" scope:"hidden" key:"type"`
+
+	// Config holds the options of the log driver
+	Config map[string]string `vic:"0.1" scope:"hidden" key:"config"`
 }
 
 // ContainerVM holds that data tightly associated with a containerVM, but that should not
@@ -137,6 +165,12 @@ type ExecutorConfig struct {
 	// Blob metadata for the caller
 	Annotations map[string]string `vic:"0.1" scope:"hidden" key:"annotation"`
 
+	// Log driver that the appliance forwards the container output to, if any
+	LogConfig LogConfig `vic:"0.1" scope:"hidden" key:"log_config"`
+
+	// AutoRemove has the appliance remove the executor, and its anonymous volumes, once it exits
+	AutoRemove bool `vic:"0.1" scope:"hidden" key:"auto_remove"`
+
 	// Repository requested by user
 	// TODO: a bit docker specific
 	RepoName string `vic:"0.1" scope:"read-only" key:"repo"`
@@ -177,11 +211,17 @@ type SessionConfig struct {
 	// Delay launching the Cmd until an attach request comes
 	RunBlock bool `vic:"0.1" scope:"read-only" key:"runblock"`
 
+	// Create the working directory of the Cmd if it does not exist
+	CreateDir bool `vic:"0.1" scope:"read-only" key:"createdir"`
+
 	// Allocate a tty or not
 	Tty bool `vic:"0.1" scope:"read-only" key:"tty"`
 
 	ExitStatus int `vic:"0.1" scope:"read-write" key:"status"`
 
+	// OOMKilled is set if the process was killed by the kernel for running out of memory
+	OOMKilled bool `vic:"0.1" scope:"read-write" key:"oomkilled"`
+
 	Started string `vic:"0.1" scope:"read-write" key:"started"`
 
 	Restart bool `vic:"0.1" scope:"read-only" key:"restart"`
@@ -189,6 +229,10 @@ type SessionConfig struct {
 	// StopSignal is the signal name or number used to stop container session
 	StopSignal string `vic:"0.1" scope:"read-only" key:"stopSignal"`
 
+	// Deadline is the number of seconds the process may run for each time it is launched, after which
+	// it is sent StopSignal and then killed. Zero for no limit.
+	Deadline int64 `vic:"0.1" scope:"read-only" key:"deadline"`
+
 	// Diagnostics holds basic diagnostics data
 	Diagnostics Diagnostics `vic:"0.1" scope:"read-only" key:"diagnostics"`
 
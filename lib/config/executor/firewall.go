@@ -0,0 +1,171 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Firewall rule actions
+const (
+	FirewallAllow = "allow"
+	FirewallDeny  = "deny"
+)
+
+// firewallAny matches any protocol, network or port in the text form of a rule
+const firewallAny = "any"
+
+// FirewallRule allows or denies the traffic of a container on a network that matches it. The rules
+// of a network are matched in order, before its firewall policy.
+type FirewallRule struct {
+	// Action is FirewallAllow or FirewallDeny
+	Action string `vic:"0.1" scope:"read-only" key:"action"`
+
+	// Protocol is tcp, udp or icmp, any if empty
+	Protocol string `vic:"0.1" scope:"read-only" key:"protocol"`
+
+	// Remote is the address or CIDR of the remote end, any if empty
+	Remote string `vic:"0.1" scope:"read-only" key:"remote"`
+
+	// Ports is the port, or range of ports as low-high, of the container for inbound rules and of the
+	// remote end for outbound rules. Any if empty, and only valid for tcp and udp.
+	Ports string `vic:"0.1" scope:"read-only" key:"ports"`
+}
+
+// String returns the rule in the form parsed by ParseFirewallRule
+func (r FirewallRule) String() string {
+	fields := []string{r.Action}
+	for _, f := range []string{r.Protocol, r.Remote, r.Ports} {
+		if f == "" {
+			f = firewallAny
+		}
+		fields = append(fields, f)
+	}
+
+	// trailing fields that match anything are implied
+	for len(fields) > 1 && fields[len(fields)-1] == firewallAny {
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, " ")
+}
+
+// PortRange returns the low and high ports of the rule, zero if it matches any port
+func (r FirewallRule) PortRange() (low, high int, err error) {
+	if r.Ports == "" {
+		return 0, 0, nil
+	}
+
+	lo, hi := r.Ports, r.Ports
+	if i := strings.Index(r.Ports, "-"); i >= 0 {
+		lo, hi = r.Ports[:i], r.Ports[i+1:]
+	}
+
+	if low, err = strconv.Atoi(lo); err == nil {
+		high, err = strconv.Atoi(hi)
+	}
+	if err != nil || low < 1 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("invalid port range %q", r.Ports)
+	}
+	return low, high, nil
+}
+
+// Validate checks that the fields of the rule are well formed
+func (r FirewallRule) Validate() error {
+	if r.Action != FirewallAllow && r.Action != FirewallDeny {
+		return fmt.Errorf("invalid firewall action %q, must be %s or %s", r.Action, FirewallAllow, FirewallDeny)
+	}
+
+	switch r.Protocol {
+	case "", "tcp", "udp", "icmp":
+	default:
+		return fmt.Errorf("invalid firewall protocol %q, must be tcp, udp or icmp", r.Protocol)
+	}
+
+	if r.Remote != "" {
+		if _, _, err := net.ParseCIDR(r.Remote); err != nil && net.ParseIP(r.Remote) == nil {
+			return fmt.Errorf("invalid remote address %q", r.Remote)
+		}
+	}
+
+	if r.Ports != "" {
+		if r.Protocol != "tcp" && r.Protocol != "udp" {
+			return fmt.Errorf("ports can only be given for tcp or udp rules")
+		}
+
+		if _, _, err := r.PortRange(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseFirewallRule parses a rule of the form "ACTION [PROTOCOL [REMOTE [PORTS]]]", where fields
+// other than the action may be "any", e.g. "allow tcp 10.0.0.0/8 22" or "deny".
+func ParseFirewallRule(s string) (FirewallRule, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 4 {
+		return FirewallRule{}, fmt.Errorf("invalid firewall rule %q, expected ACTION [PROTOCOL [REMOTE [PORTS]]]", s)
+	}
+
+	for i := range fields {
+		fields[i] = strings.ToLower(fields[i])
+		if fields[i] == firewallAny {
+			fields[i] = ""
+		}
+	}
+	fields = append(fields, make([]string, 4-len(fields))...)
+
+	r := FirewallRule{
+		Action:   fields[0],
+		Protocol: fields[1],
+		Remote:   fields[2],
+		Ports:    fields[3],
+	}
+
+	if err := r.Validate(); err != nil {
+		return FirewallRule{}, fmt.Errorf("invalid firewall rule %q: %s", s, err)
+	}
+	return r, nil
+}
+
+// ParseFirewallRules parses a comma separated list of rules, as used for network options
+func ParseFirewallRules(s string) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	for _, rs := range strings.Split(s, ",") {
+		if strings.TrimSpace(rs) == "" {
+			continue
+		}
+
+		r, err := ParseFirewallRule(rs)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// FirewallRulesString returns rules as a comma separated list, as parsed by ParseFirewallRules
+func FirewallRulesString(rules []FirewallRule) string {
+	s := make([]string, len(rules))
+	for i := range rules {
+		s[i] = rules[i].String()
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+)
+
+func TestParseFirewallRules(t *testing.T) {
+	rules, err := ParseFirewallRules("allow tcp 10.0.0.0/8 22, Allow UDP any 5000-5100,allow icmp, deny any 192.168.1.1, deny")
+	require.NoError(t, err)
+
+	assert.Equal(t, []FirewallRule{
+		{Action: FirewallAllow, Protocol: "tcp", Remote: "10.0.0.0/8", Ports: "22"},
+		{Action: FirewallAllow, Protocol: "udp", Ports: "5000-5100"},
+		{Action: FirewallAllow, Protocol: "icmp"},
+		{Action: FirewallDeny, Remote: "192.168.1.1"},
+		{Action: FirewallDeny},
+	}, rules)
+
+	assert.Equal(t, "allow tcp 10.0.0.0/8 22, allow udp any 5000-5100, allow icmp, deny any 192.168.1.1, deny", FirewallRulesString(rules))
+
+	low, high, err := rules[1].PortRange()
+	assert.NoError(t, err)
+	assert.Equal(t, 5000, low)
+	assert.Equal(t, 5100, high)
+
+	rules, err = ParseFirewallRules("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	for _, s := range []string{
+		"accept",
+		"allow sctp",
+		"allow tcp 10.0.0.0/33",
+		"allow any any 80",
+		"allow icmp any 80",
+		"allow tcp any 0",
+		"allow tcp any 90-80",
+		"allow tcp any 80 extra",
+	} {
+		_, err := ParseFirewallRule(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFirewallRulesEncoding(t *testing.T) {
+	n := ContainerNetwork{
+		Ingress: []FirewallRule{
+			{Action: FirewallAllow, Protocol: "tcp", Remote: "10.0.0.0/8", Ports: "22"},
+			{Action: FirewallDeny},
+		},
+		Egress: []FirewallRule{
+			{Action: FirewallDeny, Protocol: "udp", Ports: "53"},
+		},
+	}
+
+	encoded := map[string]string{}
+	extraconfig.Encode(extraconfig.MapSink(encoded), n)
+
+	var decoded ContainerNetwork
+	extraconfig.Decode(extraconfig.MapSource(encoded), &decoded)
+
+	assert.Equal(t, n.Ingress, decoded.Ingress)
+	assert.Equal(t, n.Egress, decoded.Egress)
+}
@@ -0,0 +1,171 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Log drivers that container output can be forwarded to by the appliance
+const (
+	LogDriverGELF    = "gelf"
+	LogDriverFluentd = "fluentd"
+
+	// FluentdPort is the port of a fluentd endpoint given without one
+	FluentdPort = 24224
+)
+
+// LogDrivers are the log drivers that container output can be forwarded to
+var LogDrivers = []string{LogDriverGELF, LogDriverFluentd}
+
+// logAddressOpts are the options holding the endpoint of each driver, which must be given as the
+// default endpoint of docker is local to the docker host
+var logAddressOpts = map[string]string{
+	LogDriverGELF:    "gelf-address",
+	LogDriverFluentd: "fluentd-address",
+}
+
+// logOptValidators check the options of each driver, as the docker log drivers do
+var logOptValidators = map[string]func(map[string]string) error{
+	LogDriverGELF:    validateGELFOpts,
+	LogDriverFluentd: validateFluentdOpts,
+}
+
+// Forwarded returns whether the output of containers with this log config is forwarded. The
+// json-file and none drivers leave it in the log of the containerVM, which is always kept so that
+// docker logs works.
+func (c LogConfig) Forwarded() bool {
+	switch c.Type {
+	case "", "json-file", "none":
+		return false
+	}
+	return true
+}
+
+// Validate checks that the log driver is supported and that its options are valid
+func (c LogConfig) Validate() error {
+	if !c.Forwarded() {
+		return nil
+	}
+
+	opt, ok := logAddressOpts[c.Type]
+	if !ok {
+		return fmt.Errorf("log driver %q is not supported, use one of: %s", c.Type, strings.Join(LogDrivers, ", "))
+	}
+
+	if c.Config[opt] == "" {
+		return fmt.Errorf("log driver %s needs the log opt %s", c.Type, opt)
+	}
+
+	// container labels are not known to the appliance
+	if _, ok := c.Config["labels"]; ok {
+		return fmt.Errorf("log opt labels is not supported")
+	}
+
+	return logOptValidators[c.Type](c.Config)
+}
+
+// ParseLogConfig parses log options given as key=value for the driver
+func ParseLogConfig(driver string, opts []string) (LogConfig, error) {
+	cfg := LogConfig{Type: driver}
+
+	for _, opt := range opts {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return cfg, fmt.Errorf("log opt %q must be in format key=value", opt)
+		}
+
+		if cfg.Config == nil {
+			cfg.Config = make(map[string]string)
+		}
+		cfg.Config[kv[0]] = kv[1]
+	}
+
+	return cfg, nil
+}
+
+// validateGELFOpts checks the options of the gelf driver
+func validateGELFOpts(cfg map[string]string) error {
+	for key, val := range cfg {
+		switch key {
+		case "gelf-address", "gelf-tag", "tag", "env":
+		case "gelf-compression-level":
+			// from flate.DefaultCompression to flate.BestCompression
+			if i, err := strconv.Atoi(val); err != nil || i < -1 || i > 9 {
+				return fmt.Errorf("unknown value %q for log opt %q for gelf log driver", val, key)
+			}
+		case "gelf-compression-type":
+			switch val {
+			case "gzip", "zlib", "none":
+			default:
+				return fmt.Errorf("unknown value %q for log opt %q for gelf log driver", val, key)
+			}
+		default:
+			return fmt.Errorf("unknown log opt %q for gelf log driver", key)
+		}
+	}
+
+	u, err := url.Parse(cfg["gelf-address"])
+	if err != nil || u.Scheme != "udp" {
+		return fmt.Errorf("gelf-address should be in form udp://host:port, got %s", cfg["gelf-address"])
+	}
+	if _, _, err = net.SplitHostPort(u.Host); err != nil {
+		return fmt.Errorf("gelf-address should be in form udp://host:port, got %s", cfg["gelf-address"])
+	}
+
+	return nil
+}
+
+// validateFluentdOpts checks the options of the fluentd driver
+func validateFluentdOpts(cfg map[string]string) error {
+	for key := range cfg {
+		switch key {
+		case "fluentd-address", "tag", "fluentd-tag", "env":
+		default:
+			return fmt.Errorf("unknown log opt %q for fluentd log driver", key)
+		}
+	}
+
+	_, err := FluentdAddress(cfg["fluentd-address"])
+	return err
+}
+
+// FluentdAddress returns the host:port of the fluentd endpoint given as [tcp://]host[:port]
+func FluentdAddress(address string) (string, error) {
+	address = strings.TrimPrefix(address, "tcp://")
+	if address == "" {
+		return "", fmt.Errorf("fluentd-address must be given")
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		// no port given
+		host, port = address, strconv.Itoa(FluentdPort)
+	}
+
+	if host == "" || strings.Contains(host, "/") {
+		return "", fmt.Errorf("invalid fluentd-address %q", address)
+	}
+
+	if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("invalid fluentd-address port %q", port)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
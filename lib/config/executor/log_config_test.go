@@ -0,0 +1,76 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogConfigValidate(t *testing.T) {
+	valid := []LogConfig{
+		{},
+		{Type: "json-file"},
+		{Type: "none"},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "udp://10.0.0.1:12201", "tag": "web"}},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "udp://10.0.0.1:12201", "gelf-compression-type": "zlib", "gelf-compression-level": "9"}},
+		{Type: "fluentd", Config: map[string]string{"fluentd-address": "10.0.0.1"}},
+		{Type: "fluentd", Config: map[string]string{"fluentd-address": "tcp://logs.example.com:24224", "env": "HOME"}},
+	}
+	for _, cfg := range valid {
+		assert.NoError(t, cfg.Validate(), "%#v", cfg)
+	}
+
+	invalid := []LogConfig{
+		{Type: "syslog", Config: map[string]string{"syslog-address": "udp://10.0.0.1"}},
+		{Type: "gelf"},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "10.0.0.1:12201"}},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "tcp://10.0.0.1:12201"}},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "udp://10.0.0.1"}},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "udp://10.0.0.1:12201", "labels": "a"}},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "udp://10.0.0.1:12201", "gelf-compression-level": "10"}},
+		{Type: "fluentd", Config: map[string]string{"fluentd-address": "10.0.0.1:port"}},
+		{Type: "fluentd", Config: map[string]string{"fluentd-address": "10.0.0.1", "fluentd-buffer-limit": "1"}},
+	}
+	for _, cfg := range invalid {
+		assert.Error(t, cfg.Validate(), "%#v", cfg)
+	}
+}
+
+func TestParseLogConfig(t *testing.T) {
+	cfg, err := ParseLogConfig("fluentd", []string{"fluentd-address=10.0.0.1:24224", "tag=a=b"})
+	require.NoError(t, err)
+	assert.Equal(t, "fluentd", cfg.Type)
+	assert.Equal(t, map[string]string{"fluentd-address": "10.0.0.1:24224", "tag": "a=b"}, cfg.Config)
+
+	_, err = ParseLogConfig("fluentd", []string{"fluentd-address"})
+	assert.Error(t, err)
+}
+
+func TestFluentdAddress(t *testing.T) {
+	for in, out := range map[string]string{
+		"10.0.0.1":               "10.0.0.1:24224",
+		"tcp://10.0.0.1:5000":    "10.0.0.1:5000",
+		"logs.example.com:24224": "logs.example.com:24224",
+		"tcp://[fd00::1]:24224":  "[fd00::1]:24224",
+	} {
+		addr, err := FluentdAddress(in)
+		if assert.NoError(t, err, in) {
+			assert.Equal(t, out, addr)
+		}
+	}
+}
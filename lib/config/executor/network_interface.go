@@ -44,6 +44,52 @@ type NetworkEndpoint struct {
 
 	// The list of exposed ports on the container
 	Ports []string `vic:"0.1" scope:"read-only" key:"ports"`
+
+	// MAC address of the vNIC, assigned by vSphere if empty
+	MAC string `vic:"0.1" scope:"read-only" key:"mac"`
+
+	// DHCP client identifier to send, derived from the interface if empty
+	DHCPClientID string `vic:"0.1" scope:"read-only" key:"dhcp_client_id"`
+}
+
+// How container addresses on a network are managed
+const (
+	// IPAMStatic assigns addresses from the pools of the network
+	IPAMStatic = "static"
+	// IPAMDHCP leaves containers to lease their addresses from a DHCP server on the network
+	IPAMDHCP = "dhcp"
+	// IPAMDHCPRelay leases addresses from DHCP servers on behalf of containers, relaying the requests
+	// from the VCH, and assigns them statically
+	IPAMDHCPRelay = "dhcp-relay"
+)
+
+// Firewall policies applied by containers to their interfaces on a network
+const (
+	// TrustOpen allows all traffic, and is the policy of networks without one
+	TrustOpen = "open"
+	// TrustOutbound allows connections from the container and their replies only
+	TrustOutbound = "outbound"
+	// TrustPublished allows connections from the container and to its published ports only
+	TrustPublished = "published"
+	// TrustClosed allows no traffic other than DHCP
+	TrustClosed = "closed"
+)
+
+// TrustLevels are the firewall policies in order of increasing restriction
+var TrustLevels = []string{TrustOpen, TrustOutbound, TrustPublished, TrustClosed}
+
+// ValidTrustLevel reports whether level is one of TrustLevels, or empty for open
+func ValidTrustLevel(level string) bool {
+	if level == "" {
+		return true
+	}
+
+	for _, l := range TrustLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
 }
 
 // ContainerNetwork is the data needed on a per container basis both for vSphere to ensure it's attached
@@ -64,9 +110,35 @@ type ContainerNetwork struct {
 	// The set of nameservers associated with this network - may be empty
 	Nameservers []net.IP `vic:"0.1" scope:"read-write" key:"dns"`
 
+	// The DNS search domains associated with this network - may be empty
+	SearchDomains []string `vic:"0.1" scope:"read-only" key:"search"`
+
+	// MTU for interfaces on this network - zero leaves the guest default
+	MTU int `vic:"0.1" scope:"read-only" key:"mtu"`
+
+	// 802.1Q VLAN ID tagged in the guest by interfaces on this network - zero for untagged
+	VLAN int `vic:"0.1" scope:"read-only" key:"vlan"`
+
+	// Firewall policy applied in the guest to interfaces on this network - one of TrustLevels, open if empty
+	TrustLevel string `vic:"0.1" scope:"read-only" key:"trust_level"`
+
+	// Firewall rules applied in the guest to traffic to and from the container on this network,
+	// matched in order ahead of the TrustLevel policy
+	Ingress []FirewallRule `vic:"0.1" scope:"read-only" key:"ingress"`
+	Egress  []FirewallRule `vic:"0.1" scope:"read-only" key:"egress"`
+
 	// The IP ranges for this network
 	Pools []ip.Range `vic:"0.1" scope:"read-only" key:"pools"`
 
+	// How container addresses are managed - one of the IPAM constants, derived from the pools if empty
+	IPAM string `vic:"0.1" scope:"read-only" key:"ipam"`
+
+	// The DHCP servers addresses are leased from with IPAMDHCPRelay
+	DHCPServers []net.IP `vic:"0.1" scope:"read-only" key:"dhcp_servers"`
+
+	// Addresses held back from the pools, only assigned to containers asking for them
+	Reserved []net.IP `vic:"0.1" scope:"read-only" key:"reserved"`
+
 	// set of network wide links and aliases for this container on this network
 	Aliases []string `vic:"0.1" scope:"hidden" key:"aliases"`
 }
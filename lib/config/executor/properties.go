@@ -0,0 +1,33 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import "fmt"
+
+// vApp properties that override the configuration of an executor. They are delivered to the guest
+// in its OVF environment, so that they can be changed from vSphere without editing extraconfig.
+const (
+	// DNSProperty holds the nameservers for all networks, comma separated
+	DNSProperty = "vic.dns"
+	// DebugProperty holds the debug level
+	DebugProperty = "vic.debug"
+	// EnvPropertyPrefix prefixes the properties that set an environment variable of a session
+	EnvPropertyPrefix = "vic.env."
+)
+
+// EnvProperty returns the ID of the property that sets the environment variable name of a session
+func EnvProperty(session, name string) string {
+	return fmt.Sprintf("%s%s.%s", EnvPropertyPrefix, session, name)
+}
@@ -0,0 +1,180 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validation checks executor configuration for consistency. It is used when the
+// configuration is encoded by the installer and portlayer, and when it is decoded by the
+// tether, so that a bad configuration is reported the same way wherever it is detected
+// rather than surfacing as a different runtime failure in each component.
+package validation
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+// maxSignal is the highest signal number accepted for a stop signal
+const maxSignal = 64
+
+// signalName matches signal names with or without the SIG prefix, e.g. TERM, SIGKILL, SIGRTMIN+3
+var signalName = regexp.MustCompile(`^(SIG)?[A-Z][A-Z0-9]*([+-][0-9]+)?$`)
+
+// Error holds all of the problems found in a configuration
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid executor configuration: %s", strings.Join(e.Problems, "; "))
+}
+
+// checker accumulates problems so that all of them are reported together
+type checker struct {
+	problems []string
+}
+
+func (c *checker) notef(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) err() error {
+	if len(c.problems) == 0 {
+		return nil
+	}
+	return &Error{Problems: c.problems}
+}
+
+// Executor validates the executor configuration, including all of its sessions, mounts and networks
+func Executor(cfg *executor.ExecutorConfig) error {
+	c := &checker{}
+
+	for id, session := range cfg.Sessions {
+		if session == nil {
+			c.notef("session %s: no configuration", id)
+			continue
+		}
+		if session.ID != id {
+			c.notef("session %s: ID %q does not match the session key", id, session.ID)
+		}
+		c.session(id, session)
+	}
+
+	for name, mount := range cfg.Mounts {
+		c.mount(name, mount)
+	}
+
+	for name, endpoint := range cfg.Networks {
+		if endpoint == nil {
+			c.notef("network %s: no configuration", name)
+			continue
+		}
+		if endpoint.Static && endpoint.IP == nil {
+			c.notef("network %s: static endpoint has no IP address", name)
+		}
+		if !executor.ValidTrustLevel(endpoint.Network.TrustLevel) {
+			c.notef("network %s: unknown firewall policy %q", name, endpoint.Network.TrustLevel)
+		}
+	}
+
+	return c.err()
+}
+
+// Session validates a single session
+func Session(id string, session *executor.SessionConfig) error {
+	c := &checker{}
+	c.session(id, session)
+	return c.err()
+}
+
+// Env validates the syntax of environment variables, which must be in the form KEY=value
+func Env(env []string) error {
+	c := &checker{}
+	c.env("environment", env)
+	return c.err()
+}
+
+func (c *checker) session(id string, session *executor.SessionConfig) {
+	subject := fmt.Sprintf("session %s", id)
+
+	if session.ID == "" {
+		c.notef("%s: ID is required", subject)
+	}
+
+	if session.Cmd.Path == "" {
+		c.notef("%s: command path is required", subject)
+	} else if strings.ContainsRune(session.Cmd.Path, 0) {
+		c.notef("%s: command path contains a NUL character", subject)
+	}
+
+	if session.Cmd.Dir != "" && !path.IsAbs(session.Cmd.Dir) {
+		c.notef("%s: working directory %q must be an absolute path", subject, session.Cmd.Dir)
+	}
+
+	c.env(subject, session.Cmd.Env)
+
+	// a session that waits for an attach before launching can never be launched without one
+	if session.RunBlock && !session.Attach {
+		c.notef("%s: runblock requires attach to be enabled", subject)
+	}
+
+	if session.StopSignal != "" && !validSignal(session.StopSignal) {
+		c.notef("%s: invalid stop signal %q", subject, session.StopSignal)
+	}
+
+	if session.Deadline < 0 {
+		c.notef("%s: deadline %d must not be negative", subject, session.Deadline)
+	}
+
+	if session.Group != "" && session.User == "" {
+		c.notef("%s: group %q requires a user", subject, session.Group)
+	}
+}
+
+func (c *checker) env(subject string, env []string) {
+	for _, e := range env {
+		i := strings.Index(e, "=")
+		switch {
+		case i < 0:
+			c.notef("%s: environment variable %q must be in the form KEY=value", subject, e)
+		case i == 0:
+			c.notef("%s: environment variable %q has an empty name", subject, e)
+		case strings.ContainsRune(e, 0):
+			c.notef("%s: environment variable %q contains a NUL character", subject, e[:i])
+		}
+	}
+}
+
+func (c *checker) mount(name string, mount executor.MountSpec) {
+	subject := fmt.Sprintf("mount %s", name)
+
+	if mount.Source.Scheme == "" {
+		c.notef("%s: source must be a URI", subject)
+	}
+
+	if !path.IsAbs(mount.Path) {
+		c.notef("%s: mount point %q must be an absolute path", subject, mount.Path)
+	}
+}
+
+// validSignal checks the syntax of a signal name or number, not whether the guest supports it
+func validSignal(sig string) bool {
+	if n, err := strconv.Atoi(sig); err == nil {
+		return n > 0 && n <= maxSignal
+	}
+	return signalName.MatchString(strings.ToUpper(sig))
+}
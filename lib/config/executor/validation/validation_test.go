@@ -0,0 +1,116 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+func testSession() *executor.SessionConfig {
+	return &executor.SessionConfig{
+		Common: executor.Common{
+			ID:   "abc",
+			Name: "test",
+		},
+		Cmd: executor.Cmd{
+			Path: "/bin/date",
+			Args: []string{"/bin/date"},
+			Env:  []string{"PATH=/bin", "EMPTY="},
+			Dir:  "/",
+		},
+	}
+}
+
+func TestSession(t *testing.T) {
+	var tests = []struct {
+		modify   func(s *executor.SessionConfig)
+		problems int
+	}{
+		{func(s *executor.SessionConfig) {}, 0},
+		{func(s *executor.SessionConfig) { s.Cmd.Path = "date"; s.Cmd.Dir = "" }, 0},
+		{func(s *executor.SessionConfig) { s.StopSignal = "SIGTERM" }, 0},
+		{func(s *executor.SessionConfig) { s.StopSignal = "rtmin+3" }, 0},
+		{func(s *executor.SessionConfig) { s.StopSignal = "9" }, 0},
+		{func(s *executor.SessionConfig) { s.RunBlock = true; s.Attach = true }, 0},
+		{func(s *executor.SessionConfig) { s.User = "daemon"; s.Group = "daemon" }, 0},
+
+		{func(s *executor.SessionConfig) { s.ID = "" }, 1},
+		{func(s *executor.SessionConfig) { s.Cmd.Path = "" }, 1},
+		{func(s *executor.SessionConfig) { s.Cmd.Path = "/bin/da\x00te" }, 1},
+		{func(s *executor.SessionConfig) { s.Cmd.Dir = "tmp" }, 1},
+		{func(s *executor.SessionConfig) { s.Cmd.Env = []string{"PATH"} }, 1},
+		{func(s *executor.SessionConfig) { s.Cmd.Env = []string{"=value", "A=b\x00"} }, 2},
+		{func(s *executor.SessionConfig) { s.RunBlock = true }, 1},
+		{func(s *executor.SessionConfig) { s.StopSignal = "0" }, 1},
+		{func(s *executor.SessionConfig) { s.StopSignal = "SIG TERM" }, 1},
+		{func(s *executor.SessionConfig) { s.Group = "daemon" }, 1},
+		{func(s *executor.SessionConfig) { s.Cmd.Path = ""; s.RunBlock = true; s.Cmd.Dir = "." }, 3},
+	}
+
+	for i, te := range tests {
+		s := testSession()
+		te.modify(s)
+
+		err := Session(s.ID, s)
+		if te.problems == 0 {
+			assert.NoError(t, err, "test %d", i)
+			continue
+		}
+
+		if assert.IsType(t, &Error{}, err, "test %d", i) {
+			assert.Len(t, err.(*Error).Problems, te.problems, "test %d: %s", i, err)
+		}
+	}
+}
+
+func TestExecutor(t *testing.T) {
+	cfg := &executor.ExecutorConfig{
+		Sessions: map[string]*executor.SessionConfig{
+			"abc": testSession(),
+		},
+		Mounts: map[string]executor.MountSpec{
+			"data": {
+				Source: url.URL{Scheme: "label", Path: "data"},
+				Path:   "/data",
+			},
+		},
+		Networks: map[string]*executor.NetworkEndpoint{
+			"bridge": {Static: false},
+		},
+	}
+	assert.NoError(t, Executor(cfg))
+
+	cfg.Sessions["def"] = testSession()
+	cfg.Mounts["bad"] = executor.MountSpec{Path: "data"}
+	cfg.Networks["static"] = &executor.NetworkEndpoint{Static: true}
+	cfg.Networks["public"] = &executor.NetworkEndpoint{Network: executor.ContainerNetwork{TrustLevel: "trusted"}}
+
+	err := Executor(cfg)
+	if assert.IsType(t, &Error{}, err) {
+		// session key mismatch, mount source and path, static network without an address, unknown firewall policy
+		assert.Len(t, err.(*Error).Problems, 5, err.Error())
+	}
+}
+
+func TestEnv(t *testing.T) {
+	assert.NoError(t, Env(nil))
+	assert.NoError(t, Env([]string{"A=b=c", "B="}))
+	assert.Error(t, Env([]string{"A=b", "B"}))
+}
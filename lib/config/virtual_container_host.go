@@ -41,6 +41,77 @@ const (
 	Name = "{name}"
 )
 
+const (
+	// AntiAffinityLabel is the docker label naming the service a container belongs to, so that
+	// it can be kept on a different host to the other containers of that service
+	AntiAffinityLabel = "com.vmware.vic.anti-affinity"
+	// AntiAffinityAnnotation is the container annotation the label is carried to the port layer in
+	AntiAffinityAnnotation = "vic.anti-affinity"
+
+	// DeadlineLabel is the docker label giving how long a container may run each time it is started,
+	// as a duration such as 90m, after which it is stopped
+	DeadlineLabel = "com.vmware.vic.deadline"
+
+	// ProtectedLabel is the docker label that, when true, stops a container being removed unless
+	// the removal is made through the VIC API with force_protected
+	ProtectedLabel = "com.vmware.vic.protected"
+
+	// ExternalLabel is the docker label, always true, carried by containers that are existing VMs adopted
+	// into the VCH inventory. They can be inspected but not changed.
+	ExternalLabel = "com.vmware.vic.external"
+	// ExternalAnnotation is the container annotation the port layer marks external containers with
+	ExternalAnnotation = "vic.external"
+
+	// TetherVersionLabel is the docker label reporting the version of the tether in a containerVM,
+	// as the tether gave it when it last connected
+	TetherVersionLabel = "com.vmware.vic.tether-version"
+	// TetherOutdatedLabel is the docker label, true or false, reporting whether the tether predates the
+	// appliance, i.e. whether the container was booted from an outdated bootstrap image
+	TetherOutdatedLabel = "com.vmware.vic.tether-outdated"
+
+	// SerialConcentratorAppliance is the SerialConcentrator value that has the appliance act as
+	// the virtual serial port concentrator of the containerVMs
+	SerialConcentratorAppliance = "appliance"
+)
+
+// Names of the maintenance jobs run periodically by the port layer
+const (
+	// ImageCleanupJob removes images left incomplete in the image stores by interrupted pulls
+	ImageCleanupJob = "image-cleanup"
+	// ContainerReconcileJob reconciles the container cache with the containerVMs in vSphere
+	ContainerReconcileJob = "container-reconcile"
+	// CertificateCheckJob checks the host and vSphere extension certificates for expiry
+	CertificateCheckJob = "certificate-check"
+)
+
+// MaintenanceJobNames are the names of all the maintenance jobs run by the port layer
+var MaintenanceJobNames = []string{ImageCleanupJob, ContainerReconcileJob, CertificateCheckJob}
+
+// Problems the appliance records in Validation when checking its configuration during boot
+const (
+	// InvalidCABundle is recorded when the client certificate authorities do not parse
+	InvalidCABundle = "invalid-ca-bundle"
+	// InvalidHostCertificate is recorded when the host certificate or its key does not parse
+	InvalidHostCertificate = "invalid-host-certificate"
+	// InvalidExtensionCertificate is recorded when the vSphere extension certificate or its key does not parse
+	InvalidExtensionCertificate = "invalid-extension-certificate"
+	// UnresolvableTarget is recorded when the vSphere target name does not resolve
+	UnresolvableTarget = "unresolvable-target"
+	// UnreachableTarget is recorded when the appliance cannot log in to the vSphere target
+	UnreachableTarget = "unreachable-target"
+	// UnreachableDatastore is recorded when an image store datastore is missing or not accessible
+	UnreachableDatastore = "unreachable-datastore"
+)
+
+var validationMessages = map[string]string{
+	InvalidCABundle:             "invalid CA bundle",
+	InvalidHostCertificate:      "invalid host certificate",
+	InvalidExtensionCertificate: "invalid vSphere extension certificate",
+	UnresolvableTarget:          "vSphere target does not resolve",
+	UnreachableTarget:           "vSphere target is unreachable",
+	UnreachableDatastore:        "image store datastore is unreachable",
+}
+
 // Can we just treat the VCH appliance as a containerVM booting off a specific bootstrap image
 // It has many of the same requirements (around networks being attached, version recorded,
 // volumes mounted, et al). Each of the components can easily be captured as a Session given they
@@ -79,6 +150,54 @@ type VirtualContainerHostConfigSpec struct {
 
 	// configuration for vic-machine
 	CreateBridgeNetwork bool `vic:"0.1" scope:"read-only" key:"create_bridge_network"`
+	// Distributed switch that the bridge port group is created on in vCenter, empty if it was supplied
+	BridgeNetworkDVS string `vic:"0.1" scope:"read-only" key:"bridge_network_dvs"`
+	// VLAN ID of the created bridge port group, 0 for none
+	BridgeNetworkVLAN int `vic:"0.1" scope:"read-only" key:"bridge_network_vlan"`
+	// Port binding of the created bridge port group, static or ephemeral
+	BridgeNetworkPortBinding string `vic:"0.1" scope:"read-only" key:"bridge_network_port_binding"`
+	// ESXi firewall rulesets enabled for serial-over-LAN, as host reference/ruleset key, disabled again on delete
+	FirewallRulesets []string `vic:"0.1" scope:"read-only" key:"firewall_rulesets"`
+	// Whether vic-machine delete refuses to remove the VCH unless --force-protected is given
+	Protected bool `vic:"0.1" scope:"read-only" key:"protected"`
+	// Port layer maintenance jobs explicitly enabled or disabled, keyed by job name; unlisted jobs run
+	MaintenanceJobs map[string]bool `vic:"0.1" scope:"read-only" key:"maintenance_jobs"`
+	// Release metadata checked for newer VIC versions, reported by docker info, vicadmin and vic-machine ls;
+	// empty to disable the check
+	ReleaseURL url.URL `vic:"0.1" scope:"read-only" key:"release_url"`
+
+	// Directory server that vicadmin users are authenticated against instead of vSphere
+	AdminLDAP `vic:"0.1" scope:"read-only" key:"admin_ldap"`
+
+	// Outcome of the appliance checking this configuration when it boots
+	Validation ApplianceValidation `vic:"0.1" scope:"read-write" key:"validation"`
+}
+
+// ApplianceValidation holds the first problem the appliance found with its configuration during boot.
+// The appliance components are not launched while there is a problem.
+type ApplianceValidation struct {
+	// Code identifies the problem, empty if the configuration is valid
+	Code string `vic:"0.1" scope:"read-write" key:"code"`
+	// Detail is the error underlying the problem
+	Detail string `vic:"0.1" scope:"read-write" key:"detail"`
+}
+
+// Failed returns whether the appliance found a problem with its configuration
+func (v *ApplianceValidation) Failed() bool {
+	return v.Code != ""
+}
+
+// Message describes the problem found, including the detail if there is any
+func (v *ApplianceValidation) Message() string {
+	msg, ok := validationMessages[v.Code]
+	if !ok {
+		msg = v.Code
+	}
+
+	if v.Detail == "" {
+		return msg
+	}
+	return msg + ": " + v.Detail
 }
 
 // ContainerConfig holds the container configuration for a virtual container host
@@ -93,6 +212,30 @@ type Container struct {
 	ContainerNameConvention string
 	// Permitted datastore URLs for container storage for this virtual container host
 	ContainerStores []url.URL `vic:"0.1" scope:"read-only" recurse:"depth=0"`
+	// DRS host group that containerVMs are kept on, if any
+	ContainerHostGroup string `vic:"0.1" scope:"read-only" key:"container_host_group"`
+	// DRS VM group holding the containerVMs that are kept on ContainerHostGroup
+	ContainerVMGroup string `vic:"0.1" scope:"read-only" key:"container_vm_group"`
+	// Whether containerVMs must, rather than should, run on ContainerHostGroup
+	ContainerHostGroupMandatory bool `vic:"0.1" scope:"read-only" key:"container_host_group_mandatory"`
+	// Prefix of the DRS rules keeping containerVMs of the same service on different hosts, empty if disabled
+	AntiAffinityRulePrefix string `vic:"0.1" scope:"read-only" key:"anti_affinity_rule_prefix"`
+	// Whether containerVMs write their kernel console to a crash log in their folder
+	ContainerCrashLogs bool `vic:"0.1" scope:"read-only" key:"container_crash_logs"`
+	// Virtual hardware profile of containerVMs, empty for the default
+	ContainerVMProfile string `vic:"0.1" scope:"read-only" key:"container_vm_profile"`
+	// Path of the VM folder, relative to the datacenter VM folder, that the appliance and containerVMs
+	// are created in, empty for the datacenter VM folder itself
+	VMFolder string `vic:"0.1" scope:"read-only" key:"vm_folder"`
+	// ID of the vSphere storage policy applied to containerVMs and their scratch disks, empty for none
+	StoragePolicy string `vic:"0.1" scope:"read-only" key:"storage_policy"`
+	// Log driver that the output of containers created without one is forwarded to, if any
+	ContainerLogConfig executor.LogConfig `vic:"0.1" scope:"read-only" key:"container_log_config"`
+	// Existing VMs that are listed as read-only external containers
+	ExternalVMs []types.ManagedObjectReference `vic:"0.1" scope:"read-only" key:"external_vms"`
+	// URI of the virtual serial port concentrator that containerVM serial ports are proxied through,
+	// SerialConcentratorAppliance for the appliance, or empty to connect them to the appliance directly
+	SerialConcentrator string `vic:"0.1" scope:"read-only" key:"serial_concentrator"`
 }
 
 // RegistryConfig defines the registries virtual container host can talk to
@@ -103,6 +246,24 @@ type Registry struct {
 	RegistryBlacklist []url.URL `vic:"0.1" scope:"read-only" recurse:"depth=0"`
 	// Insecure registries
 	InsecureRegistries []url.URL `vic:"0.1" scope:"read-only" key:"insecure_registries"`
+	// PEM encoded certificate authorities trusted for registries in addition to the system roots
+	RegistryCertificateAuthorities []byte `vic:"0.1" scope:"read-only" key:"registry_ca"`
+	// Proxies for specific registries, keyed by registry host, in place of HTTP(S)_PROXY
+	RegistryProxies map[string]*url.URL `vic:"0.1" scope:"read-only" key:"registry_proxies"`
+	// Images pulled into the image store once the VCH is up
+	PrefetchImages []string `vic:"0.1" scope:"read-only" key:"prefetch_images"`
+	// How often the prefetched images are pulled again to pick up new tags, zero for once only
+	PrefetchInterval time.Duration `vic:"0.1" scope:"read-only" key:"prefetch_interval"`
+}
+
+// AdminLDAP defines the LDAP or Active Directory server that vicadmin users are authenticated against
+type AdminLDAP struct {
+	// ldap:// or ldaps:// URL of the server, empty to authenticate vicadmin users against vSphere
+	LDAPURL url.URL `vic:"0.1" scope:"read-only" key:"url"`
+	// DN bound as to check credentials, with %s replaced by the username, e.g. uid=%s,ou=people,dc=example,dc=com
+	LDAPUserDN string `vic:"0.1" scope:"read-only" key:"user_dn"`
+	// PEM encoded certificate authorities trusted for ldaps:// servers in addition to the system roots
+	LDAPCertificateAuthorities []byte `vic:"0.1" scope:"read-only" key:"ca"`
 }
 
 // NetworkConfig defines the network configuration of virtual container host
@@ -112,21 +273,32 @@ type Network struct {
 	// Published networks available for containers to join, keyed by consumption name
 	ContainerNetworks map[string]*executor.ContainerNetwork `vic:"0.1" scope:"read-only" key:"container_networks"`
 	// The IP range for the bridge networks
-	BridgeIPRange *net.IPNet `vic:"0.1" scope:"read-only" key:"bridge-ip-range"`
+	BridgeIPRange *net.IPNet `vic:"0.1" scope:"read-only" key:"bridge_ip_range"`
 	// The width of each new bridge network
-	BridgeNetworkWidth *net.IPMask `vic:"0.1" scope:"read-only" key:"bridge-net-width"`
+	BridgeNetworkWidth *net.IPMask `vic:"0.1" scope:"read-only" key:"bridge_net_width"`
+	// External IPAM service consulted for container addresses on networks with a pool, if any
+	IPAMWebhook url.URL `vic:"0.1" scope:"read-only" key:"ipam_webhook"`
 }
 
 // StorageConfig defines the storage configuration including images and volumes
 type Storage struct {
 	// Datastore URLs for image stores - the top layer is [0], the bottom layer is [len-1]
 	ImageStores []url.URL `vic:"0.1" scope:"read-only" key:"image_stores"`
+	// Policy for choosing between image stores when there is more than one
+	ImageStorePlacement string `vic:"0.1" scope:"read-only" key:"image_store_placement"`
 	// Permitted datastore URL roots for volumes
 	// Keyed by the volume store name (which is used by the docker user to
 	// refer to the datstore + path), valued by the datastores and the path.
 	VolumeLocations map[string]*url.URL `vic:"0.1" scope:"read-only"`
 	// default size for root image
 	ScratchSize int64 `vic:"0.1" scope:"read-only" key:"scratch_size"`
+	// Provisioning of the root image and volume disks - thin, thick or eagerZeroedThick, empty for thin
+	DiskProvisioning string `vic:"0.1" scope:"read-only" key:"disk_provisioning"`
+	// IDs of the vSphere storage policies applied to new volumes, keyed by volume store name. Volumes
+	// in stores without one are created without a policy unless one is given at volume creation.
+	VolumeStoragePolicies map[string]string `vic:"0.1" scope:"read-only" key:"volume_storage_policies"`
+	// ID of the vSphere storage policy applied to the base image and image layer disks, empty for none
+	ImageStoragePolicy string `vic:"0.1" scope:"read-only" key:"image_storage_policy"`
 }
 
 type Certificate struct {
@@ -140,6 +312,12 @@ type Certificate struct {
 	CertificateAuthorities []byte `vic:"0.1" scope:"read-only"`
 	// Certificates for specific system access, keyed by FQDN
 	HostCertificates map[string]*RawCertificate
+	// Time remaining before host certificate expiry at which warnings are raised
+	CertificateExpiryThresholds []time.Duration `vic:"0.1" scope:"read-only" key:"expiry_thresholds"`
+	// Whether a self-signed host certificate is renewed automatically before it expires
+	CertificateAutoRenew bool `vic:"0.1" scope:"read-only" key:"auto_renew"`
+	// How long before expiry a self-signed host certificate is renewed
+	CertificateRenewalOverlap time.Duration `vic:"0.1" scope:"read-only" key:"renewal_overlap"`
 }
 
 // Connection holds the vSphere connection configuration
@@ -317,6 +495,29 @@ func (t *RawCertificate) X509Certificate() (*x509.Certificate, error) {
 	return cert, err
 }
 
+// HostCertificateMonitor returns an expiry monitor for the host certificate, configured with the
+// thresholds and renewal settings from the VCH configuration
+func (t *VirtualContainerHostConfigSpec) HostCertificateMonitor() (*certificate.ExpiryMonitor, error) {
+	if t.HostCertificate.IsNil() {
+		return nil, errors.New("nil certificate")
+	}
+
+	em, err := certificate.NewExpiryMonitor("host", certificate.NewKeyPair("", "", t.HostCertificate.Cert, t.HostCertificate.Key))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(t.CertificateExpiryThresholds) > 0 {
+		em.Thresholds = t.CertificateExpiryThresholds
+	}
+	if t.CertificateRenewalOverlap > 0 {
+		em.RenewalOverlap = t.CertificateRenewalOverlap
+	}
+	em.AutoRenew = t.CertificateAutoRenew
+
+	return em, nil
+}
+
 func (t *RawCertificate) IsNil() bool {
 	if t == nil {
 		return true
@@ -50,15 +50,20 @@ type Client interface {
 	// per RFC 2132, section 9.8
 	SetParameterRequestList(...byte)
 
+	// SetClientID sets the client identifier sent in place of the one
+	// derived from the interface, per RFC 2132, section 9.14
+	SetClientID([]byte)
+
 	// LastAck returns the last ack packet from a request or renew operation.
 	LastAck() *dhcp.Packet
 }
 
 type client struct {
-	timeout time.Duration
-	id      ID
-	params  []byte
-	ack     dhcp4.Packet
+	timeout  time.Duration
+	id       ID
+	clientID []byte
+	params   []byte
+	ack      dhcp4.Packet
 }
 
 // The default timeout for the client
@@ -334,6 +339,15 @@ func (c *client) SetParameterRequestList(params ...byte) {
 	log.Debugf("c.params=%#v", c.params)
 }
 
+// SetClientID sets the client identifier sent in place of the one
+// derived from the interface, per RFC 2132, section 9.14
+func (c *client) SetClientID(id []byte) {
+	defer trace.End(trace.Begin(""))
+
+	c.clientID = make([]byte, len(id))
+	copy(c.clientID, id)
+}
+
 // setOptions sets dhcp options on a dhcp packet
 func (c *client) setOptions(p dhcp4.Packet) (dhcp4.Packet, error) {
 	defer trace.End(trace.Begin(""))
@@ -354,9 +368,12 @@ func (c *client) setOptions(p dhcp4.Packet) (dhcp4.Packet, error) {
 	opts[dhcp4.OptionParameterRequestList] = rl
 
 	if _, ok := opts[dhcp4.OptionClientIdentifier]; !ok {
-		b, err := c.id.MarshalBinary()
-		if err != nil {
-			return p, err
+		b := c.clientID
+		if len(b) == 0 {
+			var err error
+			if b, err = c.id.MarshalBinary(); err != nil {
+				return p, err
+			}
 		}
 
 		opts[dhcp4.OptionClientIdentifier] = b
@@ -78,3 +78,19 @@ func TestSetOptions(t *testing.T) {
 		assert.EqualValues(t, cid, b)
 	}
 }
+
+func TestSetClientID(t *testing.T) {
+	id, err := NewID(0, dummyHWAddr)
+	assert.NoError(t, err)
+	c := &client{
+		id: id,
+	}
+	c.SetClientID([]byte("\x00vch-1"))
+
+	p, err := c.setOptions(dhcp4.NewPacket(dhcp4.BootRequest))
+	assert.NoError(t, err)
+
+	// the configured client id replaces the generated one
+	opts := p.ParseOptions()
+	assert.EqualValues(t, []byte("\x00vch-1"), opts[dhcp4.OptionClientIdentifier])
+}
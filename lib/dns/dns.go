@@ -314,7 +314,7 @@ Redo:
 	return true, nil
 }
 
-// HandleVIC returns a response to a container name/id request
+// HandleVIC returns a response to a container name/id/alias request, or to a reverse lookup of a container address
 func (s *Server) HandleVIC(w mdns.ResponseWriter, r *mdns.Msg) (bool, error) {
 	defer trace.End(trace.Begin(r.String()))
 
@@ -333,57 +333,53 @@ func (s *Server) HandleVIC(w mdns.ResponseWriter, r *mdns.Msg) (bool, error) {
 	}
 
 	log.Debugf("RemoteAddr: %s", clientIP)
-	ip := net.ParseIP(clientIP)
+	client := net.ParseIP(clientIP)
 
-	var name string
-	var domain string
-
-	name = strings.TrimSuffix(question.Name, ".")
-	// Do we have a domain?
-	i := strings.IndexRune(name, '.')
-	if i >= 0 {
-		name, domain = name[:i], name[i+1:]
-	}
-
-	// get the requesting container's endpoint
-	e := ctx.ContainerByAddr(ip)
-	if e == nil {
-		return false, fmt.Errorf("Could not find requesting container with ip %s", ip)
-	}
-	scope := e.Scope()
-
-	if domain != "" && scope.Name() != domain {
-		return false, fmt.Errorf("Inter-scope request for container %s in %s from %s", name, domain, scope.Name())
-	}
-
-	// container specific alias search
-	c := ctx.Container(fmt.Sprintf("%s:%s:%s", scope.Name(), e.Container().Name(), name))
-	if c == nil {
-		// scope-wide search
-		c = ctx.Container(fmt.Sprintf("%s:%s", scope.Name(), name))
-	}
+	var answer []mdns.RR
+	switch question.Qtype {
+	case mdns.TypeA:
+		ip, err := ctx.ResolveName(client, question.Name)
+		if err != nil {
+			return false, err
+		}
 
-	if c == nil {
-		log.Debugf("Can't find the container: %q", name)
-		return false, fmt.Errorf("Can't find the container: %q", name)
-	}
+		answer = append(answer, &mdns.A{
+			Hdr: mdns.RR_Header{
+				Name:   question.Name,
+				Rrtype: mdns.TypeA,
+				Class:  mdns.ClassINET,
+				Ttl:    uint32(DefaultTTL.Seconds()),
+			},
+			A: ip,
+		})
+	case mdns.TypeAAAA:
+		// FIXME: Add AAAA when we support it
+		// containers only have IPv4 addresses, so answer known names without records rather than forwarding them
+		if _, err := ctx.ResolveName(client, question.Name); err != nil {
+			return false, err
+		}
+	case mdns.TypePTR:
+		addr := reverseAddr(question.Name)
+		if addr == nil {
+			return false, fmt.Errorf("Not an IPv4 reverse lookup: %q", question.Name)
+		}
 
-	e = c.Endpoint(scope)
-	if e.IP().IsUnspecified() {
-		return false, fmt.Errorf("No ip for container %q", name)
-	}
+		name, err := ctx.ResolveAddr(client, addr)
+		if err != nil {
+			return false, err
+		}
 
-	// FIXME: Add AAAA when we support it
-	answer := []mdns.RR{
-		&mdns.A{
+		answer = append(answer, &mdns.PTR{
 			Hdr: mdns.RR_Header{
 				Name:   question.Name,
-				Rrtype: mdns.TypeA,
+				Rrtype: mdns.TypePTR,
 				Class:  mdns.ClassINET,
 				Ttl:    uint32(DefaultTTL.Seconds()),
 			},
-			A: e.IP(),
-		},
+			Ptr: mdns.Fqdn(name),
+		})
+	default:
+		return false, fmt.Errorf("Unsupported query type %s for containers", mdns.TypeToString[question.Qtype])
 	}
 
 	// Start crafting reply msg
@@ -442,6 +438,28 @@ func (s *Server) HandleVIC(w mdns.ResponseWriter, r *mdns.Msg) (bool, error) {
 	return true, nil
 }
 
+// reverseAddr returns the IPv4 address of an in-addr.arpa. reverse lookup name, nil for any other name
+func reverseAddr(name string) net.IP {
+	const suffix = ".in-addr.arpa."
+
+	name = strings.ToLower(mdns.Fqdn(name))
+	if !strings.HasSuffix(name, suffix) {
+		return nil
+	}
+
+	octets := strings.Split(strings.TrimSuffix(name, suffix), ".")
+	if len(octets) != net.IPv4len {
+		return nil
+	}
+
+	// the octets are in reverse order
+	for i, j := 0, len(octets)-1; i < j; i, j = i+1, j-1 {
+		octets[i], octets[j] = octets[j], octets[i]
+	}
+
+	return net.ParseIP(strings.Join(octets, ".")).To4()
+}
+
 // ServeDNS implements the handler interface
 func (s *Server) ServeDNS(w mdns.ResponseWriter, r *mdns.Msg) {
 	defer trace.End(trace.Begin(r.String()))
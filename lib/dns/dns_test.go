@@ -168,3 +168,31 @@ func TestVIC(t *testing.T) {
 	server.Stop()
 	server.Wait()
 }
+
+func TestReverseAddr(t *testing.T) {
+	var tests = []struct {
+		name string
+		ip   net.IP
+	}{
+		{"2.0.16.172.in-addr.arpa.", net.IPv4(172, 16, 0, 2)},
+		{"2.0.16.172.IN-ADDR.ARPA", net.IPv4(172, 16, 0, 2)},
+		{"0.16.172.in-addr.arpa.", nil},
+		{"x.0.16.172.in-addr.arpa.", nil},
+		{"2.0.16.172.example.com.", nil},
+		{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa.", nil},
+	}
+
+	for _, te := range tests {
+		ip := reverseAddr(te.name)
+		if te.ip == nil {
+			if ip != nil {
+				t.Fatalf("reverseAddr(%q) => %s, want nil", te.name, ip)
+			}
+			continue
+		}
+
+		if !te.ip.Equal(ip) {
+			t.Fatalf("reverseAddr(%q) => %s, want %s", te.name, ip, te.ip)
+		}
+	}
+}
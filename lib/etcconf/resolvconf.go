@@ -38,6 +38,9 @@ type ResolvConf interface {
 	AddNameservers(...net.IP)
 	RemoveNameservers(...net.IP)
 	Nameservers() []net.IP
+	AddSearch(...string)
+	RemoveSearch(...string)
+	Search() []string
 	Attempts() uint
 	Timeout() time.Duration
 	SetAttempts(uint)
@@ -52,6 +55,7 @@ type resolvConf struct {
 	dirty       bool
 	path        string
 	nameservers []net.IP
+	search      []string
 	timeout     time.Duration
 	attempts    uint
 }
@@ -102,6 +106,13 @@ func (r *resolvConf) ConsumeEntry(t string) error {
 		}
 
 		r.addNameservers(ip)
+	case "search":
+		// the last search or domain line applies
+		r.search = nil
+		r.addSearch(fs[1:]...)
+	case "domain":
+		r.search = nil
+		r.addSearch(fs[1])
 	case "options":
 		parts := strings.Split(fs[1], ":")
 		if len(parts) > 2 {
@@ -148,6 +159,7 @@ func (r *resolvConf) Load() error {
 	}
 
 	r.nameservers = rc.nameservers
+	r.search = rc.search
 	return nil
 }
 
@@ -229,6 +241,56 @@ func (r *resolvConf) Nameservers() []net.IP {
 	return r.nameservers
 }
 
+func (r *resolvConf) AddSearch(domains ...string) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.addSearch(domains...)
+}
+
+func (r *resolvConf) addSearch(domains ...string) {
+	for _, d := range domains {
+		if d == "" {
+			continue
+		}
+
+		found := false
+		for _, rd := range r.search {
+			if rd == d {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			r.search = append(r.search, d)
+			r.dirty = true
+		}
+	}
+}
+
+func (r *resolvConf) RemoveSearch(domains ...string) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, d := range domains {
+		for i, rd := range r.search {
+			if d == rd {
+				r.search = append(r.search[:i], r.search[i+1:]...)
+				r.dirty = true
+				break
+			}
+		}
+	}
+}
+
+func (r *resolvConf) Search() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.search
+}
+
 func (r *resolvConf) Timeout() time.Duration {
 	return r.timeout
 }
@@ -255,6 +317,10 @@ func (r *resolvConf) lines() []string {
 		l = append(l, fmt.Sprintf("nameserver %s", n))
 	}
 
+	if len(r.search) > 0 {
+		l = append(l, fmt.Sprintf("search %s", strings.Join(r.search, " ")))
+	}
+
 	l = append(l, []string{
 		fmt.Sprintf("options timeout:%d", r.timeout/time.Second),
 		fmt.Sprintf("options attempts:%d", r.attempts),
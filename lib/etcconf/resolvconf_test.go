@@ -54,3 +54,26 @@ func TestConsumeEntry(t *testing.T) {
 		assert.Equal(t, te.attempts, r.Attempts())
 	}
 }
+
+func TestSearch(t *testing.T) {
+	r := NewResolvConf("")
+	c := r.(EntryConsumer)
+
+	c.ConsumeEntry("search")
+	assert.Empty(t, r.Search())
+
+	c.ConsumeEntry("domain example.com")
+	assert.Equal(t, []string{"example.com"}, r.Search())
+
+	// the last search or domain line applies
+	c.ConsumeEntry("search corp.example.com example.com")
+	assert.Equal(t, []string{"corp.example.com", "example.com"}, r.Search())
+
+	r.AddSearch("example.com", "lab.example.com", "")
+	assert.Equal(t, []string{"corp.example.com", "example.com", "lab.example.com"}, r.Search())
+
+	r.RemoveSearch("example.com", "missing.example.com")
+	assert.Equal(t, []string{"corp.example.com", "lab.example.com"}, r.Search())
+
+	assert.Contains(t, r.(*resolvConf).lines(), "search corp.example.com lab.example.com")
+}
@@ -81,6 +81,8 @@ func LearnRegistryURL(options Options) (string, error) {
 			Username:           options.Username,
 			Password:           options.Password,
 			InsecureSkipVerify: options.InsecureSkipVerify,
+			RootCAs:            options.RegistryCAs,
+			Proxies:            options.Proxies,
 		})
 
 		headers, err := fetcher.Head(url)
@@ -123,6 +125,8 @@ func LearnAuthURL(options Options) (*url.URL, error) {
 		Username:           options.Username,
 		Password:           options.Password,
 		InsecureSkipVerify: options.InsecureSkipVerify,
+		RootCAs:            options.RegistryCAs,
+		Proxies:            options.Proxies,
 	})
 
 	// We expect docker registry to return a 401 to us - with a WWW-Authenticate header
@@ -159,6 +163,8 @@ func FetchToken(ctx context.Context, options Options, url *url.URL, progressOutp
 		Username:           options.Username,
 		Password:           options.Password,
 		InsecureSkipVerify: options.InsecureSkipVerify,
+		RootCAs:            options.RegistryCAs,
+		Proxies:            options.Proxies,
 	})
 
 	token, err := fetcher.FetchAuthToken(url)
@@ -194,6 +200,8 @@ func FetchImageBlob(ctx context.Context, options Options, image *ImageWithMeta,
 		Password:           options.Password,
 		Token:              options.Token,
 		InsecureSkipVerify: options.InsecureSkipVerify,
+		RootCAs:            options.RegistryCAs,
+		Proxies:            options.Proxies,
 	})
 
 	// ctx
@@ -323,6 +331,8 @@ func FetchImageManifest(ctx context.Context, options Options, progressOutput pro
 		Password:           options.Password,
 		Token:              options.Token,
 		InsecureSkipVerify: options.InsecureSkipVerify,
+		RootCAs:            options.RegistryCAs,
+		Proxies:            options.Proxies,
 	})
 
 	manifestFileName, err := fetcher.Fetch(ctx, url, true, progressOutput)
@@ -16,6 +16,7 @@ package imagec
 
 import (
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -93,6 +94,12 @@ type Options struct {
 	InsecureSkipVerify bool
 	InsecureAllowHTTP  bool
 
+	// RegistryCAs are the certificate authorities trusted for registries, the system roots if nil
+	RegistryCAs *x509.CertPool
+
+	// Proxies overrides the proxies from the environment for some registries
+	Proxies urlfetcher.RegistryProxies
+
 	ImageManifest *Manifest
 }
 
@@ -410,6 +417,16 @@ func (ic *ImageC) CreateImageConfig(images []*ImageWithMeta) (metadata.ImageConf
 		Reference: ic.Reference,
 	}
 
+	// the healthcheck is not part of the vendored container config, so decode it separately
+	var health struct {
+		Config struct {
+			Healthcheck *metadata.HealthConfig
+		} `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(imageLayer.Meta), &health); err == nil {
+		imageConfig.Healthcheck = health.Config.Healthcheck
+	}
+
 	return imageConfig, nil
 }
 
@@ -22,7 +22,9 @@ import (
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/cmd/vic-machine/common"
 	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/pkg/ip"
+	"github.com/vmware/vic/pkg/vsphere/compute"
 )
 
 // Data wraps all parameters required by value validation
@@ -35,22 +37,33 @@ type Data struct {
 	CertPEM   []byte
 	KeyPEM    []byte
 	ClientCAs []byte
+
+	CertExpiryThresholds []time.Duration
+	CertAutoRenew        bool
+	CertRenewalOverlap   time.Duration
+
 	common.Images
 
-	ImageDatastorePath     string
-	VolumeLocations        map[string]string
+	ImageDatastorePaths []string
+	ImageStorePlacement string
+	// ImageStoragePolicy is the ID of the vSphere storage policy applied to the image disks, empty for none
+	ImageStoragePolicy string
+	common.VolumeStores
 	ContainerDatastoreName string
 
 	BridgeNetworkName string
-	ClientNetwork     NetworkConfig
-	ExternalNetwork   NetworkConfig
-	ManagementNetwork NetworkConfig
-	DNS               []net.IP
-
-	MappedNetworks         map[string]string
-	MappedNetworksGateways map[string]net.IPNet
-	MappedNetworksIPRanges map[string][]ip.Range
-	MappedNetworksDNS      map[string][]net.IP
+	BridgeNetworkMTU  int
+	// BridgeNetworkDVS is the distributed switch that the bridge port group is created on in vCenter,
+	// empty to use an existing port group
+	BridgeNetworkDVS         string
+	BridgeNetworkVLAN        int
+	BridgeNetworkPortBinding string
+	ClientNetwork            NetworkConfig
+	ExternalNetwork          NetworkConfig
+	ManagementNetwork        NetworkConfig
+	DNS                      []net.IP
+
+	common.ContainerNetworks
 
 	VCHCPULimitsMHz       int
 	VCHCPUReservationsMHz int
@@ -61,28 +74,80 @@ type Data struct {
 	VCHMemoryShares         *types.SharesInfo
 
 	BridgeIPRange *net.IPNet
+	IPAMWebhook   *url.URL
 
-	InsecureRegistries []url.URL
+	common.Registries
 
-	HTTPSProxy *url.URL
-	HTTPProxy  *url.URL
+	PrefetchImages   []string
+	PrefetchInterval time.Duration
+
+	common.Proxies
+
+	common.Maintenance
+
+	common.ReleaseCheck
 
-	NumCPUs  int
-	MemoryMB int
+	common.AdminAuth
+
+	common.ApplianceResources
 
 	Timeout time.Duration
+	// DockerAPITimeout bounds the wait for the docker API of the VCH once it is deployed, within Timeout.
+	// DockerAPIAttemptTimeout bounds each request made while waiting. Zero disables either.
+	DockerAPITimeout        time.Duration
+	DockerAPIAttemptTimeout time.Duration
 
 	Force bool
 	UseRP bool
 
+	// VMFolder is the VM folder path, relative to the datacenter VM folder, to create the VCH in
+	VMFolder string
+
+	ApplianceHost         string
+	ApplianceHostGroup    string
+	ContainerHostGroup    string
+	HostGroupMandatory    bool
+	ContainerAntiAffinity bool
+
+	// ApplianceHA overrides the vSphere HA settings of the cluster for the appliance
+	ApplianceHA compute.HASettings
+
+	// ContainerCrashLogs keeps the kernel console of containerVMs on the datastore to diagnose panics
+	ContainerCrashLogs bool
+	// ContainerLogConfig is the log driver that the output of containers without one is forwarded to
+	ContainerLogConfig executor.LogConfig
+	// ContainerVMProfile selects the virtual hardware of containerVMs
+	ContainerVMProfile string
+	// ExternalVMs are the inventory paths of existing VMs listed as read-only external containers
+	ExternalVMs []string
+	// SerialConcentrator is the vSPC that containerVM serial ports are proxied through, empty for none
+	SerialConcentrator string
+
+	// Protected is whether the VCH is protected from deletion, nil to leave the protection unchanged
+	Protected *bool
+
+	// AllowFirewall enables the host firewall rulesets needed for serial-over-LAN instead of failing validation
+	AllowFirewall bool
+
 	ScratchSize string
+	// DiskProvisioning is the provisioning of the base image and volume disks, empty for thin
+	DiskProvisioning string
+	// StoragePolicy is the ID of the vSphere storage policy applied to the appliance, containerVMs and their
+	// scratch disks, empty for none
+	StoragePolicy string
 }
 
 // NetworkConfig is used to set IP addr for each network
 type NetworkConfig struct {
-	Name    string
-	Gateway net.IPNet
-	IP      net.IPNet
+	Name        string
+	Gateway     net.IPNet
+	IP          net.IPNet
+	Nameservers []net.IP
+	MTU         int
+
+	// MAC and DHCPClientID identify the VCH to DHCP servers and firewalls across re-creation
+	MAC          string
+	DHCPClientID string
 }
 
 // Empty determines if ip and gateway are unset
@@ -95,6 +160,9 @@ type InstallerData struct {
 	// Virtual Container Host capacity
 	VCHSize config.Resources
 	// Appliance capacity
+	ApplianceCPUs     int32
+	ApplianceMemoryMB int64
+	// Appliance resource allocation
 	ApplianceSize config.Resources
 
 	KeyPEM  string
@@ -111,6 +179,9 @@ type InstallerData struct {
 
 	ImageFiles map[string]string
 
+	// ApplianceOVA is the path of a signed OVA to import the appliance from, which includes the
+	// appliance and bootstrap ISOs, instead of creating the appliance and uploading the ISOs
+	ApplianceOVA      string
 	ApplianceISO      string
 	BootstrapISO      string
 	ISOVersion        string
@@ -120,18 +191,31 @@ type InstallerData struct {
 	Extension types.Extension
 	UseRP     bool
 
+	// ApplianceHost is the inventory path of the host the appliance is created on, if it is not
+	// left to DRS to place
+	ApplianceHost string
+	// ApplianceHostGroup is the DRS host group the appliance is kept on, if any
+	ApplianceHostGroup string
+	// HostGroupMandatory makes the appliance host group rule mandatory rather than preferential
+	HostGroupMandatory bool
+	// Protected is whether the VCH is protected from deletion, nil to leave the protection unchanged
+	Protected *bool
+
+	// AllowFirewall enables the host firewall rulesets needed for serial-over-LAN where they are disabled
+	AllowFirewall bool
+	// ApplianceHA overrides the vSphere HA settings of the cluster for the appliance
+	ApplianceHA compute.HASettings
+
 	HTTPSProxy *url.URL
 	HTTPProxy  *url.URL
+	NoProxy    []string
 }
 
 func NewData() *Data {
 	d := &Data{
-		Target:                 common.NewTarget(),
-		MappedNetworks:         make(map[string]string),
-		MappedNetworksGateways: make(map[string]net.IPNet),
-		MappedNetworksIPRanges: make(map[string][]ip.Range),
-		MappedNetworksDNS:      make(map[string][]net.IP),
-		Timeout:                3 * time.Minute,
+		Target:            common.NewTarget(),
+		ContainerNetworks: common.NewContainerNetworks(),
+		Timeout:           3 * time.Minute,
 	}
 	return d
 }
@@ -27,6 +27,7 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -41,6 +42,7 @@ import (
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/config/executor/validation"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/lib/portlayer/constants"
 	"github.com/vmware/vic/lib/spec"
@@ -48,8 +50,10 @@ import (
 	"github.com/vmware/vic/pkg/ip"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/compute"
+	"github.com/vmware/vic/pkg/vsphere/disk"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig/vmomi"
+	vnetwork "github.com/vmware/vic/pkg/vsphere/network"
 	"github.com/vmware/vic/pkg/vsphere/tasks"
 	"github.com/vmware/vic/pkg/vsphere/vm"
 )
@@ -61,11 +65,6 @@ const (
 	badTLSCertificate = "tls: bad certificate"
 )
 
-var (
-	lastSeenProgressMessage string
-	unitNumber              int32
-)
-
 func (d *Dispatcher) isVCH(vm *vm.VirtualMachine) (bool, error) {
 	if vm == nil {
 		return false, errors.New("nil parameter")
@@ -121,7 +120,7 @@ func (d *Dispatcher) checkExistence(conf *config.VirtualContainerHostConfigSpec,
 	}
 	if vm == nil {
 		if vapp != nil {
-			err = errors.Errorf("virtual app %q is found, but is not VCH, please choose different name", d.vchPoolPath)
+			err = &ConflictError{Message: fmt.Sprintf("virtual app %q is found, but is not VCH, please choose different name", d.vchPoolPath)}
 			log.Error(err)
 			return err
 		}
@@ -130,10 +129,21 @@ func (d *Dispatcher) checkExistence(conf *config.VirtualContainerHostConfigSpec,
 
 	log.Debugf("Appliance is found")
 	if ok, verr := d.isVCH(vm); !ok {
-		verr = errors.Errorf("VM %q is found, but is not VCH appliance, please choose different name", conf.Name)
+		verr = &ConflictError{Message: fmt.Sprintf("VM %q is found, but is not VCH appliance, please choose different name", conf.Name)}
 		return verr
 	}
-	err = errors.Errorf("Appliance %q exists, to install with same name, please delete it first.", conf.Name)
+
+	checkpoint, err := d.readCheckpoint(vm)
+	if err != nil {
+		return err
+	}
+	if checkpoint != "" {
+		d.appliance = vm
+		d.checkpoint = checkpoint
+		return nil
+	}
+
+	err = &ConflictError{Message: fmt.Sprintf("Appliance %q exists, to install with same name, please delete it first.", conf.Name)}
 	return err
 }
 
@@ -212,6 +222,14 @@ func (d *Dispatcher) addNetworkDevices(conf *config.VirtualContainerHostConfigSp
 	slots := make(map[int32]bool)
 	nets := make(map[string]*executor.NetworkEndpoint)
 
+	// a MAC requested by any of the roles sharing a NIC applies to the NIC
+	macs := make(map[string]string)
+	for _, endpoint := range conf.ExecutorConfig.Networks {
+		if endpoint.MAC != "" {
+			macs[endpoint.Network.Common.ID] = endpoint.MAC
+		}
+	}
+
 	for name, endpoint := range conf.ExecutorConfig.Networks {
 		if pnic, ok := nets[endpoint.Network.Common.ID]; ok {
 			// there's already a NIC on this network
@@ -232,6 +250,7 @@ func (d *Dispatcher) addNetworkDevices(conf *config.VirtualContainerHostConfigSp
 		if !ok {
 			return nil, fmt.Errorf("reacquired reference for network %q, from serialized form %q, was not a network: %T", endpoint.Network.Name, endpoint.Network.ID, obj)
 		}
+		network = vnetwork.Reference(network)
 
 		backing, err := network.EthernetCardBackingInfo(d.ctx)
 		if err != nil {
@@ -245,6 +264,13 @@ func (d *Dispatcher) addNetworkDevices(conf *config.VirtualContainerHostConfigSp
 			return nil, err
 		}
 
+		if mac := macs[endpoint.Network.Common.ID]; mac != "" {
+			log.Infof("Using MAC address %s for %q network card", mac, name)
+			card := nic.(types.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+			card.AddressType = string(types.VirtualEthernetCardMacTypeManual)
+			card.MacAddress = mac
+		}
+
 		slot := cspec.AssignSlotNumber(nic, slots)
 		if slot == spec.NilSlot {
 			err = errors.Errorf("Failed to assign stable PCI slot for %q network card", name)
@@ -298,16 +324,17 @@ func (d *Dispatcher) createApplianceSpec(conf *config.VirtualContainerHostConfig
 
 	spec := &spec.VirtualMachineConfigSpec{
 		VirtualMachineConfigSpec: &types.VirtualMachineConfigSpec{
-			Name:     conf.Name,
-			GuestId:  "other3xLinux64Guest",
-			Files:    &types.VirtualMachineFileInfo{VmPathName: fmt.Sprintf("[%s]", conf.ImageStores[0].Host)},
-			NumCPUs:  int32(vConf.ApplianceSize.CPU.Limit),
-			MemoryMB: vConf.ApplianceSize.Memory.Limit,
+			Name:    conf.Name,
+			GuestId: "other3xLinux64Guest",
+			Files:   &types.VirtualMachineFileInfo{VmPathName: fmt.Sprintf("[%s]", conf.ImageStores[0].Host)},
 			// Encode the config both here and after the VMs created so that it can be identified as a VCH appliance as soon as
 			// creation is complete.
 			ExtraConfig: vmomi.OptionValueFromMap(cfg),
+			// the appliance attaches volumes to create them, which needs its home encrypted when theirs are
+			VmProfile: disk.StoragePolicy(conf.StoragePolicy),
 		},
 	}
+	setApplianceSize(spec.VirtualMachineConfigSpec, vConf)
 
 	if devices, err = d.addIDEController(devices); err != nil {
 		return nil, err
@@ -401,43 +428,121 @@ func (d *Dispatcher) configIso(conf *config.VirtualContainerHostConfigSpec, vm *
 	return devices, nil
 }
 
-func (d *Dispatcher) createAppliance(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
-	defer trace.End(trace.Begin(""))
+// setDockerPort sets the docker and vicadmin endpoints for an existing appliance based on whether TLS is configured
+func (d *Dispatcher) setDockerPort(conf *config.VirtualContainerHostConfigSpec) {
+	if !conf.HostCertificate.IsNil() {
+		d.VICAdminProto = "https"
+		d.DockerPort = fmt.Sprintf("%d", opts.DefaultTLSHTTPPort)
+	} else {
+		d.VICAdminProto = "http"
+		d.DockerPort = fmt.Sprintf("%d", opts.DefaultHTTPPort)
+	}
+}
 
-	log.Infof("Creating appliance on target")
+// createApplianceVM creates the appliance VM from a spec, returning its reference
+func (d *Dispatcher) createApplianceVM(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) (*types.ManagedObjectReference, error) {
+	defer trace.End(trace.Begin(""))
 
 	spec, err := d.createApplianceSpec(conf, settings)
 	if err != nil {
 		log.Errorf("Unable to create appliance spec: %s", err)
-		return err
+		return nil, err
+	}
+
+	host, err := d.applianceHost(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// a virtual app determines the folder of its children
+	var folder *object.Folder
+	if !d.isVC || d.vchVapp == nil {
+		if folder, err = d.applianceFolder(conf); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := d.createApplianceVMOnHost(spec, folder, host)
+	if err != nil && host == nil && settings.ApplianceHost == "" {
+		// DRS could not place the appliance, so pick a host ourselves
+		log.Warnf("DRS failed to place appliance VM: %s", err)
+		if host, herr := d.placementHost(); herr == nil {
+			info, err = d.createApplianceVMOnHost(spec, folder, host)
+		} else {
+			log.Errorf("%s", herr)
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	moref := info.Result.(types.ManagedObjectReference)
+	return &moref, nil
+}
+
+// createApplianceVMOnHost creates the appliance VM from a spec on the host, which DRS chooses if nil.
+// The VM is created in folder unless there is a virtual app.
+func (d *Dispatcher) createApplianceVMOnHost(spec *types.VirtualMachineConfigSpec, folder *object.Folder, host *object.HostSystem) (*types.TaskInfo, error) {
 	var info *types.TaskInfo
+	var err error
+
 	// create appliance VM
 	if d.isVC && d.vchVapp != nil {
 		info, err = tasks.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
-			return d.vchVapp.CreateChildVM_Task(ctx, *spec, d.session.Host)
+			return d.vchVapp.CreateChildVM_Task(ctx, *spec, host)
 		})
 	} else {
 		// if vapp is not created, fall back to create VM under default resource pool
-		folder := d.session.Folders(d.ctx).VmFolder
 		info, err = tasks.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
-			return folder.CreateVM(ctx, *spec, d.vchPool, d.session.Host)
+			return folder.CreateVM(ctx, *spec, d.vchPool, host)
 		})
 	}
 
 	if err != nil {
 		log.Errorf("Unable to create appliance VM: %s", err)
-		return err
+		return nil, err
 	}
-	if info.Error != nil || info.State != types.TaskInfoStateSuccess {
-		log.Errorf("Create appliance reported: %s", info.Error.LocalizedMessage)
+	if err = tasks.TaskError(info); err != nil {
+		log.Errorf("Create appliance reported: %s", err)
+		return nil, err
 	}
 
-	// get VM reference and save it
-	moref := info.Result.(types.ManagedObjectReference)
-	conf.SetMoref(&moref)
-	obj, err := d.session.Finder.ObjectReference(d.ctx, moref)
+	return info, nil
+}
+
+// vchExtension returns the vSphere extension the appliance is registered as
+func vchExtension(conf *config.VirtualContainerHostConfigSpec) types.Extension {
+	return types.Extension{
+		Description: &types.Description{
+			Label:   "VIC",
+			Summary: "vSphere Integrated Containers Virtual Container Host",
+		},
+		Company: "VMware, Inc.",
+		Version: "0.0",
+		Key:     conf.ExtensionName,
+	}
+}
+
+func (d *Dispatcher) createAppliance(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+	defer trace.End(trace.Begin(""))
+
+	var moref *types.ManagedObjectReference
+	var err error
+
+	if settings.ApplianceOVA != "" {
+		log.Infof("Importing appliance on target from %q", settings.ApplianceOVA)
+		moref, err = d.importAppliance(conf, settings)
+	} else {
+		log.Infof("Creating appliance on target")
+		moref, err = d.createApplianceVM(conf, settings)
+	}
+	if err != nil {
+		return err
+	}
+
+	// save the VM reference
+	conf.SetMoref(moref)
+	obj, err := d.session.Finder.ObjectReference(d.ctx, *moref)
 	if err != nil {
 		log.Errorf("Failed to reacquire reference to appliance VM after creation: %s", err)
 		return err
@@ -447,6 +552,10 @@ func (d *Dispatcher) createAppliance(conf *config.VirtualContainerHostConfigSpec
 		return fmt.Errorf("Required reference after appliance creation was not for a VM: %T", obj)
 	}
 	vm2 := vm.NewVirtualMachineFromVM(d.ctx, d.session, gvm)
+	// removing the VM also removes its folder, and with it the uploaded images
+	d.undo.push(fmt.Sprintf("appliance VM %q", conf.Name), func() error {
+		return d.deleteVM(vm2, true)
+	})
 
 	// update the displayname to the actual folder name used
 	if d.vmPathName, err = vm2.FolderName(d.ctx); err != nil {
@@ -461,16 +570,44 @@ func (d *Dispatcher) createAppliance(conf *config.VirtualContainerHostConfigSpec
 		return errors.Errorf("Could not generate extension name during appliance creation due to error: %s", err)
 	}
 
-	settings.Extension = types.Extension{
-		Description: &types.Description{
-			Label:   "VIC",
-			Summary: "vSphere Integrated Containers Virtual Container Host",
-		},
-		Company: "VMware, Inc.",
-		Version: "0.0",
-		Key:     conf.ExtensionName,
+	settings.Extension = vchExtension(conf)
+	d.addApplianceComponents(conf, settings)
+
+	if settings.ApplianceOVA != "" {
+		// the bootstrap image is imported with the appliance rather than uploaded separately
+		if conf.BootstrapImagePath, err = d.importedBootstrapImage(vm2, settings); err != nil {
+			return err
+		}
+	} else {
+		conf.BootstrapImagePath = fmt.Sprintf("[%s] %s/%s", conf.ImageStores[0].Host, d.vmPathName, settings.BootstrapISO)
+	}
+
+	spec, err := d.reconfigureApplianceSpec(vm2, conf, settings)
+	if err != nil {
+		log.Errorf("Error while getting appliance reconfig spec: %s", err)
+		return err
+	}
+
+	// reconfig
+	info, err := vm2.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+		return vm2.Reconfigure(ctx, *spec)
+	})
+
+	if err != nil {
+		log.Errorf("Error while setting component parameters to appliance: %s", err)
+		return err
+	}
+	if err = tasks.TaskError(info); err != nil {
+		log.Errorf("Setting parameters to appliance reported: %s", err)
+		return err
 	}
 
+	d.appliance = vm2
+	return nil
+}
+
+// addApplianceComponents adds the components the appliance runs to conf
+func (d *Dispatcher) addApplianceComponents(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) {
 	conf.AddComponent("vicadmin", &executor.SessionConfig{
 		User:  "vicadmin",
 		Group: "vicadmin",
@@ -519,6 +656,9 @@ func (d *Dispatcher) createAppliance(conf *config.VirtualContainerHostConfigSpec
 	if settings.HTTPSProxy != nil {
 		personality.Env = append(personality.Env, fmt.Sprintf("HTTPS_PROXY=%s", settings.HTTPSProxy.String()))
 	}
+	if len(settings.NoProxy) > 0 {
+		personality.Env = append(personality.Env, fmt.Sprintf("NO_PROXY=%s", strings.Join(settings.NoProxy, ",")))
+	}
 
 	conf.AddComponent("docker-personality", &executor.SessionConfig{
 		// currently needed for iptables interaction
@@ -549,34 +689,13 @@ func (d *Dispatcher) createAppliance(conf *config.VirtualContainerHostConfigSpec
 		Restart: true,
 	},
 	)
-
-	conf.BootstrapImagePath = fmt.Sprintf("[%s] %s/%s", conf.ImageStores[0].Host, d.vmPathName, settings.BootstrapISO)
-
-	spec, err = d.reconfigureApplianceSpec(vm2, conf, settings)
-	if err != nil {
-		log.Errorf("Error while getting appliance reconfig spec: %s", err)
-		return err
-	}
-
-	// reconfig
-	info, err = vm2.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
-		return vm2.Reconfigure(ctx, *spec)
-	})
-
-	if err != nil {
-		log.Errorf("Error while setting component parameters to appliance: %s", err)
-		return err
-	}
-	if info.State != types.TaskInfoStateSuccess {
-		log.Errorf("Setting parameters to appliance reported: %s", info.Error.LocalizedMessage)
-		return err
-	}
-
-	d.appliance = vm2
-	return nil
 }
 
 func (d *Dispatcher) encodeConfig(conf *config.VirtualContainerHostConfigSpec) (map[string]string, error) {
+	if err := validation.Executor(&conf.ExecutorConfig); err != nil {
+		return nil, err
+	}
+
 	if d.secret == nil {
 		log.Debug("generating new config secret key")
 
@@ -606,10 +725,16 @@ func (d *Dispatcher) reconfigureApplianceSpec(vm *vm.VirtualMachine, conf *confi
 		Files:   &types.VirtualMachineFileInfo{VmPathName: fmt.Sprintf("[%s]", conf.ImageStores[0].Host)},
 	}
 
-	if devices, err = d.configIso(conf, vm, settings); err != nil {
-		return nil, err
+	// an imported appliance already has its ISO attached
+	if settings.ApplianceOVA == "" {
+		if devices, err = d.configIso(conf, vm, settings); err != nil {
+			return nil, err
+		}
 	}
 
+	// the kernel console is kept on the datastore so that a panic can be reported by inspect
+	devices = append(devices, d.crashLogSerialPort(conf))
+
 	deviceChange, err := devices.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
 	if err != nil {
 		log.Errorf("Failed to create config spec for appliance: %s", err)
@@ -624,6 +749,8 @@ func (d *Dispatcher) reconfigureApplianceSpec(vm *vm.VirtualMachine, conf *confi
 	}
 
 	spec.ExtraConfig = append(spec.ExtraConfig, vmomi.OptionValueFromMap(cfg)...)
+	// needed to avoid the question that occurs when opening the existing crash log on power on
+	spec.ExtraConfig = append(spec.ExtraConfig, &types.OptionValue{Key: "answer.msg.serial.file.open", Value: "Append"})
 	return spec, nil
 }
 
@@ -680,7 +807,6 @@ func (d *Dispatcher) CheckDockerAPI(conf *config.VirtualContainerHostConfigSpec,
 	var (
 		proto          string
 		client         *http.Client
-		res            *http.Response
 		err            error
 		req            *http.Request
 		tlsErrExpected bool
@@ -757,24 +883,50 @@ func (d *Dispatcher) CheckDockerAPI(conf *config.VirtualContainerHostConfigSpec,
 		client = &http.Client{Transport: tr}
 	}
 
-	dockerInfoURL := fmt.Sprintf("%s://%s:%s/info", proto, d.HostIP, d.DockerPort)
+	// bracket IPv6 literals in the URL
+	dockerInfoURL := fmt.Sprintf("%s://%s/info", proto, net.JoinHostPort(d.HostIP, d.DockerPort))
 	req, err = http.NewRequest("GET", dockerInfoURL, nil)
 	if err != nil {
 		return errors.New("invalid HTTP request for docker info")
 	}
-	req = req.WithContext(d.ctx)
+
+	ctx := d.ctx
+	if d.DockerAPITimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(d.ctx, d.DockerAPITimeout)
+		defer cancel()
+	}
+
+	apiErr := &DockerAPIError{}
 
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 	for {
-		res, err = client.Do(req)
-		if err == nil && res.StatusCode == http.StatusOK {
-			if isPortLayerRunning(res) {
-				break
-			}
+		apiErr.Attempts++
+
+		reachable, err := d.checkDockerInfo(ctx, client, req)
+		if err == nil {
+			break
 		}
 
-		if err != nil {
+		if ctx.Err() != nil {
+			// the attempt was interrupted by the overall deadline or cancellation, not a failure of its own
+			apiErr.Err = ctx.Err()
+			log.Errorf("%s", apiErr)
+			return apiErr
+		}
+
+		if tlsErrExpected && isTLSError(err) {
+			// the endpoint cannot be verified without a client certificate, but refusing the
+			// handshake shows the API server is up
+			log.Infof("Docker API endpoint is up but could not be verified without a client certificate: %s", err)
+			return nil
+		}
+
+		apiErr.Reachable = apiErr.Reachable || reachable
+		apiErr.addError(err)
+
+		if !reachable {
 			// DEBU[2016-10-11T22:22:38Z] Error recieved from endpoint: Get https://192.168.78.127:2376/info: dial tcp 192.168.78.127:2376: getsockopt: connection refused &{%!t(string=Get) %!t(string=https://192.168.78.127:2376/info) %!t(*net.OpError=&{dial tcp <nil> 0xc4204505a0 0xc4203a5e00})}
 			// DEBU[2016-10-11T22:22:39Z] Components not yet initialized, retrying
 			// ERR=&url.Error{
@@ -842,16 +994,116 @@ func (d *Dispatcher) CheckDockerAPI(conf *config.VirtualContainerHostConfigSpec,
 
 		select {
 		case <-ticker.C:
-		case <-d.ctx.Done():
-			return d.ctx.Err()
+		case <-ctx.Done():
+			apiErr.Err = ctx.Err()
+			log.Errorf("%s", apiErr)
+			return apiErr
 		}
 
-		log.Debug("Components not yet initialized, retrying")
+		log.Debugf("Components not yet initialized, retrying: %s", err)
 	}
 
 	return nil
 }
 
+// checkDockerInfo issues a single `docker info` request, bounded by DockerAPIAttemptTimeout if set.
+// reachable reports whether the endpoint responded at all, allowing callers to distinguish an
+// endpoint that cannot be contacted from one whose components are still initializing.
+func (d *Dispatcher) checkDockerInfo(ctx context.Context, client *http.Client, req *http.Request) (reachable bool, err error) {
+	if d.DockerAPIAttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.DockerAPIAttemptTimeout)
+		defer cancel()
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return true, fmt.Errorf("docker info returned %q", res.Status)
+	}
+
+	if !isPortLayerRunning(res) {
+		return true, errors.New("port layer is not yet running")
+	}
+
+	return true, nil
+}
+
+// isTLSError returns true if err shows the endpoint took part in a TLS handshake that was refused
+// by either side, as opposed to it not listening or not yet serving requests
+func isTLSError(err error) bool {
+	uerr, ok := err.(*url.Error)
+	if !ok {
+		return false
+	}
+
+	switch root := uerr.Err.(type) {
+	case *net.OpError:
+		return root.Op == "remote error"
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError, *tls.CertificateVerificationError:
+		return true
+	}
+	return false
+}
+
+// DockerAPIError is returned by CheckDockerAPI when the docker endpoint on the appliance
+// could not be verified before the check was cancelled or timed out.
+type DockerAPIError struct {
+	// Attempts is the number of requests made to the endpoint
+	Attempts int
+	// Reachable is true if the endpoint responded to at least one request
+	Reachable bool
+	// Errors holds the most recent distinct errors, oldest first
+	Errors []error
+	// Err is the context error that ended the check
+	Err error
+}
+
+// maxDockerAPIErrors is the number of distinct errors retained by DockerAPIError
+const maxDockerAPIErrors = 3
+
+func (e *DockerAPIError) addError(err error) {
+	for _, seen := range e.Errors {
+		if seen.Error() == err.Error() {
+			return
+		}
+	}
+
+	e.Errors = append(e.Errors, err)
+	if len(e.Errors) > maxDockerAPIErrors {
+		e.Errors = e.Errors[len(e.Errors)-maxDockerAPIErrors:]
+	}
+}
+
+// Timeout returns true if the check ended because its deadline was exceeded rather than
+// because it was cancelled.
+func (e *DockerAPIError) Timeout() bool {
+	return e.Err == context.DeadlineExceeded
+}
+
+func (e *DockerAPIError) Error() string {
+	reason := "docker API endpoint was never reachable"
+	if e.Reachable {
+		reason = "docker API endpoint was reachable but appliance components did not initialize"
+	}
+
+	ended := "cancelled"
+	if e.Timeout() {
+		ended = "timed out"
+	}
+
+	msg := fmt.Sprintf("%s: check %s after %d attempt(s)", reason, ended, e.Attempts)
+	for _, err := range e.Errors {
+		msg = fmt.Sprintf("%s\n  %s", msg, err)
+	}
+
+	return msg
+}
+
 // ensureApplianceInitializes checks if the appliance component processes are launched correctly
 func (d *Dispatcher) ensureApplianceInitializes(conf *config.VirtualContainerHostConfigSpec) error {
 	defer trace.End(trace.Begin(""))
@@ -864,14 +1116,11 @@ func (d *Dispatcher) ensureApplianceInitializes(conf *config.VirtualContainerHos
 	d.waitForKey("guestinfo.vice..init.networks|client.assigned.IP")
 	ctxerr := d.ctx.Err()
 
+	var failed []componentResult
 	if ctxerr == nil {
 		log.Info("Waiting for major appliance components to launch")
-		log.Debug("waiting for vicadmin to start")
-		d.waitForKey("guestinfo.vice..init.sessions|vicadmin.started")
-		log.Debug("waiting for docker personality to start")
-		d.waitForKey("guestinfo.vice..init.sessions|docker-personality.started")
-		log.Debug("waiting for port layer to start")
-		d.waitForKey("guestinfo.vice..init.sessions|port-layer.started")
+		results := waitForComponents(d.ctx, ApplianceComponents, d.ComponentTimeouts, d.appliance.WaitForKeyInExtraConfig)
+		failed = failedComponents(results)
 	}
 
 	// at this point either everything has succeeded or we're going into diagnostics, ignore error
@@ -880,7 +1129,7 @@ func (d *Dispatcher) ensureApplianceInitializes(conf *config.VirtualContainerHos
 
 	// TODO: we should call to the general vic-machine inspect implementation here for more detail
 	// but instead...
-	if !ip.IsUnspecifiedIP(conf.ExecutorConfig.Networks["client"].Assigned.IP) {
+	if len(failed) == 0 && !ip.IsUnspecifiedIP(conf.ExecutorConfig.Networks["client"].Assigned.IP) {
 		d.HostIP = conf.ExecutorConfig.Networks["client"].Assigned.IP.String()
 		log.Debugf("Obtained IP address for client interface: %q", d.HostIP)
 		return nil
@@ -898,6 +1147,25 @@ func (d *Dispatcher) ensureApplianceInitializes(conf *config.VirtualContainerHos
 		return fmt.Errorf("unable to retrieve updated configuration from appliance for diagnostics: %s", err)
 	}
 
+	// if cancelled the component failures don't need reporting
+	if len(failed) > 0 && ctxerr != context.Canceled {
+		// the appliance holds back the components if it found a problem with the configuration when it booted
+		if conf.Validation.Failed() {
+			log.Errorf("Appliance configuration is invalid: %s", conf.Validation.Message())
+			return fmt.Errorf("appliance configuration is invalid: %s", conf.Validation.Message())
+		}
+
+		log.Info("Appliance components failed to launch:")
+		for _, r := range failed {
+			status := "unknown"
+			if session, ok := conf.ExecutorConfig.Sessions[r.name]; ok {
+				status = sessionStatus(session)
+			}
+			log.Infof("    %q: %s (status: %q)", r.name, r.err, status)
+		}
+		return fmt.Errorf("%d appliance component(s) failed to launch", len(failed))
+	}
+
 	if ctxerr == context.DeadlineExceeded {
 		log.Info("Failed to retrieve IP for client interface")
 		log.Info("  State of all interfaces:")
@@ -914,13 +1182,7 @@ func (d *Dispatcher) ensureApplianceInitializes(conf *config.VirtualContainerHos
 		// if we timed out, then report status - if cancelled this doesn't need reporting
 		log.Info("  State of components:")
 		for name, session := range conf.ExecutorConfig.Sessions {
-			status := "waiting to launch"
-			if session.Started == "true" {
-				status = "started successfully"
-			} else if session.Started != "" {
-				status = session.Started
-			}
-			log.Infof("    %q: %q", name, status)
+			log.Infof("    %q: %q", name, sessionStatus(session))
 		}
 
 		return errors.New("timed out waiting for IP address information from appliance")
@@ -0,0 +1,134 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/certificate"
+)
+
+func testDispatcher(t *testing.T, url string) *Dispatcher {
+	host, port, err := net.SplitHostPort(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Dispatcher{
+		ctx:                     context.Background(),
+		HostIP:                  host,
+		DockerPort:              port,
+		DockerAPITimeout:        2 * time.Second,
+		DockerAPIAttemptTimeout: 500 * time.Millisecond,
+	}
+}
+
+func TestCheckDockerAPIReachable(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Driver": "vSphere", "SystemStatus": [["vSphere", "STOPPED"]]}`)
+	}))
+	defer s.Close()
+
+	d := testDispatcher(t, s.Listener.Addr().String())
+	err := d.CheckDockerAPI(&config.VirtualContainerHostConfigSpec{}, nil)
+
+	apiErr, ok := err.(*DockerAPIError)
+	if !assert.True(t, ok, "expected DockerAPIError, got %#v", err) {
+		return
+	}
+	assert.True(t, apiErr.Reachable)
+	assert.True(t, apiErr.Timeout())
+	assert.True(t, apiErr.Attempts > 1)
+	assert.Len(t, apiErr.Errors, 1)
+}
+
+func TestCheckDockerAPINeverReachable(t *testing.T) {
+	// reserve an address and close it so nothing is listening
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	d := testDispatcher(t, addr)
+	ctx, cancel := context.WithCancel(context.Background())
+	d.ctx = ctx
+	time.AfterFunc(1500*time.Millisecond, cancel)
+
+	err = d.CheckDockerAPI(&config.VirtualContainerHostConfigSpec{}, nil)
+
+	apiErr, ok := err.(*DockerAPIError)
+	if !assert.True(t, ok, "expected DockerAPIError, got %#v", err) {
+		return
+	}
+	assert.False(t, apiErr.Reachable)
+	assert.False(t, apiErr.Timeout())
+	assert.Equal(t, context.Canceled, apiErr.Err)
+}
+
+func TestCheckDockerAPITLSVerify(t *testing.T) {
+	cacert, cakey, err := certificate.CreateRootCA("somewhere.com", []string{"MyOrg"}, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := certificate.CreateServerCertificate("somewhere.com", []string{"MyOrg"}, 1024, cacert.Bytes(), cakey.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(cacert.Bytes())
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request served without a client certificate")
+	}))
+	s.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	s.StartTLS()
+	defer s.Close()
+
+	conf := &config.VirtualContainerHostConfigSpec{}
+	conf.HostCertificate = &config.RawCertificate{Cert: cert.Bytes(), Key: key.Bytes()}
+	conf.CertificateAuthorities = cacert.Bytes()
+
+	// the handshake is refused without a client certificate, which still shows the endpoint is up
+	d := testDispatcher(t, s.Listener.Addr().String())
+	assert.NoError(t, d.CheckDockerAPI(conf, nil))
+}
+
+func TestDockerAPIErrorDistinct(t *testing.T) {
+	e := &DockerAPIError{}
+	for i := 0; i < 5; i++ {
+		e.addError(fmt.Errorf("error %d", i))
+		e.addError(fmt.Errorf("error %d", i))
+	}
+
+	assert.Equal(t, []error{errors.New("error 2"), errors.New("error 3"), errors.New("error 4")}, e.Errors)
+}
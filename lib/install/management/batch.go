@@ -0,0 +1,121 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"crypto/tls"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// BatchVCH is a VCH to create as part of a batch
+type BatchVCH struct {
+	Conf     *config.VirtualContainerHostConfigSpec
+	Settings *data.InstallerData
+
+	// ClientCert is used to check the docker API of the VCH once created, and may be nil
+	ClientCert *tls.Certificate
+}
+
+// BatchResult is the outcome of creating a VCH of a batch
+type BatchResult struct {
+	Name string
+	Err  error
+
+	// Dispatcher is the dispatcher that created the VCH, holding the addresses it is reached at
+	Dispatcher *Dispatcher
+}
+
+// CreateVCHs creates the VCHs concurrently, at most workers at a time, and returns the outcome
+// for each in the order given. The VCHs are created over the session of d, but each by its own
+// dispatcher as creation records per-VCH state on the dispatcher and session. VCHs not started
+// before the dispatcher context is done fail with the context error.
+func (d *Dispatcher) CreateVCHs(vchs []*BatchVCH, workers int) []BatchResult {
+	defer trace.End(trace.Begin(""))
+
+	log.Infof("Creating %d VCHs, %d at a time", len(vchs), workers)
+
+	results := d.runBatch(len(vchs), workers, func(i int) BatchResult {
+		vch := vchs[i]
+		res := BatchResult{
+			Name:       vch.Conf.Name,
+			Dispatcher: d.WithContext(d.ctx),
+		}
+
+		log.Infof("Creating VCH %q (%d of %d)", vch.Conf.Name, i+1, len(vchs))
+		if res.Err = res.Dispatcher.CreateVCH(vch.Conf, vch.Settings); res.Err == nil {
+			res.Err = res.Dispatcher.CheckDockerAPI(vch.Conf, vch.ClientCert)
+		}
+
+		if res.Err != nil {
+			log.Errorf("Creating VCH %q failed: %s", vch.Conf.Name, res.Err)
+		} else {
+			log.Infof("Created VCH %q", vch.Conf.Name)
+		}
+		return res
+	})
+
+	for i := range results {
+		if results[i].Name == "" {
+			results[i].Name = vchs[i].Conf.Name
+		}
+	}
+	return results
+}
+
+// runBatch calls create for the indices 0 to n-1 from at most workers goroutines, returning the
+// results by index. Indices not yet started when the dispatcher context is done are skipped and
+// their result holds the context error.
+func (d *Dispatcher) runBatch(n, workers int, create func(i int) BatchResult) []BatchResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]BatchResult, n)
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = create(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if err := d.ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		select {
+		case next <- i:
+		case <-d.ctx.Done():
+			results[i].Err = d.ctx.Err()
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/vic/pkg/vsphere/session"
+
+	"golang.org/x/net/context"
+)
+
+func TestRunBatch(t *testing.T) {
+	d := &Dispatcher{ctx: context.Background()}
+
+	var running, peak int32
+	results := d.runBatch(10, 3, func(i int) BatchResult {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		return BatchResult{Name: fmt.Sprintf("vch-%d", i)}
+	})
+
+	assert.True(t, peak <= 3, "expected at most 3 concurrent creations, saw %d", peak)
+	for i, r := range results {
+		assert.Equal(t, fmt.Sprintf("vch-%d", i), r.Name)
+		assert.NoError(t, r.Err)
+	}
+}
+
+func TestRunBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := &Dispatcher{ctx: ctx}
+
+	results := d.runBatch(3, 2, func(i int) BatchResult {
+		t.Errorf("unexpected creation of %d after cancellation", i)
+		return BatchResult{}
+	})
+
+	for _, r := range results {
+		assert.Equal(t, context.Canceled, r.Err)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	s := session.NewSession(&session.Config{DatastorePath: "LocalDS_0"})
+	s.Finder = &find.Finder{}
+	d := &Dispatcher{
+		session:           s,
+		ctx:               context.Background(),
+		force:             true,
+		HostIP:            "10.0.0.1",
+		ComponentTimeouts: map[string]time.Duration{"port-layer": time.Minute},
+		diagnosticLogs:    map[string]*diagnosticLog{"vpxd": {}},
+		Progress:          func(ProgressEvent) {},
+		progress:          progress{operation: OperationCreate},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := d.WithContext(ctx)
+	f.session.DatastorePath = "LocalDS_1"
+
+	assert.Equal(t, ctx, f.ctx)
+	assert.Equal(t, "LocalDS_0", s.DatastorePath, "the session configuration must not be shared")
+	assert.False(t, s.Finder == f.session.Finder, "the finder must not be shared")
+	assert.True(t, f.force)
+	assert.Equal(t, d.ComponentTimeouts, f.ComponentTimeouts)
+	assert.NotNil(t, f.Progress)
+
+	// state of operations of d does not carry over
+	assert.Empty(t, f.HostIP)
+	assert.Empty(t, f.diagnosticLogs)
+	assert.Empty(t, f.progress.operation)
+}
@@ -0,0 +1,84 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// UpdateBootstrapImage uploads a new bootstrap image to the appliance folder and reconfigures the VCH to use it
+// for containerVMs, leaving the appliance image unchanged. Existing containerVMs switch to the new image when they
+// are next started. The previous image is left in place, as running containerVMs still use it.
+func (d *Dispatcher) UpdateBootstrapImage(vch *vm.VirtualMachine, current, requested *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+	defer trace.End(trace.Begin(requested.Name))
+
+	d.appliance = vch
+
+	var err error
+	if d.vmPathName, err = d.appliance.FolderName(d.ctx); err != nil {
+		log.Errorf("Failed to get canonical name for appliance: %s", err)
+		return err
+	}
+
+	ds, err := d.session.Finder.Datastore(d.ctx, requested.ImageStores[0].Host)
+	if err != nil {
+		return errors.Errorf("Failed to find image datastore %q", requested.ImageStores[0].Host)
+	}
+	d.session.Datastore = ds
+
+	image, ok := settings.ImageFiles[settings.BootstrapISO]
+	if !ok {
+		return errors.Errorf("No bootstrap image file given for %q", settings.BootstrapISO)
+	}
+
+	sum, size, err := fileChecksum(image)
+	if err != nil {
+		return err
+	}
+
+	folder := fmt.Sprintf("[%s] %s/", requested.ImageStores[0].Host, d.vmPathName)
+	if strings.HasPrefix(current.BootstrapImagePath, folder) {
+		inUse := strings.TrimPrefix(current.BootstrapImagePath, folder)
+		if d.imageUploaded(path.Join(d.vmPathName, inUse), sum, size) {
+			log.Infof("Bootstrap image %q is already in use, nothing to update", image)
+			return nil
+		}
+	}
+
+	// the image in use cannot be overwritten while containerVMs are running, and the version of a rebuilt image
+	// may be unchanged, so the name is made unique with the checksum
+	name := fmt.Sprintf("%s-%s", sum[:12], settings.BootstrapISO)
+
+	log.Infof("Uploading bootstrap image")
+	log.Infof("\t%q", image)
+	if err = d.uploadImage(image, name); err != nil {
+		return errors.Errorf("Uploading bootstrap image failed with %s. Exiting...", err)
+	}
+
+	requested.BootstrapImagePath = folder + name
+	log.Infof("Bootstrap image for containerVMs changed from %q to %q", current.BootstrapImagePath, requested.BootstrapImagePath)
+
+	return d.Reconfigure(vch, current, requested, settings)
+}
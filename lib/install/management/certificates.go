@@ -0,0 +1,185 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/certificate"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/ip"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// certificateComponents are the appliance components that serve TLS with the host certificate
+var certificateComponents = []string{"docker-personality", "vicadmin"}
+
+// CertificateRotation is the TLS configuration that replaces that of an existing VCH
+type CertificateRotation struct {
+	// HostCertificate is the new server certificate and key. If nil the current certificate is
+	// regenerated, which is only possible if it is self-signed.
+	HostCertificate *certificate.KeyPair
+	// CertificateAuthorities, if not nil, replaces the CAs used to verify clients. An empty
+	// value disables client verification.
+	CertificateAuthorities []byte
+	// ClientCertificate is presented when checking the docker endpoint once the new certificates
+	// are in use, and is needed if the VCH verifies clients
+	ClientCertificate *tls.Certificate
+}
+
+// RotateCertificates replaces the host certificate and certificate authorities of a VCH without
+// redeploying it. The certificates are written to the appliance configuration and, if the appliance is
+// running, the components serving TLS are restarted to load them and the docker endpoint is checked
+// with the new certificates. The previous certificates are restored if the check fails.
+func (d *Dispatcher) RotateCertificates(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec, rotation *CertificateRotation) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	op, err := trace.FromContext(d.ctx)
+	if err != nil {
+		op = trace.NewOperation(d.ctx, "rotate appliance certificates")
+	}
+
+	current := *conf
+	requested := *conf
+	if err = rotateCertificates(&requested, rotation); err != nil {
+		op.Errorf("Unable to rotate certificates: %s", err)
+		return err
+	}
+
+	d.appliance = vch
+
+	log.Infof("Updating certificates in the appliance configuration")
+	if err = d.setCertificates(op, vch, &current, &requested); err != nil {
+		op.Errorf("Unable to update certificates in the VCH appliance configuration: %s", err)
+		return err
+	}
+
+	state, err := vch.PowerState(op)
+	if err != nil {
+		log.Errorf("Failed to get appliance power state, service might not be available at this moment.")
+	}
+	if state != types.VirtualMachinePowerStatePoweredOn {
+		log.Infof("Appliance is not powered on, the new certificates are used once it starts")
+		*conf = requested
+		return nil
+	}
+
+	if err = d.reloadCertificates(op, vch, &requested, rotation.ClientCertificate); err == nil {
+		log.Infof("Certificates rotated successfully")
+		*conf = requested
+		return nil
+	}
+	op.Errorf("Failed to rotate certificates: %s", err)
+	log.Infof("Restoring previous certificates")
+
+	if rerr := d.setCertificates(op, vch, &requested, &current); rerr != nil {
+		log.Errorf("Failed to restore previous certificates: %s", rerr)
+		return err
+	}
+	if rerr := d.runApplianceCommand(op, vch, "restart-components", strings.Join(certificateComponents, " ")); rerr != nil {
+		log.Errorf("Failed to restart appliance components with previous certificates: %s", rerr)
+		return err
+	}
+
+	log.Infof("Appliance is restored to the previous certificates")
+	return err
+}
+
+// rotateCertificates applies the rotation to the TLS configuration in conf, regenerating the host
+// certificate if no replacement is supplied
+func rotateCertificates(conf *config.VirtualContainerHostConfigSpec, rotation *CertificateRotation) error {
+	if conf.HostCertificate.IsNil() {
+		return errors.New("VCH was created without TLS, there are no certificates to rotate")
+	}
+
+	kp := rotation.HostCertificate
+	if kp == nil {
+		log.Infof("Regenerating self-signed host certificate")
+		kp = certificate.NewKeyPair("", "", conf.HostCertificate.Cert, conf.HostCertificate.Key)
+		if err := kp.RenewSelfSigned(); err != nil {
+			return errors.Errorf("Unable to regenerate host certificate: %s", err)
+		}
+	}
+
+	if _, err := kp.Certificate(); err != nil {
+		return errors.Errorf("Invalid host certificate: %s", err)
+	}
+	conf.HostCertificate = &config.RawCertificate{
+		Key:  kp.KeyPEM,
+		Cert: kp.CertPEM,
+	}
+
+	if rotation.CertificateAuthorities == nil {
+		return nil
+	}
+
+	if len(rotation.CertificateAuthorities) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(rotation.CertificateAuthorities) {
+			return errors.New("Unable to load certificate authority data")
+		}
+	} else {
+		log.Warnf("Removing certificate authorities - client authentication disabled")
+	}
+	conf.CertificateAuthorities = rotation.CertificateAuthorities
+
+	return nil
+}
+
+// setCertificates writes the change of configuration from current to requested to the appliance,
+// without restarting it
+func (d *Dispatcher) setCertificates(ctx context.Context, vch *vm.VirtualMachine, current, requested *config.VirtualContainerHostConfigSpec) error {
+	delta := configDelta(current, requested)
+	if len(delta) == 0 {
+		return nil
+	}
+
+	info, err := vch.WaitForResult(ctx, func(ctx context.Context) (tasks.Task, error) {
+		return vch.Reconfigure(ctx, types.VirtualMachineConfigSpec{ExtraConfig: delta})
+	})
+	if err != nil {
+		return err
+	}
+
+	return tasks.TaskError(info)
+}
+
+// reloadCertificates restarts the appliance components serving TLS so that they load the certificates
+// in conf, and checks the docker endpoint with them
+func (d *Dispatcher) reloadCertificates(op trace.Operation, vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec, clientCert *tls.Certificate) error {
+	client := conf.ExecutorConfig.Networks["client"]
+	if client == nil || ip.IsUnspecifiedIP(client.Assigned.IP) {
+		return errors.New("No client IP address assigned, unable to check the docker endpoint")
+	}
+
+	log.Infof("Restarting appliance components to load the new certificates")
+	if err := d.runApplianceCommand(op, vch, "restart-components", strings.Join(certificateComponents, " ")); err != nil {
+		return err
+	}
+
+	d.setHostAddress(conf)
+
+	log.Infof("Checking docker endpoint with the new certificates")
+	return d.CheckDockerAPI(conf, clientCert)
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/certificate"
+)
+
+func testTLSConfig(t *testing.T) *config.VirtualContainerHostConfigSpec {
+	kp := certificate.NewKeyPair("", "", nil, nil)
+	require.NoError(t, kp.CreateSelfSigned("vch.example.com", []string{"vch"}, 1024))
+
+	conf := testConfig()
+	conf.HostCertificate = &config.RawCertificate{Key: kp.KeyPEM, Cert: kp.CertPEM}
+	return conf
+}
+
+func TestRotateCertificatesNoTLS(t *testing.T) {
+	assert.Error(t, rotateCertificates(testConfig(), &CertificateRotation{}))
+}
+
+func TestRotateCertificatesRegenerate(t *testing.T) {
+	current := testTLSConfig(t)
+	requested := *current
+
+	require.NoError(t, rotateCertificates(&requested, &CertificateRotation{}))
+	assert.NotEqual(t, current.HostCertificate.Cert, requested.HostCertificate.Cert)
+	assert.Nil(t, requested.CertificateAuthorities)
+
+	cert, err := requested.HostCertificate.X509Certificate()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vch.example.com"}, cert.DNSNames)
+
+	delta := configDelta(current, &requested)
+	if assert.NotEmpty(t, delta) {
+		for _, o := range delta {
+			assert.Contains(t, o.GetOptionValue().Key, "cert")
+		}
+	}
+}
+
+func TestRotateCertificatesSupplied(t *testing.T) {
+	ca := certificate.NewKeyPair("", "", nil, nil)
+	require.NoError(t, ca.CreateRootCA("vch.example.com", []string{"vch"}, 1024))
+
+	server := certificate.NewKeyPair("", "", nil, nil)
+	require.NoError(t, server.CreateServerCertificate("vch.example.com", []string{"vch"}, 1024, ca))
+
+	conf := testTLSConfig(t)
+	rotation := &CertificateRotation{
+		HostCertificate:        server,
+		CertificateAuthorities: ca.CertPEM,
+	}
+	require.NoError(t, rotateCertificates(conf, rotation))
+	assert.Equal(t, server.CertPEM, conf.HostCertificate.Cert)
+	assert.Equal(t, server.KeyPEM, conf.HostCertificate.Key)
+	assert.Equal(t, ca.CertPEM, conf.CertificateAuthorities)
+
+	// a CA signed certificate cannot be regenerated
+	assert.Error(t, rotateCertificates(conf, &CertificateRotation{}))
+
+	// an empty set of CAs disables client verification
+	require.NoError(t, rotateCertificates(conf, &CertificateRotation{HostCertificate: server, CertificateAuthorities: []byte{}}))
+	assert.Empty(t, conf.CertificateAuthorities)
+}
+
+func TestRotateCertificatesInvalid(t *testing.T) {
+	conf := testTLSConfig(t)
+
+	assert.Error(t, rotateCertificates(conf, &CertificateRotation{HostCertificate: certificate.NewKeyPair("", "", []byte("cert"), []byte("key"))}))
+	assert.Error(t, rotateCertificates(conf, &CertificateRotation{CertificateAuthorities: []byte("not a certificate")}))
+}
@@ -0,0 +1,187 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// checkpointKey is the appliance extraconfig key recording the last creation step completed once
+// the appliance VM exists. It is removed when creation completes, so its presence marks a VCH whose
+// creation can be resumed by running create again with the same name.
+const checkpointKey = "vic.create.checkpoint"
+
+// The creation steps that follow creation of the appliance VM, in order
+const (
+	stepAppliance = "appliance"
+	stepRules     = "rules"
+	stepImages    = "images"
+	stepExtension = "extension"
+)
+
+var createSteps = []string{stepAppliance, stepRules, stepImages, stepExtension}
+
+// stepDone returns whether the step was completed according to the checkpoint
+func stepDone(checkpoint, step string) bool {
+	c := stepIndex(checkpoint)
+	return c >= 0 && stepIndex(step) <= c
+}
+
+func stepIndex(step string) int {
+	for i, s := range createSteps {
+		if s == step {
+			return i
+		}
+	}
+	return -1
+}
+
+// readCheckpoint returns the checkpoint of the appliance, or "" if its creation completed
+func (d *Dispatcher) readCheckpoint(vch *vm.VirtualMachine) (string, error) {
+	info, err := vch.FetchExtraConfig(d.ctx)
+	if err != nil {
+		return "", err
+	}
+	return info[checkpointKey], nil
+}
+
+// setCheckpoint records the step as completed, or removes the checkpoint if step is ""
+func (d *Dispatcher) setCheckpoint(step string) error {
+	defer trace.End(trace.Begin(step))
+
+	// an empty value removes the key
+	spec := types.VirtualMachineConfigSpec{
+		ExtraConfig: []types.BaseOptionValue{&types.OptionValue{Key: checkpointKey, Value: step}},
+	}
+
+	info, err := d.appliance.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+		return d.appliance.Reconfigure(ctx, spec)
+	})
+	if err != nil {
+		return err
+	}
+	if err = tasks.TaskError(info); err != nil {
+		return err
+	}
+
+	d.checkpoint = step
+	return nil
+}
+
+// resumeVCH picks up the creation of a VCH from its checkpoint. The configuration stored in the
+// appliance is used, replacing conf, as the steps already completed depend on it.
+func (d *Dispatcher) resumeVCH(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+	defer trace.End(trace.Begin(d.checkpoint))
+
+	log.Infof("Resuming creation of VCH %q after step %q", conf.Name, d.checkpoint)
+
+	stored, err := d.GetVCHConfig(d.appliance)
+	if err != nil {
+		return err
+	}
+	*conf = *stored
+
+	if d.vmPathName, err = d.appliance.FolderName(d.ctx); err != nil {
+		log.Errorf("Failed to get canonical name for appliance: %s", err)
+		return err
+	}
+
+	ds, err := d.session.Finder.Datastore(d.ctx, conf.ImageStores[0].Host)
+	if err != nil {
+		return errors.Errorf("Failed to find image datastore %q", conf.ImageStores[0].Host)
+	}
+	d.session.Datastore = ds
+	d.session.DatastorePath = conf.ImageStores[0].Host
+
+	d.setDockerPort(conf)
+	settings.Extension = vchExtension(conf)
+
+	return d.completeVCH(conf, settings)
+}
+
+// completeVCH runs the creation steps that follow creation of the appliance VM, skipping those
+// already completed, and starts the appliance
+func (d *Dispatcher) completeVCH(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+	var err error
+
+	if !stepDone(d.checkpoint, stepRules) {
+		d.reportProgress("Creating appliance placement rules", 35)
+		if err = d.createApplianceRules(conf, settings); err != nil {
+			return err
+		}
+		if err = d.configureApplianceHA(conf, settings); err != nil {
+			return err
+		}
+		if err = d.setCheckpoint(stepRules); err != nil {
+			return err
+		}
+	}
+
+	if !stepDone(d.checkpoint, stepImages) {
+		// images are imported along with the appliance when deploying from an OVA
+		if settings.ApplianceOVA == "" {
+			d.reportProgress("Uploading images", 40)
+			if err = d.uploadImages(settings.ImageFiles); err != nil {
+				return errors.Errorf("Uploading images failed with %s. Exiting...", err)
+			}
+		}
+		if err = d.setCheckpoint(stepImages); err != nil {
+			return err
+		}
+	}
+
+	if !stepDone(d.checkpoint, stepExtension) {
+		if d.session.IsVC() {
+			d.reportProgress("Registering vSphere extension", 70)
+			if err = d.RegisterExtension(conf, settings.Extension); err != nil {
+				return errors.Errorf("Error registering VCH vSphere extension: %s", err)
+			}
+		}
+		if err = d.setCheckpoint(stepExtension); err != nil {
+			return err
+		}
+	}
+
+	// the appliance settings are published while it is still powered off
+	vappSpec, err := d.appliancePropertiesSpec(conf)
+	if err == nil && vappSpec != nil {
+		err = d.reconfigureAppliance(types.VirtualMachineConfigSpec{VAppConfig: vappSpec})
+	}
+	if err != nil {
+		log.Warnf("Unable to publish appliance settings as vApp properties: %s", err)
+	}
+
+	d.reportProgress("Starting appliance", 75)
+	if err = d.startAppliance(conf); err != nil {
+		return err
+	}
+
+	if err = d.publishEndpoints(conf); err != nil {
+		log.Warnf("Unable to publish VCH endpoints on its virtual app: %s", err)
+	}
+
+	return d.setCheckpoint("")
+}
@@ -0,0 +1,36 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepDone(t *testing.T) {
+	// no checkpoint, nothing is done
+	for _, s := range createSteps {
+		assert.False(t, stepDone("", s), s)
+	}
+
+	assert.True(t, stepDone(stepImages, stepAppliance))
+	assert.True(t, stepDone(stepImages, stepRules))
+	assert.True(t, stepDone(stepImages, stepImages))
+	assert.False(t, stepDone(stepImages, stepExtension))
+
+	// a checkpoint written by a different version is not trusted
+	assert.False(t, stepDone("unknown", stepAppliance))
+}
@@ -0,0 +1,106 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ApplianceComponents are the sessions that must launch before the appliance is considered initialized
+var ApplianceComponents = []string{"vicadmin", "docker-personality", "port-layer"}
+
+// componentStartedKey returns the extraconfig key a component session records its launch status in
+func componentStartedKey(name string) string {
+	return fmt.Sprintf("guestinfo.vice..init.sessions|%s.started", name)
+}
+
+// keyWaiter blocks until the key has a value, returning that value
+type keyWaiter func(ctx context.Context, key string) (string, error)
+
+// componentResult is the outcome of waiting for a single component to launch
+type componentResult struct {
+	name    string
+	status  string
+	elapsed time.Duration
+	err     error
+}
+
+// waitForComponents waits concurrently for each of the components to report its launch status. Each wait is
+// bounded by the timeout for that component, if there is one, as well as by ctx. Results are sorted by name.
+func waitForComponents(ctx context.Context, components []string, timeouts map[string]time.Duration, wait keyWaiter) []componentResult {
+	var wg sync.WaitGroup
+	results := make([]componentResult, len(components))
+
+	for i, name := range components {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			wctx := ctx
+			if timeout := timeouts[name]; timeout > 0 {
+				var cancel context.CancelFunc
+				wctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			log.Debugf("Waiting for %s to start", name)
+			start := time.Now()
+			status, err := wait(wctx, componentStartedKey(name))
+
+			r := componentResult{
+				name:    name,
+				status:  status,
+				elapsed: time.Since(start),
+				err:     err,
+			}
+			switch {
+			case err != nil && wctx.Err() == context.DeadlineExceeded:
+				r.err = fmt.Errorf("timed out after %s", r.elapsed/time.Second*time.Second)
+			case err == nil && status != "true":
+				r.err = fmt.Errorf("failed to launch: %s", status)
+			}
+
+			log.Debugf("Finished waiting for %s after %s: %v", name, r.elapsed, r.err)
+			results[i] = r
+		}(i, name)
+	}
+	wg.Wait()
+
+	sort.Sort(byComponentResultName(results))
+	return results
+}
+
+// failedComponents returns the results for components that did not launch successfully
+func failedComponents(results []componentResult) []componentResult {
+	var failed []componentResult
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+type byComponentResultName []componentResult
+
+func (s byComponentResultName) Len() int           { return len(s) }
+func (s byComponentResultName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byComponentResultName) Less(i, j int) bool { return s[i].name < s[j].name }
@@ -0,0 +1,106 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testWaiter returns the status for each key, blocking until the context is done for keys without one
+func testWaiter(status map[string]string) keyWaiter {
+	return func(ctx context.Context, key string) (string, error) {
+		if s, ok := status[key]; ok {
+			return s, nil
+		}
+
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+}
+
+func TestWaitForComponents(t *testing.T) {
+	wait := testWaiter(map[string]string{
+		componentStartedKey("vicadmin"):           "true",
+		componentStartedKey("docker-personality"): "true",
+		componentStartedKey("port-layer"):         "true",
+	})
+
+	results := waitForComponents(context.Background(), ApplianceComponents, nil, wait)
+	assert.Len(t, results, 3)
+	assert.Empty(t, failedComponents(results))
+
+	// sorted by name
+	assert.Equal(t, "docker-personality", results[0].name)
+	assert.Equal(t, "port-layer", results[1].name)
+	assert.Equal(t, "vicadmin", results[2].name)
+}
+
+func TestWaitForComponentsFailures(t *testing.T) {
+	wait := testWaiter(map[string]string{
+		componentStartedKey("vicadmin"):   "true",
+		componentStartedKey("port-layer"): "exec format error",
+	})
+
+	timeouts := map[string]time.Duration{
+		"docker-personality": 50 * time.Millisecond,
+	}
+
+	// the overall deadline must not be reached by the component that times out
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	failed := failedComponents(waitForComponents(ctx, ApplianceComponents, timeouts, wait))
+	if assert.Len(t, failed, 2) {
+		assert.Equal(t, "docker-personality", failed[0].name)
+		assert.Contains(t, failed[0].err.Error(), "timed out")
+
+		assert.Equal(t, "port-layer", failed[1].name)
+		assert.Contains(t, failed[1].err.Error(), "exec format error")
+	}
+	assert.NoError(t, ctx.Err())
+}
+
+func TestWaitForComponentsConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	waiting := 0
+	all := make(chan struct{})
+
+	// each wait only completes once all components are being waited on
+	wait := func(ctx context.Context, key string) (string, error) {
+		mu.Lock()
+		waiting++
+		if waiting == len(ApplianceComponents) {
+			close(all)
+		}
+		mu.Unlock()
+
+		select {
+		case <-all:
+			return "true", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	assert.Empty(t, failedComponents(waitForComponents(ctx, ApplianceComponents, nil, wait)))
+}
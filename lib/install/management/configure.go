@@ -0,0 +1,283 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor/validation"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+const (
+	ReconfigurePrefix = "reconfigure for"
+)
+
+// Reconfigure applies the difference between the current and requested configuration of an existing VCH,
+// restarting the appliance so that the changes take effect. The appliance is snapshotted beforehand and
+// reverted to the snapshot if it fails its health check with the new configuration.
+func (d *Dispatcher) Reconfigure(vch *vm.VirtualMachine, current, requested *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) (err error) {
+	defer trace.End(trace.Begin(requested.Name))
+
+	d.beginProgress(OperationReconfigure)
+	defer func() {
+		d.endProgress(err)
+	}()
+
+	d.appliance = vch
+	d.setDockerPort(requested)
+
+	setProxies(requested, settings)
+	if err = validation.Executor(&requested.ExecutorConfig); err != nil {
+		return err
+	}
+
+	// volume stores created here are removed again if the reconfigure fails
+	d.undo.reset()
+	defer func() {
+		if err == nil {
+			d.undo.reset()
+			return
+		}
+
+		var cancel context.CancelFunc
+		d.ctx, cancel = context.WithTimeout(context.Background(), settings.RollbackTimeout)
+		defer cancel()
+
+		if rerr := d.undo.unwind(); rerr != nil {
+			err = errors.Errorf("%s\n%s", err, rerr)
+		}
+	}()
+
+	if added := addedVolumeStores(current, requested); len(added) > 0 {
+		d.reportProgress("Creating volume stores", 5)
+		log.Infof("Creating %d new volume store(s)", len(added))
+		if err = d.createVolumeStores(&config.VirtualContainerHostConfigSpec{Storage: config.Storage{VolumeLocations: added}}); err != nil {
+			return errors.Errorf("Could not create volume stores due to error: %s", err)
+		}
+	}
+
+	resize, hot, err := d.applianceResize(settings)
+	if err != nil {
+		return err
+	}
+	if resize != nil && hot {
+		d.reportProgress("Resizing appliance", 10)
+		log.Infof("Resizing running appliance")
+		if err = d.reconfigureAppliance(*resize); err != nil {
+			return errors.Errorf("Failed to resize appliance: %s", err)
+		}
+		resize = nil
+	}
+
+	delta := configDelta(current, requested)
+	if len(delta) == 0 && resize == nil {
+		log.Infof("No configuration changes to apply")
+		return nil
+	}
+	if resize != nil {
+		log.Infof("Appliance must be powered off to be resized")
+	}
+
+	log.Infof("Applying configuration changes:")
+	for _, o := range delta {
+		log.Infof("\t%s", o.GetOptionValue().Key)
+	}
+
+	// ensure that we wait for components to come up with the new configuration
+	for id, s := range requested.ExecutorConfig.Sessions {
+		if s.Started != "" {
+			s.Started = ""
+			delta = append(delta, &types.OptionValue{Key: componentStartedKey(id), Value: ""})
+		}
+	}
+
+	snapshotName := fmt.Sprintf("%s %s", ReconfigurePrefix, time.Now().UTC().Format(time.RFC3339))
+	d.reportProgress("Creating reconfigure snapshot", 20)
+	snapshotRefID, err := d.createSnapshot(snapshotName, "reconfigure snapshot")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			d.deleteSnapshot(*snapshotRefID, snapshotName, requested.Name)
+		}
+	}()
+
+	d.reportProgress("Applying configuration", 30)
+	if err = d.applyConfigDelta(requested, delta, resize); err == nil {
+		// the appliance must pass its health check before the snapshot is discarded
+		d.reportProgress("Checking docker API", 85)
+		if err = d.CheckDockerAPI(requested, nil); err == nil {
+			if perr := d.publishEndpoints(requested); perr != nil {
+				log.Warnf("Unable to publish VCH endpoints on its virtual app: %s", perr)
+			}
+			return nil
+		}
+	}
+	log.Errorf("Failed to reconfigure: %s", err)
+	log.Infof("Rolling back configuration changes")
+	d.reportProgress("Rolling back configuration changes", 90)
+
+	// reset timeout, to make sure rollback still happens in case of deadline exceeded error in previous step
+	var cancel context.CancelFunc
+	d.ctx, cancel = context.WithTimeout(context.Background(), settings.RollbackTimeout)
+	defer cancel()
+
+	if rerr := d.rollback(current, snapshotName); rerr != nil {
+		log.Errorf("Failed to revert appliance to snapshot: %s", rerr)
+		// the appliance may still be using the new volume stores
+		d.undo.reset()
+		return err
+	}
+
+	log.Infof("Appliance is rolled back to the previous configuration")
+	return err
+}
+
+// applyConfigDelta writes the changed configuration, and the resize spec if any, to the powered off appliance
+// and restarts it
+func (d *Dispatcher) applyConfigDelta(conf *config.VirtualContainerHostConfigSpec, delta []types.BaseOptionValue, resize *types.VirtualMachineConfigSpec) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	power, err := d.appliance.PowerState(d.ctx)
+	if err != nil {
+		log.Errorf("Failed to get vm power status %q: %s", d.appliance.Reference(), err)
+		return err
+	}
+	if power != types.VirtualMachinePowerStatePoweredOff {
+		if _, err = d.appliance.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+			return d.appliance.PowerOff(ctx)
+		}); err != nil {
+			log.Errorf("Failed to power off appliance: %s", err)
+			return err
+		}
+	}
+
+	spec := types.VirtualMachineConfigSpec{}
+	if resize != nil {
+		spec = *resize
+	}
+	spec.ExtraConfig = delta
+
+	// keep the vApp properties in step with the configuration, as they take precedence over it
+	vappSpec, err := d.appliancePropertiesSpec(conf)
+	if err != nil {
+		log.Warnf("Unable to update appliance vApp properties: %s", err)
+	}
+	if vappSpec != nil {
+		spec.VAppConfig = vappSpec
+	}
+
+	log.Infof("Setting VM configuration")
+	if err = d.reconfigureAppliance(spec); err != nil {
+		return err
+	}
+
+	return d.startAppliance(conf)
+}
+
+// configDelta returns the extraconfig entries that change the current configuration into the requested one,
+// sorted by key. Keys present only in the current configuration are removed.
+func configDelta(current, requested *config.VirtualContainerHostConfigSpec) []types.BaseOptionValue {
+	before := make(map[string]string)
+	extraconfig.Encode(extraconfig.MapSink(before), current)
+
+	after := make(map[string]string)
+	extraconfig.Encode(extraconfig.MapSink(after), requested)
+
+	var keys []string
+	for k, v := range after {
+		if old, ok := before[k]; !ok || old != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var delta []types.BaseOptionValue
+	for _, k := range keys {
+		v, ok := after[k]
+		if ok && v == "" {
+			// as in OptionValueFromMap, an empty value would remove the key
+			v = "<nil>"
+		}
+		delta = append(delta, &types.OptionValue{Key: k, Value: v})
+	}
+
+	return delta
+}
+
+// addedVolumeStores returns the volume stores in requested that are new or have moved since current
+func addedVolumeStores(current, requested *config.VirtualContainerHostConfigSpec) map[string]*url.URL {
+	added := make(map[string]*url.URL)
+	for label, u := range requested.VolumeLocations {
+		if old, ok := current.VolumeLocations[label]; ok && old.String() == u.String() {
+			continue
+		}
+		added[label] = u
+	}
+	return added
+}
+
+// setProxies updates the proxy environment of the docker personality, leaving proxies that were not supplied unchanged
+func setProxies(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) {
+	personality, ok := conf.ExecutorConfig.Sessions["docker-personality"]
+	if !ok {
+		return
+	}
+
+	if settings.HTTPProxy != nil {
+		personality.Cmd.Env = setEnv(personality.Cmd.Env, "HTTP_PROXY", settings.HTTPProxy.String())
+	}
+	if settings.HTTPSProxy != nil {
+		personality.Cmd.Env = setEnv(personality.Cmd.Env, "HTTPS_PROXY", settings.HTTPSProxy.String())
+	}
+	if settings.NoProxy != nil {
+		personality.Cmd.Env = setEnv(personality.Cmd.Env, "NO_PROXY", strings.Join(settings.NoProxy, ","))
+	}
+}
+
+// setEnv replaces the value of name in env, or adds it if not already present
+func setEnv(env []string, name, value string) []string {
+	entry := fmt.Sprintf("%s=%s", name, value)
+
+	for i := range env {
+		if strings.HasPrefix(env[i], name+"=") {
+			env[i] = entry
+			return env
+		}
+	}
+
+	return append(env, entry)
+}
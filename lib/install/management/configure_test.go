@@ -0,0 +1,123 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/install/data"
+)
+
+func testConfig() *config.VirtualContainerHostConfigSpec {
+	conf := &config.VirtualContainerHostConfigSpec{}
+	conf.Name = "vch"
+	conf.BridgeNetwork = "bridge"
+	conf.VolumeLocations = map[string]*url.URL{
+		"default": {Scheme: "ds", Host: "datastore1", Path: "volumes"},
+	}
+	conf.AddNetwork(&executor.NetworkEndpoint{
+		Network: executor.ContainerNetwork{
+			Common:      executor.Common{Name: "client"},
+			Nameservers: []net.IP{net.ParseIP("8.8.8.8")},
+		},
+	})
+	conf.AddComponent("docker-personality", &executor.SessionConfig{
+		Cmd: executor.Cmd{
+			Path: "/sbin/docker-engine-server",
+			Env:  []string{"PATH=/sbin", "HTTP_PROXY=http://old:3128"},
+		},
+	})
+	return conf
+}
+
+func TestConfigDeltaUnchanged(t *testing.T) {
+	assert.Empty(t, configDelta(testConfig(), testConfig()))
+}
+
+func TestConfigDelta(t *testing.T) {
+	current := testConfig()
+	requested := testConfig()
+
+	requested.ExecutorConfig.Networks["client"].Network.Nameservers = []net.IP{net.ParseIP("10.0.0.1")}
+
+	delta := configDelta(current, requested)
+	if !assert.NotEmpty(t, delta) {
+		return
+	}
+
+	for _, o := range delta {
+		assert.Contains(t, o.GetOptionValue().Key, "client")
+	}
+}
+
+func TestConfigDeltaDebugAccess(t *testing.T) {
+	current := testConfig()
+	requested := *current
+	requested.ExecutorConfig.Diagnostics.DebugAccess = &executor.DebugAccess{SSH: true, AuthorizedKey: "ssh-rsa AAAA"}
+
+	delta := configDelta(current, &requested)
+	if !assert.Len(t, delta, 2) {
+		return
+	}
+	for _, o := range delta {
+		assert.Contains(t, o.GetOptionValue().Key, "access")
+	}
+
+	// removing the access removes its keys
+	delta = configDelta(&requested, current)
+	if !assert.Len(t, delta, 2) {
+		return
+	}
+	for _, o := range delta {
+		assert.Equal(t, "", o.GetOptionValue().Value)
+	}
+}
+
+func TestAddedVolumeStores(t *testing.T) {
+	current := testConfig()
+	requested := testConfig()
+	requested.VolumeLocations["new"] = &url.URL{Scheme: "ds", Host: "datastore2", Path: "volumes"}
+
+	added := addedVolumeStores(current, requested)
+	assert.Len(t, added, 1)
+	assert.Contains(t, added, "new")
+}
+
+func TestSetProxies(t *testing.T) {
+	conf := testConfig()
+
+	https, _ := url.Parse("https://proxy:3129")
+	setProxies(conf, &data.InstallerData{HTTPSProxy: https})
+
+	env := conf.ExecutorConfig.Sessions["docker-personality"].Cmd.Env
+	assert.Equal(t, []string{"PATH=/sbin", "HTTP_PROXY=http://old:3128", "HTTPS_PROXY=https://proxy:3129"}, env)
+
+	http, _ := url.Parse("http://new:3128")
+	setProxies(conf, &data.InstallerData{HTTPProxy: http})
+
+	env = conf.ExecutorConfig.Sessions["docker-personality"].Cmd.Env
+	assert.Equal(t, []string{"PATH=/sbin", "HTTP_PROXY=http://new:3128", "HTTPS_PROXY=https://proxy:3129"}, env)
+
+	setProxies(conf, &data.InstallerData{NoProxy: []string{"localhost", ".corp.example.com"}})
+
+	env = conf.ExecutorConfig.Sessions["docker-personality"].Cmd.Env
+	assert.Equal(t, []string{"PATH=/sbin", "HTTP_PROXY=http://new:3128", "HTTPS_PROXY=https://proxy:3129", "NO_PROXY=localhost,.corp.example.com"}, env)
+}
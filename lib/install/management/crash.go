@@ -0,0 +1,137 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/spec"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+var (
+	// consoleTimestamp matches the printk timestamp that prefixes kernel console lines
+	consoleTimestamp = regexp.MustCompile(`^\[\s*\d+\.\d+\]\s*`)
+)
+
+// KernelPanic is the last kernel panic recorded in the crash log of a VM
+type KernelPanic struct {
+	Message string `json:"message"`
+	// Time is when the panic was written, unknown if the VM has booted since
+	Time *time.Time `json:"time,omitempty"`
+	Log  string     `json:"log"`
+}
+
+// crashLogSerialPort returns the serial port writing the appliance kernel console to its folder
+func (d *Dispatcher) crashLogSerialPort(conf *config.VirtualContainerHostConfigSpec) *types.VirtualSerialPort {
+	return spec.NewCrashLogSerialPort(fmt.Sprintf("[%s] %s/%s", conf.ImageStores[0].Host, d.vmPathName, spec.CrashLogName))
+}
+
+// lastPanic scans a kernel console log for the last panic, returning its message and whether the
+// kernel has booted again since
+func lastPanic(r io.Reader) (message string, rebooted bool, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := consoleTimestamp.ReplaceAllString(scanner.Text(), "")
+
+		switch {
+		case strings.HasPrefix(line, "Kernel panic"):
+			message = line
+			rebooted = false
+		case strings.HasPrefix(line, "Linux version"):
+			rebooted = message != ""
+		}
+	}
+
+	return message, rebooted, scanner.Err()
+}
+
+// appliancePanic returns the last kernel panic recorded in the crash log of the appliance, nil if
+// there is none
+func (d *Dispatcher) appliancePanic(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec) (*KernelPanic, error) {
+	defer trace.End(trace.Begin(conf.Name))
+
+	if len(conf.ImageStores) == 0 {
+		return nil, nil
+	}
+
+	folder, err := vch.FolderName(d.ctx)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := d.session.Finder.Datastore(d.ctx, conf.ImageStores[0].Host)
+	if err != nil {
+		return nil, err
+	}
+	file := path.Join(folder, spec.CrashLogName)
+
+	info, err := ds.Stat(d.ctx, file)
+	if err != nil {
+		// VCHs created before the crash log was added have none
+		log.Debugf("No appliance crash log: %s", err)
+		return nil, nil
+	}
+
+	r, _, err := ds.Download(d.ctx, file, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	message, rebooted, err := lastPanic(r)
+	if err != nil || message == "" {
+		return nil, err
+	}
+
+	p := &KernelPanic{
+		Message: message,
+		Log:     ds.Path(file),
+	}
+	if !rebooted {
+		p.Time = info.GetFileInfo().Modification
+	}
+	return p, nil
+}
+
+// showPanic reports the last kernel panic of the appliance, if any
+func (d *Dispatcher) showPanic(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec) {
+	p, err := d.appliancePanic(vch, conf)
+	if err != nil {
+		log.Warnf("Unable to read appliance crash log: %s", err)
+		return
+	}
+	if p == nil {
+		return
+	}
+
+	when := "before its last restart"
+	if p.Time != nil {
+		when = fmt.Sprintf("at %s", p.Time.Format(time.RFC3339))
+	}
+	log.Info("")
+	log.Warnf("Appliance panicked %s: %s", when, p.Message)
+	log.Warnf("The kernel trace is in %s", p.Log)
+}
@@ -0,0 +1,48 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastPanic(t *testing.T) {
+	boot := "[    0.000000] Linux version 4.4.8-esx (root@photon) (gcc version 5.3.0 (GCC) ) #1-photon SMP\n" +
+		"[    1.234567] EXT4-fs (sda): mounted filesystem with ordered data mode\n"
+	panic := "[  311.017316] Kernel panic - not syncing: Out of memory and no killable processes...\n" +
+		"[  311.018201] CPU: 0 PID: 1 Comm: vic-init Not tainted 4.4.8-esx #1-photon\n"
+
+	var tests = []struct {
+		log      string
+		message  string
+		rebooted bool
+	}{
+		{"", "", false},
+		{boot, "", false},
+		{boot + panic, "Kernel panic - not syncing: Out of memory and no killable processes...", false},
+		{boot + panic + boot, "Kernel panic - not syncing: Out of memory and no killable processes...", true},
+		{boot + panic + boot + "Kernel panic - not syncing: Attempted to kill init!\n", "Kernel panic - not syncing: Attempted to kill init!", false},
+	}
+
+	for _, test := range tests {
+		message, rebooted, err := lastPanic(strings.NewReader(test.log))
+		assert.NoError(t, err)
+		assert.Equal(t, test.message, message)
+		assert.Equal(t, test.rebooted, rebooted)
+	}
+}
@@ -17,7 +17,7 @@ package management
 import (
 	"context"
 	"fmt"
-	"path"
+	"net/url"
 	"sync"
 	"time"
 
@@ -27,21 +27,58 @@ import (
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/datastore"
 	"github.com/vmware/vic/pkg/vsphere/tasks"
 
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
-func (d *Dispatcher) CreateVCH(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+func (d *Dispatcher) CreateVCH(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) (err error) {
 	defer trace.End(trace.Begin(conf.Name))
 
-	var err error
+	d.beginProgress(OperationCreate)
+	defer func() {
+		d.endProgress(err)
+	}()
 
+	d.reportProgress("Checking for an existing VCH", 0)
 	if err = d.checkExistence(conf, settings); err != nil {
 		return err
 	}
 
+	// an existing appliance with a checkpoint is a VCH whose creation failed part way through
+	if d.checkpoint != "" {
+		return d.resumeVCH(conf, settings)
+	}
+
+	// every resource created from here on registers an undo action, which are run in reverse
+	// order if creation fails so that a failed create leaves nothing behind. Once the appliance
+	// exists, creation is checkpointed instead and is resumed by running create again.
+	d.undo.reset()
+	defer func() {
+		if err == nil {
+			d.undo.reset()
+			return
+		}
+
+		log.Errorf("Creating VCH %q failed: %s", conf.Name, err)
+		if d.checkpoint != "" {
+			log.Errorf("Run create again with the same name to resume from step %q, or delete the VCH", d.checkpoint)
+			return
+		}
+
+		// reset timeout, to make sure rollback still happens in case of deadline exceeded error in previous step
+		var cancel context.CancelFunc
+		d.ctx, cancel = context.WithTimeout(context.Background(), settings.RollbackTimeout)
+		defer cancel()
+
+		if rerr := d.undo.unwind(); rerr != nil {
+			err = errors.Errorf("%s\n%s", err, rerr)
+		}
+	}()
+
+	d.reportProgress("Creating resource pool", 5)
 	if d.isVC && !settings.UseRP {
 		if d.vchVapp, err = d.createVApp(conf, settings); err != nil {
 			detail := fmt.Sprintf("Creating virtual app failed: %s", err)
@@ -68,28 +105,40 @@ func (d *Dispatcher) CreateVCH(conf *config.VirtualContainerHostConfigSpec, sett
 		}
 	}
 
+	d.reportProgress("Creating bridge network", 10)
 	if err = d.createBridgeNetwork(conf); err != nil {
 		return err
 	}
+	if conf.CreateBridgeNetwork {
+		d.undo.push(fmt.Sprintf("bridge network %q", conf.Name), func() error {
+			return d.removeNetwork(conf)
+		})
+	}
 
+	d.reportProgress("Creating volume stores", 15)
 	if err = d.createVolumeStores(conf); err != nil {
 		return errors.Errorf("Exiting because we could not create volume stores due to error: %s", err)
 	}
 
+	d.reportProgress("Opening host firewalls", 18)
+	if err = d.enableFirewallRules(conf, settings); err != nil {
+		return err
+	}
+
+	d.reportProgress("Creating appliance", 20)
+	if err = d.placeAppliance(conf); err != nil {
+		return errors.Errorf("Choosing an image store for the appliance failed: %s", err)
+	}
+
 	if err = d.createAppliance(conf, settings); err != nil {
 		return errors.Errorf("Creating the appliance failed with %s. Exiting...", err)
 	}
 
-	if err = d.uploadImages(settings.ImageFiles); err != nil {
-		return errors.Errorf("Uploading images failed with %s. Exiting...", err)
+	if err = d.setCheckpoint(stepAppliance); err != nil {
+		return err
 	}
 
-	if d.session.IsVC() {
-		if err = d.RegisterExtension(conf, settings.Extension); err != nil {
-			return errors.Errorf("Error registering VCH vSphere extension: %s", err)
-		}
-	}
-	return d.startAppliance(conf)
+	return d.completeVCH(conf, settings)
 }
 
 func (d *Dispatcher) startAppliance(conf *config.VirtualContainerHostConfigSpec) error {
@@ -111,10 +160,49 @@ func (d *Dispatcher) startAppliance(conf *config.VirtualContainerHostConfigSpec)
 	return nil
 }
 
+// placeAppliance chooses which of the image stores holds the appliance files and ISOs according to
+// the placement policy. The chosen store is moved to the front of conf.ImageStores, as the first
+// image store is always the one holding the appliance.
+func (d *Dispatcher) placeAppliance(conf *config.VirtualContainerHostConfigSpec) error {
+	defer trace.End(trace.Begin(""))
+
+	if len(conf.ImageStores) < 2 {
+		return nil
+	}
+
+	placer, err := datastore.NewPlacer(conf.ImageStorePlacement, conf.ImageStores, datastore.FreeSpace(d.session))
+	if err != nil {
+		return err
+	}
+
+	u, err := placer.Place(d.ctx)
+	if err != nil {
+		return err
+	}
+	chosen := *u
+
+	ds, err := d.session.Finder.Datastore(d.ctx, chosen.Host)
+	if err != nil {
+		return errors.Errorf("Failed to find image datastore %q: %s", chosen.Host, err)
+	}
+	d.session.Datastore = ds
+	d.session.DatastorePath = chosen.Host
+
+	stores := []url.URL{chosen}
+	for _, s := range conf.ImageStores {
+		if s.Host != chosen.Host {
+			stores = append(stores, s)
+		}
+	}
+	conf.ImageStores = stores
+
+	log.Infof("Placing appliance files on image store %q (%s)", chosen.Host, conf.ImageStorePlacement)
+	return nil
+}
+
 func (d *Dispatcher) uploadImages(files map[string]string) error {
 	defer trace.End(trace.Begin(""))
 
-	var err error
 	var wg sync.WaitGroup
 
 	// upload the images
@@ -126,7 +214,7 @@ func (d *Dispatcher) uploadImages(files map[string]string) error {
 			defer wg.Done()
 
 			log.Infof("\t%q", image)
-			err = d.session.Datastore.UploadFile(d.ctx, image, path.Join(d.vmPathName, key), nil)
+			err := d.uploadImage(image, key)
 			if err != nil {
 				log.Errorf("\t\tUpload failed for %q: %s", image, err)
 				if d.force {
@@ -15,6 +15,7 @@
 package management
 
 import (
+	"fmt"
 	"net/url"
 	"testing"
 	"time"
@@ -22,6 +23,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/lib/install/validate"
 	"github.com/vmware/vic/pkg/vsphere/session"
@@ -59,8 +61,8 @@ func TestMain(t *testing.T) {
 			t.Fatal(err)
 		}
 		installSettings := &data.InstallerData{}
-		installSettings.ApplianceSize.CPU.Limit = 1
-		installSettings.ApplianceSize.Memory.Limit = 1024
+		installSettings.ApplianceCPUs = 1
+		installSettings.ApplianceMemoryMB = 1024
 
 		validator, err := validate.NewValidator(ctx, input)
 		if err != nil {
@@ -80,12 +82,14 @@ func TestMain(t *testing.T) {
 		//		testCreateNetwork(ctx, validator.Session, conf, t)
 
 		testCreateVolumeStores(ctx, validator.Session, conf, false, t)
+		testVolumeStores(ctx, validator.Session, conf, t)
 		testDeleteVolumeStores(ctx, validator.Session, conf, 1, t)
 		errConf := &config.VirtualContainerHostConfigSpec{}
 		*errConf = *conf
 		errConf.VolumeLocations = make(map[string]*url.URL)
 		errConf.VolumeLocations["volume-store"], _ = url.Parse("ds://store_not_exist/volumes/test")
 		testCreateVolumeStores(ctx, validator.Session, errConf, true, t)
+		testPlanVCH(ctx, validator.Session, conf, installSettings, t)
 		testCreateAppliance(ctx, validator.Session, conf, installSettings, false, t)
 	}
 }
@@ -95,7 +99,7 @@ func getESXData(url *url.URL) *data.Data {
 	result.URL = url
 	result.DisplayName = "test001"
 	result.ComputeResourcePath = "/ha-datacenter/host/localhost.localdomain/Resources"
-	result.ImageDatastorePath = "LocalDS_0"
+	result.ImageDatastorePaths = []string{"LocalDS_0"}
 	result.BridgeNetworkName = "bridge"
 	result.ManagementNetwork.Name = "VM Network"
 	result.ExternalNetwork.Name = "VM Network"
@@ -110,7 +114,7 @@ func getVPXData(url *url.URL) *data.Data {
 	result.URL = url
 	result.DisplayName = "test001"
 	result.ComputeResourcePath = "/DC0/host/DC0_C0/Resources"
-	result.ImageDatastorePath = "LocalDS_0"
+	result.ImageDatastorePaths = []string{"LocalDS_0"}
 	result.ExternalNetwork.Name = "VM Network"
 	result.BridgeNetworkName = "bridge"
 	result.VolumeLocations = make(map[string]string)
@@ -199,6 +203,26 @@ func testCreateVolumeStores(ctx context.Context, sess *session.Session, conf *co
 	}
 }
 
+func testVolumeStores(ctx context.Context, sess *session.Session, conf *config.VirtualContainerHostConfigSpec, t *testing.T) {
+	d := &Dispatcher{
+		session: sess,
+		ctx:     ctx,
+		isVC:    sess.IsVC(),
+	}
+
+	stores := d.VolumeStores(conf)
+	if len(stores) != 1 {
+		t.Fatalf("Expected 1 volume store, got %d", len(stores))
+	}
+	store := stores[0]
+	if store.Error != "" {
+		t.Errorf("Unexpected error: %s", store.Error)
+	}
+	if store.Datastore != "LocalDS_0" || store.Capacity == 0 || store.Volumes != 0 {
+		t.Errorf("Unexpected volume store status: %+v", store)
+	}
+}
+
 func testDeleteVolumeStores(ctx context.Context, sess *session.Session, conf *config.VirtualContainerHostConfigSpec, numVols int, t *testing.T) {
 	d := &Dispatcher{
 		session: sess,
@@ -207,7 +231,13 @@ func testDeleteVolumeStores(ctx context.Context, sess *session.Session, conf *co
 		force:   true,
 	}
 
-	if removed := d.deleteVolumeStoreIfForced(conf); removed != numVols {
+	if removed := d.deleteVolumeStores(conf, VolumesPreserve); removed != 0 {
+		t.Errorf("Removed %d volume stores that should have been preserved", removed)
+	}
+
+	// the action applies whether or not the delete is forced
+	d.force = false
+	if removed := d.deleteVolumeStores(conf, VolumesDelete); removed != numVols {
 		t.Errorf("Did not successfully remove all specified volumes")
 	}
 
@@ -228,3 +258,53 @@ func testCreateAppliance(ctx context.Context, sess *session.Session, conf *confi
 		t.Logf("Expected error: %s", err)
 	}
 }
+
+func testPlanVCH(ctx context.Context, sess *session.Session, conf *config.VirtualContainerHostConfigSpec, vConf *data.InstallerData, t *testing.T) {
+	d := &Dispatcher{
+		session: sess,
+		ctx:     ctx,
+		isVC:    sess.IsVC(),
+	}
+
+	planConf := &config.VirtualContainerHostConfigSpec{}
+	*planConf = *conf
+	planConf.ExecutorConfig.Sessions = nil
+	planConf.ExecutorConfig.Networks = make(map[string]*executor.NetworkEndpoint)
+	for name, endpoint := range conf.ExecutorConfig.Networks {
+		// FIXME: cannot create bridge network right now
+		if name != "bridge" {
+			planConf.ExecutorConfig.Networks[name] = endpoint
+		}
+	}
+
+	settings := *vConf
+	settings.ImageFiles = map[string]string{"1.0-bootstrap.iso": "bootstrap.iso"}
+	settings.BootstrapISO = "1.0-bootstrap.iso"
+
+	plan, err := d.PlanVCH(planConf, &settings)
+	if err != nil {
+		t.Fatalf("Failed to plan VCH: %s", err)
+	}
+
+	if plan.Appliance == nil || plan.Appliance.Name != conf.Name {
+		t.Errorf("Expected appliance spec for %q, got %#v", conf.Name, plan.Appliance)
+	}
+	if len(plan.Config) == 0 {
+		t.Errorf("Expected appliance configuration in plan")
+	}
+	image := fmt.Sprintf("[%s] %s/1.0-bootstrap.iso", conf.ImageStores[0].Host, conf.Name)
+	if plan.Images[image] != "bootstrap.iso" {
+		t.Errorf("Expected %q in planned images, got %v", image, plan.Images)
+	}
+	if planConf.BootstrapImagePath != image {
+		t.Errorf("Expected bootstrap image path %q, got %q", image, planConf.BootstrapImagePath)
+	}
+
+	// nothing is created by planning
+	if err = d.checkExistence(conf, vConf); err != nil {
+		t.Errorf("Unexpected error checking for VCH after plan: %s", err)
+	}
+	if d.appliance != nil {
+		t.Errorf("Expected no appliance to have been created")
+	}
+}
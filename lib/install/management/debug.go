@@ -22,11 +22,15 @@ import (
 	"github.com/vmware/govmomi/guest"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
 	"github.com/vmware/vic/pkg/vsphere/vm"
 )
 
+// DebugVCH enables SSH in the running appliance, placing the key in the authorized_keys of root.
+// The setting is recorded in the appliance configuration so that it is applied again after a reboot.
 func (d *Dispatcher) DebugVCH(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec, password, authorizedKey string) error {
 	defer trace.End(trace.Begin(conf.Name))
 
@@ -35,6 +39,12 @@ func (d *Dispatcher) DebugVCH(vch *vm.VirtualMachine, conf *config.VirtualContai
 		op = trace.NewOperation(d.ctx, "enable appliance debug")
 	}
 
+	err = d.setDebugAccess(op, vch, conf, &executor.DebugAccess{SSH: true, AuthorizedKey: authorizedKey})
+	if err != nil {
+		op.Errorf("Unable to record debug access in the VCH appliance configuration: %s", err)
+		return err
+	}
+
 	err = d.enableSSH(op, vch, password, authorizedKey)
 	if err != nil {
 		op.Errorf("Unable to enable ssh on the VCH appliance VM: %s", err)
@@ -46,12 +56,73 @@ func (d *Dispatcher) DebugVCH(vch *vm.VirtualMachine, conf *config.VirtualContai
 	return nil
 }
 
+// DisableDebugVCH reverts DebugVCH, stopping SSH in the running appliance and removing the key
+func (d *Dispatcher) DisableDebugVCH(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	op, err := trace.FromContext(d.ctx)
+	if err != nil {
+		op = trace.NewOperation(d.ctx, "disable appliance debug")
+	}
+
+	err = d.setDebugAccess(op, vch, conf, nil)
+	if err != nil {
+		op.Errorf("Unable to remove debug access from the VCH appliance configuration: %s", err)
+		return err
+	}
+
+	err = d.runApplianceCommand(op, vch, "disable-ssh", "")
+	if err != nil {
+		op.Errorf("Unable to disable ssh on the VCH appliance VM: %s", err)
+		return err
+	}
+
+	d.sshEnabled = false
+
+	return nil
+}
+
+// setDebugAccess updates the debug access of the appliance in its configuration, without restarting it
+func (d *Dispatcher) setDebugAccess(ctx context.Context, vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec, access *executor.DebugAccess) error {
+	current := *conf
+	conf.ExecutorConfig.Diagnostics.DebugAccess = access
+
+	delta := configDelta(&current, conf)
+	if len(delta) == 0 {
+		return nil
+	}
+
+	info, err := vch.WaitForResult(ctx, func(ctx context.Context) (tasks.Task, error) {
+		return vch.Reconfigure(ctx, types.VirtualMachineConfigSpec{ExtraConfig: delta})
+	})
+	if err != nil {
+		conf.ExecutorConfig.Diagnostics.DebugAccess = current.ExecutorConfig.Diagnostics.DebugAccess
+		return err
+	}
+
+	return tasks.TaskError(info)
+}
+
 func (d *Dispatcher) enableSSH(ctx context.Context, vch *vm.VirtualMachine, password, authorizedKey string) error {
 	op, err := trace.FromContext(ctx)
 	if err != nil {
 		op = trace.NewOperation(ctx, "enable ssh in appliance")
 	}
 
+	if err = d.runApplianceCommand(op, vch, "enable-ssh", authorizedKey); err != nil {
+		return err
+	}
+
+	if password == "" {
+		return nil
+	}
+
+	// set the password as well
+	return d.runApplianceCommand(op, vch, "passwd", password)
+}
+
+// runApplianceCommand runs one of the synthetic commands that vic-init permits via guest operations
+func (d *Dispatcher) runApplianceCommand(op trace.Operation, vch *vm.VirtualMachine, program, args string) error {
 	state, err := vch.PowerState(op)
 	if err != nil {
 		log.Errorf("Failed to get appliance power state, service might not be available at this moment.")
@@ -80,34 +151,15 @@ func (d *Dispatcher) enableSSH(ctx context.Context, vch *vm.VirtualMachine, pass
 	auth := types.NamePasswordAuthentication{}
 
 	spec := types.GuestProgramSpec{
-		ProgramPath:      "enable-ssh",
-		Arguments:        string(authorizedKey),
-		WorkingDirectory: "/",
-		EnvVariables:     []string{},
-	}
-
-	_, err = processManager.StartProgram(op, &auth, &spec)
-	if err != nil {
-		err = errors.Errorf("Unable to enable SSH in appliance VM: %s", err)
-		op.Errorf("%s", err)
-		return err
-	}
-
-	if password == "" {
-		return nil
-	}
-
-	// set the password as well
-	spec = types.GuestProgramSpec{
-		ProgramPath:      "passwd",
-		Arguments:        password,
+		ProgramPath:      program,
+		Arguments:        args,
 		WorkingDirectory: "/",
 		EnvVariables:     []string{},
 	}
 
 	_, err = processManager.StartProgram(op, &auth, &spec)
 	if err != nil {
-		err = errors.Errorf("Unable to enable in appliance VM: %s", err)
+		err = errors.Errorf("Unable to run %s in appliance VM: %s", program, err)
 		op.Errorf("%s", err)
 		return err
 	}
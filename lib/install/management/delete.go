@@ -30,14 +30,33 @@ import (
 	"github.com/vmware/vic/pkg/vsphere/vm"
 )
 
-func (d *Dispatcher) DeleteVCH(conf *config.VirtualContainerHostConfigSpec) error {
+// VolumeAction is what deleting a VCH does with its volume stores
+type VolumeAction string
+
+const (
+	// VolumesDefault removes the volume stores only if the delete is forced
+	VolumesDefault VolumeAction = ""
+	// VolumesPreserve leaves the volume stores in place, for a later VCH to use them
+	VolumesPreserve VolumeAction = "preserve"
+	// VolumesDelete removes the volume stores along with all volumes in them
+	VolumesDelete VolumeAction = "delete"
+)
+
+// DeleteVCH removes the VCH with its containers and images. Its volume stores are removed or
+// preserved as volumes says.
+func (d *Dispatcher) DeleteVCH(conf *config.VirtualContainerHostConfigSpec, volumes VolumeAction) (err error) {
 	defer trace.End(trace.Begin(conf.Name))
 
+	d.beginProgress(OperationDelete)
+	defer func() {
+		d.endProgress(err)
+	}()
+
 	var errs []string
 
-	var err error
 	var vmm *vm.VirtualMachine
 
+	d.reportProgress("Finding appliance", 0)
 	if vmm, err = d.findApplianceByID(conf); err != nil {
 		return err
 	}
@@ -45,18 +64,22 @@ func (d *Dispatcher) DeleteVCH(conf *config.VirtualContainerHostConfigSpec) erro
 		return nil
 	}
 
+	d.reportProgress("Deleting container VMs", 10)
 	if err = d.DeleteVCHInstances(vmm, conf); err != nil {
 		// if container delete failed, do not remove anything else
 		log.Infof("Specify --force to force delete")
 		return err
 	}
 
+	d.reportProgress("Deleting images", 40)
 	if err = d.deleteImages(conf); err != nil {
 		errs = append(errs, err.Error())
 	}
 
-	d.deleteVolumeStoreIfForced(conf) // logs errors but doesn't ever bail out if it has an issue
+	d.reportProgress("Deleting volume stores", 55)
+	d.deleteVolumeStores(conf, volumes) // logs errors but doesn't ever bail out if it has an issue
 
+	d.reportProgress("Deleting networks", 65)
 	if err = d.deleteNetworkDevices(vmm, conf); err != nil {
 		errs = append(errs, err.Error())
 	}
@@ -78,13 +101,21 @@ func (d *Dispatcher) DeleteVCH(conf *config.VirtualContainerHostConfigSpec) erro
 		}
 	}
 
+	d.reportProgress("Deleting appliance", 80)
 	err = d.deleteVM(vmm, true)
 	if err != nil {
 		log.Debugf("Error deleting appliance VM %s", err)
 		return err
 	}
-	if err = d.destroyResourcePoolIfEmpty(conf); err != nil {
-		log.Warnf("VCH resource pool is not removed: %s", err)
+	d.reportProgress("Deleting resource pool", 90)
+	if rerr := d.deleteRules(conf); rerr != nil {
+		log.Warnf("DRS rules for VCH are not removed: %s", rerr)
+	}
+	if rerr := d.disableFirewallRules(conf); rerr != nil {
+		log.Warnf("Firewall rulesets enabled for VCH are not disabled: %s", rerr)
+	}
+	if rerr := d.destroyResourcePoolIfEmpty(conf); rerr != nil {
+		log.Warnf("VCH resource pool is not removed: %s", rerr)
 	}
 	return nil
 }
@@ -67,8 +67,8 @@ func TestDelete(t *testing.T) {
 			t.Fatal(err)
 		}
 		installSettings := &data.InstallerData{}
-		installSettings.ApplianceSize.CPU.Limit = 1
-		installSettings.ApplianceSize.Memory.Limit = 1024
+		installSettings.ApplianceCPUs = 1
+		installSettings.ApplianceMemoryMB = 1024
 		installSettings.ResourcePoolPath = path.Join(input.ComputeResourcePath, input.DisplayName)
 
 		validator, err := validate.NewValidator(ctx, input)
@@ -180,7 +180,7 @@ func testDeleteVCH(v *validate.Validator, conf *config.VirtualContainerHostConfi
 		force:   false,
 	}
 	// failed to get vm FolderName, that will eventually cause panic in simulator to delete empty datastore file
-	if err := d.DeleteVCH(conf); err != nil {
+	if err := d.DeleteVCH(conf, VolumesDefault); err != nil {
 		t.Errorf("Failed to get VCH: %s", err)
 		return
 	}
@@ -22,6 +22,7 @@ import (
 	"net"
 	"os"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 
@@ -37,6 +38,27 @@ import (
 	"golang.org/x/net/context"
 )
 
+// defaultDockerAPIAttemptTimeout keeps a single unresponsive request from consuming the whole CheckDockerAPI budget
+const defaultDockerAPIAttemptTimeout = 10 * time.Second
+
+// ConflictError is returned when an operation conflicts with the state of the target, such as a VCH
+// name already in use or another operation in progress on the VCH
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// Conflict reports that the error is a conflict with the state of the target
+func (e *ConflictError) Conflict() bool {
+	return true
+}
+
+// Dispatcher carries out VIC management operations over a vSphere session. It holds the state of
+// the operation in progress, so a dispatcher runs one operation at a time. Operations that run
+// concurrently each need a dispatcher of their own, see WithContext.
 type Dispatcher struct {
 	session *session.Session
 	ctx     context.Context
@@ -52,13 +74,38 @@ type Dispatcher struct {
 	HostIP        string
 	VICAdminProto string
 
+	// DockerAPITimeout bounds the overall time CheckDockerAPI waits for the docker endpoint, in addition
+	// to the dispatcher context. DockerAPIAttemptTimeout bounds each individual request. Zero disables either.
+	DockerAPITimeout        time.Duration
+	DockerAPIAttemptTimeout time.Duration
+
+	// ComponentTimeouts bounds the time spent waiting for each appliance component to launch, keyed by
+	// component name. Components without a timeout are bounded only by the dispatcher context.
+	ComponentTimeouts map[string]time.Duration
+
+	// Progress, if set, receives the progress events of the operations run by the dispatcher
+	Progress ProgressFunc
+
 	vchPool   *object.ResourcePool
 	vchVapp   *object.VirtualApp
 	appliance *vm.VirtualMachine
 
 	oldApplianceISO string
+	// obsoleteKeys are extraconfig keys from an earlier schema, removed when the config is next written
+	obsoleteKeys []string
+
+	// undo holds the rollback actions for resources created by the current operation
+	undo rollbackStack
+	// checkpoint is the last step completed in creating the appliance, see checkpointKey
+	checkpoint string
 
 	sshEnabled bool
+
+	// diagnosticLogs are the vSphere logs collected if the operation fails, by host or vCenter
+	diagnosticLogs map[string]*diagnosticLog
+
+	// progress is the step reached by the current operation, see reportProgress
+	progress progress
 }
 
 type diagnosticLog struct {
@@ -69,8 +116,6 @@ type diagnosticLog struct {
 	collect bool
 }
 
-var diagnosticLogs = make(map[string]*diagnosticLog)
-
 // NewDispatcher creates a dispatcher that can act upon VIC management operations.
 // clientCert is an optional client certificate to allow interaction with the Docker API for verification
 // force will ignore some errors
@@ -82,6 +127,8 @@ func NewDispatcher(ctx context.Context, s *session.Session, conf *config.Virtual
 		ctx:     ctx,
 		isVC:    isVC,
 		force:   force,
+
+		DockerAPIAttemptTimeout: defaultDockerAPIAttemptTimeout,
 	}
 	if conf != nil {
 		e.InitDiagnosticLogs(conf)
@@ -89,11 +136,42 @@ func NewDispatcher(ctx context.Context, s *session.Session, conf *config.Virtual
 	return e
 }
 
+// WithContext returns a dispatcher for a separate operation over the same connection as d, bound
+// by ctx. The session is copied, along with its configuration and finder, as operations record
+// the datastore, datacenter and other state they act on in them. Settings of d carry over, but
+// none of the state of its operations.
+func (d *Dispatcher) WithContext(ctx context.Context) *Dispatcher {
+	s := *d.session
+	if s.Config != nil {
+		c := *s.Config
+		s.Config = &c
+	}
+	if s.Finder != nil {
+		f := *s.Finder
+		s.Finder = &f
+	}
+
+	return &Dispatcher{
+		session: &s,
+		ctx:     ctx,
+		isVC:    d.isVC,
+		force:   d.force,
+
+		DockerAPITimeout:        d.DockerAPITimeout,
+		DockerAPIAttemptTimeout: d.DockerAPIAttemptTimeout,
+		ComponentTimeouts:       d.ComponentTimeouts,
+		Progress:                d.Progress,
+	}
+}
+
 // Get the current log header LineEnd of the hostd/vpxd logs.
 // With this we avoid collecting log file data that existed prior to install.
 func (d *Dispatcher) InitDiagnosticLogs(conf *config.VirtualContainerHostConfigSpec) {
 	defer trace.End(trace.Begin(""))
 
+	d.diagnosticLogs = make(map[string]*diagnosticLog)
+	diagnosticLogs := d.diagnosticLogs
+
 	if d.isVC {
 		diagnosticLogs[d.session.ServiceContent.About.InstanceUuid] =
 			&diagnosticLog{"vpxd:vpxd.log", "vpxd.log", 0, nil, true}
@@ -166,7 +244,7 @@ func (d *Dispatcher) CollectDiagnosticLogs() {
 
 	m := diagnostic.NewDiagnosticManager(d.session)
 
-	for k, l := range diagnosticLogs {
+	for k, l := range d.diagnosticLogs {
 		if l == nil || !l.collect {
 			continue
 		}
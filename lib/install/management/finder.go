@@ -143,9 +143,12 @@ func (d *Dispatcher) GetVCHConfig(vm *vm.VirtualMachine) (*config.VirtualContain
 		return nil, err
 	}
 
-	data := vmomi.OptionValueSource(mapConfig)
+	kv := vmomi.OptionValueMap(mapConfig)
+	// rename keys written by earlier versions, recording the old keys so upgrade can remove them
+	kv, d.obsoleteKeys = migrateExtraConfig(kv, extraConfigRenames)
+
 	vchConfig := &config.VirtualContainerHostConfigSpec{}
-	result := extraconfig.Decode(data, vchConfig)
+	result := extraconfig.Decode(extraconfig.MapSource(kv), vchConfig)
 	if result == nil {
 		err = errors.Errorf("Failed to decode VM configuration %q: %s", vm.Reference(), err)
 		log.Error(err)
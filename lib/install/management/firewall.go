@@ -0,0 +1,170 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/lib/portlayer/constants"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// serialOverLANRule is the rule the host firewalls must permit for containerVMs to reach the appliance
+var serialOverLANRule = types.HostFirewallRule{
+	Port:      constants.SerialOverLANPort,
+	PortType:  types.HostFirewallRulePortTypeDst,
+	Protocol:  string(types.HostFirewallRuleProtocolTcp),
+	Direction: types.HostFirewallRuleDirectionOutbound,
+}
+
+// remoteSerialPortRuleset is the ESXi ruleset intended for serial ports over the network, which is
+// preferred over any other ruleset covering the port
+const remoteSerialPortRuleset = "remoteSerialPort"
+
+// serialOverLANRuleset chooses the ruleset to enable from the rulesets matching serialOverLANRule
+func serialOverLANRuleset(rulesets object.HostFirewallRulesetList) (string, error) {
+	disabled := rulesets.ByRule(serialOverLANRule).Disabled()
+	if len(disabled) == 0 {
+		return "", errors.Errorf("no firewall ruleset permits %d/tcp outbound", serialOverLANRule.Port)
+	}
+
+	for _, rs := range disabled {
+		if rs.Key == remoteSerialPortRuleset {
+			return rs.Key, nil
+		}
+	}
+	return disabled[0].Key, nil
+}
+
+// firewallRulesetID identifies a ruleset on a host in the VCH configuration
+func firewallRulesetID(host types.ManagedObjectReference, key string) string {
+	return fmt.Sprintf("%s/%s", host.Value, key)
+}
+
+// parseFirewallRulesetID splits an ID from firewallRulesetID into the host reference and ruleset key
+func parseFirewallRulesetID(id string) (types.ManagedObjectReference, string, error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.ManagedObjectReference{}, "", errors.Errorf("invalid firewall ruleset %q", id)
+	}
+	return types.ManagedObjectReference{Type: "HostSystem", Value: parts[0]}, parts[1], nil
+}
+
+// enableFirewallRules enables a ruleset permitting serial-over-LAN on the hosts whose firewall
+// blocks it. The rulesets enabled are recorded in conf so that deleting the VCH disables them again.
+func (d *Dispatcher) enableFirewallRules(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	if !settings.AllowFirewall {
+		return nil
+	}
+
+	hosts, err := d.session.Datastore.AttachedClusterHosts(d.ctx, d.session.Cluster)
+	if err != nil {
+		return errors.Errorf("Failed to get the hosts attached to the datastore: %s", err)
+	}
+
+	for _, host := range hosts {
+		fs, err := host.ConfigManager().FirewallSystem(d.ctx)
+		if err != nil {
+			return err
+		}
+		info, err := fs.Info(d.ctx)
+		if err != nil {
+			return err
+		}
+
+		rulesets := object.HostFirewallRulesetList(info.Ruleset)
+		if len(rulesets.ByRule(serialOverLANRule).Enabled()) > 0 {
+			continue
+		}
+
+		key, err := serialOverLANRuleset(rulesets)
+		if err != nil {
+			return errors.Errorf("Unable to open the firewall on %q: %s", host.InventoryPath, err)
+		}
+
+		log.Infof("Enabling firewall ruleset %q on %q", key, host.InventoryPath)
+		if err = fs.EnableRuleset(d.ctx, key); err != nil {
+			return errors.Errorf("Failed to enable firewall ruleset %q on %q: %s", key, host.InventoryPath, err)
+		}
+
+		id := firewallRulesetID(host.Reference(), key)
+		conf.FirewallRulesets = append(conf.FirewallRulesets, id)
+		d.undo.push(fmt.Sprintf("firewall ruleset %q on %q", key, host.InventoryPath), func() error {
+			return d.disableFirewallRuleset(id)
+		})
+	}
+	return nil
+}
+
+// disableFirewallRules disables the rulesets enabled when the VCH was created. They are left enabled
+// while other VCHs remain, as those may depend on them.
+func (d *Dispatcher) disableFirewallRules(conf *config.VirtualContainerHostConfigSpec) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	if len(conf.FirewallRulesets) == 0 {
+		return nil
+	}
+
+	vchs, err := d.SearchVCHs("")
+	if err != nil {
+		return err
+	}
+	if len(vchs) > 0 {
+		log.Infof("Leaving firewall rulesets enabled for the %d remaining VCHs", len(vchs))
+		return nil
+	}
+
+	var errs []string
+	for _, id := range conf.FirewallRulesets {
+		if err = d.disableFirewallRuleset(id); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// disableFirewallRuleset disables a ruleset identified by firewallRulesetID
+func (d *Dispatcher) disableFirewallRuleset(id string) error {
+	ref, key, err := parseFirewallRulesetID(id)
+	if err != nil {
+		return err
+	}
+
+	host := object.NewHostSystem(d.session.Vim25(), ref)
+	fs, err := host.ConfigManager().FirewallSystem(d.ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Disabling firewall ruleset %q on host %s", key, ref.Value)
+	if err = fs.DisableRuleset(d.ctx, key); err != nil {
+		return errors.Errorf("Failed to disable firewall ruleset %q on host %s: %s", key, ref.Value, err)
+	}
+	return nil
+}
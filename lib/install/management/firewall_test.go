@@ -0,0 +1,76 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestSerialOverLANRuleset(t *testing.T) {
+	outbound := func(key string, port, end int32, enabled bool) types.HostFirewallRuleset {
+		return types.HostFirewallRuleset{
+			Key:     key,
+			Enabled: enabled,
+			Rule: []types.HostFirewallRule{{
+				Port:      port,
+				EndPort:   end,
+				PortType:  types.HostFirewallRulePortTypeDst,
+				Protocol:  string(types.HostFirewallRuleProtocolTcp),
+				Direction: types.HostFirewallRuleDirectionOutbound,
+			}},
+		}
+	}
+
+	rulesets := object.HostFirewallRulesetList{
+		outbound("sshClient", 22, 0, false),
+		outbound("vMotion", 2000, 8000, false),
+		outbound(remoteSerialPortRuleset, 1024, 65535, false),
+	}
+	key, err := serialOverLANRuleset(rulesets)
+	if assert.NoError(t, err) {
+		assert.Equal(t, remoteSerialPortRuleset, key)
+	}
+
+	key, err = serialOverLANRuleset(rulesets[:2])
+	if assert.NoError(t, err) {
+		assert.Equal(t, "vMotion", key)
+	}
+
+	_, err = serialOverLANRuleset(object.HostFirewallRulesetList{outbound("webAccess", 2378, 0, false), outbound("vMotion", 2000, 8000, true)})
+	assert.Error(t, err)
+}
+
+func TestFirewallRulesetID(t *testing.T) {
+	host := types.ManagedObjectReference{Type: "HostSystem", Value: "host-21"}
+
+	id := firewallRulesetID(host, remoteSerialPortRuleset)
+	assert.Equal(t, "host-21/remoteSerialPort", id)
+
+	ref, key, err := parseFirewallRulesetID(id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, host, ref)
+		assert.Equal(t, remoteSerialPortRuleset, key)
+	}
+
+	for _, id := range []string{"", "host-21", "/remoteSerialPort", "host-21/"} {
+		_, _, err = parseFirewallRulesetID(id)
+		assert.Error(t, err, id)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+)
+
+// applianceFolder returns the VM folder the appliance, or its virtual app, is created in. This is the
+// datacenter VM folder unless the configuration names a folder below it, in which case any folders
+// missing from that path are created.
+func (d *Dispatcher) applianceFolder(conf *config.VirtualContainerHostConfigSpec) (*object.Folder, error) {
+	defer trace.End(trace.Begin(conf.VMFolder))
+
+	folder := d.session.Folders(d.ctx).VmFolder
+	if conf.VMFolder == "" {
+		return folder, nil
+	}
+
+	for _, name := range strings.Split(conf.VMFolder, "/") {
+		folderPath := path.Join(folder.InventoryPath, name)
+
+		child, err := d.session.Finder.Folder(d.ctx, folderPath)
+		if err == nil {
+			folder = child
+			continue
+		}
+		if _, ok := err.(*find.NotFoundError); !ok {
+			return nil, errors.Errorf("Failed to find VM folder %q: %s", folderPath, err)
+		}
+
+		log.Infof("Creating VM folder %q", folderPath)
+		if child, err = folder.CreateFolder(d.ctx, name); err != nil {
+			return nil, errors.Errorf("Failed to create VM folder %q: %s", folderPath, err)
+		}
+		child.InventoryPath = folderPath
+
+		// only folders created here are removed on rollback, and only once the appliance is gone
+		d.undo.push(fmt.Sprintf("VM folder %q", folderPath), func() error {
+			_, err := tasks.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+				return child.Destroy(ctx)
+			})
+			return err
+		})
+		folder = child
+	}
+
+	return folder, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/vsphere/simulator"
+)
+
+func TestApplianceFolder(t *testing.T) {
+	ctx := context.Background()
+
+	model := simulator.VPX()
+	defer model.Remove()
+	require.NoError(t, model.Create())
+
+	s := model.Service.NewServer()
+	defer s.Close()
+	s.URL.User = url.UserPassword("user", "pass")
+	s.URL.Path = ""
+
+	validator, err := validate.CreateNoDCCheck(ctx, getVPXData(s.URL))
+	require.NoError(t, err)
+
+	d := &Dispatcher{
+		session: validator.Session,
+		ctx:     validator.Context,
+		isVC:    validator.Session.IsVC(),
+	}
+	root := d.session.Folders(ctx).VmFolder
+
+	// no folder is the datacenter VM folder
+	conf := &config.VirtualContainerHostConfigSpec{}
+	folder, err := d.applianceFolder(conf)
+	require.NoError(t, err)
+	assert.Equal(t, root.Reference(), folder.Reference())
+
+	// missing folders are created, and rolled back
+	conf.VMFolder = "vic/production"
+	folder, err = d.applianceFolder(conf)
+	require.NoError(t, err)
+	assert.Equal(t, root.InventoryPath+"/vic/production", folder.InventoryPath)
+	assert.Len(t, d.undo.steps, 2)
+
+	found, err := d.session.Finder.Folder(ctx, folder.InventoryPath)
+	require.NoError(t, err)
+	assert.Equal(t, folder.Reference(), found.Reference())
+
+	// existing folders are reused, and left alone on rollback
+	d.undo.reset()
+	again, err := d.applianceFolder(conf)
+	require.NoError(t, err)
+	assert.Equal(t, folder.Reference(), again.Reference())
+	assert.Empty(t, d.undo.steps)
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+const (
+	// HealthHealthy means the component is running and its heartbeat is current
+	HealthHealthy = "healthy"
+	// HealthDegraded means the component failed to launch, or has stopped publishing its heartbeat
+	HealthDegraded = "degraded"
+	// HealthUnknown means the component has not published a heartbeat, such as on older appliances
+	HealthUnknown = "unknown"
+)
+
+// staleHeartbeat is the age at which a heartbeat is considered missed. This allows for a couple of
+// late updates and some clock skew between the appliance and vSphere.
+const staleHeartbeat = 3 * executor.HeartbeatInterval
+
+// componentHealth determines the health of a component session from its launch status and heartbeat,
+// returning the health and, if not healthy, the reason
+func componentHealth(session *executor.SessionConfig, now time.Time) (string, string) {
+	if session.Started != "true" {
+		return HealthDegraded, sessionStatus(session)
+	}
+
+	heartbeat := session.Diagnostics.Heartbeat
+	if heartbeat.IsZero() {
+		return HealthUnknown, "no heartbeat published"
+	}
+
+	if age := now.Sub(heartbeat); age > staleHeartbeat {
+		return HealthDegraded, fmt.Sprintf("last heartbeat %s ago", age/time.Second*time.Second)
+	}
+
+	return HealthHealthy, ""
+}
+
+// componentStatus returns the launch state of the appliance components, sorted by name. If now is
+// not zero the health of each component is also assessed against that time.
+func componentStatus(conf *config.VirtualContainerHostConfigSpec, now time.Time) []ComponentStatus {
+	var components []ComponentStatus
+
+	for name, session := range conf.ExecutorConfig.Sessions {
+		status := ComponentStatus{
+			Name:          name,
+			Status:        sessionStatus(session),
+			Started:       session.Started == "true",
+			Resurrections: session.Diagnostics.ResurrectionCount,
+		}
+
+		if heartbeat := session.Diagnostics.Heartbeat; !heartbeat.IsZero() {
+			status.Heartbeat = &heartbeat
+		}
+		if !now.IsZero() {
+			status.Health, status.HealthReason = componentHealth(session, now)
+		}
+
+		components = append(components, status)
+	}
+	sort.Sort(byComponentName(components))
+
+	return components
+}
+
+// serverTime returns the current time according to vSphere, which the appliance clock is synchronized
+// with, falling back to the local time if that cannot be retrieved
+func (d *Dispatcher) serverTime() time.Time {
+	now, err := methods.GetCurrentTime(d.ctx, d.session.Vim25())
+	if err != nil || now == nil {
+		log.Debugf("Unable to get vSphere time, using local time: %s", err)
+		return time.Now()
+	}
+	return *now
+}
+
+// ComponentHealth refreshes the configuration from the appliance and assesses the health of each
+// appliance component from its heartbeat. The appliance must be powered on.
+func (d *Dispatcher) ComponentHealth(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec) ([]ComponentStatus, error) {
+	defer trace.End(trace.Begin(conf.Name))
+
+	d.appliance = vch
+	if err := d.applianceConfiguration(conf); err != nil {
+		return nil, fmt.Errorf("unable to retrieve configuration from appliance: %s", err)
+	}
+
+	state, err := vch.PowerState(d.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine power state: %s", err)
+	}
+	if state != types.VirtualMachinePowerStatePoweredOn {
+		return nil, fmt.Errorf("VCH is not powered on, state %s", state)
+	}
+
+	return componentStatus(conf, d.serverTime()), nil
+}
+
+// ShowHealth logs the health of each component, returning an error naming those that are degraded
+func ShowHealth(components []ComponentStatus) error {
+	var degraded []string
+
+	log.Info("")
+	log.Info("Component health:")
+	for _, c := range components {
+		switch c.Health {
+		case HealthHealthy:
+			log.Infof("  %s: %s", c.Name, c.Health)
+		case HealthDegraded:
+			log.Warnf("  %s: %s - %s", c.Name, c.Health, c.HealthReason)
+			degraded = append(degraded, c.Name)
+		default:
+			log.Infof("  %s: %s - %s", c.Name, c.Health, c.HealthReason)
+		}
+	}
+
+	if len(degraded) > 0 {
+		return fmt.Errorf("degraded components: %s", strings.Join(degraded, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+func TestComponentHealth(t *testing.T) {
+	now := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		started   string
+		heartbeat time.Time
+		health    string
+		reason    string
+	}{
+		{"true", now.Add(-executor.HeartbeatInterval), HealthHealthy, ""},
+		{"true", now.Add(-staleHeartbeat), HealthHealthy, ""},
+		{"true", now.Add(-5 * time.Minute), HealthDegraded, "last heartbeat 5m0s ago"},
+		{"true", time.Time{}, HealthUnknown, "no heartbeat published"},
+		{"", time.Time{}, HealthDegraded, "waiting to launch"},
+		{"exec format error", now, HealthDegraded, "exec format error"},
+	}
+
+	for _, te := range tests {
+		session := &executor.SessionConfig{Started: te.started}
+		session.Diagnostics.Heartbeat = te.heartbeat
+
+		health, reason := componentHealth(session, now)
+		assert.Equal(t, te.health, health, "started %q heartbeat %s", te.started, te.heartbeat)
+		assert.Equal(t, te.reason, reason, "started %q heartbeat %s", te.started, te.heartbeat)
+	}
+}
+
+func TestComponentStatus(t *testing.T) {
+	now := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	conf := &config.VirtualContainerHostConfigSpec{}
+	for _, name := range []string{"vicadmin", "port-layer", "docker-personality"} {
+		session := &executor.SessionConfig{Started: "true"}
+		session.Diagnostics.Heartbeat = now
+		conf.AddComponent(name, session)
+	}
+	conf.ExecutorConfig.Sessions["port-layer"].Diagnostics.Heartbeat = now.Add(-time.Hour)
+
+	// without a reference time health is not assessed
+	components := componentStatus(conf, time.Time{})
+	require.Len(t, components, 3)
+	assert.Equal(t, "docker-personality", components[0].Name)
+	for _, c := range components {
+		assert.Empty(t, c.Health)
+		assert.NotNil(t, c.Heartbeat)
+	}
+
+	components = componentStatus(conf, now)
+	require.Len(t, components, 3)
+	assert.Equal(t, HealthHealthy, components[0].Health)
+	assert.Equal(t, HealthDegraded, components[1].Health)
+	assert.Equal(t, HealthHealthy, components[2].Health)
+
+	err := ShowHealth(components)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "port-layer")
+	}
+
+	assert.NoError(t, ShowHealth(components[:1]))
+}
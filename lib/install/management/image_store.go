@@ -0,0 +1,232 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	portlayer "github.com/vmware/vic/lib/portlayer/storage"
+	"github.com/vmware/vic/lib/portlayer/storage/vsphere"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/datastore"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// What VerifyImageStores does with the damaged layers it finds
+const (
+	// ImageActionReport only reports the damaged layers
+	ImageActionReport = "report"
+	// ImageActionQuarantine moves the damaged layers out of the image store
+	ImageActionQuarantine = "quarantine"
+	// ImageActionRepair removes the damaged layers, so that they are pulled again
+	ImageActionRepair = "repair"
+)
+
+// ImageActions are the actions VerifyImageStores takes on damaged layers
+var ImageActions = []string{ImageActionReport, ImageActionQuarantine, ImageActionRepair}
+
+// ImageStoreReport is the result of verifying an image store of a VCH
+type ImageStoreReport struct {
+	// Path is the datastore path of the image store
+	Path string
+	// Layers is the number of layers checked
+	Layers int
+	// Faults are the damaged layers, and the layers built on them
+	Faults []vsphere.ImageFault `json:",omitempty"`
+	// Error is why the store could not be verified or repaired, if it could not
+	Error string `json:",omitempty"`
+}
+
+// imageStore is an image store of a VCH found by VerifyImageStores
+type imageStore struct {
+	ds   *datastore.Helper
+	name string
+}
+
+// ValidImageAction returns an error if action is not one of ImageActions
+func ValidImageAction(action string) error {
+	for _, a := range ImageActions {
+		if action == a {
+			return nil
+		}
+	}
+	return errors.Errorf("Invalid image store action %q, must be one of %s", action, strings.Join(ImageActions, ", "))
+}
+
+// VerifyImageStores checks the layers in the image stores of the VCH and reports those that are damaged.
+// With ImageActionQuarantine or ImageActionRepair the damaged layers are then moved aside or removed,
+// with the appliance powered off so that the portlayer reloads the image stores when it is powered on
+// again. The scratch layer that all layers are built on is never moved, as an image store cannot be used
+// without it.
+func (d *Dispatcher) VerifyImageStores(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec, action string) ([]ImageStoreReport, error) {
+	defer trace.End(trace.Begin(conf.Name))
+
+	if err := ValidImageAction(action); err != nil {
+		return nil, err
+	}
+
+	op := trace.NewOperation(d.ctx, "verify image stores")
+
+	var reports []ImageStoreReport
+	var damaged []int
+	var stores []imageStore
+	for i := range conf.ImageStores {
+		found, err := d.imageStores(op, &conf.ImageStores[i])
+		if err != nil {
+			reports = append(reports, ImageStoreReport{Path: conf.ImageStores[i].String(), Error: err.Error()})
+			continue
+		}
+
+		for _, s := range found {
+			report := ImageStoreReport{Path: path.Join(s.ds.RootURL, s.name)}
+			log.Infof("Verifying image store %s", report.Path)
+
+			report.Faults, report.Layers, err = vsphere.VerifyImages(op, s.ds, s.name)
+			if err != nil {
+				report.Error = err.Error()
+			}
+			if len(report.Faults) > 0 {
+				damaged = append(damaged, len(reports))
+				stores = append(stores, s)
+			}
+			reports = append(reports, report)
+		}
+	}
+
+	if action == ImageActionReport || len(damaged) == 0 {
+		return reports, nil
+	}
+
+	d.appliance = vch
+	power, err := vch.PowerState(d.ctx)
+	if err != nil {
+		return reports, err
+	}
+	if power != types.VirtualMachinePowerStatePoweredOff {
+		log.Infof("Powering off the appliance to update its image stores")
+		if _, err = vch.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+			return vch.PowerOff(ctx)
+		}); err != nil {
+			return reports, errors.Errorf("Failed to power off appliance: %s", err)
+		}
+	}
+
+	var errs []string
+	for i, r := range damaged {
+		s := stores[i]
+		if action == ImageActionQuarantine {
+			err = vsphere.QuarantineImages(op, s.ds, s.name, reports[r].Faults)
+		} else {
+			err = vsphere.RemoveImages(op, s.ds, s.name, reports[r].Faults)
+		}
+		if err != nil {
+			reports[r].Error = err.Error()
+			errs = append(errs, fmt.Sprintf("%s: %s", reports[r].Path, err))
+		}
+	}
+
+	if power != types.VirtualMachinePowerStatePoweredOff {
+		if err = d.startAppliance(conf); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return reports, errors.New(strings.Join(errs, "\n"))
+	}
+	return reports, nil
+}
+
+// imageStores returns the image stores at u, one for each VCH appliance that has used it
+func (d *Dispatcher) imageStores(op trace.Operation, u *url.URL) ([]imageStore, error) {
+	ds, err := d.session.Finder.Datastore(d.ctx, u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	helper, err := datastore.NewHelper(op, d.session, ds, path.Join(u.Path, vsphere.StorageParentDir))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := helper.Ls(op, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var stores []imageStore
+	for _, f := range res.File {
+		name := f.GetFileInfo().Path
+		if _, err := helper.Stat(op, path.Join(name, vsphere.StorageImageDir)); err != nil {
+			if err != os.ErrNotExist && !types.IsFileNotFound(err) {
+				return nil, err
+			}
+			continue
+		}
+		stores = append(stores, imageStore{ds: helper, name: name})
+	}
+
+	return stores, nil
+}
+
+// ShowImageStores logs the result of verifying the image stores, returning an error if any store could
+// not be verified, or if any layers are damaged and were left in place
+func ShowImageStores(reports []ImageStoreReport, action string) error {
+	var faults, failed int
+	for _, r := range reports {
+		log.Info("")
+		log.Infof("Image store %s:", r.Path)
+		if r.Error != "" {
+			log.Errorf("  %s", r.Error)
+			failed++
+		}
+		log.Infof("  %d layers, %d damaged", r.Layers, len(r.Faults))
+		for _, f := range r.Faults {
+			log.Warnf("  %s: %s", f.ID, f.Reason)
+			if f.ID == portlayer.Scratch.ID {
+				log.Warnf("  The scratch layer that all images are built on is damaged, so the VCH must be recreated")
+			}
+		}
+		faults += len(r.Faults)
+	}
+
+	if failed > 0 {
+		return errors.New("Image stores could not be verified")
+	}
+	if faults == 0 {
+		return nil
+	}
+
+	switch action {
+	case ImageActionQuarantine:
+		log.Infof("Damaged layers were moved to the %s directory of their image store", vsphere.QuarantineDir)
+	case ImageActionRepair:
+		log.Infof("Damaged layers were removed, and are pulled again by the next pull of the images using them")
+	default:
+		return errors.New("Image stores have damaged layers")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/portlayer/storage/vsphere"
+)
+
+func TestValidImageAction(t *testing.T) {
+	for _, action := range ImageActions {
+		assert.NoError(t, ValidImageAction(action))
+	}
+	assert.Error(t, ValidImageAction(""))
+	assert.Error(t, ValidImageAction("delete"))
+}
+
+func TestShowImageStores(t *testing.T) {
+	intact := []ImageStoreReport{{Path: "[datastore1] vch/VIC/store", Layers: 3}}
+	damaged := []ImageStoreReport{{Path: "[datastore1] vch/VIC/store", Layers: 3, Faults: []vsphere.ImageFault{{ID: "a", Reason: "missing disk"}}}}
+	failed := []ImageStoreReport{{Path: "ds://datastore2/vch", Error: "datastore not found"}}
+
+	assert.NoError(t, ShowImageStores(intact, ImageActionReport))
+	assert.Error(t, ShowImageStores(damaged, ImageActionReport))
+	assert.NoError(t, ShowImageStores(damaged, ImageActionRepair))
+	assert.NoError(t, ShowImageStores(damaged, ImageActionQuarantine))
+	assert.Error(t, ShowImageStores(failed, ImageActionRepair))
+}
@@ -37,6 +37,9 @@ import (
 func (d *Dispatcher) InspectVCH(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec) error {
 	defer trace.End(trace.Begin(conf.Name))
 
+	// a panicked appliance may have been reset, or be hung while still powered on
+	d.showPanic(vch, conf)
+
 	state, err := vch.PowerState(d.ctx)
 	if err != nil {
 		log.Errorf("Failed to get VM power state, service might not be available at this moment.")
@@ -47,6 +50,23 @@ func (d *Dispatcher) InspectVCH(vch *vm.VirtualMachine, conf *config.VirtualCont
 		return err
 	}
 
+	if conf.VMFolder != "" {
+		log.Info("")
+		log.Infof("VM folder: %s", conf.VMFolder)
+	}
+
+	d.showToolsStatus(vch, conf)
+
+	// degraded components are reported as warnings here, inspect --health fails on them
+	_ = ShowHealth(componentStatus(conf, d.serverTime()))
+
+	if conf.ImageStoragePolicy != "" {
+		log.Info("")
+		log.Infof("Image store policy: %s", conf.ImageStoragePolicy)
+	}
+
+	showVolumeStores(d.VolumeStores(conf))
+
 	clientIP := conf.ExecutorConfig.Networks["client"].Assigned.IP
 	externalIP := conf.ExecutorConfig.Networks["external"].Assigned.IP
 
@@ -62,6 +82,44 @@ func (d *Dispatcher) InspectVCH(vch *vm.VirtualMachine, conf *config.VirtualCont
 		return err
 	}
 
+	d.setHostAddress(conf)
+	d.ShowVCH(conf, "", "", "", "")
+	return nil
+}
+
+// showToolsStatus reports the guest tools state of the appliance and the container VMs that are
+// powered on without running tools
+func (d *Dispatcher) showToolsStatus(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec) {
+	tools, err := vch.ToolsStatus(d.ctx)
+	if err != nil {
+		log.Warnf("Unable to get appliance tools status: %s", err)
+		return
+	}
+
+	log.Info("")
+	log.Infof("Appliance tools: %s", tools)
+	if !tools.Running() {
+		log.Warnf("Appliance %s", toolsNotRunning)
+	}
+
+	containers, err := d.containerVMStatus(vch, conf)
+	if err != nil {
+		log.Warnf("Unable to get container VM tools status: %s", err)
+		return
+	}
+
+	for _, c := range containers {
+		if c.ToolsWarning != "" {
+			log.Warnf("Container VM %s: %s", c.Name, c.ToolsWarning)
+		}
+	}
+}
+
+// setHostAddress configures the address, ports and protocol used to reach the appliance from the
+// assigned client IP, preferring a name from the host certificate if there is one
+func (d *Dispatcher) setHostAddress(conf *config.VirtualContainerHostConfigSpec) {
+	clientIP := conf.ExecutorConfig.Networks["client"].Assigned.IP
+
 	d.HostIP = clientIP.String()
 	log.Debugf("IP address for client interface: %s", d.HostIP)
 	if !conf.HostCertificate.IsNil() {
@@ -90,9 +148,6 @@ func (d *Dispatcher) InspectVCH(vch *vm.VirtualMachine, conf *config.VirtualCont
 	} else {
 		log.Debugf("Failed to load host cert: %s", err)
 	}
-
-	d.ShowVCH(conf, "", "", "", "")
-	return nil
 }
 
 func (d *Dispatcher) ShowVCH(conf *config.VirtualContainerHostConfigSpec, key string, cert string, cacert string, envfile string) {
@@ -104,7 +159,7 @@ func (d *Dispatcher) ShowVCH(conf *config.VirtualContainerHostConfigSpec, key st
 
 	log.Infof("")
 	log.Infof("vic-admin portal:")
-	log.Infof("%s://%s:2378", d.VICAdminProto, d.HostIP)
+	log.Infof("%s://%s", d.VICAdminProto, net.JoinHostPort(d.HostIP, "2378"))
 
 	log.Infof("")
 	externalIP := conf.ExecutorConfig.Networks["external"].Assigned.IP
@@ -136,7 +191,7 @@ func (d *Dispatcher) ShowVCH(conf *config.VirtualContainerHostConfigSpec, key st
 		}
 	}
 
-	dEnv = append(dEnv, fmt.Sprintf("DOCKER_HOST=%s:%s", d.HostIP, d.DockerPort))
+	dEnv = append(dEnv, fmt.Sprintf("DOCKER_HOST=%s", net.JoinHostPort(d.HostIP, d.DockerPort)))
 	log.Info("")
 	log.Infof("Docker environment variables:")
 	log.Info(strings.Join(dEnv, " "))
@@ -149,5 +204,5 @@ func (d *Dispatcher) ShowVCH(conf *config.VirtualContainerHostConfigSpec, key st
 
 	log.Infof("")
 	log.Infof("Connect to docker:")
-	log.Infof("docker -H %s:%s%s info", d.HostIP, d.DockerPort, tls)
+	log.Infof("docker -H %s%s info", net.JoinHostPort(d.HostIP, d.DockerPort), tls)
 }
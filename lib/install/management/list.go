@@ -0,0 +1,156 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"net"
+	"path"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/docker/docker/opts"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/ip"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/version"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// VCHListing summarises a VCH, as reported by vic-machine ls
+type VCHListing struct {
+	ID            string `json:"id"`
+	Path          string `json:"path"`
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Address       string `json:"address,omitempty"`
+	DockerHost    string `json:"docker_host,omitempty"`
+	UpgradeStatus string `json:"upgrade_status"`
+	// ReleaseStatus reports whether a newer release is published at the release URL of the VCH,
+	// empty if the VCH has no release URL
+	ReleaseStatus string `json:"release_status,omitempty"`
+}
+
+// ListVCHs finds the VCHs under the compute resource at computePath, or in the datacenter of the
+// session or all datacenters if computePath is empty, and summarises each of them. The upgrade
+// status is relative to the installer version, the release status to the release published at the
+// release URL of each VCH.
+func (d *Dispatcher) ListVCHs(computePath string, installerVer *version.Build) ([]VCHListing, error) {
+	defer trace.End(trace.Begin(computePath))
+
+	vchs, err := d.SearchVCHs(computePath)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := &version.ReleaseChecker{}
+	listing := make([]VCHListing, 0, len(vchs))
+	for _, vch := range vchs {
+		listing = append(listing, d.describeVCH(vch, installerVer, releases))
+	}
+	return listing, nil
+}
+
+// describeVCH summarises the VCH, reporting what it cannot determine as unknown
+func (d *Dispatcher) describeVCH(vch *vm.VirtualMachine, installerVer *version.Build, releases *version.ReleaseChecker) VCHListing {
+	l := VCHListing{
+		ID:            vch.Reference().Value,
+		Path:          path.Dir(path.Dir(vch.InventoryPath)),
+		Name:          path.Base(vch.InventoryPath),
+		Version:       "unknown",
+		UpgradeStatus: "Unknown",
+	}
+
+	conf, err := d.GetVCHConfig(vch)
+	if err != nil {
+		log.Errorf("Failed to get configuration of VCH %q: %s", l.Name, err)
+		return l
+	}
+	if conf.Version != nil {
+		l.Version = conf.Version.ShortVersion()
+	}
+	l.UpgradeStatus = d.upgradeStatusMessage(vch, installerVer, conf.Version)
+	if conf.ReleaseURL.Host != "" && conf.Version != nil {
+		l.ReleaseStatus = releases.Status(d.ctx, &conf.ReleaseURL, conf.Version)
+	}
+
+	// the assigned address is stale once the appliance is off
+	state, err := vch.PowerState(d.ctx)
+	if err != nil {
+		log.Debugf("Unable to determine power state of VCH %q: %s", l.Name, err)
+		return l
+	}
+	if state == types.VirtualMachinePowerStatePoweredOn {
+		l.Address, l.DockerHost = clientEndpoint(conf)
+	}
+	return l
+}
+
+// clientEndpoint returns the client address of the appliance and the docker endpoint at that
+// address, or empty strings if no address is assigned yet
+func clientEndpoint(conf *config.VirtualContainerHostConfigSpec) (string, string) {
+	client := conf.ExecutorConfig.Networks["client"]
+	if client == nil || ip.IsUnspecifiedIP(client.Assigned.IP) {
+		return "", ""
+	}
+
+	port := opts.DefaultHTTPPort
+	if !conf.HostCertificate.IsNil() {
+		port = opts.DefaultTLSHTTPPort
+	}
+
+	address := client.Assigned.IP.String()
+	return address, net.JoinHostPort(address, fmt.Sprintf("%d", port))
+}
+
+// upgradeStatusMessage generates a user facing status string about upgrade progress and status
+func (d *Dispatcher) upgradeStatusMessage(vch *vm.VirtualMachine, installerVer *version.Build, vchVer *version.Build) string {
+	if vchVer == nil {
+		return "Unknown: VCH version not found"
+	}
+
+	if sameVer := installerVer.Equal(vchVer); sameVer {
+		return "Up to date"
+	}
+
+	upgrading, _, err := vch.UpgradeInProgress(d.ctx, UpgradePrefix)
+	if err != nil {
+		return fmt.Sprintf("Unknown: %s", err)
+	}
+	if upgrading {
+		return "Upgrade in progress"
+	}
+
+	canUpgrade, err := installerVer.IsNewer(vchVer)
+	if err != nil {
+		return fmt.Sprintf("Unknown: %s", err)
+	}
+	if canUpgrade {
+		return fmt.Sprintf("Upgradeable to %s", installerVer.ShortVersion())
+	}
+
+	oldInstaller, err := installerVer.IsOlder(vchVer)
+	if err != nil {
+		return fmt.Sprintf("Unknown: %s", err)
+	}
+	if oldInstaller {
+		return "VCH has newer version"
+	}
+
+	// can't get here
+	return "Invalid upgrade status"
+}
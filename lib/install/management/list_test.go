@@ -0,0 +1,57 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/version"
+)
+
+func TestClientEndpoint(t *testing.T) {
+	conf := testConfig()
+
+	// no address assigned yet
+	address, docker := clientEndpoint(conf)
+	assert.Empty(t, address)
+	assert.Empty(t, docker)
+
+	conf.ExecutorConfig.Networks["client"].Assigned = net.IPNet{IP: net.ParseIP("10.0.0.5"), Mask: net.CIDRMask(24, 32)}
+	address, docker = clientEndpoint(conf)
+	assert.Equal(t, "10.0.0.5", address)
+	assert.Equal(t, "10.0.0.5:2375", docker)
+
+	conf.HostCertificate = &config.RawCertificate{Cert: []byte("cert"), Key: []byte("key")}
+	_, docker = clientEndpoint(conf)
+	assert.Equal(t, "10.0.0.5:2376", docker)
+
+	delete(conf.ExecutorConfig.Networks, "client")
+	address, docker = clientEndpoint(conf)
+	assert.Empty(t, address)
+	assert.Empty(t, docker)
+}
+
+func TestUpgradeStatusMessage(t *testing.T) {
+	d := &Dispatcher{}
+	installer := &version.Build{Version: "v0.8.0", BuildNumber: "100"}
+
+	// neither case requires the appliance
+	assert.Equal(t, "Up to date", d.upgradeStatusMessage(nil, installer, &version.Build{BuildNumber: "100"}))
+	assert.Contains(t, d.upgradeStatusMessage(nil, installer, nil), "Unknown")
+}
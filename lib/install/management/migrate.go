@@ -0,0 +1,111 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// keyRename records an extraconfig key, and all keys nested below it, being renamed between releases
+type keyRename struct {
+	From string
+	To   string
+}
+
+// extraConfigRenames lists the extraconfig keys renamed since the first release, oldest first so that
+// chained renames resolve to the current key. Add an entry here whenever a config field's key tag changes.
+var extraConfigRenames = []keyRename{
+	// the bridge network range and width keys were the only ones not in snake case
+	{From: "guestinfo.vice./network/bridge-ip-range", To: "guestinfo.vice./network/bridge_ip_range"},
+	{From: "guestinfo.vice./network/bridge-net-width", To: "guestinfo.vice./network/bridge_net_width"},
+}
+
+// migrateExtraConfig rewrites keys from earlier config schemas to their current names so that the
+// configuration decodes with the current structures. It returns the migrated configuration, leaving
+// kv untouched, and the obsolete keys, which must be removed from the appliance when the migrated
+// configuration is written back.
+func migrateExtraConfig(kv map[string]string, renames []keyRename) (map[string]string, []string) {
+	current := kv
+	for _, r := range renames {
+		// each rename builds a new map so a renamed key is never revisited by the same rename
+		next := make(map[string]string, len(current))
+		var moved map[string]string
+
+		for k, v := range current {
+			if !hasKeyPrefix(k, r.From) {
+				next[k] = v
+				continue
+			}
+
+			nk := r.To + k[len(r.From):]
+			log.Debugf("Migrating extraconfig key %q to %q", k, nk)
+
+			if moved == nil {
+				moved = make(map[string]string)
+			}
+			moved[nk] = v
+		}
+
+		// a value already stored under the new key takes precedence
+		for k, v := range moved {
+			if _, ok := next[k]; !ok {
+				next[k] = v
+			}
+		}
+		current = next
+	}
+
+	// only keys present on the appliance need removing, and a later rename may
+	// have moved a key back to an earlier name
+	var keys []string
+	for k := range kv {
+		if _, ok := current[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return current, keys
+}
+
+// hasKeyPrefix returns true if key is prefix itself or a key nested below it
+func hasKeyPrefix(key, prefix string) bool {
+	if !strings.HasPrefix(key, prefix) {
+		return false
+	}
+	if len(key) == len(prefix) {
+		return true
+	}
+
+	switch key[len(prefix)] {
+	case '/', '|', '.', '~':
+		return true
+	}
+	return false
+}
+
+// removeKeysSpec returns the extraconfig entries that remove the given keys from a VM
+func removeKeysSpec(keys []string) []types.BaseOptionValue {
+	var options []types.BaseOptionValue
+	for _, k := range keys {
+		// vSphere removes an extraconfig key when it's set to the empty string
+		options = append(options, &types.OptionValue{Key: k, Value: ""})
+	}
+	return options
+}
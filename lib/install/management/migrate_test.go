@@ -0,0 +1,107 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+)
+
+func TestMigrateExtraConfig(t *testing.T) {
+	kv := map[string]string{
+		"guestinfo.vice./old":         "a",
+		"guestinfo.vice./old/nested":  "b",
+		"guestinfo.vice./older":       "untouched",
+		"guestinfo.vice./chain/first": "c",
+		"guestinfo.vice./kept":        "d",
+	}
+
+	renames := []keyRename{
+		{From: "guestinfo.vice./old", To: "guestinfo.vice./new"},
+		{From: "guestinfo.vice./chain", To: "guestinfo.vice./middle"},
+		{From: "guestinfo.vice./middle", To: "guestinfo.vice./last"},
+	}
+
+	migrated, obsolete := migrateExtraConfig(kv, renames)
+
+	assert.Len(t, kv, 5)
+	assert.Equal(t, "a", kv["guestinfo.vice./old"])
+
+	assert.Equal(t, map[string]string{
+		"guestinfo.vice./new":        "a",
+		"guestinfo.vice./new/nested": "b",
+		"guestinfo.vice./older":      "untouched",
+		"guestinfo.vice./last/first": "c",
+		"guestinfo.vice./kept":       "d",
+	}, migrated)
+
+	assert.Equal(t, []string{
+		"guestinfo.vice./chain/first",
+		"guestinfo.vice./old",
+		"guestinfo.vice./old/nested",
+	}, obsolete)
+
+	for _, o := range removeKeysSpec(obsolete) {
+		assert.Equal(t, "", o.GetOptionValue().Value)
+	}
+}
+
+func TestMigrateExtraConfigPrecedence(t *testing.T) {
+	kv := map[string]string{
+		"old": "stale",
+		"new": "current",
+	}
+
+	migrated, obsolete := migrateExtraConfig(kv, []keyRename{{From: "old", To: "new"}})
+
+	assert.Equal(t, map[string]string{"new": "current"}, migrated)
+	assert.Equal(t, []string{"old"}, obsolete)
+}
+
+func TestMigrateBridgeNetworkKeys(t *testing.T) {
+	_, bridgeRange, _ := net.ParseCIDR("172.16.0.0/12")
+	width := net.CIDRMask(16, 32)
+
+	conf := &config.VirtualContainerHostConfigSpec{}
+	conf.BridgeIPRange = bridgeRange
+	conf.BridgeNetworkWidth = &width
+
+	current := make(map[string]string)
+	extraconfig.Encode(extraconfig.MapSink(current), conf)
+
+	// write the configuration as a release using the dashed keys would have
+	earlier := make(map[string]string)
+	for k, v := range current {
+		k = strings.Replace(k, "/bridge_ip_range", "/bridge-ip-range", 1)
+		k = strings.Replace(k, "/bridge_net_width", "/bridge-net-width", 1)
+		earlier[k] = v
+	}
+	assert.NotEqual(t, current, earlier)
+
+	migrated, obsolete := migrateExtraConfig(earlier, extraConfigRenames)
+	assert.Equal(t, current, migrated)
+	assert.NotEmpty(t, obsolete)
+
+	decoded := &config.VirtualContainerHostConfigSpec{}
+	extraconfig.Decode(extraconfig.MapSource(migrated), decoded)
+	assert.Equal(t, bridgeRange.String(), decoded.BridgeIPRange.String())
+	assert.Equal(t, width.String(), decoded.BridgeNetworkWidth.String())
+}
@@ -15,15 +15,30 @@
 package management
 
 import (
+	"context"
+
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+)
+
+// Port bindings of a bridge port group created on a distributed switch
+const (
+	// PortBindingStatic assigns a port to the VM when it is connected, as vCenter defaults to
+	PortBindingStatic = "static"
+	// PortBindingEphemeral creates a port when the VM is powered on, and works while vCenter is down
+	PortBindingEphemeral = "ephemeral"
 )
 
+// bridgePortgroupPorts is the initial number of ports of a static bridge port group, which expands as needed
+const bridgePortgroupPorts = 128
+
 func (d *Dispatcher) createBridgeNetwork(conf *config.VirtualContainerHostConfigSpec) error {
 	defer trace.End(trace.Begin(""))
 
@@ -35,8 +50,11 @@ func (d *Dispatcher) createBridgeNetwork(conf *config.VirtualContainerHostConfig
 
 	// network didn't exist during validation given we don't have a moref, so create it
 	if d.session.Client.IsVC() {
-		// double check
-		return errors.New("bridge network must already exist for vCenter environments")
+		if conf.BridgeNetworkDVS == "" {
+			// double check
+			return errors.New("bridge network must already exist for vCenter environments")
+		}
+		return d.createBridgePortgroup(conf)
 	}
 
 	// in this case the name to use is held in container network ID
@@ -49,8 +67,10 @@ func (d *Dispatcher) createBridgeNetwork(conf *config.VirtualContainerHostConfig
 		return err
 	}
 
+	// the switch must carry the largest frames of the bridge network
 	if err = hostNetSystem.AddVirtualSwitch(d.ctx, name, &types.HostVirtualSwitchSpec{
 		NumPorts: 1024,
+		Mtu:      int32(bnet.Network.MTU),
 	}); err != nil {
 		err = errors.Errorf("Failed to add virtual switch (%q): %s", name, err)
 		return err
@@ -83,17 +103,72 @@ func (d *Dispatcher) createBridgeNetwork(conf *config.VirtualContainerHostConfig
 	return nil
 }
 
+// createBridgePortgroup creates the bridge port group on the distributed switch given at create
+func (d *Dispatcher) createBridgePortgroup(conf *config.VirtualContainerHostConfigSpec) error {
+	defer trace.End(trace.Begin(conf.BridgeNetworkDVS))
+
+	bnet := conf.ExecutorConfig.Networks[conf.BridgeNetwork]
+	name := bnet.Network.ID
+
+	var ref types.ManagedObjectReference
+	if !ref.FromString(conf.BridgeNetworkDVS) {
+		return errors.Errorf("Invalid distributed switch reference %q", conf.BridgeNetworkDVS)
+	}
+	dvs := object.NewDistributedVirtualSwitch(d.session.Vim25(), ref)
+
+	log.Infof("Creating distributed port group %q", name)
+	spec := bridgePortgroupSpec(name, conf.BridgeNetworkVLAN, conf.BridgeNetworkPortBinding)
+	_, err := tasks.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+		return dvs.AddPortgroup(ctx, []types.DVPortgroupConfigSpec{spec})
+	})
+	if err != nil {
+		return errors.Errorf("Failed to add distributed port group (%q): %s", name, err)
+	}
+
+	net, err := d.session.Finder.Network(d.ctx, name)
+	if err != nil {
+		return errors.Errorf("Failed to query distributed port group (%q): %s", name, err)
+	}
+
+	// assign the moref to the bridge network config on the appliance
+	bnet.ID = net.Reference().String()
+	bnet.Network.ID = net.Reference().String()
+	conf.CreateBridgeNetwork = true
+	return nil
+}
+
+// bridgePortgroupSpec returns the spec of a bridge port group with the VLAN ID, 0 for none, and port binding
+func bridgePortgroupSpec(name string, vlan int, binding string) types.DVPortgroupConfigSpec {
+	spec := types.DVPortgroupConfigSpec{
+		Name: name,
+		DefaultPortConfig: &types.VMwareDVSPortSetting{
+			Vlan: &types.VmwareDistributedVirtualSwitchVlanIdSpec{
+				VlanId: int32(vlan),
+			},
+		},
+	}
+
+	if binding == PortBindingEphemeral {
+		spec.Type = string(types.DistributedVirtualPortgroupPortgroupTypeEphemeral)
+		return spec
+	}
+
+	spec.Type = string(types.DistributedVirtualPortgroupPortgroupTypeEarlyBinding)
+	spec.NumPorts = bridgePortgroupPorts
+	spec.AutoExpand = types.NewBool(true)
+	return spec
+}
+
 func (d *Dispatcher) removeNetwork(conf *config.VirtualContainerHostConfigSpec) error {
 	defer trace.End(trace.Begin(conf.Name))
 
-	if d.session.IsVC() {
-		log.Debugf("Remove network is not supported for vCenter")
-		return nil
-	}
 	if !conf.CreateBridgeNetwork {
 		log.Infof("Bridge network was not created during VCH deployment, leaving it there")
 		return nil
 	}
+	if d.session.IsVC() {
+		return d.removeBridgePortgroup(conf)
+	}
 
 	name := conf.Name
 	if network, err := d.session.Finder.Network(d.ctx, name); err != nil || network == nil {
@@ -120,3 +195,28 @@ func (d *Dispatcher) removeNetwork(conf *config.VirtualContainerHostConfigSpec)
 	}
 	return nil
 }
+
+// removeBridgePortgroup removes the distributed port group created for the bridge network
+func (d *Dispatcher) removeBridgePortgroup(conf *config.VirtualContainerHostConfigSpec) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	bnet := conf.ExecutorConfig.Networks[conf.BridgeNetwork]
+	var ref types.ManagedObjectReference
+	if bnet == nil || !ref.FromString(bnet.Network.ID) {
+		log.Infof("Didn't find bridge network of %q", conf.Name)
+		return nil
+	}
+
+	log.Infof("Removing distributed port group %q", bnet.Network.ID)
+	dpg := object.NewDistributedVirtualPortgroup(d.session.Vim25(), ref)
+	_, err := tasks.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+		return dpg.Destroy(ctx)
+	})
+	if err != nil {
+		if tasks.IsFault(err, &types.ManagedObjectNotFound{}) {
+			return nil
+		}
+		return errors.Errorf("Failed to remove distributed port group (%q): %s", bnet.Network.ID, err)
+	}
+	return nil
+}
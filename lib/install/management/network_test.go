@@ -0,0 +1,39 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestBridgePortgroupSpec(t *testing.T) {
+	spec := bridgePortgroupSpec("vch-bridge", 0, "")
+	assert.Equal(t, "vch-bridge", spec.Name)
+	assert.Equal(t, string(types.DistributedVirtualPortgroupPortgroupTypeEarlyBinding), spec.Type)
+	assert.EqualValues(t, bridgePortgroupPorts, spec.NumPorts)
+	assert.True(t, *spec.AutoExpand)
+
+	spec = bridgePortgroupSpec("vch-bridge", 42, PortBindingEphemeral)
+	assert.Equal(t, string(types.DistributedVirtualPortgroupPortgroupTypeEphemeral), spec.Type)
+	assert.EqualValues(t, 0, spec.NumPorts)
+	assert.Nil(t, spec.AutoExpand)
+
+	setting := spec.DefaultPortConfig.(*types.VMwareDVSPortSetting)
+	assert.EqualValues(t, 42, setting.Vlan.(*types.VmwareDistributedVirtualSwitchVlanIdSpec).VlanId)
+}
@@ -0,0 +1,533 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/lib/spec"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig/vmomi"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// leaseProgressInterval is how often import progress is reported, which also keeps the lease alive
+const leaseProgressInterval = 2 * time.Second
+
+// digestLine matches manifest and certificate entries, e.g. SHA256(appliance.ovf)= 0123abcd
+var digestLine = regexp.MustCompile(`^(SHA1|SHA256)\((.+)\)\s*=\s*([0-9a-fA-F]+)$`)
+
+// ova provides access to the files in an OVA, which is a tar archive containing an OVF descriptor,
+// the files it references and optionally a manifest and signing certificate.
+type ova struct {
+	path string
+}
+
+// ovaEntry is an open file within the archive
+type ovaEntry struct {
+	io.Reader
+	f *os.File
+}
+
+func (e *ovaEntry) Close() error {
+	return e.f.Close()
+}
+
+// open returns the first entry whose base name matches the pattern, along with its name and size
+func (o *ova) open(pattern string) (io.ReadCloser, string, int64, error) {
+	f, err := os.Open(o.path)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	r := tar.NewReader(f)
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, "", 0, err
+		}
+
+		name := path.Base(h.Name)
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			f.Close()
+			return nil, "", 0, err
+		}
+		if matched {
+			return &ovaEntry{r, f}, name, h.Size, nil
+		}
+	}
+
+	f.Close()
+	return nil, "", 0, os.ErrNotExist
+}
+
+// read returns the content and name of the first entry whose base name matches the pattern
+func (o *ova) read(pattern string) ([]byte, string, error) {
+	r, name, _, err := o.open(pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	return b, name, err
+}
+
+// descriptor returns the OVF descriptor
+func (o *ova) descriptor() (string, error) {
+	b, _, err := o.read("*.ovf")
+	if err != nil {
+		return "", errors.Errorf("Failed to read OVF descriptor from %q: %s", o.path, err)
+	}
+	return string(b), nil
+}
+
+// verify checks that the OVA is signed by a certificate trusted by roots, or the system roots if nil, and
+// that every file listed in the signed manifest matches its digest. Files not listed in the manifest are
+// rejected, as they would otherwise be imported without verification.
+func (o *ova) verify(roots *x509.CertPool) error {
+	defer trace.End(trace.Begin(o.path))
+
+	manifest, mfName, err := o.read("*.mf")
+	if err != nil {
+		return errors.Errorf("OVA %q is not signed: no manifest found", o.path)
+	}
+
+	cert, _, err := o.read("*.cert")
+	if err != nil {
+		return errors.Errorf("OVA %q is not signed: no certificate found", o.path)
+	}
+
+	signer, err := verifySignature(mfName, manifest, cert, roots)
+	if err != nil {
+		return errors.Errorf("OVA %q has an invalid signature: %s", o.path, err)
+	}
+	log.Infof("OVA %q is signed by %q", path.Base(o.path), signer.Subject.CommonName)
+
+	digests, err := parseDigests(manifest)
+	if err != nil {
+		return errors.Errorf("OVA %q has an invalid manifest: %s", o.path, err)
+	}
+
+	f, err := os.Open(o.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := tar.NewReader(f)
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Base(h.Name)
+		if name == mfName || strings.HasSuffix(name, ".cert") {
+			continue
+		}
+
+		d, ok := digests[name]
+		if !ok {
+			return errors.Errorf("OVA %q contains %q, which is not in the manifest", o.path, name)
+		}
+		delete(digests, name)
+
+		if _, err = io.Copy(d.hash, r); err != nil {
+			return err
+		}
+		if sum := hex.EncodeToString(d.hash.Sum(nil)); sum != d.sum {
+			return errors.Errorf("OVA %q is corrupt: digest of %q does not match the manifest", o.path, name)
+		}
+	}
+
+	for name := range digests {
+		return errors.Errorf("OVA %q is missing %q, which is listed in the manifest", o.path, name)
+	}
+
+	return nil
+}
+
+// digest is an expected file digest from a manifest
+type digest struct {
+	hash hash.Hash
+	sum  string
+}
+
+func newHash(algorithm string) (hash.Hash, crypto.Hash) {
+	if algorithm == "SHA1" {
+		return sha1.New(), crypto.SHA1
+	}
+	return sha256.New(), crypto.SHA256
+}
+
+// parseDigests parses manifest entries into a map of file name to expected digest
+func parseDigests(manifest []byte) (map[string]*digest, error) {
+	digests := make(map[string]*digest)
+
+	s := bufio.NewScanner(bytes.NewReader(manifest))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		m := digestLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("unrecognized entry %q", line)
+		}
+
+		h, _ := newHash(m[1])
+		digests[m[2]] = &digest{hash: h, sum: strings.ToLower(m[3])}
+	}
+
+	return digests, s.Err()
+}
+
+// verifySignature checks the manifest signature held in the certificate file, which contains a digest line
+// for the manifest signed with the key of the PEM encoded certificate that follows it.
+func verifySignature(mfName string, manifest, cert []byte, roots *x509.CertPool) (*x509.Certificate, error) {
+	var sig []byte
+	var alg crypto.Hash
+	var h hash.Hash
+
+	rest := cert
+	for len(rest) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i], rest[i+1:]
+		} else {
+			line, rest = rest, nil
+		}
+
+		m := digestLine.FindStringSubmatch(strings.TrimSpace(string(line)))
+		if m == nil || m[2] != mfName {
+			continue
+		}
+
+		var err error
+		if sig, err = hex.DecodeString(m[3]); err != nil {
+			return nil, fmt.Errorf("malformed signature: %s", err)
+		}
+		h, alg = newHash(m[1])
+		break
+	}
+	if sig == nil {
+		return nil, fmt.Errorf("no signature for %s", mfName)
+	}
+
+	block, rest := pem.Decode(rest)
+	if block == nil {
+		return nil, fmt.Errorf("no certificate follows the signature")
+	}
+
+	signer, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// any further certificates are intermediates
+	intermediates := x509.NewCertPool()
+	for {
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if c, err := x509.ParseCertificate(block.Bytes); err == nil {
+			intermediates.AddCert(c)
+		}
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err = signer.Verify(opts); err != nil {
+		return nil, err
+	}
+
+	key, ok := signer.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key type %T", signer.PublicKey)
+	}
+
+	h.Write(manifest)
+	if err = rsa.VerifyPKCS1v15(key, alg, h.Sum(nil), sig); err != nil {
+		return nil, fmt.Errorf("manifest signature does not match: %s", err)
+	}
+
+	return signer, nil
+}
+
+// importAppliance creates the appliance VM by importing the OVA with the OVF manager. The import spec is
+// modified before import so that the VM has the same name, size, networks and encoded configuration as
+// one created by createApplianceVM, and so can be identified as a VCH appliance as soon as it exists.
+func (d *Dispatcher) importAppliance(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) (*types.ManagedObjectReference, error) {
+	defer trace.End(trace.Begin(settings.ApplianceOVA))
+
+	o := &ova{path: settings.ApplianceOVA}
+	if err := o.verify(nil); err != nil {
+		if !d.force {
+			return nil, err
+		}
+		log.Warnf("Importing unverified appliance OVA (--force=true): %s", err)
+	}
+
+	descriptor, err := o.descriptor()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := d.vchPool
+	var folder *object.Folder
+	if d.isVC && d.vchVapp != nil {
+		pool = d.vchVapp.ResourcePool
+	} else if folder, err = d.applianceFolder(conf); err != nil {
+		return nil, err
+	}
+
+	cisp := types.OvfCreateImportSpecParams{
+		EntityName: conf.Name,
+		OvfManagerCommonParams: types.OvfManagerCommonParams{
+			Locale: "US",
+		},
+	}
+
+	m := object.NewOvfManager(d.session.Vim25())
+	res, err := m.CreateImportSpec(d.ctx, descriptor, pool, d.session.Datastore, cisp)
+	if err != nil {
+		return nil, errors.Errorf("Failed to create import spec for %q: %s", o.path, err)
+	}
+	if len(res.Error) > 0 {
+		return nil, errors.Errorf("Failed to create import spec for %q: %s", o.path, res.Error[0].LocalizedMessage)
+	}
+	for _, w := range res.Warning {
+		log.Warnf("Importing %q: %s", o.path, w.LocalizedMessage)
+	}
+
+	ispec, ok := res.ImportSpec.(*types.VirtualMachineImportSpec)
+	if !ok {
+		return nil, errors.Errorf("OVA %q must contain a single virtual machine, not %T", o.path, res.ImportSpec)
+	}
+
+	if err = d.customizeImportSpec(conf, settings, &ispec.ConfigSpec); err != nil {
+		return nil, err
+	}
+
+	host, err := d.applianceHost(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	lease, err := pool.ImportVApp(d.ctx, ispec, folder, host)
+	if err != nil {
+		return nil, errors.Errorf("Failed to import %q: %s", o.path, err)
+	}
+
+	info, err := lease.Wait(d.ctx)
+	if err != nil {
+		return nil, errors.Errorf("Failed to import %q: %s", o.path, err)
+	}
+
+	if err = d.uploadImportFiles(o, lease, info, res.FileItem); err != nil {
+		if aerr := lease.HttpNfcLeaseAbort(d.ctx, nil); aerr != nil {
+			log.Warnf("Failed to abort import of %q: %s", o.path, aerr)
+		}
+		return nil, errors.Errorf("Failed to upload appliance files from %q: %s", o.path, err)
+	}
+
+	if err = lease.HttpNfcLeaseComplete(d.ctx); err != nil {
+		return nil, errors.Errorf("Failed to complete import of %q: %s", o.path, err)
+	}
+
+	return &info.Entity, nil
+}
+
+// customizeImportSpec replaces the network devices defined by the OVF with those for the VCH networks, and
+// applies the appliance name, size and encoded configuration
+func (d *Dispatcher) customizeImportSpec(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData, cspec *types.VirtualMachineConfigSpec) error {
+	defer trace.End(trace.Begin(""))
+
+	cfg, err := d.encodeConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	cspec.Name = conf.Name
+	setApplianceSize(cspec, settings)
+	cspec.ExtraConfig = append(cspec.ExtraConfig, vmomi.OptionValueFromMap(cfg)...)
+
+	var changes []types.BaseVirtualDeviceConfigSpec
+	for _, c := range cspec.DeviceChange {
+		if _, ok := c.GetVirtualDeviceConfigSpec().Device.(types.BaseVirtualEthernetCard); ok {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	cspec.DeviceChange = changes
+
+	devices, err := d.addNetworkDevices(conf, &spec.VirtualMachineConfigSpec{VirtualMachineConfigSpec: cspec}, nil)
+	if err != nil {
+		return err
+	}
+
+	nics, err := devices.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
+	if err != nil {
+		return err
+	}
+
+	cspec.DeviceChange = append(cspec.DeviceChange, nics...)
+	return nil
+}
+
+// countingReader records the number of bytes read so that lease progress can be reported
+type countingReader struct {
+	io.Reader
+	n *int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
+}
+
+// uploadImportFiles uploads the files referenced by the import spec to the lease URLs, reporting progress
+// while doing so as the lease expires if it is idle
+func (d *Dispatcher) uploadImportFiles(o *ova, lease *object.HttpNfcLease, info *types.HttpNfcLeaseInfo, items []types.OvfFileItem) error {
+	defer trace.End(trace.Begin(""))
+
+	var total, done int64
+	for _, item := range items {
+		total += item.Size
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		tick := time.NewTicker(leaseProgressInterval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-tick.C:
+				percent := int32(0)
+				if total > 0 {
+					percent = int32(100 * atomic.LoadInt64(&done) / total)
+				}
+				if err := lease.HttpNfcLeaseProgress(d.ctx, percent); err != nil {
+					log.Debugf("Failed to update import progress: %s", err)
+				}
+			}
+		}
+	}()
+
+	for _, device := range info.DeviceUrl {
+		for _, item := range items {
+			if device.ImportKey != item.DeviceId {
+				continue
+			}
+
+			u, err := d.session.Vim25().ParseURL(device.Url)
+			if err != nil {
+				return err
+			}
+
+			r, name, size, err := o.open(path.Base(item.Path))
+			if err != nil {
+				return errors.Errorf("%q: %s", item.Path, err)
+			}
+
+			log.Infof("Uploading %s", name)
+
+			// files that replace existing content, rather than creating it, are posted
+			param := soap.DefaultUpload
+			param.ContentLength = size
+			if !item.Create {
+				param.Method = "POST"
+			}
+
+			err = d.session.Vim25().Upload(countingReader{r, &done}, u, &param)
+			r.Close()
+			if err != nil {
+				return errors.Errorf("%q: %s", item.Path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// importedBootstrapImage returns the datastore path of the bootstrap ISO imported along with the appliance
+func (d *Dispatcher) importedBootstrapImage(vm *vm.VirtualMachine, settings *data.InstallerData) (string, error) {
+	defer trace.End(trace.Begin(""))
+
+	devices, err := vm.Device(d.ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var isos []string
+	for _, dev := range devices.SelectByType((*types.VirtualCdrom)(nil)) {
+		backing, ok := dev.GetVirtualDevice().Backing.(*types.VirtualCdromIsoBackingInfo)
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(backing.FileName, settings.BootstrapISO) {
+			return backing.FileName, nil
+		}
+		isos = append(isos, backing.FileName)
+	}
+
+	return "", errors.Errorf("Imported appliance has no %s attached, found: %s", settings.BootstrapISO, strings.Join(isos, ", "))
+}
@@ -0,0 +1,181 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"archive/tar"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/pkg/certificate"
+)
+
+type ovaFile struct {
+	name    string
+	content []byte
+}
+
+// writeOVA writes the files into an OVA in dir, signing it with the certificate if one is provided
+func writeOVA(t *testing.T, dir string, files []ovaFile, signer *tls.Certificate, certPEM []byte) string {
+	manifest := ""
+	for _, f := range files {
+		manifest += fmt.Sprintf("SHA256(%s)= %x\n", f.name, sha256.Sum256(f.content))
+	}
+	files = append(files, ovaFile{"appliance.mf", []byte(manifest)})
+
+	if signer != nil {
+		sum := sha256.Sum256([]byte(manifest))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, signer.PrivateKey.(*rsa.PrivateKey), crypto.SHA256, sum[:])
+		require.NoError(t, err)
+
+		cert := fmt.Sprintf("SHA256(appliance.mf)= %x\n%s", sig, certPEM)
+		files = append(files, ovaFile{"appliance.cert", []byte(cert)})
+	}
+
+	p := filepath.Join(dir, "appliance.ova")
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := tar.NewWriter(f)
+	for _, file := range files {
+		require.NoError(t, w.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content))}))
+		_, err = w.Write(file.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	return p
+}
+
+func testSigner(t *testing.T) (*tls.Certificate, []byte, *x509.CertPool) {
+	ca, cakey, err := certificate.CreateRootCA("ca.example.com", []string{"VIC"}, 2048)
+	require.NoError(t, err)
+
+	cert, key, err := certificate.CreateServerCertificate("builder.example.com", []string{"VIC"}, 2048, ca.Bytes(), cakey.Bytes())
+	require.NoError(t, err)
+
+	signer, err := tls.X509KeyPair(cert.Bytes(), key.Bytes())
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(ca.Bytes()))
+
+	return &signer, cert.Bytes(), roots
+}
+
+var testOVAFiles = []ovaFile{
+	{"appliance.ovf", []byte("<Envelope/>")},
+	{"appliance.iso", []byte("appliance")},
+	{"bootstrap.iso", []byte("bootstrap")},
+}
+
+func TestOVADescriptor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ova")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	o := &ova{path: writeOVA(t, dir, testOVAFiles, nil, nil)}
+
+	descriptor, err := o.descriptor()
+	assert.NoError(t, err)
+	assert.Equal(t, "<Envelope/>", descriptor)
+
+	r, name, size, err := o.open("bootstrap.iso")
+	require.NoError(t, err)
+	defer r.Close()
+
+	content, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "bootstrap.iso", name)
+	assert.Equal(t, int64(len("bootstrap")), size)
+	assert.Equal(t, "bootstrap", string(content))
+
+	_, _, _, err = o.open("missing.vmdk")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestOVAVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ova")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	signer, cert, roots := testSigner(t)
+
+	o := &ova{path: writeOVA(t, dir, testOVAFiles, signer, cert)}
+	assert.NoError(t, o.verify(roots))
+
+	// not trusted
+	assert.Error(t, o.verify(x509.NewCertPool()))
+
+	// not signed
+	o = &ova{path: writeOVA(t, dir, testOVAFiles, nil, nil)}
+	assert.Error(t, o.verify(roots))
+}
+
+func TestOVAVerifyTampered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ova")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	signer, cert, roots := testSigner(t)
+	p := writeOVA(t, dir, testOVAFiles, signer, cert)
+
+	// rewrite the OVA with a modified image, keeping the signed manifest
+	o := &ova{path: p}
+	mf, _, err := o.read("*.mf")
+	require.NoError(t, err)
+	sig, _, err := o.read("*.cert")
+	require.NoError(t, err)
+
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	w := tar.NewWriter(f)
+	for _, file := range []ovaFile{testOVAFiles[0], testOVAFiles[1], {"bootstrap.iso", []byte("tampered")}, {"appliance.mf", mf}, {"appliance.cert", sig}} {
+		require.NoError(t, w.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content))}))
+		_, err = w.Write(file.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	err = o.verify(roots)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "bootstrap.iso")
+	}
+}
+
+func TestParseDigests(t *testing.T) {
+	digests, err := parseDigests([]byte("SHA1(a.ovf)= 0A0B\n\nSHA256(disk 1.vmdk) = 0c0d\n"))
+	require.NoError(t, err)
+	require.Len(t, digests, 2)
+	assert.Equal(t, "0a0b", digests["a.ovf"].sum)
+	assert.Equal(t, "0c0d", digests["disk 1.vmdk"].sum)
+
+	_, err = parseDigests([]byte("MD5(a.ovf)= 0a0b"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/compute"
+)
+
+// applianceHost returns the host to create the appliance on, or nil to leave the choice to DRS.
+// A host requested in settings is always used. Otherwise DRS places the appliance in a DRS enabled
+// cluster, and elsewhere the appliance goes on the session host or, failing that, the host chosen
+// by placementHost.
+func (d *Dispatcher) applianceHost(settings *data.InstallerData) (*object.HostSystem, error) {
+	defer trace.End(trace.Begin(settings.ApplianceHost))
+
+	if settings.ApplianceHost != "" {
+		host, err := d.session.Finder.HostSystem(d.ctx, settings.ApplianceHost)
+		if err != nil {
+			return nil, errors.Errorf("Unable to find appliance host %q: %s", settings.ApplianceHost, err)
+		}
+		log.Infof("Creating appliance on host %q", host.InventoryPath)
+		return host, nil
+	}
+
+	if d.isVC && d.session.Cluster != nil {
+		drs, err := compute.DRSEnabled(d.ctx, d.session.Cluster)
+		if err != nil {
+			log.Warnf("Unable to determine whether DRS is enabled on %q: %s", d.session.Cluster.Name(), err)
+		}
+		if drs {
+			log.Debugf("Leaving appliance placement to DRS")
+			return nil, nil
+		}
+	}
+
+	return d.placementHost()
+}
+
+// placementHost returns the host to create the appliance on when DRS does not place it
+func (d *Dispatcher) placementHost() (*object.HostSystem, error) {
+	if d.session.Host != nil {
+		return d.session.Host, nil
+	}
+
+	if d.session.Cluster == nil {
+		return nil, errors.New("No host or compute resource to place the appliance on")
+	}
+
+	host, err := compute.PlacementHost(d.ctx, d.session.Cluster)
+	if err != nil {
+		return nil, errors.Errorf("Unable to choose a host for the appliance: %s", err)
+	}
+	log.Infof("Creating appliance on host %q", host.Name())
+	return host, nil
+}
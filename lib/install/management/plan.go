@@ -0,0 +1,148 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"path"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// Plan describes what creating a VCH does, without doing it
+type Plan struct {
+	Name string `json:"name"`
+
+	// Resume is the step a VCH whose creation failed part way through is resumed from
+	Resume string `json:"resume,omitempty"`
+
+	// ResourcePool is the inventory path of the resource pool or virtual app created for the VCH
+	ResourcePool string `json:"resource_pool"`
+	VirtualApp   bool   `json:"virtual_app"`
+	// Folder is the VM folder below the datacenter VM folder that the appliance is created in
+	Folder string `json:"folder,omitempty"`
+	// ApplianceHost is the host the appliance is created on, empty when DRS places it
+	ApplianceHost string `json:"appliance_host,omitempty"`
+
+	// BridgeSwitch is the virtual switch and port group created for the bridge network on ESX, or the
+	// distributed port group created for it on vCenter
+	BridgeSwitch string `json:"bridge_switch,omitempty"`
+	// FirewallRules is whether serial-over-LAN is enabled on host firewalls that block it
+	FirewallRules bool `json:"firewall_rules"`
+
+	ImageStore   string            `json:"image_store"`
+	VolumeStores map[string]string `json:"volume_stores,omitempty"`
+	// Images maps the datastore path of each uploaded image to its local file
+	Images map[string]string `json:"images,omitempty"`
+	// ApplianceOVA is the OVA the appliance is imported from, instead of creating it from Appliance
+	ApplianceOVA string `json:"appliance_ova,omitempty"`
+
+	// Appliance is the spec the appliance VM is created with
+	Appliance *types.VirtualMachineConfigSpec `json:"appliance,omitempty"`
+	// Config is the configuration written to the appliance once it is created, with secrets encrypted
+	Config map[string]string `json:"config"`
+}
+
+// PlanVCH validates that the VCH can be created and returns what creating it does, making no changes
+// to the target. The appliance folder, which is only known once the appliance exists, is assumed to be
+// named after the VCH. A bridge network that is yet to be created has no card in the appliance spec.
+func (d *Dispatcher) PlanVCH(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) (*Plan, error) {
+	defer trace.End(trace.Begin(conf.Name))
+
+	plan := &Plan{
+		Name:          conf.Name,
+		VirtualApp:    d.isVC && !settings.UseRP,
+		Folder:        conf.VMFolder,
+		FirewallRules: settings.AllowFirewall,
+		ApplianceOVA:  settings.ApplianceOVA,
+	}
+
+	if err := d.checkExistence(conf, settings); err != nil {
+		return nil, err
+	}
+	plan.ResourcePool = d.vchPoolPath
+	if d.checkpoint != "" {
+		plan.Resume = d.checkpoint
+		return plan, nil
+	}
+
+	if bnet := conf.ExecutorConfig.Networks[conf.BridgeNetwork]; bnet != nil && bnet.ID == "" && (!d.isVC || conf.BridgeNetworkDVS != "") {
+		plan.BridgeSwitch = bnet.Network.ID
+	}
+
+	if len(conf.VolumeLocations) > 0 {
+		plan.VolumeStores = make(map[string]string)
+		for label, u := range conf.VolumeLocations {
+			plan.VolumeStores[label] = u.String()
+		}
+	}
+
+	if err := d.placeAppliance(conf); err != nil {
+		return nil, errors.Errorf("Choosing an image store for the appliance failed: %s", err)
+	}
+	plan.ImageStore = conf.ImageStores[0].String()
+
+	host, err := d.applianceHost(settings)
+	if err != nil {
+		return nil, err
+	}
+	if host != nil {
+		plan.ApplianceHost = host.InventoryPath
+	}
+
+	d.vmPathName = conf.Name
+	if len(settings.ImageFiles) > 0 {
+		plan.Images = make(map[string]string)
+		for name, file := range settings.ImageFiles {
+			plan.Images[fmt.Sprintf("[%s] %s", conf.ImageStores[0].Host, path.Join(d.vmPathName, name))] = file
+		}
+	}
+
+	if settings.ApplianceOVA == "" {
+		networks := conf.ExecutorConfig.Networks
+		if plan.BridgeSwitch != "" {
+			// the bridge network does not exist yet, so its card is left out of the spec
+			conf.ExecutorConfig.Networks = make(map[string]*executor.NetworkEndpoint)
+			for name, endpoint := range networks {
+				if name != conf.BridgeNetwork {
+					conf.ExecutorConfig.Networks[name] = endpoint
+				}
+			}
+		}
+		plan.Appliance, err = d.createApplianceSpec(conf, settings)
+		conf.ExecutorConfig.Networks = networks
+		if err != nil {
+			log.Errorf("Unable to create appliance spec: %s", err)
+			return nil, err
+		}
+		conf.BootstrapImagePath = fmt.Sprintf("[%s] %s/%s", conf.ImageStores[0].Host, d.vmPathName, settings.BootstrapISO)
+	}
+
+	settings.Extension = vchExtension(conf)
+	d.addApplianceComponents(conf, settings)
+
+	if plan.Config, err = d.encodeConfig(conf); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
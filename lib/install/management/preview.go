@@ -0,0 +1,184 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// Redacted replaces the values of secret configuration in a preview
+const Redacted = "<redacted>"
+
+// configKeyPrefix is the prefix of the persistent configuration keys of the appliance
+const configKeyPrefix = "guestinfo.vice./"
+
+// componentsByKey maps configuration key prefixes to the appliance components that read them.
+// Keys that match none are only used by vic-machine.
+var componentsByKey = []struct {
+	prefix     string
+	components []string
+}{
+	{"connect/", ApplianceComponents},
+	{"cert/", certificateComponents},
+	{"registry/", []string{"docker-personality"}},
+	{"init/networks", []string{"vic-init"}},
+	{"network/", []string{"port-layer"}},
+	{"storage/", []string{"port-layer"}},
+	{"container/", []string{"port-layer"}},
+	{"release_url", []string{"docker-personality", "vicadmin"}},
+	{"admin_ldap/", []string{"vicadmin"}},
+}
+
+// ConfigChange is the change of a single key of the appliance configuration
+type ConfigChange struct {
+	Key    string
+	Before string
+	After  string
+	// Components are the appliance components that read the key
+	Components []string
+}
+
+// ReconfigurePreview is what Reconfigure would change on a VCH
+type ReconfigurePreview struct {
+	Changes []ConfigChange
+	// Resize is the change of appliance size, nil if unchanged
+	Resize *types.VirtualMachineConfigSpec
+	// HotResize is whether the resize applies without powering off the appliance
+	HotResize bool
+}
+
+// Empty returns whether there is nothing to change
+func (p *ReconfigurePreview) Empty() bool {
+	return len(p.Changes) == 0 && p.Resize == nil
+}
+
+// Restart returns whether applying the changes restarts the appliance, and so all of its components
+func (p *ReconfigurePreview) Restart() bool {
+	return len(p.Changes) > 0 || (p.Resize != nil && !p.HotResize)
+}
+
+// Components returns the appliance components that read the changed configuration, sorted
+func (p *ReconfigurePreview) Components() []string {
+	seen := make(map[string]bool)
+	var components []string
+	for _, c := range p.Changes {
+		for _, name := range c.Components {
+			if !seen[name] {
+				seen[name] = true
+				components = append(components, name)
+			}
+		}
+	}
+	sort.Strings(components)
+	return components
+}
+
+// PreviewReconfigure returns what Reconfigure would change on the VCH, without changing anything
+func (d *Dispatcher) PreviewReconfigure(vch *vm.VirtualMachine, current, requested *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) (*ReconfigurePreview, error) {
+	defer trace.End(trace.Begin(requested.Name))
+
+	d.appliance = vch
+	setProxies(requested, settings)
+
+	resize, hot, err := d.applianceResize(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReconfigurePreview{
+		Changes:   ConfigDiff(current, requested),
+		Resize:    resize,
+		HotResize: hot,
+	}, nil
+}
+
+// ConfigDiff returns the changes between the current and requested configuration, sorted by key, with
+// the values of secrets redacted. Runtime state that the appliance records is not included, nor are keys
+// added or removed without a value.
+func ConfigDiff(current, requested *config.VirtualContainerHostConfigSpec) []ConfigChange {
+	before := make(map[string]string)
+	extraconfig.Encode(extraconfig.MapSink(before), current)
+
+	after := make(map[string]string)
+	extraconfig.Encode(extraconfig.MapSink(after), requested)
+
+	var changes []ConfigChange
+	for _, o := range configDelta(current, requested) {
+		k := o.GetOptionValue().Key
+		if !strings.HasPrefix(k, configKeyPrefix) {
+			continue
+		}
+
+		// keys added or removed without a value change nothing
+		if before[k] == after[k] {
+			continue
+		}
+
+		key := strings.TrimPrefix(k, configKeyPrefix)
+		c := ConfigChange{
+			Key:        key,
+			Before:     before[k],
+			After:      after[k],
+			Components: keyComponents(key),
+		}
+		if secretKey(key) {
+			if c.Before != "" {
+				c.Before = Redacted
+			}
+			if c.After != "" {
+				c.After = Redacted
+			}
+		}
+		changes = append(changes, c)
+	}
+
+	return changes
+}
+
+// keyComponents returns the appliance components that read the configuration key
+func keyComponents(key string) []string {
+	if strings.HasPrefix(key, "init/sessions|") {
+		name := strings.TrimPrefix(key, "init/sessions|")
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[:i]
+		}
+		return []string{name}
+	}
+
+	for _, c := range componentsByKey {
+		if strings.HasPrefix(key, c.prefix) {
+			return c.components
+		}
+	}
+	return nil
+}
+
+// secretKey returns whether the value of the configuration key must not be shown
+func secretKey(key string) bool {
+	if strings.HasSuffix(key, "@secret") {
+		return true
+	}
+
+	name := key[strings.LastIndex(key, "/")+1:]
+	return name == "Key" || strings.HasSuffix(name, "_key")
+}
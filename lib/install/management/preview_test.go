@@ -0,0 +1,86 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+func TestConfigDiff(t *testing.T) {
+	current := &config.VirtualContainerHostConfigSpec{}
+	current.Name = "vch"
+	current.UserPassword = "old"
+	current.HostCertificate = &config.RawCertificate{Key: []byte("key"), Cert: []byte("cert")}
+	current.ExecutorConfig.Sessions = map[string]*executor.SessionConfig{
+		"docker-personality": {Cmd: executor.Cmd{Path: "/sbin/docker-engine-server"}},
+	}
+
+	requested := &config.VirtualContainerHostConfigSpec{}
+	*requested = *current
+	assert.Empty(t, ConfigDiff(current, requested))
+
+	requested.UserPassword = "new"
+	requested.HostCertificate = &config.RawCertificate{Key: []byte("key2"), Cert: []byte("cert")}
+	requested.InsecureRegistries = []url.URL{{Scheme: "http", Host: "registry.example.com"}}
+	requested.ExecutorConfig.Sessions = map[string]*executor.SessionConfig{
+		"docker-personality": {Cmd: executor.Cmd{Path: "/sbin/docker-engine-server", Env: []string{"HTTP_PROXY=http://proxy:3128"}}},
+	}
+
+	changes := ConfigDiff(current, requested)
+	byKey := make(map[string]ConfigChange)
+	for _, c := range changes {
+		byKey[c.Key] = c
+	}
+
+	c, ok := byKey["cert/HostCertificate/Key"]
+	require.True(t, ok)
+	assert.Equal(t, Redacted, c.Before)
+	assert.Equal(t, Redacted, c.After)
+	assert.Equal(t, certificateComponents, c.Components)
+
+	c, ok = byKey["connect/userpw@secret"]
+	require.True(t, ok)
+	assert.Equal(t, Redacted, c.After)
+	assert.Equal(t, ApplianceComponents, c.Components)
+
+	c, ok = byKey["registry/insecure_registries|0/Host"]
+	require.True(t, ok)
+	assert.Equal(t, "registry.example.com", c.After)
+	assert.Equal(t, []string{"docker-personality"}, c.Components)
+
+	c, ok = byKey["init/sessions|docker-personality/cmd/Env~"]
+	require.True(t, ok)
+	assert.Equal(t, "http://proxy:3128", c.After[len("HTTP_PROXY="):])
+	assert.Equal(t, []string{"docker-personality"}, c.Components)
+
+	// keys that are added without a value are not changes
+	_, ok = byKey["registry/insecure_registries|0/Fragment"]
+	assert.False(t, ok)
+
+	for _, c := range changes {
+		assert.NotContains(t, c.Before+c.After, "key2", c.Key)
+	}
+
+	preview := &ReconfigurePreview{Changes: changes}
+	assert.True(t, preview.Restart())
+	assert.Equal(t, []string{"docker-personality", "port-layer", "vicadmin"}, preview.Components())
+}
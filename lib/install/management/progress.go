@@ -0,0 +1,91 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Operations reported in ProgressEvent
+const (
+	OperationCreate      = "create"
+	OperationDelete      = "delete"
+	OperationUpgrade     = "upgrade"
+	OperationReconfigure = "configure"
+)
+
+// ProgressDone is the step reported when an operation completes successfully
+const ProgressDone = "done"
+
+// ProgressEvent describes the progress of a dispatcher operation. Percent is an estimate of the
+// share of the operation completed when Step began. An event with Error set is the last one
+// reported for the operation, and names the step reached when it failed.
+type ProgressEvent struct {
+	Operation string    `json:"operation"`
+	Step      string    `json:"step"`
+	Percent   int       `json:"percent"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// ProgressFunc receives the progress events of dispatcher operations. It is called synchronously
+// from the operation so must not block for long.
+type ProgressFunc func(ProgressEvent)
+
+// progress tracks the operation in progress, so the step in which it fails can be reported
+type progress struct {
+	operation string
+	step      string
+	percent   int
+}
+
+// beginProgress starts reporting the progress of operation
+func (d *Dispatcher) beginProgress(operation string) {
+	d.progress = progress{operation: operation}
+}
+
+// reportProgress records that the current operation has reached step
+func (d *Dispatcher) reportProgress(step string, percent int) {
+	d.progress.step = step
+	d.progress.percent = percent
+
+	log.Debugf("%s: %s (%d%%)", d.progress.operation, step, percent)
+	d.emitProgress("")
+}
+
+// endProgress reports the outcome of the current operation
+func (d *Dispatcher) endProgress(err error) {
+	if err != nil {
+		d.emitProgress(err.Error())
+		return
+	}
+	d.reportProgress(ProgressDone, 100)
+}
+
+func (d *Dispatcher) emitProgress(detail string) {
+	if d.Progress == nil || d.progress.operation == "" {
+		return
+	}
+
+	d.Progress(ProgressEvent{
+		Operation: d.progress.operation,
+		Step:      d.progress.step,
+		Percent:   d.progress.percent,
+		Error:     detail,
+		Time:      time.Now().UTC(),
+	})
+}
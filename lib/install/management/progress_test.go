@@ -0,0 +1,74 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	var events []ProgressEvent
+	d := &Dispatcher{
+		Progress: func(e ProgressEvent) {
+			events = append(events, e)
+		},
+	}
+
+	// nothing is reported outside of an operation
+	d.reportProgress("Creating resource pool", 5)
+	assert.Empty(t, events)
+
+	d.beginProgress(OperationCreate)
+	d.reportProgress("Creating resource pool", 5)
+	d.reportProgress("Uploading images", 40)
+	d.endProgress(nil)
+
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, "Creating resource pool", events[0].Step)
+		assert.Equal(t, 40, events[1].Percent)
+		assert.Equal(t, ProgressDone, events[2].Step)
+		assert.Equal(t, 100, events[2].Percent)
+		for _, e := range events {
+			assert.Equal(t, OperationCreate, e.Operation)
+			assert.Empty(t, e.Error)
+			assert.False(t, e.Time.IsZero())
+		}
+	}
+
+	events = nil
+	d.beginProgress(OperationDelete)
+	d.reportProgress("Deleting images", 40)
+	d.endProgress(errors.New("datastore is not accessible"))
+
+	if assert.Len(t, events, 2) {
+		failed := events[1]
+		assert.Equal(t, OperationDelete, failed.Operation)
+		assert.Equal(t, "Deleting images", failed.Step, "the failure must name the step reached")
+		assert.Equal(t, 40, failed.Percent)
+		assert.Equal(t, "datastore is not accessible", failed.Error)
+	}
+}
+
+func TestProgressWithoutReporter(t *testing.T) {
+	d := &Dispatcher{}
+
+	d.beginProgress(OperationUpgrade)
+	d.reportProgress("Uploading images", 0)
+	d.endProgress(errors.New("failed"))
+	assert.Equal(t, "Uploading images", d.progress.step)
+}
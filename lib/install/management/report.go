@@ -0,0 +1,325 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/pkg/ip"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/compute"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// toolsNotRunning explains the consequence of a powered on VM without running guest tools
+const toolsNotRunning = "tools not running - IP addresses and guest operations are unavailable"
+
+// Inspection is the structured state of a VCH, as reported by vic-machine inspect
+type Inspection struct {
+	Name             string `json:"name"`
+	ID               string `json:"id"`
+	PowerState       string `json:"power_state"`
+	Version          string `json:"version"`
+	InstallerVersion string `json:"installer_version"`
+	UpgradeStatus    string `json:"upgrade_status"`
+	Folder           string `json:"folder,omitempty"`
+
+	Components   []ComponentStatus `json:"components"`
+	Networks     []NetworkStatus   `json:"networks"`
+	Certificates CertificateStatus `json:"certificates"`
+	VolumeStores []VolumeStore     `json:"volume_stores"`
+	Endpoints    *Endpoints        `json:"endpoints,omitempty"`
+
+	Tools        *vm.ToolsStatus     `json:"tools,omitempty"`
+	ToolsWarning string              `json:"tools_warning,omitempty"`
+	Containers   []ContainerVMStatus `json:"containers,omitempty"`
+
+	Panic *KernelPanic `json:"panic,omitempty"`
+}
+
+// ContainerVMStatus is the power and guest tools state of a container VM
+type ContainerVMStatus struct {
+	Name         string          `json:"name"`
+	ID           string          `json:"id"`
+	PowerState   string          `json:"power_state"`
+	Tools        *vm.ToolsStatus `json:"tools"`
+	ToolsWarning string          `json:"tools_warning,omitempty"`
+}
+
+// ComponentStatus is the launch state of an appliance component
+type ComponentStatus struct {
+	Name          string     `json:"name"`
+	Status        string     `json:"status"`
+	Started       bool       `json:"started"`
+	Resurrections int        `json:"resurrections"`
+	Heartbeat     *time.Time `json:"heartbeat,omitempty"`
+	Health        string     `json:"health,omitempty"`
+	HealthReason  string     `json:"health_reason,omitempty"`
+}
+
+// NetworkStatus is the address assigned to an appliance network
+type NetworkStatus struct {
+	Name     string `json:"name"`
+	Address  string `json:"address,omitempty"`
+	Gateway  string `json:"gateway,omitempty"`
+	Assigned bool   `json:"assigned"`
+}
+
+// CertificateStatus describes the TLS configuration of the appliance
+type CertificateStatus struct {
+	TLS                    bool       `json:"tls"`
+	ClientVerification     bool       `json:"client_verification"`
+	CertificateAuthorities int        `json:"certificate_authorities"`
+	Subject                string     `json:"subject,omitempty"`
+	NotAfter               *time.Time `json:"not_after,omitempty"`
+}
+
+// VolumeStore is a named volume store location, with the capacity of its datastore and the space
+// used by the volumes in it. Capacity is only reported if the datastore could be queried, and
+// Error explains why it could not.
+type VolumeStore struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// StoragePolicy is the ID of the storage policy applied to new volumes in the store
+	StoragePolicy string `json:"storage_policy,omitempty"`
+
+	Datastore string `json:"datastore,omitempty"`
+	Capacity  int64  `json:"capacity,omitempty"`
+	FreeSpace int64  `json:"free_space,omitempty"`
+	Used      int64  `json:"used,omitempty"`
+	Volumes   int    `json:"volumes"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Endpoints are the addresses used to reach the appliance services. They are only
+// available once the appliance is running and has a client address.
+type Endpoints struct {
+	DockerHost     string `json:"docker_host"`
+	VICAdmin       string `json:"vicadmin"`
+	PublishedPorts string `json:"published_ports,omitempty"`
+}
+
+// sessionStatus returns a user facing description of the session launch state
+func sessionStatus(session *executor.SessionConfig) string {
+	switch session.Started {
+	case "true":
+		return "started successfully"
+	case "":
+		return "waiting to launch"
+	default:
+		return session.Started
+	}
+}
+
+// InspectionReport gathers the current state of the VCH, refreshing the supplied configuration
+// from the appliance. Unlike InspectVCH this does not fail if the appliance is not running, so
+// that the state of a stopped or initializing VCH can still be reported.
+func (d *Dispatcher) InspectionReport(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec) (*Inspection, error) {
+	defer trace.End(trace.Begin(conf.Name))
+
+	d.appliance = vch
+	if err := d.applianceConfiguration(conf); err != nil {
+		return nil, fmt.Errorf("unable to retrieve configuration from appliance: %s", err)
+	}
+
+	state, err := vch.PowerState(d.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine power state: %s", err)
+	}
+
+	report := &Inspection{
+		Name:       conf.Name,
+		ID:         vch.Reference().Value,
+		PowerState: string(state),
+		Folder:     conf.VMFolder,
+	}
+	if conf.Version != nil {
+		report.Version = conf.Version.ShortVersion()
+	}
+
+	// health is only meaningful while the appliance is running
+	var now time.Time
+	if state == types.VirtualMachinePowerStatePoweredOn {
+		now = d.serverTime()
+	}
+	report.Components = componentStatus(conf, now)
+
+	for name, endpoint := range conf.ExecutorConfig.Networks {
+		status := NetworkStatus{
+			Name:     name,
+			Assigned: !ip.IsUnspecifiedIP(endpoint.Assigned.IP),
+		}
+		if status.Assigned {
+			status.Address = endpoint.Assigned.String()
+		}
+		if !ip.Empty(endpoint.Network.Gateway) {
+			status.Gateway = endpoint.Network.Gateway.IP.String()
+		}
+		report.Networks = append(report.Networks, status)
+	}
+	sort.Sort(byNetworkName(report.Networks))
+
+	report.Certificates = certificateStatus(conf)
+
+	report.VolumeStores = d.VolumeStores(conf)
+
+	client := conf.ExecutorConfig.Networks["client"]
+	if state == types.VirtualMachinePowerStatePoweredOn && client != nil && !ip.IsUnspecifiedIP(client.Assigned.IP) {
+		d.setHostAddress(conf)
+		report.Endpoints = &Endpoints{
+			DockerHost: net.JoinHostPort(d.HostIP, d.DockerPort),
+			VICAdmin:   fmt.Sprintf("%s://%s", d.VICAdminProto, net.JoinHostPort(d.HostIP, "2378")),
+		}
+		if external := conf.ExecutorConfig.Networks["external"]; external != nil && !ip.IsUnspecifiedIP(external.Assigned.IP) {
+			report.Endpoints.PublishedPorts = external.Assigned.IP.String()
+		}
+	}
+
+	if tools, err := vch.ToolsStatus(d.ctx); err != nil {
+		log.Debugf("Failed to get appliance tools status: %s", err)
+	} else {
+		report.Tools = tools
+		report.ToolsWarning = toolsWarning(string(state), tools)
+	}
+
+	if report.Containers, err = d.containerVMStatus(vch, conf); err != nil {
+		log.Debugf("Failed to get container VM status: %s", err)
+	}
+
+	if report.Panic, err = d.appliancePanic(vch, conf); err != nil {
+		log.Debugf("Failed to read appliance crash log: %s", err)
+	}
+
+	return report, nil
+}
+
+// toolsWarning returns a warning if the tools of a powered on VM are not running, which
+// usually explains missing addresses and failing guest operations
+func toolsWarning(powerState string, tools *vm.ToolsStatus) string {
+	if powerState != string(types.VirtualMachinePowerStatePoweredOn) || tools.Running() {
+		return ""
+	}
+	return toolsNotRunning
+}
+
+// containerVMStatus returns the power and tools state of the container VMs in the VCH resource pool
+func (d *Dispatcher) containerVMStatus(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec) ([]ContainerVMStatus, error) {
+	if len(conf.ComputeResources) == 0 {
+		return nil, nil
+	}
+
+	rp := compute.NewResourcePool(d.ctx, d.session, conf.ComputeResources[len(conf.ComputeResources)-1])
+	children, err := rp.GetChildrenVMs(d.ctx, d.session)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []types.ManagedObjectReference
+	for _, child := range children {
+		if child.Reference() != vch.Reference() {
+			refs = append(refs, child.Reference())
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	var mvms []mo.VirtualMachine
+	pc := property.DefaultCollector(d.session.Vim25())
+	if err = pc.Retrieve(d.ctx, refs, []string{"name", "guest", "runtime.powerState"}, &mvms); err != nil {
+		return nil, err
+	}
+
+	status := make([]ContainerVMStatus, 0, len(mvms))
+	for _, mvm := range mvms {
+		tools := vm.NewToolsStatus(mvm.Guest)
+		status = append(status, ContainerVMStatus{
+			Name:         mvm.Name,
+			ID:           mvm.Reference().Value,
+			PowerState:   string(mvm.Runtime.PowerState),
+			Tools:        tools,
+			ToolsWarning: toolsWarning(string(mvm.Runtime.PowerState), tools),
+		})
+	}
+	sort.Sort(byContainerVMName(status))
+
+	return status, nil
+}
+
+// certificateStatus summarises the host certificate and certificate authorities
+func certificateStatus(conf *config.VirtualContainerHostConfigSpec) CertificateStatus {
+	status := CertificateStatus{
+		TLS:                !conf.HostCertificate.IsNil(),
+		ClientVerification: len(conf.CertificateAuthorities) > 0,
+	}
+
+	rest := conf.CertificateAuthorities
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		status.CertificateAuthorities++
+	}
+
+	if !status.TLS {
+		return status
+	}
+
+	cert, err := conf.HostCertificate.X509Certificate()
+	if err != nil {
+		log.Debugf("Failed to load host cert: %s", err)
+		return status
+	}
+
+	status.Subject = cert.Subject.CommonName
+	status.NotAfter = &cert.NotAfter
+	return status
+}
+
+type byComponentName []ComponentStatus
+
+func (s byComponentName) Len() int           { return len(s) }
+func (s byComponentName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byComponentName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+type byNetworkName []NetworkStatus
+
+func (s byNetworkName) Len() int           { return len(s) }
+func (s byNetworkName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byNetworkName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+type byVolumeStoreName []VolumeStore
+
+func (s byVolumeStoreName) Len() int           { return len(s) }
+func (s byVolumeStoreName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byVolumeStoreName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+type byContainerVMName []ContainerVMStatus
+
+func (s byContainerVMName) Len() int           { return len(s) }
+func (s byContainerVMName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byContainerVMName) Less(i, j int) bool { return s[i].Name < s[j].Name }
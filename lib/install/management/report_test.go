@@ -0,0 +1,82 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/pkg/certificate"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+func TestSessionStatus(t *testing.T) {
+	var tests = []struct {
+		started string
+		status  string
+	}{
+		{"", "waiting to launch"},
+		{"true", "started successfully"},
+		{"exec format error", "exec format error"},
+	}
+
+	for _, te := range tests {
+		assert.Equal(t, te.status, sessionStatus(&executor.SessionConfig{Started: te.started}))
+	}
+}
+
+func TestCertificateStatusNoTLS(t *testing.T) {
+	status := certificateStatus(testConfig())
+
+	assert.False(t, status.TLS)
+	assert.False(t, status.ClientVerification)
+	assert.Equal(t, 0, status.CertificateAuthorities)
+	assert.Nil(t, status.NotAfter)
+}
+
+func TestCertificateStatus(t *testing.T) {
+	ca, cakey, err := certificate.CreateRootCA("ca.example.com", []string{"VIC"}, 2048)
+	require.NoError(t, err)
+
+	cert, key, err := certificate.CreateServerCertificate("vch.example.com", []string{"VIC"}, 2048, ca.Bytes(), cakey.Bytes())
+	require.NoError(t, err)
+
+	conf := testConfig()
+	conf.HostCertificate = &config.RawCertificate{Cert: cert.Bytes(), Key: key.Bytes()}
+	conf.CertificateAuthorities = append(ca.Bytes(), ca.Bytes()...)
+
+	status := certificateStatus(conf)
+
+	assert.True(t, status.TLS)
+	assert.True(t, status.ClientVerification)
+	assert.Equal(t, 2, status.CertificateAuthorities)
+	assert.Equal(t, "vch.example.com", status.Subject)
+	if assert.NotNil(t, status.NotAfter) {
+		assert.False(t, status.NotAfter.IsZero())
+	}
+}
+
+func TestToolsWarning(t *testing.T) {
+	running := &vm.ToolsStatus{RunningStatus: "guestToolsRunning"}
+	stopped := &vm.ToolsStatus{RunningStatus: "guestToolsNotRunning"}
+
+	assert.Empty(t, toolsWarning("poweredOn", running))
+	assert.Empty(t, toolsWarning("poweredOff", stopped))
+	assert.Equal(t, toolsNotRunning, toolsWarning("poweredOn", stopped))
+}
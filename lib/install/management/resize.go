@@ -0,0 +1,161 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+)
+
+// applianceAllocation returns the requested reservation, limit and shares applied over the current
+// allocation, or nil if they leave it unchanged. Values that are not requested keep their current
+// setting, and the limit of a new appliance defaults to unlimited.
+func applianceAllocation(current types.BaseResourceAllocationInfo, requested types.ResourceAllocationInfo) *types.ResourceAllocationInfo {
+	alloc := types.ResourceAllocationInfo{Limit: -1}
+	if current != nil {
+		alloc = *current.GetResourceAllocationInfo()
+	}
+
+	changed := false
+	if requested.Reservation != 0 && requested.Reservation != alloc.Reservation {
+		alloc.Reservation = requested.Reservation
+		changed = true
+	}
+	if requested.Limit != 0 && requested.Limit != alloc.Limit {
+		alloc.Limit = requested.Limit
+		changed = true
+	}
+	if requested.Shares != nil && !sameShares(requested.Shares, alloc.Shares) {
+		alloc.Shares = requested.Shares
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return &alloc
+}
+
+// sameShares returns whether the requested shares are already set, the number of shares only
+// being compared for a custom level
+func sameShares(requested, current *types.SharesInfo) bool {
+	if current == nil || requested.Level != current.Level {
+		return false
+	}
+	return requested.Level != types.SharesLevelCustom || requested.Shares == current.Shares
+}
+
+// setApplianceSize sets the size and allocation of a new appliance in the spec, enabling hot add so that
+// the appliance can be grown later without a restart
+func setApplianceSize(spec *types.VirtualMachineConfigSpec, settings *data.InstallerData) {
+	if settings.ApplianceCPUs > 0 {
+		spec.NumCPUs = settings.ApplianceCPUs
+	}
+	if settings.ApplianceMemoryMB > 0 {
+		spec.MemoryMB = settings.ApplianceMemoryMB
+	}
+	spec.CpuHotAddEnabled = types.NewBool(true)
+	spec.MemoryHotAddEnabled = types.NewBool(true)
+
+	// a nil allocation must not be assigned to the interface, as it would not compare equal to nil
+	if cpu := applianceAllocation(nil, settings.ApplianceSize.CPU); cpu != nil {
+		spec.CpuAllocation = cpu
+	}
+	if memory := applianceAllocation(nil, settings.ApplianceSize.Memory); memory != nil {
+		spec.MemoryAllocation = memory
+	}
+}
+
+// resizeSpec returns the spec changing the appliance from its current configuration to the requested
+// size and allocation, or nil if nothing changes. hot is set if the spec can be applied to the running
+// appliance, which is the case when CPU and memory only grow and hot add of them is enabled.
+func resizeSpec(current *types.VirtualMachineConfigInfo, settings *data.InstallerData) (spec *types.VirtualMachineConfigSpec, hot bool) {
+	spec = &types.VirtualMachineConfigSpec{}
+	changed := false
+	hot = true
+
+	if n := settings.ApplianceCPUs; n > 0 && n != current.Hardware.NumCPU {
+		spec.NumCPUs = n
+		changed = true
+		hot = hot && n > current.Hardware.NumCPU && current.CpuHotAddEnabled != nil && *current.CpuHotAddEnabled
+	}
+	if mb := settings.ApplianceMemoryMB; mb > 0 && mb != int64(current.Hardware.MemoryMB) {
+		spec.MemoryMB = mb
+		changed = true
+		hot = hot && mb > int64(current.Hardware.MemoryMB) && current.MemoryHotAddEnabled != nil && *current.MemoryHotAddEnabled
+	}
+
+	if cpu := applianceAllocation(current.CpuAllocation, settings.ApplianceSize.CPU); cpu != nil {
+		spec.CpuAllocation = cpu
+		changed = true
+	}
+	if memory := applianceAllocation(current.MemoryAllocation, settings.ApplianceSize.Memory); memory != nil {
+		spec.MemoryAllocation = memory
+		changed = true
+	}
+
+	if !changed {
+		return nil, false
+	}
+	if !hot {
+		// appliances created before hot add was enabled get it while powered off
+		spec.CpuHotAddEnabled = types.NewBool(true)
+		spec.MemoryHotAddEnabled = types.NewBool(true)
+	}
+	return spec, hot
+}
+
+// applianceResize returns the spec resizing the appliance as requested by settings, or nil if its size
+// is unchanged, and whether the spec can be applied while the appliance is running
+func (d *Dispatcher) applianceResize(settings *data.InstallerData) (*types.VirtualMachineConfigSpec, bool, error) {
+	defer trace.End(trace.Begin(""))
+
+	var mvm mo.VirtualMachine
+	if err := d.appliance.Properties(d.ctx, d.appliance.Reference(), []string{"config"}, &mvm); err != nil {
+		return nil, false, errors.Errorf("Failed to get appliance configuration: %s", err)
+	}
+	if mvm.Config == nil {
+		return nil, false, errors.New("Appliance configuration is not available")
+	}
+
+	spec, hot := resizeSpec(mvm.Config, settings)
+	return spec, hot, nil
+}
+
+// reconfigureAppliance applies the spec to the appliance in its current power state
+func (d *Dispatcher) reconfigureAppliance(spec types.VirtualMachineConfigSpec) error {
+	defer trace.End(trace.Begin(""))
+
+	info, err := d.appliance.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+		return d.appliance.Reconfigure(ctx, spec)
+	})
+	if err != nil {
+		log.Errorf("Error while reconfiguring appliance: %s", err)
+		return err
+	}
+	if err = tasks.TaskError(info); err != nil {
+		log.Errorf("Reconfiguring appliance reported: %s", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/install/data"
+)
+
+func TestApplianceAllocation(t *testing.T) {
+	assert.Nil(t, applianceAllocation(nil, types.ResourceAllocationInfo{}))
+
+	alloc := applianceAllocation(nil, types.ResourceAllocationInfo{Reservation: 512})
+	require.NotNil(t, alloc)
+	assert.Equal(t, int64(512), alloc.Reservation)
+	assert.Equal(t, int64(-1), alloc.Limit, "a new appliance is unlimited by default")
+
+	current := &types.ResourceAllocationInfo{
+		Reservation: 512,
+		Limit:       -1,
+		Shares:      &types.SharesInfo{Level: types.SharesLevelHigh, Shares: 2000},
+	}
+	assert.Nil(t, applianceAllocation(current, types.ResourceAllocationInfo{
+		Reservation: 512,
+		Shares:      &types.SharesInfo{Level: types.SharesLevelHigh},
+	}), "settings already in place are no change")
+
+	alloc = applianceAllocation(current, types.ResourceAllocationInfo{Limit: 4096})
+	require.NotNil(t, alloc)
+	assert.Equal(t, int64(512), alloc.Reservation, "unset values are kept")
+	assert.Equal(t, int64(4096), alloc.Limit)
+	assert.Equal(t, types.SharesLevelHigh, alloc.Shares.Level)
+
+	alloc = applianceAllocation(current, types.ResourceAllocationInfo{Shares: &types.SharesInfo{Level: types.SharesLevelCustom, Shares: 2000}})
+	require.NotNil(t, alloc, "custom shares differ from a level")
+	assert.Equal(t, types.SharesLevelCustom, alloc.Shares.Level)
+}
+
+func TestResizeSpec(t *testing.T) {
+	current := &types.VirtualMachineConfigInfo{
+		Hardware:            types.VirtualHardware{NumCPU: 2, MemoryMB: 2048},
+		CpuHotAddEnabled:    types.NewBool(true),
+		MemoryHotAddEnabled: types.NewBool(true),
+	}
+
+	var tests = []struct {
+		settings data.InstallerData
+		change   bool
+		hot      bool
+	}{
+		{data.InstallerData{}, false, false},
+		{data.InstallerData{ApplianceCPUs: 2, ApplianceMemoryMB: 2048}, false, false},
+		{data.InstallerData{ApplianceCPUs: 4}, true, true},
+		{data.InstallerData{ApplianceCPUs: 4, ApplianceMemoryMB: 4096}, true, true},
+		{data.InstallerData{ApplianceCPUs: 1}, true, false},
+		{data.InstallerData{ApplianceCPUs: 4, ApplianceMemoryMB: 1024}, true, false},
+	}
+
+	for _, test := range tests {
+		spec, hot := resizeSpec(current, &test.settings)
+		assert.Equal(t, test.change, spec != nil, "%+v", test.settings)
+		assert.Equal(t, test.hot, hot, "%+v", test.settings)
+		if spec != nil && !hot {
+			assert.True(t, *spec.CpuHotAddEnabled, "hot add is enabled when powered off")
+		}
+	}
+
+	// allocations change on a running appliance
+	settings := &data.InstallerData{}
+	settings.ApplianceSize.Memory.Reservation = 1024
+	spec, hot := resizeSpec(current, settings)
+	require.NotNil(t, spec)
+	assert.True(t, hot)
+	assert.Equal(t, int64(1024), spec.MemoryAllocation.GetResourceAllocationInfo().Reservation)
+
+	// appliances without hot add must be powered off to grow
+	current.CpuHotAddEnabled = nil
+	spec, hot = resizeSpec(current, &data.InstallerData{ApplianceCPUs: 4})
+	require.NotNil(t, spec)
+	assert.False(t, hot)
+	assert.Equal(t, int32(4), spec.NumCPUs)
+}
@@ -16,6 +16,7 @@ package management
 
 import (
 	"context"
+	"fmt"
 	"path"
 
 	log "github.com/Sirupsen/logrus"
@@ -100,6 +101,12 @@ func (d *Dispatcher) createResourcePool(conf *config.VirtualContainerHostConfigS
 	}
 
 	conf.ComputeResources = append(conf.ComputeResources, rp.Reference())
+	d.undo.push(fmt.Sprintf("resource pool %q", d.vchPoolPath), func() error {
+		_, err := tasks.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+			return rp.Destroy(ctx)
+		})
+		return err
+	})
 	return rp, nil
 }
 
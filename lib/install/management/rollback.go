@@ -0,0 +1,95 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// rollbackStep is the undo action for a single resource created by the dispatcher
+type rollbackStep struct {
+	name string
+	undo func() error
+}
+
+// rollbackStack records an undo action for each resource as it is created so that a failed
+// operation can be unwound in reverse order of creation.
+type rollbackStack struct {
+	steps []rollbackStep
+}
+
+// push registers the undo action for a resource that has just been created
+func (r *rollbackStack) push(name string, undo func() error) {
+	log.Debugf("Registered rollback step %q", name)
+	r.steps = append(r.steps, rollbackStep{name: name, undo: undo})
+}
+
+// reset discards all registered undo actions, e.g. once the operation has succeeded
+func (r *rollbackStack) reset() {
+	r.steps = nil
+}
+
+// unwind runs the registered undo actions in reverse order. Every step is attempted even if
+// an earlier one fails, and a RollbackError listing the failed steps is returned if any did.
+func (r *rollbackStack) unwind() error {
+	if len(r.steps) == 0 {
+		return nil
+	}
+
+	log.Infof("Rolling back %d created resource(s)", len(r.steps))
+
+	rerr := &RollbackError{}
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+
+		log.Infof("\tRolling back %s", step.name)
+		if err := step.undo(); err != nil {
+			log.Errorf("\tFailed to roll back %s: %s", step.name, err)
+			rerr.Failed = append(rerr.Failed, step.name)
+			rerr.Errors = append(rerr.Errors, err)
+			continue
+		}
+		log.Debugf("\tRolled back %s", step.name)
+	}
+	r.reset()
+
+	if len(rerr.Failed) == 0 {
+		log.Infof("Rollback completed successfully")
+		return nil
+	}
+
+	log.Errorf("Rollback did not complete, the following resources must be removed manually:")
+	for i := range rerr.Failed {
+		log.Errorf("\t%s: %s", rerr.Failed[i], rerr.Errors[i])
+	}
+	return rerr
+}
+
+// RollbackError reports the resources that could not be cleaned up after a failed operation
+type RollbackError struct {
+	Failed []string
+	Errors []error
+}
+
+func (e *RollbackError) Error() string {
+	details := make([]string, len(e.Failed))
+	for i := range e.Failed {
+		details[i] = fmt.Sprintf("%s (%s)", e.Failed[i], e.Errors[i])
+	}
+	return fmt.Sprintf("failed to roll back %d resource(s): %s", len(e.Failed), strings.Join(details, ", "))
+}
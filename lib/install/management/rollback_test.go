@@ -0,0 +1,79 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackUnwind(t *testing.T) {
+	var r rollbackStack
+	var order []string
+
+	for _, name := range []string{"pool", "network", "vm"} {
+		n := name
+		r.push(n, func() error {
+			order = append(order, n)
+			return nil
+		})
+	}
+
+	assert.NoError(t, r.unwind())
+	assert.Equal(t, []string{"vm", "network", "pool"}, order)
+
+	// steps are consumed by unwind
+	order = nil
+	assert.NoError(t, r.unwind())
+	assert.Empty(t, order)
+}
+
+func TestRollbackUnwindFailure(t *testing.T) {
+	var r rollbackStack
+	var order []string
+
+	r.push("pool", func() error {
+		order = append(order, "pool")
+		return nil
+	})
+	r.push("vm", func() error {
+		order = append(order, "vm")
+		return errors.New("vm is locked")
+	})
+
+	err := r.unwind()
+	rerr, ok := err.(*RollbackError)
+	if !assert.True(t, ok, "expected RollbackError, got %#v", err) {
+		return
+	}
+
+	// a failed step does not prevent the remaining steps from running
+	assert.Equal(t, []string{"vm", "pool"}, order)
+	assert.Equal(t, []string{"vm"}, rerr.Failed)
+	assert.Contains(t, rerr.Error(), "vm is locked")
+}
+
+func TestRollbackReset(t *testing.T) {
+	var r rollbackStack
+	r.push("vm", func() error {
+		t.Fatal("reset steps must not run")
+		return nil
+	})
+
+	r.reset()
+	assert.NoError(t, r.unwind())
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/compute"
+)
+
+// createApplianceRules keeps the appliance on the requested DRS host group. The rules for
+// containerVMs are created by the port layer as containers are created.
+func (d *Dispatcher) createApplianceRules(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+	defer trace.End(trace.Begin(settings.ApplianceHostGroup))
+
+	if settings.ApplianceHostGroup == "" {
+		return nil
+	}
+
+	info, err := compute.ClusterConfig(d.ctx, d.session.Cluster)
+	if err != nil {
+		return err
+	}
+
+	group := compute.ApplianceVMGroupName(conf.Name)
+	groupSpec, err := compute.VMGroupSpec(info, group, d.appliance.Reference())
+	if err != nil {
+		return err
+	}
+
+	spec := &types.ClusterConfigSpecEx{
+		GroupSpec: []types.ClusterGroupSpec{groupSpec},
+		RulesSpec: []types.ClusterRuleSpec{
+			compute.VMHostRuleSpec(compute.HostRuleName(group), group, settings.ApplianceHostGroup, settings.HostGroupMandatory),
+		},
+	}
+
+	log.Infof("Keeping appliance on DRS host group %q", settings.ApplianceHostGroup)
+	if err = compute.ReconfigureCluster(d.ctx, d.session.Cluster, spec); err != nil {
+		return errors.Errorf("Failed to create DRS rule for appliance: %s", err)
+	}
+
+	d.undo.push(fmt.Sprintf("DRS rules for %q", conf.Name), func() error {
+		return d.deleteRules(conf)
+	})
+	return nil
+}
+
+// configureApplianceHA applies the vSphere HA overrides for the appliance, so that it is restarted
+// as requested after a host failure. The overrides are removed by vSphere with the appliance.
+func (d *Dispatcher) configureApplianceHA(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	if !settings.ApplianceHA.IsSet() {
+		return nil
+	}
+
+	info, err := compute.ClusterConfig(d.ctx, d.session.Cluster)
+	if err != nil {
+		return err
+	}
+
+	spec := &types.ClusterConfigSpecEx{
+		DasVmConfigSpec: []types.ClusterDasVmConfigSpec{
+			compute.DasVMConfigSpec(info, d.appliance.Reference(), settings.ApplianceHA),
+		},
+	}
+
+	log.Infof("Setting vSphere HA overrides for appliance")
+	if err = compute.ReconfigureCluster(d.ctx, d.session.Cluster, spec); err != nil {
+		return errors.Errorf("Failed to set vSphere HA overrides for appliance: %s", err)
+	}
+	return nil
+}
+
+// deleteRules removes the DRS VM groups and rules created for the appliance and its containerVMs
+func (d *Dispatcher) deleteRules(conf *config.VirtualContainerHostConfigSpec) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	if !d.isVC || d.session.Cluster == nil || d.session.Cluster.Reference().Type != "ClusterComputeResource" {
+		return nil
+	}
+
+	info, err := compute.ClusterConfig(d.ctx, d.session.Cluster)
+	if err != nil {
+		return err
+	}
+
+	groups := map[string]bool{
+		compute.ApplianceVMGroupName(conf.Name): true,
+	}
+	if conf.ContainerVMGroup != "" {
+		groups[conf.ContainerVMGroup] = true
+	}
+
+	rules := make(map[string]bool, len(groups))
+	for group := range groups {
+		rules[compute.HostRuleName(group)] = true
+	}
+
+	spec := compute.RemoveSpec(info,
+		func(name string) bool {
+			return rules[name] || (conf.AntiAffinityRulePrefix != "" && strings.HasPrefix(name, conf.AntiAffinityRulePrefix))
+		},
+		func(name string) bool {
+			return groups[name]
+		})
+	if spec == nil {
+		return nil
+	}
+
+	log.Infof("Removing DRS rules")
+	return compute.ReconfigureCluster(d.ctx, d.session.Cluster, spec)
+}
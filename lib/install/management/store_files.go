@@ -30,6 +30,7 @@ import (
 	"github.com/vmware/vic/lib/portlayer/storage/vsphere"
 	"github.com/vmware/vic/lib/portlayer/store"
 	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/nfs"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/datastore"
 	"github.com/vmware/vic/pkg/vsphere/tasks"
@@ -268,7 +269,12 @@ func (d *Dispatcher) lsFolder(ds *object.Datastore, dsPath string) (*types.HostD
 
 func (d *Dispatcher) createVolumeStores(conf *config.VirtualContainerHostConfigSpec) error {
 	defer trace.End(trace.Begin(""))
-	for _, url := range conf.VolumeLocations {
+	for label, url := range conf.VolumeLocations {
+		// NFS exports are prepared by the port layer when it mounts them
+		if nfs.IsExport(url) {
+			continue
+		}
+
 		ds, err := d.session.Finder.Datastore(d.ctx, url.Host)
 		if err != nil {
 			return errors.Errorf("Could not retrieve datastore with host %q due to error %s", url.Host, err)
@@ -278,36 +284,67 @@ func (d *Dispatcher) createVolumeStores(conf *config.VirtualContainerHostConfigS
 			url.Path = vsphere.StorageParentDir
 		}
 
+		// existing volume stores may hold volumes, so only those created here are removed on rollback
+		_, serr := ds.Stat(d.ctx, url.Path)
+
 		nds, err := datastore.NewHelper(d.ctx, d.session, ds, url.Path)
 		if err != nil {
 			return errors.Errorf("Could not create volume store due to error: %s", err)
 		}
+
+		switch serr.(type) {
+		case object.DatastoreNoSuchFileError, object.DatastoreNoSuchDirectoryError:
+			storePath := url.Path
+			d.undo.push(fmt.Sprintf("volume store %q", label), func() error {
+				_, err := d.deleteDatastoreFiles(ds, storePath, true)
+				return err
+			})
+		}
 		// FIXME: (GitHub Issue #1301) this is not valid URL syntax and should be translated appropriately when time allows
 		url.Path = nds.RootURL
 	}
 	return nil
 }
 
-// returns # of removed stores
-func (d *Dispatcher) deleteVolumeStoreIfForced(conf *config.VirtualContainerHostConfigSpec) (removed int) {
-	defer trace.End(trace.Begin(""))
+// deleteVolumeStores removes the volume stores if the action is to delete them, or if none is
+// given and the delete is forced, and returns # of removed stores
+func (d *Dispatcher) deleteVolumeStores(conf *config.VirtualContainerHostConfigSpec, action VolumeAction) (removed int) {
+	defer trace.End(trace.Begin(string(action)))
 	removed = 0
 
-	if !d.force {
+	if action == VolumesDefault && d.force {
+		action = VolumesDelete
+	}
+
+	if action != VolumesDelete {
 		if len(conf.VolumeLocations) == 0 {
 			return 0
 		}
 
 		volumeStores := new(bytes.Buffer)
 		for label, url := range conf.VolumeLocations {
+			if nfs.IsExport(url) {
+				volumeStores.WriteString(fmt.Sprintf("\t%s: %s\n", label, url.String()))
+				continue
+			}
 			volumeStores.WriteString(fmt.Sprintf("\t%s: %s\n", label, url.Path))
 		}
-		log.Warnf("Since --force was not specified, the following volume stores will not be removed. Use the vSphere UI to delete content you do not wish to keep.\n%s", volumeStores.String())
+		if action == VolumesPreserve {
+			log.Infof("Preserving the following volume stores. Give them to vic-machine create with --volume-store for a new VCH to use the volumes in them.\n%s", volumeStores.String())
+			return 0
+		}
+		log.Warnf("Since --force was not specified, the following volume stores will not be removed. Use --volume-action delete to remove them, or the vSphere UI to delete content you do not wish to keep.\n%s", volumeStores.String())
 		return 0
 	}
 
 	log.Infoln("Removing volume stores")
 	for label, url := range conf.VolumeLocations {
+		// the volumes on NFS exports may be shared with other VCHs, so they are left in place
+		if nfs.IsExport(url) {
+			log.Infof("Leaving volume store %q on NFS export %q, remove its volumes directory on the file server if it is no longer needed", label, url.String())
+			continue
+		}
+
 		// FIXME: url is being encoded by the portlayer incorrectly, so we have to convert url.Path to the right url.URL object
 		dsURL, err := datastore.ToURL(url.Path)
 		if err != nil {
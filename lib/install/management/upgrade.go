@@ -22,8 +22,6 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 
-	"github.com/docker/docker/opts"
-
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
@@ -43,6 +41,11 @@ const (
 func (d *Dispatcher) Upgrade(vch *vm.VirtualMachine, conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) (err error) {
 	defer trace.End(trace.Begin(conf.Name))
 
+	d.beginProgress(OperationUpgrade)
+	defer func() {
+		d.endProgress(err)
+	}()
+
 	d.appliance = vch
 
 	// update the displayname to the actual folder name used
@@ -57,14 +60,9 @@ func (d *Dispatcher) Upgrade(vch *vm.VirtualMachine, conf *config.VirtualContain
 		return err
 	}
 	d.session.Datastore = ds
-	if !conf.HostCertificate.IsNil() {
-		d.VICAdminProto = "https"
-		d.DockerPort = fmt.Sprintf("%d", opts.DefaultTLSHTTPPort)
-	} else {
-		d.VICAdminProto = "http"
-		d.DockerPort = fmt.Sprintf("%d", opts.DefaultHTTPPort)
-	}
+	d.setDockerPort(conf)
 
+	d.reportProgress("Uploading images", 0)
 	if err = d.uploadImages(settings.ImageFiles); err != nil {
 		return errors.Errorf("Uploading images failed with %s. Exiting...", err)
 	}
@@ -78,6 +76,7 @@ func (d *Dispatcher) Upgrade(vch *vm.VirtualMachine, conf *config.VirtualContain
 
 	snapshotName := fmt.Sprintf("%s %s", UpgradePrefix, conf.Version.BuildNumber)
 	snapshotName = strings.TrimSpace(snapshotName)
+	d.reportProgress("Creating upgrade snapshot", 40)
 	snapshotRefID, err := d.createSnapshot(snapshotName, "upgrade snapshot")
 	if err != nil {
 		d.deleteUpgradeImages(ds, settings)
@@ -90,11 +89,21 @@ func (d *Dispatcher) Upgrade(vch *vm.VirtualMachine, conf *config.VirtualContain
 		}
 	}()
 
+	d.reportProgress("Upgrading appliance", 50)
 	if err = d.update(conf, settings); err == nil {
-		return nil
+		// the new appliance must pass its health check before the snapshot is discarded
+		d.reportProgress("Checking docker API", 85)
+		if err = d.CheckDockerAPI(conf, nil); err == nil {
+			d.obsoleteKeys = nil
+			if perr := d.publishEndpoints(conf); perr != nil {
+				log.Warnf("Unable to publish VCH endpoints on its virtual app: %s", perr)
+			}
+			return nil
+		}
 	}
 	log.Errorf("Failed to upgrade: %s", err)
 	log.Infof("Rolling back upgrade")
+	d.reportProgress("Rolling back upgrade", 90)
 
 	// reset timeout, to make sure rollback still happens in case of deadline exceeded error in previous step
 	var cancel context.CancelFunc
@@ -150,7 +159,7 @@ func (d *Dispatcher) tryCreateSnapshot(name, desc string) (*types.ManagedObjectR
 		return nil, err
 	}
 	if upgrading {
-		return nil, errors.Errorf("Detected another upgrade process in progress. If this is incorrect, manually remove appliance snapshot %q and restart upgrade", snapshot)
+		return nil, &ConflictError{Message: fmt.Sprintf("Detected another upgrade process in progress. If this is incorrect, manually remove appliance snapshot %q and restart upgrade", snapshot)}
 	}
 
 	taskInfo, err := d.appliance.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
@@ -173,14 +182,14 @@ func (d *Dispatcher) deleteUpgradeImages(ds *object.Datastore, settings *data.In
 
 	m := object.NewFileManager(ds.Client())
 
-	file := ds.Path(path.Join(d.vmPathName, settings.ApplianceISO))
-	if err := d.deleteVMFSFiles(m, ds, file); err != nil {
-		log.Warnf("Image file %q is not removed for %s. Use the vSphere UI to delete content", file, err)
-	}
+	for _, iso := range []string{settings.ApplianceISO, settings.BootstrapISO} {
+		file := ds.Path(path.Join(d.vmPathName, iso))
+		if err := d.deleteVMFSFiles(m, ds, file); err != nil {
+			log.Warnf("Image file %q is not removed for %s. Use the vSphere UI to delete content", file, err)
+		}
 
-	file = ds.Path(path.Join(d.vmPathName, settings.BootstrapISO))
-	if err := d.deleteVMFSFiles(m, ds, file); err != nil {
-		log.Warnf("Image file %q is not removed for %s. Use the vSphere UI to delete content", file, err)
+		// the checksum is recorded once the image is fully uploaded, and may not exist
+		_ = d.deleteVMFSFiles(m, ds, file+checksumSuffix)
 	}
 }
 
@@ -256,6 +265,11 @@ func (d *Dispatcher) reconfigVCH(conf *config.VirtualContainerHostConfigSpec, is
 		}
 
 		spec.ExtraConfig = append(spec.ExtraConfig, vmomi.OptionValueFromMap(cfg)...)
+
+		if len(d.obsoleteKeys) > 0 {
+			log.Infof("Removing %d obsolete configuration key(s)", len(d.obsoleteKeys))
+			spec.ExtraConfig = append(spec.ExtraConfig, removeKeysSpec(d.obsoleteKeys)...)
+		}
 	}
 
 	if spec.DeviceChange == nil && spec.ExtraConfig == nil {
@@ -274,8 +288,8 @@ func (d *Dispatcher) reconfigVCH(conf *config.VirtualContainerHostConfigSpec, is
 		log.Errorf("Error while reconfiguring appliance: %s", err)
 		return err
 	}
-	if info.State != types.TaskInfoStateSuccess {
-		log.Errorf("Reconfiguring appliance reported: %s", info.Error.LocalizedMessage)
+	if err = tasks.TaskError(info); err != nil {
+		log.Errorf("Reconfiguring appliance reported: %s", err)
 		return err
 	}
 	return nil
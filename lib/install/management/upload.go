@@ -0,0 +1,248 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+const (
+	// checksumSuffix is appended to the name of an uploaded image for the file holding its SHA256 checksum,
+	// in the format of sha256sum
+	checksumSuffix = ".sha256"
+
+	// uploadAttempts is how many times an image is uploaded before giving up
+	uploadAttempts = 3
+	// uploadRetryDelay is the delay before the first retry of a failed upload, doubled on each retry
+	uploadRetryDelay = 5 * time.Second
+)
+
+// uploadImage uploads the image file to name in the appliance folder, unless an identical image is already
+// there. The checksum file is written before the image, so that a partial image left by a failed upload,
+// in this or an earlier create or upgrade, can be resumed from its current size. The image is verified by
+// its size on the datastore and the checksum of the bytes sent, rather than by reading it back.
+func (d *Dispatcher) uploadImage(image, name string) error {
+	defer trace.End(trace.Begin(image))
+
+	sum, size, err := fileChecksum(image)
+	if err != nil {
+		return err
+	}
+
+	dsPath := path.Join(d.vmPathName, name)
+	uploaded, err := d.uploadedSize(dsPath, sum)
+	if err == nil && uploaded == size {
+		log.Infof("\t%q is already uploaded, skipping", image)
+		return nil
+	}
+
+	if err != nil {
+		// any existing image is not this one
+		uploaded = 0
+
+		line := checksumLine(sum, name)
+		param := soap.DefaultUpload
+		param.ContentLength = int64(len(line))
+		if err = d.session.Datastore.Upload(d.ctx, strings.NewReader(line), dsPath+checksumSuffix, &param); err != nil {
+			return err
+		}
+	}
+
+	delay := uploadRetryDelay
+	for attempt := 1; ; attempt++ {
+		if uploaded > 0 && uploaded < size {
+			log.Infof("\tResuming upload of %q from %d of %d bytes", image, uploaded, size)
+			err = d.uploadFrom(image, dsPath, sum, uploaded, size)
+			if err == errResumeUnsupported {
+				log.Infof("\tThe datastore does not support resuming uploads, uploading %q from the start", image)
+				err = d.uploadFrom(image, dsPath, sum, 0, size)
+			}
+		} else {
+			err = d.uploadFrom(image, dsPath, sum, 0, size)
+		}
+		if err == nil {
+			return nil
+		}
+
+		if attempt == uploadAttempts || d.ctx.Err() != nil {
+			return err
+		}
+
+		log.Warnf("\t\tUpload attempt %d of %d failed for %q, retrying in %s: %s", attempt, uploadAttempts, image, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		}
+		delay *= 2
+
+		if uploaded, err = d.uploadedSize(dsPath, sum); err != nil {
+			uploaded = 0
+		}
+	}
+}
+
+// errResumeUnsupported is returned by uploadFrom when the datastore replaced the image with the bytes sent
+// instead of writing them at the requested offset
+var errResumeUnsupported = errors.New("resuming uploads is not supported")
+
+// imageUploaded returns whether the image at dsPath has the given checksum and size, according to its
+// checksum file
+func (d *Dispatcher) imageUploaded(dsPath, sum string, size int64) bool {
+	uploaded, err := d.uploadedSize(dsPath, sum)
+	return err == nil && uploaded == size
+}
+
+// uploadedSize returns the size of the image at dsPath on the datastore, if its checksum file has the given
+// checksum
+func (d *Dispatcher) uploadedSize(dsPath, sum string) (int64, error) {
+	r, _, err := d.session.Datastore.Download(d.ctx, dsPath+checksumSuffix, &soap.DefaultDownload)
+	if err != nil {
+		log.Debugf("No checksum for %q: %s", dsPath, err)
+		return 0, err
+	}
+	defer r.Close()
+
+	uploaded, err := parseChecksum(io.LimitReader(r, 4096), path.Base(dsPath))
+	if err != nil {
+		log.Debugf("Invalid checksum for %q: %s", dsPath, err)
+		return 0, err
+	}
+	if uploaded != sum {
+		return 0, fmt.Errorf("checksum of %q is %s, expected %s", dsPath, uploaded, sum)
+	}
+
+	info, err := d.session.Datastore.Stat(d.ctx, dsPath)
+	if err != nil {
+		if _, ok := err.(object.DatastoreNoSuchFileError); ok {
+			return 0, nil
+		}
+		log.Debugf("Unable to stat %q: %s", dsPath, err)
+		return 0, err
+	}
+
+	return info.GetFileInfo().FileSize, nil
+}
+
+// uploadFrom uploads the image file from offset to dsPath, writing the bytes at the same offset when offset
+// is not zero. It checks that the image on the datastore has the given size afterwards, and that the image
+// file, including the part already uploaded, still has the given checksum.
+func (d *Dispatcher) uploadFrom(image, dsPath, sum string, offset, size int64) error {
+	f, err := os.Open(image)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.CopyN(h, f, offset); err != nil {
+		return err
+	}
+
+	param := soap.DefaultUpload
+	param.ContentLength = size - offset
+	if offset > 0 {
+		param.Headers = map[string]string{
+			"Content-Range": fmt.Sprintf("bytes %d-%d/%d", offset, size-1, size),
+		}
+	}
+
+	if err = d.session.Datastore.Upload(d.ctx, io.TeeReader(io.LimitReader(f, size-offset), h), dsPath, &param); err != nil {
+		return err
+	}
+
+	info, err := d.session.Datastore.Stat(d.ctx, dsPath)
+	if err != nil {
+		return fmt.Errorf("unable to stat uploaded %q: %s", dsPath, err)
+	}
+
+	if uploaded := info.GetFileInfo().FileSize; uploaded != size {
+		if offset > 0 && uploaded == size-offset {
+			return errResumeUnsupported
+		}
+		return fmt.Errorf("size of uploaded %q is %d, expected %d", dsPath, uploaded, size)
+	}
+
+	if sent := hex.EncodeToString(h.Sum(nil)); sent != sum {
+		return fmt.Errorf("checksum of uploaded %q is %s, expected %s, was it changed during the upload?", dsPath, sent, sum)
+	}
+
+	return nil
+}
+
+// fileChecksum returns the hex encoded SHA256 checksum and the size of the file
+func fileChecksum(name string) (string, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// checksumLine formats the checksum of the named file as sha256sum does
+func checksumLine(sum, name string) string {
+	return fmt.Sprintf("%s  %s\n", sum, name)
+}
+
+// parseChecksum returns the checksum of the named file from the output of sha256sum
+func parseChecksum(r io.Reader, name string) (string, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := bytes.Fields(s.Bytes())
+		if len(fields) != 2 {
+			continue
+		}
+
+		// sha256sum marks files read in binary mode with a *
+		if strings.TrimPrefix(string(fields[1]), "*") != name {
+			continue
+		}
+
+		sum := string(fields[0])
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return "", fmt.Errorf("invalid checksum %q for %s", sum, name)
+		}
+		return strings.ToLower(sum), nil
+	}
+
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum for %s", name)
+}
@@ -0,0 +1,153 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/vim25/soap"
+
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/vsphere/simulator"
+)
+
+func TestFileChecksum(t *testing.T) {
+	f, err := ioutil.TempFile("", "vic-iso")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("hello\n")
+	require.NoError(t, err)
+	f.Close()
+
+	sum, size, err := fileChecksum(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", sum)
+	assert.EqualValues(t, 6, size)
+
+	_, _, err = fileChecksum(f.Name() + ".missing")
+	assert.Error(t, err)
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+	parsed, err := parseChecksum(strings.NewReader(checksumLine(sum, "appliance.iso")), "appliance.iso")
+	if assert.NoError(t, err) {
+		assert.Equal(t, sum, parsed)
+	}
+
+	// output of sha256sum -b for several files
+	out := "0000000000000000000000000000000000000000000000000000000000000000 *bootstrap.iso\n" + strings.ToUpper(sum) + " *appliance.iso\n"
+	parsed, err = parseChecksum(strings.NewReader(out), "appliance.iso")
+	if assert.NoError(t, err) {
+		assert.Equal(t, sum, parsed)
+	}
+
+	for _, out := range []string{
+		"",
+		checksumLine(sum, "bootstrap.iso"),
+		checksumLine(sum[1:], "appliance.iso"),
+		checksumLine("not-hex", "appliance.iso"),
+	} {
+		_, err = parseChecksum(strings.NewReader(out), "appliance.iso")
+		assert.Error(t, err, out)
+	}
+}
+
+func TestUploadImage(t *testing.T) {
+	ctx := context.Background()
+
+	model := simulator.VPX()
+	defer model.Remove()
+	require.NoError(t, model.Create())
+
+	s := model.Service.NewServer()
+	defer s.Close()
+	s.URL.User = url.UserPassword("user", "pass")
+	s.URL.Path = ""
+
+	validator, err := validate.CreateNoDCCheck(ctx, getVPXData(s.URL))
+	require.NoError(t, err)
+
+	d := &Dispatcher{
+		session: validator.Session,
+		ctx:     validator.Context,
+		isVC:    validator.Session.IsVC(),
+	}
+	d.session.Datastore, err = d.session.Finder.Datastore(ctx, "LocalDS_0")
+	require.NoError(t, err)
+
+	iso := bytes.Repeat([]byte("appliance"), 1024)
+	f, err := ioutil.TempFile("", "vic-iso")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.Write(iso)
+	require.NoError(t, err)
+	f.Close()
+
+	sum, size, err := fileChecksum(f.Name())
+	require.NoError(t, err)
+
+	download := func(name string) []byte {
+		r, _, err := d.session.Datastore.Download(ctx, name, &soap.DefaultDownload)
+		require.NoError(t, err)
+		defer r.Close()
+
+		b, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		return b
+	}
+
+	require.NoError(t, d.uploadImage(f.Name(), "appliance.iso"))
+	assert.Equal(t, iso, download("appliance.iso"))
+	assert.Equal(t, checksumLine(sum, "appliance.iso"), string(download("appliance.iso"+checksumSuffix)))
+	assert.True(t, d.imageUploaded("appliance.iso", sum, size))
+
+	// a partial image is resumed, and uploaded from the start as the simulator replaces the file on PUT
+	param := soap.DefaultUpload
+	param.ContentLength = size / 2
+	require.NoError(t, d.session.Datastore.Upload(ctx, bytes.NewReader(iso[:size/2]), "appliance.iso", &param))
+	assert.False(t, d.imageUploaded("appliance.iso", sum, size))
+
+	uploaded, err := d.uploadedSize("appliance.iso", sum)
+	require.NoError(t, err)
+	assert.Equal(t, size/2, uploaded)
+
+	assert.Equal(t, errResumeUnsupported, d.uploadFrom(f.Name(), "appliance.iso", sum, uploaded, size))
+	require.NoError(t, d.uploadImage(f.Name(), "appliance.iso"))
+	assert.Equal(t, iso, download("appliance.iso"))
+
+	// a different image replaces the existing one and its checksum
+	iso = append(iso, "v2"...)
+	require.NoError(t, ioutil.WriteFile(f.Name(), iso, 0644))
+
+	require.NoError(t, d.uploadImage(f.Name(), "appliance.iso"))
+	assert.Equal(t, iso, download("appliance.iso"))
+	assert.False(t, d.imageUploaded("appliance.iso", sum, size))
+
+	sum, size, err = fileChecksum(f.Name())
+	require.NoError(t, err)
+	assert.True(t, d.imageUploaded("appliance.iso", sum, size))
+}
@@ -15,16 +15,25 @@
 package management
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/version"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
 )
 
 func (d *Dispatcher) createVApp(conf *config.VirtualContainerHostConfigSpec, settings *data.InstallerData) (*object.VirtualApp, error) {
@@ -94,12 +103,23 @@ func (d *Dispatcher) createVApp(conf *config.VirtualContainerHostConfigSpec, set
 		},
 	}
 
-	app, err := d.session.Pool.CreateVApp(d.ctx, conf.Name, resSpec, configSpec, d.session.Folders(d.ctx).VmFolder)
+	folder, err := d.applianceFolder(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := d.session.Pool.CreateVApp(d.ctx, conf.Name, resSpec, configSpec, folder)
 	if err != nil {
 		log.Debugf("Failed to create virtual app %q: %s", conf.Name, err)
 		return nil, err
 	}
 	conf.ComputeResources = append(conf.ComputeResources, app.Reference())
+	d.undo.push(fmt.Sprintf("virtual app %q", conf.Name), func() error {
+		_, err := tasks.WaitForResult(d.ctx, func(ctx context.Context) (tasks.Task, error) {
+			return app.Destroy(ctx)
+		})
+		return err
+	})
 	return app, nil
 }
 
@@ -116,3 +136,196 @@ func (d *Dispatcher) findVirtualApp(path string) (*object.VirtualApp, error) {
 	}
 	return vapp, nil
 }
+
+// vApp properties used to publish the VCH endpoints on its virtual app
+const (
+	dockerEndpointProperty   = "vic.docker.endpoint"
+	vicadminEndpointProperty = "vic.vicadmin.url"
+	versionProperty          = "vic.version"
+)
+
+// vappProperty is a vApp property published by vic-machine
+type vappProperty struct {
+	id    string
+	label string
+	value string
+	// configurable properties can be edited from vSphere
+	configurable bool
+}
+
+// vchVirtualApp returns the virtual app containing the appliance, or nil if the VCH is not a virtual app
+func (d *Dispatcher) vchVirtualApp() (*object.VirtualApp, error) {
+	if d.vchVapp != nil {
+		return d.vchVapp, nil
+	}
+
+	var mvm mo.VirtualMachine
+	if err := d.appliance.Properties(d.ctx, d.appliance.Reference(), []string{"parentVApp"}, &mvm); err != nil {
+		return nil, errors.Errorf("Failed to get appliance virtual app: %s", err)
+	}
+	if mvm.ParentVApp == nil {
+		return nil, nil
+	}
+
+	return object.NewVirtualApp(d.session.Vim25(), *mvm.ParentVApp), nil
+}
+
+// publishEndpoints records the docker endpoint, vicadmin URL and version of the VCH as properties of
+// its virtual app, so that they are visible in vSphere without running inspect. VCHs deployed in a
+// resource pool are left unchanged.
+func (d *Dispatcher) publishEndpoints(conf *config.VirtualContainerHostConfigSpec) error {
+	defer trace.End(trace.Begin(conf.Name))
+
+	if !d.isVC || d.appliance == nil {
+		return nil
+	}
+
+	vapp, err := d.vchVirtualApp()
+	if err != nil || vapp == nil {
+		return err
+	}
+
+	var mapp mo.VirtualApp
+	if err = vapp.Properties(d.ctx, vapp.Reference(), []string{"vAppConfig"}, &mapp); err != nil {
+		return errors.Errorf("Failed to get virtual app configuration: %s", err)
+	}
+	var existing []types.VAppPropertyInfo
+	if mapp.VAppConfig != nil {
+		existing = mapp.VAppConfig.Property
+	}
+
+	d.setHostAddress(conf)
+
+	v := version.GetBuild()
+	if conf.Version != nil {
+		v = conf.Version
+	}
+
+	properties := []vappProperty{
+		{dockerEndpointProperty, "Docker endpoint", fmt.Sprintf("tcp://%s", net.JoinHostPort(d.HostIP, d.DockerPort)), false},
+		{vicadminEndpointProperty, "VCH Admin portal", fmt.Sprintf("%s://%s", d.VICAdminProto, net.JoinHostPort(d.HostIP, "2378")), false},
+		{versionProperty, "Version", v.ShortVersion(), false},
+	}
+
+	log.Infof("Publishing VCH endpoints on virtual app %q", conf.Name)
+	spec := types.VAppConfigSpec{
+		VmConfigSpec: types.VmConfigSpec{
+			Property: propertySpecs(existing, properties),
+		},
+	}
+	return vapp.UpdateVAppConfig(d.ctx, spec)
+}
+
+// propertySpecs returns the specs that set the properties, editing those already present in
+// existing and adding the rest with unused keys
+func propertySpecs(existing []types.VAppPropertyInfo, properties []vappProperty) []types.VAppPropertySpec {
+	var next int32
+	keys := make(map[string]int32)
+	for _, p := range existing {
+		keys[p.Id] = p.Key
+		if p.Key >= next {
+			next = p.Key + 1
+		}
+	}
+
+	var specs []types.VAppPropertySpec
+	for _, p := range properties {
+		op := types.ArrayUpdateOperationEdit
+		key, ok := keys[p.id]
+		if !ok {
+			op = types.ArrayUpdateOperationAdd
+			key = next
+			next++
+		}
+
+		specs = append(specs, types.VAppPropertySpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{
+				Operation: op,
+			},
+			Info: &types.VAppPropertyInfo{
+				Key:              key,
+				Id:               p.id,
+				Category:         "vSphere Integrated Containers",
+				Label:            p.label,
+				Type:             "string",
+				UserConfigurable: types.NewBool(p.configurable),
+				DefaultValue:     p.value,
+				Value:            p.value,
+			},
+		})
+	}
+
+	return specs
+}
+
+// applianceProperties returns the appliance settings that can be changed from vSphere, with their
+// current values
+func applianceProperties(conf *config.VirtualContainerHostConfigSpec) []vappProperty {
+	var dns []string
+	for name, endpoint := range conf.ExecutorConfig.Networks {
+		if name == conf.BridgeNetwork || len(dns) > 0 {
+			continue
+		}
+		for _, ns := range endpoint.Network.Nameservers {
+			dns = append(dns, ns.String())
+		}
+	}
+
+	properties := []vappProperty{
+		{executor.DNSProperty, "DNS servers", strings.Join(dns, ","), true},
+		{executor.DebugProperty, "Debug level", strconv.Itoa(conf.Diagnostics.DebugLevel), true},
+	}
+
+	var env []string
+	if personality, ok := conf.ExecutorConfig.Sessions["docker-personality"]; ok {
+		env = personality.Cmd.Env
+	}
+	for _, proxy := range []struct {
+		name  string
+		label string
+	}{
+		{"HTTP_PROXY", "HTTP proxy"},
+		{"HTTPS_PROXY", "HTTPS proxy"},
+		{"NO_PROXY", "Proxy exclusions"},
+	} {
+		var value string
+		for _, e := range env {
+			if strings.HasPrefix(e, proxy.name+"=") {
+				value = strings.TrimPrefix(e, proxy.name+"=")
+			}
+		}
+		properties = append(properties, vappProperty{executor.EnvProperty("docker-personality", proxy.name), proxy.label, value, true})
+	}
+
+	return properties
+}
+
+// appliancePropertiesSpec returns the vApp configuration that publishes the appliance settings as
+// properties of the appliance VM, delivered to the guest through its OVF environment. Returns nil if
+// the VCH is not a virtual app. The spec must be applied while the appliance is powered off.
+func (d *Dispatcher) appliancePropertiesSpec(conf *config.VirtualContainerHostConfigSpec) (*types.VmConfigSpec, error) {
+	defer trace.End(trace.Begin(conf.Name))
+
+	if !d.isVC || d.appliance == nil {
+		return nil, nil
+	}
+
+	vapp, err := d.vchVirtualApp()
+	if err != nil || vapp == nil {
+		return nil, err
+	}
+
+	var mvm mo.VirtualMachine
+	if err = d.appliance.Properties(d.ctx, d.appliance.Reference(), []string{"config.vAppConfig"}, &mvm); err != nil {
+		return nil, errors.Errorf("Failed to get appliance vApp configuration: %s", err)
+	}
+	var existing []types.VAppPropertyInfo
+	if mvm.Config != nil && mvm.Config.VAppConfig != nil {
+		existing = mvm.Config.VAppConfig.GetVmConfigInfo().Property
+	}
+
+	return &types.VmConfigSpec{
+		OvfEnvironmentTransport: []string{"com.vmware.guestInfo"},
+		Property:                propertySpecs(existing, applianceProperties(conf)),
+	}, nil
+}
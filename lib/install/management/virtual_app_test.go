@@ -0,0 +1,98 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+func TestPropertySpecs(t *testing.T) {
+	properties := []vappProperty{
+		{dockerEndpointProperty, "Docker endpoint", "tcp://10.0.0.2:2376", false},
+		{vicadminEndpointProperty, "VCH Admin portal", "https://10.0.0.2:2378", false},
+		{versionProperty, "Version", "v0.8.0-1234-abcdef", false},
+	}
+
+	specs := propertySpecs(nil, properties)
+	require.Len(t, specs, 3)
+	for i, s := range specs {
+		assert.Equal(t, types.ArrayUpdateOperationAdd, s.Operation)
+		assert.Equal(t, int32(i), s.Info.Key)
+		assert.Equal(t, properties[i].id, s.Info.Id)
+		assert.Equal(t, properties[i].value, s.Info.Value)
+		assert.False(t, *s.Info.UserConfigurable)
+	}
+
+	// existing properties are edited in place, new ones are given unused keys
+	existing := []types.VAppPropertyInfo{
+		{Key: 4, Id: "other"},
+		{Key: 7, Id: dockerEndpointProperty, Value: "tcp://10.0.0.1:2376"},
+	}
+	specs = propertySpecs(existing, properties)
+	require.Len(t, specs, 3)
+
+	assert.Equal(t, types.ArrayUpdateOperationEdit, specs[0].Operation)
+	assert.Equal(t, int32(7), specs[0].Info.Key)
+	assert.Equal(t, "tcp://10.0.0.2:2376", specs[0].Info.Value)
+
+	assert.Equal(t, types.ArrayUpdateOperationAdd, specs[1].Operation)
+	assert.Equal(t, int32(8), specs[1].Info.Key)
+	assert.Equal(t, types.ArrayUpdateOperationAdd, specs[2].Operation)
+	assert.Equal(t, int32(9), specs[2].Info.Key)
+}
+
+func TestApplianceProperties(t *testing.T) {
+	conf := &config.VirtualContainerHostConfigSpec{}
+	conf.BridgeNetwork = "bridge"
+	conf.Diagnostics.DebugLevel = 1
+	conf.AddNetwork(&executor.NetworkEndpoint{
+		Network: executor.ContainerNetwork{
+			Common: executor.Common{Name: "bridge"},
+		},
+	})
+	conf.AddNetwork(&executor.NetworkEndpoint{
+		Network: executor.ContainerNetwork{
+			Common:      executor.Common{Name: "client"},
+			Nameservers: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+		},
+	})
+	conf.AddComponent("docker-personality", &executor.SessionConfig{
+		Cmd: executor.Cmd{
+			Env: []string{"PATH=/sbin", "HTTP_PROXY=http://proxy:3128"},
+		},
+	})
+
+	values := make(map[string]string)
+	for _, p := range applianceProperties(conf) {
+		assert.True(t, p.configurable)
+		values[p.id] = p.value
+	}
+
+	assert.Equal(t, map[string]string{
+		executor.DNSProperty:   "10.0.0.1,10.0.0.2",
+		executor.DebugProperty: "1",
+		executor.EnvProperty("docker-personality", "HTTP_PROXY"):  "http://proxy:3128",
+		executor.EnvProperty("docker-personality", "HTTPS_PROXY"): "",
+		executor.EnvProperty("docker-personality", "NO_PROXY"):    "",
+	}, values)
+}
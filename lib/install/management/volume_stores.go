@@ -0,0 +1,158 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/docker/go-units"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/portlayer/storage/vsphere"
+	"github.com/vmware/vic/pkg/nfs"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/datastore"
+)
+
+// VolumeStores returns the volume stores of the VCH, sorted by name, with the capacity of their
+// datastores and the space used by the volumes in them. A store whose usage cannot be determined
+// is still reported, with the reason.
+func (d *Dispatcher) VolumeStores(conf *config.VirtualContainerHostConfigSpec) []VolumeStore {
+	defer trace.End(trace.Begin(conf.Name))
+
+	stores := make([]VolumeStore, 0, len(conf.VolumeLocations))
+	for name, u := range conf.VolumeLocations {
+		store := VolumeStore{Name: name, URL: u.String(), StoragePolicy: conf.VolumeStoragePolicies[name]}
+		if err := d.volumeStoreUsage(u, &store); err != nil {
+			log.Debugf("Failed to get usage of volume store %q: %s", name, err)
+			store.Error = err.Error()
+		}
+		stores = append(stores, store)
+	}
+	sort.Sort(byVolumeStoreName(stores))
+
+	return stores
+}
+
+// volumeStoreUsage fills in the capacity of the datastore backing the volume store at u, and the
+// number of volumes in the store and the space they use
+func (d *Dispatcher) volumeStoreUsage(u *url.URL, store *VolumeStore) error {
+	if nfs.IsExport(u) {
+		return fmt.Errorf("usage of volume stores on NFS exports is not available")
+	}
+
+	// once created, the path of a volume store is its datastore path rather than a URL path
+	dsURL, err := datastore.ToURL(u.Path)
+	if err != nil {
+		dsURL = &url.URL{Scheme: "ds", Host: u.Host, Path: u.Path}
+	}
+	store.Datastore = dsURL.Host
+
+	ds, err := d.session.Finder.Datastore(d.ctx, dsURL.Host)
+	if err != nil {
+		return fmt.Errorf("unable to find datastore %q: %s", dsURL.Host, err)
+	}
+
+	var mds mo.Datastore
+	if err = ds.Properties(d.ctx, ds.Reference(), []string{"summary"}, &mds); err != nil {
+		return fmt.Errorf("unable to get capacity of datastore %q: %s", dsURL.Host, err)
+	}
+	store.Capacity = mds.Summary.Capacity
+	store.FreeSpace = mds.Summary.FreeSpace
+
+	// volumes are kept in a directory each, below the volumes directory of the store
+	volumesDir := ds.Path(path.Join(strings.TrimPrefix(dsURL.Path, "/"), vsphere.VolumesDir))
+
+	b, err := ds.Browser(d.ctx)
+	if err != nil {
+		return err
+	}
+
+	spec := types.HostDatastoreBrowserSearchSpec{
+		MatchPattern: []string{"*"},
+		Details: &types.FileQueryFlags{
+			FileType: true,
+			FileSize: true,
+		},
+	}
+	task, err := b.SearchDatastoreSubFolders(d.ctx, volumesDir, &spec)
+	if err != nil {
+		return err
+	}
+	info, err := task.WaitForResult(d.ctx, nil)
+	if err != nil {
+		if types.IsFileNotFound(err) {
+			// nothing has been stored yet
+			return nil
+		}
+		return fmt.Errorf("unable to list volumes in %q: %s", volumesDir, err)
+	}
+
+	res := info.Result.(types.ArrayOfHostDatastoreBrowserSearchResults)
+	store.Volumes, store.Used = volumeUsage(volumesDir, res.HostDatastoreBrowserSearchResults)
+	return nil
+}
+
+// volumeUsage counts the volume directories directly below volumesDir and sums the size of all the
+// files below it from the results of a recursive datastore search
+func volumeUsage(volumesDir string, results []types.HostDatastoreBrowserSearchResults) (int, int64) {
+	volumes := 0
+	var used int64
+
+	for _, r := range results {
+		top := strings.TrimSuffix(r.FolderPath, "/") == strings.TrimSuffix(volumesDir, "/")
+
+		for _, f := range r.File {
+			if _, ok := f.(*types.FolderFileInfo); ok {
+				if top {
+					volumes++
+				}
+				continue
+			}
+			used += f.GetFileInfo().FileSize
+		}
+	}
+
+	return volumes, used
+}
+
+// showVolumeStores logs the capacity and usage of each volume store
+func showVolumeStores(stores []VolumeStore) {
+	if len(stores) == 0 {
+		return
+	}
+
+	log.Info("")
+	log.Info("Volume stores:")
+	for _, s := range stores {
+		if s.Error != "" {
+			log.Warnf("  %s: %s - %s", s.Name, s.URL, s.Error)
+			continue
+		}
+		log.Infof("  %s: %d volumes using %s on datastore %s, %s free of %s", s.Name, s.Volumes,
+			units.BytesSize(float64(s.Used)), s.Datastore, units.BytesSize(float64(s.FreeSpace)), units.BytesSize(float64(s.Capacity)))
+		if s.StoragePolicy != "" {
+			log.Infof("    storage policy: %s", s.StoragePolicy)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package management
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/vsphere/simulator"
+)
+
+func TestVolumeUsage(t *testing.T) {
+	dir := "[datastore1] volumes/test/volumes"
+
+	results := []types.HostDatastoreBrowserSearchResults{
+		{
+			FolderPath: dir,
+			File: []types.BaseFileInfo{
+				&types.FolderFileInfo{FileInfo: types.FileInfo{Path: "vol1", FileSize: 512}},
+				&types.FolderFileInfo{FileInfo: types.FileInfo{Path: "vol2", FileSize: 512}},
+			},
+		},
+		{
+			FolderPath: dir + "/vol1/",
+			File: []types.BaseFileInfo{
+				&types.VmDiskFileInfo{FileInfo: types.FileInfo{Path: "vol1.vmdk", FileSize: 1024}},
+				&types.FolderFileInfo{FileInfo: types.FileInfo{Path: "imageMetadata", FileSize: 512}},
+			},
+		},
+		{
+			FolderPath: dir + "/vol1/imageMetadata",
+			File: []types.BaseFileInfo{
+				&types.FileInfo{Path: "labels", FileSize: 10},
+			},
+		},
+		{
+			FolderPath: dir + "/vol2/",
+			File: []types.BaseFileInfo{
+				&types.VmDiskFileInfo{FileInfo: types.FileInfo{Path: "vol2.vmdk", FileSize: 2048}},
+			},
+		},
+	}
+
+	volumes, used := volumeUsage(dir+"/", results)
+	assert.Equal(t, 2, volumes)
+	assert.Equal(t, int64(1024+10+2048), used)
+
+	volumes, used = volumeUsage(dir, nil)
+	assert.Equal(t, 0, volumes)
+	assert.Equal(t, int64(0), used)
+}
+
+func TestCreateVolumeStoresRollback(t *testing.T) {
+	ctx := context.Background()
+
+	model := simulator.VPX()
+	defer model.Remove()
+	require.NoError(t, model.Create())
+
+	s := model.Service.NewServer()
+	defer s.Close()
+	s.URL.User = url.UserPassword("user", "pass")
+	s.URL.Path = ""
+
+	validator, err := validate.CreateNoDCCheck(ctx, getVPXData(s.URL))
+	require.NoError(t, err)
+
+	d := &Dispatcher{
+		session: validator.Session,
+		ctx:     validator.Context,
+		isVC:    validator.Session.IsVC(),
+	}
+
+	ds, err := d.session.Finder.Datastore(ctx, "LocalDS_0")
+	require.NoError(t, err)
+
+	conf := &config.VirtualContainerHostConfigSpec{}
+	conf.VolumeLocations = map[string]*url.URL{
+		"default": {Scheme: "ds", Host: "LocalDS_0", Path: "volumes/test"},
+	}
+
+	// a new volume store is removed on rollback
+	require.NoError(t, d.createVolumeStores(conf))
+	assert.Len(t, d.undo.steps, 1)
+
+	_, err = ds.Stat(ctx, "volumes/test")
+	require.NoError(t, err)
+
+	require.NoError(t, d.undo.unwind())
+	_, err = ds.Stat(ctx, "volumes/test")
+	assert.IsType(t, object.DatastoreNoSuchFileError{}, err)
+
+	// an existing one may hold volumes, and is left alone
+	conf.VolumeLocations["default"].Path = "volumes/test"
+	require.NoError(t, d.createVolumeStores(conf))
+	d.undo.reset()
+
+	conf.VolumeLocations["default"].Path = "volumes/test"
+	require.NoError(t, d.createVolumeStores(conf))
+	assert.Empty(t, d.undo.steps)
+}
@@ -0,0 +1,208 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry reports the outcome of vic-machine operations to an endpoint chosen by the
+// operator, so that installs across a fleet can be followed in one place. Nothing is reported
+// unless an endpoint is given, and reports carry no names, addresses, paths or credentials.
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/version"
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+// Timeout bounds how long sending a report may hold up vic-machine
+const Timeout = 10 * time.Second
+
+// Environment describes the vSphere installation an operation ran against
+type Environment struct {
+	// Product is the vSphere product, such as VMware vCenter Server or VMware ESXi
+	Product string `json:"product"`
+	Version string `json:"version"`
+	Build   string `json:"build"`
+	// APIType is VirtualCenter or HostAgent
+	APIType string `json:"api_type"`
+}
+
+// Report is the outcome of a single vic-machine operation
+type Report struct {
+	Operation  string `json:"operation"`
+	VICVersion string `json:"vic_version"`
+	Success    bool   `json:"success"`
+	// Stage is where a failed operation stopped, empty on success. Error messages are not
+	// reported as they may name parts of the environment.
+	Stage    string  `json:"stage,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+
+	Environment *Environment `json:"environment,omitempty"`
+	// Features lists the optional features the operation was asked to configure
+	Features []string `json:"features,omitempty"`
+}
+
+// Reporter sends reports to the endpoint given by the operator
+type Reporter struct {
+	endpoint *url.URL
+	client   *http.Client
+
+	report Report
+	start  time.Time
+}
+
+// NewReporter returns a reporter for the operation that posts to the endpoint, or nil if the
+// endpoint is empty so that reporting stays disabled
+func NewReporter(endpoint, operation string) (*Reporter, error) {
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("telemetry endpoint %q must be an http or https URL", endpoint)
+	}
+
+	return &Reporter{
+		endpoint: u,
+		client:   &http.Client{},
+		report: Report{
+			Operation:  operation,
+			VICVersion: version.GetBuild().ShortVersion(),
+		},
+		start: time.Now(),
+	}, nil
+}
+
+// Stage records the stage the operation has reached, reported if it then fails
+func (r *Reporter) Stage(stage string) {
+	if r == nil {
+		return
+	}
+	r.report.Stage = stage
+}
+
+// Environment records the vSphere product the session is connected to
+func (r *Reporter) Environment(s *session.Session) {
+	if r == nil || s == nil || s.Client == nil {
+		return
+	}
+
+	about := s.ServiceContent.About
+	r.report.Environment = &Environment{
+		Product: about.Name,
+		Version: about.Version,
+		Build:   about.Build,
+		APIType: about.ApiType,
+	}
+}
+
+// Features records the optional features in the input
+func (r *Reporter) Features(input *data.Data) {
+	if r == nil {
+		return
+	}
+	r.report.Features = Features(input)
+}
+
+// Send reports the outcome of the operation. Failure to report is logged and never fails the
+// operation itself.
+func (r *Reporter) Send(err error) {
+	if r == nil {
+		return
+	}
+	defer trace.End(trace.Begin(r.endpoint.Host))
+
+	r.report.Success = err == nil
+	if r.report.Success {
+		r.report.Stage = ""
+	}
+	r.report.Duration = time.Since(r.start).Seconds()
+
+	body, merr := json.Marshal(r.report)
+	if merr != nil {
+		log.Debugf("Failed to encode telemetry report: %s", merr)
+		return
+	}
+
+	// the context of the operation may already be done, so the report has its own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	res, perr := ctxhttp.Post(ctx, r.client, r.endpoint.String(), "application/json", bytes.NewReader(body))
+	if perr != nil {
+		log.Warnf("Failed to send telemetry report to %s: %s", r.endpoint.Host, perr)
+		return
+	}
+	res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		log.Warnf("Telemetry endpoint %s rejected the report: %s", r.endpoint.Host, res.Status)
+		return
+	}
+	log.Debugf("Sent telemetry report to %s", r.endpoint.Host)
+}
+
+// Features returns the names of the optional features configured by the input, sorted
+func Features(input *data.Data) []string {
+	used := map[string]bool{
+		"bridge-network-dvs":    input.BridgeNetworkDVS != "",
+		"container-networks":    len(input.MappedNetworks) > 0,
+		"volume-stores":         len(input.VolumeLocations) > 0,
+		"volume-store-policies": len(input.VolumeStoragePolicies) > 0,
+		"insecure-registries":   len(input.InsecureRegistries) > 0,
+		"registry-ca":           len(input.RegistryCAs) > 0,
+		"registry-proxies":      len(input.RegistryProxies) > 0,
+		"proxies":               input.HTTPProxy != nil || input.HTTPSProxy != nil,
+		"prefetch-images":       len(input.PrefetchImages) > 0,
+		"ipam-webhook":          input.IPAMWebhook != nil,
+		"release-url":           input.ReleaseURL != nil && input.ReleaseURL.Host != "",
+		"admin-ldap":            input.AdminLDAPURL != nil && input.AdminLDAPURL.Host != "",
+		"tls-verify":            len(input.ClientCAs) > 0,
+		"static-ip":             !input.ClientNetwork.Empty() || !input.ExternalNetwork.Empty() || !input.ManagementNetwork.Empty(),
+		"appliance-host":        input.ApplianceHost != "",
+		"container-host-group":  input.ContainerHostGroup != "",
+		"anti-affinity":         input.ContainerAntiAffinity,
+		"container-crash-logs":  input.ContainerCrashLogs,
+		"container-log-driver":  input.ContainerLogConfig.Type != "",
+		"container-vm-profile":  input.ContainerVMProfile != "",
+		"serial-concentrator":   input.SerialConcentrator != "",
+		"vm-folder":             input.VMFolder != "",
+		"external-vms":          len(input.ExternalVMs) > 0,
+		"storage-policy":        input.StoragePolicy != "" || input.ImageStoragePolicy != "",
+		"disk-provisioning":     input.DiskProvisioning != "",
+		"protected":             input.Protected != nil && *input.Protected,
+		"use-rp":                input.UseRP,
+	}
+
+	var features []string
+	for name, ok := range used {
+		if ok {
+			features = append(features, name)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/install/data"
+)
+
+func TestNewReporterDisabled(t *testing.T) {
+	r, err := NewReporter("", "create")
+	require.NoError(t, err)
+	assert.Nil(t, r)
+
+	// a disabled reporter does nothing
+	r.Stage("validation")
+	r.Features(data.NewData())
+	r.Send(nil)
+
+	for _, endpoint := range []string{"collector.example.com", "ftp://collector.example.com", "https://"} {
+		_, err = NewReporter(endpoint, "create")
+		assert.Error(t, err, endpoint)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var reports []Report
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var report Report
+		assert.Equal(t, "POST", req.Method)
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&report))
+		reports = append(reports, report)
+	}))
+	defer ts.Close()
+
+	input := data.NewData()
+	input.DisplayName = "secret-vch"
+	input.ApplianceHost = "esx-17.example.com"
+	input.PrefetchImages = []string{"busybox"}
+
+	r, err := NewReporter(ts.URL, "create")
+	require.NoError(t, err)
+	r.Features(input)
+	r.Stage("validation")
+	r.Send(errors.New("failed to reach esx-17.example.com"))
+
+	r.Stage("create")
+	r.Send(nil)
+
+	require.Len(t, reports, 2)
+	assert.Equal(t, "create", reports[0].Operation)
+	assert.False(t, reports[0].Success)
+	assert.Equal(t, "validation", reports[0].Stage)
+	assert.Equal(t, []string{"appliance-host", "prefetch-images"}, reports[0].Features)
+
+	assert.True(t, reports[1].Success)
+	assert.Empty(t, reports[1].Stage)
+
+	// nothing identifying is reported
+	body, _ := json.Marshal(reports)
+	assert.NotContains(t, string(body), "secret-vch")
+	assert.NotContains(t, string(body), "esx-17")
+}
+
+func TestSendUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL
+	ts.Close()
+
+	r, err := NewReporter(url, "create")
+	require.NoError(t, err)
+
+	// failure to report is not an error of the operation
+	r.Send(nil)
+}
@@ -0,0 +1,226 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package test runs the VCH management operations of vic-machine end to end against the vSphere
+// simulator, so that they are covered without a vSphere environment. The simulator stands in for
+// the appliance guest, reporting the appliance components as started once it powers on.
+package test
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	"github.com/vmware/vic/pkg/vsphere/simulator"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// Inventory paths of the simulated ESX host
+const (
+	ComputeResourcePath = "/ha-datacenter/host/localhost.localdomain/Resources"
+	Datastore           = "LocalDS_0"
+	Network             = "VM Network"
+)
+
+// ClientIP is the address appliances report for their client network once powered on
+var ClientIP = net.ParseIP("10.10.10.10")
+
+// Harness is a simulated ESX host to run VCH operations against
+type Harness struct {
+	Model  *simulator.Model
+	Server *simulator.Server
+}
+
+// NewHarness starts the simulator with the ESX model, with the guest of appliance VMs simulated
+func NewHarness() (*Harness, error) {
+	model := simulator.ESX()
+	if err := model.Create(); err != nil {
+		model.Remove()
+		return nil, err
+	}
+
+	simulator.SetGuestHook(startAppliance)
+
+	server := model.Service.NewServer()
+	server.URL.User = url.UserPassword("user", "pass")
+	server.URL.Path = ""
+
+	return &Harness{
+		Model:  model,
+		Server: server,
+	}, nil
+}
+
+// Close stops the simulator and removes its datastores
+func (h *Harness) Close() {
+	simulator.SetGuestHook(nil)
+	h.Server.Close()
+	h.Model.Remove()
+}
+
+// Data returns the input of vic-machine create for a VCH with the name and a volume store
+func (h *Harness) Data(name string) *data.Data {
+	u := *h.Server.URL
+
+	input := data.NewData()
+	input.URL = &u
+	input.DisplayName = name
+	input.ComputeResourcePath = ComputeResourcePath
+	input.ImageDatastorePaths = []string{Datastore}
+	input.BridgeNetworkName = name
+	input.ManagementNetwork.Name = Network
+	input.ExternalNetwork.Name = Network
+	input.ClientNetwork.Name = Network
+	input.VolumeLocations = map[string]string{
+		"default": Datastore + "/volumes/" + name,
+	}
+	input.ScratchSize = "8GB"
+	input.NumCPUs = 1
+	input.MemoryMB = 1024
+	input.Force = true
+
+	return input
+}
+
+// validator connects to the simulator as vic-machine would for the input, skipping the checks of
+// host configuration the simulator does not model
+func (h *Harness) validator(ctx context.Context, input *data.Data) (*validate.Validator, error) {
+	v, err := validate.NewValidator(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	v.DisableFirewallCheck = true
+	v.DisableDRSCheck = true
+
+	return v, nil
+}
+
+// Create creates the VCH of the input as vic-machine create does, up to the check of its docker API,
+// and returns its configuration
+func (h *Harness) Create(ctx context.Context, input *data.Data) (*config.VirtualContainerHostConfigSpec, error) {
+	v, err := h.validator(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := v.Validate(ctx, input)
+	if err != nil {
+		v.ListIssues()
+		return nil, err
+	}
+
+	settings := v.AddDeprecatedFields(ctx, conf, input)
+	settings.ApplianceISO = "appliance.iso"
+	settings.BootstrapISO = "bootstrap.iso"
+
+	d := management.NewDispatcher(ctx, v.Session, conf, input.Force)
+	if err = d.CreateVCH(conf, settings); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// vch returns a dispatcher for the VCH with the ID, and its appliance and configuration
+func (h *Harness) vch(ctx context.Context, input *data.Data, id string) (*management.Dispatcher, *vm.VirtualMachine, *config.VirtualContainerHostConfigSpec, error) {
+	v, err := h.validator(ctx, input)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	d := management.NewDispatcher(ctx, v.Session, nil, input.Force)
+	vch, err := d.NewVCHFromID(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	conf, err := d.GetVCHConfig(vch)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return d, vch, conf, nil
+}
+
+// Inspect returns the inspection report of the VCH with the ID
+func (h *Harness) Inspect(ctx context.Context, input *data.Data, id string) (*management.Inspection, error) {
+	d, vch, conf, err := h.vch(ctx, input, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.InspectionReport(vch, conf)
+}
+
+// Delete deletes the VCH with the ID as vic-machine delete does, acting on its volume stores as
+// volumes says
+func (h *Harness) Delete(ctx context.Context, input *data.Data, id string, volumes management.VolumeAction) error {
+	d, _, conf, err := h.vch(ctx, input, id)
+	if err != nil {
+		return err
+	}
+
+	return d.DeleteVCH(conf, volumes)
+}
+
+// startAppliance simulates the guest of an appliance VM, reporting an address on the client network
+// and the launch of the appliance components as the appliance does once it is powered on. Other VMs
+// are left alone.
+func startAppliance(v *simulator.VirtualMachine) {
+	before := make(map[string]string)
+	for _, o := range v.Config.ExtraConfig {
+		opt := o.GetOptionValue()
+		if s, ok := opt.Value.(string); ok {
+			before[opt.Key] = s
+		}
+	}
+
+	conf := &config.VirtualContainerHostConfigSpec{}
+	extraconfig.Decode(extraconfig.MapSource(before), conf)
+
+	client, ok := conf.ExecutorConfig.Networks["client"]
+	if !ok {
+		return
+	}
+	client.Assigned = net.IPNet{IP: ClientIP, Mask: net.CIDRMask(24, 32)}
+
+	for _, name := range management.ApplianceComponents {
+		if session, ok := conf.ExecutorConfig.Sessions[name]; ok {
+			session.Started = "true"
+		}
+	}
+
+	after := make(map[string]string)
+	extraconfig.Encode(extraconfig.MapSink(after), conf)
+
+	// only the guestinfo the guest reports is updated, the rest of the configuration being that of
+	// vic-machine, some of it encrypted
+	var options []types.BaseOptionValue
+	for k, value := range after {
+		reported := strings.Contains(k, "networks|client.assigned") || strings.HasSuffix(k, ".started")
+		if reported && value != before[k] {
+			options = append(options, &types.OptionValue{Key: k, Value: value})
+		}
+	}
+
+	v.SetExtraConfig(options)
+}
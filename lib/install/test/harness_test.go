@@ -0,0 +1,66 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/install/management"
+	"github.com/vmware/vic/pkg/vsphere/simulator"
+)
+
+func TestCreateInspectDelete(t *testing.T) {
+	log.SetLevel(log.DebugLevel)
+
+	h, err := NewHarness()
+	require.NoError(t, err)
+	defer h.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	input := h.Data("vch1")
+	conf, err := h.Create(ctx, input)
+	require.NoError(t, err)
+
+	moref := new(types.ManagedObjectReference)
+	require.True(t, moref.FromString(conf.ID))
+	t.Logf("created %s", moref)
+
+	appliance, ok := simulator.Map.Get(*moref).(*simulator.VirtualMachine)
+	require.True(t, ok, "appliance %s not in inventory", moref)
+	assert.Equal(t, "vch1", appliance.Name)
+	assert.Equal(t, types.VirtualMachinePowerStatePoweredOn, appliance.Runtime.PowerState)
+
+	report, err := h.Inspect(ctx, input, moref.Value)
+	require.NoError(t, err)
+	assert.Equal(t, "vch1", report.Name)
+	assert.Equal(t, string(types.VirtualMachinePowerStatePoweredOn), report.PowerState)
+	for _, c := range report.Components {
+		assert.True(t, c.Started, "component %s not started", c.Name)
+	}
+
+	require.NoError(t, h.Delete(ctx, input, moref.Value, management.VolumesDelete))
+
+	assert.Nil(t, simulator.Map.Get(*moref), "appliance %s still in inventory", moref)
+}
@@ -26,10 +26,13 @@ import (
 
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/compute"
 )
 
 func (v *Validator) compute(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
@@ -74,6 +77,162 @@ func (v *Validator) compute(ctx context.Context, input *data.Data, conf *config.
 	// TODO: for RP creation assert whatever we decide about the pool - most likely that it's empty
 }
 
+// placementRules checks the DRS host groups the appliance and containerVMs are to be kept on, and
+// records the VM group and rule names the port layer uses for containerVMs
+func (v *Validator) placementRules(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
+	if input.ApplianceHostGroup == "" && input.ContainerHostGroup == "" && !input.ContainerAntiAffinity {
+		if input.HostGroupMandatory {
+			v.NoteIssue(errors.New("--host-group-mandatory requires --appliance-host-group or --container-host-group"))
+		}
+		return
+	}
+
+	if !v.sessionValid("DRS placement rule check SKIPPED") {
+		return
+	}
+
+	if !v.isVC || v.isStandaloneHost() {
+		v.NoteIssue(errors.New("DRS host groups and anti-affinity rules require a vCenter cluster"))
+		return
+	}
+
+	info, err := compute.ClusterConfig(ctx, v.Session.Cluster)
+	if err != nil {
+		v.NoteIssue(errors.Errorf("Failed to read DRS rules of cluster %q: %s", v.Session.Cluster.Name(), err))
+		return
+	}
+
+	if input.ApplianceHostGroup != "" {
+		v.NoteIssue(compute.HostGroupExists(info, input.ApplianceHostGroup))
+	}
+
+	if input.ContainerHostGroup != "" {
+		v.NoteIssue(compute.HostGroupExists(info, input.ContainerHostGroup))
+
+		conf.ContainerHostGroup = input.ContainerHostGroup
+		conf.ContainerVMGroup = compute.ContainerVMGroupName(input.DisplayName)
+		conf.ContainerHostGroupMandatory = input.HostGroupMandatory
+	}
+
+	if input.ContainerAntiAffinity {
+		conf.AntiAffinityRulePrefix = compute.AntiAffinityRulePrefix(input.DisplayName)
+	}
+}
+
+// applianceHost checks that the host the appliance is to be created on, if one is requested, belongs
+// to the compute resource, can run the appliance, and mounts its image stores. Its inventory path is recorded.
+func (v *Validator) applianceHost(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(input.ApplianceHost))
+
+	if input.ApplianceHost == "" {
+		return
+	}
+
+	if !v.sessionValid("Appliance host check SKIPPED") {
+		return
+	}
+
+	host, err := v.Session.Finder.HostSystem(ctx, input.ApplianceHost)
+	if err != nil {
+		v.NoteIssue(errors.Errorf("Unable to find appliance host %q: %s", input.ApplianceHost, err))
+		return
+	}
+
+	hosts, err := v.Session.Cluster.Hosts(ctx)
+	if err != nil {
+		v.NoteIssue(errors.Errorf("Failed to list hosts of compute resource %q: %s", v.Session.Cluster.Name(), err))
+		return
+	}
+
+	member := false
+	for _, h := range hosts {
+		if h.Reference() == host.Reference() {
+			member = true
+			break
+		}
+	}
+	if !member {
+		v.NoteIssue(errors.Errorf("Appliance host %q is not part of compute resource %q", input.ApplianceHost, v.Session.Cluster.Name()))
+		return
+	}
+
+	var mh mo.HostSystem
+	if err = host.Properties(ctx, host.Reference(), []string{"runtime", "datastore"}, &mh); err != nil {
+		v.NoteIssue(errors.Errorf("Failed to get the state of appliance host %q: %s", input.ApplianceHost, err))
+		return
+	}
+
+	usable := true
+	if mh.Runtime.ConnectionState != types.HostSystemConnectionStateConnected {
+		v.NoteIssue(errors.Errorf("Appliance host %q is not connected (%s)", input.ApplianceHost, mh.Runtime.ConnectionState))
+		usable = false
+	}
+	if mh.Runtime.InMaintenanceMode {
+		v.NoteIssue(errors.Errorf("Appliance host %q is in maintenance mode", input.ApplianceHost))
+		usable = false
+	}
+
+	mounted := make(map[types.ManagedObjectReference]bool)
+	for _, ds := range mh.Datastore {
+		mounted[ds] = true
+	}
+	for _, store := range conf.ImageStores {
+		ds, err := v.Session.Finder.Datastore(ctx, store.Host)
+		if err != nil {
+			// reported by the storage checks
+			continue
+		}
+		if !mounted[ds.Reference()] {
+			v.NoteIssue(errors.Errorf("Image store %q is not mounted on appliance host %q", store.Host, input.ApplianceHost))
+			usable = false
+		}
+	}
+
+	if usable {
+		v.ApplianceHostPath = host.InventoryPath
+	}
+}
+
+// externalVMs checks that the VMs to be listed as external containers exist and are not templates, and
+// records their references
+func (v *Validator) externalVMs(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(strings.Join(input.ExternalVMs, ",")))
+
+	if len(input.ExternalVMs) == 0 {
+		return
+	}
+
+	if !v.sessionValid("External VM check SKIPPED") {
+		return
+	}
+
+	seen := make(map[types.ManagedObjectReference]bool)
+	for _, path := range input.ExternalVMs {
+		vm, err := v.Session.Finder.VirtualMachine(ctx, path)
+		if err != nil {
+			v.NoteIssue(errors.Errorf("Unable to find external VM %q: %s", path, err))
+			continue
+		}
+
+		var mvm mo.VirtualMachine
+		if err = vm.Properties(ctx, vm.Reference(), []string{"config.template"}, &mvm); err != nil {
+			v.NoteIssue(errors.Errorf("Failed to get the configuration of external VM %q: %s", path, err))
+			continue
+		}
+		if mvm.Config != nil && mvm.Config.Template {
+			v.NoteIssue(errors.Errorf("External VM %q is a template", path))
+			continue
+		}
+
+		if !seen[vm.Reference()] {
+			seen[vm.Reference()] = true
+			conf.ExternalVMs = append(conf.ExternalVMs, vm.Reference())
+		}
+	}
+}
+
 func (v *Validator) ResourcePoolHelper(ctx context.Context, path string) (*object.ResourcePool, error) {
 	defer trace.End(trace.Begin(path))
 
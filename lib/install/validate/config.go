@@ -106,7 +106,12 @@ func (v *Validator) CheckFirewall(ctx context.Context) {
 			log.Infof("  %q", h)
 		}
 	}
-	if len(misconfiguredEnabled) > 0 {
+	if len(misconfiguredEnabled) > 0 && v.AllowFirewall {
+		log.Warn("Firewall rulesets permitting serial-over-LAN will be enabled on hosts:")
+		for _, h := range misconfiguredEnabled {
+			log.Warnf("  %q", h)
+		}
+	} else if len(misconfiguredEnabled) > 0 {
 		log.Error("Firewall configuration incorrect on hosts:")
 		for _, h := range misconfiguredEnabled {
 			log.Errorf("  %q", h)
@@ -115,7 +120,7 @@ func (v *Validator) CheckFirewall(ctx context.Context) {
 		// can proceed if there is at least one host properly configured. For now this prevents install.
 		err = fmt.Errorf("Firewall must permit %d/tcp outbound to use VIC", rule.Port)
 		log.Error(err)
-		v.NoteIssue(err)
+		v.noteIssue(IssueFirewall, err, fmt.Sprintf("Enable a firewall ruleset allowing outbound %d/tcp on the hosts, allow all outbound connections, or specify --firewall=allow", rule.Port))
 	}
 	if len(misconfiguredDisabled) > 0 {
 		log.Warning("Firewall configuration will be incorrect if firewall is reenabled on hosts:")
@@ -137,12 +142,12 @@ func (v *Validator) CheckLicense(ctx context.Context) {
 
 	if v.IsVC() {
 		if err = v.checkAssignedLicenses(ctx); err != nil {
-			v.NoteIssue(err)
+			v.noteIssue(IssueLicense, err, "Assign the hosts a license with the serialuri and dvs features, such as vSphere Enterprise Plus")
 			return
 		}
 	} else {
 		if err = v.checkLicense(ctx); err != nil {
-			v.NoteIssue(err)
+			v.noteIssue(IssueLicense, err, "Assign the host a license with the serialuri feature, such as vSphere Enterprise Plus")
 			return
 		}
 	}
@@ -299,7 +304,7 @@ func (v *Validator) CheckDrs(ctx context.Context) {
 	if !(*z.Enabled) {
 		log.Error("DRS check FAILED")
 		log.Errorf("  DRS must be enabled on cluster %q", v.Session.Pool.InventoryPath)
-		v.NoteIssue(errors.New("DRS must be enabled to use VIC"))
+		v.noteIssue(IssueDRS, errors.New("DRS must be enabled to use VIC"), "Enable DRS on the cluster, or target a standalone host")
 		return
 	}
 	log.Info("DRS check OK on:")
@@ -0,0 +1,144 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"net/url"
+
+	log "github.com/Sirupsen/logrus"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// ValidateReconfigure applies the settings supplied in input to conf, the configuration of an existing VCH,
+// validating each of them. Settings that are not supplied are left unchanged. Volume stores and container
+// networks are added or updated but never removed, so existing volumes and containers are not orphaned.
+func (v *Validator) ValidateReconfigure(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) (*config.VirtualContainerHostConfigSpec, error) {
+	defer trace.End(trace.Begin(conf.Name))
+	log.Infof("Validating supplied configuration changes")
+
+	if len(input.DNS) > 0 {
+		v.dnsServers(input, conf)
+	}
+
+	if len(input.VolumeLocations) > 0 {
+		v.volumeStores(ctx, input, conf)
+	}
+	v.volumeStoragePolicies(input, conf)
+
+	if len(input.MappedNetworks) > 0 {
+		v.containerNetworks(ctx, input, conf)
+		v.checkBridgeNotMapped(conf)
+	}
+
+	if input.InsecureRegistries != nil || len(input.RegistryCAs) > 0 {
+		// settings that are not supplied are kept
+		if input.InsecureRegistries == nil {
+			input.InsecureRegistries = conf.InsecureRegistries
+		}
+		if len(input.RegistryCAs) == 0 {
+			input.RegistryCAs = conf.RegistryCertificateAuthorities
+		}
+		v.registries(input, conf)
+	}
+
+	// the proxies of the given registries are replaced, the others are kept
+	for registry, proxy := range input.RegistryProxies {
+		if conf.RegistryProxies == nil {
+			conf.RegistryProxies = make(map[string]*url.URL)
+		}
+		conf.RegistryProxies[registry] = proxy
+	}
+
+	// the given maintenance jobs are enabled or disabled, the others are kept
+	for job, enabled := range input.MaintenanceJobs {
+		if conf.MaintenanceJobs == nil {
+			conf.MaintenanceJobs = make(map[string]bool)
+		}
+		conf.MaintenanceJobs[job] = enabled
+	}
+
+	if len(input.CertPEM) > 0 {
+		// keep the expiry settings of the existing certificate unless new ones were supplied
+		if input.CertExpiryThresholds == nil {
+			input.CertExpiryThresholds = conf.CertificateExpiryThresholds
+		}
+		if input.CertRenewalOverlap == 0 {
+			input.CertRenewalOverlap = conf.CertificateRenewalOverlap
+		}
+		input.CertAutoRenew = input.CertAutoRenew || conf.CertificateAutoRenew
+
+		v.certificate(ctx, input, conf)
+	}
+
+	if len(input.ClientCAs) > 0 {
+		if len(input.CertPEM) == 0 && !conf.HostCertificate.IsNil() {
+			// client verification against the existing host certificate
+			input.CertPEM = conf.HostCertificate.Cert
+		}
+		v.certificateAuthorities(ctx, input, conf)
+	}
+
+	if input.Protected != nil {
+		conf.Protected = *input.Protected
+	}
+
+	// an empty url disables the release check
+	if input.ReleaseURL != nil {
+		conf.ReleaseURL = *input.ReleaseURL
+	}
+
+	adminAuth(input, conf)
+
+	return conf, v.ListIssues()
+}
+
+// dnsServers sets the DNS servers used by the appliance on all but the bridge network
+func (v *Validator) dnsServers(input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
+	static := false
+	for name, endpoint := range conf.ExecutorConfig.Networks {
+		if name == conf.BridgeNetwork {
+			continue
+		}
+
+		endpoint.Network.Nameservers = input.DNS
+		static = static || endpoint.Static
+	}
+
+	if !static {
+		log.Warn("Specified DNS servers are ignored if static IP is not set on any networks. VCH will use DNS servers provided by DHCP.")
+	}
+}
+
+// checkBridgeNotMapped ensures the bridge network port group is not also used as a container network
+func (v *Validator) checkBridgeNotMapped(conf *config.VirtualContainerHostConfigSpec) {
+	bridge, ok := conf.ExecutorConfig.Networks[conf.BridgeNetwork]
+	if !ok {
+		return
+	}
+
+	for name, net := range conf.ContainerNetworks {
+		if name != conf.BridgeNetwork && net.ID == bridge.Network.ID {
+			v.NoteIssue(errors.Errorf("the bridge network must not be shared with another network role - also mapped as container network %q", name))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	vnetwork "github.com/vmware/vic/pkg/vsphere/network"
+)
+
+// defaultSwitchMTU is the MTU of a virtual switch that does not report one
+const defaultSwitchMTU = 1500
+
+// checkMTU notes an issue if mtu is larger than the MTU of the virtual switch backing the network,
+// as frames larger than the switch MTU are dropped
+func (v *Validator) checkMTU(ctx context.Context, network types.ManagedObjectReference, netName string, mtu int) {
+	defer trace.End(trace.Begin(netName))
+
+	if mtu == 0 || network.Value == "" {
+		return
+	}
+
+	if vnetwork.IsOpaque(network) {
+		log.Warnf("Unable to check the MTU of %q, as opaque networks are managed outside of vSphere", netName)
+		return
+	}
+
+	max, err := v.switchMTU(ctx, network)
+	if err != nil {
+		log.Warnf("Unable to check the MTU of the virtual switch backing %q: %s", netName, err)
+		return
+	}
+
+	if mtu > max {
+		v.NoteIssue(errors.Errorf("MTU %d for %q exceeds the MTU %d of the virtual switch backing it", mtu, netName, max))
+		return
+	}
+	log.Debugf("MTU %d for %q is within the virtual switch MTU %d", mtu, netName, max)
+}
+
+// switchMTU returns the MTU of the virtual switch backing the network. For a standard port group this
+// is the lowest MTU of the vSwitches backing it on the hosts attached to it.
+func (v *Validator) switchMTU(ctx context.Context, network types.ManagedObjectReference) (int, error) {
+	client := v.Session.Client.Client
+
+	if network.Type == "DistributedVirtualPortgroup" {
+		var dvpg mo.DistributedVirtualPortgroup
+		r := object.NewDistributedVirtualPortgroup(client, network)
+		if err := r.Properties(ctx, network, []string{"config.distributedVirtualSwitch"}, &dvpg); err != nil {
+			return 0, err
+		}
+		if dvpg.Config.DistributedVirtualSwitch == nil {
+			return 0, fmt.Errorf("no distributed switch found for %s", network.Value)
+		}
+
+		var dvs mo.DistributedVirtualSwitch
+		s := object.NewCommon(client, *dvpg.Config.DistributedVirtualSwitch)
+		if err := s.Properties(ctx, s.Reference(), []string{"config"}, &dvs); err != nil {
+			return 0, err
+		}
+		if c, ok := dvs.Config.(*types.VMwareDVSConfigInfo); ok && c.MaxMtu > 0 {
+			return int(c.MaxMtu), nil
+		}
+		return defaultSwitchMTU, nil
+	}
+
+	var n mo.Network
+	r := object.NewNetwork(client, network)
+	if err := r.Properties(ctx, network, []string{"name", "host"}, &n); err != nil {
+		return 0, err
+	}
+	if len(n.Host) == 0 {
+		return defaultSwitchMTU, nil
+	}
+
+	var hosts []mo.HostSystem
+	if err := property.DefaultCollector(client).Retrieve(ctx, n.Host, []string{"config.network"}, &hosts); err != nil {
+		return 0, err
+	}
+
+	min := 0
+	for _, h := range hosts {
+		if h.Config == nil || h.Config.Network == nil {
+			continue
+		}
+		if mtu := portGroupMTU(h.Config.Network, n.Name); mtu > 0 && (min == 0 || mtu < min) {
+			min = mtu
+		}
+	}
+	if min == 0 {
+		return defaultSwitchMTU, nil
+	}
+	return min, nil
+}
+
+// portGroupMTU returns the MTU of the vSwitch backing the named port group in the host network
+// configuration, or zero if the port group is not found
+func portGroupMTU(info *types.HostNetworkInfo, name string) int {
+	for _, pg := range info.Portgroup {
+		if pg.Spec.Name != name {
+			continue
+		}
+
+		for _, vs := range info.Vswitch {
+			if vs.Key != pg.Vswitch {
+				continue
+			}
+			if vs.Mtu == 0 {
+				return defaultSwitchMTU
+			}
+			return int(vs.Mtu)
+		}
+	}
+	return 0
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/ip"
 	"github.com/vmware/vic/pkg/trace"
+	vnetwork "github.com/vmware/vic/pkg/vsphere/network"
 )
 
 func (v *Validator) getEndpoint(ctx context.Context, conf *config.VirtualContainerHostConfigSpec, network data.NetworkConfig, epName, contNetName string, def bool, ns []net.IP) (*executor.NetworkEndpoint, error) {
@@ -44,12 +45,21 @@ func (v *Validator) getEndpoint(ctx context.Context, conf *config.VirtualContain
 		log.Debugf("Setting static IP for %q on port group %q", contNetName, network.Name)
 		gw = network.Gateway
 		staticIP = &network.IP
+
+		// per-network DNS servers take precedence over those for all networks
+		if len(network.Nameservers) > 0 {
+			ns = network.Nameservers
+		}
 	}
 
-	moid, err := v.networkHelper(ctx, network.Name)
+	n, err := v.getNetwork(ctx, network.Name)
 	if err != nil {
 		return nil, err
 	}
+	moref := n.Reference()
+	moid := moref.String()
+
+	v.checkMTU(ctx, moref, network.Name, network.MTU)
 
 	e := &executor.NetworkEndpoint{
 		Common: executor.Common{
@@ -63,8 +73,11 @@ func (v *Validator) getEndpoint(ctx context.Context, conf *config.VirtualContain
 			Default:     def,
 			Gateway:     gw,
 			Nameservers: ns,
+			MTU:         network.MTU,
 		},
-		IP: staticIP,
+		IP:           staticIP,
+		MAC:          network.MAC,
+		DHCPClientID: network.DHCPClientID,
 	}
 	if staticIP != nil {
 		e.Static = true
@@ -240,7 +253,13 @@ func (v *Validator) network(ctx context.Context, input *data.Data, conf *config.
 	}
 
 	checkBridgeVDS := true
-	if err != nil {
+	if input.BridgeNetworkDVS != "" {
+		// the port group is created on the switch during create
+		v.bridgeNetworkDVS(ctx, input, conf, err)
+		bridgeID = ""
+		netMoid = input.BridgeNetworkName
+		checkBridgeVDS = false
+	} else if err != nil {
 		if _, ok := err.(*find.NotFoundError); !ok || v.IsVC() {
 			v.NoteIssue(fmt.Errorf("An existing distributed port group must be specified for bridge network on vCenter: %s", err))
 			v.suggestNetwork("--bridge-network", false)
@@ -266,23 +285,89 @@ func (v *Validator) network(ctx context.Context, input *data.Data, conf *config.
 				ID:   netMoid,
 			},
 			Type: "bridge",
+			MTU:  input.BridgeNetworkMTU,
 		},
 	}
+	if bridgeID != "" {
+		v.checkMTU(ctx, endpointMoref, input.BridgeNetworkName, input.BridgeNetworkMTU)
+	}
 	// we need to have the bridge network identified as an available container network
 	conf.AddContainerNetwork(&bridgeNet.Network)
 	// we also need to have the appliance attached to the bridge network to allow
 	// port forwarding
 	conf.AddNetwork(bridgeNet)
 	conf.BridgeIPRange = input.BridgeIPRange
+	if input.IPAMWebhook != nil {
+		conf.IPAMWebhook = *input.IPAMWebhook
+	}
 
 	err = v.checkVDSMembership(ctx, endpointMoref, input.BridgeNetworkName)
 	if err != nil && checkBridgeVDS {
 		v.NoteIssue(fmt.Errorf("Unable to check hosts in vDS for %q: %s", input.BridgeNetworkName, err))
 	}
 
+	v.containerNetworks(ctx, input, conf)
+	v.nicNumbers(conf)
+}
+
+// checkContainerNetworkIPAM checks the IPAM settings of a container network are consistent with its gateway and IP ranges
+func checkContainerNetworkIPAM(name, ipam string, gw net.IPNet, pools []ip.Range, dhcpServers, reserved []net.IP) error {
+	static := ipam == executor.IPAMStatic || (ipam == "" && !ip.IsUnspecifiedSubnet(&gw))
+
+	switch ipam {
+	case executor.IPAMStatic:
+		if ip.IsUnspecifiedSubnet(&gw) {
+			return fmt.Errorf("Static IPAM specified without gateway for container network %q", name)
+		}
+	case executor.IPAMDHCP:
+		if len(pools) > 0 {
+			return fmt.Errorf("IP range specified for DHCP container network %q", name)
+		}
+	case executor.IPAMDHCPRelay:
+		if ip.IsUnspecifiedSubnet(&gw) {
+			return fmt.Errorf("DHCP relay specified without gateway for container network %q", name)
+		}
+		if len(pools) > 0 {
+			return fmt.Errorf("IP range specified for DHCP relay container network %q", name)
+		}
+		if len(dhcpServers) == 0 {
+			return fmt.Errorf("DHCP relay specified without DHCP server for container network %q", name)
+		}
+	}
+
+	if len(dhcpServers) > 0 && ipam != executor.IPAMDHCPRelay {
+		return fmt.Errorf("DHCP server specified for container network %q, which does not use DHCP relay", name)
+	}
+
+	for _, r := range reserved {
+		if !static {
+			return fmt.Errorf("Reserved IP %s specified for container network %q, which does not use static IPAM", r, name)
+		}
+
+		if !gw.Contains(r) || r.Equal(gw.IP) {
+			return fmt.Errorf("Reserved IP %s is not an available address in subnet %q", r, gw)
+		}
+
+		inPool := len(pools) == 0
+		for i := range pools {
+			inPool = inPool || pools[i].Overlaps(ip.Range{FirstIP: r, LastIP: r})
+		}
+		if !inPool {
+			return fmt.Errorf("Reserved IP %s is not in the IP ranges of container network %q", r, name)
+		}
+	}
+
+	return nil
+}
+
+// containerNetworks validates the mapped networks (from --container-network) and adds them to conf
+func (v *Validator) containerNetworks(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
 	// add mapped networks (from --container-network)
 	//   these should be a distributed port groups in vCenter
 	suggestedMapped := false // only suggest mapped nets once
+	var err error
 	for name, net := range input.MappedNetworks {
 		checkMappedVDS := true
 		// "bridge" is reserved
@@ -325,6 +410,10 @@ func (v *Validator) network(ctx context.Context, input *data.Data, conf *config.
 			}
 		}
 
+		if err == nil {
+			err = checkContainerNetworkIPAM(name, input.MappedNetworksIPAM[name], gw, pools, input.MappedNetworksDHCP[name], input.MappedNetworksReserved[name])
+		}
+
 		if err != nil {
 			v.NoteIssue(err)
 			continue
@@ -344,10 +433,20 @@ func (v *Validator) network(ctx context.Context, input *data.Data, conf *config.
 				Name: name,
 				ID:   moref.String(),
 			},
-			Type:        "external",
-			Gateway:     gw,
-			Nameservers: dns,
-			Pools:       pools,
+			Type:          "external",
+			Gateway:       gw,
+			Nameservers:   dns,
+			SearchDomains: input.MappedNetworksSearch[name],
+			Pools:         pools,
+			MTU:           input.MappedNetworksMTU[name],
+			VLAN:          input.MappedNetworksVLAN[name],
+			IPAM:          input.MappedNetworksIPAM[name],
+			DHCPServers:   input.MappedNetworksDHCP[name],
+			Reserved:      input.MappedNetworksReserved[name],
+			TrustLevel:    input.MappedNetworksFirewall[name],
+		}
+		if checkMappedVDS {
+			v.checkMTU(ctx, moref, net, mappedNet.MTU)
 		}
 		if input.BridgeNetworkName == net {
 			v.NoteIssue(errors.Errorf("the bridge network must not be shared with another network role - %q also mapped as container network %q", input.BridgeNetworkName, name))
@@ -360,7 +459,6 @@ func (v *Validator) network(ctx context.Context, input *data.Data, conf *config.
 
 		conf.AddContainerNetwork(mappedNet)
 	}
-	v.nicNumbers(conf)
 }
 
 // nicNumbers will check vch appliance nic numbers. currently we don't support more than three nics for issue #1674.
@@ -398,19 +496,7 @@ func (v *Validator) getNetwork(ctx context.Context, name string) (object.Network
 		// TODO: error about required disabmiguation and list entries in nets
 		return nil, errors.New("ambiguous network " + name)
 	}
-	return nets[0], nil
-}
-
-// networkHelper gets a moid based on the network name
-func (v *Validator) networkHelper(ctx context.Context, name string) (string, error) {
-	defer trace.End(trace.Begin(name))
-
-	net, err := v.getNetwork(ctx, name)
-	if err != nil {
-		return "", err
-	}
-	moref := net.Reference()
-	return moref.String(), nil
+	return vnetwork.Reference(nets[0]), nil
 }
 
 func (v *Validator) dpgMorefHelper(ctx context.Context, ref string) (string, error) {
@@ -429,12 +515,12 @@ func (v *Validator) dpgMorefHelper(ctx context.Context, ref string) (string, err
 		return "", errors.New("unable to locate network from moref: " + ref)
 	}
 
-	// ensure that the type of the network is a Distributed Port Group if the target is a vCenter
-	// if it's not then any network suffices
+	// ensure that the type of the network is a Distributed Port Group or an opaque network, such as
+	// an NSX logical switch, if the target is a vCenter. If it's not then any network suffices
 	if v.IsVC() {
 		_, dpg := net.(*object.DistributedVirtualPortgroup)
-		if !dpg {
-			return "", fmt.Errorf("%q is not a Distributed Port Group", ref)
+		if !dpg && !vnetwork.IsOpaque(net.Reference()) {
+			return "", fmt.Errorf("%q is not a Distributed Port Group or opaque network", ref)
 		}
 	}
 
@@ -449,12 +535,12 @@ func (v *Validator) dpgHelper(ctx context.Context, path string) (types.ManagedOb
 		return types.ManagedObjectReference{}, err
 	}
 
-	// ensure that the type of the network is a Distributed Port Group if the target is a vCenter
-	// if it's not then any network suffices
+	// ensure that the type of the network is a Distributed Port Group or an opaque network, such as
+	// an NSX logical switch, if the target is a vCenter. If it's not then any network suffices
 	if v.IsVC() {
 		_, dpg := net.(*object.DistributedVirtualPortgroup)
-		if !dpg {
-			return types.ManagedObjectReference{}, fmt.Errorf("%q is not a Distributed Port Group", path)
+		if !dpg && !vnetwork.IsOpaque(net.Reference()) {
+			return types.ManagedObjectReference{}, fmt.Errorf("%q is not a Distributed Port Group or opaque network", path)
 		}
 	}
 
@@ -484,6 +570,12 @@ func (v *Validator) checkVDSMembership(ctx context.Context, network types.Manage
 		return nil
 	}
 
+	// hosts are attached to opaque networks by their external manager
+	if vnetwork.IsOpaque(network) {
+		log.Debugf("Not checking host membership of opaque network %q", netName)
+		return nil
+	}
+
 	if v.Session.Cluster == nil {
 		return errors.New("Invalid cluster. Check --compute-resource")
 	}
@@ -519,6 +611,79 @@ func (v *Validator) checkVDSMembership(ctx context.Context, network types.Manage
 	return nil
 }
 
+// bridgeNetworkDVS checks the distributed switch that the bridge port group is to be created on.
+// lookupErr is the result of looking up the bridge network, which must not exist yet.
+func (v *Validator) bridgeNetworkDVS(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec, lookupErr error) {
+	defer trace.End(trace.Begin(input.BridgeNetworkDVS))
+
+	if !v.IsVC() {
+		v.NoteIssue(errors.New("--bridge-network-dvs is only supported on vCenter, the bridge network is created on ESX without it"))
+		return
+	}
+
+	if lookupErr == nil {
+		v.NoteIssue(fmt.Errorf("Bridge network %q already exists: specify it without --bridge-network-dvs to use it", input.BridgeNetworkName))
+		return
+	}
+	if _, ok := lookupErr.(*find.NotFoundError); !ok {
+		v.NoteIssue(fmt.Errorf("Unable to check for existing bridge network %q: %s", input.BridgeNetworkName, lookupErr))
+		return
+	}
+
+	net, err := v.getNetwork(ctx, input.BridgeNetworkDVS)
+	if err != nil {
+		v.NoteIssue(fmt.Errorf("Unable to find distributed switch %q: %s", input.BridgeNetworkDVS, err))
+		return
+	}
+	dvs, ok := net.(*object.DistributedVirtualSwitch)
+	if !ok {
+		v.NoteIssue(fmt.Errorf("%q is not a distributed switch", input.BridgeNetworkDVS))
+		return
+	}
+
+	if err = v.checkDVSMembership(ctx, dvs, input.BridgeNetworkDVS); err != nil {
+		v.NoteIssue(fmt.Errorf("Unable to check hosts in vDS %q: %s", input.BridgeNetworkDVS, err))
+		return
+	}
+
+	conf.BridgeNetworkDVS = dvs.Reference().String()
+	conf.BridgeNetworkVLAN = input.BridgeNetworkVLAN
+	conf.BridgeNetworkPortBinding = input.BridgeNetworkPortBinding
+}
+
+// checkDVSMembership checks that all hosts in the cluster are members of the distributed switch
+func (v *Validator) checkDVSMembership(ctx context.Context, dvs *object.DistributedVirtualSwitch, name string) error {
+	defer trace.End(trace.Begin(name))
+
+	if v.Session.Cluster == nil {
+		return errors.New("Invalid cluster. Check --compute-resource")
+	}
+
+	clusterHosts, err := v.Session.Cluster.Hosts(ctx)
+	if err != nil {
+		return err
+	}
+
+	var mdvs mo.DistributedVirtualSwitch
+	if err = dvs.Properties(ctx, dvs.Reference(), []string{"summary.hostMember"}, &mdvs); err != nil {
+		return err
+	}
+
+	var nonMembers []string
+	for _, h := range clusterHosts {
+		if !v.inDVP(h.Reference(), mdvs.Summary.HostMember) {
+			nonMembers = append(nonMembers, h.InventoryPath)
+		}
+	}
+
+	if len(nonMembers) > 0 {
+		v.NoteIssue(fmt.Errorf("All cluster hosts must be in the vDS. %q is missing hosts: %s", name, nonMembers))
+	} else {
+		log.Infof("vDS configuration OK on %q", name)
+	}
+	return nil
+}
+
 // suggestNetwork suggests all networks
 // incStdNets includes standard Networks in addition to DPGs
 func (v *Validator) suggestNetwork(flag string, incStdNets bool) {
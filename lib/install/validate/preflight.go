@@ -0,0 +1,346 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-units"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/compute"
+)
+
+// IssueCode identifies the pre-flight check an issue was found by, so that installers can act on it
+type IssueCode string
+
+// Pre-flight issue codes
+const (
+	IssueFirewall            IssueCode = "firewall"
+	IssueLicense             IssueCode = "license"
+	IssueDRS                 IssueCode = "drs"
+	IssueDatastoreSpace      IssueCode = "datastore-space"
+	IssuePermissions         IssueCode = "permissions"
+	IssueNetworkReachability IssueCode = "network-reachability"
+	IssueHA                  IssueCode = "ha"
+)
+
+// minImageStoreFreeSpace is the free space needed on an image store for the appliance and bootstrap
+// ISOs, the appliance logs and a handful of images
+const minImageStoreFreeSpace = 2 * units.GiB
+
+// Privileges the user needs on the resources the VCH uses
+var (
+	computePrivileges = []string{
+		"Resource.AssignVMToPool",
+		"Resource.CreatePool",
+		"VirtualMachine.Config.AddNewDisk",
+		"VirtualMachine.Interact.PowerOn",
+		"VirtualMachine.Inventory.Create",
+	}
+	vappPrivileges = []string{
+		"VApp.Create",
+	}
+	datastorePrivileges = []string{
+		"Datastore.AllocateSpace",
+		"Datastore.Browse",
+		"Datastore.FileManagement",
+	}
+	networkPrivileges = []string{
+		"Network.Assign",
+	}
+)
+
+// adminRoleID is the system role holding all privileges
+const adminRoleID = -1
+
+// Issue is a problem with the target environment found by a pre-flight check, with a hint on how to
+// resolve it
+type Issue struct {
+	Code        IssueCode
+	Err         error
+	Remediation string
+}
+
+func (i *Issue) Error() string {
+	return i.Err.Error()
+}
+
+// ValidationError is returned when validation finds issues. Issues found by pre-flight checks are of
+// type *Issue.
+type ValidationError struct {
+	Issues []error
+}
+
+func (e *ValidationError) Error() string {
+	return "validation of configuration failed"
+}
+
+// noteIssue notes err as an issue found by the check with code
+func (v *Validator) noteIssue(code IssueCode, err error, remediation string) {
+	if err != nil {
+		v.NoteIssue(&Issue{Code: code, Err: err, Remediation: remediation})
+	}
+}
+
+// preflight checks that the target environment can host the VCH, so that problems are reported
+// before any resources are created rather than part way through the install. It runs after the
+// compute, storage and network configuration is validated, as it checks the resources chosen.
+func (v *Validator) preflight(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
+	v.CheckFirewall(ctx)
+	v.CheckLicense(ctx)
+	v.CheckDrs(ctx)
+
+	// the checks above already report an incomplete session
+	if len(v.checkSessionSet()) > 0 {
+		return
+	}
+
+	v.checkDatastoreSpace(ctx, conf)
+	v.checkPermissions(ctx, input, conf)
+	v.checkNetworkReachability(ctx, conf)
+	v.checkHA(ctx, input)
+}
+
+// checkDatastoreSpace checks the image stores have room for the appliance
+func (v *Validator) checkDatastoreSpace(ctx context.Context, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
+	for _, u := range conf.ImageStores {
+		ds, err := v.Session.Finder.Datastore(ctx, u.Host)
+		if err != nil {
+			// reported by storage validation
+			continue
+		}
+
+		var mds mo.Datastore
+		if err = ds.Properties(ctx, ds.Reference(), []string{"summary"}, &mds); err != nil {
+			log.Warnf("Unable to check free space on datastore %q: %s", u.Host, err)
+			continue
+		}
+
+		free := mds.Summary.FreeSpace
+		if free < minImageStoreFreeSpace {
+			v.noteIssue(IssueDatastoreSpace,
+				errors.Errorf("Datastore %q has %s free, at least %s is needed for the VCH", u.Host, units.BytesSize(float64(free)), units.BytesSize(minImageStoreFreeSpace)),
+				"Free space on the datastore, or choose another with --image-store")
+			continue
+		}
+		log.Infof("Datastore space check OK on %q: %s free", u.Host, units.BytesSize(float64(free)))
+	}
+}
+
+// checkPermissions checks the user holds the privileges needed to create the VCH on the resource pool,
+// image stores and networks it uses
+func (v *Validator) checkPermissions(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
+	client := v.Session.Client.Client
+
+	roles, err := object.NewAuthorizationManager(client).RoleList(ctx)
+	if err != nil {
+		log.Warnf("Permissions check SKIPPED - unable to list roles: %s", err)
+		return
+	}
+
+	type entity struct {
+		ref        types.ManagedObjectReference
+		name       string
+		privileges []string
+	}
+
+	pool := computePrivileges
+	if v.IsVC() && !input.UseRP {
+		pool = append(append([]string{}, computePrivileges...), vappPrivileges...)
+	}
+	entities := []entity{{v.Session.Pool.Reference(), v.Session.Pool.InventoryPath, pool}}
+
+	for _, u := range conf.ImageStores {
+		if ds, err := v.Session.Finder.Datastore(ctx, u.Host); err == nil {
+			entities = append(entities, entity{ds.Reference(), u.Host, datastorePrivileges})
+		}
+	}
+
+	seen := make(map[types.ManagedObjectReference]bool)
+	for name, ne := range conf.ExecutorConfig.Networks {
+		var ref types.ManagedObjectReference
+		// the bridge network on ESX has no reference until it is created
+		if !ref.FromString(ne.Network.ID) || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		entities = append(entities, entity{ref, fmt.Sprintf("%s network", name), networkPrivileges})
+	}
+
+	var failed bool
+	for _, e := range entities {
+		var me mo.ManagedEntity
+		if err := object.NewCommon(client, e.ref).Properties(ctx, e.ref, []string{"effectiveRole"}, &me); err != nil {
+			log.Warnf("Unable to check permissions on %q: %s", e.name, err)
+			continue
+		}
+
+		missing := missingPrivileges(roles, me.EffectiveRole, e.privileges)
+		if len(missing) == 0 {
+			continue
+		}
+
+		failed = true
+		v.noteIssue(IssuePermissions,
+			errors.Errorf("Missing privileges on %q: %s", e.name, strings.Join(missing, ", ")),
+			"Assign a role holding these privileges to the user on the resource, or use an administrator account")
+	}
+
+	if !failed {
+		log.Info("Permissions check OK")
+	}
+}
+
+// missingPrivileges returns the privileges in required not held through any of the roles in effective
+func missingPrivileges(roles object.AuthorizationRoleList, effective []int32, required []string) []string {
+	held := make(map[string]bool)
+	for _, id := range effective {
+		if id == adminRoleID {
+			return nil
+		}
+
+		if role := roles.ById(id); role != nil {
+			for _, p := range role.Privilege {
+				held[p] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, p := range required {
+		if !held[p] {
+			missing = append(missing, p)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// checkNetworkReachability checks the networks of the appliance are attached to the hosts that can
+// access its datastores, as the appliance cannot run on a host without them
+func (v *Validator) checkNetworkReachability(ctx context.Context, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
+	hosts, err := v.Session.Datastore.AttachedClusterHosts(ctx, v.Session.Cluster)
+	if err != nil {
+		// reported by the compatibility checks
+		return
+	}
+
+	seen := make(map[types.ManagedObjectReference]bool)
+	for name, ne := range conf.ExecutorConfig.Networks {
+		var ref types.ManagedObjectReference
+		// the bridge network on ESX has no reference until it is created
+		if !ref.FromString(ne.Network.ID) || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		var n mo.Network
+		if err := property.DefaultCollector(v.Session.Client.Client).RetrieveOne(ctx, ref, []string{"name", "host"}, &n); err != nil {
+			log.Warnf("Unable to check the hosts attached to the %s network: %s", name, err)
+			continue
+		}
+
+		if len(n.Host) == 0 {
+			log.Debugf("No hosts reported for the %s network %q, skipping reachability check", name, n.Name)
+			continue
+		}
+
+		missing := hostsWithout(hosts, n.Host)
+		switch {
+		case len(missing) == 0:
+			log.Infof("Network reachability check OK for %s network %q", name, n.Name)
+		case len(missing) == len(hosts):
+			v.noteIssue(IssueNetworkReachability,
+				errors.Errorf("None of the hosts that can access the VCH datastores are attached to the %s network %q", name, n.Name),
+				"Add the port group to the hosts of the compute resource, or choose another network")
+		default:
+			log.Warnf("The %s network %q is not attached to all hosts that can access the VCH datastores, the VCH cannot run on:", name, n.Name)
+			for _, h := range missing {
+				log.Warnf("  %q", h)
+			}
+		}
+	}
+}
+
+// checkHA checks vSphere HA is enabled on the cluster, so that the appliance is restarted after a
+// host failure. It is an issue only if HA settings are given for the appliance.
+func (v *Validator) checkHA(ctx context.Context, input *data.Data) {
+	defer trace.End(trace.Begin(""))
+
+	if !v.isVC || v.isStandaloneHost() {
+		if input.ApplianceHA.IsSet() {
+			v.noteIssue(IssueHA, errors.New("HA settings for the appliance require a vCenter cluster"),
+				"Target a cluster with vSphere HA enabled, or remove the appliance HA options")
+		}
+		return
+	}
+
+	info, err := compute.ClusterConfig(ctx, v.Session.Cluster)
+	if err != nil {
+		log.Warnf("Unable to check vSphere HA configuration: %s", err)
+		return
+	}
+
+	if !compute.HAEnabled(info) {
+		if input.ApplianceHA.IsSet() {
+			v.noteIssue(IssueHA, errors.Errorf("vSphere HA is not enabled on cluster %q", v.Session.Cluster.Name()),
+				"Enable vSphere HA on the cluster, or remove the appliance HA options")
+			return
+		}
+		log.Warnf("vSphere HA is not enabled on cluster %q, the VCH is not restarted after a host failure", v.Session.Cluster.Name())
+		return
+	}
+
+	if m := input.ApplianceHA.Monitoring; m != "" && m != "disabled" && !compute.VMMonitoringEnabled(info) {
+		log.Warnf("VM monitoring is disabled on cluster %q, appliance monitoring takes effect once it is enabled", v.Session.Cluster.Name())
+	}
+	log.Infof("vSphere HA check OK on cluster %q", v.Session.Cluster.Name())
+}
+
+// hostsWithout returns the inventory paths of the hosts not in attached
+func hostsWithout(hosts []*object.HostSystem, attached []types.ManagedObjectReference) []string {
+	refs := make(map[types.ManagedObjectReference]bool)
+	for _, ref := range attached {
+		refs[ref] = true
+	}
+
+	var missing []string
+	for _, h := range hosts {
+		if !refs[h.Reference()] {
+			missing = append(missing, h.InventoryPath)
+		}
+	}
+	return missing
+}
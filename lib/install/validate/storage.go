@@ -20,50 +20,194 @@ import (
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/reference"
 	"golang.org/x/net/context"
 
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/install/data"
 	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/nfs"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/datastore"
+	"github.com/vmware/vic/pkg/vsphere/disk"
 )
 
 func (v *Validator) storage(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
 	defer trace.End(trace.Begin(""))
 
-	// Image Store
-	imageDSpath, ds, err := v.DatastoreHelper(ctx, input.ImageDatastorePath, "", "--image-store")
+	// Image Stores - with none specified the default datastore is used
+	paths := input.ImageDatastorePaths
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+
+	// the datastores the base image and volume disks are created on
+	var datastores []*object.Datastore
+
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		imageDSpath, ds, err := v.DatastoreHelper(ctx, p, "", "--image-store")
+
+		if imageDSpath == nil {
+			v.NoteIssue(err)
+			continue
+		}
+
+		// provide a default path if only a DS name is provided
+		if imageDSpath.Path == "" {
+			imageDSpath.Path = input.DisplayName
+		}
+
+		// each datastore can only hold one image store for the VCH
+		if seen[imageDSpath.Host] {
+			v.NoteIssue(errors.Errorf("Datastore %q is specified by more than one --image-store", imageDSpath.Host))
+			continue
+		}
+		seen[imageDSpath.Host] = true
 
-	if imageDSpath == nil {
 		v.NoteIssue(err)
-		return
+		if ds != nil {
+			// the first image store is used until the appliance is placed
+			if len(conf.ImageStores) == 0 {
+				v.SetDatastore(ds, imageDSpath)
+			}
+			conf.AddImageStore(imageDSpath)
+			datastores = append(datastores, ds)
+		}
 	}
 
-	// provide a default path if only a DS name is provided
-	if imageDSpath.Path == "" {
-		imageDSpath.Path = input.DisplayName
+	if input.ImageStorePlacement != "" {
+		v.NoteIssue(datastore.ValidPlacementPolicy(input.ImageStorePlacement))
 	}
+	conf.ImageStorePlacement = input.ImageStorePlacement
+	conf.ImageStoragePolicy = input.ImageStoragePolicy
 
-	v.NoteIssue(err)
-	if ds != nil {
-		v.SetDatastore(ds, imageDSpath)
-		conf.AddImageStore(imageDSpath)
+	for _, image := range input.PrefetchImages {
+		if _, err := reference.ParseNamed(image); err != nil {
+			v.NoteIssue(errors.Errorf("Invalid image %q for --prefetch-image: %s", image, err))
+		}
 	}
+	conf.PrefetchImages = input.PrefetchImages
+	conf.PrefetchInterval = input.PrefetchInterval
+
+	datastores = append(datastores, v.volumeStores(ctx, input, conf)...)
+	v.diskProvisioning(ctx, input, conf, datastores)
+	v.volumeStoragePolicies(input, conf)
+	conf.StoragePolicy = input.StoragePolicy
+}
+
+// volumeStores validates the volume store locations and adds them to conf, returning their datastores
+func (v *Validator) volumeStores(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) []*object.Datastore {
+	defer trace.End(trace.Begin(""))
 
 	if conf.VolumeLocations == nil {
 		conf.VolumeLocations = make(map[string]*url.URL)
 	}
 
-	// TODO: add volume locations
+	var datastores []*object.Datastore
 	for label, volDSpath := range input.VolumeLocations {
-		dsURL, _, err := v.DatastoreHelper(ctx, volDSpath, label, "--volume-store")
+		// NFS exports are mounted by the appliance and containerVMs, so there is no datastore to check
+		if strings.HasPrefix(volDSpath, nfs.Scheme+"://") {
+			u, err := url.Parse(volDSpath)
+			if err == nil {
+				err = nfs.Validate(u)
+			}
+			if err != nil {
+				v.NoteIssue(errors.Errorf("Volume store %q has an invalid NFS export %q: %s", label, volDSpath, err))
+				continue
+			}
+			conf.VolumeLocations[label] = u
+			continue
+		}
+
+		dsURL, ds, err := v.DatastoreHelper(ctx, volDSpath, label, "--volume-store")
 		v.NoteIssue(err)
 		if dsURL != nil {
 			conf.VolumeLocations[label] = dsURL
 		}
+		if ds != nil {
+			datastores = append(datastores, ds)
+		}
+	}
+	return datastores
+}
+
+// volumeStoragePolicies checks that the volume stores given storage policies exist and adds the policies
+// to conf. The policies themselves are checked by vSphere when the first volume is created with them.
+func (v *Validator) volumeStoragePolicies(input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
+	if len(input.VolumeStoragePolicies) == 0 {
+		return
+	}
+
+	if conf.VolumeStoragePolicies == nil {
+		conf.VolumeStoragePolicies = make(map[string]string)
+	}
+
+	for label, policy := range input.VolumeStoragePolicies {
+		u, ok := conf.VolumeLocations[label]
+		if !ok {
+			v.NoteIssue(errors.Errorf("Volume store %q given a storage policy by --volume-store-policy does not exist", label))
+			continue
+		}
+		if nfs.IsExport(u) {
+			v.NoteIssue(errors.Errorf("Volume store %q is an NFS export and cannot be given a storage policy", label))
+			continue
+		}
+		conf.VolumeStoragePolicies[label] = policy
+	}
+}
+
+// diskProvisioning validates the provisioning of the base image and volume disks against the
+// datastores they are created on, and adds it to conf
+func (v *Validator) diskProvisioning(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec, datastores []*object.Datastore) {
+	defer trace.End(trace.Begin(input.DiskProvisioning))
+
+	provisioning, err := disk.ParseProvisioning(input.DiskProvisioning)
+	if err != nil {
+		v.NoteIssue(err)
+		return
+	}
+	conf.DiskProvisioning = input.DiskProvisioning
+
+	// thin is the default and has always been used, so only an explicit choice is checked
+	if input.DiskProvisioning == "" {
+		return
+	}
+
+	checked := make(map[types.ManagedObjectReference]bool)
+	for _, ds := range datastores {
+		if checked[ds.Reference()] {
+			continue
+		}
+		checked[ds.Reference()] = true
+
+		var mds mo.Datastore
+		if err = ds.Properties(ctx, ds.Reference(), []string{"summary", "capability"}, &mds); err != nil {
+			v.NoteIssue(errors.Errorf("Unable to check disk provisioning support of datastore %q: %s", ds.Name(), err))
+			continue
+		}
+		v.NoteIssue(provisioningSupported(provisioning, &mds))
+	}
+}
+
+// provisioningSupported returns an error if disks with the provisioning cannot be created on the datastore
+func provisioningSupported(provisioning disk.Provisioning, mds *mo.Datastore) error {
+	switch {
+	case provisioning == disk.Thin && !mds.Capability.PerFileThinProvisioningSupported:
+		return errors.Errorf("Datastore %q does not support thin provisioned disks, specify --disk-provisioning %s", mds.Summary.Name, disk.Thick)
+	case provisioning != disk.Thin && strings.HasPrefix(mds.Summary.Type, "NFS"):
+		// whether thick disks can be created depends on the VAAI-NAS plugin of the hosts, which cannot be
+		// checked here, and without it vSphere fails the creation
+		log.Warnf("Datastore %q is %s, which only supports %s disks if the hosts have a VAAI-NAS plugin", mds.Summary.Name, mds.Summary.Type, provisioning)
+	case provisioning != disk.Thin && mds.Summary.Type == "vsan":
+		log.Warnf("Datastore %q is vSAN, which allocates disks by storage policy rather than as %s", mds.Summary.Name, provisioning)
 	}
+	return nil
 }
 
 func (v *Validator) DatastoreHelper(ctx context.Context, path string, label string, flag string) (*url.URL, *object.Datastore, error) {
@@ -31,7 +31,9 @@ import (
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/certificate"
 	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/nfs"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/version"
 	"github.com/vmware/vic/pkg/vsphere/session"
@@ -42,6 +44,7 @@ type Validator struct {
 	DatacenterPath      string
 	ClusterPath         string
 	ResourcePoolPath    string
+	ApplianceHostPath   string
 	ImageStorePath      string
 	ExternalNetworkPath string
 	BridgeNetworkPath   string
@@ -54,7 +57,9 @@ type Validator struct {
 	issues []error
 
 	DisableFirewallCheck bool
-	DisableDRSCheck      bool
+	// AllowFirewall reports hosts whose firewall blocks serial-over-LAN as a warning, as the rulesets are enabled on create
+	AllowFirewall   bool
+	DisableDRSCheck bool
 }
 
 func CreateFromVCHConfig(ctx context.Context, vch *config.VirtualContainerHostConfigSpec, sess *session.Session) (*Validator, error) {
@@ -67,6 +72,22 @@ func CreateFromVCHConfig(ctx context.Context, vch *config.VirtualContainerHostCo
 	return v, nil
 }
 
+// CertificateError is returned when the certificate of the target cannot be verified and no thumbprint was given
+type CertificateError struct {
+	Host       string
+	Thumbprint string
+	Err        error
+}
+
+func (e *CertificateError) Error() string {
+	return e.Err.Error()
+}
+
+// AuthFailure reports that the error is a rejection of the target certificate
+func (e *CertificateError) AuthFailure() bool {
+	return true
+}
+
 func NewValidator(ctx context.Context, input *data.Data) (*Validator, error) {
 	v, err := CreateNoDCCheck(ctx, input)
 	if err != nil {
@@ -82,6 +103,7 @@ func CreateNoDCCheck(ctx context.Context, input *data.Data) (*Validator, error)
 
 	v := &Validator{}
 	v.Context = ctx
+	v.AllowFirewall = input.AllowFirewall
 	tURL := input.URL
 
 	// default to https scheme
@@ -106,7 +128,7 @@ func CreateNoDCCheck(ctx context.Context, input *data.Data) (*Validator, error)
 				// TODO: prompt user / check ./known_hosts
 				log.Errorf("Failed to verify certificate for target=%s (thumbprint=%s)",
 					tURL.Host, cert.ThumbprintSHA1)
-				return nil, cert.Err
+				return nil, &CertificateError{Host: tURL.Host, Thumbprint: cert.ThumbprintSHA1, Err: cert.Err}
 			}
 		}
 
@@ -117,6 +139,8 @@ func CreateNoDCCheck(ctx context.Context, input *data.Data) (*Validator, error)
 	sessionconfig := &session.Config{
 		Thumbprint: input.Thumbprint,
 		Insecure:   input.Force,
+		Token:      input.Token,
+		Ticket:     input.Ticket,
 	}
 
 	// if a datacenter was specified, set it
@@ -212,9 +236,12 @@ func (v *Validator) ListIssues() error {
 	log.Error("--------------------")
 	for _, err := range v.issues {
 		log.Error(err)
+		if i, ok := err.(*Issue); ok && i.Remediation != "" {
+			log.Errorf("  [%s] %s", i.Code, i.Remediation)
+		}
 	}
 
-	return errors.New("validation of configuration failed")
+	return &ValidationError{Issues: v.issues}
 }
 
 func (v *Validator) GetIssues() []error {
@@ -236,15 +263,18 @@ func (v *Validator) Validate(ctx context.Context, input *data.Data) (*config.Vir
 	v.basics(ctx, input, conf)
 
 	v.target(ctx, input, conf)
+	v.credentials(input)
 	v.compute(ctx, input, conf)
 	v.storage(ctx, input, conf)
 	v.network(ctx, input, conf)
-	v.CheckFirewall(ctx)
-	v.CheckLicense(ctx)
-	v.CheckDrs(ctx)
+	v.preflight(ctx, input, conf)
+	v.placementRules(ctx, input, conf)
+	v.applianceHost(ctx, input, conf)
+	v.externalVMs(ctx, input, conf)
 
 	v.certificate(ctx, input, conf)
 	v.certificateAuthorities(ctx, input, conf)
+	v.registries(input, conf)
 
 	// Perform the higher level compatibility and consistency checks
 	v.compatibility(ctx, conf)
@@ -280,6 +310,39 @@ func (v *Validator) basics(ctx context.Context, input *data.Data, conf *config.V
 		log.Debugf("Setting scratch image size to %d KB in VCHConfig", conf.ScratchSize)
 	}
 
+	conf.ContainerCrashLogs = input.ContainerCrashLogs
+
+	if err := input.ContainerLogConfig.Validate(); err != nil {
+		v.NoteIssue(errors.Errorf("Invalid container log driver: %s", err))
+	}
+	conf.ContainerLogConfig = input.ContainerLogConfig
+	conf.ContainerVMProfile = input.ContainerVMProfile
+	conf.SerialConcentrator = input.SerialConcentrator
+	conf.VMFolder = input.VMFolder
+
+	if input.Protected != nil {
+		conf.Protected = *input.Protected
+	}
+
+	conf.MaintenanceJobs = input.MaintenanceJobs
+
+	if input.ReleaseURL != nil {
+		conf.ReleaseURL = *input.ReleaseURL
+	}
+
+	adminAuth(input, conf)
+}
+
+// adminAuth sets the directory server that vicadmin users are authenticated against, if one is given
+func adminAuth(input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	if input.AdminLDAPURL == nil {
+		return
+	}
+
+	// an empty url authenticates against vSphere
+	conf.LDAPURL = *input.AdminLDAPURL
+	conf.LDAPUserDN = input.AdminLDAPUserDN
+	conf.LDAPCertificateAuthorities = input.AdminLDAPCAs
 }
 
 func (v *Validator) checkSessionSet() []string {
@@ -333,6 +396,16 @@ func (v *Validator) target(ctx context.Context, input *data.Data, conf *config.V
 	// TODO: more checks needed here if specifying service account for VCH
 }
 
+// credentials checks that the appliance can log in to the target. The appliance logs in to vCenter
+// with its extension certificate, but to ESX with the user and password it was created with, which
+// a SAML token or session ticket does not provide.
+func (v *Validator) credentials(input *data.Data) {
+	if v.IsVC() || (input.Token == "" && input.Ticket == "") {
+		return
+	}
+	v.NoteIssue(errors.New("Creating a VCH on ESX requires user and password, token and session ticket login is only supported with vCenter"))
+}
+
 func (v *Validator) managedbyVC(ctx context.Context) {
 	defer trace.End(trace.Begin(""))
 
@@ -375,6 +448,16 @@ func (v *Validator) certificate(ctx context.Context, input *data.Data, conf *con
 		Key:  input.KeyPEM,
 		Cert: input.CertPEM,
 	}
+
+	conf.CertificateExpiryThresholds = input.CertExpiryThresholds
+	conf.CertificateRenewalOverlap = input.CertRenewalOverlap
+	conf.CertificateAutoRenew = input.CertAutoRenew
+
+	if input.CertAutoRenew && err == nil {
+		if cert, _, perr := certificate.ParseCertificate(input.CertPEM, input.KeyPEM); perr == nil && !certificate.IsSelfSigned(cert) {
+			log.Warn("Certificate auto-renewal only applies to self-signed certificates and will have no effect")
+		}
+	}
 }
 
 func (v *Validator) certificateAuthorities(ctx context.Context, input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
@@ -402,6 +485,26 @@ func (v *Validator) certificateAuthorities(ctx context.Context, input *data.Data
 	conf.CertificateAuthorities = input.ClientCAs
 }
 
+// registries checks that the registry certificate authorities can be loaded and adds them and the
+// insecure registries to conf
+func (v *Validator) registries(input *data.Data, conf *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+
+	conf.InsecureRegistries = input.InsecureRegistries
+
+	if len(input.RegistryCAs) == 0 {
+		return
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(input.RegistryCAs) {
+		v.NoteIssue(errors.New("Unable to load registry certificate authority data"))
+		return
+	}
+
+	conf.RegistryCertificateAuthorities = input.RegistryCAs
+}
+
 func (v *Validator) compatibility(ctx context.Context, conf *config.VirtualContainerHostConfigSpec) {
 	defer trace.End(trace.Begin(""))
 
@@ -448,6 +551,9 @@ func (v *Validator) getAllDatastores(ctx context.Context, conf *config.VirtualCo
 		datastoreSet = v.getDatastore(ctx, &u, datastoreSet)
 	}
 	for _, u := range conf.VolumeLocations {
+		if nfs.IsExport(u) {
+			continue
+		}
 		datastoreSet = v.getDatastore(ctx, u, datastoreSet)
 	}
 	return datastoreSet
@@ -602,8 +708,16 @@ func (v *Validator) AddDeprecatedFields(ctx context.Context, conf *config.Virtua
 
 	dconfig := data.InstallerData{}
 
-	dconfig.ApplianceSize.CPU.Limit = int64(input.NumCPUs)
-	dconfig.ApplianceSize.Memory.Limit = int64(input.MemoryMB)
+	dconfig.ApplianceCPUs = int32(input.NumCPUs)
+	dconfig.ApplianceMemoryMB = int64(input.MemoryMB)
+
+	dconfig.ApplianceSize.CPU.Reservation = int64(input.ApplianceCPUReservationMHz)
+	dconfig.ApplianceSize.CPU.Limit = int64(input.ApplianceCPULimitMHz)
+	dconfig.ApplianceSize.CPU.Shares = input.ApplianceCPUShares
+
+	dconfig.ApplianceSize.Memory.Reservation = int64(input.ApplianceMemoryReservationMB)
+	dconfig.ApplianceSize.Memory.Limit = int64(input.ApplianceMemoryLimitMB)
+	dconfig.ApplianceSize.Memory.Shares = input.ApplianceMemoryShares
 
 	dconfig.Datacenter = v.Session.Datacenter.Reference()
 	dconfig.DatacenterName = v.Session.Datacenter.Name()
@@ -614,6 +728,12 @@ func (v *Validator) AddDeprecatedFields(ctx context.Context, conf *config.Virtua
 	dconfig.ResourcePoolPath = v.ResourcePoolPath
 	dconfig.UseRP = input.UseRP
 
+	dconfig.ApplianceHost = v.ApplianceHostPath
+	dconfig.ApplianceHostGroup = input.ApplianceHostGroup
+	dconfig.HostGroupMandatory = input.HostGroupMandatory
+	dconfig.AllowFirewall = input.AllowFirewall
+	dconfig.ApplianceHA = input.ApplianceHA
+
 	log.Debugf("Datacenter: %q, Cluster: %q, Resource Pool: %q", dconfig.DatacenterName, dconfig.ClusterPath, dconfig.ResourcePoolPath)
 
 	dconfig.VCHSize.CPU.Reservation = int64(input.VCHCPUReservationsMHz)
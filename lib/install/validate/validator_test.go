@@ -15,6 +15,7 @@
 package validate
 
 import (
+	"errors"
 	"net"
 	"net/url"
 	"testing"
@@ -23,11 +24,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/install/data"
+	"github.com/vmware/vic/pkg/ip"
 	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/disk"
 	"github.com/vmware/vic/pkg/vsphere/session"
 	"github.com/vmware/vic/pkg/vsphere/simulator"
 
@@ -122,6 +127,9 @@ func TestMain(t *testing.T) {
 		conf := testCompute(validator, input, t)
 		testTargets(validator, input, conf, t)
 		testStorage(validator, input, conf, t)
+		testPlacementRules(validator, input, conf, t)
+		testApplianceHost(validator, input, conf, t)
+		testExternalVMs(validator, input, conf, t)
 		//		testNetwork() need dvs support
 	}
 }
@@ -132,7 +140,7 @@ func getESXData(url *url.URL) *data.Data {
 	result.URL = url
 	result.DisplayName = "test001"
 	result.ComputeResourcePath = "/ha-datacenter/host/localhost.localdomain/Resources"
-	result.ImageDatastorePath = "LocalDS_0"
+	result.ImageDatastorePaths = []string{"LocalDS_0"}
 	result.BridgeNetworkName = "bridge"
 	_, result.BridgeIPRange, _ = net.ParseCIDR("172.16.0.0/12")
 	result.ManagementNetwork.Name = "VM Network"
@@ -149,7 +157,7 @@ func getVPXData(url *url.URL) *data.Data {
 	result.URL = url
 	result.DisplayName = "test001"
 	result.ComputeResourcePath = "/DC0/host/DC0_C0/Resources"
-	result.ImageDatastorePath = "LocalDS_0"
+	result.ImageDatastorePaths = []string{"LocalDS_0"}
 	result.ExternalNetwork.Name = "VM Network"
 	result.BridgeNetworkName = "bridge"
 	_, result.BridgeIPRange, _ = net.ParseCIDR("172.16.0.0/12")
@@ -291,6 +299,22 @@ func testStorage(v *Validator, input *data.Data, conf *config.VirtualContainerHo
 			map[string]string{"volume1": "ds://LocalDS_0/volumes/volume1",
 				"volume2": "ds://LocalDS_0/volumes/volume2"}},
 
+		{"ds://LocalDS_0/images",
+			map[string]string{"volume1": "LocalDS_0/volumes/volume1",
+				"volume2": "ds://LocalDS_0/volumes/volume2",
+				"shared":  "nfs://fileserver/export"},
+			false,
+			"ds://LocalDS_0/images",
+			map[string]string{"volume1": "ds://LocalDS_0/volumes/volume1",
+				"volume2": "ds://LocalDS_0/volumes/volume2",
+				"shared":  "nfs://fileserver/export"}},
+
+		{"ds://LocalDS_0/images",
+			map[string]string{"shared": "nfs://fileserver"},
+			true,
+			"ds://LocalDS_0/images",
+			nil},
+
 		{"ds://😗",
 			map[string]string{"volume1": "😗/volumes/volume1",
 				"volume2": "ds://😗/volumes/volume2"},
@@ -336,7 +360,7 @@ func testStorage(v *Validator, input *data.Data, conf *config.VirtualContainerHo
 
 	for _, test := range tests {
 		t.Logf("%+v", test)
-		input.ImageDatastorePath = test.image
+		input.ImageDatastorePaths = []string{test.image}
 		input.VolumeLocations = test.volumes
 		v.storage(v.Context, input, conf)
 		v.ListIssues()
@@ -352,4 +376,249 @@ func testStorage(v *Validator, input *data.Data, conf *config.VirtualContainerHo
 		}
 		v.issues = nil
 	}
+
+	// a datastore can only hold one image store
+	conf.ImageStores = nil
+	input.ImageDatastorePaths = []string{"LocalDS_0/a", "ds://LocalDS_0/b"}
+	input.VolumeLocations = nil
+	v.storage(v.Context, input, conf)
+	assert.Equal(t, 1, len(v.issues))
+	assert.Equal(t, 1, len(conf.ImageStores))
+	v.issues = nil
+
+	conf.ImageStores = nil
+	input.ImageStorePlacement = "fastest"
+	input.ImageDatastorePaths = []string{"LocalDS_0"}
+	v.storage(v.Context, input, conf)
+	assert.Equal(t, 1, len(v.issues))
+	v.issues = nil
+}
+
+func testApplianceHost(v *Validator, input *data.Data, conf *config.VirtualContainerHostConfigSpec, t *testing.T) {
+	v.applianceHost(v.Context, input, conf)
+	assert.Equal(t, 0, len(v.issues))
+	assert.Equal(t, "", v.ApplianceHostPath)
+
+	hosts, err := v.Session.Cluster.Hosts(v.Context)
+	if !assert.NoError(t, err) || !assert.NotEmpty(t, hosts) {
+		return
+	}
+
+	input.ApplianceHost = hosts[0].InventoryPath
+	v.applianceHost(v.Context, input, conf)
+	assert.Equal(t, 0, len(v.issues))
+	assert.Equal(t, hosts[0].InventoryPath, v.ApplianceHostPath)
+	v.ApplianceHostPath = ""
+
+	host := simulator.Map.Get(hosts[0].Reference()).(*simulator.HostSystem)
+
+	host.Runtime.ConnectionState = types.HostSystemConnectionStateDisconnected
+	v.applianceHost(v.Context, input, conf)
+	assert.Equal(t, 1, len(v.issues))
+	assert.Equal(t, "", v.ApplianceHostPath)
+	host.Runtime.ConnectionState = types.HostSystemConnectionStateConnected
+	v.issues = nil
+
+	host.Runtime.InMaintenanceMode = true
+	v.applianceHost(v.Context, input, conf)
+	assert.Equal(t, 1, len(v.issues))
+	assert.Equal(t, "", v.ApplianceHostPath)
+	host.Runtime.InMaintenanceMode = false
+	v.issues = nil
+
+	if assert.NotEmpty(t, conf.ImageStores) {
+		mounted := host.Datastore
+		host.Datastore = nil
+		v.applianceHost(v.Context, input, conf)
+		assert.Equal(t, len(conf.ImageStores), len(v.issues))
+		assert.Equal(t, "", v.ApplianceHostPath)
+		host.Datastore = mounted
+		v.issues = nil
+	}
+
+	input.ApplianceHost = "missing"
+	v.applianceHost(v.Context, input, conf)
+	assert.Equal(t, 1, len(v.issues))
+	assert.Equal(t, "", v.ApplianceHostPath)
+
+	input.ApplianceHost = ""
+	v.issues = nil
+}
+
+func testExternalVMs(v *Validator, input *data.Data, conf *config.VirtualContainerHostConfigSpec, t *testing.T) {
+	vms, err := v.Session.Finder.VirtualMachineList(v.Context, "*")
+	if !assert.NoError(t, err) || !assert.NotEmpty(t, vms) {
+		return
+	}
+
+	// a VM given twice is recorded once
+	input.ExternalVMs = []string{vms[0].InventoryPath, vms[0].InventoryPath}
+	v.externalVMs(v.Context, input, conf)
+	assert.Equal(t, 0, len(v.issues))
+	assert.Equal(t, []types.ManagedObjectReference{vms[0].Reference()}, conf.ExternalVMs)
+	conf.ExternalVMs = nil
+
+	input.ExternalVMs = []string{"missing"}
+	v.externalVMs(v.Context, input, conf)
+	assert.Equal(t, 1, len(v.issues))
+	assert.Empty(t, conf.ExternalVMs)
+
+	input.ExternalVMs = nil
+	v.issues = nil
+}
+
+func testPlacementRules(v *Validator, input *data.Data, conf *config.VirtualContainerHostConfigSpec, t *testing.T) {
+	v.placementRules(v.Context, input, conf)
+	assert.Equal(t, 0, len(v.issues))
+	assert.Equal(t, "", conf.ContainerVMGroup)
+	assert.Equal(t, "", conf.AntiAffinityRulePrefix)
+
+	// mandatory makes no sense without a host group
+	input.HostGroupMandatory = true
+	v.placementRules(v.Context, input, conf)
+	assert.Equal(t, 1, len(v.issues))
+	input.HostGroupMandatory = false
+	v.issues = nil
+}
+
+func TestPortGroupMTU(t *testing.T) {
+	info := &types.HostNetworkInfo{
+		Vswitch: []types.HostVirtualSwitch{
+			{Key: "key-vim.host.VirtualSwitch-vSwitch0"},
+			{Key: "key-vim.host.VirtualSwitch-vSwitch1", Mtu: 9000},
+		},
+		Portgroup: []types.HostPortGroup{
+			{Vswitch: "key-vim.host.VirtualSwitch-vSwitch0", Spec: types.HostPortGroupSpec{Name: "VM Network"}},
+			{Vswitch: "key-vim.host.VirtualSwitch-vSwitch1", Spec: types.HostPortGroupSpec{Name: "jumbo"}},
+		},
+	}
+
+	assert.Equal(t, defaultSwitchMTU, portGroupMTU(info, "VM Network"))
+	assert.Equal(t, 9000, portGroupMTU(info, "jumbo"))
+	assert.Equal(t, 0, portGroupMTU(info, "missing"))
+}
+
+func TestCheckContainerNetworkIPAM(t *testing.T) {
+	gw, _ := ip.ParseIPandMask("10.10.0.1/16")
+	pools := []ip.Range{*ip.ParseRange("10.10.1.0-10.10.1.255")}
+	servers := []net.IP{net.ParseIP("10.20.0.2")}
+
+	var tests = []struct {
+		ipam     string
+		gw       net.IPNet
+		pools    []ip.Range
+		servers  []net.IP
+		reserved []net.IP
+		valid    bool
+	}{
+		{"", gw, pools, nil, nil, true},
+		{"", net.IPNet{}, nil, nil, nil, true},
+		{executor.IPAMStatic, gw, pools, nil, []net.IP{net.ParseIP("10.10.1.10")}, true},
+		{executor.IPAMStatic, gw, nil, nil, []net.IP{net.ParseIP("10.10.2.10")}, true},
+		{executor.IPAMStatic, net.IPNet{}, nil, nil, nil, false},
+		{executor.IPAMStatic, gw, pools, nil, []net.IP{net.ParseIP("10.10.2.10")}, false},
+		{executor.IPAMStatic, gw, nil, nil, []net.IP{net.ParseIP("10.10.0.1")}, false},
+		{executor.IPAMStatic, gw, nil, nil, []net.IP{net.ParseIP("10.11.0.10")}, false},
+		{executor.IPAMStatic, gw, pools, servers, nil, false},
+		{executor.IPAMDHCP, net.IPNet{}, nil, nil, nil, true},
+		{executor.IPAMDHCP, gw, pools, nil, nil, false},
+		{executor.IPAMDHCP, net.IPNet{}, nil, nil, []net.IP{net.ParseIP("10.10.1.10")}, false},
+		{executor.IPAMDHCPRelay, gw, nil, servers, nil, true},
+		{executor.IPAMDHCPRelay, net.IPNet{}, nil, servers, nil, false},
+		{executor.IPAMDHCPRelay, gw, nil, nil, nil, false},
+		{executor.IPAMDHCPRelay, gw, pools, servers, nil, false},
+	}
+
+	for i, te := range tests {
+		err := checkContainerNetworkIPAM("net", te.ipam, te.gw, te.pools, te.servers, te.reserved)
+		if te.valid {
+			assert.NoError(t, err, "test %d", i)
+		} else {
+			assert.Error(t, err, "test %d", i)
+		}
+	}
+}
+
+func TestProvisioningSupported(t *testing.T) {
+	vmfs := &mo.Datastore{
+		Summary:    types.DatastoreSummary{Name: "datastore1", Type: "VMFS"},
+		Capability: types.DatastoreCapability{PerFileThinProvisioningSupported: true},
+	}
+	nfs := &mo.Datastore{
+		Summary:    types.DatastoreSummary{Name: "nfs1", Type: "NFS"},
+		Capability: types.DatastoreCapability{PerFileThinProvisioningSupported: true},
+	}
+	thick := &mo.Datastore{
+		Summary: types.DatastoreSummary{Name: "thick1", Type: "VMFS"},
+	}
+
+	for _, p := range []disk.Provisioning{disk.Thin, disk.Thick, disk.EagerZeroedThick} {
+		assert.NoError(t, provisioningSupported(p, vmfs), string(p))
+		// thick on NFS depends on the host plugins so is only warned about
+		assert.NoError(t, provisioningSupported(p, nfs), string(p))
+	}
+
+	assert.Error(t, provisioningSupported(disk.Thin, thick))
+	assert.NoError(t, provisioningSupported(disk.Thick, thick))
+}
+
+func TestVolumeStoragePolicies(t *testing.T) {
+	conf := &config.VirtualContainerHostConfigSpec{}
+	conf.VolumeLocations = map[string]*url.URL{"default": {Scheme: "ds", Host: "datastore1", Path: "volumes"}}
+
+	input := data.NewData()
+	input.VolumeStoragePolicies = map[string]string{"default": "encrypted", "missing": "encrypted"}
+
+	v := &Validator{}
+	v.volumeStoragePolicies(input, conf)
+
+	assert.Equal(t, map[string]string{"default": "encrypted"}, conf.VolumeStoragePolicies)
+	assert.Len(t, v.issues, 1)
+}
+
+func TestMissingPrivileges(t *testing.T) {
+	roles := object.AuthorizationRoleList{
+		{RoleId: 10, Privilege: []string{"Resource.AssignVMToPool", "VirtualMachine.Inventory.Create"}},
+		{RoleId: 11, Privilege: []string{"Network.Assign"}},
+	}
+	required := []string{"VirtualMachine.Inventory.Create", "Resource.AssignVMToPool", "Network.Assign"}
+
+	assert.Empty(t, missingPrivileges(roles, []int32{adminRoleID}, required))
+	assert.Empty(t, missingPrivileges(roles, []int32{10, 11}, required))
+	assert.Equal(t, []string{"Network.Assign"}, missingPrivileges(roles, []int32{10}, required))
+	assert.Equal(t, []string{"Network.Assign", "Resource.AssignVMToPool", "VirtualMachine.Inventory.Create"}, missingPrivileges(roles, []int32{-2}, required))
+}
+
+func TestHostsWithout(t *testing.T) {
+	h1 := object.NewHostSystem(nil, types.ManagedObjectReference{Type: "HostSystem", Value: "host-1"})
+	h1.InventoryPath = "/dc/host/cluster/h1"
+	h2 := object.NewHostSystem(nil, types.ManagedObjectReference{Type: "HostSystem", Value: "host-2"})
+	h2.InventoryPath = "/dc/host/cluster/h2"
+
+	hosts := []*object.HostSystem{h1, h2}
+	assert.Empty(t, hostsWithout(hosts, []types.ManagedObjectReference{h2.Reference(), h1.Reference()}))
+	assert.Equal(t, []string{h2.InventoryPath}, hostsWithout(hosts, []types.ManagedObjectReference{h1.Reference()}))
+	assert.Len(t, hostsWithout(hosts, nil), 2)
+}
+
+func TestListIssues(t *testing.T) {
+	v := &Validator{}
+	assert.NoError(t, v.ListIssues())
+
+	v.NoteIssue(errors.New("invalid image store"))
+	v.noteIssue(IssueDRS, errors.New("DRS must be enabled to use VIC"), "Enable DRS on the cluster")
+
+	err := v.ListIssues()
+	verr, ok := err.(*ValidationError)
+	if !assert.True(t, ok, "expected ValidationError, got %#v", err) {
+		return
+	}
+
+	if assert.Len(t, verr.Issues, 2) {
+		issue, ok := verr.Issues[1].(*Issue)
+		if assert.True(t, ok) {
+			assert.Equal(t, IssueDRS, issue.Code)
+			assert.Equal(t, "DRS must be enabled to use VIC", issue.Error())
+		}
+	}
 }
@@ -15,6 +15,8 @@
 package metadata
 
 import (
+	"time"
+
 	docker "github.com/docker/docker/image"
 )
 
@@ -30,4 +32,17 @@ type ImageConfig struct {
 	DiffIDs   map[string]string `json:"diff_ids,omitempty"`
 	History   []docker.History  `json:"history,omitempty"`
 	Reference string            `json:"registry"`
+
+	// Healthcheck is held separately as the vendored container config predates it
+	Healthcheck *HealthConfig `json:"healthcheck,omitempty"`
+}
+
+// HealthConfig is the healthcheck defined by an image
+type HealthConfig struct {
+	// Test is the check to perform, e.g. ["CMD-SHELL", "curl -f http://localhost/"], or ["NONE"] to disable
+	// a healthcheck inherited from the base image
+	Test     []string      `json:",omitempty"`
+	Interval time.Duration `json:",omitempty"`
+	Timeout  time.Duration `json:",omitempty"`
+	Retries  int           `json:",omitempty"`
 }
@@ -0,0 +1,198 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attach
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/vmware/vic/cmd/tether/msgs"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// ArchiveProtocolVersion is the first tether protocol revision that serves archives
+const ArchiveProtocolVersion uint32 = 2
+
+// ErrArchiveUnsupported is returned for containers whose tether predates archive support
+var ErrArchiveUnsupported = errors.New("the container's tether does not support copying files")
+
+// ArchiveInteraction reads and writes the filesystem of a running containerVM as tar archives
+type ArchiveInteraction interface {
+	// Stat returns the stat of path. A missing path is reported with an error satisfying os.IsNotExist
+	Stat(path string) (*types.ContainerPathStat, error)
+	// Read returns a tar archive of path. Errors in the transfer are returned by the final Read.
+	Read(path string) (io.ReadCloser, error)
+	// Write extracts the tar archive content to the directory path
+	Write(path string, content io.Reader, noOverwriteDirNonDir bool) error
+}
+
+type archiveSSH struct {
+	client *ssh.Client
+}
+
+// SSHArchive returns the archive interface of the filesystem served by the tether.
+// The ssh client is assumed to be connected to a tether supporting ArchiveProtocolVersion.
+func SSHArchive(client *ssh.Client) ArchiveInteraction {
+	return &archiveSSH{client: client}
+}
+
+// statusError converts the status reported by the tether for path into an error
+func statusError(op, path string, payload []byte) error {
+	status := msgs.ArchiveStatusMsg{}
+	if err := status.Unmarshal(payload); err != nil {
+		return fmt.Errorf("failed to unmarshal archive status from remote: %s", err)
+	}
+
+	if status.NotExist {
+		return &os.PathError{Op: op, Path: path, Err: syscall.ENOENT}
+	}
+
+	if status.Error != "" {
+		return &os.PathError{Op: op, Path: path, Err: errors.New(status.Error)}
+	}
+
+	return nil
+}
+
+// waitStatus returns the outcome reported on an archive channel
+func waitStatus(op, path string, requests <-chan *ssh.Request) error {
+	for req := range requests {
+		if req.Type != msgs.ArchiveStatusReq {
+			// default, preserving OpenSSH behaviour
+			req.Reply(false, nil)
+			continue
+		}
+
+		if req.WantReply {
+			req.Reply(true, nil)
+		}
+
+		err := statusError(op, path, req.Payload)
+
+		// drain the channel until it's closed
+		go ssh.DiscardRequests(requests)
+		return err
+	}
+
+	return fmt.Errorf("archive channel for %s closed without status", path)
+}
+
+func (t *archiveSSH) open(msg *msgs.ArchiveMsg) (ssh.Channel, <-chan *ssh.Request, error) {
+	channel, requests, err := t.client.OpenChannel(msgs.ArchiveChannelType, msg.Marshal())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open archive channel for %s: %s", msg.Path, err)
+	}
+
+	return channel, requests, nil
+}
+
+func (t *archiveSSH) Stat(path string) (*types.ContainerPathStat, error) {
+	defer trace.End(trace.Begin(path))
+
+	msg := msgs.StatMsg{Path: path}
+	ok, reply, err := t.client.SendRequest(msgs.StatReq, true, msg.Marshal())
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s on remote: %s", path, err)
+	}
+
+	if !ok {
+		if err = statusError("stat", path, reply); err == nil {
+			err = fmt.Errorf("failed to stat %s on remote", path)
+		}
+		return nil, err
+	}
+
+	if err = msg.Unmarshal(reply); err != nil {
+		log.Debugf("raw stat response: %+v", reply)
+		return nil, fmt.Errorf("failed to unmarshal stat from remote: %s", err)
+	}
+
+	return &types.ContainerPathStat{
+		Name:       msg.Name,
+		Size:       int64(msg.Size),
+		Mode:       os.FileMode(msg.Mode),
+		Mtime:      time.Unix(0, int64(msg.Mtime)),
+		LinkTarget: msg.LinkTarget,
+	}, nil
+}
+
+// archiveReader returns the data of an archive channel followed by the transfer status
+type archiveReader struct {
+	channel  ssh.Channel
+	requests <-chan *ssh.Request
+	path     string
+}
+
+func (r *archiveReader) Read(p []byte) (int, error) {
+	n, err := r.channel.Read(p)
+	if err == io.EOF {
+		if serr := waitStatus("read", r.path, r.requests); serr != nil {
+			return n, serr
+		}
+	}
+
+	return n, err
+}
+
+func (r *archiveReader) Close() error {
+	return r.channel.Close()
+}
+
+func (t *archiveSSH) Read(path string) (io.ReadCloser, error) {
+	defer trace.End(trace.Begin(path))
+
+	channel, requests, err := t.open(&msgs.ArchiveMsg{Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	return &archiveReader{channel: channel, requests: requests, path: path}, nil
+}
+
+func (t *archiveSSH) Write(path string, content io.Reader, noOverwriteDirNonDir bool) error {
+	defer trace.End(trace.Begin(path))
+
+	channel, requests, err := t.open(&msgs.ArchiveMsg{Path: path, Write: true, NoOverwriteDirNonDir: noOverwriteDirNonDir})
+	if err != nil {
+		return err
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+
+		if _, err := io.Copy(channel, content); err != nil {
+			log.Debugf("copy to archive channel for %s ended: %s", path, err)
+		}
+		channel.CloseWrite()
+	}()
+
+	// the status is authoritative - the copy fails once the tether has stopped reading, and a
+	// truncated upload fails the extraction
+	err = waitStatus("write", path, requests)
+
+	channel.Close()
+	<-copied
+
+	return err
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/portlayer/constants"
 	"github.com/vmware/vic/lib/portlayer/exec"
 	"github.com/vmware/vic/pkg/trace"
@@ -45,6 +46,19 @@ func lookupVCHIP() (net.IP, error) {
 	return ips[0], nil
 }
 
+// concentratorURI returns the URI of the virtual serial port concentrator that the serial port
+// is proxied through, empty to connect it to the appliance directly
+func concentratorURI(ip net.IP) string {
+	switch exec.Config.SerialConcentrator {
+	case "":
+		return ""
+	case config.SerialConcentratorAppliance:
+		return fmt.Sprintf("telnet://%s:%d", ip, constants.SerialConcentratorPort)
+	default:
+		return exec.Config.SerialConcentrator
+	}
+}
+
 func toggle(handle *exec.Handle, connected bool) (*exec.Handle, error) {
 	// get the virtual device list
 	devices := object.VirtualDeviceList(handle.Config.Hardware.Device)
@@ -70,9 +84,10 @@ func toggle(handle *exec.Handle, connected bool) (*exec.Handle, error) {
 	b := serial.GetVirtualDevice().Backing.(*types.VirtualSerialPortURIBackingInfo)
 
 	serviceURI := fmt.Sprintf("tcp://%s:%d", ip, constants.SerialOverLANPort)
+	proxyURI := concentratorURI(ip)
 
-	if b.ServiceURI == serviceURI && c.Connected == connected {
-		log.Debugf("Already in the desired state (connected: %t, serviceURI: %s)", connected, serviceURI)
+	if b.ServiceURI == serviceURI && b.ProxyURI == proxyURI && c.Connected == connected {
+		log.Debugf("Already in the desired state (connected: %t, serviceURI: %s, proxyURI: %s)", connected, serviceURI, proxyURI)
 		return handle, nil
 	}
 
@@ -86,6 +101,8 @@ func toggle(handle *exec.Handle, connected bool) (*exec.Handle, error) {
 
 	log.Debugf("Setting ServiceURI to %s", serviceURI)
 	b.ServiceURI = serviceURI
+	log.Debugf("Setting ProxyURI to %q", proxyURI)
+	b.ProxyURI = proxyURI
 
 	config := &types.VirtualDeviceConfigSpec{
 		Device:    serial,
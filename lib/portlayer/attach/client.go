@@ -71,6 +71,30 @@ func SSHls(client *ssh.Client) ([]string, error) {
 	return ids.IDs, nil
 }
 
+// SSHVersion returns the version reported by the tether. Tethers that predate version
+// reporting reject the request, in which case a zero VersionMsg is returned.
+func SSHVersion(client *ssh.Client) (*msgs.VersionMsg, error) {
+	defer trace.End(trace.Begin(""))
+
+	ok, reply, err := client.SendRequest(msgs.VersionReq, true, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version from remote: %s", err)
+	}
+
+	v := &msgs.VersionMsg{}
+	if !ok {
+		log.Debugf("remote does not support version request: %s", string(reply))
+		return v, nil
+	}
+
+	if err = v.Unmarshal(reply); err != nil {
+		log.Debugf("raw version response: %+v", reply)
+		return nil, fmt.Errorf("failed to unmarshal version from remote: %s", err)
+	}
+
+	return v, nil
+}
+
 // SSHAttach returns a stream connection to the requested session
 // The ssh client is assumed to be connected to the Executor hosting the session
 func SSHAttach(client *ssh.Client, id string) (SessionInteraction, error) {
@@ -0,0 +1,693 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attach
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Telnet commands and options used by the virtual serial port concentrator protocol
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+
+	telnetBinary    = 0
+	telnetSGA       = 3
+	telnetVMwareExt = 232
+)
+
+// Subcommands of the VMware telnet extension, from "Using a Proxy with Virtual Serial Ports"
+const (
+	vmwareKnownSuboptions1      = 0
+	vmwareKnownSuboptions2      = 1
+	vmwareUnknownSuboptionRcvd2 = 3
+	vmwareVMotionBegin          = 40
+	vmwareVMotionGoahead        = 41
+	vmwareVMotionNotNow         = 43
+	vmwareVMotionPeer           = 44
+	vmwareVMotionPeerOK         = 45
+	vmwareVMotionComplete       = 46
+	vmwareVMotionAbort          = 48
+	vmwareDoProxy               = 70
+	vmwareWillProxy             = 71
+	vmwareVMVCUUID              = 80
+	vmwareGetVMVCUUID           = 81
+)
+
+// vmwareSuboptions are the subcommands the concentrator understands, as announced to hosts
+var vmwareSuboptions = []byte{
+	vmwareKnownSuboptions1,
+	vmwareKnownSuboptions2,
+	vmwareUnknownSuboptionRcvd2,
+	vmwareVMotionBegin,
+	vmwareVMotionGoahead,
+	vmwareVMotionNotNow,
+	vmwareVMotionPeer,
+	vmwareVMotionPeerOK,
+	vmwareVMotionComplete,
+	vmwareVMotionAbort,
+	vmwareDoProxy,
+	vmwareWillProxy,
+	vmwareVMVCUUID,
+	vmwareGetVMVCUUID,
+}
+
+var errConcentratorClosed = errors.New("serial port concentrator closed")
+
+// Concentrator is a virtual serial port concentrator (vSPC) for the serial ports of containerVMs.
+// ESX hosts connect to it with one telnet session per containerVM, and it hands out those proxied
+// serial ports as connections from its Accept, so that it can stand in for the TCP listener of the
+// attach connector. Connections follow their containerVM when it is moved by vMotion.
+type Concentrator struct {
+	l net.Listener
+
+	// conns holds the proxied serial ports until they are accepted
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+	err    error
+
+	mu    sync.Mutex
+	hosts map[*hostConn]struct{}
+	// vmotions holds the serial ports being moved by vMotion, by the cookie given to the source host
+	vmotions map[string]*vspcConn
+}
+
+// NewConcentrator serves the virtual serial port concentrator protocol to the ESX hosts connecting to l
+func NewConcentrator(l net.Listener) *Concentrator {
+	c := &Concentrator{
+		l:        l,
+		conns:    make(chan net.Conn),
+		closed:   make(chan struct{}),
+		hosts:    make(map[*hostConn]struct{}),
+		vmotions: make(map[string]*vspcConn),
+	}
+
+	go c.serve()
+	return c
+}
+
+func (c *Concentrator) serve() {
+	for {
+		conn, err := c.l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				log.Warnf("vSPC: error waiting for host connection: %s", err)
+				continue
+			}
+
+			c.shutdown(err)
+			return
+		}
+
+		h := &hostConn{
+			c:    c,
+			conn: conn,
+			him:  make(map[byte]bool),
+			us:   make(map[byte]bool),
+		}
+
+		c.mu.Lock()
+		c.hosts[h] = struct{}{}
+		c.mu.Unlock()
+
+		log.Debugf("vSPC: host connection from %s", conn.RemoteAddr())
+		go h.run()
+	}
+}
+
+func (c *Concentrator) shutdown(err error) {
+	c.once.Do(func() {
+		if err == nil {
+			err = errConcentratorClosed
+		}
+		c.err = err
+		close(c.closed)
+	})
+}
+
+// Accept waits for the serial port of a containerVM to be proxied through the concentrator
+func (c *Concentrator) Accept() (net.Conn, error) {
+	select {
+	case conn := <-c.conns:
+		return conn, nil
+	case <-c.closed:
+		return nil, c.err
+	}
+}
+
+// Close stops the concentrator and closes the connections of the hosts
+func (c *Concentrator) Close() error {
+	c.shutdown(nil)
+	err := c.l.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for h := range c.hosts {
+		h.conn.Close()
+	}
+	return err
+}
+
+// Addr returns the address the hosts connect to
+func (c *Concentrator) Addr() net.Addr {
+	return c.l.Addr()
+}
+
+// forget drops the vMotion cookies of v
+func (c *Concentrator) forget(v *vspcConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for cookie, m := range c.vmotions {
+		if m == v {
+			delete(c.vmotions, cookie)
+		}
+	}
+}
+
+// hostConn is the telnet session of an ESX host for the serial port of one containerVM
+type hostConn struct {
+	c    *Concentrator
+	conn net.Conn
+
+	// wmu serializes writes to conn
+	wmu sync.Mutex
+
+	// him and us hold the telnet options enabled, or asked for, on the host side and ours
+	him map[byte]bool
+	us  map[byte]bool
+
+	// vm is the serial port proxied by the session, nil until the host asks for it
+	vm *vspcConn
+}
+
+func (h *hostConn) write(b ...byte) error {
+	h.wmu.Lock()
+	defer h.wmu.Unlock()
+
+	_, err := h.conn.Write(b)
+	return err
+}
+
+// subnegotiate sends a VMware extension subcommand with its data
+func (h *hostConn) subnegotiate(cmd byte, data []byte) error {
+	b := []byte{telnetIAC, telnetSB, telnetVMwareExt, cmd}
+	b = append(b, escapeIAC(data)...)
+	return h.write(append(b, telnetIAC, telnetSE)...)
+}
+
+func (h *hostConn) run() {
+	defer h.close()
+
+	// ask for the options needed up front rather than waiting on the host
+	if err := h.negotiate(); err != nil {
+		log.Debugf("vSPC: negotiation with %s failed: %s", h.conn.RemoteAddr(), err)
+		return
+	}
+
+	r := bufio.NewReader(h.conn)
+	var data []byte
+	for {
+		if len(data) > 0 && r.Buffered() == 0 {
+			h.deliver(data)
+			data = nil
+		}
+
+		b, err := r.ReadByte()
+		if err != nil {
+			h.deliver(data)
+			return
+		}
+		if b != telnetIAC {
+			data = append(data, b)
+			continue
+		}
+
+		cmd, err := r.ReadByte()
+		if err != nil {
+			h.deliver(data)
+			return
+		}
+		if cmd == telnetIAC {
+			data = append(data, telnetIAC)
+			continue
+		}
+
+		// commands may move the serial port, so deliver what came before them first
+		h.deliver(data)
+		data = nil
+
+		switch cmd {
+		case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+			opt, err := r.ReadByte()
+			if err == nil {
+				err = h.option(cmd, opt)
+			}
+			if err != nil {
+				return
+			}
+		case telnetSB:
+			sb, err := readSubnegotiation(r)
+			if err == nil {
+				err = h.subnegotiation(sb)
+			}
+			if err != nil {
+				log.Debugf("vSPC: closing connection from %s: %s", h.conn.RemoteAddr(), err)
+				return
+			}
+		default:
+			// NOP and the like carry nothing for a serial port
+		}
+	}
+}
+
+// close ends the session, closing the serial port unless it has been handed to another host
+func (h *hostConn) close() {
+	h.conn.Close()
+
+	h.c.mu.Lock()
+	delete(h.c.hosts, h)
+	h.c.mu.Unlock()
+
+	if h.vm != nil && h.vm.release(h) {
+		h.c.forget(h.vm)
+	}
+	log.Debugf("vSPC: host connection from %s closed", h.conn.RemoteAddr())
+}
+
+// deliver passes data from the host to the serial port
+func (h *hostConn) deliver(data []byte) {
+	if len(data) == 0 || h.vm == nil {
+		return
+	}
+	h.vm.deliver(data)
+}
+
+func (h *hostConn) negotiate() error {
+	var b []byte
+	for _, opt := range []byte{telnetVMwareExt, telnetBinary, telnetSGA} {
+		h.him[opt] = true
+		b = append(b, telnetIAC, telnetDO, opt)
+	}
+	for _, opt := range []byte{telnetBinary, telnetSGA} {
+		h.us[opt] = true
+		b = append(b, telnetIAC, telnetWILL, opt)
+	}
+	return h.write(b...)
+}
+
+// option answers the host enabling or disabling a telnet option, replying only to changes so that
+// negotiation does not loop
+func (h *hostConn) option(cmd, opt byte) error {
+	supported := opt == telnetBinary || opt == telnetSGA || opt == telnetVMwareExt
+
+	switch cmd {
+	case telnetWILL:
+		if !supported {
+			return h.write(telnetIAC, telnetDONT, opt)
+		}
+		if !h.him[opt] {
+			h.him[opt] = true
+			return h.write(telnetIAC, telnetDO, opt)
+		}
+	case telnetDO:
+		if !supported || opt == telnetVMwareExt {
+			// the extension is only spoken by the host
+			return h.write(telnetIAC, telnetWONT, opt)
+		}
+		if !h.us[opt] {
+			h.us[opt] = true
+			return h.write(telnetIAC, telnetWILL, opt)
+		}
+	case telnetWONT:
+		if h.him[opt] {
+			h.him[opt] = false
+			return h.write(telnetIAC, telnetDONT, opt)
+		}
+	case telnetDONT:
+		if h.us[opt] {
+			h.us[opt] = false
+			return h.write(telnetIAC, telnetWONT, opt)
+		}
+	}
+	return nil
+}
+
+// readSubnegotiation reads the option and data of a subnegotiation up to IAC SE, unescaping IAC
+func readSubnegotiation(r *bufio.Reader) ([]byte, error) {
+	var sb []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != telnetIAC {
+			sb = append(sb, b)
+			continue
+		}
+
+		b, err = r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case telnetSE:
+			return sb, nil
+		case telnetIAC:
+			sb = append(sb, telnetIAC)
+		default:
+			return nil, fmt.Errorf("unexpected telnet command %d in subnegotiation", b)
+		}
+	}
+}
+
+// subnegotiation handles a VMware extension subcommand from the host
+func (h *hostConn) subnegotiation(sb []byte) error {
+	if len(sb) < 2 || sb[0] != telnetVMwareExt {
+		// only the VMware extension is subnegotiated
+		return nil
+	}
+
+	cmd, data := sb[1], sb[2:]
+	switch cmd {
+	case vmwareKnownSuboptions1:
+		return h.subnegotiate(vmwareKnownSuboptions2, vmwareSuboptions)
+
+	case vmwareDoProxy:
+		if h.vm != nil {
+			return fmt.Errorf("serial port already proxied for %s", h.vm.service)
+		}
+
+		// the data is the direction of the serial port followed by the URI of the service
+		service := ""
+		if len(data) > 0 {
+			service = string(data[1:])
+		}
+		v := newVSPCConn(h, service)
+		if err := h.subnegotiate(vmwareWillProxy, nil); err != nil {
+			return err
+		}
+		if err := h.subnegotiate(vmwareGetVMVCUUID, nil); err != nil {
+			return err
+		}
+
+		log.Infof("vSPC: proxying serial port from %s for %s", h.conn.RemoteAddr(), service)
+		h.vm = v
+		select {
+		case h.c.conns <- v:
+		case <-h.c.closed:
+			return errConcentratorClosed
+		}
+
+	case vmwareVMVCUUID:
+		if h.vm != nil {
+			h.vm.setUUID(string(data))
+		}
+
+	case vmwareVMotionBegin:
+		if h.vm == nil {
+			return h.subnegotiate(vmwareVMotionNotNow, data)
+		}
+
+		// the cookie is the sequence from the source host with a secret the destination host must present
+		secret := make([]byte, 4)
+		if _, err := rand.Read(secret); err != nil {
+			log.Errorf("vSPC: unable to create vMotion secret: %s", err)
+			return h.subnegotiate(vmwareVMotionNotNow, data)
+		}
+		cookie := append(append([]byte{}, data...), secret...)
+
+		h.c.mu.Lock()
+		h.c.vmotions[string(cookie)] = h.vm
+		h.c.mu.Unlock()
+
+		log.Debugf("vSPC: vMotion of serial port for %s beginning", h.vm.ID())
+		return h.subnegotiate(vmwareVMotionGoahead, cookie)
+
+	case vmwareVMotionPeer:
+		h.c.mu.Lock()
+		v := h.c.vmotions[string(data)]
+		h.c.mu.Unlock()
+
+		if v == nil || h.vm != nil {
+			return fmt.Errorf("vMotion peer from %s with unknown cookie", h.conn.RemoteAddr())
+		}
+
+		log.Infof("vSPC: serial port for %s moving to %s", v.ID(), h.conn.RemoteAddr())
+		h.vm = v
+		v.peer(h)
+
+		return h.subnegotiate(vmwareVMotionPeerOK, data)
+
+	case vmwareVMotionComplete:
+		if h.vm != nil {
+			log.Infof("vSPC: vMotion of serial port for %s complete", h.vm.ID())
+			h.c.forget(h.vm)
+			h.vm.settle(h.vm.current())
+		}
+
+	case vmwareVMotionAbort:
+		if h.vm != nil {
+			// the source host keeps the serial port
+			log.Infof("vSPC: vMotion of serial port for %s aborted", h.vm.ID())
+			h.c.forget(h.vm)
+			h.vm.settle(h)
+		}
+
+	default:
+		return h.subnegotiate(vmwareUnknownSuboptionRcvd2, []byte{cmd})
+	}
+
+	return nil
+}
+
+// escapeIAC doubles the IAC bytes in b so that they are sent as data
+func escapeIAC(b []byte) []byte {
+	if bytes.IndexByte(b, telnetIAC) < 0 {
+		return b
+	}
+	return bytes.Replace(b, []byte{telnetIAC}, []byte{telnetIAC, telnetIAC}, -1)
+}
+
+// timeoutError is returned by reads that pass their deadline
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// vspcConn is the serial port of a containerVM proxied through the concentrator
+type vspcConn struct {
+	// service is the URI the serial port is backed by
+	service string
+
+	// data carries data from the host to Read, which keeps what did not fit in pending
+	data    chan []byte
+	pending []byte
+
+	closed chan struct{}
+	once   sync.Once
+
+	mu   sync.Mutex
+	host *hostConn
+	// source is the host the serial port is moving from during vMotion
+	source   *hostConn
+	uuid     string
+	deadline time.Time
+}
+
+func newVSPCConn(h *hostConn, service string) *vspcConn {
+	return &vspcConn{
+		service: service,
+		data:    make(chan []byte),
+		closed:  make(chan struct{}),
+		host:    h,
+	}
+}
+
+// ID identifies the containerVM for logging, by vCenter UUID once the host has given it
+func (v *vspcConn) ID() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.uuid != "" {
+		return v.uuid
+	}
+	return v.host.conn.RemoteAddr().String()
+}
+
+func (v *vspcConn) setUUID(uuid string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.uuid = uuid
+}
+
+func (v *vspcConn) current() *hostConn {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.host
+}
+
+// peer moves the serial port to the session of h, the destination host of a vMotion
+func (v *vspcConn) peer(h *hostConn) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.source = v.host
+	v.host = h
+}
+
+// settle leaves the serial port with the session of h at the end of a vMotion
+func (v *vspcConn) settle(h *hostConn) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.source = nil
+	v.host = h
+}
+
+// release closes the serial port when the session of h ends, unless it has been handed off,
+// and returns whether it was closed. A destination host dropping out of a vMotion returns the
+// serial port to the source host.
+func (v *vspcConn) release(h *hostConn) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.source == h {
+		v.source = nil
+		return false
+	}
+	if v.host != h {
+		return false
+	}
+	if v.source != nil {
+		v.host = v.source
+		v.source = nil
+		return false
+	}
+
+	v.once.Do(func() { close(v.closed) })
+	return true
+}
+
+func (v *vspcConn) deliver(data []byte) {
+	select {
+	case v.data <- data:
+	case <-v.closed:
+	}
+}
+
+// Read reads data from the serial port. The read deadline is taken when the read starts.
+func (v *vspcConn) Read(b []byte) (int, error) {
+	v.mu.Lock()
+	if len(v.pending) > 0 {
+		n := copy(b, v.pending)
+		v.pending = v.pending[n:]
+		v.mu.Unlock()
+		return n, nil
+	}
+	deadline := v.deadline
+	v.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := deadline.Sub(time.Now())
+		if d <= 0 {
+			return 0, timeoutError{}
+		}
+
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case data := <-v.data:
+		n := copy(b, data)
+		if n < len(data) {
+			v.mu.Lock()
+			v.pending = data[n:]
+			v.mu.Unlock()
+		}
+		return n, nil
+	case <-v.closed:
+		return 0, io.EOF
+	case <-timeout:
+		return 0, timeoutError{}
+	}
+}
+
+// Write writes data to the serial port through the current host
+func (v *vspcConn) Write(b []byte) (int, error) {
+	select {
+	case <-v.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	if err := v.current().write(escapeIAC(b)...); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close closes the serial port and the session of its host
+func (v *vspcConn) Close() error {
+	v.once.Do(func() { close(v.closed) })
+	return v.current().conn.Close()
+}
+
+func (v *vspcConn) LocalAddr() net.Addr {
+	return v.current().conn.LocalAddr()
+}
+
+func (v *vspcConn) RemoteAddr() net.Addr {
+	return v.current().conn.RemoteAddr()
+}
+
+func (v *vspcConn) SetDeadline(t time.Time) error {
+	v.SetReadDeadline(t)
+	return v.SetWriteDeadline(t)
+}
+
+func (v *vspcConn) SetReadDeadline(t time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.deadline = t
+	return nil
+}
+
+func (v *vspcConn) SetWriteDeadline(t time.Time) error {
+	return v.current().conn.SetWriteDeadline(t)
+}
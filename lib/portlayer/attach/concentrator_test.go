@@ -0,0 +1,253 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attach
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHost plays the ESX host side of a serial port proxied through a concentrator
+type testHost struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialHost(t *testing.T, c *Concentrator) *testHost {
+	conn, err := net.Dial("tcp", c.Addr().String())
+	require.NoError(t, err)
+
+	h := &testHost{t: t, conn: conn, r: bufio.NewReader(conn)}
+
+	// the concentrator asks for the options it needs first
+	h.expect(telnetIAC, telnetDO, telnetVMwareExt, telnetIAC, telnetDO, telnetBinary, telnetIAC, telnetDO, telnetSGA,
+		telnetIAC, telnetWILL, telnetBinary, telnetIAC, telnetWILL, telnetSGA)
+	// agreeing to them gets no reply
+	h.send(telnetIAC, telnetWILL, telnetVMwareExt, telnetIAC, telnetWILL, telnetBinary, telnetIAC, telnetDO, telnetBinary)
+	return h
+}
+
+func (h *testHost) send(b ...byte) {
+	_, err := h.conn.Write(b)
+	require.NoError(h.t, err)
+}
+
+func (h *testHost) sb(cmd byte, data ...byte) {
+	b := []byte{telnetIAC, telnetSB, telnetVMwareExt, cmd}
+	b = append(b, escapeIAC(data)...)
+	h.send(append(b, telnetIAC, telnetSE)...)
+}
+
+func (h *testHost) expect(b ...byte) {
+	h.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, len(b))
+	_, err := io.ReadFull(h.r, buf)
+	require.NoError(h.t, err)
+	require.Equal(h.t, b, buf)
+}
+
+// expectSB reads a VMware extension subnegotiation, returning its data
+func (h *testHost) expectSB(cmd byte) []byte {
+	h.expect(telnetIAC, telnetSB, telnetVMwareExt, cmd)
+	sb, err := readSubnegotiation(h.r)
+	require.NoError(h.t, err)
+	return sb
+}
+
+// proxy has the host proxy its serial port and returns the connection accepted for it
+func (h *testHost) proxy(c *Concentrator) net.Conn {
+	h.sb(vmwareDoProxy, append([]byte("C"), "tcp://10.0.0.1:2377"...)...)
+	h.expectSB(vmwareWillProxy)
+	h.expectSB(vmwareGetVMVCUUID)
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := c.Accept()
+		assert.NoError(h.t, err)
+		accepted <- conn
+	}()
+
+	select {
+	case conn := <-accepted:
+		require.NotNil(h.t, conn)
+		return conn
+	case <-time.After(5 * time.Second):
+		h.t.Fatal("serial port was not accepted")
+		return nil
+	}
+}
+
+func newTestConcentrator(t *testing.T) *Concentrator {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return NewConcentrator(l)
+}
+
+func TestConcentratorNegotiation(t *testing.T) {
+	c := newTestConcentrator(t)
+	defer c.Close()
+
+	h := dialHost(t, c)
+	defer h.conn.Close()
+
+	// only the host speaks the VMware extension, and unknown options are refused
+	h.send(telnetIAC, telnetDO, telnetVMwareExt)
+	h.expect(telnetIAC, telnetWONT, telnetVMwareExt)
+	h.send(telnetIAC, telnetWILL, 24)
+	h.expect(telnetIAC, telnetDONT, 24)
+
+	h.sb(vmwareKnownSuboptions1, vmwareKnownSuboptions1, vmwareDoProxy)
+	assert.Equal(t, vmwareSuboptions, h.expectSB(vmwareKnownSuboptions2))
+
+	h.sb(99)
+	assert.Equal(t, []byte{99}, h.expectSB(vmwareUnknownSuboptionRcvd2))
+}
+
+func TestConcentratorProxy(t *testing.T) {
+	c := newTestConcentrator(t)
+	defer c.Close()
+
+	h := dialHost(t, c)
+	conn := h.proxy(c)
+	defer conn.Close()
+
+	h.sb(vmwareVMVCUUID, []byte("50 1a 2b 3c")...)
+
+	// IAC is escaped in both directions
+	h.send('a', telnetIAC, telnetIAC, 'b')
+	buf := make([]byte, 3)
+	_, err := io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{'a', telnetIAC, 'b'}, buf)
+
+	_, err = conn.Write([]byte{1, telnetIAC, 2})
+	require.NoError(t, err)
+	h.expect(1, telnetIAC, telnetIAC, 2)
+
+	// reads honour their deadline as the attach connector expects
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err = conn.Read(buf)
+	if assert.Error(t, err) {
+		ne, ok := err.(net.Error)
+		assert.True(t, ok && ne.Timeout())
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	// the serial port closes with the host connection
+	h.conn.Close()
+	_, err = conn.Read(buf)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestConcentratorVMotion(t *testing.T) {
+	c := newTestConcentrator(t)
+	defer c.Close()
+
+	src := dialHost(t, c)
+	conn := src.proxy(c)
+	defer conn.Close()
+
+	src.sb(vmwareVMotionBegin, 7, telnetIAC)
+	cookie := src.expectSB(vmwareVMotionGoahead)
+	require.Len(t, cookie, 6)
+	assert.Equal(t, []byte{7, telnetIAC}, cookie[:2])
+
+	// a destination with the wrong cookie is refused
+	bad := dialHost(t, c)
+	bad.sb(vmwareVMotionPeer, 7, telnetIAC, 0, 0, 0, 0)
+	bad.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := bad.r.ReadByte()
+	assert.Equal(t, io.EOF, err)
+	bad.conn.Close()
+
+	dst := dialHost(t, c)
+	defer dst.conn.Close()
+	dst.sb(vmwareVMotionPeer, cookie...)
+	assert.Equal(t, cookie, dst.expectSB(vmwareVMotionPeerOK))
+
+	src.sb(vmwareVMotionComplete, 7, telnetIAC)
+	src.conn.Close()
+
+	// the serial port carries on through the destination host
+	dst.send('x')
+	buf := make([]byte, 1)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{'x'}, buf)
+
+	_, err = conn.Write([]byte{'y'})
+	require.NoError(t, err)
+	dst.expect('y')
+}
+
+func TestConcentratorVMotionAbort(t *testing.T) {
+	c := newTestConcentrator(t)
+	defer c.Close()
+
+	src := dialHost(t, c)
+	defer src.conn.Close()
+	conn := src.proxy(c)
+	defer conn.Close()
+
+	src.sb(vmwareVMotionBegin, 1)
+	cookie := src.expectSB(vmwareVMotionGoahead)
+
+	dst := dialHost(t, c)
+	dst.sb(vmwareVMotionPeer, cookie...)
+	dst.expectSB(vmwareVMotionPeerOK)
+
+	// the source keeps the serial port, even once the destination drops out
+	src.sb(vmwareVMotionAbort, 1)
+	src.sb(99)
+	src.expectSB(vmwareUnknownSuboptionRcvd2)
+	dst.conn.Close()
+
+	_, err := conn.Write([]byte{'w'})
+	require.NoError(t, err)
+	src.expect('w')
+
+	src.send('z')
+	buf := make([]byte, 1)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{'z'}, buf)
+}
+
+func TestConcentratorClose(t *testing.T) {
+	c := newTestConcentrator(t)
+
+	h := dialHost(t, c)
+	defer h.conn.Close()
+
+	assert.NoError(t, c.Close())
+
+	_, err := c.Accept()
+	assert.Equal(t, errConcentratorClosed, err)
+
+	// the host connection is closed rather than timing out
+	h.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = h.r.ReadByte()
+	if assert.Error(t, err) {
+		ne, ok := err.(net.Error)
+		assert.False(t, ok && ne.Timeout())
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/vmware/vic/cmd/tether/msgs"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/serial"
 	"github.com/vmware/vic/pkg/trace"
@@ -37,26 +38,40 @@ import (
 type Connection struct {
 	spty SessionInteraction
 
+	// the ssh client of the tether, and the version it reported
+	client  *ssh.Client
+	version *msgs.VersionMsg
+
 	// the container's ID
 	id string
 }
 
+// ConnectHandler is notified of each container session established by a tether, along with
+// the version that the tether reported
+type ConnectHandler func(id string, version *msgs.VersionMsg)
+
 type Connector struct {
 	mutex       sync.RWMutex
 	cond        *sync.Cond
 	connections map[string]*Connection
 
 	listener net.Listener
+	// listeners are the further listeners served, closed by Stop
+	listeners []net.Listener
 	// Quit channel for listener routine
 	listenerQuit chan bool
 	wg           sync.WaitGroup
 
 	// enable extra debug on the line
 	debug bool
+
+	// notified of new connections, may be nil
+	onConnect ConnectHandler
 }
 
 // On connect from a client (over TCP), attempt to SSH (over the same sock) to the client.
-func NewConnector(listener net.Listener, debug bool) *Connector {
+// onConnect, if not nil, is called for each container session established.
+func NewConnector(listener net.Listener, debug bool, onConnect ConnectHandler) *Connector {
 	defer trace.End(trace.Begin(""))
 
 	connector := &Connector{
@@ -64,21 +79,62 @@ func NewConnector(listener net.Listener, debug bool) *Connector {
 		listener:     listener,
 		listenerQuit: make(chan bool),
 		debug:        debug,
+		onConnect:    onConnect,
 	}
 	connector.cond = sync.NewCond(connector.mutex.RLocker())
 
 	connector.wg.Add(1)
-	go connector.serve()
+	go connector.serve(listener)
 
 	return connector
 }
 
+// Serve has the connector also take client connections from l, such as the serial ports proxied
+// by a Concentrator. l is closed by Stop.
+func (c *Connector) Serve(l net.Listener) {
+	defer trace.End(trace.Begin(l.Addr().String()))
+
+	c.mutex.Lock()
+	c.listeners = append(c.listeners, l)
+	c.mutex.Unlock()
+
+	c.wg.Add(1)
+	go c.serve(l)
+}
+
 // Returns a connection corresponding to the specified ID. If the connection doesn't exist
 // the method will wait for the specified timeout, returning when the connection is created
 // or the timeout expires, whichever occurs first
 func (c *Connector) Get(ctx context.Context, id string, timeout time.Duration) (SessionInteraction, error) {
 	defer trace.End(trace.Begin(id))
 
+	conn, err := c.connection(ctx, id, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.spty, nil
+}
+
+// Archive returns the archive interface of the containerVM hosting the specified ID, waiting
+// for the connection as Get does
+func (c *Connector) Archive(ctx context.Context, id string, timeout time.Duration) (ArchiveInteraction, error) {
+	defer trace.End(trace.Begin(id))
+
+	conn, err := c.connection(ctx, id, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if conn.version == nil || conn.version.Protocol < ArchiveProtocolVersion {
+		return nil, ErrArchiveUnsupported
+	}
+
+	return SSHArchive(conn.client), nil
+}
+
+func (c *Connector) connection(ctx context.Context, id string, timeout time.Duration) (*Connection, error) {
+
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -86,7 +142,7 @@ func (c *Connector) Get(ctx context.Context, id string, timeout time.Duration) (
 	conn := c.connections[id]
 	c.mutex.RUnlock()
 	if conn != nil {
-		return conn.spty, nil
+		return conn, nil
 	} else if timeout == 0 {
 		return nil, fmt.Errorf("no such connection")
 	}
@@ -117,7 +173,7 @@ func (c *Connector) Get(ctx context.Context, id string, timeout time.Duration) (
 	select {
 	case client := <-result:
 		log.Debugf("attach connector: Found connection for %s: %p", id, client)
-		return client.spty, nil
+		return client, nil
 	case <-ctx.Done():
 		err := fmt.Errorf("attach connector: Connection not found error for id:%s: %s", id, ctx.Err())
 		log.Error(err)
@@ -219,6 +275,14 @@ func (c *Connector) processIncoming(conn net.Conn) {
 		return
 	}
 
+	var version *msgs.VersionMsg
+	version, err = SSHVersion(client)
+	if err != nil {
+		log.Errorf("SSH connection could not be established: %s", errors.ErrorStack(err))
+		return
+	}
+	log.Debugf("Tether reported version %s (protocol %d)", version.Build().ShortVersion(), version.Protocol)
+
 	var si SessionInteraction
 	for _, id := range ids {
 		si, err = SSHAttach(client, id)
@@ -231,14 +295,20 @@ func (c *Connector) processIncoming(conn net.Conn) {
 
 		c.mutex.Lock()
 		connection := &Connection{
-			spty: si,
-			id:   id,
+			spty:    si,
+			client:  client,
+			version: version,
+			id:      id,
 		}
 
 		c.connections[connection.id] = connection
 
 		c.cond.Broadcast()
 		c.mutex.Unlock()
+
+		if c.onConnect != nil {
+			c.onConnect(id, version)
+		}
 	}
 
 	return
@@ -247,15 +317,15 @@ func (c *Connector) processIncoming(conn net.Conn) {
 // Starts the connector listening on the specified source
 // TODO: should have mechanism for stopping this, and probably handing off the connections to another
 // routine to insert into the map
-func (c *Connector) serve() {
+func (c *Connector) serve(listener net.Listener) {
 	defer c.wg.Done()
 	for {
-		if c.listener == nil {
+		if listener == nil {
 			log.Debugf("attach connector: listener closed")
 			break
 		}
 
-		conn, err := c.listener.Accept()
+		conn, err := listener.Accept()
 
 		select {
 		case <-c.listenerQuit:
@@ -278,6 +348,11 @@ func (c *Connector) Stop() {
 	defer trace.End(trace.Begin(""))
 
 	c.listener.Close()
+	c.mutex.RLock()
+	for _, l := range c.listeners {
+		l.Close()
+	}
+	c.mutex.RUnlock()
 	close(c.listenerQuit)
 	c.wg.Wait()
 }
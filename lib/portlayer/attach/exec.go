@@ -0,0 +1,139 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attach
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/cmd/tether/msgs"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// ExecProtocolVersion is the first tether protocol revision that launches exec sessions
+const ExecProtocolVersion uint32 = 3
+
+// ErrExecUnsupported is returned for containers whose tether predates exec support
+var ErrExecUnsupported = errors.New("the container's tether does not support exec")
+
+// SSHExec asks the tether to launch the session described by msg.
+// The ssh client is assumed to be connected to a tether supporting ExecProtocolVersion.
+func SSHExec(client *ssh.Client, msg *msgs.ExecMsg) error {
+	defer trace.End(trace.Begin(msg.ID))
+
+	ok, reply, err := client.SendRequest(msgs.ExecReq, true, msg.Marshal())
+	if err != nil {
+		return fmt.Errorf("failed to exec %s on remote: %s", msg.ID, err)
+	}
+
+	if !ok {
+		return fmt.Errorf("failed to exec %s on remote: %s", msg.ID, string(reply))
+	}
+
+	return nil
+}
+
+// SSHExecStatus returns the state of the session launched by SSHExec with the given id
+func SSHExecStatus(client *ssh.Client, id string) (*msgs.ExecStatusMsg, error) {
+	defer trace.End(trace.Begin(id))
+
+	ok, reply, err := client.SendRequest(msgs.ExecStatusReq, true, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of %s from remote: %s", id, err)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("failed to get status of %s from remote: %s", id, string(reply))
+	}
+
+	msg := &msgs.ExecStatusMsg{}
+	if err = msg.Unmarshal(reply); err != nil {
+		log.Debugf("raw exec status response: %+v", reply)
+		return nil, fmt.Errorf("failed to unmarshal exec status from remote: %s", err)
+	}
+
+	return msg, nil
+}
+
+// execConnection returns the connection of the containerVM hosting the specified ID if its tether
+// supports exec, waiting for the connection as Get does
+func (c *Connector) execConnection(ctx context.Context, id string, timeout time.Duration) (*Connection, error) {
+	conn, err := c.connection(ctx, id, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if conn.version == nil || conn.version.Protocol < ExecProtocolVersion {
+		return nil, ErrExecUnsupported
+	}
+
+	return conn, nil
+}
+
+// Exec launches the session described by msg in the containerVM hosting the specified ID.
+// Sessions that allow attach can then be retrieved with Get using the ID of the session, and
+// are not launched until they are.
+func (c *Connector) Exec(ctx context.Context, id string, msg *msgs.ExecMsg, timeout time.Duration) error {
+	defer trace.End(trace.Begin(msg.ID))
+
+	conn, err := c.execConnection(ctx, id, timeout)
+	if err != nil {
+		return err
+	}
+
+	if err = SSHExec(conn.client, msg); err != nil {
+		return err
+	}
+
+	if !msg.Attach {
+		return nil
+	}
+
+	si, err := SSHAttach(conn.client, msg.ID)
+	if err != nil {
+		return fmt.Errorf("failed to attach to %s: %s", msg.ID, err)
+	}
+
+	log.Infof("Established connection with exec session %s in %s", msg.ID, id)
+
+	c.mutex.Lock()
+	c.connections[msg.ID] = &Connection{
+		spty:    si,
+		client:  conn.client,
+		version: conn.version,
+		id:      msg.ID,
+	}
+	c.cond.Broadcast()
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// ExecStatus returns the state of the session launched by Exec in the containerVM hosting the specified ID
+func (c *Connector) ExecStatus(ctx context.Context, id, execID string) (*msgs.ExecStatusMsg, error) {
+	defer trace.End(trace.Begin(execID))
+
+	conn, err := c.execConnection(ctx, id, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return SSHExecStatus(conn.client, execID)
+}
@@ -0,0 +1,141 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attach
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// ReplaySize is how much of the most recent output of each container stream is kept, so that it can be
+// replayed to a client that reattaches, e.g. after a network interruption
+const ReplaySize = 64 * 1024
+
+// replayBuffer is a ring buffer holding the most recent output of a stream
+type replayBuffer struct {
+	mu sync.Mutex
+
+	data []byte
+	// next is where the next write starts
+	next int
+	full bool
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{data: make([]byte, size)}
+}
+
+// Write records p, discarding the oldest output once the buffer is full. It never fails.
+func (b *replayBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	size := len(b.data)
+	n := len(p)
+	if n >= size {
+		copy(b.data, p[n-size:])
+		b.next = 0
+		b.full = true
+		return n, nil
+	}
+
+	c := copy(b.data[b.next:], p)
+	copy(b.data, p[c:])
+	if b.next+n >= size {
+		b.full = true
+	}
+	b.next = (b.next + n) % size
+
+	return n, nil
+}
+
+// Last returns up to n bytes of the most recent output
+func (b *replayBuffer) Last(n int) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	size := len(b.data)
+	available := b.next
+	if b.full {
+		available = size
+	}
+	if n > available {
+		n = available
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	out := make([]byte, n)
+	start := (b.next - n + size) % size
+	c := copy(out, b.data[start:])
+	copy(out[c:], b.data[:n-c])
+
+	return out
+}
+
+// replays holds the replay buffers of container streams, by container ID and stream name
+type replays struct {
+	mu      sync.Mutex
+	buffers map[string]map[string]*replayBuffer
+}
+
+func (r *replays) buffer(id, stream string) *replayBuffer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.buffers == nil {
+		r.buffers = make(map[string]map[string]*replayBuffer)
+	}
+	streams, ok := r.buffers[id]
+	if !ok {
+		streams = make(map[string]*replayBuffer)
+		r.buffers[id] = streams
+	}
+	b, ok := streams[stream]
+	if !ok {
+		b = newReplayBuffer(ReplaySize)
+		streams[stream] = b
+	}
+
+	return b
+}
+
+func (r *replays) forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.buffers, id)
+}
+
+// Output returns a reader for the named stream of a container, that reads up to replay bytes of the
+// output recorded by earlier reads before the output from r. What is read from r is recorded for replay
+// to later readers.
+func (n *Server) Output(id, stream string, r io.Reader, replay int) io.Reader {
+	b := n.replays.buffer(id, stream)
+
+	live := io.TeeReader(r, b)
+	if replay <= 0 {
+		return live
+	}
+
+	return io.MultiReader(bytes.NewReader(b.Last(replay)), live)
+}
+
+// Forget discards the output recorded for a container
+func (n *Server) Forget(id string) {
+	n.replays.forget(id)
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attach
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayBuffer(t *testing.T) {
+	b := newReplayBuffer(8)
+	assert.Empty(t, b.Last(4))
+
+	b.Write([]byte("abc"))
+	assert.Equal(t, "abc", string(b.Last(8)))
+	assert.Equal(t, "bc", string(b.Last(2)))
+
+	// wraps around, discarding the oldest output
+	b.Write([]byte("defghij"))
+	assert.Equal(t, "cdefghij", string(b.Last(8)))
+	assert.Equal(t, "hij", string(b.Last(3)))
+
+	b.Write([]byte("k"))
+	assert.Equal(t, "defghijk", string(b.Last(100)))
+
+	// a write larger than the buffer keeps its end
+	b.Write([]byte("0123456789"))
+	assert.Equal(t, "23456789", string(b.Last(8)))
+	assert.Empty(t, b.Last(0))
+}
+
+func TestServerOutput(t *testing.T) {
+	s := NewAttachServer("", -1)
+
+	out, err := ioutil.ReadAll(s.Output("id", "stdout", bytes.NewBufferString("first attach\n"), 1024))
+	assert.NoError(t, err)
+	assert.Equal(t, "first attach\n", string(out))
+
+	// a reattach is sent the recorded output first
+	out, err = ioutil.ReadAll(s.Output("id", "stdout", bytes.NewBufferString("second\n"), 6))
+	assert.NoError(t, err)
+	assert.Equal(t, "ttach\nsecond\n", string(out))
+
+	// without replay, only live output is sent
+	out, err = ioutil.ReadAll(s.Output("id", "stdout", bytes.NewBufferString("third\n"), 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "third\n", string(out))
+
+	// streams and containers are recorded separately
+	out, err = ioutil.ReadAll(s.Output("id", "stderr", bytes.NewBufferString(""), 1024))
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+
+	s.Forget("id")
+	out, err = ioutil.ReadAll(s.Output("id", "stdout", bytes.NewBufferString(""), 1024))
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+}
@@ -23,6 +23,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/vmware/vic/cmd/tether/msgs"
 	"github.com/vmware/vic/lib/portlayer/constants"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/trace"
@@ -36,7 +37,15 @@ type Server struct {
 	ip   string
 	l    *net.TCPListener
 
+	// vspcPort is the port of the serial port concentrator, zero if it is not enabled
+	vspcPort int
+	vspc     *Concentrator
+
 	connServer *Connector
+	onConnect  ConnectHandler
+
+	// replays holds the recent output of container streams
+	replays replays
 }
 
 func NewAttachServer(ip string, port int) *Server {
@@ -49,6 +58,18 @@ func NewAttachServer(ip string, port int) *Server {
 	return &Server{ip: ip, port: port}
 }
 
+// SetConnectHandler registers h to be notified of container sessions as they're established.
+// It must be called before Start.
+func (n *Server) SetConnectHandler(h ConnectHandler) {
+	n.onConnect = h
+}
+
+// EnableConcentrator has the server also act as the virtual serial port concentrator of containerVMs,
+// listening on the given port. It must be called before Start.
+func (n *Server) EnableConcentrator(port int) {
+	n.vspcPort = port
+}
+
 // Start starts the TCP listener.
 func (n *Server) Start(debug bool) error {
 	defer trace.End(trace.Begin(""))
@@ -65,7 +86,24 @@ func (n *Server) Start(debug bool) error {
 	}
 
 	// starts serving requests immediately
-	n.connServer = NewConnector(n.l, debug)
+	n.connServer = NewConnector(n.l, debug, n.onConnect)
+
+	if n.vspcPort == 0 {
+		return nil
+	}
+
+	log.Infof("Serial port concentrator listening on %s:%d", n.ip, n.vspcPort)
+
+	l, err := net.Listen("tcp", fmt.Sprintf("%s:%d", n.ip, n.vspcPort))
+	if err != nil {
+		err = fmt.Errorf("Serial port concentrator error %s:%d: %s", n.ip, n.vspcPort, errors.ErrorStack(err))
+		log.Errorf("%s", err)
+		n.connServer.Stop()
+		return err
+	}
+
+	n.vspc = NewConcentrator(l)
+	n.connServer.Serve(n.vspc)
 
 	return nil
 }
@@ -93,6 +131,36 @@ func (n *Server) Get(ctx context.Context, id string, timeout time.Duration) (Ses
 	return n.connServer.Get(ctx, id, timeout)
 }
 
+// Archive returns the archive interface for the filesystem of the given running container,
+// waiting for the given timeout as Get does.
+func (n *Server) Archive(ctx context.Context, id string, timeout time.Duration) (ArchiveInteraction, error) {
+	defer trace.End(trace.Begin(id))
+
+	return n.connServer.Archive(ctx, id, timeout)
+}
+
+// Exec launches the session described by msg in the given running container, waiting for the
+// given timeout as Get does.
+func (n *Server) Exec(ctx context.Context, id string, msg *msgs.ExecMsg, timeout time.Duration) error {
+	defer trace.End(trace.Begin(id))
+
+	return n.connServer.Exec(ctx, id, msg, timeout)
+}
+
+// ExecStatus returns the state of the session launched by Exec in the given running container
+func (n *Server) ExecStatus(ctx context.Context, id, execID string) (*msgs.ExecStatusMsg, error) {
+	defer trace.End(trace.Begin(id))
+
+	return n.connServer.ExecStatus(ctx, id, execID)
+}
+
+// Top returns the processes running in the given running container
+func (n *Server) Top(ctx context.Context, id string) ([]msgs.ProcessMsg, error) {
+	defer trace.End(trace.Begin(id))
+
+	return n.connServer.Top(ctx, id)
+}
+
 func (n *Server) Remove(id string) error {
 	defer trace.End(trace.Begin(id))
 
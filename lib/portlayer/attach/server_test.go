@@ -139,6 +139,10 @@ func TestAttachSshSession(t *testing.T) {
 			if req.Type == msgs.ContainersReq {
 				msg := msgs.ContainersMsg{IDs: []string{expectedID}}
 				req.Reply(true, msg.Marshal())
+			}
+			// the version is requested after the container IDs
+			if req.Type == msgs.VersionReq {
+				req.Reply(true, msgs.NewVersionMsg().Marshal())
 				break
 			}
 		}
@@ -0,0 +1,72 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attach
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/cmd/tether/msgs"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// TopProtocolVersion is the first tether protocol revision that lists processes
+const TopProtocolVersion uint32 = 4
+
+// ErrTopUnsupported is returned for containers whose tether predates process listing
+var ErrTopUnsupported = errors.New("the container's tether does not support listing processes")
+
+// SSHTop returns the processes running in the containerVM.
+// The ssh client is assumed to be connected to a tether supporting TopProtocolVersion.
+func SSHTop(client *ssh.Client) ([]msgs.ProcessMsg, error) {
+	defer trace.End(trace.Begin(""))
+
+	ok, reply, err := client.SendRequest(msgs.TopReq, true, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes on remote: %s", err)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("failed to list processes on remote: %s", string(reply))
+	}
+
+	msg := &msgs.TopMsg{}
+	if err = msg.Unmarshal(reply); err != nil {
+		log.Debugf("raw top response: %+v", reply)
+		return nil, fmt.Errorf("failed to unmarshal processes from remote: %s", err)
+	}
+
+	return msg.Processes, nil
+}
+
+// Top returns the processes running in the containerVM hosting the specified ID
+func (c *Connector) Top(ctx context.Context, id string) ([]msgs.ProcessMsg, error) {
+	defer trace.End(trace.Begin(id))
+
+	conn, err := c.connection(ctx, id, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if conn.version == nil || conn.version.Protocol < TopProtocolVersion {
+		return nil, ErrTopUnsupported
+	}
+
+	return SSHTop(conn.client)
+}
@@ -0,0 +1,174 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portlayer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/portlayer/event/events"
+	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/scheduler"
+	"github.com/vmware/vic/pkg/certificate"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig/vmomi"
+	"github.com/vmware/vic/pkg/vsphere/guest"
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+// certificateConfig is the slice of the VCH config holding the certificates monitored by the port layer
+type certificateConfig struct {
+	config.Certificate `vic:"0.1" scope:"read-only" key:"cert"`
+
+	ExtensionCert string `vic:"0.1" scope:"read-only" key:"connect/extension_cert"`
+	ExtensionKey  string `vic:"0.1" scope:"read-only" key:"connect/extension_key"`
+}
+
+// hostCertificateConfig is the slice of the VCH config updated when the host certificate is renewed
+type hostCertificateConfig struct {
+	HostCertificate *config.RawCertificate `vic:"0.1" scope:"read-only" key:"cert/HostCertificate"`
+}
+
+// monitorCertificates registers a maintenance job checking the host certificate and the vSphere extension
+// certificate for expiry, publishing a CertificateEvent whenever one reaches a warning threshold, expires
+// or is renewed. A self-signed host certificate is renewed here, if enabled, and written back to the
+// appliance configuration so that the personality serving it, and any later boot, picks it up.
+func monitorCertificates(ctx context.Context, sess *session.Session, source extraconfig.DataSource) {
+	var conf certificateConfig
+	extraconfig.Decode(source, &conf)
+
+	var monitors []*certificate.ExpiryMonitor
+
+	add := func(name string, cert, key []byte) *certificate.ExpiryMonitor {
+		em, err := certificate.NewExpiryMonitor(name, certificate.NewKeyPair("", "", cert, key))
+		if err != nil {
+			log.Warnf("Unable to monitor %s certificate for expiry: %s", name, err)
+			return nil
+		}
+
+		if len(conf.CertificateExpiryThresholds) > 0 {
+			em.Thresholds = conf.CertificateExpiryThresholds
+		}
+		monitors = append(monitors, em)
+		return em
+	}
+
+	if !conf.HostCertificate.IsNil() {
+		if em := add("host", conf.HostCertificate.Cert, conf.HostCertificate.Key); em != nil && sess != nil {
+			if conf.CertificateRenewalOverlap > 0 {
+				em.RenewalOverlap = conf.CertificateRenewalOverlap
+			}
+			em.AutoRenew = conf.CertificateAutoRenew
+			em.Persist = func(kp *certificate.KeyPair) error {
+				return persistHostCertificate(ctx, sess, kp)
+			}
+		}
+	}
+
+	if conf.ExtensionCert != "" {
+		add("vSphere extension", []byte(conf.ExtensionCert), []byte(conf.ExtensionKey))
+	}
+
+	if len(monitors) == 0 {
+		return
+	}
+
+	for _, em := range monitors {
+		em.Notify = publishCertificateEvent
+	}
+
+	err := Maintenance.Register(scheduler.Job{
+		Name:     config.CertificateCheckJob,
+		Interval: certificate.DefaultExpiryCheckInterval,
+		Run: func(ctx context.Context) error {
+			now := time.Now()
+			var expired []string
+			for _, em := range monitors {
+				if s := em.Check(now); s.State == certificate.CertificateExpired {
+					expired = append(expired, em.Name)
+				}
+			}
+
+			if len(expired) > 0 {
+				return fmt.Errorf("expired certificates: %s", strings.Join(expired, ", "))
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		log.Errorf("Unable to register maintenance job: %s", err)
+	}
+}
+
+// persistHostCertificate writes a renewed host certificate to the extraconfig of the appliance VM
+func persistHostCertificate(ctx context.Context, sess *session.Session, kp *certificate.KeyPair) error {
+	self, err := guest.GetSelf(ctx, sess)
+	if err != nil {
+		return err
+	}
+
+	cfg := make(map[string]string)
+	extraconfig.Encode(extraconfig.MapSink(cfg), hostCertificateConfig{
+		HostCertificate: &config.RawCertificate{
+			Cert: kp.CertPEM,
+			Key:  kp.KeyPEM,
+		},
+	})
+
+	spec := types.VirtualMachineConfigSpec{
+		ExtraConfig: vmomi.OptionValueFromMap(cfg),
+	}
+
+	task, err := self.Reconfigure(ctx, spec)
+	if err != nil {
+		return err
+	}
+
+	return task.Wait(ctx)
+}
+
+func publishCertificateEvent(status certificate.ExpiryStatus) {
+	if exec.Config.EventManager == nil {
+		return
+	}
+
+	var event string
+	switch status.State {
+	case certificate.CertificateExpiring:
+		event = events.CertificateExpiring
+	case certificate.CertificateExpired:
+		event = events.CertificateExpired
+	case certificate.CertificateRenewed:
+		event = events.CertificateRenewed
+	default:
+		return
+	}
+
+	exec.Config.EventManager.Publish(&events.CertificateEvent{
+		BaseEvent: &events.BaseEvent{
+			Ref:         status.Name,
+			CreatedTime: time.Now(),
+			Event:       event,
+			Detail:      status.String(),
+		},
+	})
+}
@@ -15,8 +15,10 @@
 package constants
 
 const (
-	SerialOverLANPort  = 2377
-	ManagementHostName = "management.localhost"
+	SerialOverLANPort = 2377
+	// SerialConcentratorPort is where the appliance serves as the serial port concentrator of containerVMs
+	SerialConcentratorPort = 2379
+	ManagementHostName     = "management.localhost"
 	// BridgeScopeType denotes a scope that is of type bridge
 	BridgeScopeType = "bridge"
 	// ExternalScopeType denotes a scope that is of type external
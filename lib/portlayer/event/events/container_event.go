@@ -26,6 +26,10 @@ const (
 	ContainerStarted      = "Started"
 	ContainerStopped      = "Stopped"
 	ContainerRegistered   = "Registered"
+
+	// ContainerTetherOutdated is published when a container's tether predates the appliance,
+	// meaning the container was booted from an outdated bootstrap image and should be restarted
+	ContainerTetherOutdated = "TetherOutdated"
 )
 
 type ContainerEvent struct {
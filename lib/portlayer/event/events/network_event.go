@@ -0,0 +1,32 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+const (
+	NetworkRemoved = "Removed"
+)
+
+// NetworkEvent is published when a scope is removed, whether deleted or pruned.
+// The reference is the name of the scope.
+type NetworkEvent struct {
+	*BaseEvent
+}
+
+func (ne *NetworkEvent) Topic() string {
+	if ne.Type == "" {
+		ne.Type = NewEventType(ne)
+	}
+	return ne.Type.Topic()
+}
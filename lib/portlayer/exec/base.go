@@ -59,6 +59,9 @@ type containerBase struct {
 
 	// doesn't change so can be copied here
 	vm *vm.VirtualMachine
+
+	// whether this is an existing VM listed as an external container
+	external bool
 }
 
 func newBase(vm *vm.VirtualMachine, c *types.VirtualMachineConfigInfo, r *types.VirtualMachineRuntimeInfo) *containerBase {
@@ -108,6 +111,10 @@ func (c *containerBase) updates(ctx context.Context) (*containerBase, error) {
 		return nil, err
 	}
 
+	if c.external {
+		return newExternalBase(c.vm, o.Config, &o.Runtime), nil
+	}
+
 	base := &containerBase{
 		vm:         c.vm,
 		Config:     o.Config,
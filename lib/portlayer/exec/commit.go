@@ -17,11 +17,14 @@ package exec
 import (
 	"context"
 	"fmt"
+	"path"
 	"time"
 
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/portlayer/event/events"
+	"github.com/vmware/vic/lib/portlayer/store"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/session"
 	"github.com/vmware/vic/pkg/vsphere/tasks"
@@ -55,8 +58,20 @@ func Commit(ctx context.Context, sess *session.Session, h *Handle, waitTime *int
 			return fmt.Errorf("a container already exists in the cache with this ID")
 		}
 
+		// the name is held until the container is removed
+		if err := reserveName(ctx, h.ExecConfig); err != nil {
+			return err
+		}
+
 		var res *types.TaskInfo
 		var err error
+		defer func() {
+			// a create that fails leaves the name free for another attempt
+			if h.vm == nil {
+				releaseName(ctx, h.ExecConfig)
+			}
+		}()
+
 		if sess.IsVC() && Config.VirtualApp.ResourcePool != nil {
 			// Create the vm
 			res, err = tasks.WaitForResult(ctx, func(ctx context.Context) (tasks.Task, error) {
@@ -71,6 +86,13 @@ func Commit(ctx context.Context, sess *session.Session, h *Handle, waitTime *int
 				return err
 			}
 			parent := folders.VmFolder
+			if Config.VMFolder != "" {
+				// containerVMs are kept in the same folder as the appliance
+				if parent, err = sess.Finder.Folder(ctx, path.Join(folders.VmFolder.InventoryPath, Config.VMFolder)); err != nil {
+					log.Errorf("Could not find VM folder %q", Config.VMFolder)
+					return err
+				}
+			}
 
 			// Create the vm
 			res, err = tasks.WaitForResult(ctx, func(ctx context.Context) (tasks.Task, error) {
@@ -89,6 +111,14 @@ func Commit(ctx context.Context, sess *session.Session, h *Handle, waitTime *int
 		// inform of creation irrespective of remaining operations
 		publishContainerEvent(c.ExecConfig.ID, time.Now().UTC(), events.ContainerCreated)
 
+		if err = applyRules(ctx, sess, c); err != nil {
+			// a containerVM that must stay on its host group cannot be left to run elsewhere
+			if Config.ContainerHostGroupMandatory {
+				return fmt.Errorf("unable to apply DRS rules to %s: %s", h.ExecConfig.ID, err)
+			}
+			log.Warnf("Unable to apply DRS rules to %s: %s", h.ExecConfig.ID, err)
+		}
+
 		// clear the spec as we've acted on it - this prevents a reconfigure from occurring in follow-on
 		// processing
 		h.Spec = nil
@@ -208,3 +238,22 @@ func Commit(ctx context.Context, sess *session.Session, h *Handle, waitTime *int
 
 	return nil
 }
+
+// reserveName reserves the name of a container being created, so that of concurrent creates with the
+// same name only one succeeds
+func reserveName(ctx context.Context, conf *executor.ExecutorConfig) error {
+	if conf.Name == "" {
+		return nil
+	}
+	return store.Names().Reserve(ctx, store.ContainerName, conf.Name, conf.ID)
+}
+
+// releaseName releases the name of a container that is removed or that failed to be created
+func releaseName(ctx context.Context, conf *executor.ExecutorConfig) {
+	if conf.Name == "" {
+		return
+	}
+	if err := store.Names().Release(ctx, store.ContainerName, conf.Name, conf.ID); err != nil {
+		log.Warnf("Unable to release name %q of container %s: %s", conf.Name, conf.ID, err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/portlayer/event"
+	"github.com/vmware/vic/pkg/trace"
 )
 
 var Config Configuration
@@ -40,6 +41,10 @@ type Configuration struct {
 	// For now throw the Event Manager here
 	EventManager event.EventManager
 
+	// VolumeDestroyer destroys a volume by ID. It is set by the storage layer so that the anonymous
+	// volumes of auto-removed containers can be cleaned up along with them.
+	VolumeDestroyer func(op trace.Operation, ID string) error
+
 	// Information about the VCH resource pool and about the real host that we want
 	// tol retrieve just once.
 	VCHMhz          int64
@@ -50,4 +55,7 @@ type Configuration struct {
 
 	// Datastore URLs for image stores - the top layer is [0], the bottom layer is [len-1]
 	ImageStores []url.URL `vic:"0.1" scope:"read-only" key:"storage/image_stores"`
+
+	// The image store chosen from ImageStores to hold container image layers
+	ImageStore *url.URL
 }
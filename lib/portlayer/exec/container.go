@@ -24,11 +24,14 @@ import (
 
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/mo"
-	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/portlayer/event/events"
+	"github.com/vmware/vic/lib/portlayer/metrics"
 	"github.com/vmware/vic/pkg/errors"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/uid"
+	"github.com/vmware/vic/pkg/version"
 	"github.com/vmware/vic/pkg/vsphere/session"
 	"github.com/vmware/vic/pkg/vsphere/sys"
 	"github.com/vmware/vic/pkg/vsphere/tasks"
@@ -117,6 +120,9 @@ type ContainerInfo struct {
 
 	// Size of the leaf (unused)
 	VMUnsharedDisk int64
+
+	// TetherVersion is the version reported by the tether when it last connected, nil if it has not
+	TetherVersion *version.Build
 }
 
 // Container is used for an entry in the container cache - this is a "live" representation
@@ -361,6 +367,10 @@ func (c *Container) Signal(ctx context.Context, num int64) error {
 		return fmt.Errorf("vm not set")
 	}
 
+	if c.external {
+		return ExternalError{c.ExecConfig.ID}
+	}
+
 	if num == int64(syscall.SIGKILL) {
 		return c.containerBase.kill(ctx)
 	}
@@ -378,6 +388,41 @@ func (c *Container) onStop() {
 	}
 }
 
+// persistExitState writes the exit status, stop time and OOM state of the sessions of a powered off
+// containerVM, as reported by the tether, to the VM configuration. Values set by the guest are only
+// kept until the VM is reloaded, which would leave inspect and wait with no record of how it exited.
+func (c *Container) persistExitState(ctx context.Context) error {
+	defer trace.End(trace.Begin(c.ExecConfig.ID))
+
+	h := c.NewHandle(ctx)
+	if h == nil {
+		return fmt.Errorf("unable to get a handle for %s", c.ExecConfig.ID)
+	}
+	defer removeHandle(h.key)
+
+	if h.external || h.Runtime == nil || h.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOff {
+		return nil
+	}
+
+	if !exited(h.ExecConfig) {
+		log.Debugf("No session of container %s has exited, nothing to persist", c.ExecConfig.ID)
+		return nil
+	}
+
+	// committing the handle with no target state rewrites the ExtraConfig as read from the VM
+	return h.Commit(ctx, nil, nil)
+}
+
+// exited reports whether any session of the container has run and reported an exit
+func exited(config *executor.ExecutorConfig) bool {
+	for _, s := range config.Sessions {
+		if s.Started != "" && s.StopTime != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Container) LogReader(ctx context.Context, tail int, follow bool) (io.ReadCloser, error) {
 	defer trace.End(trace.Begin(c.ExecConfig.ID))
 	c.m.Lock()
@@ -387,16 +432,7 @@ func (c *Container) LogReader(ctx context.Context, tail int, follow bool) (io.Re
 		return nil, fmt.Errorf("vm not set")
 	}
 
-	url, err := c.vm.DSPath(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	name := fmt.Sprintf("%s/%s", url.Path, containerLogName)
-
-	log.Infof("pulling %s", name)
-
-	file, err := c.vm.Datastore.Open(ctx, name)
+	file, err := c.openLog(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -419,8 +455,79 @@ func (c *Container) LogReader(ctx context.Context, tail int, follow bool) (io.Re
 	return file, nil
 }
 
-// Remove removes a containerVM after detaching the disks
-func (c *Container) Remove(ctx context.Context, sess *session.Session) error {
+// LogFollower returns a reader that follows the output of the running container from offset bytes into
+// its log, or from the current end of the log for a negative offset, along with the offset it starts at.
+// The reader returns io.EOF once the container stops.
+func (c *Container) LogFollower(ctx context.Context, offset int64) (io.ReadCloser, int64, error) {
+	defer trace.End(trace.Begin(c.ExecConfig.ID))
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.vm == nil {
+		return nil, 0, fmt.Errorf("vm not set")
+	}
+
+	if c.state != StateRunning {
+		return nil, 0, fmt.Errorf("container %s is not running", c.ExecConfig.ID)
+	}
+
+	file, err := c.openLog(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if offset < 0 {
+		offset, err = file.Seek(0, io.SeekEnd)
+	} else {
+		offset, err = file.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	follower := file.Follow(time.Second)
+	c.logFollowers = append(c.logFollowers, follower)
+
+	return follower, offset, nil
+}
+
+// openLog opens the file holding the output of the container. It must be called with the container lock held.
+func (c *Container) openLog(ctx context.Context) (*object.DatastoreFile, error) {
+	url, err := c.vm.DSPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s/%s", url.Path, containerLogName)
+
+	log.Infof("pulling %s", name)
+
+	return c.vm.Datastore.Open(ctx, name)
+}
+
+// Metrics subscribes to the performance metrics of the container VM. The returned channel is closed
+// once ctx is done.
+func (c *Container) Metrics(ctx context.Context) (<-chan *metrics.VMMetrics, error) {
+	defer trace.End(trace.Begin(c.ExecConfig.ID))
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.vm == nil {
+		return nil, fmt.Errorf("vm not set")
+	}
+
+	var memoryMB int64
+	if c.Config != nil && c.Config.Hardware.MemoryMB > 0 {
+		memoryMB = int64(c.Config.Hardware.MemoryMB)
+	}
+
+	return metrics.Supervisor.Subscribe(ctx, c.vm.Reference(), memoryMB), nil
+}
+
+// Remove removes a containerVM after detaching the disks. The anonymous volumes of the container
+// are destroyed too if volumes is set, as with docker rm -v, or if the container is auto-removed.
+func (c *Container) Remove(ctx context.Context, sess *session.Session, volumes bool) error {
 	defer trace.End(trace.Begin(c.ExecConfig.ID))
 	c.m.Lock()
 	defer c.m.Unlock()
@@ -429,6 +536,10 @@ func (c *Container) Remove(ctx context.Context, sess *session.Session) error {
 		return NotFoundError{}
 	}
 
+	if c.external {
+		return ExternalError{c.ExecConfig.ID}
+	}
+
 	// check state first
 	if c.state == StateRunning {
 		return RemovePowerError{fmt.Errorf("Container is powered on")}
@@ -443,12 +554,9 @@ func (c *Container) Remove(ctx context.Context, sess *session.Session) error {
 	if err != nil {
 
 		// handle the out-of-band removal case
-		if soap.IsSoapFault(err) {
-			fault := soap.ToSoapFault(err).VimFault()
-			if _, ok := fault.(types.ManagedObjectNotFound); ok {
-				Containers.Remove(c.ExecConfig.ID)
-				return NotFoundError{}
-			}
+		if tasks.IsFault(err, &types.ManagedObjectNotFound{}) {
+			Containers.Remove(c.ExecConfig.ID)
+			return NotFoundError{}
 		}
 
 		log.Errorf("Failed to get datastore path for %s: %s", c.ExecConfig.ID, err)
@@ -464,12 +572,10 @@ func (c *Container) Remove(ctx context.Context, sess *session.Session) error {
 		return c.vm.DeleteExceptDisks(ctx)
 	})
 	if err != nil {
-		f, ok := err.(types.HasFault)
-		if !ok {
+		switch f := tasks.Fault(err).(type) {
+		case nil:
 			c.updateState(existingState)
 			return err
-		}
-		switch f.Fault().(type) {
 		case *types.InvalidState:
 			log.Warnf("container VM is in invalid state, unregistering")
 			if err := c.vm.Unregister(ctx); err != nil {
@@ -477,7 +583,7 @@ func (c *Container) Remove(ctx context.Context, sess *session.Session) error {
 				return err
 			}
 		default:
-			log.Debugf("Fault while attempting to destroy vm: %#v", f.Fault())
+			log.Debugf("Fault while attempting to destroy vm: %#v", f)
 			c.updateState(existingState)
 			return err
 		}
@@ -495,9 +601,50 @@ func (c *Container) Remove(ctx context.Context, sess *session.Session) error {
 
 	//remove container from cache
 	Containers.Remove(c.ExecConfig.ID)
+
+	if volumes || c.ExecConfig.AutoRemove {
+		c.removeAnonymousVolumes(ctx)
+	}
 	return nil
 }
 
+// autoRemove removes a containerVM that was created to be removed once it exits, so that this does
+// not depend on the client staying connected to remove it
+func (c *Container) autoRemove(ctx context.Context, sess *session.Session) {
+	defer trace.End(trace.Begin(c.ExecConfig.ID))
+
+	if !c.ExecConfig.AutoRemove || c.CurrentState() != StateStopped {
+		return
+	}
+
+	if err := c.Remove(ctx, sess, true); err != nil {
+		if _, ok := err.(NotFoundError); !ok {
+			log.Errorf("Unable to auto-remove container %s: %s", c.ExecConfig.ID, err)
+		}
+		return
+	}
+
+	publishContainerEvent(c.ExecConfig.ID, time.Now().UTC(), events.ContainerRemoved)
+}
+
+// removeAnonymousVolumes destroys the volumes that were created for a removed container alone
+func (c *Container) removeAnonymousVolumes(ctx context.Context) {
+	if Config.VolumeDestroyer == nil {
+		return
+	}
+
+	for id, m := range c.ExecConfig.Mounts {
+		if !m.Anonymous {
+			continue
+		}
+
+		op := trace.NewOperation(ctx, "VolumeDestroy(%s)", id)
+		if err := Config.VolumeDestroyer(op, id); err != nil {
+			log.Warnf("Unable to remove anonymous volume %s of container %s: %s", id, c.ExecConfig.ID, err)
+		}
+	}
+}
+
 // get the containerVMs from infrastructure for this resource pool
 func infraContainers(ctx context.Context, sess *session.Session) ([]*Container, error) {
 	defer trace.End(trace.Begin(""))
@@ -514,7 +661,8 @@ func infraContainers(ctx context.Context, sess *session.Session) ([]*Container,
 		return nil, err
 	}
 
-	return convertInfraContainers(ctx, sess, vms), nil
+	cons := convertInfraContainers(ctx, sess, vms)
+	return append(cons, externalContainers(ctx, sess)...), nil
 }
 
 func instanceUUID(id string) (string, error) {
@@ -16,13 +16,21 @@ package exec
 
 import (
 	"sync"
+	"time"
 
+	log "github.com/Sirupsen/logrus"
 	"golang.org/x/net/context"
 
+	"github.com/vmware/vic/lib/portlayer/event/events"
+	"github.com/vmware/vic/lib/portlayer/store"
 	"github.com/vmware/vic/pkg/uid"
 	"github.com/vmware/vic/pkg/vsphere/session"
 )
 
+// nameReservationGrace is how long the name of a container being created stays reserved before its
+// containerVM exists, after which the create is taken to have been abandoned
+const nameReservationGrace = 10 * time.Minute
+
 /*
 * ContainerCache will provide an in-memory cache of containerVMs.  It will
 * be refreshed on portlayer start and updated via container lifecycle
@@ -102,6 +110,7 @@ func (conCache *containerCache) Remove(idOrRef string) {
 	if container != nil {
 		delete(conCache.cache, container.ExecConfig.ID)
 		delete(conCache.cache, container.vm.Reference().String())
+		releaseName(context.Background(), container.ExecConfig)
 	}
 }
 
@@ -122,6 +131,80 @@ func (conCache *containerCache) sync(ctx context.Context, sess *session.Session)
 	return nil
 }
 
+// reconcile brings the cache in line with the containerVMs in vSphere, adding containerVMs that are
+// missing from it and removing containers whose VM has gone, such as those removed directly in vSphere
+// while an event was missed. Containers added to the cache while the containerVMs are listed are
+// left alone.
+func (conCache *containerCache) reconcile(ctx context.Context, sess *session.Session) error {
+	conCache.m.RLock()
+	cached := make(map[string]*Container)
+	for id, con := range conCache.cache {
+		if isContainerID(id) {
+			cached[id] = con
+		}
+	}
+	conCache.m.RUnlock()
+
+	cons, err := infraContainers(ctx, sess)
+	if err != nil {
+		return err
+	}
+
+	var removed []string
+
+	conCache.m.Lock()
+	for _, c := range cons {
+		if _, ok := conCache.cache[c.ExecConfig.ID]; !ok {
+			log.Infof("Adding container %s found in vSphere to the cache", c.ExecConfig.ID)
+			conCache.put(c)
+		}
+		delete(cached, c.ExecConfig.ID)
+	}
+
+	for id, con := range cached {
+		// containers being removed leave the cache when the removal completes
+		if con.CurrentState() == StateRemoving || conCache.cache[id] != con {
+			continue
+		}
+
+		log.Infof("Removing container %s from the cache as its VM no longer exists", id)
+		delete(conCache.cache, id)
+		delete(conCache.cache, con.vm.Reference().String())
+		removed = append(removed, id)
+	}
+	conCache.m.Unlock()
+
+	for _, id := range removed {
+		publishContainerEvent(id, time.Now().UTC(), events.ContainerRemoved)
+	}
+
+	return conCache.reconcileNames(ctx, nameReservationGrace)
+}
+
+// reconcileNames reserves the names of the cached containers and releases those of containers that
+// were not created within grace
+func (conCache *containerCache) reconcileNames(ctx context.Context, grace time.Duration) error {
+	inUse := make(map[string]string)
+
+	conCache.m.RLock()
+	for id, con := range conCache.cache {
+		if isContainerID(id) && con.ExecConfig.Name != "" {
+			inUse[con.ExecConfig.Name] = id
+		}
+	}
+	conCache.m.RUnlock()
+
+	return store.Names().Reconcile(ctx, store.ContainerName, inUse, grace)
+}
+
+// Reconcile brings the container cache in line with the containerVMs in vSphere
+func Reconcile(ctx context.Context, sess *session.Session) error {
+	if Containers == nil {
+		return nil
+	}
+	return Containers.reconcile(ctx, sess)
+}
+
 func isContainerID(id string) bool {
 	return uid.Parse(id) != uid.NilUID
 }
@@ -15,9 +15,12 @@
 package exec
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/uid"
 )
 
@@ -55,3 +58,46 @@ func NewContainer(id uid.UID) *Handle {
 
 	return h
 }
+
+func TestExited(t *testing.T) {
+	config := &executor.ExecutorConfig{
+		Sessions: map[string]*executor.SessionConfig{
+			"primary": {},
+		},
+	}
+	assert.False(t, exited(config), "a session that never started has not exited")
+
+	config.Sessions["primary"].Started = "true"
+	assert.False(t, exited(config), "a running session has not exited")
+
+	config.Sessions["primary"].StopTime = 1492000000
+	config.Sessions["primary"].ExitStatus = 137
+	assert.True(t, exited(config))
+}
+
+func TestRemoveAnonymousVolumes(t *testing.T) {
+	var destroyed []string
+	Config.VolumeDestroyer = func(op trace.Operation, ID string) error {
+		destroyed = append(destroyed, ID)
+		return nil
+	}
+	defer func() { Config.VolumeDestroyer = nil }()
+
+	c := newContainer(&containerBase{
+		ExecConfig: &executor.ExecutorConfig{
+			AutoRemove: true,
+			Mounts: map[string]executor.MountSpec{
+				"anonymous": {Path: "/data", Anonymous: true},
+				"named":     {Path: "/shared"},
+			},
+		},
+	})
+
+	c.removeAnonymousVolumes(context.Background())
+	assert.Equal(t, []string{"anonymous"}, destroyed)
+
+	// a container is only removed once it has stopped
+	c.state = StateRunning
+	c.autoRemove(context.Background(), nil)
+	assert.Equal(t, StateRunning, c.CurrentState())
+}
@@ -97,7 +97,9 @@ func Init(ctx context.Context, sess *session.Session, source extraconfig.DataSou
 		Config.EventManager = event.NewEventManager(ec)
 
 		// subscribe the exec layer to the event stream for Vm events
-		Config.EventManager.Subscribe(events.NewEventType(vsphere.VMEvent{}).Topic(), "exec", eventCallback)
+		Config.EventManager.Subscribe(events.NewEventType(vsphere.VMEvent{}).Topic(), "exec", func(ie events.Event) {
+			eventCallback(sess, ie)
+		})
 		// subscribe callback to handle vm registered event
 		Config.EventManager.Subscribe(events.NewEventType(vsphere.VMEvent{}).Topic(), "registeredVMEvent", func(ie events.Event) {
 			registeredVMCallback(sess, ie)
@@ -120,12 +122,15 @@ func Init(ctx context.Context, sess *session.Session, source extraconfig.DataSou
 		if err = Containers.sync(ctx, sess); err != nil {
 			return
 		}
+
+		// no create is in progress yet, so only the names of existing containers stay reserved
+		err = Containers.reconcileNames(ctx, 0)
 	})
 	return initializer.err
 }
 
 // eventCallback will process events
-func eventCallback(ie events.Event) {
+func eventCallback(sess *session.Session, ie events.Event) {
 	// grab the container from the cache
 	container := Containers.Container(ie.Reference())
 	if container != nil {
@@ -153,12 +158,25 @@ func eventCallback(ie events.Event) {
 					ctx, cancel := context.WithTimeout(context.Background(), propertyCollectorTimeout)
 					defer cancel()
 
+					if newState == StateStopped {
+						// the final session state reported by the tether is not kept by the VM
+						// once it is reloaded, so it is written back to its configuration
+						if err := container.persistExitState(ctx); err != nil {
+							log.Errorf("Unable to persist exit state of container %s: %s", container.ExecConfig.ID, err)
+						}
+					}
+
 					err := container.Refresh(ctx)
 					if err != nil {
 						log.Errorf("Event driven container update failed: %s", err.Error())
 					}
 					// regardless of update success failure publish the container event
 					publishContainerEvent(container.ExecConfig.ID, ie.Created(), ie.String())
+
+					if newState == StateStopped {
+						// the exit state has been persisted, so anyone waiting on the container has what they need
+						container.autoRemove(ctx, sess)
+					}
 				}()
 			case StateRemoved:
 				log.Debugf("Container(%s) %s via event activity", container.ExecConfig.ID, newState.String())
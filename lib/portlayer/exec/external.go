@@ -0,0 +1,252 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig/vmomi"
+	"github.com/vmware/vic/pkg/vsphere/guest"
+	"github.com/vmware/vic/pkg/vsphere/session"
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+// ExternalError is returned when an operation that would change a container is attempted on an
+// external container
+type ExternalError struct {
+	ID string
+}
+
+func (e ExternalError) Error() string {
+	return fmt.Sprintf("container %s is an existing VM listed as an external container and cannot be changed", e.ID)
+}
+
+// invalidNameChars matches the characters of a VM name that are not permitted in container names
+var invalidNameChars = regexp.MustCompile("[^a-zA-Z0-9_.-]+")
+
+// External returns whether the container is an existing VM listed as an external container
+func (c *containerBase) External() bool {
+	return c.external
+}
+
+var (
+	// externalVMsLock guards the external VMs of the configuration, which are listed while the container
+	// cache is locked
+	externalVMsLock sync.Mutex
+	// registerLock serializes the registration of external VMs
+	registerLock sync.Mutex
+)
+
+// externalVMsConfig is the slice of the VCH config updated when an external VM is registered
+type externalVMsConfig struct {
+	ExternalVMs []types.ManagedObjectReference `vic:"0.1" scope:"read-only" key:"container/external_vms"`
+}
+
+// externalContainers returns the containers for the existing VMs listed as external containers. VMs
+// that no longer exist, or cannot be retrieved, are skipped so that they do not hide the containerVMs.
+func externalContainers(ctx context.Context, sess *session.Session) []*Container {
+	externalVMsLock.Lock()
+	refs := Config.ExternalVMs
+	externalVMsLock.Unlock()
+
+	defer trace.End(trace.Begin(fmt.Sprintf("%d external VMs", len(refs))))
+
+	var cons []*Container
+	for _, ref := range refs {
+		c, err := externalContainer(ctx, sess, ref)
+		if err != nil {
+			if _, ok := err.(NotFoundError); ok {
+				log.Warnf("Skipping external VM %s as it no longer exists", ref)
+			} else {
+				log.Warnf("Skipping external VM %s: %s", ref, err)
+			}
+			continue
+		}
+
+		cons = append(cons, c)
+	}
+
+	return cons
+}
+
+// externalContainer returns the container for the existing VM
+func externalContainer(ctx context.Context, sess *session.Session, ref types.ManagedObjectReference) (*Container, error) {
+	var v mo.VirtualMachine
+	if err := sess.RetrieveOne(ctx, ref, []string{"config", "runtime", "summary"}, &v); err != nil {
+		if tasks.IsFault(err, &types.ManagedObjectNotFound{}) {
+			return nil, NotFoundError{err}
+		}
+		return nil, err
+	}
+
+	if v.Config == nil {
+		return nil, fmt.Errorf("the configuration of VM %s is not available", ref)
+	}
+	if v.Config.Template {
+		return nil, ExternalVMError{ref, "it is a template"}
+	}
+
+	c := newContainer(newExternalBase(vm.NewVirtualMachine(ctx, sess, ref), v.Config, &v.Runtime))
+	if v.Summary.Storage != nil {
+		c.VMUnsharedDisk = v.Summary.Storage.Unshared
+	}
+
+	return c, nil
+}
+
+// ExternalVMError is returned when a VM cannot be registered as an external container
+type ExternalVMError struct {
+	Ref    types.ManagedObjectReference
+	Reason string
+}
+
+func (e ExternalVMError) Error() string {
+	return fmt.Sprintf("VM %s cannot be listed as an external container as %s", e.Ref, e.Reason)
+}
+
+// RegisterExternal lists the existing VM as a read-only external container, and records it in the
+// configuration of the appliance so that it is listed after a restart. Registering a VM that is already
+// listed returns its container.
+func RegisterExternal(ctx context.Context, sess *session.Session, ref types.ManagedObjectReference) (*Container, error) {
+	defer trace.End(trace.Begin(ref.String()))
+
+	registerLock.Lock()
+	defer registerLock.Unlock()
+
+	if c := Containers.Container(ref.String()); c != nil {
+		if c.external {
+			return c, nil
+		}
+		return nil, ExternalVMError{ref, "it is a containerVM of this VCH"}
+	}
+
+	c, err := externalContainer(ctx, sess, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	externalVMsLock.Lock()
+	refs := append([]types.ManagedObjectReference{}, Config.ExternalVMs...)
+	externalVMsLock.Unlock()
+
+	refs = append(refs, ref)
+	if err = persistExternalVMs(ctx, sess, refs); err != nil {
+		return nil, err
+	}
+
+	externalVMsLock.Lock()
+	Config.ExternalVMs = refs
+	externalVMsLock.Unlock()
+
+	Containers.Put(c)
+	return c, nil
+}
+
+// persistExternalVMs writes the external VMs to the extraconfig of the appliance VM
+func persistExternalVMs(ctx context.Context, sess *session.Session, refs []types.ManagedObjectReference) error {
+	self, err := guest.GetSelf(ctx, sess)
+	if err != nil {
+		return err
+	}
+
+	cfg := make(map[string]string)
+	extraconfig.Encode(extraconfig.MapSink(cfg), externalVMsConfig{ExternalVMs: refs})
+
+	spec := types.VirtualMachineConfigSpec{
+		ExtraConfig: vmomi.OptionValueFromMap(cfg),
+	}
+
+	task, err := self.Reconfigure(ctx, spec)
+	if err != nil {
+		return err
+	}
+
+	return task.Wait(ctx)
+}
+
+// newExternalBase constructs the containerBase of an external container. The VM has no container
+// configuration of its own, so one is derived from the VM for inspection.
+func newExternalBase(vm *vm.VirtualMachine, c *types.VirtualMachineConfigInfo, r *types.VirtualMachineRuntimeInfo) *containerBase {
+	return &containerBase{
+		ExecConfig: externalConfig(vm.Reference(), c, r),
+		Config:     c,
+		Runtime:    r,
+		vm:         vm,
+		external:   true,
+	}
+}
+
+// externalConfig derives the container configuration of an external container from its VM. The ID is
+// stable for the VM and the VM name is used as the container name. The VM has no record of when it was
+// created so the last change to its configuration stands in for it.
+func externalConfig(ref types.ManagedObjectReference, c *types.VirtualMachineConfigInfo, r *types.VirtualMachineRuntimeInfo) *executor.ExecutorConfig {
+	key := c.InstanceUuid
+	if key == "" {
+		key = ref.String()
+	}
+	sum := sha256.Sum256([]byte(key))
+	id := hex.EncodeToString(sum[:])
+
+	session := &executor.SessionConfig{
+		Common: executor.Common{
+			ID:   id,
+			Name: c.Name,
+		},
+		// the VM is not launched by VIC, so it is reported as started whenever it has been powered on
+		Started: "true",
+	}
+	if r != nil && r.BootTime != nil {
+		session.StartTime = r.BootTime.UTC().Unix()
+	}
+
+	return &executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   id,
+			Name: externalName(c.Name),
+		},
+		CreateTime: c.Modified.UTC().Unix(),
+		Sessions: map[string]*executor.SessionConfig{
+			id: session,
+		},
+		Annotations: map[string]string{
+			config.ExternalAnnotation: "true",
+		},
+		RepoName: c.GuestId,
+	}
+}
+
+// externalName converts a VM name to a permitted container name
+func externalName(name string) string {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "_.-")
+	if len(name) < 2 {
+		name = "vm-" + name
+	}
+	return name
+}
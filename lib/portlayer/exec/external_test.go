@@ -0,0 +1,92 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/vsphere/vm"
+)
+
+func TestExternalName(t *testing.T) {
+	names := map[string]string{
+		"db01":            "db01",
+		"legacy app (v2)": "legacy-app-v2",
+		"_build.agent_":   "build.agent",
+		"x":               "vm-x",
+		"日本":              "vm-",
+	}
+
+	for in, out := range names {
+		assert.Equal(t, out, externalName(in), "converting %q", in)
+	}
+}
+
+func TestExternalConfig(t *testing.T) {
+	ref := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-42"}
+	boot := time.Unix(1492000000, 0)
+	c := &types.VirtualMachineConfigInfo{
+		Name:         "legacy app",
+		InstanceUuid: "502f4a1f-13b8-a5ef-7c2c-2b5e7e3ab7a9",
+		GuestId:      "centos64Guest",
+		Modified:     time.Unix(1491000000, 0),
+	}
+	r := &types.VirtualMachineRuntimeInfo{BootTime: &boot}
+
+	ec := externalConfig(ref, c, r)
+	assert.Len(t, ec.ID, 64)
+	assert.Equal(t, "legacy-app", ec.Name)
+	assert.Equal(t, int64(1491000000), ec.CreateTime)
+	assert.Equal(t, "centos64Guest", ec.RepoName)
+	assert.Equal(t, "true", ec.Annotations[config.ExternalAnnotation])
+	if assert.Contains(t, ec.Sessions, ec.ID) {
+		assert.Equal(t, int64(1492000000), ec.Sessions[ec.ID].StartTime)
+		assert.NotEmpty(t, ec.Sessions[ec.ID].Started)
+	}
+
+	assert.Equal(t, ec.ID, externalConfig(ref, c, nil).ID, "the ID must be stable for the VM")
+
+	c.InstanceUuid = ""
+	assert.NotEqual(t, ec.ID, externalConfig(ref, c, r).ID, "the ID falls back to the VM reference")
+}
+
+func TestExternalReadOnly(t *testing.T) {
+	ctx := context.Background()
+	ref := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-42"}
+	v := vm.NewVirtualMachineFromVM(ctx, nil, object.NewVirtualMachine(nil, ref))
+	c := &types.VirtualMachineConfigInfo{Name: "legacy"}
+	r := &types.VirtualMachineRuntimeInfo{PowerState: types.VirtualMachinePowerStatePoweredOff}
+
+	con := newContainer(newExternalBase(v, c, r))
+	assert.True(t, con.External())
+	assert.Equal(t, StateStopped, con.CurrentState())
+
+	assert.IsType(t, ExternalError{}, con.Remove(ctx, nil, true))
+	assert.IsType(t, ExternalError{}, con.Signal(ctx, 9))
+
+	h := newHandle(con)
+	defer h.Close()
+
+	assert.True(t, h.External())
+	h.SetTargetState(StateRunning)
+	assert.IsType(t, ExternalError{}, h.Commit(ctx, nil, nil))
+}
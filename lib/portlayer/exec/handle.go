@@ -28,8 +28,10 @@ import (
 	"github.com/golang/groupcache/lru"
 	"golang.org/x/net/context"
 
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/config/executor/validation"
 	"github.com/vmware/vic/lib/guest"
 	"github.com/vmware/vic/lib/portlayer/constants"
 	"github.com/vmware/vic/lib/spec"
@@ -100,10 +102,15 @@ func TestHandle(id string) *Handle {
 // newHandle creates a handle for an existing container
 // con must not be nil
 func newHandle(con *Container) *Handle {
+	base := newBase(con.vm, con.Config, con.Runtime)
+	if con.external {
+		base = newExternalBase(con.vm, con.Config, con.Runtime)
+	}
+
 	h := &Handle{
 		key:           newHandleKey(),
 		targetState:   StateUnknown,
-		containerBase: *newBase(con.vm, con.Config, con.Runtime),
+		containerBase: *base,
 		// currently every operation has a spec, because even the power operations
 		// make changes to extraconfig for timestamps and session status
 		Spec: &spec.VirtualMachineConfigSpec{
@@ -175,6 +182,10 @@ func (h *Handle) String() string {
 }
 
 func (h *Handle) Commit(ctx context.Context, sess *session.Session, waitTime *int32) error {
+	if h.external {
+		return ExternalError{h.ExecConfig.ID}
+	}
+
 	cfg := make(map[string]string)
 
 	// Set timestamps based on target state
@@ -184,6 +195,7 @@ func (h *Handle) Commit(ctx context.Context, sess *session.Session, waitTime *in
 			sc.StartTime = time.Now().UTC().Unix()
 			sc.Started = ""
 			sc.ExitStatus = 0
+			sc.OOMKilled = false
 		}
 	case StateStopped:
 		for _, sc := range h.ExecConfig.Sessions {
@@ -191,10 +203,19 @@ func (h *Handle) Commit(ctx context.Context, sess *session.Session, waitTime *in
 		}
 	}
 
+	if err := validation.Executor(h.ExecConfig); err != nil {
+		return err
+	}
+
 	extraconfig.Encode(extraconfig.MapSink(cfg), h.ExecConfig)
 	s := h.Spec.Spec()
 	s.ExtraConfig = append(s.ExtraConfig, vmomi.OptionValueFromMap(cfg)...)
 
+	// pick up a bootstrap image updated since the container was last started
+	if h.TargetState() == StateRunning && h.Runtime != nil && h.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOff {
+		s.DeviceChange = append(s.DeviceChange, bootMediaChange(h.Config, Config.BootstrapImagePath)...)
+	}
+
 	if err := Commit(ctx, sess, h, waitTime); err != nil {
 		return err
 	}
@@ -203,6 +224,38 @@ func (h *Handle) Commit(ctx context.Context, sess *session.Session, waitTime *in
 	return nil
 }
 
+// bootMediaChange returns the changes that point the CD-ROM of a containerVM at image, if it uses another one
+func bootMediaChange(config *types.VirtualMachineConfigInfo, image string) []types.BaseVirtualDeviceConfigSpec {
+	if config == nil || image == "" {
+		return nil
+	}
+
+	var changes []types.BaseVirtualDeviceConfigSpec
+	for _, device := range object.VirtualDeviceList(config.Hardware.Device).SelectByType((*types.VirtualCdrom)(nil)) {
+		cdrom := device.(*types.VirtualCdrom)
+		backing, ok := cdrom.Backing.(*types.VirtualCdromIsoBackingInfo)
+		if !ok || backing.FileName == image {
+			continue
+		}
+
+		log.Infof("Switching boot media of %s from %q to %q", config.Name, backing.FileName, image)
+
+		// the device is shared with the container cache, so is copied rather than modified
+		edit := *cdrom
+		edit.Backing = &types.VirtualCdromIsoBackingInfo{
+			VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{
+				FileName: image,
+			},
+		}
+		changes = append(changes, &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationEdit,
+			Device:    &edit,
+		})
+	}
+
+	return changes
+}
+
 func (h *Handle) Close() {
 	removeHandle(h.key)
 }
@@ -250,6 +303,11 @@ func Create(ctx context.Context, sess *session.Session, config *ContainerCreateC
 		return nil, errors.New(detail)
 	}
 
+	imageStore := Config.ImageStore
+	if imageStore == nil {
+		imageStore = &Config.ImageStores[0]
+	}
+
 	specconfig := &spec.VirtualMachineConfigSpecConfig{
 		NumCPUs:  int32(config.Resources.NumCPUs),
 		MemoryMB: config.Resources.MemoryMB,
@@ -263,7 +321,10 @@ func Create(ctx context.Context, sess *session.Session, config *ContainerCreateC
 		VMPathName:    fmt.Sprintf("[%s]", sess.Datastore.Name()),
 
 		ImageStoreName: config.ImageStoreName,
-		ImageStorePath: &Config.ImageStores[0],
+		ImageStorePath: imageStore,
+
+		Profile:       Config.ContainerVMProfile,
+		StoragePolicy: Config.StoragePolicy,
 
 		Metadata: config.Metadata,
 	}
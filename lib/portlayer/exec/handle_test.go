@@ -0,0 +1,64 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestBootMediaChange(t *testing.T) {
+	current := "[datastore1] vch/1.0-bootstrap.iso"
+	updated := "[datastore1] vch/0123456789ab-1.0-bootstrap.iso"
+
+	cdrom := &types.VirtualCdrom{
+		VirtualDevice: types.VirtualDevice{
+			Key: 3000,
+			Backing: &types.VirtualCdromIsoBackingInfo{
+				VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{
+					FileName: current,
+				},
+			},
+		},
+	}
+	config := &types.VirtualMachineConfigInfo{
+		Name: "container",
+		Hardware: types.VirtualHardware{
+			Device: []types.BaseVirtualDevice{
+				&types.VirtualDisk{VirtualDevice: types.VirtualDevice{Key: 2000}},
+				cdrom,
+			},
+		},
+	}
+
+	assert.Empty(t, bootMediaChange(nil, updated))
+	assert.Empty(t, bootMediaChange(config, ""))
+	assert.Empty(t, bootMediaChange(config, current))
+
+	changes := bootMediaChange(config, updated)
+	require.Len(t, changes, 1)
+
+	change := changes[0].GetVirtualDeviceConfigSpec()
+	assert.Equal(t, types.VirtualDeviceConfigSpecOperationEdit, change.Operation)
+	assert.EqualValues(t, 3000, change.Device.GetVirtualDevice().Key)
+	assert.Equal(t, updated, change.Device.GetVirtualDevice().Backing.(*types.VirtualCdromIsoBackingInfo).FileName)
+
+	// the cached device is left unchanged
+	assert.Equal(t, current, cdrom.Backing.(*types.VirtualCdromIsoBackingInfo).FileName)
+}
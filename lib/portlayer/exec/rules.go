@@ -0,0 +1,90 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/compute"
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+// rulesLock serializes changes to the DRS groups and rules, as each change is computed from the
+// current cluster configuration
+var rulesLock sync.Mutex
+
+// applyRules adds a newly created containerVM to the DRS VM group keeping containerVMs on the
+// configured host group, and to the anti-affinity rule of its service
+func applyRules(ctx context.Context, sess *session.Session, c *Container) error {
+	defer trace.End(trace.Begin(c.ExecConfig.ID))
+
+	service := c.ExecConfig.Annotations[config.AntiAffinityAnnotation]
+	antiAffinity := Config.AntiAffinityRulePrefix != "" && service != ""
+	if Config.ContainerHostGroup == "" && !antiAffinity {
+		return nil
+	}
+
+	rulesLock.Lock()
+	defer rulesLock.Unlock()
+
+	info, err := compute.ClusterConfig(ctx, sess.Cluster)
+	if err != nil {
+		return err
+	}
+
+	ref := c.vm.Reference()
+	spec := &types.ClusterConfigSpecEx{}
+
+	if Config.ContainerHostGroup != "" {
+		group, err := compute.VMGroupSpec(info, Config.ContainerVMGroup, ref)
+		if err != nil {
+			return err
+		}
+		spec.GroupSpec = append(spec.GroupSpec, group)
+
+		// the rule is created along with the group for the first containerVM
+		name := compute.HostRuleName(Config.ContainerVMGroup)
+		if compute.FindRule(info, name) == nil {
+			spec.RulesSpec = append(spec.RulesSpec, compute.VMHostRuleSpec(name, Config.ContainerVMGroup, Config.ContainerHostGroup, Config.ContainerHostGroupMandatory))
+		}
+	}
+
+	if antiAffinity {
+		vms := []types.ManagedObjectReference{ref}
+		for _, peer := range Containers.Containers(nil) {
+			if peer != c && peer.vm != nil && peer.ExecConfig.Annotations[config.AntiAffinityAnnotation] == service {
+				vms = append(vms, peer.vm.Reference())
+			}
+		}
+
+		rule, err := compute.AntiAffinityRuleSpec(info, Config.AntiAffinityRulePrefix+service, vms...)
+		if err != nil {
+			return err
+		}
+		if rule != nil {
+			spec.RulesSpec = append(spec.RulesSpec, *rule)
+		}
+	}
+
+	if len(spec.GroupSpec) == 0 && len(spec.RulesSpec) == 0 {
+		return nil
+	}
+
+	return compute.ReconfigureCluster(ctx, sess.Cluster, spec)
+}
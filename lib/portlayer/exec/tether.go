@@ -0,0 +1,93 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/lib/portlayer/event/events"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/version"
+)
+
+// TetherConnected records the version reported by the tether of container id, publishing
+// a ContainerTetherOutdated event if that tether is older than the appliance
+func TetherConnected(id string, v *version.Build) {
+	defer trace.End(trace.Begin(id))
+
+	c := Containers.Container(id)
+	if c == nil {
+		log.Debugf("Tether connected for unknown container %s", id)
+		return
+	}
+
+	if c.SetTetherVersion(v) {
+		log.Warnf("Container %s is running an outdated tether (%s) and should be restarted to update it", id, v.ShortVersion())
+		publishTetherOutdatedEvent(id, v)
+	}
+}
+
+// SetTetherVersion records the version reported by the container's tether, returning
+// whether that version is outdated
+func (c *Container) SetTetherVersion(v *version.Build) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.TetherVersion = v
+	return c.ContainerInfo.TetherOutdated()
+}
+
+// TetherOutdated determines whether the tether is older than the appliance, i.e. the container was
+// booted from a bootstrap image that predates the current one. Unknown versions are not outdated.
+func (c *ContainerInfo) TetherOutdated() bool {
+	return tetherOutdated(c.TetherVersion, version.GetBuild())
+}
+
+func tetherOutdated(tether, current *version.Build) bool {
+	if tether == nil {
+		return false
+	}
+
+	if tether.Equal(current) {
+		return false
+	}
+
+	older, err := tether.IsOlder(current)
+	if err != nil {
+		// tethers that predate version reporting don't provide a build number
+		log.Debugf("Unable to compare tether version: %s", err)
+		return tether.BuildNumber == ""
+	}
+
+	return older
+}
+
+func publishTetherOutdatedEvent(id string, v *version.Build) {
+	if Config.EventManager == nil {
+		return
+	}
+
+	Config.EventManager.Publish(&events.ContainerEvent{
+		BaseEvent: &events.BaseEvent{
+			Ref:         id,
+			CreatedTime: time.Now(),
+			Event:       events.ContainerTetherOutdated,
+			Detail:      fmt.Sprintf("Container %s tether version %s is older than %s", id, v.ShortVersion(), version.GetBuild().ShortVersion()),
+		},
+	})
+}
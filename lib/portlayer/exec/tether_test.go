@@ -0,0 +1,44 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/pkg/version"
+)
+
+func TestTetherOutdated(t *testing.T) {
+	current := &version.Build{Version: "v0.8.0", BuildNumber: "100"}
+
+	tests := []struct {
+		tether   *version.Build
+		outdated bool
+	}{
+		// not yet reported
+		{nil, false},
+		// predates version reporting
+		{&version.Build{}, true},
+		{&version.Build{Version: "v0.7.0", BuildNumber: "90"}, true},
+		{&version.Build{Version: "v0.8.0", BuildNumber: "100"}, false},
+		{&version.Build{Version: "v0.9.0", BuildNumber: "110"}, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.outdated, tetherOutdated(test.tether, current), "tether %+v", test.tether)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package driver forwards the output of containers to the log drivers supported by the appliance.
+// The drivers are those of docker, so they take the same --log-opt options.
+package driver
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+	// registers the gelf driver with the docker logger factory
+	_ "github.com/docker/docker/daemon/logger/gelf"
+
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+// Info describes the container whose output is forwarded
+type Info struct {
+	ID        string
+	Name      string
+	ImageID   string
+	ImageName string
+	Created   time.Time
+	Path      string
+	Args      []string
+	Env       []string
+}
+
+// Validate checks that the log driver of cfg is supported and that its options are valid, also
+// with the option validation of the docker log driver
+func Validate(cfg executor.LogConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if !cfg.Forwarded() {
+		return nil
+	}
+	return logger.ValidateLogOpts(cfg.Type, cfg.Config)
+}
+
+// New creates the logger that forwards the output of the container described by info
+func New(cfg executor.LogConfig, info Info) (logger.Logger, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	create, err := logger.GetLogDriver(cfg.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := logger.Context{
+		Config:              cfg.Config,
+		ContainerID:         info.ID,
+		ContainerName:       "/" + strings.TrimPrefix(info.Name, "/"),
+		ContainerEntrypoint: info.Path,
+		ContainerArgs:       info.Args,
+		ContainerImageID:    info.ImageID,
+		ContainerImageName:  info.ImageName,
+		ContainerCreated:    info.Created,
+		ContainerEnv:        info.Env,
+	}
+
+	return create(ctx)
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []executor.LogConfig{
+		{},
+		{Type: "json-file"},
+		{Type: "none"},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "udp://10.0.0.1:12201", "tag": "web"}},
+		{Type: "fluentd", Config: map[string]string{"fluentd-address": "10.0.0.1"}},
+		{Type: "fluentd", Config: map[string]string{"fluentd-address": "tcp://logs.example.com:24224", "env": "HOME"}},
+	}
+	for _, cfg := range valid {
+		assert.NoError(t, Validate(cfg), "%#v", cfg)
+	}
+
+	invalid := []executor.LogConfig{
+		{Type: "syslog", Config: map[string]string{"syslog-address": "udp://10.0.0.1"}},
+		{Type: "gelf"},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "10.0.0.1:12201"}},
+		{Type: "gelf", Config: map[string]string{"gelf-address": "udp://10.0.0.1:12201", "labels": "a"}},
+		{Type: "fluentd", Config: map[string]string{"fluentd-address": "10.0.0.1:port"}},
+		{Type: "fluentd", Config: map[string]string{"fluentd-address": "10.0.0.1", "fluentd-buffer-limit": "1"}},
+	}
+	for _, cfg := range invalid {
+		assert.Error(t, Validate(cfg), "%#v", cfg)
+	}
+}
+
+func TestFluentd(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	received := make(chan []interface{}, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var msg []interface{}
+		if err := codec.NewDecoder(conn, &codec.MsgpackHandle{RawToString: true}).Decode(&msg); err == nil {
+			received <- msg
+		}
+	}()
+
+	cfg := executor.LogConfig{Type: "fluentd", Config: map[string]string{"fluentd-address": l.Addr().String()}}
+	info := Info{ID: "0123456789abcdef", Name: "web", ImageID: "fedcba9876543210"}
+
+	f, err := New(cfg, info)
+	require.NoError(t, err)
+	defer f.Close()
+
+	now := time.Now()
+	require.NoError(t, f.Log(&logger.Message{ContainerID: info.ID, Line: []byte("hello"), Source: "stdout", Timestamp: now}))
+
+	select {
+	case msg := <-received:
+		require.Len(t, msg, 3)
+		assert.Equal(t, "docker.0123456789ab", msg[0])
+		assert.EqualValues(t, now.Unix(), msg[1])
+
+		record, ok := msg[2].(map[interface{}]interface{})
+		require.True(t, ok, "%#v", msg[2])
+		assert.Equal(t, "hello", record["log"])
+		assert.Equal(t, "stdout", record["source"])
+		assert.Equal(t, "/web", record["container_name"])
+		assert.Equal(t, info.ID, record["container_id"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("fluentd message not received")
+	}
+}
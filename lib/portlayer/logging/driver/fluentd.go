@@ -0,0 +1,132 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/docker/docker/daemon/logger"
+	"github.com/docker/docker/daemon/logger/loggerutils"
+	"github.com/hashicorp/go-msgpack/codec"
+
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+const (
+	fluentdTag     = "docker.{{.ID}}"
+	fluentdTimeout = 10 * time.Second
+)
+
+// fluentd sends container output to a fluentd endpoint using the forward protocol. The fluentd
+// driver of docker needs a client library that is not vendored, so this implements the subset of
+// the protocol that the driver uses, sending each line as a [tag, time, record] message.
+type fluentd struct {
+	m sync.Mutex
+
+	address string
+	tag     string
+	record  map[string]string
+	conn    net.Conn
+}
+
+func init() {
+	if err := logger.RegisterLogDriver(executor.LogDriverFluentd, newFluentd); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func newFluentd(ctx logger.Context) (logger.Logger, error) {
+	address, err := executor.FluentdAddress(ctx.Config["fluentd-address"])
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := loggerutils.ParseLogTag(ctx, fluentdTag)
+	if err != nil {
+		return nil, err
+	}
+
+	record := ctx.ExtraAttributes(nil)
+	record["container_id"] = ctx.ContainerID
+	record["container_name"] = ctx.ContainerName
+
+	// the connection is made on the first message, so that an endpoint that is down does not keep
+	// the output from being forwarded once it is back
+	return &fluentd{
+		address: address,
+		tag:     tag,
+		record:  record,
+	}, nil
+}
+
+func (f *fluentd) Log(msg *logger.Message) error {
+	record := make(map[string]string, len(f.record)+2)
+	for k, v := range f.record {
+		record[k] = v
+	}
+	record["source"] = msg.Source
+	record["log"] = string(msg.Line)
+
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, &codec.MsgpackHandle{}).Encode([]interface{}{f.tag, msg.Timestamp.Unix(), record}); err != nil {
+		return err
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	// reconnect once if the endpoint has dropped the connection
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if f.conn == nil {
+			if f.conn, err = net.DialTimeout("tcp", f.address, fluentdTimeout); err != nil {
+				f.conn = nil
+				continue
+			}
+		}
+
+		f.conn.SetWriteDeadline(time.Now().Add(fluentdTimeout))
+		if _, err = f.conn.Write(b); err == nil {
+			return nil
+		}
+
+		f.conn.Close()
+		f.conn = nil
+	}
+
+	return fmt.Errorf("fluentd: cannot send message to %s: %s", f.address, err)
+}
+
+func (f *fluentd) Close() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.conn == nil {
+		return nil
+	}
+
+	err := f.conn.Close()
+	f.conn = nil
+	return err
+}
+
+func (f *fluentd) Name() string {
+	return executor.LogDriverFluentd
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/docker/docker/daemon/logger"
+
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/portlayer/event/events"
+	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/logging/driver"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// forwarding tracks the forwarding of container output to log drivers
+var forwarding = struct {
+	sync.Mutex
+
+	// active holds the containers whose output is being forwarded
+	active map[string]bool
+	// offsets holds how far into the log of each stopped container its output has been forwarded, with
+	// a negative offset for containers whose log was not followed by this portlayer
+	offsets map[string]int64
+}{
+	active:  make(map[string]bool),
+	offsets: make(map[string]int64),
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// Init forwards the output of containers with a log driver while they run. Containers that are already
+// running are followed from the end of their log, so that output is not forwarded twice across restarts
+// of the portlayer. It must be called after exec.Init.
+func Init(ctx context.Context) error {
+	defer trace.End(trace.Begin(""))
+
+	em := exec.Config.EventManager
+	if em == nil {
+		return fmt.Errorf("event manager is required to forward container output")
+	}
+
+	forwarding.Lock()
+	for _, c := range exec.Containers.Containers(nil) {
+		forwarding.offsets[c.ExecConfig.ID] = -1
+	}
+	forwarding.Unlock()
+
+	em.Subscribe(events.NewEventType(events.ContainerEvent{}).Topic(), "logging", func(ie events.Event) {
+		handleEvent(ctx, ie)
+	})
+
+	running := exec.StateRunning
+	for _, c := range exec.Containers.Containers(&running) {
+		forward(ctx, c)
+	}
+
+	return nil
+}
+
+// handleEvent starts forwarding the output of containers as they start
+func handleEvent(ctx context.Context, ie events.Event) {
+	switch ie.String() {
+	case events.ContainerStarted, events.ContainerPoweredOn:
+		if c := exec.Containers.Container(ie.Reference()); c != nil {
+			forward(ctx, c)
+		}
+	case events.ContainerRemoved:
+		forwarding.Lock()
+		delete(forwarding.offsets, ie.Reference())
+		forwarding.Unlock()
+	}
+}
+
+// LogConfig returns the log driver that the output of the container is forwarded to, which is that of
+// the container or else that of the VCH
+func LogConfig(c *exec.Container) executor.LogConfig {
+	if c.ExecConfig.LogConfig.Type != "" {
+		return c.ExecConfig.LogConfig
+	}
+	return exec.Config.ContainerLogConfig
+}
+
+// forward follows the log of the running container and sends each line to its log driver until the
+// container stops. A container started for the first time is followed from the start of its log, and
+// a restarted one from where forwarding stopped.
+func forward(ctx context.Context, c *exec.Container) {
+	id := c.ExecConfig.ID
+
+	cfg := LogConfig(c)
+	if !cfg.Forwarded() {
+		return
+	}
+
+	forwarding.Lock()
+	defer forwarding.Unlock()
+
+	// both the commit and the vSphere power on event start forwarding
+	if forwarding.active[id] {
+		return
+	}
+
+	l, err := driver.New(cfg, containerInfo(c))
+	if err != nil {
+		log.Errorf("Unable to forward output of %s to log driver %s: %s", id, cfg.Type, err)
+		return
+	}
+
+	follower, offset, err := c.LogFollower(ctx, forwarding.offsets[id])
+	if err != nil {
+		log.Errorf("Unable to follow output of %s: %s", id, err)
+		l.Close()
+		return
+	}
+
+	log.Infof("Forwarding output of %s to log driver %s from offset %d", id, cfg.Type, offset)
+
+	// the log of a containerVM holds both stdout and stderr
+	r := &countingReader{r: follower}
+	copier := logger.NewCopier(id, map[string]io.Reader{"stdout": r}, l)
+	copier.Run()
+	forwarding.active[id] = true
+
+	go func() {
+		copier.Wait()
+		follower.Close()
+		l.Close()
+
+		forwarding.Lock()
+		defer forwarding.Unlock()
+
+		delete(forwarding.active, id)
+		// a removed container has no log to resume
+		if exec.Containers.Container(id) != nil {
+			forwarding.offsets[id] = offset + r.n
+		}
+
+		log.Infof("Stopped forwarding output of %s at offset %d", id, offset+r.n)
+	}()
+}
+
+// containerInfo describes the container to the log driver
+func containerInfo(c *exec.Container) driver.Info {
+	info := driver.Info{
+		ID:        c.ExecConfig.ID,
+		Name:      c.ExecConfig.Name,
+		ImageID:   c.ExecConfig.LayerID,
+		ImageName: c.ExecConfig.RepoName,
+		Created:   time.Unix(c.ExecConfig.CreateTime, 0).UTC(),
+	}
+
+	if session, ok := c.ExecConfig.Sessions[c.ExecConfig.ID]; ok {
+		info.Path = session.Cmd.Path
+		if len(session.Cmd.Args) > 0 {
+			info.Args = session.Cmd.Args[1:]
+		}
+		info.Env = session.Cmd.Env
+	}
+
+	return info
+}
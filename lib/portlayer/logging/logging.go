@@ -19,6 +19,7 @@ import (
 
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/spec"
 	"github.com/vmware/vic/pkg/trace"
 )
 
@@ -60,6 +61,16 @@ func Join(h interface{}) (interface{}, error) {
 		handle.Spec.DeviceChange = append(handle.Spec.DeviceChange, config)
 	}
 
+	// kernel console, so that a panic of the containerVM is not lost
+	if exec.Config.ContainerCrashLogs {
+		filename := fmt.Sprintf("%s/%s/%s", VMPathName, VMName, spec.CrashLogName)
+		config := &types.VirtualDeviceConfigSpec{
+			Device:    spec.NewCrashLogSerialPort(filename),
+			Operation: types.VirtualDeviceConfigSpecOperationAdd,
+		}
+		handle.Spec.DeviceChange = append(handle.Spec.DeviceChange, config)
+	}
+
 	return handle, nil
 }
 
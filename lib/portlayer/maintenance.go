@@ -0,0 +1,64 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portlayer
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/scheduler"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+// ContainerReconcileInterval is how often the container cache is reconciled with vSphere
+const ContainerReconcileInterval = 10 * time.Minute
+
+// Maintenance runs the periodic maintenance jobs of the port layer. Components register their jobs
+// with it once the port layer is initialized.
+var Maintenance *scheduler.Scheduler
+
+// maintenanceConfig is the slice of the VCH config enabling and disabling maintenance jobs
+type maintenanceConfig struct {
+	MaintenanceJobs map[string]bool `vic:"0.1" scope:"read-only" key:"maintenance_jobs"`
+}
+
+// initMaintenance creates the maintenance scheduler and registers the jobs of the port layer itself
+func initMaintenance(ctx context.Context, sess *session.Session, source extraconfig.DataSource) {
+	var conf maintenanceConfig
+	extraconfig.Decode(source, &conf)
+
+	Maintenance = scheduler.New(conf.MaintenanceJobs)
+	Maintenance.StatusPath = scheduler.DefaultStatusPath
+
+	err := Maintenance.Register(scheduler.Job{
+		Name:     config.ContainerReconcileJob,
+		Interval: ContainerReconcileInterval,
+		Run: func(ctx context.Context) error {
+			return exec.Reconcile(ctx, sess)
+		},
+	})
+	if err != nil {
+		log.Errorf("Unable to register maintenance job: %s", err)
+	}
+
+	monitorCertificates(ctx, sess, source)
+
+	Maintenance.Start(ctx)
+}
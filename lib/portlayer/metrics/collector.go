@@ -0,0 +1,256 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+// The performance counters sampled for each VM. Averages are per second over the sample interval,
+// summations are totals over it.
+const (
+	cpuUsed      = "cpu.used.summation" // milliseconds, per virtual CPU
+	memConsumed  = "mem.consumed.average"
+	netRx        = "net.bytesRx.average" // KB per second, per virtual NIC
+	netTx        = "net.bytesTx.average"
+	netPacketsRx = "net.packetsRx.summation"
+	netPacketsTx = "net.packetsTx.summation"
+	netDroppedRx = "net.droppedRx.summation"
+	netDroppedTx = "net.droppedTx.summation"
+	diskRead     = "virtualDisk.read.average" // KB per second, per virtual disk
+	diskWrite    = "virtualDisk.write.average"
+	diskReads    = "virtualDisk.numberReadAveraged.average"
+	diskWrites   = "virtualDisk.numberWriteAveraged.average"
+)
+
+// Counters are the names of the performance counters in a Sample
+var Counters = []string{
+	cpuUsed,
+	memConsumed,
+	netRx, netTx, netPacketsRx, netPacketsTx, netDroppedRx, netDroppedTx,
+	diskRead, diskWrite, diskReads, diskWrites,
+}
+
+// Sample holds the values of the performance counters of a VM over one sample interval, by counter
+// name and device instance. The instance of the aggregate value of a counter is "".
+type Sample struct {
+	Time     time.Time
+	Interval time.Duration
+	Values   map[string]map[string]int64
+}
+
+// Sampler reads the latest sample of the performance counters of a VM
+type Sampler interface {
+	Sample(ctx context.Context, vm types.ManagedObjectReference) (*Sample, error)
+}
+
+// Collector samples VMs for as long as something is subscribed to their metrics
+type Collector struct {
+	sampler  Sampler
+	interval time.Duration
+
+	mu  sync.Mutex
+	vms map[types.ManagedObjectReference]*collection
+}
+
+// collection is the sampling of one VM, shared by its subscribers
+type collection struct {
+	vm       types.ManagedObjectReference
+	memoryMB int64
+
+	subscribers map[chan *VMMetrics]struct{}
+	// last is the latest metrics, nil until the first sample
+	last *VMMetrics
+
+	cancel context.CancelFunc
+}
+
+func NewCollector(sampler Sampler, interval time.Duration) *Collector {
+	return &Collector{
+		sampler:  sampler,
+		interval: interval,
+		vms:      make(map[types.ManagedObjectReference]*collection),
+	}
+}
+
+// Subscribe returns a channel that receives the metrics of the VM after each sample, starting with the
+// latest if there is one. A subscriber that has not taken the previous metrics misses the next. The
+// channel is closed once ctx is done, and the VM is no longer sampled once it has no subscribers.
+func (c *Collector) Subscribe(ctx context.Context, vm types.ManagedObjectReference, memoryMB int64) <-chan *VMMetrics {
+	ch := make(chan *VMMetrics, 1)
+
+	c.mu.Lock()
+	col, ok := c.vms[vm]
+	if !ok {
+		cctx, cancel := context.WithCancel(context.Background())
+		col = &collection{
+			vm:          vm,
+			memoryMB:    memoryMB,
+			subscribers: make(map[chan *VMMetrics]struct{}),
+			cancel:      cancel,
+		}
+		c.vms[vm] = col
+
+		go c.collect(cctx, col)
+	}
+
+	col.subscribers[ch] = struct{}{}
+	if col.last != nil {
+		ch <- col.last
+	}
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(col.subscribers, ch)
+		close(ch)
+
+		if len(col.subscribers) == 0 {
+			col.cancel()
+			delete(c.vms, vm)
+		}
+	}()
+
+	return ch
+}
+
+func (c *Collector) collect(ctx context.Context, col *collection) {
+	m := &VMMetrics{}
+
+	for {
+		s, err := c.sampler.Sample(ctx, col.vm)
+		switch {
+		case err != nil:
+			if ctx.Err() == nil {
+				log.Warnf("Failed to sample performance counters of %s: %s", col.vm, err)
+			}
+		case s.Time.After(m.SampleTime):
+			// samples are only new once vSphere has closed the next interval
+			m = m.add(s, col.memoryMB)
+			c.publish(col, m)
+		}
+
+		select {
+		case <-time.After(c.interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Collector) publish(col *collection, m *VMMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	col.last = m
+	for ch := range col.subscribers {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// add returns the metrics with the sample added. The metrics are shared by subscribers, so are not modified.
+func (m *VMMetrics) add(s *Sample, memoryMB int64) *VMMetrics {
+	next := &VMMetrics{
+		SampleTime: s.Time,
+		CPU: CPUMetrics{
+			Usage:   append([]uint64(nil), m.CPU.Usage...),
+			Elapsed: m.CPU.Elapsed + uint64(s.Interval),
+		},
+		Memory:   m.Memory,
+		Networks: make(map[string]NetworkMetrics),
+		Disks:    make(map[string]DiskMetrics),
+	}
+
+	s.each(cpuUsed, func(instance string, v uint64) {
+		i, err := strconv.Atoi(instance)
+		if err != nil || i < 0 {
+			return
+		}
+		for len(next.CPU.Usage) <= i {
+			next.CPU.Usage = append(next.CPU.Usage, 0)
+		}
+		next.CPU.Usage[i] += v * uint64(time.Millisecond)
+	})
+
+	if v, ok := s.Values[memConsumed][""]; ok && v >= 0 {
+		next.Memory.Consumed = uint64(v) * 1024
+		if next.Memory.Consumed > next.Memory.MaxConsumed {
+			next.Memory.MaxConsumed = next.Memory.Consumed
+		}
+	}
+	next.Memory.Provisioned = uint64(memoryMB) * 1024 * 1024
+
+	// rates are converted to totals over the interval
+	seconds := uint64(s.Interval / time.Second)
+	total := func(rate uint64) uint64 { return rate * 1024 * seconds }
+
+	for k, v := range m.Networks {
+		next.Networks[k] = v
+	}
+	network := func(counter string, fn func(n *NetworkMetrics, v uint64)) {
+		s.each(counter, func(instance string, v uint64) {
+			n := next.Networks[instance]
+			fn(&n, v)
+			next.Networks[instance] = n
+		})
+	}
+	network(netRx, func(n *NetworkMetrics, v uint64) { n.RxBytes += total(v) })
+	network(netTx, func(n *NetworkMetrics, v uint64) { n.TxBytes += total(v) })
+	network(netPacketsRx, func(n *NetworkMetrics, v uint64) { n.RxPackets += v })
+	network(netPacketsTx, func(n *NetworkMetrics, v uint64) { n.TxPackets += v })
+	network(netDroppedRx, func(n *NetworkMetrics, v uint64) { n.RxDropped += v })
+	network(netDroppedTx, func(n *NetworkMetrics, v uint64) { n.TxDropped += v })
+
+	for k, v := range m.Disks {
+		next.Disks[k] = v
+	}
+	disk := func(counter string, fn func(d *DiskMetrics, v uint64)) {
+		s.each(counter, func(instance string, v uint64) {
+			d := next.Disks[instance]
+			fn(&d, v)
+			next.Disks[instance] = d
+		})
+	}
+	disk(diskRead, func(d *DiskMetrics, v uint64) { d.ReadBytes += total(v) })
+	disk(diskWrite, func(d *DiskMetrics, v uint64) { d.WriteBytes += total(v) })
+	disk(diskReads, func(d *DiskMetrics, v uint64) { d.Reads += v * seconds })
+	disk(diskWrites, func(d *DiskMetrics, v uint64) { d.Writes += v * seconds })
+
+	return next
+}
+
+// each calls fn with the value of each device instance of the counter. The aggregate value and
+// values vSphere has no data for, which are negative, are skipped.
+func (s *Sample) each(counter string, fn func(instance string, v uint64)) {
+	for instance, v := range s.Values[counter] {
+		if instance == "" || v < 0 {
+			continue
+		}
+		fn(instance, uint64(v))
+	}
+}
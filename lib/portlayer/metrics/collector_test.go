@@ -0,0 +1,121 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+// fakeSampler returns a new sample on each call, with the time of the sample advancing only every other call
+type fakeSampler struct {
+	mu    sync.Mutex
+	calls int
+	start time.Time
+}
+
+func (f *fakeSampler) Sample(ctx context.Context, vm types.ManagedObjectReference) (*Sample, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	return &Sample{
+		Time:     f.start.Add(time.Duration(f.calls/2) * SampleInterval),
+		Interval: SampleInterval,
+		Values: map[string]map[string]int64{
+			cpuUsed:      {"": 15000, "0": 10000, "1": 5000},
+			memConsumed:  {"": 1024},
+			netRx:        {"": 2, "4000": 2},
+			netPacketsRx: {"4000": 10},
+			diskWrite:    {"scsi0:0": 1, "scsi0:1": -1},
+		},
+	}, nil
+}
+
+func (f *fakeSampler) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+func TestAdd(t *testing.T) {
+	f := &fakeSampler{start: time.Now()}
+	s, err := f.Sample(context.Background(), types.ManagedObjectReference{})
+	require.NoError(t, err)
+
+	m := (&VMMetrics{}).add(s, 512)
+	m = m.add(s, 512)
+
+	assert.Equal(t, []uint64{20 * uint64(time.Second), 10 * uint64(time.Second)}, m.CPU.Usage)
+	assert.Equal(t, 2*uint64(SampleInterval), m.CPU.Elapsed)
+
+	assert.Equal(t, uint64(1024*1024), m.Memory.Consumed)
+	assert.Equal(t, uint64(1024*1024), m.Memory.MaxConsumed)
+	assert.Equal(t, uint64(512*1024*1024), m.Memory.Provisioned)
+
+	// the aggregate instance is not a device
+	require.Len(t, m.Networks, 1)
+	assert.Equal(t, uint64(2*2*1024*20), m.Networks["4000"].RxBytes)
+	assert.Equal(t, uint64(20), m.Networks["4000"].RxPackets)
+
+	// and negative values have no data
+	require.Len(t, m.Disks, 1)
+	assert.Equal(t, uint64(2*1024*20), m.Disks["scsi0:0"].WriteBytes)
+}
+
+func TestSubscribe(t *testing.T) {
+	f := &fakeSampler{start: time.Now()}
+	c := NewCollector(f, time.Millisecond)
+	vm := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := c.Subscribe(ctx, vm, 512)
+
+	first := <-ch
+	second := <-ch
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+
+	// samples for an interval already seen are skipped
+	assert.Equal(t, SampleInterval, second.SampleTime.Sub(first.SampleTime))
+	assert.Equal(t, 2*first.CPU.Usage[0], second.CPU.Usage[0])
+
+	// a later subscriber starts with the latest metrics
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	latest := <-c.Subscribe(ctx2, vm, 512)
+	assert.False(t, latest.SampleTime.Before(second.SampleTime))
+	cancel2()
+
+	cancel()
+	for range ch {
+	}
+
+	// sampling stops once there are no subscribers
+	time.Sleep(10 * time.Millisecond)
+	calls := f.count()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, calls, f.count())
+
+	c.mu.Lock()
+	assert.Empty(t, c.vms)
+	c.mu.Unlock()
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics collects the resource use of container VMs from the vSphere performance counters,
+// accumulating the sampled rates into counters of the kind docker stats reports.
+package metrics
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+// SampleInterval is the interval of the vSphere realtime performance counters
+const SampleInterval = 20 * time.Second
+
+// Supervisor collects the metrics of container VMs for the port layer
+var Supervisor *Collector
+
+// VMMetrics holds the resource use of a VM accumulated since its collection started
+type VMMetrics struct {
+	// SampleTime is the end of the latest sample
+	SampleTime time.Time
+
+	CPU    CPUMetrics
+	Memory MemoryMetrics
+
+	// Networks and Disks are keyed by the device instance of their counters
+	Networks map[string]NetworkMetrics
+	Disks    map[string]DiskMetrics
+}
+
+// CPUMetrics holds the CPU time used by a VM
+type CPUMetrics struct {
+	// Usage is the time used by each virtual CPU, in nanoseconds
+	Usage []uint64
+	// Elapsed is the time covered by the samples, in nanoseconds
+	Elapsed uint64
+}
+
+// MemoryMetrics holds the memory use of a VM
+type MemoryMetrics struct {
+	// Consumed is the host memory backing guest memory in the latest sample, in bytes
+	Consumed uint64
+	// MaxConsumed is the highest Consumed of the samples
+	MaxConsumed uint64
+	// Provisioned is the memory size of the VM, in bytes
+	Provisioned uint64
+}
+
+// NetworkMetrics holds the traffic of a virtual NIC
+type NetworkMetrics struct {
+	RxBytes   uint64
+	RxPackets uint64
+	RxDropped uint64
+	TxBytes   uint64
+	TxPackets uint64
+	TxDropped uint64
+}
+
+// DiskMetrics holds the I/O of a virtual disk
+type DiskMetrics struct {
+	ReadBytes  uint64
+	Reads      uint64
+	WriteBytes uint64
+	Writes     uint64
+}
+
+// Init creates the Supervisor, sampling through the performance manager of the session
+func Init(ctx context.Context, sess *session.Session) error {
+	Supervisor = NewCollector(NewPerfSampler(sess.Vim25()), SampleInterval)
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/net/context"
+)
+
+// realtimeInterval is the ID of the realtime interval of the performance manager
+const realtimeInterval = 20
+
+// perfSampler samples the realtime performance counters of VMs through the performance manager
+type perfSampler struct {
+	client *vim25.Client
+
+	mu sync.Mutex
+	// names are the names of the Counters by key, looked up on first use
+	names map[int32]string
+	ids   []types.PerfMetricId
+}
+
+// NewPerfSampler returns a Sampler that queries the performance manager of the client
+func NewPerfSampler(client *vim25.Client) Sampler {
+	return &perfSampler{client: client}
+}
+
+// metricIDs returns the IDs of the Counters for all device instances
+func (p *perfSampler) metricIDs(ctx context.Context) ([]types.PerfMetricId, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ids != nil {
+		return p.ids, nil
+	}
+
+	var pm mo.PerformanceManager
+	if err := property.DefaultCollector(p.client).RetrieveOne(ctx, *p.client.ServiceContent.PerfManager, []string{"perfCounter"}, &pm); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range Counters {
+		wanted[name] = true
+	}
+
+	names := make(map[int32]string)
+	var ids []types.PerfMetricId
+	for _, info := range pm.PerfCounter {
+		name := fmt.Sprintf("%s.%s.%s", info.GroupInfo.GetElementDescription().Key, info.NameInfo.GetElementDescription().Key, info.RollupType)
+		if !wanted[name] {
+			continue
+		}
+
+		names[info.Key] = name
+		ids = append(ids, types.PerfMetricId{CounterId: info.Key, Instance: "*"})
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("performance manager has none of the counters %v", Counters)
+	}
+
+	p.names = names
+	p.ids = ids
+	return ids, nil
+}
+
+func (p *perfSampler) Sample(ctx context.Context, vm types.ManagedObjectReference) (*Sample, error) {
+	ids, err := p.metricIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := types.QueryPerf{
+		This: *p.client.ServiceContent.PerfManager,
+		QuerySpec: []types.PerfQuerySpec{
+			{
+				Entity:     vm,
+				MaxSample:  1,
+				IntervalId: realtimeInterval,
+				MetricId:   ids,
+			},
+		},
+	}
+
+	res, err := methods.QueryPerf(ctx, p.client, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, base := range res.Returnval {
+		em, ok := base.(*types.PerfEntityMetric)
+		if !ok || len(em.SampleInfo) == 0 {
+			continue
+		}
+
+		info := em.SampleInfo[len(em.SampleInfo)-1]
+		s := &Sample{
+			Time:     info.Timestamp,
+			Interval: time.Duration(info.Interval) * time.Second,
+			Values:   make(map[string]map[string]int64),
+		}
+
+		for _, series := range em.Value {
+			is, ok := series.(*types.PerfMetricIntSeries)
+			if !ok || len(is.Value) == 0 {
+				continue
+			}
+
+			name := p.names[is.Id.CounterId]
+			if s.Values[name] == nil {
+				s.Values[name] = make(map[string]int64)
+			}
+			s.Values[name][is.Id.Instance] = is.Value[len(is.Value)-1]
+		}
+
+		return s, nil
+	}
+
+	// VMs that are powered off have no realtime samples
+	return nil, fmt.Errorf("no performance samples for %s", vm)
+}
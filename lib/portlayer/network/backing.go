@@ -0,0 +1,133 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Backing describes the vSphere network backing a scope, so that scopes can be correlated with
+// the vSphere networking configuration
+type Backing struct {
+	// Name of the portgroup
+	Name string
+	// Name of the distributed switch, or of the standard vSwitch on the hosts
+	Switch string
+	// VLAN ID, zero if untagged or not a single VLAN
+	VLAN int32
+	// Managed object reference of the portgroup
+	Moref string
+}
+
+// Backing looks up the vSphere network backing the scope, which is nil for scopes without one
+func (s *Scope) Backing(ctx context.Context) (*Backing, error) {
+	n := s.Network()
+	if n == nil {
+		return nil, nil
+	}
+
+	switch n := n.(type) {
+	case *object.DistributedVirtualPortgroup:
+		return dvsBacking(ctx, n)
+	case *object.Network:
+		return standardBacking(ctx, n)
+	default:
+		return &Backing{Moref: n.Reference().String()}, nil
+	}
+}
+
+// dvsBacking describes a distributed portgroup
+func dvsBacking(ctx context.Context, pg *object.DistributedVirtualPortgroup) (*Backing, error) {
+	var mpg mo.DistributedVirtualPortgroup
+	if err := pg.Properties(ctx, pg.Reference(), []string{"config"}, &mpg); err != nil {
+		return nil, fmt.Errorf("failed to get portgroup %s config: %s", pg.Reference(), err)
+	}
+
+	b := &Backing{
+		Name:  mpg.Config.Name,
+		VLAN:  portgroupVLAN(mpg.Config),
+		Moref: pg.Reference().String(),
+	}
+
+	if mpg.Config.DistributedVirtualSwitch != nil {
+		var dvs mo.DistributedVirtualSwitch
+		if err := pg.Properties(ctx, *mpg.Config.DistributedVirtualSwitch, []string{"name"}, &dvs); err != nil {
+			return nil, fmt.Errorf("failed to get switch of portgroup %s: %s", pg.Reference(), err)
+		}
+		b.Switch = dvs.Name
+	}
+
+	return b, nil
+}
+
+// portgroupVLAN returns the VLAN of a distributed portgroup, if it has a single VLAN
+func portgroupVLAN(config types.DVPortgroupConfigInfo) int32 {
+	setting, ok := config.DefaultPortConfig.(*types.VMwareDVSPortSetting)
+	if !ok {
+		return 0
+	}
+
+	if spec, ok := setting.Vlan.(*types.VmwareDistributedVirtualSwitchVlanIdSpec); ok {
+		return spec.VlanId
+	}
+	return 0
+}
+
+// standardBacking describes a standard portgroup, whose switch and VLAN are taken from the first
+// host the portgroup is on, as they are configured per host
+func standardBacking(ctx context.Context, n *object.Network) (*Backing, error) {
+	var mn mo.Network
+	if err := n.Properties(ctx, n.Reference(), []string{"name", "host"}, &mn); err != nil {
+		return nil, fmt.Errorf("failed to get network %s: %s", n.Reference(), err)
+	}
+
+	b := &Backing{
+		Name:  mn.Name,
+		Moref: n.Reference().String(),
+	}
+	if len(mn.Host) == 0 {
+		return b, nil
+	}
+
+	var host mo.HostSystem
+	if err := n.Properties(ctx, mn.Host[0], []string{"config.network.portgroup"}, &host); err != nil {
+		return nil, fmt.Errorf("failed to get portgroups of host %s: %s", mn.Host[0], err)
+	}
+	if host.Config == nil || host.Config.Network == nil {
+		return b, nil
+	}
+
+	if spec := hostPortgroup(host.Config.Network.Portgroup, mn.Name); spec != nil {
+		b.Switch = spec.VswitchName
+		b.VLAN = spec.VlanId
+	}
+	return b, nil
+}
+
+// hostPortgroup finds the spec of the named portgroup on a host
+func hostPortgroup(pgs []types.HostPortGroup, name string) *types.HostPortGroupSpec {
+	for i := range pgs {
+		if pgs[i].Spec.Name == name {
+			return &pgs[i].Spec
+		}
+	}
+	return nil
+}
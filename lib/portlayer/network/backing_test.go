@@ -0,0 +1,64 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/pkg/uid"
+	"golang.org/x/net/context"
+)
+
+func TestPortgroupVLAN(t *testing.T) {
+	config := types.DVPortgroupConfigInfo{
+		DefaultPortConfig: &types.VMwareDVSPortSetting{
+			Vlan: &types.VmwareDistributedVirtualSwitchVlanIdSpec{VlanId: 100},
+		},
+	}
+	assert.EqualValues(t, 100, portgroupVLAN(config))
+
+	// trunks carry more than one VLAN
+	config.DefaultPortConfig = &types.VMwareDVSPortSetting{
+		Vlan: &types.VmwareDistributedVirtualSwitchTrunkVlanSpec{},
+	}
+	assert.EqualValues(t, 0, portgroupVLAN(config))
+
+	assert.EqualValues(t, 0, portgroupVLAN(types.DVPortgroupConfigInfo{}))
+}
+
+func TestHostPortgroup(t *testing.T) {
+	pgs := []types.HostPortGroup{
+		{Spec: types.HostPortGroupSpec{Name: "VM Network", VswitchName: "vSwitch0"}},
+		{Spec: types.HostPortGroupSpec{Name: "backend", VswitchName: "vSwitch1", VlanId: 20}},
+	}
+
+	spec := hostPortgroup(pgs, "backend")
+	if assert.NotNil(t, spec) {
+		assert.Equal(t, "vSwitch1", spec.VswitchName)
+		assert.EqualValues(t, 20, spec.VlanId)
+	}
+
+	assert.Nil(t, hostPortgroup(pgs, "frontend"))
+}
+
+func TestScopeBackingWithoutNetwork(t *testing.T) {
+	s := newScope(uid.New(), "foo", "bridge", nil, nil, nil, nil)
+
+	b, err := s.Backing(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, b)
+}
@@ -15,12 +15,15 @@
 package network
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/go-connections/nat"
@@ -29,7 +32,9 @@ import (
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/portlayer/constants"
+	"github.com/vmware/vic/lib/portlayer/event/events"
 	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/store"
 	"github.com/vmware/vic/lib/spec"
 	"github.com/vmware/vic/pkg/ip"
 	"github.com/vmware/vic/pkg/kvstore"
@@ -61,6 +66,9 @@ type Context struct {
 	defaultScope *Scope
 
 	kv kvstore.KeyValueStore
+
+	// external address manager, if any
+	external ExternalIPAM
 }
 
 type AddContainerOptions struct {
@@ -137,6 +145,9 @@ func NewContext(config *Configuration, kv kvstore.KeyValueStore) (*Context, erro
 		}
 
 		s.builtin = true
+		if err = configureScope(s, n); err != nil {
+			return nil, err
+		}
 	}
 
 	// load saved scopes in the kv store
@@ -173,11 +184,110 @@ func NewContext(config *Configuration, kv kvstore.KeyValueStore) (*Context, erro
 				}
 			}
 		}
+
+		ctx.loadReservations()
+		ctx.loadFirewalls()
 	}
 
 	return ctx, nil
 }
 
+// configureScope applies the IPAM and reserved addresses of a container network to its scope
+func configureScope(s *Scope, n *executor.ContainerNetwork) error {
+	switch n.IPAM {
+	case "", executor.IPAMStatic:
+	case executor.IPAMDHCP:
+		s.ipam = dhcpIPAM{}
+	case executor.IPAMDHCPRelay:
+		if len(n.DHCPServers) == 0 {
+			return fmt.Errorf("no DHCP servers to relay to for network %s", s.name)
+		}
+		s.ipam = newDHCPRelayIPAM(n.DHCPServers, &udpRelayTransport{})
+	default:
+		return fmt.Errorf("unknown IPAM %q for network %s", n.IPAM, s.name)
+	}
+
+	for _, addr := range n.Reserved {
+		if err := s.Reserve(addr); err != nil {
+			log.Warnf("skipping address reservation %s: %s", addr, err)
+		}
+	}
+
+	if err := s.SetFirewall(n.Ingress, n.Egress); err != nil {
+		return fmt.Errorf("network %s: %s", s.name, err)
+	}
+
+	return nil
+}
+
+// loadReservations restores the address reservations saved in the kv store
+func (c *Context) loadReservations() {
+	values, err := c.kv.List(`context\.reservations\..+`)
+	if err != nil {
+		if err != kvstore.ErrKeyNotFound {
+			log.Warnf("error listing address reservations from key value store: %s", err)
+		}
+		return
+	}
+
+	for k, v := range values {
+		var addrs []net.IP
+		if err := json.Unmarshal(v, &addrs); err != nil {
+			log.Warnf("error loading address reservations from key %s, skipping: %s", k, err)
+			continue
+		}
+
+		sn := strings.TrimPrefix(k, reservationsKey(""))
+		s, ok := c.scopes[sn]
+		if !ok {
+			log.Warnf("skipping address reservations for scope %s: scope not found", sn)
+			continue
+		}
+
+		for _, a := range addrs {
+			if err := s.Reserve(a); err != nil {
+				log.Warnf("skipping address reservation %s: %s", a, err)
+			}
+		}
+	}
+}
+
+// firewallPolicy is how the firewall rules of a scope are saved in the kv store
+type firewallPolicy struct {
+	Ingress []executor.FirewallRule `json:"ingress,omitempty"`
+	Egress  []executor.FirewallRule `json:"egress,omitempty"`
+}
+
+// loadFirewalls restores the firewall rules saved in the kv store
+func (c *Context) loadFirewalls() {
+	values, err := c.kv.List(`context\.firewall\..+`)
+	if err != nil {
+		if err != kvstore.ErrKeyNotFound {
+			log.Warnf("error listing firewall rules from key value store: %s", err)
+		}
+		return
+	}
+
+	for k, v := range values {
+		var p firewallPolicy
+		if err := json.Unmarshal(v, &p); err != nil {
+			log.Warnf("error loading firewall rules from key %s, skipping: %s", k, err)
+			continue
+		}
+
+		sn := strings.TrimPrefix(k, firewallKey(""))
+		s, ok := c.scopes[sn]
+		if !ok {
+			log.Warnf("skipping firewall rules for scope %s: scope not found", sn)
+			continue
+		}
+
+		if err := s.SetFirewall(p.Ingress, p.Egress); err != nil {
+			log.Warnf("skipping firewall rules for scope %s: %s", sn, err)
+		}
+	}
+}
+
 func reserveGateway(gateway net.IP, subnet *net.IPNet, spaces []*AddressSpace) (net.IP, error) {
 	defer trace.End(trace.Begin(""))
 	if ip.IsUnspecifiedSubnet(subnet) {
@@ -303,6 +413,7 @@ func (c *Context) addScope(s *Scope) error {
 		s.subnet = subnet
 	}
 
+	s.external = c.external
 	c.scopes[s.name] = s
 
 	return nil
@@ -329,6 +440,58 @@ func (c *Context) newScopeCommon(id uid.UID, name, scopeType string, subnet *net
 	return newScope, nil
 }
 
+// scopeMTU returns the MTU configured for the network backing the scope, zero if none is. Bridge
+// scopes all share the bridge network.
+func (c *Context) scopeMTU(s *Scope) int {
+	nn := s.Name()
+	if s.Type() == constants.BridgeScopeType {
+		nn = c.config.BridgeNetwork
+	}
+
+	if n := c.config.ContainerNetworks[nn]; n != nil {
+		return n.MTU
+	}
+	return 0
+}
+
+// scopeSearchDomains returns the DNS search domains of the container network backing the scope
+func (c *Context) scopeSearchDomains(s *Scope) []string {
+	if s.Type() != constants.ExternalScopeType {
+		return nil
+	}
+
+	if n := c.config.ContainerNetworks[s.Name()]; n != nil && len(n.SearchDomains) > 0 {
+		return append([]string(nil), n.SearchDomains...)
+	}
+	return nil
+}
+
+// scopeVLAN returns the VLAN ID tagged in the guest on the network backing the scope, zero if untagged.
+// Bridge scopes are never tagged.
+func (c *Context) scopeVLAN(s *Scope) int {
+	if s.Type() == constants.BridgeScopeType {
+		return 0
+	}
+
+	if n := c.config.ContainerNetworks[s.Name()]; n != nil {
+		return n.VLAN
+	}
+	return 0
+}
+
+// scopeTrustLevel returns the firewall policy applied in the guest on the network backing the scope, empty
+// for open. Bridge scopes are left open, as they are only reached through the VCH.
+func (c *Context) scopeTrustLevel(s *Scope) string {
+	if s.Type() == constants.BridgeScopeType {
+		return ""
+	}
+
+	if n := c.config.ContainerNetworks[s.Name()]; n != nil {
+		return n.TrustLevel
+	}
+	return ""
+}
+
 func (c *Context) newBridgeScope(id uid.UID, name string, subnet *net.IPNet, gateway net.IP, dns []net.IP, pools []string) (newScope *Scope, err error) {
 	defer trace.End(trace.Begin(""))
 	bnPG, ok := c.config.PortGroups[c.config.BridgeNetwork]
@@ -468,6 +631,14 @@ func scopeKey(sn string) string {
 	return fmt.Sprintf("context.scopes.%s", sn)
 }
 
+func reservationsKey(sn string) string {
+	return fmt.Sprintf("context.reservations.%s", sn)
+}
+
+func firewallKey(sn string) string {
+	return fmt.Sprintf("context.firewall.%s", sn)
+}
+
 func (c *Context) NewScope(ctx context.Context, scopeType, name string, subnet *net.IPNet, gateway net.IP, dns []net.IP, pools []string) (*Scope, error) {
 	defer trace.End(trace.Begin(""))
 
@@ -481,9 +652,18 @@ func (c *Context) NewScope(ctx context.Context, scopeType, name string, subnet *
 	defer func() {
 		if err != nil {
 			c.deleteScope(s)
+			store.Names().Release(ctx, store.NetworkName, s.Name(), s.ID().String())
 		}
 	}()
 
+	// the name is held until the scope is removed
+	if err = store.Names().Reserve(ctx, store.NetworkName, s.Name(), s.ID().String()); err != nil {
+		if _, ok := err.(store.NameReservedError); ok {
+			return nil, DuplicateResourceError{resID: name}
+		}
+		return nil, err
+	}
+
 	// save the scope in the kv store
 	if c.kv != nil {
 		var d []byte
@@ -692,6 +872,11 @@ func (c *Context) bindContainer(h *exec.Handle) ([]*Endpoint, error) {
 		ne.Network.Gateway = net.IPNet{IP: e.Gateway(), Mask: e.Subnet().Mask}
 		ne.Network.Nameservers = make([]net.IP, len(s.dns))
 		copy(ne.Network.Nameservers, s.dns)
+		ne.Network.SearchDomains = c.scopeSearchDomains(s)
+		ne.Network.MTU = c.scopeMTU(s)
+		ne.Network.VLAN = c.scopeVLAN(s)
+		ne.Network.TrustLevel = c.scopeTrustLevel(s)
+		ne.Network.Ingress, ne.Network.Egress = s.Firewall()
 
 		// mark the external network as default
 		if !defaultMarked && e.Scope().Type() == constants.ExternalScopeType {
@@ -1123,13 +1308,7 @@ func (c *Context) ContainerByAddr(addr net.IP) *Endpoint {
 	c.Lock()
 	defer c.Unlock()
 
-	for _, s := range c.scopes {
-		if e := s.ContainerByAddr(addr); e != nil {
-			return e
-		}
-	}
-
-	return nil
+	return c.endpointByAddr(addr)
 }
 
 func (c *Context) DeleteScope(ctx context.Context, name string) error {
@@ -1148,24 +1327,119 @@ func (c *Context) DeleteScope(ctx context.Context, name string) error {
 	}
 
 	if s.builtin {
-		return fmt.Errorf("cannot remove builtin scope")
+		return ForbiddenError{error: fmt.Errorf("cannot remove builtin scope %s", s.Name())}
+	}
+
+	if id := attachedContainer(s); id != "" {
+		return ForbiddenError{error: fmt.Errorf("%s has active endpoints, container %s is attached", s.Name(), id)}
 	}
 
-	if len(s.Endpoints()) != 0 {
-		return fmt.Errorf("%s has active endpoints", s.Name())
+	return c.removeScope(ctx, s)
+}
+
+// attachedContainer returns the ID of a container attached to the scope, whether it is running or not,
+// or "" if no containers are attached
+func attachedContainer(s *Scope) string {
+	for _, e := range s.Endpoints() {
+		return e.Container().ID().String()
+	}
+
+	if exec.Containers == nil {
+		return ""
 	}
 
+	for _, con := range exec.Containers.Containers(nil) {
+		if con.ExecConfig == nil {
+			continue
+		}
+
+		if _, ok := con.ExecConfig.Networks[s.Name()]; ok {
+			return con.ExecConfig.ID
+		}
+	}
+
+	return ""
+}
+
+// PruneScopes removes the user defined scopes that no containers are attached to, returning the
+// names of the scopes removed
+func (c *Context) PruneScopes(ctx context.Context) ([]string, error) {
+	defer trace.End(trace.Begin(""))
+
+	c.Lock()
+	defer c.Unlock()
+
+	var pruned []string
+	for _, s := range c.scopes {
+		if s.builtin || attachedContainer(s) != "" {
+			continue
+		}
+
+		if err := c.removeScope(ctx, s); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, s.Name())
+	}
+
+	sort.Strings(pruned)
+	return pruned, nil
+}
+
+// removeScope removes the persisted state of a scope along with the scope, and publishes its removal
+func (c *Context) removeScope(ctx context.Context, s *Scope) error {
 	if c.kv != nil {
-		if err = c.kv.Delete(ctx, scopeKey(s.Name())); err != nil && err != kvstore.ErrKeyNotFound {
+		if err := c.kv.Delete(ctx, scopeKey(s.Name())); err != nil && err != kvstore.ErrKeyNotFound {
+			return err
+		}
+		if err := c.kv.Delete(ctx, reservationsKey(s.Name())); err != nil && err != kvstore.ErrKeyNotFound {
 			return err
 		}
+		if err := c.kv.Delete(ctx, firewallKey(s.Name())); err != nil && err != kvstore.ErrKeyNotFound {
+			return err
+		}
+	}
+
+	if err := store.Names().Release(ctx, store.NetworkName, s.Name(), s.ID().String()); err != nil {
+		log.Warnf("Unable to release name of scope %s: %s", s.Name(), err)
 	}
 
 	c.deleteScope(s)
+	publishScopeEvent(s.Name(), events.NetworkRemoved)
 	return nil
 }
 
+// reconcileNames reserves the names of the scopes and releases those of scopes that no longer exist
+func (c *Context) reconcileNames(ctx context.Context) error {
+	c.Lock()
+	defer c.Unlock()
+
+	inUse := make(map[string]string)
+	for name, s := range c.scopes {
+		inUse[name] = s.ID().String()
+	}
+
+	return store.Names().Reconcile(ctx, store.NetworkName, inUse, 0)
+}
+
+// publishScopeEvent publishes a change to the scope with name
+func publishScopeEvent(name, event string) {
+	if exec.Config.EventManager == nil {
+		return
+	}
+
+	exec.Config.EventManager.Publish(&events.NetworkEvent{
+		BaseEvent: &events.BaseEvent{
+			Ref:         name,
+			CreatedTime: time.Now(),
+			Event:       event,
+			Detail:      fmt.Sprintf("scope %s %s", name, strings.ToLower(event)),
+		},
+	})
+}
+
 func (c *Context) deleteScope(s *Scope) {
+	c.releaseSubnet(s)
+
 	if s.Type() == constants.BridgeScopeType {
 		// remove gateway ip from bridge interface
 		addr := net.IPNet{IP: s.Gateway(), Mask: s.Subnet().Mask}
@@ -1179,6 +1453,198 @@ func (c *Context) deleteScope(s *Scope) {
 	delete(c.scopes, s.Name())
 }
 
+// releaseSubnet returns the subnet of a scope to the default bridge pool, if it was reserved from it
+func (c *Context) releaseSubnet(s *Scope) {
+	subnet := s.Subnet()
+	if ip.IsUnspecifiedSubnet(subnet) {
+		return
+	}
+
+	pool := c.defaultBridgePool.Network
+	if !pool.Contains(subnet.IP) || !pool.Contains(highestIP4(subnet)) {
+		return
+	}
+
+	space := NewAddressSpaceFromNetwork(subnet)
+	space.Parent = c.defaultBridgePool
+	if err := c.defaultBridgePool.ReleaseIP4Range(space); err != nil {
+		log.Warnf("could not release subnet %s of scope %s: %s", subnet, s.Name(), err)
+	}
+}
+
+// SetExternalIPAM sets the external address manager consulted when containers are
+// assigned addresses on scopes with a static pool
+func (c *Context) SetExternalIPAM(ipam ExternalIPAM) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.external = ipam
+	for _, s := range c.scopes {
+		s.Lock()
+		s.external = ipam
+		s.Unlock()
+	}
+}
+
+// ReserveIP reserves the address on the scope, so that it is only assigned
+// to a container that asks for it
+func (c *Context) ReserveIP(ctx context.Context, scope string, addr net.IP) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("%s %s", scope, addr)))
+
+	c.Lock()
+	defer c.Unlock()
+
+	s, err := c.resolveScope(scope)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return ResourceNotFoundError{error: fmt.Errorf("scope %s not found", scope)}
+	}
+
+	if err = s.Reserve(addr); err != nil {
+		return err
+	}
+
+	if err = c.saveReservations(ctx, s); err != nil {
+		s.Release(addr)
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseIP returns a reserved address to the pools of the scope
+func (c *Context) ReleaseIP(ctx context.Context, scope string, addr net.IP) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("%s %s", scope, addr)))
+
+	c.Lock()
+	defer c.Unlock()
+
+	s, err := c.resolveScope(scope)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return ResourceNotFoundError{error: fmt.Errorf("scope %s not found", scope)}
+	}
+
+	if err = s.Release(addr); err != nil {
+		return err
+	}
+
+	return c.saveReservations(ctx, s)
+}
+
+// Reservations returns the reserved addresses of the scope
+func (c *Context) Reservations(scope string) ([]net.IP, error) {
+	defer trace.End(trace.Begin(scope))
+
+	c.Lock()
+	defer c.Unlock()
+
+	s, err := c.resolveScope(scope)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ResourceNotFoundError{error: fmt.Errorf("scope %s not found", scope)}
+	}
+
+	return s.Reservations(), nil
+}
+
+func (c *Context) saveReservations(ctx context.Context, s *Scope) error {
+	if c.kv == nil {
+		return nil
+	}
+
+	addrs := s.Reservations()
+	if len(addrs) == 0 {
+		if err := c.kv.Delete(ctx, reservationsKey(s.Name())); err != nil && err != kvstore.ErrKeyNotFound {
+			return err
+		}
+		return nil
+	}
+
+	d, err := json.Marshal(addrs)
+	if err != nil {
+		return err
+	}
+
+	return c.kv.Put(ctx, reservationsKey(s.Name()), d)
+}
+
+// SetFirewall replaces the ingress and egress firewall rules of the scope. The rules
+// apply to containers from the next time they are bound to the scope.
+func (c *Context) SetFirewall(ctx context.Context, scope string, ingress, egress []executor.FirewallRule) error {
+	defer trace.End(trace.Begin(scope))
+
+	c.Lock()
+	defer c.Unlock()
+
+	s, err := c.resolveScope(scope)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return ResourceNotFoundError{error: fmt.Errorf("scope %s not found", scope)}
+	}
+
+	oldIngress, oldEgress := s.Firewall()
+	if err = s.SetFirewall(ingress, egress); err != nil {
+		return err
+	}
+
+	if err = c.saveFirewall(ctx, s); err != nil {
+		s.SetFirewall(oldIngress, oldEgress)
+		return err
+	}
+
+	return nil
+}
+
+// Firewall returns the ingress and egress firewall rules of the scope
+func (c *Context) Firewall(scope string) (ingress, egress []executor.FirewallRule, err error) {
+	defer trace.End(trace.Begin(scope))
+
+	c.Lock()
+	defer c.Unlock()
+
+	s, err := c.resolveScope(scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s == nil {
+		return nil, nil, ResourceNotFoundError{error: fmt.Errorf("scope %s not found", scope)}
+	}
+
+	ingress, egress = s.Firewall()
+	return ingress, egress, nil
+}
+
+func (c *Context) saveFirewall(ctx context.Context, s *Scope) error {
+	if c.kv == nil {
+		return nil
+	}
+
+	var p firewallPolicy
+	p.Ingress, p.Egress = s.Firewall()
+	if len(p.Ingress) == 0 && len(p.Egress) == 0 {
+		if err := c.kv.Delete(ctx, firewallKey(s.Name())); err != nil && err != kvstore.ErrKeyNotFound {
+			return err
+		}
+		return nil
+	}
+
+	d, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return c.kv.Put(ctx, firewallKey(s.Name()), d)
+}
+
 func atoiOrZero(a string) int32 {
 	i, _ := strconv.Atoi(a)
 	return int32(i)
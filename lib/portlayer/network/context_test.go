@@ -240,6 +240,93 @@ func TestMapExternalNetworks(t *testing.T) {
 	}
 }
 
+func TestScopeMTU(t *testing.T) {
+	conf := testConfig()
+	conf.ContainerNetworks["bridge"].MTU = 1450
+	conf.ContainerNetworks["bar7"].MTU = 9000
+	ctx, err := NewContext(conf, nil)
+	if err != nil {
+		t.Fatalf("NewContext() => (nil, %s), want (ctx, nil)", err)
+	}
+
+	// user created bridge scopes share the MTU of the bridge network
+	s, err := ctx.NewScope(context.TODO(), constants.BridgeScopeType, "mtu-bridge", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewScope() => (nil, %s), want (s, nil)", err)
+	}
+	assert.Equal(t, 1450, ctx.scopeMTU(s))
+	assert.Equal(t, 1450, ctx.scopeMTU(ctx.DefaultScope()))
+
+	for name, mtu := range map[string]int{"bar7": 9000, "bar71": 0} {
+		scopes, err := ctx.findScopes(&name)
+		if err != nil || len(scopes) != 1 {
+			t.Fatalf("external network %s was not loaded", name)
+		}
+		assert.Equal(t, mtu, ctx.scopeMTU(scopes[0]), "MTU of %s", name)
+	}
+}
+
+func TestScopeVLAN(t *testing.T) {
+	conf := testConfig()
+	conf.ContainerNetworks["bridge"].VLAN = 10
+	conf.ContainerNetworks["bar7"].VLAN = 42
+	ctx, err := NewContext(conf, nil)
+	if err != nil {
+		t.Fatalf("NewContext() => (nil, %s), want (ctx, nil)", err)
+	}
+
+	// the bridge network is never tagged in the guest
+	assert.Equal(t, 0, ctx.scopeVLAN(ctx.DefaultScope()))
+
+	for name, vlan := range map[string]int{"bar7": 42, "bar71": 0} {
+		scopes, err := ctx.findScopes(&name)
+		if err != nil || len(scopes) != 1 {
+			t.Fatalf("external network %s was not loaded", name)
+		}
+		assert.Equal(t, vlan, ctx.scopeVLAN(scopes[0]), "VLAN of %s", name)
+	}
+}
+
+func TestScopeTrustLevel(t *testing.T) {
+	conf := testConfig()
+	conf.ContainerNetworks["bridge"].TrustLevel = executor.TrustClosed
+	conf.ContainerNetworks["bar7"].TrustLevel = executor.TrustPublished
+	ctx, err := NewContext(conf, nil)
+	if err != nil {
+		t.Fatalf("NewContext() => (nil, %s), want (ctx, nil)", err)
+	}
+
+	// the bridge network is only reached through the VCH, so is left open
+	assert.Equal(t, "", ctx.scopeTrustLevel(ctx.DefaultScope()))
+
+	for name, level := range map[string]string{"bar7": executor.TrustPublished, "bar71": ""} {
+		scopes, err := ctx.findScopes(&name)
+		if err != nil || len(scopes) != 1 {
+			t.Fatalf("external network %s was not loaded", name)
+		}
+		assert.Equal(t, level, ctx.scopeTrustLevel(scopes[0]), "firewall policy of %s", name)
+	}
+}
+
+func TestScopeSearchDomains(t *testing.T) {
+	conf := testConfig()
+	conf.ContainerNetworks["bar7"].SearchDomains = []string{"bar7.example.com", "example.com"}
+	ctx, err := NewContext(conf, nil)
+	if err != nil {
+		t.Fatalf("NewContext() => (nil, %s), want (ctx, nil)", err)
+	}
+
+	assert.Empty(t, ctx.scopeSearchDomains(ctx.DefaultScope()))
+
+	for name, domains := range map[string][]string{"bar7": {"bar7.example.com", "example.com"}, "bar71": nil} {
+		scopes, err := ctx.findScopes(&name)
+		if err != nil || len(scopes) != 1 {
+			t.Fatalf("external network %s was not loaded", name)
+		}
+		assert.Equal(t, domains, ctx.scopeSearchDomains(scopes[0]), "search domains of %s", name)
+	}
+}
+
 func TestContextNewScope(t *testing.T) {
 	kv := &kvstore.MockKeyValueStore{}
 	kv.On("List", mock.Anything).Return(nil, nil)
@@ -248,8 +335,10 @@ func TestContextNewScope(t *testing.T) {
 		t.Fatalf("NewContext() => (nil, %s), want (ctx, nil)", err)
 	}
 
-	kv.AssertNumberOfCalls(t, "List", 1)
+	kv.AssertNumberOfCalls(t, "List", 3)
 	kv.AssertCalled(t, "List", `context\.scopes\..+`)
+	kv.AssertCalled(t, "List", `context\.reservations\..+`)
+	kv.AssertCalled(t, "List", `context\.firewall\..+`)
 
 	var tests = []struct {
 		in  params
@@ -1057,8 +1146,8 @@ func TestDeleteScope(t *testing.T) {
 		err  error
 	}{
 		{"", ResourceNotFoundError{}},
-		{ctx.DefaultScope().Name(), fmt.Errorf("cannot delete builtin scopes")},
-		{bar.Name(), fmt.Errorf("cannot delete scope with bound endpoints")},
+		{ctx.DefaultScope().Name(), ForbiddenError{}},
+		{bar.Name(), ForbiddenError{}},
 		// full name
 		{foo.Name(), nil},
 		// full id
@@ -1083,7 +1172,8 @@ func TestDeleteScope(t *testing.T) {
 			continue
 		}
 
-		calls++
+		// the scope, its reservations and its firewall rules are removed
+		calls += 3
 		kv.AssertNumberOfCalls(t, "Delete", calls)
 		scopes, err := ctx.findScopes(&te.name)
 		if _, ok := err.(ResourceNotFoundError); !ok || len(scopes) != 0 {
@@ -1092,6 +1182,52 @@ func TestDeleteScope(t *testing.T) {
 	}
 }
 
+func TestPruneScopes(t *testing.T) {
+	kv := &kvstore.MockKeyValueStore{}
+	kv.On("List", mock.Anything).Return(nil, nil)
+	kv.On("Put", context.TODO(), mock.Anything, mock.Anything).Return(nil)
+	kv.On("Delete", context.TODO(), mock.Anything).Return(nil)
+	ctx, err := NewContext(testConfig(), kv)
+	if err != nil {
+		t.Fatalf("NewContext() => (nil, %s), want (ctx, nil)", err)
+	}
+
+	var subnets []string
+	for _, name := range []string{"foo", "bar", "baz"} {
+		s, err := ctx.NewScope(context.TODO(), constants.BridgeScopeType, name, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("ctx.NewScope(%s, %q, nil, nil, nil, nil) => (nil, %#v), want (s, nil)", constants.BridgeScopeType, name, err)
+		}
+		subnets = append(subnets, s.Subnet().String())
+	}
+
+	// bar has a bound endpoint
+	h := newContainer("container")
+	ctx.AddContainer(h, &AddContainerOptions{Scope: "bar"})
+	ctx.BindContainer(h)
+
+	pruned, err := ctx.PruneScopes(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baz", "foo"}, pruned)
+	kv.AssertNumberOfCalls(t, "Delete", 6)
+
+	scopes, err := ctx.Scopes(context.TODO(), nil)
+	assert.NoError(t, err)
+	var names []string
+	for _, s := range scopes {
+		names = append(names, s.Name())
+	}
+	assert.Contains(t, names, "bar")
+	assert.NotContains(t, names, "foo")
+	assert.NotContains(t, names, "baz")
+
+	// the subnets of the pruned scopes are available again
+	s, err := ctx.NewScope(context.TODO(), constants.BridgeScopeType, "qux", nil, nil, nil, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, subnets[0], s.Subnet().String())
+	}
+}
+
 func TestAliases(t *testing.T) {
 	ctx, err := NewContext(testConfig(), nil)
 	assert.NoError(t, err)
@@ -1269,6 +1405,8 @@ func TestLoadScopesFromKV(t *testing.T) {
 	for _, e := range []error{nil, kvstore.ErrKeyNotFound, assert.AnError} {
 		kv := &kvstore.MockKeyValueStore{}
 		kv.On("List", `context\.scopes\..+`).Return(nil, e)
+		kv.On("List", `context\.reservations\..+`).Return(nil, kvstore.ErrKeyNotFound)
+		kv.On("List", `context\.firewall\..+`).Return(nil, kvstore.ErrKeyNotFound)
 		ctx, err := NewContext(testConfig(), kv)
 		assert.NoError(t, err)
 		assert.NotNil(t, ctx)
@@ -1283,6 +1421,8 @@ func TestLoadScopesFromKV(t *testing.T) {
 	// kv.List returns kvdata
 	kv := &kvstore.MockKeyValueStore{}
 	kv.On("List", `context\.scopes\..+`).Return(kvdata, nil)
+	kv.On("List", `context\.reservations\..+`).Return(nil, kvstore.ErrKeyNotFound)
+	kv.On("List", `context\.firewall\..+`).Return(nil, kvstore.ErrKeyNotFound)
 	ctx, err := NewContext(testConfig(), kv)
 	assert.NoError(t, err)
 	assert.NotNil(t, ctx)
@@ -1338,3 +1478,110 @@ func TestKVStoreSetFails(t *testing.T) {
 	assert.IsType(t, ResourceNotFoundError{}, err)
 	assert.Len(t, scs, 0)
 }
+
+func TestReservationsKV(t *testing.T) {
+	kv := &kvstore.MockKeyValueStore{}
+	kv.On("List", mock.Anything).Return(nil, nil)
+	kv.On("Put", context.TODO(), mock.Anything, mock.Anything).Return(nil)
+	kv.On("Delete", context.TODO(), mock.Anything).Return(nil)
+
+	ctx, err := NewContext(testConfig(), kv)
+	assert.NoError(t, err)
+
+	sn := ctx.defaultScope.Name()
+	addr := net.ParseIP("172.16.0.10")
+
+	assert.NoError(t, ctx.ReserveIP(context.TODO(), sn, addr))
+	kv.AssertCalled(t, "Put", context.TODO(), reservationsKey(sn), []byte(`["172.16.0.10"]`))
+
+	_, err = ctx.Reservations("missing")
+	assert.IsType(t, ResourceNotFoundError{}, err)
+
+	// reservations are restored from the kv store
+	kv2 := &kvstore.MockKeyValueStore{}
+	kv2.On("List", `context\.scopes\..+`).Return(nil, nil)
+	kv2.On("List", `context\.reservations\..+`).Return(map[string][]byte{
+		reservationsKey(sn):        []byte(`["172.16.0.10"]`),
+		reservationsKey("missing"): []byte(`["172.16.0.11"]`),
+	}, nil)
+	kv2.On("List", `context\.firewall\..+`).Return(nil, nil)
+
+	ctx2, err := NewContext(testConfig(), kv2)
+	assert.NoError(t, err)
+
+	res, err := ctx2.Reservations(sn)
+	assert.NoError(t, err)
+	if assert.Len(t, res, 1) {
+		assert.True(t, res[0].Equal(addr))
+	}
+
+	// the key is removed with the last reservation
+	assert.NoError(t, ctx.ReleaseIP(context.TODO(), sn, addr))
+	kv.AssertCalled(t, "Delete", context.TODO(), reservationsKey(sn))
+	assert.IsType(t, ResourceNotFoundError{}, ctx.ReleaseIP(context.TODO(), sn, addr))
+}
+
+func TestFirewallKV(t *testing.T) {
+	kv := &kvstore.MockKeyValueStore{}
+	kv.On("List", mock.Anything).Return(nil, nil)
+	kv.On("Put", context.TODO(), mock.Anything, mock.Anything).Return(nil)
+	kv.On("Delete", context.TODO(), mock.Anything).Return(nil)
+
+	ctx, err := NewContext(testConfig(), kv)
+	assert.NoError(t, err)
+
+	sn := ctx.defaultScope.Name()
+	ingress := []executor.FirewallRule{{Action: executor.FirewallAllow, Protocol: "tcp", Ports: "22"}}
+	egress := []executor.FirewallRule{{Action: executor.FirewallDeny, Remote: "10.0.0.0/8"}}
+
+	assert.NoError(t, ctx.SetFirewall(context.TODO(), sn, ingress, egress))
+	kv.AssertCalled(t, "Put", context.TODO(), firewallKey(sn), mock.Anything)
+
+	assert.Error(t, ctx.SetFirewall(context.TODO(), sn, []executor.FirewallRule{{Action: "reject"}}, nil))
+	_, _, err = ctx.Firewall("missing")
+	assert.IsType(t, ResourceNotFoundError{}, err)
+
+	in, out, err := ctx.Firewall(sn)
+	assert.NoError(t, err)
+	assert.Equal(t, ingress, in)
+	assert.Equal(t, egress, out)
+
+	// the rules are passed on to containers bound to the scope
+	h := newContainer("foo")
+	options := &AddContainerOptions{Scope: sn}
+	assert.NoError(t, ctx.AddContainer(h, options))
+	_, err = ctx.BindContainer(h)
+	assert.NoError(t, err)
+	ne := h.ExecConfig.Networks[sn]
+	if assert.NotNil(t, ne) {
+		assert.Equal(t, ingress, ne.Network.Ingress)
+		assert.Equal(t, egress, ne.Network.Egress)
+	}
+
+	// rules are restored from the kv store
+	var saved []byte
+	for _, c := range kv.Calls {
+		if c.Method == "Put" && c.Arguments.String(1) == firewallKey(sn) {
+			saved = c.Arguments.Get(2).([]byte)
+		}
+	}
+
+	kv2 := &kvstore.MockKeyValueStore{}
+	kv2.On("List", `context\.firewall\..+`).Return(map[string][]byte{
+		firewallKey(sn):        saved,
+		firewallKey("missing"): saved,
+	}, nil)
+	kv2.On("List", mock.Anything).Return(nil, nil)
+
+	ctx2, err := NewContext(testConfig(), kv2)
+	assert.NoError(t, err)
+
+	in, out, err = ctx2.Firewall(sn)
+	assert.NoError(t, err)
+	assert.Equal(t, ingress, in)
+	assert.Equal(t, egress, out)
+
+	// the key is removed with the last rule
+	assert.NoError(t, ctx.SetFirewall(context.TODO(), sn, nil, nil))
+	kv.AssertCalled(t, "Delete", context.TODO(), firewallKey(sn))
+}
@@ -0,0 +1,360 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/d2g/dhcp4"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/pkg/ip"
+)
+
+const (
+	// relayTimeout is how long a DHCP server is given to answer a relayed message
+	relayTimeout = 5 * time.Second
+	// relayRetryInterval is how long a failed lease renewal waits before it is retried
+	relayRetryInterval = time.Minute
+	// defaultRelayLeaseTime is assumed for leases that do not state their duration
+	defaultRelayLeaseTime = time.Hour
+
+	dhcpServerPort = 67
+
+	// optionSubnetSelection (RFC 3011) names the subnet to lease from, which otherwise is that of the relay
+	optionSubnetSelection dhcp4.OptionCode = 118
+)
+
+// relayTransport exchanges relayed DHCP messages with a server
+type relayTransport interface {
+	// Exchange sends p to server and, if reply is set, returns the first reply with the same transaction ID
+	Exchange(ctx context.Context, server net.IP, p dhcp4.Packet, reply bool) (dhcp4.Packet, error)
+}
+
+// dhcpRelayIPAM leases container addresses from DHCP servers on behalf of the containers, relaying the
+// requests from the VCH with the subnet selection option so that the servers lease from the subnet of the
+// scope rather than that of the VCH. Leased addresses are assigned to the containers statically, and renewed
+// for as long as the containers hold them.
+type dhcpRelayIPAM struct {
+	servers   []net.IP
+	transport relayTransport
+
+	m sync.Mutex
+	// leases held, keyed by container ID
+	leases map[string]*relayLease
+}
+
+// relayLease is an address leased for a container
+type relayLease struct {
+	container string
+	link      net.IP
+	addr      net.IP
+	server    net.IP
+	duration  time.Duration
+	renewal   *time.Timer
+}
+
+func newDHCPRelayIPAM(servers []net.IP, transport relayTransport) *dhcpRelayIPAM {
+	return &dhcpRelayIPAM{
+		servers:   servers,
+		transport: transport,
+		leases:    make(map[string]*relayLease),
+	}
+}
+
+func (r *dhcpRelayIPAM) Dynamic() bool {
+	return false
+}
+
+// Reserve leases an address for the endpoint, asking for the one it has if it is set so that containers
+// keep their address across restarts of the port layer
+func (r *dhcpRelayIPAM) Reserve(s *Scope, e *Endpoint) error {
+	if ip.IsUnspecifiedIP(s.gateway) {
+		return fmt.Errorf("scope %s has no gateway to lease addresses for", s.name)
+	}
+
+	var requested net.IP
+	if !ip.IsUnspecifiedIP(e.ip) {
+		requested = e.ip
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(len(r.servers)+1)*relayTimeout)
+	defer cancel()
+
+	l, err := r.lease(ctx, e.container.id.String(), s.gateway, requested)
+	if err != nil {
+		return err
+	}
+
+	if s.subnet != nil && !s.subnet.Contains(l.addr) {
+		r.release(l)
+		return fmt.Errorf("DHCP server %s leased %s, which is not in subnet %s of scope %s", l.server, l.addr, s.subnet, s.name)
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if old := r.leases[l.container]; old != nil {
+		old.renewal.Stop()
+	}
+	r.leases[l.container] = l
+	r.scheduleRenewal(l, l.duration/2)
+
+	e.ip = l.addr
+	return nil
+}
+
+func (r *dhcpRelayIPAM) Release(s *Scope, e *Endpoint) error {
+	id := e.container.id.String()
+
+	r.m.Lock()
+	l := r.leases[id]
+	delete(r.leases, id)
+	r.m.Unlock()
+
+	if l == nil {
+		return fmt.Errorf("no address leased for container %s on scope %s", id, s.name)
+	}
+
+	l.renewal.Stop()
+	r.release(l)
+
+	if !e.static {
+		e.ip = net.IPv4(0, 0, 0, 0)
+	}
+	return nil
+}
+
+// lease obtains a lease for the container from the first server that grants one
+func (r *dhcpRelayIPAM) lease(ctx context.Context, container string, link, requested net.IP) (*relayLease, error) {
+	var errs []string
+	for _, server := range r.servers {
+		l, err := r.leaseFrom(ctx, server, container, link, requested)
+		if err == nil {
+			return l, nil
+		}
+
+		log.Warnf("Unable to lease an address for container %s from %s: %s", container, server, err)
+		errs = append(errs, fmt.Sprintf("%s: %s", server, err))
+	}
+
+	return nil, fmt.Errorf("unable to lease an address for container %s: %s", container, strings.Join(errs, ", "))
+}
+
+// leaseFrom obtains a lease from server, requesting the given address directly if it is set and
+// discovering one otherwise
+func (r *dhcpRelayIPAM) leaseFrom(ctx context.Context, server net.IP, container string, link, requested net.IP) (*relayLease, error) {
+	var opts []dhcp4.Option
+	if requested == nil {
+		offer, err := r.exchange(ctx, server, relayPacket(dhcp4.Discover, container, link, nil), dhcp4.Offer)
+		if err != nil {
+			return nil, err
+		}
+
+		requested = offer.YIAddr()
+		if id := offer.ParseOptions()[dhcp4.OptionServerIdentifier]; id != nil {
+			opts = append(opts, dhcp4.Option{Code: dhcp4.OptionServerIdentifier, Value: id})
+		}
+	}
+
+	opts = append(opts, dhcp4.Option{Code: dhcp4.OptionRequestedIPAddress, Value: requested.To4()})
+	ack, err := r.exchange(ctx, server, relayPacket(dhcp4.Request, container, link, nil, opts...), dhcp4.ACK)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRelayLease(ack, server, container, link), nil
+}
+
+// renew extends the lease with the server that granted it
+func (r *dhcpRelayIPAM) renew(l *relayLease) error {
+	ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+	defer cancel()
+
+	ack, err := r.exchange(ctx, l.server, relayPacket(dhcp4.Request, l.container, l.link, l.addr), dhcp4.ACK)
+	if err != nil {
+		return err
+	}
+
+	renewed := newRelayLease(ack, l.server, l.container, l.link)
+	if !renewed.addr.Equal(l.addr) {
+		return fmt.Errorf("DHCP server %s renewed the lease with address %s instead of %s", l.server, renewed.addr, l.addr)
+	}
+
+	l.duration = renewed.duration
+	return nil
+}
+
+// scheduleRenewal renews the lease after d, and again at half of its duration for as long as it is
+// held. It must be called with r.m held.
+func (r *dhcpRelayIPAM) scheduleRenewal(l *relayLease, d time.Duration) {
+	l.renewal = time.AfterFunc(d, func() {
+		r.m.Lock()
+		defer r.m.Unlock()
+
+		if r.leases[l.container] != l {
+			// released in the meantime
+			return
+		}
+
+		if err := r.renew(l); err != nil {
+			log.Errorf("Unable to renew lease of %s for container %s: %s", l.addr, l.container, err)
+			r.scheduleRenewal(l, relayRetryInterval)
+			return
+		}
+
+		r.scheduleRenewal(l, l.duration/2)
+	})
+}
+
+// release tells the server that granted the lease that it is no longer needed
+func (r *dhcpRelayIPAM) release(l *relayLease) {
+	ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+	defer cancel()
+
+	p := relayPacket(dhcp4.Release, l.container, l.link, l.addr)
+	if _, err := r.transport.Exchange(ctx, l.server, p, false); err != nil {
+		log.Warnf("Unable to release %s for container %s with %s: %s", l.addr, l.container, l.server, err)
+	}
+}
+
+// exchange sends p to server and returns the reply, which must be of type want
+func (r *dhcpRelayIPAM) exchange(ctx context.Context, server net.IP, p dhcp4.Packet, want dhcp4.MessageType) (dhcp4.Packet, error) {
+	reply, err := r.transport.Exchange(ctx, server, p, true)
+	if err != nil {
+		return nil, err
+	}
+
+	mt := reply.ParseOptions()[dhcp4.OptionDHCPMessageType]
+	switch {
+	case len(mt) != 1:
+		return nil, fmt.Errorf("reply has no message type")
+	case dhcp4.MessageType(mt[0]) == dhcp4.NAK:
+		return nil, fmt.Errorf("request declined")
+	case dhcp4.MessageType(mt[0]) != want:
+		return nil, fmt.Errorf("unexpected reply of type %d", mt[0])
+	}
+
+	return reply, nil
+}
+
+// newRelayLease returns the lease granted by ack
+func newRelayLease(ack dhcp4.Packet, server net.IP, container string, link net.IP) *relayLease {
+	l := &relayLease{
+		container: container,
+		link:      link,
+		addr:      copyIP(ack.YIAddr()),
+		server:    server,
+		duration:  defaultRelayLeaseTime,
+	}
+
+	opts := ack.ParseOptions()
+	if id := opts[dhcp4.OptionServerIdentifier]; len(id) == net.IPv4len {
+		l.server = copyIP(net.IP(id))
+	}
+	if d := opts[dhcp4.OptionIPAddressLeaseTime]; len(d) == 4 {
+		l.duration = time.Duration(binary.BigEndian.Uint32(d)) * time.Second
+	}
+
+	return l
+}
+
+// relayPacket returns a relayed request of type mt for the container, for an address in the subnet of link
+func relayPacket(mt dhcp4.MessageType, container string, link, ciaddr net.IP, opts ...dhcp4.Option) dhcp4.Packet {
+	opts = append(opts,
+		dhcp4.Option{Code: dhcp4.OptionClientIdentifier, Value: relayClientID(container)},
+		dhcp4.Option{Code: optionSubnetSelection, Value: link.To4()},
+	)
+
+	xid := make([]byte, 4)
+	rand.Read(xid)
+
+	p := dhcp4.RequestPacket(mt, relayHardwareAddr(container), ciaddr, xid, false, opts)
+	p.SetHops(1)
+	return p
+}
+
+// relayClientID returns the DHCP client identifier of the container, which servers key leases on
+func relayClientID(container string) []byte {
+	return append([]byte{0}, container...)
+}
+
+// relayHardwareAddr returns a locally administered unicast address for the container, as servers
+// expect requests to carry one. It is stable so that servers see the same client across requests.
+func relayHardwareAddr(container string) net.HardwareAddr {
+	sum := sha1.Sum([]byte(container))
+	mac := net.HardwareAddr(sum[:6])
+	mac[0] = (mac[0] | 0x02) &^ 0x01
+	return mac
+}
+
+// udpRelayTransport exchanges relayed messages with servers from the DHCP server port of the VCH,
+// which is where servers send replies to relays
+type udpRelayTransport struct {
+	// the port is shared by all scopes, so exchanges take turns
+	m sync.Mutex
+}
+
+func (t *udpRelayTransport) Exchange(ctx context.Context, server net.IP, p dhcp4.Packet, reply bool) (dhcp4.Packet, error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	conn, err := net.DialUDP("udp4", &net.UDPAddr{Port: dhcpServerPort}, &net.UDPAddr{IP: server, Port: dhcpServerPort})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// the relay address is the one the server is reached from
+	p.SetGIAddr(conn.LocalAddr().(*net.UDPAddr).IP)
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(relayTimeout)
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err = conn.Write(p); err != nil {
+		return nil, err
+	}
+
+	if !reply {
+		return nil, nil
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		res := dhcp4.Packet(buf[:n])
+		if n > 240 && res.OpCode() == dhcp4.BootReply && bytes.Equal(res.XId(), p.XId()) {
+			return append(dhcp4.Packet(nil), res...), nil
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/d2g/dhcp4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/pkg/uid"
+)
+
+// mockDHCPServer leases addresses from the subnet selected by relayed requests, one per client identifier
+type mockDHCPServer struct {
+	id       net.IP
+	next     net.IP
+	leases   map[string]net.IP
+	released []net.IP
+	requests []dhcp4.Packet
+}
+
+func newMockDHCPServer(id, first string) *mockDHCPServer {
+	return &mockDHCPServer{
+		id:     net.ParseIP(id).To4(),
+		next:   net.ParseIP(first).To4(),
+		leases: make(map[string]net.IP),
+	}
+}
+
+func (m *mockDHCPServer) Exchange(ctx context.Context, server net.IP, p dhcp4.Packet, reply bool) (dhcp4.Packet, error) {
+	if !server.Equal(m.id) {
+		return nil, fmt.Errorf("no route to %s", server)
+	}
+
+	m.requests = append(m.requests, p)
+	opts := p.ParseOptions()
+	client := string(opts[dhcp4.OptionClientIdentifier])
+	lease := m.leases[client]
+
+	switch dhcp4.MessageType(opts[dhcp4.OptionDHCPMessageType][0]) {
+	case dhcp4.Discover:
+		if lease == nil {
+			lease = m.next
+			m.next = dhcp4.IPAdd(m.next, 1)
+			m.leases[client] = lease
+		}
+		return dhcp4.ReplyPacket(p, dhcp4.Offer, m.id, lease, time.Hour, nil), nil
+	case dhcp4.Request:
+		requested := net.IP(opts[dhcp4.OptionRequestedIPAddress])
+		if requested == nil {
+			requested = p.CIAddr()
+		}
+		if lease != nil && !lease.Equal(requested) {
+			return dhcp4.ReplyPacket(p, dhcp4.NAK, m.id, nil, 0, nil), nil
+		}
+		m.leases[client] = requested
+		return dhcp4.ReplyPacket(p, dhcp4.ACK, m.id, requested, time.Hour, nil), nil
+	case dhcp4.Release:
+		m.released = append(m.released, p.CIAddr())
+		delete(m.leases, client)
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unexpected request")
+}
+
+func TestDHCPRelayIPAM(t *testing.T) {
+	ctx, err := NewContext(testConfig(), nil)
+	require.NoError(t, err)
+
+	s, err := ctx.findScopes(&[]string{"bar73"}[0])
+	require.NoError(t, err)
+	scope := s[0]
+
+	server := newMockDHCPServer("10.0.0.2", "10.133.0.100")
+	scope.ipam = newDHCPRelayIPAM([]net.IP{net.ParseIP("10.0.0.1"), server.id}, server)
+	assert.False(t, scope.isDynamic())
+
+	c := &Container{id: uid.New()}
+	e := newEndpoint(c, scope, nil, nil)
+	require.NoError(t, scope.AddContainer(c, e))
+	assert.Equal(t, "10.133.0.100", e.IP().String())
+
+	// requests are relayed for the subnet of the scope
+	discover := server.requests[0].ParseOptions()
+	assert.Equal(t, net.ParseIP("10.133.0.1").To4(), net.IP(discover[optionSubnetSelection]))
+	assert.Equal(t, relayClientID(c.id.String()), discover[dhcp4.OptionClientIdentifier])
+	assert.Equal(t, byte(1), server.requests[0].Hops())
+
+	// an endpoint with an address asks for it directly
+	other := &Container{id: uid.New()}
+	addr := net.ParseIP("10.133.0.200")
+	oe := newEndpoint(other, scope, &addr, nil)
+	require.NoError(t, scope.AddContainer(other, oe))
+	assert.Equal(t, "10.133.0.200", oe.IP().String())
+
+	// and fails if the server declines
+	declined := &Container{id: uid.New()}
+	server.leases[string(relayClientID(declined.id.String()))] = net.ParseIP("10.133.0.201")
+	assert.Error(t, scope.AddContainer(declined, newEndpoint(declined, scope, &addr, nil)))
+
+	require.NoError(t, scope.RemoveContainer(c))
+	assert.True(t, e.IP().IsUnspecified())
+	if assert.Len(t, server.released, 1) {
+		assert.Equal(t, "10.133.0.100", server.released[0].String())
+	}
+
+	relay := scope.ipam.(*dhcpRelayIPAM)
+	assert.Len(t, relay.leases, 1)
+	require.NoError(t, scope.RemoveContainer(other))
+	assert.Empty(t, relay.leases)
+}
+
+func TestDHCPRelayIPAMRenew(t *testing.T) {
+	server := newMockDHCPServer("10.0.0.2", "10.133.0.100")
+	relay := newDHCPRelayIPAM([]net.IP{server.id}, server)
+
+	l, err := relay.lease(context.TODO(), "c1", net.ParseIP("10.133.0.1"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, l.duration)
+	assert.True(t, l.server.Equal(server.id))
+
+	assert.NoError(t, relay.renew(l))
+	assert.Equal(t, l.addr, net.IP(server.requests[len(server.requests)-1].CIAddr()).To4())
+
+	// a lease the server no longer knows about is not silently moved
+	server.leases[string(relayClientID("c1"))] = net.ParseIP("10.133.0.150")
+	assert.Error(t, relay.renew(l))
+}
+
+func TestConfigureScope(t *testing.T) {
+	conf := testConfig()
+	conf.ContainerNetworks["bar7"].Reserved = []net.IP{net.ParseIP("10.13.1.10"), net.ParseIP("10.99.0.1")}
+	conf.ContainerNetworks["bar73"].IPAM = executor.IPAMDHCPRelay
+	conf.ContainerNetworks["bar73"].DHCPServers = []net.IP{net.ParseIP("10.0.0.2")}
+
+	ctx, err := NewContext(conf, nil)
+	require.NoError(t, err)
+
+	// reservations outside of the pools are skipped
+	s, err := ctx.findScopes(&[]string{"bar7"}[0])
+	require.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("10.13.1.10")}, s[0].Reservations())
+
+	s, err = ctx.findScopes(&[]string{"bar73"}[0])
+	require.NoError(t, err)
+	assert.IsType(t, &dhcpRelayIPAM{}, s[0].ipam)
+
+	conf.ContainerNetworks["bar73"].DHCPServers = nil
+	_, err = NewContext(conf, nil)
+	assert.Error(t, err, "relaying requires DHCP servers")
+
+	conf.ContainerNetworks["bar73"].IPAM = "bogus"
+	_, err = NewContext(conf, nil)
+	assert.Error(t, err)
+}
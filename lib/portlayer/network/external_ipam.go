@@ -0,0 +1,146 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// ExternalIPAM is an external IP address manager that is consulted when a container is assigned an
+// address on a scope with a static pool, and told when the address is released. The address
+// returned must be within the pools of the scope.
+type ExternalIPAM interface {
+	// Request returns the address to assign to the container, given the address it asked for,
+	// if any. A nil address leaves the choice to the scope.
+	Request(ctx context.Context, scope string, subnet *net.IPNet, container string, preferred net.IP) (net.IP, error)
+
+	// Release is called once the container no longer holds the address
+	Release(ctx context.Context, scope string, container string, addr net.IP) error
+}
+
+// DefaultIPAMTimeout is how long the webhook is given to respond
+const DefaultIPAMTimeout = 10 * time.Second
+
+// ipamRequest is the body POSTed to the webhook
+type ipamRequest struct {
+	Scope     string `json:"scope"`
+	Subnet    string `json:"subnet,omitempty"`
+	Container string `json:"container"`
+	IP        string `json:"ip,omitempty"`
+}
+
+// ipamResponse is the body returned by the webhook for a request
+type ipamResponse struct {
+	IP string `json:"ip"`
+}
+
+// WebhookIPAM delegates address assignment to an HTTP service. Addresses are requested by
+// POSTing to <url>/request, which responds with {"ip": "<address>"}, and released by POSTing
+// to <url>/release.
+type WebhookIPAM struct {
+	url    url.URL
+	client *http.Client
+}
+
+// NewWebhookIPAM returns an ExternalIPAM that calls the webhook at u
+func NewWebhookIPAM(u *url.URL, client *http.Client) *WebhookIPAM {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultIPAMTimeout}
+	}
+
+	return &WebhookIPAM{
+		url:    *u,
+		client: client,
+	}
+}
+
+// Request asks the webhook for an address for the container
+func (w *WebhookIPAM) Request(ctx context.Context, scope string, subnet *net.IPNet, container string, preferred net.IP) (net.IP, error) {
+	req := ipamRequest{
+		Scope:     scope,
+		Container: container,
+	}
+	if subnet != nil {
+		req.Subnet = subnet.String()
+	}
+	if preferred != nil && !preferred.IsUnspecified() {
+		req.IP = preferred.String()
+	}
+
+	var res ipamResponse
+	if err := w.post(ctx, "request", &req, &res); err != nil {
+		return nil, err
+	}
+
+	if res.IP == "" {
+		return nil, nil
+	}
+
+	addr := net.ParseIP(res.IP)
+	if addr == nil {
+		return nil, fmt.Errorf("external IPAM returned invalid address %q", res.IP)
+	}
+	return addr, nil
+}
+
+// Release tells the webhook that the container no longer holds the address
+func (w *WebhookIPAM) Release(ctx context.Context, scope string, container string, addr net.IP) error {
+	req := ipamRequest{
+		Scope:     scope,
+		Container: container,
+		IP:        addr.String(),
+	}
+
+	return w.post(ctx, "release", &req, nil)
+}
+
+func (w *WebhookIPAM) post(ctx context.Context, op string, req interface{}, res interface{}) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	u := w.url
+	u.Path = path.Join(u.Path, op)
+
+	resp, err := ctxhttp.Post(ctx, w.client, u.String(), "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("external IPAM %s failed: %s", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("external IPAM %s failed: %s", op, resp.Status)
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("external IPAM %s returned invalid response: %s", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/pkg/uid"
+)
+
+func TestWebhookIPAM(t *testing.T) {
+	var released []ipamRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ipamRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/ipam/request":
+			if req.Container == "bad" {
+				w.Write([]byte(`{"ip": "not-an-ip"}`))
+				return
+			}
+			if req.IP != "" {
+				w.Write([]byte(`{"ip": "` + req.IP + `"}`))
+				return
+			}
+			w.Write([]byte(`{"ip": "172.16.0.20"}`))
+		case "/ipam/release":
+			released = append(released, req)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL + "/ipam")
+	assert.NoError(t, err)
+	ipam := NewWebhookIPAM(u, nil)
+
+	addr, err := ipam.Request(context.TODO(), "bridge", nil, "c1", nil)
+	assert.NoError(t, err)
+	assert.True(t, addr.Equal(net.ParseIP("172.16.0.20")))
+
+	addr, err = ipam.Request(context.TODO(), "bridge", nil, "c1", net.ParseIP("172.16.0.30"))
+	assert.NoError(t, err)
+	assert.True(t, addr.Equal(net.ParseIP("172.16.0.30")))
+
+	_, err = ipam.Request(context.TODO(), "bridge", nil, "bad", nil)
+	assert.Error(t, err)
+
+	assert.NoError(t, ipam.Release(context.TODO(), "bridge", "c1", net.ParseIP("172.16.0.20")))
+	if assert.Len(t, released, 1) {
+		assert.Equal(t, "c1", released[0].Container)
+		assert.Equal(t, "172.16.0.20", released[0].IP)
+	}
+
+	u.Path = "/missing"
+	_, err = NewWebhookIPAM(u, nil).Request(context.TODO(), "bridge", nil, "c1", nil)
+	assert.Error(t, err)
+}
+
+func TestScopeExternalIPAM(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/request" {
+			w.Write([]byte(`{"ip": "172.16.0.42"}`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx, err := NewContext(testConfig(), nil)
+	assert.NoError(t, err)
+
+	u, _ := url.Parse(srv.URL)
+	ctx.SetExternalIPAM(NewWebhookIPAM(u, nil))
+
+	s := ctx.defaultScope
+	c := &Container{id: uid.New()}
+	e := newEndpoint(c, s, nil, nil)
+	assert.NoError(t, s.AddContainer(c, e))
+	assert.True(t, e.IP().Equal(net.ParseIP("172.16.0.42")))
+
+	// the address is taken from the pools
+	other := &Container{id: uid.New()}
+	assert.Error(t, s.AddContainer(other, newEndpoint(other, s, nil, nil)))
+
+	assert.NoError(t, s.RemoveContainer(c))
+	assert.NoError(t, s.AddContainer(other, newEndpoint(other, s, nil, nil)))
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/uid"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+	vnetwork "github.com/vmware/vic/pkg/vsphere/network"
 	"github.com/vmware/vic/pkg/vsphere/session"
 	"golang.org/x/net/context"
 )
@@ -52,6 +53,12 @@ type ResourceNotFoundError struct {
 	error
 }
 
+// ForbiddenError is returned for operations a resource does not allow in its current state,
+// such as removing a builtin scope or one that containers are attached to
+type ForbiddenError struct {
+	error
+}
+
 func (e DuplicateResourceError) Error() string {
 	return fmt.Sprintf("%s already exists", e.resID)
 }
@@ -87,7 +94,7 @@ func Init(ctx context.Context, sess *session.Session, source extraconfig.DataSou
 				continue
 			}
 
-			config.PortGroups[nn] = r.(object.NetworkReference)
+			config.PortGroups[nn] = vnetwork.Reference(r.(object.NetworkReference))
 		}
 
 		// make sure a NIC attached to the bridge network exists
@@ -107,6 +114,16 @@ func Init(ctx context.Context, sess *session.Session, source extraconfig.DataSou
 			return
 		}
 
+		// scopes are only created through the context, so any other reservation is stale
+		if err = netctx.reconcileNames(ctx); err != nil {
+			return
+		}
+
+		if config.IPAMWebhook.Host != "" {
+			log.Infof("Using external IPAM at %s", config.IPAMWebhook.String())
+			netctx.SetExternalIPAM(NewWebhookIPAM(&config.IPAMWebhook, nil))
+		}
+
 		if err = engageContext(ctx, netctx, exec.Config.EventManager); err == nil {
 			DefaultContext = netctx
 			log.Infof("Default network context allocated")
@@ -126,6 +143,11 @@ func handleEvent(netctx *Context, ie events.Event) {
 			return
 		}
 		defer handle.Close()
+
+		// external containers are not attached to container networks
+		if handle.External() {
+			return
+		}
 		if _, err := netctx.UnbindContainer(handle); err != nil {
 			log.Warnf("Failed to unbind container %s: %s", ie.Reference(), err)
 			return
@@ -171,6 +193,10 @@ func engageContext(ctx context.Context, netctx *Context, em event.EventManager)
 	}()
 
 	for _, c := range exec.Containers.Containers(nil) {
+		if c.External() {
+			continue
+		}
+
 		log.Debugf("adding container %s", c.ExecConfig.ID)
 		h := c.NewHandle(ctx)
 		defer h.Close()
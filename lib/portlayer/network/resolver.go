@@ -0,0 +1,95 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ResolveName returns the address of the container known as name to the container with address client,
+// as served by the embedded DNS server. name is a container name, short ID or network alias, optionally
+// qualified with the name of the network, e.g. web.bridge. Aliases created by the client itself, such
+// as those for docker links, take precedence over those of the network. Only containers on the same
+// network as the client are resolved.
+func (c *Context) ResolveName(client net.IP, name string) (net.IP, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	e := c.endpointByAddr(client)
+	if e == nil {
+		return nil, ResourceNotFoundError{error: fmt.Errorf("no container with address %s", client)}
+	}
+	s := e.Scope()
+
+	name = strings.TrimSuffix(name, ".")
+	con := c.lookupName(s, e, name)
+	if con == nil && strings.HasSuffix(name, "."+s.Name()) {
+		con = c.lookupName(s, e, strings.TrimSuffix(name, "."+s.Name()))
+	}
+	if con == nil {
+		return nil, ResourceNotFoundError{error: fmt.Errorf("container %s not found in %s", name, s.Name())}
+	}
+
+	ce := con.Endpoint(s)
+	if ce == nil || ce.IP().IsUnspecified() {
+		return nil, ResourceNotFoundError{error: fmt.Errorf("no address for container %s in %s", name, s.Name())}
+	}
+
+	return ce.IP(), nil
+}
+
+// lookupName returns the container known as name in scope s to the container of endpoint e, searching the
+// aliases created by e before the names of the scope. Callers must hold the context lock.
+func (c *Context) lookupName(s *Scope, e *Endpoint, name string) *Container {
+	if con, ok := c.containers[fmt.Sprintf("%s:%s:%s", s.Name(), e.Container().Name(), name)]; ok {
+		return con
+	}
+
+	return c.containers[fmt.Sprintf("%s:%s", s.Name(), name)]
+}
+
+// ResolveAddr returns the name of the container with address addr, qualified with the name of its network,
+// for reverse lookups from the container with address client. Only containers on the same network as the
+// client are resolved.
+func (c *Context) ResolveAddr(client, addr net.IP) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	e := c.endpointByAddr(client)
+	if e == nil {
+		return "", ResourceNotFoundError{error: fmt.Errorf("no container with address %s", client)}
+	}
+	s := e.Scope()
+
+	ae := s.ContainerByAddr(addr)
+	if ae == nil {
+		return "", ResourceNotFoundError{error: fmt.Errorf("no container with address %s in %s", addr, s.Name())}
+	}
+
+	return fmt.Sprintf("%s.%s", ae.Container().Name(), s.Name()), nil
+}
+
+// endpointByAddr returns the endpoint with address addr in any scope. Callers must hold the context lock.
+func (c *Context) endpointByAddr(addr net.IP) *Endpoint {
+	for _, s := range c.scopes {
+		if e := s.ContainerByAddr(addr); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	ctx, err := NewContext(testConfig(), nil)
+	require.NoError(t, err)
+
+	scope := ctx.DefaultScope()
+	bind := func(name string, ip net.IP, aliases ...string) {
+		c := newContainer(name)
+		require.NoError(t, ctx.AddContainer(c, &AddContainerOptions{Scope: scope.Name(), IP: &ip, Aliases: aliases}))
+		_, err := ctx.BindContainer(c)
+		require.NoError(t, err)
+	}
+
+	db := net.ParseIP("172.16.0.2").To4()
+	web := net.ParseIP("172.16.0.3").To4()
+	other := net.ParseIP("172.16.0.4").To4()
+	bind("db", db, ":database", ":db.local")
+	bind("web", web, "db:sql")
+	bind("other", other)
+
+	var tests = []struct {
+		client net.IP
+		name   string
+		ip     net.IP
+	}{
+		{web, "db.", db},
+		{web, "db.bridge.", db},
+		{web, "database", db},
+		{web, "db.local.", db},
+		{other, "web", web},
+		// links are only visible to the linking container
+		{web, "sql.", db},
+		{other, "sql.", nil},
+		// other networks and external names are not resolved
+		{web, "db.bar7.", nil},
+		{web, "example.com.", nil},
+		// nor are requests from outside the container networks
+		{net.ParseIP("10.10.10.10"), "db", nil},
+	}
+
+	for _, te := range tests {
+		ip, err := ctx.ResolveName(te.client, te.name)
+		if te.ip == nil {
+			assert.Error(t, err, "%s from %s", te.name, te.client)
+			assert.IsType(t, ResourceNotFoundError{}, err)
+			continue
+		}
+
+		if assert.NoError(t, err, "%s from %s", te.name, te.client) {
+			assert.True(t, te.ip.Equal(ip), "%s from %s => %s, want %s", te.name, te.client, ip, te.ip)
+		}
+	}
+
+	name, err := ctx.ResolveAddr(other, db)
+	assert.NoError(t, err)
+	assert.Equal(t, "db.bridge", name)
+
+	_, err = ctx.ResolveAddr(other, net.ParseIP("172.16.0.100"))
+	assert.Error(t, err)
+}
@@ -18,9 +18,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"sort"
 	"sync"
 
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/lib/portlayer/constants"
 	"github.com/vmware/vic/lib/portlayer/exec"
 	"github.com/vmware/vic/pkg/ip"
@@ -41,6 +43,17 @@ type Scope struct {
 	spaces     []*AddressSpace
 	builtin    bool
 	network    object.NetworkReference
+
+	// addresses held back from the pools, keyed by address, that are
+	// only assigned to containers asking for them
+	reservations map[string]net.IP
+	// firewall rules applied to the traffic of the containers on the scope
+	ingress []executor.FirewallRule
+	egress  []executor.FirewallRule
+	// how container addresses are managed, derived from the pools of the scope if nil
+	ipam IPAM
+	// external address manager, if any
+	external ExternalIPAM
 }
 
 func newScope(id uid.UID, name string, scopeType string, subnet *net.IPNet, gateway net.IP, dns []net.IP, network object.NetworkReference) *Scope {
@@ -53,6 +66,8 @@ func newScope(id uid.UID, name string, scopeType string, subnet *net.IPNet, gate
 		dns:        dns,
 		network:    network,
 		containers: make(map[uid.UID]*Container),
+
+		reservations: make(map[string]net.IP),
 	}
 }
 
@@ -84,8 +99,21 @@ func (s *Scope) Network() object.NetworkReference {
 	return s.network
 }
 
+// addressing returns the IPAM of the scope. Scopes without one are static if they have pools, and
+// are addressed by DHCP in the guest otherwise, except for bridge scopes which always have pools.
+func (s *Scope) addressing() IPAM {
+	if s.ipam != nil {
+		return s.ipam
+	}
+
+	if s.scopeType != constants.BridgeScopeType && len(s.spaces) == 0 {
+		return dhcpIPAM{}
+	}
+	return staticIPAM{}
+}
+
 func (s *Scope) isDynamic() bool {
-	return s.scopeType != constants.BridgeScopeType && len(s.spaces) == 0
+	return s.addressing().Dynamic()
 }
 
 func (s *Scope) Pools() []*ip.Range {
@@ -115,46 +143,110 @@ func (s *Scope) pools() []*ip.Range {
 }
 
 func (s *Scope) reserveEndpointIP(e *Endpoint) error {
-	if s.isDynamic() {
-		return nil
+	return s.addressing().Reserve(s, e)
+}
+
+func (s *Scope) releaseEndpointIP(e *Endpoint) error {
+	return s.addressing().Release(s, e)
+}
+
+// Reserve holds the address back from the pools of the scope, so that it is only
+// assigned to a container that asks for it
+func (s *Scope) Reserve(addr net.IP) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.addressing().(staticIPAM); !ok {
+		return fmt.Errorf("scope %s has no address pool to reserve from", s.name)
+	}
+
+	if _, ok := s.reservations[addr.String()]; ok {
+		return DuplicateResourceError{resID: addr.String()}
 	}
 
-	// reserve an ip address
 	var err error
 	for _, p := range s.spaces {
-		if !ip.IsUnspecifiedIP(e.ip) {
-			if err = p.ReserveIP4(e.ip); err == nil {
-				return nil
-			}
-		} else {
-			var eip net.IP
-			if eip, err = p.ReserveNextIP4(); err == nil {
-				e.ip = eip
-				return nil
-			}
+		if err = p.ReserveIP4(addr); err == nil {
+			s.reservations[addr.String()] = addr
+			return nil
 		}
 	}
 
-	return err
+	return fmt.Errorf("could not reserve %s on scope %s: %s", addr, s.name, err)
 }
 
-func (s *Scope) releaseEndpointIP(e *Endpoint) error {
-	if s.isDynamic() {
-		return nil
+// Release returns a reserved address to the pools of the scope
+func (s *Scope) Release(addr net.IP) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.reservations[addr.String()]; !ok {
+		return ResourceNotFoundError{error: fmt.Errorf("%s is not reserved on scope %s", addr, s.name)}
+	}
+
+	if s.endpointByAddr(addr) != nil {
+		return fmt.Errorf("reserved address %s is in use", addr)
 	}
 
 	for _, p := range s.spaces {
-		if err := p.ReleaseIP4(e.ip); err == nil {
-			if !e.static {
-				e.ip = net.IPv4(0, 0, 0, 0)
-			}
+		if err := p.ReleaseIP4(addr); err == nil {
+			delete(s.reservations, addr.String())
 			return nil
 		}
 	}
 
-	return fmt.Errorf("could not release IP for endpoint")
+	return fmt.Errorf("could not release %s on scope %s", addr, s.name)
+}
+
+// Reservations returns the reserved addresses of the scope in ascending order
+// Firewall returns the ingress and egress firewall rules of the scope
+func (s *Scope) Firewall() (ingress, egress []executor.FirewallRule) {
+	s.RLock()
+	defer s.RUnlock()
+
+	ingress = append(ingress, s.ingress...)
+	egress = append(egress, s.egress...)
+	return ingress, egress
+}
+
+// SetFirewall replaces the firewall rules of the scope, which apply to
+// containers as they are bound to it
+func (s *Scope) SetFirewall(ingress, egress []executor.FirewallRule) error {
+	for _, rules := range [][]executor.FirewallRule{ingress, egress} {
+		for _, r := range rules {
+			if err := r.Validate(); err != nil {
+				return fmt.Errorf("invalid firewall rule %q: %s", r, err)
+			}
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.ingress = append([]executor.FirewallRule(nil), ingress...)
+	s.egress = append([]executor.FirewallRule(nil), egress...)
+	return nil
+}
+
+func (s *Scope) Reservations() []net.IP {
+	s.RLock()
+	defer s.RUnlock()
+
+	res := make([]net.IP, 0, len(s.reservations))
+	for _, r := range s.reservations {
+		res = append(res, r)
+	}
+
+	sort.Sort(byAddr(res))
+	return res
 }
 
+type byAddr []net.IP
+
+func (a byAddr) Len() int           { return len(a) }
+func (a byAddr) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byAddr) Less(i, j int) bool { return compareIP4(a[i], a[j]) < 0 }
+
 func (s *Scope) AddContainer(con *Container, e *Endpoint) error {
 	s.Lock()
 	defer s.Unlock()
@@ -231,6 +323,10 @@ func (s *Scope) ContainerByAddr(addr net.IP) *Endpoint {
 	s.RLock()
 	defer s.RUnlock()
 
+	return s.endpointByAddr(addr)
+}
+
+func (s *Scope) endpointByAddr(addr net.IP) *Endpoint {
 	if addr == nil || addr.IsUnspecified() {
 		return nil
 	}
@@ -336,7 +432,8 @@ func (s *Scope) UnmarshalJSON(data []byte) error {
 	}
 
 	ns := Scope{
-		containers: make(map[uid.UID]*Container),
+		containers:   make(map[uid.UID]*Container),
+		reservations: make(map[string]net.IP),
 	}
 	ns.id = sj.ID
 	ns.name = sj.Name
@@ -372,4 +469,7 @@ func (s *Scope) swap(other *Scope) {
 	s.endpoints, other.endpoints = other.endpoints, s.endpoints
 	s.containers, other.containers = other.containers, s.containers
 	s.network, other.network = other.network, s.network
+	s.reservations, other.reservations = other.reservations, s.reservations
+	s.ipam, other.ipam = other.ipam, s.ipam
+	s.external, other.external = other.external, s.external
 }
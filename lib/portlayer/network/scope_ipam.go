@@ -0,0 +1,143 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"fmt"
+	"net"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/pkg/ip"
+)
+
+// IPAM manages the addresses of container endpoints on a scope. Its methods are called with
+// the scope locked.
+type IPAM interface {
+	// Dynamic reports whether endpoints are addressed in the guest rather than by the IPAM
+	Dynamic() bool
+
+	// Reserve assigns the endpoint an address, the one it already has if it is set
+	Reserve(s *Scope, e *Endpoint) error
+
+	// Release returns the address of the endpoint
+	Release(s *Scope, e *Endpoint) error
+}
+
+// staticIPAM assigns addresses from the pools of the scope, consulting the external IPAM
+// of the scope if it has one
+type staticIPAM struct{}
+
+func (staticIPAM) Dynamic() bool {
+	return false
+}
+
+func (staticIPAM) Reserve(s *Scope, e *Endpoint) error {
+	if s.external != nil {
+		addr, err := s.external.Request(context.TODO(), s.name, s.subnet, e.container.id.String(), e.ip)
+		if err != nil {
+			return err
+		}
+
+		if addr != nil {
+			e.ip = addr
+		}
+	}
+
+	err := reservePoolAddr(s, e)
+	if err != nil && s.external != nil && !ip.IsUnspecifiedIP(e.ip) {
+		releaseExternal(s, e)
+	}
+
+	return err
+}
+
+// reservePoolAddr reserves the address of the endpoint, or the next free one, from the pools of the scope
+func reservePoolAddr(s *Scope, e *Endpoint) error {
+	// a reserved address is handed to the first container asking for it
+	if r, ok := s.reservations[e.ip.String()]; ok {
+		if s.endpointByAddr(r) != nil {
+			return fmt.Errorf("reserved address %s is in use", r)
+		}
+
+		return nil
+	}
+
+	// reserve an ip address
+	var err error
+	for _, p := range s.spaces {
+		if !ip.IsUnspecifiedIP(e.ip) {
+			if err = p.ReserveIP4(e.ip); err == nil {
+				return nil
+			}
+		} else {
+			var eip net.IP
+			if eip, err = p.ReserveNextIP4(); err == nil {
+				e.ip = eip
+				return nil
+			}
+		}
+	}
+
+	return err
+}
+
+func (staticIPAM) Release(s *Scope, e *Endpoint) error {
+	if s.external != nil {
+		releaseExternal(s, e)
+	}
+
+	// reserved addresses stay out of the pools
+	if _, ok := s.reservations[e.ip.String()]; ok {
+		if !e.static {
+			e.ip = net.IPv4(0, 0, 0, 0)
+		}
+		return nil
+	}
+
+	for _, p := range s.spaces {
+		if err := p.ReleaseIP4(e.ip); err == nil {
+			if !e.static {
+				e.ip = net.IPv4(0, 0, 0, 0)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("could not release IP for endpoint")
+}
+
+// releaseExternal tells the external IPAM that the endpoint address is free
+func releaseExternal(s *Scope, e *Endpoint) {
+	if err := s.external.Release(context.TODO(), s.name, e.container.id.String(), e.ip); err != nil {
+		log.Warnf("Failed to release %s on scope %s with external IPAM: %s", e.ip, s.name, err)
+	}
+}
+
+// dhcpIPAM leaves addressing to the DHCP client of the container
+type dhcpIPAM struct{}
+
+func (dhcpIPAM) Dynamic() bool {
+	return true
+}
+
+func (dhcpIPAM) Reserve(s *Scope, e *Endpoint) error {
+	return nil
+}
+
+func (dhcpIPAM) Release(s *Scope, e *Endpoint) error {
+	return nil
+}
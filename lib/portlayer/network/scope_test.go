@@ -20,6 +20,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/portlayer/exec"
 	"github.com/vmware/vic/pkg/uid"
@@ -190,3 +191,53 @@ func TestScopeAddRemoveContainer(t *testing.T) {
 
 	}
 }
+
+func TestScopeReservations(t *testing.T) {
+	ctx, err := NewContext(testConfig(), nil)
+	assert.NoError(t, err)
+
+	s := ctx.defaultScope
+	reserved := net.ParseIP("172.16.0.2")
+
+	assert.NoError(t, s.Reserve(reserved))
+	assert.IsType(t, DuplicateResourceError{}, s.Reserve(reserved))
+	assert.NoError(t, s.Reserve(net.ParseIP("172.16.0.10")))
+	assert.Error(t, s.Reserve(net.ParseIP("10.0.0.1")), "address outside of the pools")
+
+	res := s.Reservations()
+	if assert.Len(t, res, 2) {
+		assert.True(t, res[0].Equal(reserved))
+		assert.True(t, res[1].Equal(net.ParseIP("172.16.0.10")))
+	}
+
+	// a container without an address is not given a reserved one
+	dyn := &Container{id: uid.New()}
+	e := newEndpoint(dyn, s, nil, nil)
+	assert.NoError(t, s.AddContainer(dyn, e))
+	assert.True(t, e.IP().Equal(net.ParseIP("172.16.0.3")))
+
+	// a container asking for a reserved address gets it, once
+	c := &Container{id: uid.New()}
+	e = newEndpoint(c, s, &reserved, nil)
+	assert.NoError(t, s.AddContainer(c, e))
+	assert.True(t, e.IP().Equal(reserved))
+
+	other := &Container{id: uid.New()}
+	assert.Error(t, s.AddContainer(other, newEndpoint(other, s, &reserved, nil)))
+
+	// the address cannot be released while in use, and stays reserved
+	// once the container is removed
+	assert.Error(t, s.Release(reserved))
+	assert.NoError(t, s.RemoveContainer(c))
+	assert.Len(t, s.Reservations(), 2)
+
+	assert.NoError(t, s.Release(reserved))
+	assert.IsType(t, ResourceNotFoundError{}, s.Release(reserved))
+	assert.Len(t, s.Reservations(), 1)
+
+	// a released address goes back to the pools
+	dyn = &Container{id: uid.New()}
+	e = newEndpoint(dyn, s, nil, nil)
+	assert.NoError(t, s.AddContainer(dyn, e))
+	assert.True(t, e.IP().Equal(reserved))
+}
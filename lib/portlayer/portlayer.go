@@ -16,9 +16,13 @@ package portlayer
 
 import (
 	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/logging"
+	"github.com/vmware/vic/lib/portlayer/metrics"
 	"github.com/vmware/vic/lib/portlayer/network"
 	"github.com/vmware/vic/lib/portlayer/storage"
+	"github.com/vmware/vic/lib/portlayer/storage/vsphere"
 	"github.com/vmware/vic/lib/portlayer/store"
+	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
 	"github.com/vmware/vic/pkg/vsphere/session"
 
@@ -67,9 +71,29 @@ func Init(ctx context.Context, sess *session.Session) error {
 		return err
 	}
 
+	// choose the image store for container image layers once, so that the exec and storage
+	// layers agree on where images live
+	if len(storage.Config.ImageStores) > 0 {
+		op := trace.NewOperation(ctx, "image store placement")
+		exec.Config.ImageStore, err = vsphere.PlaceImageStore(op, sess, storage.Config.ImageStores, storage.Config.ImageStorePlacement)
+		if err != nil {
+			return err
+		}
+	}
+
 	if err = network.Init(ctx, sess, source, sink); err != nil {
 		return err
 	}
 
+	if err = logging.Init(ctx); err != nil {
+		return err
+	}
+
+	if err = metrics.Init(ctx, sess); err != nil {
+		return err
+	}
+
+	initMaintenance(ctx, sess, source)
+
 	return nil
 }
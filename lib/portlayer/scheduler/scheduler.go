@@ -0,0 +1,272 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scheduler runs the periodic maintenance jobs of the port layer, such as removing inconsistent
+// images, reconciling the container cache with vSphere and checking certificates for expiry.
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/pkg/trace"
+)
+
+const (
+	// DefaultStatusPath is the file on the appliance the status of the maintenance jobs is written to,
+	// from which vicadmin reports it
+	DefaultStatusPath = "/var/run/maintenance.json"
+
+	// DefaultJitter is the fraction of the interval of a job by which each run is randomly delayed, so
+	// that jobs with the same interval do not all load vSphere at once
+	DefaultJitter = 0.1
+)
+
+// Job is a maintenance job run periodically by a Scheduler
+type Job struct {
+	// Name identifies the job, and is what it is enabled or disabled by in the VCH config
+	Name string
+	// Interval is the time between runs of the job
+	Interval time.Duration
+	// Run performs the job, returning once it is done or the context is cancelled
+	Run func(ctx context.Context) error
+}
+
+// Status is the status of a job as of its last run
+type Status struct {
+	Name     string        `json:"name"`
+	Enabled  bool          `json:"enabled"`
+	Interval time.Duration `json:"interval"`
+	Runs     int           `json:"runs"`
+	LastRun  time.Time     `json:"last_run"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Scheduler runs registered jobs at their interval, with jitter, until its context is done
+type Scheduler struct {
+	// Jitter is the fraction of the interval by which each run of a job is randomly delayed
+	Jitter float64
+	// StatusPath is the file the status of all jobs is written to after each run, empty for none
+	StatusPath string
+
+	m       sync.Mutex
+	enabled map[string]bool
+	jobs    map[string]*Job
+	status  map[string]*Status
+	ctx     context.Context
+}
+
+// New returns a scheduler that runs the jobs that are not disabled in enabled, which is keyed by job name
+func New(enabled map[string]bool) *Scheduler {
+	return &Scheduler{
+		Jitter:  DefaultJitter,
+		enabled: enabled,
+		jobs:    make(map[string]*Job),
+		status:  make(map[string]*Status),
+	}
+}
+
+// Enabled reports whether the named job runs, which it does unless it is disabled in the VCH config
+func (s *Scheduler) Enabled(name string) bool {
+	enabled, ok := s.enabled[name]
+	return !ok || enabled
+}
+
+// Register adds a job to the scheduler. Jobs registered once the scheduler has started are started
+// straight away.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Interval <= 0 || job.Run == nil {
+		return fmt.Errorf("job %q must have a name, a positive interval and a function to run", job.Name)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("job %q is already registered", job.Name)
+	}
+
+	s.jobs[job.Name] = &job
+	s.status[job.Name] = &Status{
+		Name:     job.Name,
+		Enabled:  s.Enabled(job.Name),
+		Interval: job.Interval,
+	}
+
+	if s.ctx != nil {
+		s.start(&job)
+	}
+	return nil
+}
+
+// Start runs the registered jobs until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.ctx != nil {
+		return
+	}
+	s.ctx = ctx
+
+	for _, job := range s.jobs {
+		s.start(job)
+	}
+	s.writeStatus()
+}
+
+// start runs job in the background if it is enabled. The caller must hold s.m.
+func (s *Scheduler) start(job *Job) {
+	if !s.Enabled(job.Name) {
+		log.Infof("Maintenance job %s is disabled", job.Name)
+		return
+	}
+
+	log.Infof("Scheduling maintenance job %s every %s", job.Name, job.Interval)
+	go s.loop(s.ctx, job)
+}
+
+// loop runs job until ctx is done. The first run happens within the jitter of the start, so that
+// status is available soon after the port layer starts.
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	delay := s.jitter(job.Interval)
+	for {
+		select {
+		case <-time.After(delay):
+			s.run(ctx, job)
+		case <-ctx.Done():
+			return
+		}
+		delay = job.Interval + s.jitter(job.Interval)
+	}
+}
+
+// jitter returns a random delay of up to Jitter of interval
+func (s *Scheduler) jitter(interval time.Duration) time.Duration {
+	if s.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(float64(interval)*s.Jitter) + 1))
+}
+
+// run runs job once and records its status
+func (s *Scheduler) run(ctx context.Context, job *Job) {
+	defer trace.End(trace.Begin(job.Name))
+
+	start := time.Now()
+	err := job.Run(ctx)
+	duration := time.Since(start)
+
+	if err != nil {
+		log.Errorf("Maintenance job %s failed after %s: %s", job.Name, duration, err)
+	} else {
+		log.Debugf("Maintenance job %s completed in %s", job.Name, duration)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	st := s.status[job.Name]
+	st.Runs++
+	st.LastRun = start.UTC()
+	st.Duration = duration
+	st.Error = ""
+	if err != nil {
+		st.Error = err.Error()
+	}
+
+	s.writeStatus()
+}
+
+// Status returns the status of the registered jobs, ordered by name
+func (s *Scheduler) Status() []Status {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.statusList()
+}
+
+// statusList returns the status of the registered jobs. The caller must hold s.m.
+func (s *Scheduler) statusList() []Status {
+	status := make([]Status, 0, len(s.status))
+	for _, st := range s.status {
+		status = append(status, *st)
+	}
+	sort.Sort(byName(status))
+
+	return status
+}
+
+// writeStatus writes the status of the jobs to StatusPath. The caller must hold s.m.
+func (s *Scheduler) writeStatus() {
+	if s.StatusPath == "" {
+		return
+	}
+
+	b, err := json.Marshal(s.statusList())
+	if err != nil {
+		log.Errorf("Unable to marshal maintenance job status: %s", err)
+		return
+	}
+
+	// written to a temporary file and renamed, so readers never see a partial file
+	tmp, err := ioutil.TempFile(filepath.Dir(s.StatusPath), filepath.Base(s.StatusPath))
+	if err != nil {
+		log.Errorf("Unable to write maintenance job status: %s", err)
+		return
+	}
+
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.StatusPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Errorf("Unable to write maintenance job status: %s", err)
+	}
+}
+
+// ReadStatus reads the status of the maintenance jobs written by a scheduler to path
+func ReadStatus(path string) ([]Status, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []Status
+	if err = json.Unmarshal(b, &status); err != nil {
+		return nil, fmt.Errorf("unable to parse maintenance job status %s: %s", path, err)
+	}
+	return status, nil
+}
+
+type byName []Status
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
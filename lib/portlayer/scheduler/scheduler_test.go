@@ -0,0 +1,125 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRegister(t *testing.T) {
+	s := New(nil)
+	run := func(ctx context.Context) error { return nil }
+
+	assert.NoError(t, s.Register(Job{Name: "a", Interval: time.Minute, Run: run}))
+	assert.Error(t, s.Register(Job{Name: "a", Interval: time.Minute, Run: run}), "duplicate job")
+	assert.Error(t, s.Register(Job{Name: "b", Run: run}), "no interval")
+	assert.Error(t, s.Register(Job{Name: "c", Interval: time.Minute}), "no function")
+
+	status := s.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, "a", status[0].Name)
+	assert.True(t, status[0].Enabled)
+	assert.Equal(t, 0, status[0].Runs)
+}
+
+func TestRunAndStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := New(map[string]bool{"disabled": false, "enabled": true})
+	s.Jitter = 0
+	s.StatusPath = filepath.Join(dir, "status.json")
+
+	ran := make(chan string, 10)
+	job := func(name string, err error) Job {
+		return Job{
+			Name:     name,
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				ran <- name
+				return err
+			},
+		}
+	}
+
+	require.NoError(t, s.Register(job("disabled", nil)))
+	require.NoError(t, s.Register(job("enabled", nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	// registered after start, enabled by default, and failing
+	require.NoError(t, s.Register(job("failing", errors.New("boom"))))
+
+	seen := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-ran:
+			seen[name] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("jobs did not run, ran %v", seen)
+		}
+	}
+	assert.Equal(t, map[string]bool{"enabled": true, "failing": true}, seen)
+
+	// the status is written after the run is recorded
+	var status []Status
+	for i := 0; i < 50; i++ {
+		status, err = ReadStatus(s.StatusPath)
+		require.NoError(t, err)
+		if len(status) == 3 && status[0].Runs == 0 && status[1].Runs == 1 && status[2].Runs == 1 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	require.Len(t, status, 3)
+	assert.Equal(t, "disabled", status[0].Name)
+	assert.False(t, status[0].Enabled)
+	assert.Equal(t, 0, status[0].Runs)
+
+	assert.Equal(t, "enabled", status[1].Name)
+	assert.Equal(t, 1, status[1].Runs)
+	assert.Empty(t, status[1].Error)
+	assert.False(t, status[1].LastRun.IsZero())
+
+	assert.Equal(t, "failing", status[2].Name)
+	assert.Equal(t, 1, status[2].Runs)
+	assert.Equal(t, "boom", status[2].Error)
+}
+
+func TestJitter(t *testing.T) {
+	s := New(nil)
+	s.Jitter = 0.5
+
+	for i := 0; i < 100; i++ {
+		d := s.jitter(time.Minute)
+		assert.True(t, d >= 0 && d <= 30*time.Second, "jitter %s out of range", d)
+	}
+
+	s.Jitter = 0
+	assert.Equal(t, time.Duration(0), s.jitter(time.Minute))
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nfs
+
+import (
+	"net/url"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+
+	vicnfs "github.com/vmware/vic/pkg/nfs"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// KernelMounter mounts exports with the NFS client of the appliance kernel
+type KernelMounter struct{}
+
+func (m *KernelMounter) Mount(op trace.Operation, export *url.URL, target string) error {
+	defer trace.End(trace.Begin(export.String()))
+
+	source, data, err := vicnfs.MountArgs(export, false)
+	if err != nil {
+		return err
+	}
+
+	// drop a mount left behind by a previous run of the port layer
+	if err = syscall.Unmount(target, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL {
+		log.Debugf("unmounting %s: %s", target, err)
+	}
+
+	return syscall.Mount(source, target, "nfs", syscall.MS_NOATIME, data)
+}
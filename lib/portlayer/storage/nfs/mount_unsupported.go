@@ -0,0 +1,31 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !linux
+
+package nfs
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// KernelMounter mounts exports with the NFS client of the appliance kernel
+type KernelMounter struct{}
+
+func (m *KernelMounter) Mount(op trace.Operation, export *url.URL, target string) error {
+	return errors.New("mounting NFS exports is only supported on linux")
+}
@@ -0,0 +1,301 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nfs implements volume stores on NFS exports. Each volume is a directory of the export,
+// which the tether of every containerVM joined to it mounts, so that the volume can be shared.
+package nfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/lib/portlayer/storage"
+	"github.com/vmware/vic/lib/portlayer/util"
+	vicnfs "github.com/vmware/vic/pkg/nfs"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+const (
+	// VolumesDir is the directory of the export holding the volume directories
+	VolumesDir = "volumes"
+	// metadataDir is the directory of the export holding the volume metadata, kept apart from the
+	// volume directories so that containers do not see it
+	metadataDir = "volumedata"
+
+	// DefaultMountRoot is the appliance directory the exports are mounted under, one per volume store
+	DefaultMountRoot = "/var/lib/vic/nfs"
+)
+
+// Mounter mounts NFS exports in the appliance, so that volumes can be created on them
+type Mounter interface {
+	Mount(op trace.Operation, export *url.URL, target string) error
+}
+
+// Export is the NFS export directory backing a volume
+type Export struct {
+	URL url.URL
+}
+
+// MountPath returns the path of the volume on the export
+func (e *Export) MountPath() (string, error) {
+	return e.URL.Path, nil
+}
+
+// DiskPath returns the URL of the volume directory on the export
+func (e *Export) DiskPath() string {
+	return e.URL.String()
+}
+
+// VolumeStore creates volumes as directories of NFS exports
+type VolumeStore struct {
+	mounter Mounter
+
+	// root is the appliance directory the exports are mounted under
+	root string
+
+	// maps volume store URL to its export
+	exports     map[url.URL]*url.URL
+	exportsLock sync.RWMutex
+}
+
+// NewVolumeStore returns a volume store that mounts exports with mounter under the root directory
+func NewVolumeStore(op trace.Operation, mounter Mounter, root string) *VolumeStore {
+	return &VolumeStore{
+		mounter: mounter,
+		root:    root,
+		exports: make(map[url.URL]*url.URL),
+	}
+}
+
+// AddStore mounts the export and adds it as the volume store storeName, returning the URL used to
+// refer to the volume store
+func (v *VolumeStore) AddStore(op trace.Operation, export *url.URL, storeName string) (*url.URL, error) {
+	v.exportsLock.Lock()
+	defer v.exportsLock.Unlock()
+
+	if err := vicnfs.Validate(export); err != nil {
+		return nil, fmt.Errorf("invalid export for volume store %s: %s", storeName, err)
+	}
+
+	u, err := util.VolumeStoreNameToURL(storeName)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := v.exports[*u]; ok {
+		return nil, fmt.Errorf("volumestore (%s) already added", u.String())
+	}
+
+	target := filepath.Join(v.root, storeName)
+	if err = os.MkdirAll(target, 0755); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Mounting %s on %s for volume store %s", export, target, storeName)
+	if err = v.mounter.Mount(op, export, target); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range []string{VolumesDir, metadataDir} {
+		if err = os.MkdirAll(filepath.Join(target, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	v.exports[*u] = export
+	return u, nil
+}
+
+func (v *VolumeStore) VolumeStoresList(op trace.Operation) (map[string]url.URL, error) {
+	m := make(map[string]url.URL)
+
+	v.exportsLock.RLock()
+	defer v.exportsLock.RUnlock()
+
+	for u, export := range v.exports {
+		storeName, err := util.VolumeStoreName(&u)
+		if err != nil {
+			return nil, err
+		}
+
+		m[storeName] = *export
+	}
+
+	return m, nil
+}
+
+// storeDir returns the appliance directory the export of the volume store is mounted on
+func (v *VolumeStore) storeDir(store *url.URL) (string, error) {
+	v.exportsLock.RLock()
+	defer v.exportsLock.RUnlock()
+
+	if _, ok := v.exports[*store]; !ok {
+		return "", storage.VolumeStoreNotFoundError{Msg: fmt.Sprintf("volume store (%s) not found", store.String())}
+	}
+
+	storeName, err := util.VolumeStoreName(store)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(v.root, storeName), nil
+}
+
+// export returns the directory of the volume on the export of the volume store
+func (v *VolumeStore) export(store *url.URL, ID string) *Export {
+	v.exportsLock.RLock()
+	defer v.exportsLock.RUnlock()
+
+	e := &Export{URL: *v.exports[*store]}
+	e.URL.Path = path.Join(e.URL.Path, VolumesDir, ID)
+	return e
+}
+
+// VolumeCreate creates the volume directory on the export. NFS volumes share the capacity of the
+// export, so capacityKB is not applied.
+func (v *VolumeStore) VolumeCreate(op trace.Operation, ID string, store *url.URL, capacityKB uint64, info map[string][]byte) (*storage.Volume, error) {
+	dir, err := v.storeDir(store)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Mkdir(filepath.Join(dir, VolumesDir, ID), 0755); err != nil {
+		return nil, err
+	}
+
+	if err = writeMetadata(filepath.Join(dir, metadataDir, ID), info); err != nil {
+		os.RemoveAll(filepath.Join(dir, VolumesDir, ID))
+		return nil, err
+	}
+
+	vol, err := storage.NewVolume(store, ID, info, v.export(store, ID))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("volumestore: %s (%s)", ID, vol.SelfLink)
+	return vol, nil
+}
+
+func (v *VolumeStore) VolumeDestroy(op trace.Operation, vol *storage.Volume) error {
+	if err := storage.VolumeInUse(vol.ID); err != nil {
+		log.Errorf("VolumeStore: delete error: %s", err.Error())
+		return err
+	}
+
+	dir, err := v.storeDir(vol.Store)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("VolumeStore: Deleting %s", filepath.Join(dir, VolumesDir, vol.ID))
+	if err = os.RemoveAll(filepath.Join(dir, VolumesDir, vol.ID)); err != nil {
+		log.Errorf("VolumeStore: delete error: %s", err.Error())
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(dir, metadataDir, vol.ID))
+}
+
+func (v *VolumeStore) VolumesList(op trace.Operation) ([]*storage.Volume, error) {
+	volumes := []*storage.Volume{}
+
+	v.exportsLock.RLock()
+	stores := make([]url.URL, 0, len(v.exports))
+	for u := range v.exports {
+		stores = append(stores, u)
+	}
+	v.exportsLock.RUnlock()
+
+	for i := range stores {
+		store := &stores[i]
+
+		dir, err := v.storeDir(store)
+		if err != nil {
+			return nil, err
+		}
+
+		files, err := ioutil.ReadDir(filepath.Join(dir, VolumesDir))
+		if err != nil {
+			return nil, fmt.Errorf("error listing vols: %s", err)
+		}
+
+		for _, f := range files {
+			if !f.IsDir() {
+				continue
+			}
+
+			ID := f.Name()
+			meta, err := readMetadata(filepath.Join(dir, metadataDir, ID))
+			if err != nil {
+				return nil, err
+			}
+
+			vol, err := storage.NewVolume(store, ID, meta, v.export(store, ID))
+			if err != nil {
+				return nil, err
+			}
+
+			volumes = append(volumes, vol)
+		}
+	}
+
+	return volumes, nil
+}
+
+// writeMetadata writes each metadata blob to a file with its name in dir
+func writeMetadata(dir string, meta map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for name, value := range meta {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), value, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMetadata reads the metadata blobs written by writeMetadata, nil if there are none
+func readMetadata(dir string) (map[string][]byte, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	meta := make(map[string][]byte)
+	for _, f := range files {
+		value, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		meta[f.Name()] = value
+	}
+	return meta, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nfs
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/util"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// mockMounter records the mounts instead of mounting the exports
+type mockMounter struct {
+	mounts map[string]url.URL
+}
+
+func (m *mockMounter) Mount(op trace.Operation, export *url.URL, target string) error {
+	m.mounts[target] = *export
+	return nil
+}
+
+func TestVolumeStore(t *testing.T) {
+	op := trace.NewOperation(context.Background(), "test")
+	exec.NewContainerCache()
+
+	root, err := ioutil.TempDir("", "nfs")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	mounter := &mockMounter{mounts: make(map[string]url.URL)}
+	vs := NewVolumeStore(op, mounter, root)
+
+	_, err = vs.AddStore(op, &url.URL{Scheme: "http", Host: "10.0.0.5", Path: "/export"}, "bad")
+	assert.Error(t, err)
+
+	export := &url.URL{Scheme: "nfs", Host: "10.0.0.5", Path: "/export"}
+	store, err := vs.AddStore(op, export, "shared")
+	require.NoError(t, err)
+	assert.Equal(t, *export, mounter.mounts[filepath.Join(root, "shared")])
+
+	_, err = vs.AddStore(op, export, "shared")
+	assert.Error(t, err, "volume store added twice")
+
+	stores, err := vs.VolumeStoresList(op)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]url.URL{"shared": *export}, stores)
+	}
+
+	info := map[string][]byte{"foo": []byte("bar")}
+	vol, err := vs.VolumeCreate(op, "vol1", store, 1024, info)
+	require.NoError(t, err)
+	assert.Equal(t, "nfs://10.0.0.5/export/volumes/vol1", vol.Device.DiskPath())
+	assert.True(t, dirExists(filepath.Join(root, "shared", VolumesDir, "vol1")))
+
+	_, err = vs.VolumeCreate(op, "vol1", store, 1024, info)
+	assert.Error(t, err, "volume created twice")
+
+	missing, _ := util.VolumeStoreNameToURL("missing")
+	_, err = vs.VolumeCreate(op, "vol2", missing, 1024, info)
+	assert.Error(t, err)
+
+	vols, err := vs.VolumesList(op)
+	if assert.NoError(t, err) && assert.Len(t, vols, 1) {
+		assert.Equal(t, "vol1", vols[0].ID)
+		assert.Equal(t, info, vols[0].Info)
+		assert.Equal(t, vol.Device.DiskPath(), vols[0].Device.DiskPath())
+	}
+
+	handle := exec.TestHandle("container")
+	handle, err = VolumeJoin(op, handle, vol, "/data", map[string]string{"Mode": "rw"})
+	require.NoError(t, err)
+	assert.Equal(t, executor.MountSpec{
+		Source: url.URL{Scheme: "nfs", Host: "10.0.0.5", Path: "/export/volumes/vol1"},
+		Path:   "/data",
+		Mode:   "rw",
+	}, handle.ExecConfig.Mounts["vol1"])
+	assert.Empty(t, handle.Spec.DeviceChange, "no device is attached for NFS volumes")
+
+	_, err = VolumeJoin(op, handle, vol, "/data", nil)
+	assert.Error(t, err, "volume joined twice")
+
+	require.NoError(t, vs.VolumeDestroy(op, vol))
+	assert.False(t, dirExists(filepath.Join(root, "shared", VolumesDir, "vol1")))
+	assert.False(t, dirExists(filepath.Join(root, "shared", metadataDir, "vol1")))
+
+	vols, err = vs.VolumesList(op)
+	if assert.NoError(t, err) {
+		assert.Empty(t, vols)
+	}
+}
+
+func dirExists(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
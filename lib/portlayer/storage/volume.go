@@ -22,10 +22,15 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/vmware/vic/lib/portlayer/exec"
 	"github.com/vmware/vic/lib/portlayer/util"
 	"github.com/vmware/vic/pkg/trace"
 )
 
+// StoragePolicyKey is the volume metadata key holding the ID of the vSphere storage policy of the volume
+// disk. Given at creation it overrides the policy of the volume store.
+const StoragePolicyKey = "StoragePolicy"
+
 type Disk interface {
 	MountPath() (string, error)
 	DiskPath() string
@@ -139,3 +144,26 @@ func (v *Volume) Parse(u *url.URL) error {
 
 	return nil
 }
+
+// VolumeInUse returns an ErrVolumeInUse error if a container has the volume with the given ID mounted
+func VolumeInUse(ID string) error {
+	conts := exec.Containers.Containers(nil)
+	if len(conts) == 0 {
+		return nil
+	}
+
+	for _, cont := range conts {
+
+		if cont.ExecConfig.Mounts == nil {
+			continue
+		}
+
+		if _, mounted := cont.ExecConfig.Mounts[ID]; mounted {
+			return &ErrVolumeInUse{
+				Msg: fmt.Sprintf("volume %s in use by %s", ID, cont.ExecConfig.ID),
+			}
+		}
+	}
+
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"sync"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/vmware/vic/lib/portlayer/store"
 	"github.com/vmware/vic/pkg/trace"
 )
 
@@ -50,7 +51,7 @@ func (v *VolumeLookupCache) VolumeStoresList(op trace.Operation) (map[string]url
 	return v.volumeStore.VolumeStoresList(op)
 }
 
-func (v *VolumeLookupCache) VolumeCreate(op trace.Operation, ID string, store *url.URL, capacityKB uint64, info map[string][]byte) (*Volume, error) {
+func (v *VolumeLookupCache) VolumeCreate(op trace.Operation, ID string, storeURL *url.URL, capacityKB uint64, info map[string][]byte) (*Volume, error) {
 	v.vlcLock.Lock()
 	defer v.vlcLock.Unlock()
 
@@ -60,8 +61,17 @@ func (v *VolumeLookupCache) VolumeCreate(op trace.Operation, ID string, store *u
 		return nil, os.ErrExist
 	}
 
-	vol, err := v.volumeStore.VolumeCreate(op, ID, store, capacityKB, info)
+	// the name is held until the volume is destroyed
+	if err := store.Names().Reserve(op, store.VolumeName, ID, volumeOwner(storeURL)); err != nil {
+		if _, ok := err.(store.NameReservedError); ok {
+			return nil, os.ErrExist
+		}
+		return nil, err
+	}
+
+	vol, err := v.volumeStore.VolumeCreate(op, ID, storeURL, capacityKB, info)
 	if err != nil {
+		store.Names().Release(op, store.VolumeName, ID, volumeOwner(storeURL))
 		return nil, err
 	}
 	// Add it to the cache.
@@ -86,6 +96,10 @@ func (v *VolumeLookupCache) VolumeDestroy(op trace.Operation, ID string) error {
 	}
 	delete(v.vlc, vol.ID)
 
+	if err := store.Names().Release(op, store.VolumeName, vol.ID, volumeOwner(vol.Store)); err != nil {
+		log.Warnf("Unable to release name of volume %s: %s", vol.ID, err)
+	}
+
 	return nil
 }
 
@@ -132,11 +146,19 @@ func (v *VolumeLookupCache) rebuildCache(op trace.Operation) error {
 		return err
 	}
 
+	inUse := make(map[string]string)
 	for _, vol := range vols {
 		log.Infof("Volumestore: Found vol %s on store %s.", vol.ID, vol.Store)
 		// Add it to the cache.
 		v.vlc[vol.ID] = *vol
+		inUse[vol.ID] = volumeOwner(vol.Store)
 	}
 
-	return nil
+	// volumes are only created through the cache, so any other reservation is stale
+	return store.Names().Reconcile(op, store.VolumeName, inUse, 0)
+}
+
+// volumeOwner is the holder of the name of a volume, the volume store it is created on
+func volumeOwner(storeURL *url.URL) string {
+	return storeURL.String()
 }
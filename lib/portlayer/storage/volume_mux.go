@@ -0,0 +1,102 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/vmware/vic/lib/portlayer/util"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// VolumeMux is a VolumeStorer over volume store implementations of different types, e.g. datastores
+// and NFS exports, routing each request to the implementation holding the volume store
+type VolumeMux struct {
+	stores []VolumeStorer
+}
+
+// NewVolumeMux returns a VolumeStorer over the given implementations, whose volume store names must not overlap
+func NewVolumeMux(stores ...VolumeStorer) *VolumeMux {
+	return &VolumeMux{stores: stores}
+}
+
+// storer returns the implementation holding the volume store at store
+func (m *VolumeMux) storer(op trace.Operation, store *url.URL) (VolumeStorer, error) {
+	storeName, err := util.VolumeStoreName(store)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range m.stores {
+		names, err := s.VolumeStoresList(op)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := names[storeName]; ok {
+			return s, nil
+		}
+	}
+
+	return nil, VolumeStoreNotFoundError{Msg: fmt.Sprintf("volume store (%s) not found", store.String())}
+}
+
+func (m *VolumeMux) VolumeCreate(op trace.Operation, ID string, store *url.URL, capacityKB uint64, info map[string][]byte) (*Volume, error) {
+	s, err := m.storer(op, store)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.VolumeCreate(op, ID, store, capacityKB, info)
+}
+
+func (m *VolumeMux) VolumeDestroy(op trace.Operation, vol *Volume) error {
+	s, err := m.storer(op, vol.Store)
+	if err != nil {
+		return err
+	}
+
+	return s.VolumeDestroy(op, vol)
+}
+
+func (m *VolumeMux) VolumesList(op trace.Operation) ([]*Volume, error) {
+	var volumes []*Volume
+	for _, s := range m.stores {
+		vols, err := s.VolumesList(op)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, vols...)
+	}
+
+	return volumes, nil
+}
+
+func (m *VolumeMux) VolumeStoresList(op trace.Operation) (map[string]url.URL, error) {
+	stores := make(map[string]url.URL)
+	for _, s := range m.stores {
+		names, err := s.VolumeStoresList(op)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, u := range names {
+			stores[name] = u
+		}
+	}
+
+	return stores, nil
+}
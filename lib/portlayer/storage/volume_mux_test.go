@@ -0,0 +1,73 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/portlayer/util"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// storeMockVolumeStore is a MockVolumeStore holding the named volume stores
+type storeMockVolumeStore struct {
+	*MockVolumeStore
+	stores map[string]url.URL
+}
+
+func (m *storeMockVolumeStore) VolumeStoresList(op trace.Operation) (map[string]url.URL, error) {
+	return m.stores, nil
+}
+
+func TestVolumeMux(t *testing.T) {
+	op := trace.NewOperation(context.Background(), "test")
+
+	ds := &storeMockVolumeStore{NewMockVolumeStore(), map[string]url.URL{"default": {Scheme: "ds", Host: "datastore1", Path: "/volumes"}}}
+	nfs := &storeMockVolumeStore{NewMockVolumeStore(), map[string]url.URL{"shared": {Scheme: "nfs", Host: "10.0.0.5", Path: "/export"}}}
+	mux := NewVolumeMux(ds, nfs)
+
+	stores, err := mux.VolumeStoresList(op)
+	if assert.NoError(t, err) {
+		assert.Len(t, stores, 2)
+	}
+
+	defaultURL, _ := util.VolumeStoreNameToURL("default")
+	sharedURL, _ := util.VolumeStoreNameToURL("shared")
+	missingURL, _ := util.VolumeStoreNameToURL("missing")
+
+	_, err = mux.VolumeCreate(op, "local", defaultURL, 1024, nil)
+	assert.NoError(t, err)
+	vol, err := mux.VolumeCreate(op, "share", sharedURL, 0, nil)
+	assert.NoError(t, err)
+
+	assert.Contains(t, ds.db, "local")
+	assert.Contains(t, nfs.db, "share")
+
+	_, err = mux.VolumeCreate(op, "lost", missingURL, 0, nil)
+	assert.IsType(t, VolumeStoreNotFoundError{}, err)
+
+	vols, err := mux.VolumesList(op)
+	if assert.NoError(t, err) {
+		assert.Len(t, vols, 2)
+	}
+
+	assert.NoError(t, mux.VolumeDestroy(op, vol))
+	assert.NotContains(t, nfs.db, "share")
+	assert.Contains(t, ds.db, "local")
+}
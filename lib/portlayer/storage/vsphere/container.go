@@ -0,0 +1,142 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/portlayer/exec"
+	"github.com/vmware/vic/lib/portlayer/storage"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/disk"
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+// ContainerStore gives the appliance access to the filesystems of powered off containerVMs
+// by attaching their read-write layer disks.
+type ContainerStore struct {
+	dm *disk.Manager
+}
+
+func NewContainerStore(op trace.Operation, s *session.Session) (*ContainerStore, error) {
+	dm, err := disk.NewDiskManager(op, s)
+	if err != nil {
+		return nil, err
+	}
+	dm.Provisioning = disk.Provisioning(storage.Config.DiskProvisioning)
+
+	return &ContainerStore{dm: dm}, nil
+}
+
+// layerDisk returns the datastore path of the read-write layer of the containerVM, which is
+// the only disk backed by a child of an image.
+func layerDisk(info *exec.ContainerInfo) (string, error) {
+	if info.Config == nil {
+		return "", fmt.Errorf("no configuration for container %s", info.ExecConfig.ID)
+	}
+
+	for _, device := range info.Config.Hardware.Device {
+		vdisk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+
+		backing, ok := vdisk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+		if ok && backing.Parent != nil {
+			return backing.FileName, nil
+		}
+	}
+
+	return "", fmt.Errorf("no layer disk found for container %s", info.ExecConfig.ID)
+}
+
+// VolumePath returns the mount path of the volume of the container holding the container path
+// p, or the empty string if p is not on a volume.
+func VolumePath(info *exec.ContainerInfo, p string) string {
+	p = path.Clean("/" + p)
+
+	for _, mount := range info.ExecConfig.Mounts {
+		mp := path.Clean("/" + mount.Path)
+		if p == mp || strings.HasPrefix(p, strings.TrimSuffix(mp, "/")+"/") {
+			return mount.Path
+		}
+	}
+
+	return ""
+}
+
+// Mount attaches the layer disk of the powered off container and mounts it, returning the mount
+// point. Changes are discarded unless write is set. The returned function unmounts and detaches
+// the disk and must be called once the filesystem is no longer in use.
+func (c *ContainerStore) Mount(op trace.Operation, info *exec.ContainerInfo, write bool) (string, func(), error) {
+	defer trace.End(trace.Begin(info.ExecConfig.ID))
+
+	if info.State() == exec.StateRunning {
+		return "", nil, fmt.Errorf("container %s is running", info.ExecConfig.ID)
+	}
+
+	diskDsURI, err := layerDisk(info)
+	if err != nil {
+		return "", nil, err
+	}
+
+	flags := os.O_RDONLY
+	if write {
+		flags = os.O_RDWR
+	}
+
+	// attaching an existing disk requires no parent or capacity
+	vmdisk, err := c.dm.CreateAndAttach(op, diskDsURI, "", 0, flags)
+	if err != nil {
+		return "", nil, err
+	}
+
+	detach := func() {
+		if err := c.dm.Detach(op, vmdisk); err != nil {
+			log.Errorf("Failed to detach layer disk of container %s: %s", info.ExecConfig.ID, err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "mnt-"+info.ExecConfig.ID)
+	if err != nil {
+		detach()
+		return "", nil, err
+	}
+
+	if err = vmdisk.Mount(dir, nil); err != nil {
+		os.RemoveAll(dir)
+		detach()
+		return "", nil, err
+	}
+
+	cleanup := func() {
+		if err := vmdisk.Unmount(); err != nil {
+			log.Errorf("Failed to unmount layer disk of container %s: %s", info.ExecConfig.ID, err)
+			// leave the disk attached rather than pull it from under the mount
+			return
+		}
+		os.RemoveAll(dir)
+		detach()
+	}
+
+	return dir, cleanup, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/portlayer/exec"
+)
+
+func TestContainerLayerDisk(t *testing.T) {
+	info := &exec.ContainerInfo{}
+	info.ExecConfig = &executor.ExecutorConfig{
+		Common: executor.Common{ID: "abc"},
+		Mounts: map[string]executor.MountSpec{
+			"vol": {Path: "/var/lib/data"},
+		},
+	}
+
+	_, err := layerDisk(info)
+	assert.Error(t, err)
+
+	image := &types.VirtualDiskFlatVer2BackingInfo{
+		VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{FileName: "[ds] VIC/images/abc/abc.vmdk"},
+	}
+	info.Config = &types.VirtualMachineConfigInfo{
+		Hardware: types.VirtualHardware{
+			Device: []types.BaseVirtualDevice{
+				&types.VirtualCdrom{},
+				// volumes have no parent
+				&types.VirtualDisk{
+					VirtualDevice: types.VirtualDevice{
+						Backing: &types.VirtualDiskFlatVer2BackingInfo{
+							VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{FileName: "[ds] volumes/vol/vol.vmdk"},
+						},
+					},
+				},
+				&types.VirtualDisk{
+					VirtualDevice: types.VirtualDevice{
+						Backing: &types.VirtualDiskFlatVer2BackingInfo{
+							VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{FileName: "[ds] abc/abc.vmdk"},
+							Parent:                       image,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	uri, err := layerDisk(info)
+	assert.NoError(t, err)
+	assert.Equal(t, "[ds] abc/abc.vmdk", uri)
+
+	assert.Equal(t, "/var/lib/data", VolumePath(info, "/var/lib/data"))
+	assert.Equal(t, "/var/lib/data", VolumePath(info, "var/lib/data/db/../file"))
+	assert.Equal(t, "", VolumePath(info, "/var/lib/database"))
+	assert.Equal(t, "", VolumePath(info, "/"))
+}
@@ -22,6 +22,8 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strings"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/archive"
@@ -62,6 +64,11 @@ type ImageStore struct {
 	// disk.  So, for now, persist this data in the datastore and look it up
 	// when we need it.
 	parents *parentM
+
+	// Images being written, keyed by image store and ID, which cleanup must leave alone as they
+	// do not have a manifest until the write completes
+	writingLock sync.Mutex
+	writing     map[string]bool
 }
 
 func NewImageStore(op trace.Operation, s *session.Session, u *url.URL) (*ImageStore, error) {
@@ -69,6 +76,7 @@ func NewImageStore(op trace.Operation, s *session.Session, u *url.URL) (*ImageSt
 	if err != nil {
 		return nil, err
 	}
+	dm.Provisioning = disk.Provisioning(portlayer.Config.DiskProvisioning)
 
 	datastores, err := s.Finder.DatastoreList(op, u.Host)
 	if err != nil {
@@ -85,9 +93,10 @@ func NewImageStore(op trace.Operation, s *session.Session, u *url.URL) (*ImageSt
 	}
 
 	vis := &ImageStore{
-		dm: dm,
-		ds: ds,
-		s:  s,
+		dm:      dm,
+		ds:      ds,
+		s:       s,
+		writing: make(map[string]bool),
 	}
 
 	return vis, nil
@@ -240,7 +249,10 @@ func (v *ImageStore) WriteImage(op trace.Operation, parent *portlayer.Image, ID
 			return nil, err
 		}
 
-		if err := v.writeImage(op, storeName, parent.ID, ID, meta, sum, r); err != nil {
+		v.setWriting(storeName, ID, true)
+		err := v.writeImage(op, storeName, parent.ID, ID, meta, sum, r)
+		v.setWriting(storeName, ID, false)
+		if err != nil {
 			return nil, err
 		}
 
@@ -310,7 +322,7 @@ func (v *ImageStore) writeImage(op trace.Operation, storeName, parentID, ID stri
 	}()
 
 	// Create the disk
-	vmdisk, err = v.dm.CreateAndAttach(op, diskDsURI, parentDiskDsURI, 0, os.O_RDWR)
+	vmdisk, err = v.dm.CreateAndAttachWithPolicy(op, diskDsURI, parentDiskDsURI, portlayer.Config.ImageStoragePolicy, 0, os.O_RDWR)
 	if err != nil {
 		return err
 	}
@@ -352,8 +364,8 @@ func (v *ImageStore) writeImage(op trace.Operation, storeName, parentID, ID stri
 
 	// Write our own bookkeeping manifest file to the image's directory.  We
 	// treat the manifest file like a done file.  Its existence means this vmdk
-	// is consistent.
-	if err = v.writeManifest(op, storeName, ID, nil); err != nil {
+	// is consistent.  It holds the digest the layer was verified against.
+	if err = v.writeManifest(op, storeName, ID, strings.NewReader(sum)); err != nil {
 		return err
 	}
 
@@ -384,7 +396,7 @@ func (v *ImageStore) scratch(op trace.Operation, storeName string) error {
 	}
 
 	// Create the disk
-	vmdisk, err := v.dm.CreateAndAttach(op, imageDiskDsURI, "", size, os.O_RDWR)
+	vmdisk, err := v.dm.CreateAndAttachWithPolicy(op, imageDiskDsURI, "", portlayer.Config.ImageStoragePolicy, size, os.O_RDWR)
 	if err != nil {
 		return err
 	}
@@ -465,26 +477,28 @@ func (v *ImageStore) ListImages(op trace.Operation, store *url.URL, IDs []string
 		return nil, err
 	}
 
-	res, err := v.ds.Ls(op, v.imageStorePath(storeName))
-	if err != nil {
-		return nil, err
-	}
-
 	images := []*portlayer.Image{}
-	for _, f := range res.File {
-		file, ok := f.(*types.FileInfo)
-		if !ok {
-			continue
-		}
+	err = v.ds.LsEach(op, v.imageStorePath(storeName), 0, func(files []types.BaseFileInfo) error {
+		for _, f := range files {
+			file, ok := f.(*types.FileInfo)
+			if !ok {
+				continue
+			}
 
-		ID := file.Path
+			ID := file.Path
 
-		img, err := v.GetImage(op, store, ID)
-		if err != nil {
-			return nil, err
+			img, err := v.GetImage(op, store, ID)
+			if err != nil {
+				return err
+			}
+
+			images = append(images, img)
 		}
 
-		images = append(images, img)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return images, nil
@@ -515,43 +529,78 @@ func (v *ImageStore) DeleteImage(op trace.Operation, image *portlayer.Image) err
 	return nil
 }
 
-// Find any image directories without the manifest file and remove them.
-func (v *ImageStore) cleanup(op trace.Operation, store *url.URL) error {
-	log.Infof("Checking for inconsistent images on %s", store.String())
+// setWriting records whether the image is being written
+func (v *ImageStore) setWriting(storeName, ID string, writing bool) {
+	v.writingLock.Lock()
+	defer v.writingLock.Unlock()
 
-	storeName, err := util.ImageStoreName(store)
+	key := path.Join(storeName, ID)
+	if writing {
+		v.writing[key] = true
+	} else {
+		delete(v.writing, key)
+	}
+}
+
+// isWriting reports whether the image is being written
+func (v *ImageStore) isWriting(storeName, ID string) bool {
+	v.writingLock.Lock()
+	defer v.writingLock.Unlock()
+
+	return v.writing[path.Join(storeName, ID)]
+}
+
+// Cleanup removes the inconsistent images, such as those left by interrupted pulls, from all the
+// image stores. Images being written are left alone.
+func (v *ImageStore) Cleanup(op trace.Operation) error {
+	stores, err := v.ListImageStores(op)
 	if err != nil {
 		return err
 	}
 
-	res, err := v.ds.Ls(op, v.imageStorePath(storeName))
+	for _, store := range stores {
+		if err := v.cleanup(op, store); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find any image directories without the manifest file and remove them.
+func (v *ImageStore) cleanup(op trace.Operation, store *url.URL) error {
+	log.Infof("Checking for inconsistent images on %s", store.String())
+
+	storeName, err := util.ImageStoreName(store)
 	if err != nil {
 		return err
 	}
 
-	for _, f := range res.File {
-		file, ok := f.(*types.FileInfo)
-		if !ok {
-			continue
-		}
+	return v.ds.LsEach(op, v.imageStorePath(storeName), 0, func(files []types.BaseFileInfo) error {
+		for _, f := range files {
+			file, ok := f.(*types.FileInfo)
+			if !ok {
+				continue
+			}
 
-		ID := file.Path
+			ID := file.Path
 
-		if ID == portlayer.Scratch.ID {
-			continue
-		}
+			if ID == portlayer.Scratch.ID || v.isWriting(storeName, ID) {
+				continue
+			}
 
-		if err := v.verifyImage(op, storeName, ID); err != nil {
-			imageDir := v.imageDirPath(storeName, ID)
-			log.Infof("Removing inconsistent image (%s) %s", ID, imageDir)
+			if err := v.verifyImage(op, storeName, ID); err != nil {
+				imageDir := v.imageDirPath(storeName, ID)
+				log.Infof("Removing inconsistent image (%s) %s", ID, imageDir)
 
-			// Eat the error so we can continue cleaning up.  The tasks package will log the error if there is one.
-			_ = v.ds.Rm(op, imageDir)
+				// Eat the error so we can continue cleaning up.  The tasks package will log the error if there is one.
+				_ = v.ds.Rm(op, imageDir)
 
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // Manifest file for the image.
@@ -0,0 +1,89 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/vmware/govmomi/object"
+
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/datastore"
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+// PlaceImageStore chooses the image store to hold container image layers. A store that already
+// holds image data is reused so that existing images remain available across restarts, otherwise
+// the store is chosen according to the placement policy.
+func PlaceImageStore(op trace.Operation, s *session.Session, stores []url.URL, policy string) (*url.URL, error) {
+	defer trace.End(trace.Begin(""))
+
+	if len(stores) == 0 {
+		return nil, fmt.Errorf("no image stores provided")
+	}
+
+	if len(stores) == 1 {
+		return &stores[0], nil
+	}
+
+	for i := range stores {
+		u := &stores[i]
+
+		ok, err := hasImageData(op, s, u)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			op.Infof("Using image store %s as it holds existing images", u.String())
+			return u, nil
+		}
+	}
+
+	placer, err := datastore.NewPlacer(policy, stores, datastore.FreeSpace(s))
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := placer.Place(op)
+	if err != nil {
+		return nil, err
+	}
+
+	op.Infof("Placed image store on %s", u.String())
+	return u, nil
+}
+
+// hasImageData returns true if the VIC storage directory exists on the image store
+func hasImageData(op trace.Operation, s *session.Session, u *url.URL) (bool, error) {
+	ds, err := s.Finder.Datastore(op, u.Host)
+	if err != nil {
+		return false, fmt.Errorf("unable to find image store %s: %s", u.String(), err)
+	}
+
+	_, err = ds.Stat(op, path.Join(u.Path, StorageParentDir))
+	if err == nil {
+		return true, nil
+	}
+
+	switch err.(type) {
+	case object.DatastoreNoSuchDirectoryError, object.DatastoreNoSuchFileError:
+		return false, nil
+	}
+
+	return false, err
+}
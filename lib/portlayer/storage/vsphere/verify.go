@@ -0,0 +1,265 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/lib/metadata"
+	portlayer "github.com/vmware/vic/lib/portlayer/storage"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/datastore"
+)
+
+// QuarantineDir is the directory of an image store that damaged layers are moved to
+const QuarantineDir = "quarantine"
+
+// digestRegex matches the layer digests recorded in the manifest file of a layer
+var digestRegex = regexp.MustCompile("^sha256:[a-f0-9]{64}$")
+
+// ImageFault is a damaged layer found in an image store
+type ImageFault struct {
+	// ID of the layer
+	ID string
+	// Reason the layer is damaged, or the damaged layer it is built on
+	Reason string
+}
+
+// layer is what VerifyImages finds of a layer in the image store
+type layer struct {
+	manifest *string
+	disk     bool
+	meta     map[string][]byte
+	metaErr  error
+}
+
+// VerifyImages checks the layers of the image store storeName on ds and returns those that are damaged,
+// along with the layers built on them, and the number of layers checked. The contents of a layer are checked against its digest when it is
+// written, so this checks what is recorded of each layer: its manifest and the digest in it, its disk, its
+// metadata, and that its parent is present.
+func VerifyImages(op trace.Operation, ds *datastore.Helper, storeName string) ([]ImageFault, int, error) {
+	defer trace.End(trace.Begin(storeName))
+
+	imagesDir := path.Join(storeName, StorageImageDir)
+	layers := make(map[string]*layer)
+	err := ds.LsEach(op, imagesDir, 0, func(files []types.BaseFileInfo) error {
+		for _, f := range files {
+			file, ok := f.(*types.FileInfo)
+			if !ok {
+				continue
+			}
+
+			ID := file.Path
+			l, err := readLayer(op, ds, path.Join(imagesDir, ID), ID)
+			if err != nil {
+				return err
+			}
+			layers[ID] = l
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	parents, err := restoreParentMap(op, ds, storeName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return imageFaults(layers, parents.db), len(layers), nil
+}
+
+// readLayer lists the files of the layer in dir
+func readLayer(op trace.Operation, ds *datastore.Helper, dir, ID string) (*layer, error) {
+	res, err := ds.Ls(op, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &layer{}
+	meta := false
+	for _, f := range res.File {
+		switch f.GetFileInfo().Path {
+		case manifest:
+			rc, err := ds.Download(op, path.Join(dir, manifest))
+			if err != nil {
+				return nil, err
+			}
+			buf, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			s := string(buf)
+			l.manifest = &s
+		case ID + ".vmdk":
+			l.disk = true
+		case metaDataDir:
+			meta = true
+		}
+	}
+
+	if meta {
+		l.meta, l.metaErr = getMetadata(op, ds, path.Join(dir, metaDataDir))
+	} else {
+		l.metaErr = fmt.Errorf("no %s directory", metaDataDir)
+	}
+
+	return l, nil
+}
+
+// imageFaults returns the damaged layers, and the layers built on them, sorted by ID. parents maps layers
+// to their parent layer.
+func imageFaults(layers map[string]*layer, parents map[string]string) []ImageFault {
+	damaged := make(map[string]string)
+	for ID, l := range layers {
+		if reason := layerFault(ID, l, parents[ID], layers); reason != "" {
+			damaged[ID] = reason
+		}
+	}
+
+	IDs := make([]string, 0, len(layers))
+	for ID := range layers {
+		IDs = append(IDs, ID)
+	}
+	sort.Strings(IDs)
+
+	var faults []ImageFault
+	for _, ID := range IDs {
+		reason, ok := damaged[ID]
+		if !ok {
+			// a layer built on a damaged layer cannot be used either
+			for p, n := parents[ID], 0; p != "" && n < len(layers); p, n = parents[p], n+1 {
+				if _, ok := damaged[p]; ok {
+					reason = fmt.Sprintf("built on damaged layer %s", p)
+					break
+				}
+			}
+		}
+
+		if reason != "" {
+			faults = append(faults, ImageFault{ID: ID, Reason: reason})
+		}
+	}
+
+	return faults
+}
+
+// layerFault returns why the layer is damaged, or an empty string if it is not
+func layerFault(ID string, l *layer, parent string, layers map[string]*layer) string {
+	if l.manifest == nil {
+		return "missing manifest, the layer was not completely written"
+	}
+	// layers written before digests were recorded, and the scratch layer, have an empty manifest
+	if *l.manifest != "" && !digestRegex.MatchString(*l.manifest) {
+		return "corrupt manifest, the layer digest is unreadable"
+	}
+	if !l.disk {
+		return "missing disk"
+	}
+	if l.metaErr != nil {
+		return fmt.Sprintf("unreadable metadata: %s", l.metaErr)
+	}
+
+	if ID == portlayer.Scratch.ID {
+		return ""
+	}
+
+	if parent == "" {
+		return "missing from the parent map"
+	}
+	if _, ok := layers[parent]; !ok {
+		return fmt.Sprintf("missing parent layer %s", parent)
+	}
+
+	blob, ok := l.meta[metadata.MetaDataKey]
+	if !ok {
+		return ""
+	}
+
+	var v1 struct {
+		ID     string `json:"id"`
+		Parent string `json:"parent"`
+	}
+	if err := json.Unmarshal(blob, &v1); err != nil {
+		return fmt.Sprintf("corrupt metadata: %s", err)
+	}
+	if v1.ID != ID {
+		return fmt.Sprintf("metadata is for layer %s", v1.ID)
+	}
+	// the base layer of an image has no parent in its metadata, but scratch in the parent map
+	if (v1.Parent != "" && v1.Parent != parent) || (v1.Parent == "" && parent != portlayer.Scratch.ID) {
+		return fmt.Sprintf("metadata has parent %q, the parent map %s", v1.Parent, parent)
+	}
+
+	return ""
+}
+
+// QuarantineImages moves the damaged layers out of the image store, where the portlayer no longer sees
+// them but they can still be examined. The scratch layer is left in place, as the image store cannot
+// be used without it.
+func QuarantineImages(op trace.Operation, ds *datastore.Helper, storeName string, faults []ImageFault) error {
+	defer trace.End(trace.Begin(storeName))
+
+	dir := path.Join(storeName, QuarantineDir)
+	if _, err := ds.Mkdir(op, true, dir); err != nil {
+		return err
+	}
+
+	suffix := time.Now().UTC().Format("20060102T150405Z")
+	for _, f := range faults {
+		if f.ID == portlayer.Scratch.ID {
+			continue
+		}
+
+		log.Infof("Quarantining layer %s: %s", f.ID, f.Reason)
+		if err := ds.Mv(op, path.Join(storeName, StorageImageDir, f.ID), path.Join(dir, f.ID+"-"+suffix)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveImages deletes the damaged layers from the image store, so that they are written again by the
+// next pull of the images using them. The scratch layer is left in place, as the image store cannot be
+// used without it.
+func RemoveImages(op trace.Operation, ds *datastore.Helper, storeName string, faults []ImageFault) error {
+	defer trace.End(trace.Begin(storeName))
+
+	for _, f := range faults {
+		if f.ID == portlayer.Scratch.ID {
+			continue
+		}
+
+		log.Infof("Removing layer %s: %s", f.ID, f.Reason)
+		if err := ds.Rm(op, path.Join(storeName, StorageImageDir, f.ID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
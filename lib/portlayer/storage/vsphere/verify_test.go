@@ -0,0 +1,93 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsphere
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/metadata"
+	portlayer "github.com/vmware/vic/lib/portlayer/storage"
+)
+
+func TestImageFaults(t *testing.T) {
+	digest := "sha256:b5ae5b3b1d8ca8d0a36a5b4b5d9f3f4a2d2a9d1b0b1a6b5c4a8f4d3e2c1b0a99"
+	empty := ""
+	corrupt := "not a digest"
+
+	intact := func(id, parent string) *layer {
+		v1 := `{"id":"` + id + `","parent":"` + parent + `"}`
+		return &layer{manifest: &digest, disk: true, meta: map[string][]byte{metadata.MetaDataKey: []byte(v1)}}
+	}
+
+	layers := map[string]*layer{
+		portlayer.Scratch.ID: {manifest: &empty, disk: true},
+		"base":               intact("base", ""),
+		"top":                intact("top", "base"),
+		"incomplete":         {disk: true},
+		"child":              intact("child", "incomplete"),
+		"grandchild":         intact("grandchild", "child"),
+		"nodisk":             {manifest: &digest},
+		"badmanifest":        {manifest: &corrupt, disk: true},
+		"badmeta":            {manifest: &digest, disk: true, meta: map[string][]byte{metadata.MetaDataKey: []byte("{")}},
+		"unreadable":         {manifest: &digest, disk: true, metaErr: errors.New("no such file")},
+		"wrongid":            intact("other", ""),
+		"orphan":             intact("orphan", "gone"),
+		"legacy":             {manifest: &empty, disk: true},
+	}
+	parents := map[string]string{
+		"base":        portlayer.Scratch.ID,
+		"top":         "base",
+		"incomplete":  "base",
+		"child":       "incomplete",
+		"grandchild":  "child",
+		"nodisk":      "base",
+		"badmanifest": "base",
+		"badmeta":     "base",
+		"unreadable":  "base",
+		"wrongid":     portlayer.Scratch.ID,
+		"orphan":      "gone",
+		"legacy":      "base",
+	}
+
+	faults := imageFaults(layers, parents)
+
+	reasons := make(map[string]string)
+	var IDs []string
+	for _, f := range faults {
+		reasons[f.ID] = f.Reason
+		IDs = append(IDs, f.ID)
+	}
+
+	assert.Equal(t, []string{"badmanifest", "badmeta", "child", "grandchild", "incomplete", "nodisk", "orphan", "unreadable", "wrongid"}, IDs)
+	assert.Contains(t, reasons["incomplete"], "missing manifest")
+	assert.Equal(t, "built on damaged layer incomplete", reasons["child"])
+	assert.Equal(t, "built on damaged layer incomplete", reasons["grandchild"])
+	assert.Equal(t, "missing disk", reasons["nodisk"])
+	assert.Contains(t, reasons["badmanifest"], "corrupt manifest")
+	assert.Contains(t, reasons["badmeta"], "corrupt metadata")
+	assert.Contains(t, reasons["unreadable"], "unreadable metadata")
+	assert.Equal(t, "metadata is for layer other", reasons["wrongid"])
+	assert.Equal(t, "missing parent layer gone", reasons["orphan"])
+
+	// a parent map that does not match the metadata
+	parents["top"] = portlayer.Scratch.ID
+	faults = imageFaults(map[string]*layer{portlayer.Scratch.ID: layers[portlayer.Scratch.ID], "base": layers["base"], "top": layers["top"]}, parents)
+	if assert.Len(t, faults, 1) {
+		assert.Equal(t, "top", faults[0].ID)
+	}
+}
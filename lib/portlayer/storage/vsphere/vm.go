@@ -37,8 +37,9 @@ func VolumeJoin(op trace.Operation, handle *exec.Handle, volume *storage.Volume,
 			Scheme: "label",
 			Path:   volume.Label,
 		},
-		Path: mountPath,
-		Mode: diskOpts["Mode"],
+		Path:      mountPath,
+		Mode:      diskOpts["Mode"],
+		Anonymous: diskOpts["Anonymous"] == "true",
 	}
 
 	unitNumber := int32(-1)
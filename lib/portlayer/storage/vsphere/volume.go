@@ -24,7 +24,6 @@ import (
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/vmware/govmomi/vim25/types"
-	"github.com/vmware/vic/lib/portlayer/exec"
 	"github.com/vmware/vic/lib/portlayer/storage"
 	"github.com/vmware/vic/lib/portlayer/util"
 	"github.com/vmware/vic/pkg/trace"
@@ -53,6 +52,7 @@ func NewVolumeStore(op trace.Operation, s *session.Session) (*VolumeStore, error
 	if err != nil {
 		return nil, err
 	}
+	dm.Provisioning = disk.Provisioning(storage.Config.DiskProvisioning)
 
 	v := &VolumeStore{
 		dm:   dm,
@@ -176,8 +176,19 @@ func (v *VolumeStore) VolumeCreate(op trace.Operation, ID string, store *url.URL
 		return nil, err
 	}
 
-	// Create the disk
-	vmdisk, err := v.dm.CreateAndAttach(op, volDiskDsURL, "", int64(capacityKB), os.O_RDWR)
+	// Create the disk, with the storage policy recorded in the metadata so that it is reported
+	policy, err := volumeStoragePolicy(store, info)
+	if err != nil {
+		return nil, err
+	}
+	if policy != "" {
+		if info == nil {
+			info = make(map[string][]byte)
+		}
+		info[storage.StoragePolicyKey] = []byte(policy)
+	}
+
+	vmdisk, err := v.dm.CreateAndAttachWithPolicy(op, volDiskDsURL, "", policy, int64(capacityKB), os.O_RDWR)
 	if err != nil {
 		return nil, err
 	}
@@ -203,8 +214,23 @@ func (v *VolumeStore) VolumeCreate(op trace.Operation, ID string, store *url.URL
 	return vol, nil
 }
 
+// volumeStoragePolicy returns the storage policy for a new volume in store, which is the one given in the
+// volume metadata or else the one configured for the store
+func volumeStoragePolicy(store *url.URL, info map[string][]byte) (string, error) {
+	if policy := info[storage.StoragePolicyKey]; len(policy) > 0 {
+		return string(policy), nil
+	}
+
+	storeName, err := util.VolumeStoreName(store)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.Config.VolumeStoragePolicies[storeName], nil
+}
+
 func (v *VolumeStore) VolumeDestroy(op trace.Operation, vol *storage.Volume) error {
-	if err := volumeInUse(vol.ID); err != nil {
+	if err := storage.VolumeInUse(vol.ID); err != nil {
 		log.Errorf("VolumeStore: delete error: %s", err.Error())
 		return err
 	}
@@ -240,67 +266,46 @@ func (v *VolumeStore) VolumesList(op trace.Operation) ([]*storage.Volume, error)
 
 		store := volStore
 
-		res, err := vols.Ls(op, VolumesDir)
-		if err != nil {
-			return nil, fmt.Errorf("error listing vols: %s", err)
-		}
-
-		for _, f := range res.File {
-			file, ok := f.(*types.FileInfo)
-			if !ok {
-				continue
-			}
-
-			ID := file.Path
-
-			// Get the path to the disk in datastore uri format
-			volDiskDsURL, err := v.volDiskDsURL(&store, ID)
-			if err != nil {
-				return nil, err
+		err := vols.LsEach(op, VolumesDir, 0, func(files []types.BaseFileInfo) error {
+			for _, f := range files {
+				file, ok := f.(*types.FileInfo)
+				if !ok {
+					continue
+				}
+
+				ID := file.Path
+
+				// Get the path to the disk in datastore uri format
+				volDiskDsURL, err := v.volDiskDsURL(&store, ID)
+				if err != nil {
+					return err
+				}
+
+				dev, err := disk.NewVirtualDisk(volDiskDsURL)
+				if err != nil {
+					return err
+				}
+
+				metaDataDir := v.volMetadataDirPath(ID)
+				meta, err := getMetadata(op, vols, metaDataDir)
+				if err != nil {
+					return err
+				}
+
+				vol, err := storage.NewVolume(&store, ID, meta, dev)
+				if err != nil {
+					return err
+				}
+
+				volumes = append(volumes, vol)
 			}
 
-			dev, err := disk.NewVirtualDisk(volDiskDsURL)
-			if err != nil {
-				return nil, err
-			}
-
-			metaDataDir := v.volMetadataDirPath(ID)
-			meta, err := getMetadata(op, vols, metaDataDir)
-			if err != nil {
-				return nil, err
-			}
-
-			vol, err := storage.NewVolume(&store, ID, meta, dev)
-			if err != nil {
-				return nil, err
-			}
-
-			volumes = append(volumes, vol)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing vols: %s", err)
 		}
-
 	}
 
 	return volumes, nil
 }
-
-func volumeInUse(ID string) error {
-	conts := exec.Containers.Containers(nil)
-	if len(conts) == 0 {
-		return nil
-	}
-
-	for _, cont := range conts {
-
-		if cont.ExecConfig.Mounts == nil {
-			continue
-		}
-
-		if _, mounted := cont.ExecConfig.Mounts[ID]; mounted {
-			return &storage.ErrVolumeInUse{
-				Msg: fmt.Sprintf("volume %s in use by %s", ID, cont.ExecConfig.ID),
-			}
-		}
-	}
-
-	return nil
-}
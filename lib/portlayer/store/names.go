@@ -0,0 +1,213 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/pkg/kvstore"
+)
+
+// Kinds of names that can be reserved
+const (
+	ContainerName = "container"
+	NetworkName   = "network"
+	VolumeName    = "volume"
+)
+
+// NamesKV is the store that name reservations are persisted in
+const NamesKV = "names"
+
+// NameReservedError is returned when reserving a name that another owner holds
+type NameReservedError struct {
+	Kind  string
+	Name  string
+	Owner string
+}
+
+func (e NameReservedError) Error() string {
+	return fmt.Sprintf("%s name %q is already in use by %s", e.Kind, e.Name, e.Owner)
+}
+
+// Reservation is the holder of a reserved name
+type Reservation struct {
+	Owner   string    `json:"owner"`
+	Created time.Time `json:"created"`
+}
+
+// Reservations hands out container, network and volume names, so that of concurrent creates with the
+// same name exactly one succeeds. Reservations are persisted in a key/value store so that they survive
+// restarts of the port layer. A nil Reservations reserves nothing.
+type Reservations struct {
+	m sync.Mutex
+
+	kv     kvstore.KeyValueStore
+	byName map[string]Reservation
+}
+
+var names *Reservations
+
+// Names returns the name reservations of the port layer, or nil before Init
+func Names() *Reservations {
+	return names
+}
+
+// NewReservations restores the reservations persisted in kv
+func NewReservations(kv kvstore.KeyValueStore) (*Reservations, error) {
+	r := &Reservations{
+		kv:     kv,
+		byName: make(map[string]Reservation),
+	}
+
+	values, err := kv.List(`^(` + ContainerName + `|` + NetworkName + `|` + VolumeName + `)\.`)
+	if err != nil && err != kvstore.ErrKeyNotFound {
+		return nil, err
+	}
+	for k, v := range values {
+		var res Reservation
+		if err := json.Unmarshal(v, &res); err != nil {
+			log.Warnf("Dropping unreadable name reservation %s: %s", k, err)
+			continue
+		}
+		r.byName[k] = res
+	}
+
+	return r, nil
+}
+
+func reservationKey(kind, name string) string {
+	return kind + "." + name
+}
+
+// Reserve reserves name for owner, failing with NameReservedError if another owner holds it. Reserving
+// a name again for the same owner succeeds.
+func (r *Reservations) Reserve(ctx context.Context, kind, name, owner string) error {
+	if r == nil {
+		return nil
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	key := reservationKey(kind, name)
+	if res, ok := r.byName[key]; ok {
+		if res.Owner == owner {
+			return nil
+		}
+		return NameReservedError{Kind: kind, Name: name, Owner: res.Owner}
+	}
+
+	return r.put(ctx, key, Reservation{Owner: owner, Created: time.Now().UTC()})
+}
+
+// Release releases name if owner holds it
+func (r *Reservations) Release(ctx context.Context, kind, name, owner string) error {
+	if r == nil {
+		return nil
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	key := reservationKey(kind, name)
+	if res, ok := r.byName[key]; !ok || res.Owner != owner {
+		return nil
+	}
+
+	return r.delete(ctx, key)
+}
+
+// Owner returns the holder of name, if it is reserved
+func (r *Reservations) Owner(kind, name string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	res, ok := r.byName[reservationKey(kind, name)]
+	return res.Owner, ok
+}
+
+// Reconcile brings the reservations of kind in line with the names in use, given as owners by name.
+// Names in use are reserved for their owner, and reservations of names not in use that are older than
+// grace are released, as the create that made them did not complete.
+func (r *Reservations) Reconcile(ctx context.Context, kind string, inUse map[string]string, grace time.Duration) error {
+	if r == nil {
+		return nil
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	now := time.Now().UTC()
+	for name, owner := range inUse {
+		key := reservationKey(kind, name)
+		if res, ok := r.byName[key]; ok && res.Owner == owner {
+			continue
+		}
+		if err := r.put(ctx, key, Reservation{Owner: owner, Created: now}); err != nil {
+			return err
+		}
+	}
+
+	prefix := reservationKey(kind, "")
+	for key, res := range r.byName {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if _, ok := inUse[strings.TrimPrefix(key, prefix)]; ok || now.Sub(res.Created) < grace {
+			continue
+		}
+
+		log.Infof("Releasing %s name %q reserved by %s as it is not in use", kind, strings.TrimPrefix(key, prefix), res.Owner)
+		if err := r.delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Reservations) put(ctx context.Context, key string, res Reservation) error {
+	d, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	if err = r.kv.Put(ctx, key, d); err != nil {
+		return fmt.Errorf("unable to persist name reservation %s: %s", key, err)
+	}
+	r.byName[key] = res
+
+	return nil
+}
+
+func (r *Reservations) delete(ctx context.Context, key string) error {
+	if err := r.kv.Delete(ctx, key); err != nil && err != kvstore.ErrKeyNotFound {
+		return fmt.Errorf("unable to remove name reservation %s: %s", key, err)
+	}
+	delete(r.byName, key)
+
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/pkg/kvstore"
+)
+
+func TestReserve(t *testing.T) {
+	ctx := context.TODO()
+	backend := &kvstore.MockBackend{}
+	kv, err := kvstore.NewKeyValueStore(ctx, backend, NamesKV)
+	require.NoError(t, err)
+
+	r, err := NewReservations(kv)
+	require.NoError(t, err)
+
+	require.NoError(t, r.Reserve(ctx, ContainerName, "web", "c1"))
+	assert.NoError(t, r.Reserve(ctx, ContainerName, "web", "c1"), "reserving again for the same owner")
+	assert.NoError(t, r.Reserve(ctx, NetworkName, "web", "n1"), "kinds have their own names")
+
+	err = r.Reserve(ctx, ContainerName, "web", "c2")
+	assert.Equal(t, NameReservedError{Kind: ContainerName, Name: "web", Owner: "c1"}, err)
+
+	// only the owner releases a name
+	require.NoError(t, r.Release(ctx, ContainerName, "web", "c2"))
+	owner, ok := r.Owner(ContainerName, "web")
+	assert.True(t, ok)
+	assert.Equal(t, "c1", owner)
+
+	// reservations survive a restart
+	kv, err = kvstore.NewKeyValueStore(ctx, backend, NamesKV)
+	require.NoError(t, err)
+	restored, err := NewReservations(kv)
+	require.NoError(t, err)
+	owner, _ = restored.Owner(ContainerName, "web")
+	assert.Equal(t, "c1", owner)
+
+	require.NoError(t, restored.Release(ctx, ContainerName, "web", "c1"))
+	assert.NoError(t, restored.Reserve(ctx, ContainerName, "web", "c2"))
+}
+
+func TestConcurrentReserve(t *testing.T) {
+	ctx := context.TODO()
+	kv, err := kvstore.NewKeyValueStore(ctx, &kvstore.MockBackend{}, NamesKV)
+	require.NoError(t, err)
+	r, err := NewReservations(kv)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- r.Reserve(ctx, VolumeName, "data", fmt.Sprintf("owner%d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.IsType(t, NameReservedError{}, err)
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestReconcileReservations(t *testing.T) {
+	ctx := context.TODO()
+	kv, err := kvstore.NewKeyValueStore(ctx, &kvstore.MockBackend{}, NamesKV)
+	require.NoError(t, err)
+	r, err := NewReservations(kv)
+	require.NoError(t, err)
+
+	require.NoError(t, r.Reserve(ctx, ContainerName, "pending", "c1"))
+	require.NoError(t, r.Reserve(ctx, ContainerName, "gone", "c2"))
+	require.NoError(t, r.Reserve(ctx, NetworkName, "net", "n1"))
+	r.byName[reservationKey(ContainerName, "gone")] = Reservation{Owner: "c2", Created: time.Now().Add(-time.Hour)}
+
+	require.NoError(t, r.Reconcile(ctx, ContainerName, map[string]string{"running": "c3"}, time.Minute))
+
+	_, ok := r.Owner(ContainerName, "pending")
+	assert.True(t, ok, "recent reservations are kept for creates in progress")
+	_, ok = r.Owner(ContainerName, "gone")
+	assert.False(t, ok)
+	owner, _ := r.Owner(ContainerName, "running")
+	assert.Equal(t, "c3", owner)
+	_, ok = r.Owner(NetworkName, "net")
+	assert.True(t, ok, "other kinds are left alone")
+
+	// without grace only names in use stay reserved
+	require.NoError(t, r.Reconcile(ctx, ContainerName, map[string]string{"running": "c3"}, 0))
+	_, ok = r.Owner(ContainerName, "pending")
+	assert.False(t, ok)
+}
+
+func TestNilReservations(t *testing.T) {
+	var r *Reservations
+	assert.NoError(t, r.Reserve(context.TODO(), ContainerName, "web", "c1"))
+	assert.NoError(t, r.Reserve(context.TODO(), ContainerName, "web", "c2"))
+	_, ok := r.Owner(ContainerName, "web")
+	assert.False(t, ok)
+}
@@ -77,7 +77,16 @@ func Init(ctx context.Context, session *session.Session, imgStoreURL url.URL) er
 			datastoreURL: imgStoreURL,
 		}
 		//create or restore the api accessible datastore backed k/v store
-		_, err = NewDatastoreKeyValue(ctx, session, APIKV)
+		if _, err = NewDatastoreKeyValue(ctx, session, APIKV); err != nil {
+			return
+		}
+
+		// create or restore the name reservations
+		var kv kvstore.KeyValueStore
+		if kv, err = NewDatastoreKeyValue(ctx, session, NamesKV); err != nil {
+			return
+		}
+		names, err = NewReservations(kv)
 	})
 
 	return initializer.err
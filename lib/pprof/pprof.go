@@ -26,6 +26,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
 )
 
@@ -33,6 +34,9 @@ type PprofPort int
 
 const basePort = 6060
 
+// traceSampleInterval is the proportion of traces whose latency is exported, one in every n
+const traceSampleInterval = 10
+
 const (
 	VCHInitPort PprofPort = iota
 	VicadminPort
@@ -46,6 +50,9 @@ var (
 )
 
 func init() {
+	// export sampled trace latencies alongside expvar and pprof
+	http.Handle("/metrics", trace.Metrics)
+
 	// load the vch config
 	// TODO: Optimize this to just pull the fields we need...
 	src, err := extraconfig.GuestInfoSource()
@@ -74,7 +81,7 @@ func GetPprofEndpoint(component PprofPort) *url.URL {
 		}
 	}
 
-	endpoint, err := url.Parse(fmt.Sprintf("http://%s:%d", ip, port))
+	endpoint, err := url.Parse(fmt.Sprintf("http://%s", net.JoinHostPort(ip, fmt.Sprintf("%d", port))))
 	if err != nil {
 		return nil
 	}
@@ -90,6 +97,8 @@ func StartPprof(name string, component PprofPort) error {
 	}
 	location := url.String()[7:] // Strip off leading "http://"
 
+	trace.EnableSampling(traceSampleInterval)
+
 	log.Info(fmt.Sprintf("Launching %s pprof server on %s", name, location))
 	go func() {
 		log.Info(http.ListenAndServe(location, nil))
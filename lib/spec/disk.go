@@ -81,15 +81,25 @@ func (s *VirtualMachineConfigSpec) AddVirtualDisk(device *types.VirtualDisk) *Vi
 				// XXX This needs to come from a storage helper in the future
 				// and should not be computed here like this.
 
-				FileName: s.Datastore.Path(fmt.Sprintf("%s/VIC/%s/images/%s/%[3]s.vmdk",
+				// The image store may be on a different datastore to the container VM.
+				FileName: fmt.Sprintf("[%s] %s/VIC/%s/images/%s/%[4]s.vmdk",
+					s.ImageStorePath().Host,
 					s.ImageStorePath().Path,
 					s.ImageStoreName(),
-					s.ParentImageID())),
+					s.ParentImageID()),
 			},
 		}
 	}
 
-	return s.AddAndCreateVirtualDevice(device)
+	s.AddAndCreateVirtualDevice(device)
+
+	// the scratch disk follows the storage policy of the VM
+	if s.config.StoragePolicy != "" {
+		change := s.DeviceChange[len(s.DeviceChange)-1].GetVirtualDeviceConfigSpec()
+		change.Profile = storagePolicy(s.config.StoragePolicy)
+	}
+
+	return s
 }
 
 // RemoveVirtualDisk remvoes the virtual disk from a virtual machine.
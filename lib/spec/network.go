@@ -22,6 +22,7 @@ import (
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/pkg/trace"
+	vnetwork "github.com/vmware/vic/pkg/vsphere/network"
 )
 
 // NewVirtualVmxnet3 returns VirtualVmxnet3 spec.
@@ -123,7 +124,7 @@ func (s *VirtualMachineConfigSpec) FindNICs(ctx context.Context, network object.
 		if _, ok := dev.(types.BaseVirtualEthernetCard); ok {
 			var dl object.VirtualDeviceList
 			dl = append(dl, dev)
-			dl = dl.SelectByBackingInfo(backing)
+			dl = vnetwork.SelectByBackingInfo(dl, backing)
 			if len(dl) > 0 {
 				dcs = append(dcs, d)
 			}
@@ -0,0 +1,80 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Container VM profiles select the virtual hardware of container VMs.
+const (
+	// DefaultProfile keeps the devices vSphere adds to every VM.
+	DefaultProfile = "default"
+	// DensityProfile removes the devices a container VM does not use, such as the SVGA device and its
+	// video memory, to lower the overhead of each VM on hosts running hundreds of them. The VM console
+	// in vSphere shows nothing for these VMs.
+	DensityProfile = "density"
+)
+
+// Profiles are the container VM profiles accepted.
+var Profiles = []string{DefaultProfile, DensityProfile}
+
+// densityOptions disable the devices that vSphere adds to every VM and that a container VM, which is
+// reached over its serial ports, does not need.
+var densityOptions = []types.BaseOptionValue{
+	&types.OptionValue{Key: "svga.present", Value: "FALSE"},
+	&types.OptionValue{Key: "mks.enable3d", Value: "FALSE"},
+	&types.OptionValue{Key: "floppy0.present", Value: "FALSE"},
+	&types.OptionValue{Key: "sound.present", Value: "FALSE"},
+	&types.OptionValue{Key: "usb.present", Value: "FALSE"},
+	&types.OptionValue{Key: "ehci.present", Value: "FALSE"},
+}
+
+// ValidateProfile returns an error if profile is not a known container VM profile. An empty profile is
+// the default.
+func ValidateProfile(profile string) error {
+	if profile == "" {
+		return nil
+	}
+	for _, p := range Profiles {
+		if profile == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown container VM profile %q, must be one of %s", profile, strings.Join(Profiles, ", "))
+}
+
+// ApplyProfile adjusts the virtual hardware in the spec for the container VM profile.
+func (s *VirtualMachineConfigSpec) ApplyProfile(profile string) *VirtualMachineConfigSpec {
+	if profile == DensityProfile {
+		s.ExtraConfig = append(s.ExtraConfig, densityOptions...)
+	}
+	return s
+}
+
+// storagePolicy returns the profile spec applying the vSphere storage policy with the given ID, or nil
+// for an empty ID. This cannot use pkg/vsphere/disk, whose tests depend on this package.
+func storagePolicy(id string) []types.BaseVirtualMachineProfileSpec {
+	if id == "" {
+		return nil
+	}
+
+	return []types.BaseVirtualMachineProfileSpec{
+		&types.VirtualMachineDefinedProfileSpec{ProfileId: id},
+	}
+}
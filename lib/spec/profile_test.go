@@ -0,0 +1,51 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestValidateProfile(t *testing.T) {
+	assert.NoError(t, ValidateProfile(""))
+	assert.NoError(t, ValidateProfile(DefaultProfile))
+	assert.NoError(t, ValidateProfile(DensityProfile))
+	assert.Error(t, ValidateProfile("tiny"))
+}
+
+func TestApplyProfile(t *testing.T) {
+	options := func(profile string) map[string]string {
+		s := &VirtualMachineConfigSpec{VirtualMachineConfigSpec: &types.VirtualMachineConfigSpec{}}
+		s.ApplyProfile(profile)
+
+		m := make(map[string]string)
+		for _, o := range s.ExtraConfig {
+			v := o.GetOptionValue()
+			m[v.Key] = v.Value.(string)
+		}
+		return m
+	}
+
+	assert.Empty(t, options(""))
+	assert.Empty(t, options(DefaultProfile))
+
+	density := options(DensityProfile)
+	assert.Equal(t, "FALSE", density["svga.present"])
+	assert.Equal(t, "FALSE", density["floppy0.present"])
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+const (
+	// CrashLogName is the file in the VM folder that the kernel console, and with it the trace of
+	// any kernel panic, is written to.
+	CrashLogName = "kernel.log"
+	// CrashLogUnit places the crash log serial port on ttyS3, which the kernel command line of the
+	// appliance and bootstrap images names as a console.
+	CrashLogUnit = 3
+)
+
+// NewCrashLogSerialPort returns a serial port writing the kernel console to the datastore file.
+func NewCrashLogSerialPort(file string) *types.VirtualSerialPort {
+	defer trace.End(trace.Begin(file))
+
+	unit := int32(CrashLogUnit)
+	return &types.VirtualSerialPort{
+		VirtualDevice: types.VirtualDevice{
+			Backing: &types.VirtualSerialPortFileBackingInfo{
+				VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{
+					FileName: file,
+				},
+			},
+			Connectable: &types.VirtualDeviceConnectInfo{
+				Connected:      true,
+				StartConnected: true,
+			},
+			UnitNumber: &unit,
+		},
+		YieldOnPoll: true,
+	}
+}
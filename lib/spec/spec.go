@@ -73,6 +73,12 @@ type VirtualMachineConfigSpecConfig struct {
 	// url path to image store
 	ImageStorePath *url.URL
 
+	// Profile selects the virtual hardware of the VM, empty for the default
+	Profile string
+
+	// StoragePolicy is the ID of the vSphere storage policy applied to the VM and its disk, empty for none
+	StoragePolicy string
+
 	// Temporary
 	Metadata *executor.ExecutorConfig
 }
@@ -156,6 +162,8 @@ func NewVirtualMachineConfigSpec(ctx context.Context, session *session.Session,
 		VirtualMachineConfigSpec: s,
 		config: config,
 	}
+	vmcs.ApplyProfile(config.Profile)
+	vmcs.VmProfile = storagePolicy(config.StoragePolicy)
 
 	log.Debugf("Virtual machine config spec created: %+v", vmcs)
 	return vmcs, nil
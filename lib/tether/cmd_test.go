@@ -16,11 +16,16 @@ package tether
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path"
 	"testing"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
@@ -220,6 +225,50 @@ func TestHalt(t *testing.T) {
 	}
 }
 
+func TestDeadline(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	cfg := executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   "deadline",
+			Name: "tether_test_executor",
+		},
+
+		Sessions: map[string]*executor.SessionConfig{
+			"deadline": &executor.SessionConfig{
+				Common: executor.Common{
+					ID:   "deadline",
+					Name: "tether_test_session",
+				},
+				Tty: false,
+				Cmd: executor.Cmd{
+					Path: "/bin/sleep",
+					Args: []string{"sleep", "60"},
+					Env:  []string{},
+					Dir:  "/",
+				},
+				Deadline: 1,
+			},
+		},
+	}
+
+	start := time.Now()
+
+	_, src, err := RunTether(t, &cfg, mocker)
+	assert.NoError(t, err, "Didn't expected error from RunTether")
+
+	// block until tether exits
+	<-mocker.Cleaned
+
+	result := ExecutorConfig{}
+	extraconfig.Decode(src, &result)
+
+	assert.Equal(t, "true", result.Sessions["deadline"].Started, "Expected command to have been started successfully")
+	assert.NotEqual(t, 0, result.Sessions["deadline"].ExitStatus, "Expected command to have been stopped")
+	assert.True(t, time.Since(start) < 30*time.Second, "Expected command to have been stopped at its deadline")
+}
+
 func TestAbsPathRepeat(t *testing.T) {
 	log.SetLevel(log.WarnLevel)
 
@@ -327,3 +376,169 @@ func TestMissingRelativeBinary(t *testing.T) {
 
 //
 /////////////////////////////////////////////////////////////////////////////////////
+
+/////////////////////////////////////////////////////////////////////////////////////
+// TestWorkingDir constructs the spec for Sessions with working directories that are
+// missing, not a directory, or missing but to be created
+//
+
+func workingDirConfig(dir string, create bool) *executor.ExecutorConfig {
+	return &executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   "workdir",
+			Name: "tether_test_executor",
+		},
+
+		Sessions: map[string]*executor.SessionConfig{
+			"workdir": &executor.SessionConfig{
+				Common: executor.Common{
+					ID:   "workdir",
+					Name: "tether_test_session",
+				},
+				Tty:       false,
+				CreateDir: create,
+				Cmd: executor.Cmd{
+					Path: "/bin/true",
+					Args: []string{"/bin/true"},
+					Env:  []string{},
+					Dir:  dir,
+				},
+			},
+		},
+	}
+}
+
+func TestMissingWorkingDir(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	cfg := workingDirConfig("/not/there", false)
+
+	_, src, err := RunTether(t, cfg, mocker)
+	assert.Error(t, err, "Expected error from RunTether")
+
+	extraconfig.Decode(src, cfg)
+
+	status := cfg.Sessions["workdir"].Started
+	assert.Equal(t, "chdir /not/there: no such file or directory", status, "Expected status to have a missing directory error message")
+}
+
+func TestWorkingDirNotDir(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	f, err := ioutil.TempFile("", "workdir")
+	if !assert.NoError(t, err) {
+		return
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	cfg := workingDirConfig(f.Name(), true)
+
+	_, src, err := RunTether(t, cfg, mocker)
+	assert.Error(t, err, "Expected error from RunTether")
+
+	extraconfig.Decode(src, cfg)
+
+	status := cfg.Sessions["workdir"].Started
+	assert.Equal(t, fmt.Sprintf("chdir %s: not a directory", f.Name()), status, "Expected status to have a not a directory error message")
+}
+
+func TestCreateWorkingDir(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	tmp, err := ioutil.TempDir("", "workdir")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	dir := path.Join(tmp, "created", "here")
+	cfg := workingDirConfig(dir, true)
+
+	_, src, err := RunTether(t, cfg, mocker)
+	assert.NoError(t, err, "Didn't expected error from RunTether")
+
+	result := ExecutorConfig{}
+	extraconfig.Decode(src, &result)
+
+	assert.Equal(t, "true", result.Sessions["workdir"].Started, "Expected command to have been started successfully")
+	assert.Equal(t, 0, result.Sessions["workdir"].ExitStatus, "Expected command to have exited cleanly")
+
+	fi, err := os.Stat(dir)
+	if assert.NoError(t, err) {
+		assert.True(t, fi.IsDir(), "Expected working directory to have been created")
+	}
+}
+
+//
+/////////////////////////////////////////////////////////////////////////////////////
+
+func TestExec(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	cfg := executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   "exec",
+			Name: "tether_test_executor",
+		},
+
+		Sessions: map[string]*executor.SessionConfig{
+			"exec": &executor.SessionConfig{
+				Common: executor.Common{
+					ID:   "exec",
+					Name: "tether_test_session",
+				},
+				Tty: false,
+				Cmd: executor.Cmd{
+					Path: "/bin/sleep",
+					Args: []string{"sleep", "60"},
+					Env:  []string{},
+					Dir:  "/",
+				},
+			},
+		},
+	}
+
+	tthr, src := StartTether(t, &cfg, mocker)
+	defer tthr.Stop()
+
+	// wait for the tether to finish its startup
+	<-mocker.Started
+
+	session := &SessionConfig{
+		Common: executor.Common{
+			ID: "task",
+		},
+		Cmd: *exec.Command("/bin/sh", "-c", "exit 3"),
+	}
+	session.Cmd.Dir = "/"
+
+	require.NoError(t, tthr.Exec(session), "Expected exec session to launch")
+	assert.Error(t, tthr.Exec(&SessionConfig{Common: executor.Common{ID: "task"}}), "Expected duplicate exec ID to be rejected")
+	assert.Error(t, tthr.Exec(&SessionConfig{Common: executor.Common{ID: "exec"}}), "Expected configured session ID to be rejected")
+
+	exited := func() bool {
+		session.Lock()
+		defer session.Unlock()
+		return session.StopTime != 0
+	}
+	for i := 0; i < 100 && !exited(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	require.True(t, exited(), "Expected exec session to have exited")
+	assert.Equal(t, "true", session.Started, "Expected exec session to have been started successfully")
+	assert.Equal(t, 3, session.ExitStatus, "Expected exit status of exec session")
+
+	// the exec session is not part of the configuration and does not stop the executor
+	result := ExecutorConfig{}
+	extraconfig.Decode(src, &result)
+
+	assert.NotContains(t, result.Sessions, "task", "Expected exec session not to be recorded")
+	assert.Equal(t, "true", result.Sessions["exec"].Started, "Expected primary session to be running")
+	assert.Equal(t, int64(0), result.Sessions["exec"].StopTime, "Expected primary session to be running")
+}
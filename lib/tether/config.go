@@ -19,6 +19,7 @@ import (
 	"os"
 	"os/exec"
 	"sync"
+	"time"
 
 	"github.com/vmware/vic/lib/config/executor"
 	"github.com/vmware/vic/pkg/dio"
@@ -45,6 +46,13 @@ type ExecutorConfig struct {
 	// These are keyed by session ID
 	Sessions map[string]*SessionConfig `vic:"0.1" scope:"read-only" key:"sessions"`
 
+	// Exclusive access to Execs
+	execMutex sync.Mutex `vic:"0.1" scope:"read-only" recurse:"depth=0"`
+
+	// Execs is the set of sessions launched while the executor is running, e.g. by docker exec.
+	// They are not part of the configuration so do not survive a restart of the executor.
+	Execs map[string]*SessionConfig `vic:"0.1" scope:"read-only" recurse:"depth=0"`
+
 	// Maps the mount name to the detail mount specification
 	Mounts map[string]executor.MountSpec `vic:"0.1" scope:"read-only" key:"mounts"`
 
@@ -77,6 +85,9 @@ type SessionConfig struct {
 	// The exit status of the process, if any
 	ExitStatus int `vic:"0.1" scope:"read-write" key:"status"`
 
+	// Whether the process was killed by the kernel for running out of memory
+	OOMKilled bool `vic:"0.1" scope:"read-write" key:"oomkilled"`
+
 	Started string `vic:"0.1" scope:"read-write" key:"started"`
 
 	// Allow attach
@@ -85,6 +96,9 @@ type SessionConfig struct {
 	// Delay launching the Cmd until an attach request comes
 	RunBlock bool `vic:"0.1" scope:"read-only" key:"runblock"`
 
+	// Create the working directory of the Cmd if it does not exist
+	CreateDir bool `vic:"0.1" scope:"read-only" key:"createdir"`
+
 	// Allocate a tty or not
 	Tty bool `vic:"0.1" scope:"read-only" key:"tty"`
 
@@ -94,6 +108,9 @@ type SessionConfig struct {
 	// StopSignal is the signal name or number used to stop a container
 	StopSignal string `vic:"0.1" scope:"read-only" key:"stopSignal"`
 
+	// Deadline is the number of seconds the process may run for each time it is launched, zero for no limit
+	Deadline int64 `vic:"0.1" scope:"read-only" key:"deadline"`
+
 	// User and group for setuid programs
 	User  string `vic:"0.1" scope:"read-only" key:"user"`
 	Group string `vic:"0.1" scope:"read-only" key:"group"`
@@ -106,11 +123,30 @@ type SessionConfig struct {
 
 	wait *sync.WaitGroup `vic:"0.1" scope:"read-only" recurse:"depth=0"`
 
+	// enforces the deadline of the running process, if it has one
+	deadline *time.Timer `vic:"0.1" scope:"read-only" recurse:"depth=0"`
+
+	// set for sessions launched by Exec, whose state is not written back to the configuration
+	exec bool `vic:"0.1" scope:"read-only" recurse:"depth=0"`
+
 	// Blocks launching the process.
 	// The channel contains no value; we’re only interested in its closed property.
 	ClearToLaunch chan struct{} `vic:"0.1" scope:"read-only" recurse:"depth=0"`
 }
 
+// Session returns the configured or exec session with the given ID
+func (c *ExecutorConfig) Session(id string) (*SessionConfig, bool) {
+	if session, ok := c.Sessions[id]; ok {
+		return session, true
+	}
+
+	c.execMutex.Lock()
+	defer c.execMutex.Unlock()
+
+	session, ok := c.Execs[id]
+	return session, ok
+}
+
 type NetworkEndpoint struct {
 	// Common.Name - the nic alias requested (only one name and one alias possible in linux)
 	// Common.ID - pci slot of the vnic allowing for interface identifcation in-guest
@@ -129,6 +165,12 @@ type NetworkEndpoint struct {
 	// as a pointer so that we can ensure the data is consistent
 	Network executor.ContainerNetwork `vic:"0.1" scope:"read-only" key:"network"`
 
+	// The published ports of the container, allowed through the firewall of published networks
+	Ports []string `vic:"0.1" scope:"read-only" key:"ports"`
+
+	// DHCP client identifier to send, derived from the interface if empty
+	DHCPClientID string `vic:"0.1" scope:"read-only" key:"dhcp_client_id"`
+
 	// DHCP runtime info
 	DHCP *DHCPInfo `vic:"0.1" scope:"read-only" recurse:"depth=0"`
 
@@ -145,3 +187,56 @@ type DHCPInfo struct {
 	Nameservers []net.IP
 	Gateway     net.IPNet
 }
+
+// spec returns the configuration in the form used by the portlayer and installer, so that
+// it can be checked with the same validation
+func (c *ExecutorConfig) spec() *executor.ExecutorConfig {
+	spec := &executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   c.ID,
+			Name: c.Name,
+		},
+		Sessions: make(map[string]*executor.SessionConfig, len(c.Sessions)),
+		Mounts:   c.Mounts,
+		Networks: make(map[string]*executor.NetworkEndpoint, len(c.Networks)),
+	}
+
+	for id, session := range c.Sessions {
+		spec.Sessions[id] = session.spec()
+	}
+
+	for name, endpoint := range c.Networks {
+		spec.Networks[name] = &executor.NetworkEndpoint{
+			Common:  endpoint.Common,
+			Static:  endpoint.Static,
+			IP:      endpoint.IP,
+			Network: endpoint.Network,
+			Ports:   endpoint.Ports,
+
+			DHCPClientID: endpoint.DHCPClientID,
+		}
+	}
+
+	return spec
+}
+
+func (s *SessionConfig) spec() *executor.SessionConfig {
+	return &executor.SessionConfig{
+		Common: s.Common,
+		Cmd: executor.Cmd{
+			Path: s.Cmd.Path,
+			Args: s.Cmd.Args,
+			Env:  s.Cmd.Env,
+			Dir:  s.Cmd.Dir,
+		},
+		Attach:     s.Attach,
+		RunBlock:   s.RunBlock,
+		CreateDir:  s.CreateDir,
+		Tty:        s.Tty,
+		Restart:    s.Restart,
+		StopSignal: s.StopSignal,
+		Deadline:   s.Deadline,
+		User:       s.User,
+		Group:      s.Group,
+	}
+}
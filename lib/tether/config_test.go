@@ -62,10 +62,15 @@ func TestToExtraConfig(t *testing.T) {
 					Common: executor.Common{
 						Name: "notsure",
 					},
-					Gateway:     net.IPNet{IP: gateway, Mask: gmask.Mask},
-					Nameservers: []net.IP{},
-					Pools:       []ip.Range{},
-					Aliases:     []string{},
+					Gateway:       net.IPNet{IP: gateway, Mask: gmask.Mask},
+					Nameservers:   []net.IP{},
+					SearchDomains: []string{},
+					Ingress:       []executor.FirewallRule{},
+					Egress:        []executor.FirewallRule{},
+					Pools:         []ip.Range{},
+					DHCPServers:   []net.IP{},
+					Reserved:      []net.IP{},
+					Aliases:       []string{},
 				},
 			},
 		},
@@ -0,0 +1,215 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tether
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+// iptablesDir holds the iptables of the bootstrap image, with its libraries and loader, copied there
+// before the switch to the container filesystem as the container image may not have one
+var iptablesDir = "/.tether/iptables"
+
+// iptables runs iptables with the given arguments, replaced in tests
+var iptables = func(args ...string) error {
+	lib := path.Join(iptablesDir, "lib")
+
+	cmdArgs := append([]string{"--library-path", lib, path.Join(iptablesDir, "xtables-multi"), "iptables"}, args...)
+	cmd := exec.Command(path.Join(lib, "ld-linux-x86-64.so.2"), cmdArgs...)
+	cmd.Env = []string{"XTABLES_LIBDIR=" + path.Join(iptablesDir, "xtables")}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("iptables %s: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// firewallChains returns the chains holding the inbound and outbound rules of link
+func firewallChains(link string) (in, out string) {
+	return "VIC-IN-" + link, "VIC-OUT-" + link
+}
+
+// ruleArgs returns the iptables arguments, without the chain, matching rule. The remote end is the
+// source of inbound traffic and the destination of outbound traffic, while the ports are always
+// destination ports.
+func ruleArgs(rule executor.FirewallRule, inbound bool) ([]string, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	var args []string
+	if rule.Protocol != "" {
+		args = append(args, "-p", rule.Protocol)
+	}
+
+	if rule.Remote != "" {
+		dir := "-d"
+		if inbound {
+			dir = "-s"
+		}
+		args = append(args, dir, rule.Remote)
+	}
+
+	if rule.Ports != "" {
+		low, high, err := rule.PortRange()
+		if err != nil {
+			return nil, err
+		}
+
+		port := strconv.Itoa(low)
+		if high != low {
+			port = fmt.Sprintf("%d:%d", low, high)
+		}
+		args = append(args, "--dport", port)
+	}
+
+	target := "DROP"
+	if rule.Action == executor.FirewallAllow {
+		target = "ACCEPT"
+	}
+	return append(args, "-j", target), nil
+}
+
+// firewallRules returns the inbound and outbound rules, without the chain, enforcing level and the ingress
+// and egress rules of a network on an interface with the given published ports. The rules of the network
+// are matched first. DHCP is always allowed so that leases can be renewed, and replies are allowed for the
+// connections the rules let through.
+func firewallRules(level string, ports []string, ingress, egress []executor.FirewallRule) (in, out [][]string, err error) {
+	switch level {
+	case "", executor.TrustOpen:
+		if len(ingress) == 0 && len(egress) == 0 {
+			return nil, nil, nil
+		}
+		level = executor.TrustOpen
+	case executor.TrustOutbound, executor.TrustPublished, executor.TrustClosed:
+	default:
+		return nil, nil, fmt.Errorf("unknown firewall policy %q", level)
+	}
+
+	established := []string{"-m", "state", "--state", "ESTABLISHED,RELATED", "-j", "ACCEPT"}
+	custom := len(ingress) > 0 || len(egress) > 0
+
+	in = append(in, []string{"-p", "udp", "--sport", "67", "--dport", "68", "-j", "ACCEPT"})
+	if level != executor.TrustClosed || custom {
+		in = append(in, established)
+	}
+
+	for _, rule := range ingress {
+		args, err := ruleArgs(rule, true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid ingress rule %q: %s", rule, err)
+		}
+		in = append(in, args)
+	}
+
+	if level == executor.TrustClosed || len(egress) > 0 {
+		out = append(out, []string{"-p", "udp", "--sport", "68", "--dport", "67", "-j", "ACCEPT"})
+		if custom {
+			out = append(out, established)
+		}
+
+		for _, rule := range egress {
+			args, err := ruleArgs(rule, false)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid egress rule %q: %s", rule, err)
+			}
+			out = append(out, args)
+		}
+
+		if level == executor.TrustClosed {
+			out = append(out, []string{"-j", "DROP"})
+		}
+	}
+
+	if level == executor.TrustPublished {
+		exposed, _, err := nat.ParsePortSpecs(ports)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to parse published ports: %s", err)
+		}
+
+		var published []string
+		for p := range exposed {
+			published = append(published, string(p))
+		}
+		sort.Strings(published)
+
+		for _, p := range published {
+			port := nat.Port(p)
+			in = append(in, []string{"-p", port.Proto(), "--dport", port.Port(), "-j", "ACCEPT"})
+		}
+	}
+
+	if level != executor.TrustOpen {
+		in = append(in, []string{"-j", "DROP"})
+	}
+	return in, out, nil
+}
+
+// applyFirewall enforces the firewall policy and rules of the network of endpoint on link, replacing any
+// rules applied to it before. Nothing is done for open networks without rules.
+func applyFirewall(link string, endpoint *NetworkEndpoint) error {
+	level := endpoint.Network.TrustLevel
+
+	in, out, err := firewallRules(level, endpoint.Ports, endpoint.Network.Ingress, endpoint.Network.Egress)
+	if err != nil || (in == nil && out == nil) {
+		return err
+	}
+
+	log.Infof("Applying %s firewall policy with %d ingress and %d egress rules to link %s", level, len(endpoint.Network.Ingress), len(endpoint.Network.Egress), link)
+
+	inChain, outChain := firewallChains(link)
+	if err = firewallChain(inChain, "INPUT", "-i", link, in); err != nil {
+		return err
+	}
+	return firewallChain(outChain, "OUTPUT", "-o", link, out)
+}
+
+// firewallChain fills chain with rules, jumping to it from parent for traffic through link, or removes
+// the jump if there are no rules
+func firewallChain(chain, parent, dir, link string, rules [][]string) error {
+	jump := []string{parent, dir, link, "-j", chain}
+
+	// the chain may already exist from an earlier pass, so the error is ignored and it is flushed instead
+	_ = iptables("-N", chain)
+	if err := iptables("-F", chain); err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		_ = iptables(append([]string{"-D"}, jump...)...)
+		return nil
+	}
+
+	for _, rule := range rules {
+		if err := iptables(append([]string{"-A", chain}, rule...)...); err != nil {
+			return err
+		}
+	}
+
+	if iptables(append([]string{"-C"}, jump...)...) == nil {
+		return nil
+	}
+	return iptables(append([]string{"-I"}, jump...)...)
+}
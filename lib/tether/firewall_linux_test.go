@@ -0,0 +1,151 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tether
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+func TestFirewallRules(t *testing.T) {
+	for _, level := range []string{"", executor.TrustOpen} {
+		in, out, err := firewallRules(level, []string{"80/tcp"}, nil, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, in, level)
+		assert.Nil(t, out, level)
+	}
+
+	_, _, err := firewallRules("trusted", nil, nil, nil)
+	assert.Error(t, err)
+
+	dhcp := []string{"-p", "udp", "--sport", "67", "--dport", "68", "-j", "ACCEPT"}
+	established := []string{"-m", "state", "--state", "ESTABLISHED,RELATED", "-j", "ACCEPT"}
+	drop := []string{"-j", "DROP"}
+
+	in, out, err := firewallRules(executor.TrustOutbound, []string{"80/tcp"}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{dhcp, established, drop}, in)
+	assert.Nil(t, out)
+
+	in, out, err = firewallRules(executor.TrustPublished, []string{"8080:80/tcp", "53/udp"}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		dhcp,
+		established,
+		{"-p", "udp", "--dport", "53", "-j", "ACCEPT"},
+		{"-p", "tcp", "--dport", "80", "-j", "ACCEPT"},
+		drop,
+	}, in)
+	assert.Nil(t, out)
+
+	in, out, err = firewallRules(executor.TrustClosed, []string{"80/tcp"}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{dhcp, drop}, in)
+	assert.Equal(t, [][]string{{"-p", "udp", "--sport", "68", "--dport", "67", "-j", "ACCEPT"}, drop}, out)
+}
+
+func TestFirewallCustomRules(t *testing.T) {
+	dhcpIn := []string{"-p", "udp", "--sport", "67", "--dport", "68", "-j", "ACCEPT"}
+	dhcpOut := []string{"-p", "udp", "--sport", "68", "--dport", "67", "-j", "ACCEPT"}
+	established := []string{"-m", "state", "--state", "ESTABLISHED,RELATED", "-j", "ACCEPT"}
+	drop := []string{"-j", "DROP"}
+
+	ingress := []executor.FirewallRule{
+		{Action: executor.FirewallAllow, Protocol: "tcp", Remote: "10.0.0.0/8", Ports: "22"},
+		{Action: executor.FirewallDeny, Protocol: "tcp", Ports: "8000-8080"},
+	}
+	egress := []executor.FirewallRule{
+		{Action: executor.FirewallDeny, Remote: "192.168.0.0/16"},
+	}
+
+	// rules on an open network are matched with nothing behind them
+	in, out, err := firewallRules("", nil, ingress, nil)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		dhcpIn,
+		established,
+		{"-p", "tcp", "-s", "10.0.0.0/8", "--dport", "22", "-j", "ACCEPT"},
+		{"-p", "tcp", "--dport", "8000:8080", "-j", "DROP"},
+	}, in)
+	assert.Nil(t, out)
+
+	// and ahead of the policy of the network otherwise
+	in, out, err = firewallRules(executor.TrustClosed, nil, ingress, egress)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		dhcpIn,
+		established,
+		{"-p", "tcp", "-s", "10.0.0.0/8", "--dport", "22", "-j", "ACCEPT"},
+		{"-p", "tcp", "--dport", "8000:8080", "-j", "DROP"},
+		drop,
+	}, in)
+	assert.Equal(t, [][]string{
+		dhcpOut,
+		established,
+		{"-d", "192.168.0.0/16", "-j", "DROP"},
+		drop,
+	}, out)
+
+	_, _, err = firewallRules("", nil, []executor.FirewallRule{{Action: "reject"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestApplyFirewall(t *testing.T) {
+	defer func(f func(args ...string) error) { iptables = f }(iptables)
+
+	var calls []string
+	iptables = func(args ...string) error {
+		cmd := strings.Join(args, " ")
+		calls = append(calls, cmd)
+
+		// neither jump exists yet
+		if args[0] == "-C" || args[0] == "-D" {
+			return errors.New("no such rule")
+		}
+		return nil
+	}
+
+	endpoint := &NetworkEndpoint{
+		Network: executor.ContainerNetwork{TrustLevel: executor.TrustPublished},
+		Ports:   []string{"80/tcp"},
+	}
+	require.NoError(t, applyFirewall("public", endpoint))
+
+	assert.Equal(t, []string{
+		"-N VIC-IN-public",
+		"-F VIC-IN-public",
+		"-A VIC-IN-public -p udp --sport 67 --dport 68 -j ACCEPT",
+		"-A VIC-IN-public -m state --state ESTABLISHED,RELATED -j ACCEPT",
+		"-A VIC-IN-public -p tcp --dport 80 -j ACCEPT",
+		"-A VIC-IN-public -j DROP",
+		"-C INPUT -i public -j VIC-IN-public",
+		"-I INPUT -i public -j VIC-IN-public",
+		"-N VIC-OUT-public",
+		"-F VIC-OUT-public",
+		"-D OUTPUT -o public -j VIC-OUT-public",
+	}, calls)
+
+	// open networks are left alone
+	calls = nil
+	endpoint.Network.TrustLevel = ""
+	require.NoError(t, applyFirewall("public", endpoint))
+	assert.Empty(t, calls)
+}
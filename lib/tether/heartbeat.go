@@ -0,0 +1,128 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tether
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+)
+
+// Heartbeat is a tether extension that periodically records the current time in the
+// diagnostics of every running session. This lets a client tell a component that is still
+// alive apart from one that has wedged or died since it reported itself started.
+type Heartbeat struct {
+	sink     extraconfig.DataSink
+	interval time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*SessionConfig
+	stop     chan struct{}
+}
+
+// NewHeartbeat returns a heartbeat extension publishing to sink every interval
+func NewHeartbeat(sink extraconfig.DataSink, interval time.Duration) *Heartbeat {
+	return &Heartbeat{
+		sink:     sink,
+		interval: interval,
+	}
+}
+
+// Start implementation of the tether.Extension interface
+func (h *Heartbeat) Start() error {
+	defer trace.End(trace.Begin(""))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stop != nil {
+		return nil
+	}
+	h.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				h.beat(time.Now())
+			case <-stop:
+				return
+			}
+		}
+	}(h.stop)
+
+	return nil
+}
+
+// Reload implementation of the tether.Extension interface
+func (h *Heartbeat) Reload(config *ExecutorConfig) error {
+	defer trace.End(trace.Begin(""))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sessions = config.Sessions
+	return nil
+}
+
+// Stop implementation of the tether.Extension interface
+func (h *Heartbeat) Stop() error {
+	defer trace.End(trace.Begin(""))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+	}
+	return nil
+}
+
+// beat publishes now as the heartbeat of every session that has started and not yet exited
+func (h *Heartbeat) beat(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the monotonic clock reading is not part of the encoded form and must be dropped
+	now = now.UTC().Round(0)
+
+	for id, session := range h.sessions {
+		session.Lock()
+		if session.Started == "true" && alive(session) {
+			session.Diagnostics.Heartbeat = now
+
+			// FIXME: shares the embedded knowledge of the extraconfig encoding pattern with handleSessionExit
+			extraconfig.EncodeWithPrefix(h.sink, now, fmt.Sprintf("guestinfo.vice..sessions|%s.diagnostics.heartbeat", id))
+			log.Debugf("Published heartbeat for session %s", id)
+		}
+		session.Unlock()
+	}
+}
+
+// alive reports whether the current process of the session is still running. A relaunch
+// replaces the command, so this only ever considers the most recent process.
+func alive(session *SessionConfig) bool {
+	return session.Cmd.Process != nil && session.Cmd.Process.Signal(syscall.Signal(0)) == nil
+}
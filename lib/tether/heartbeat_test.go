@@ -0,0 +1,64 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tether
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/pkg/vsphere/extraconfig"
+)
+
+func TestHeartbeat(t *testing.T) {
+	running := exec.Command("/bin/sleep", "60")
+	require.NoError(t, running.Start())
+	defer running.Process.Kill()
+
+	exited := exec.Command("/bin/true")
+	require.NoError(t, exited.Run())
+
+	sessions := map[string]*SessionConfig{
+		"running": {Cmd: *running, Started: "true"},
+		"exited":  {Cmd: *exited, Started: "true"},
+		"failed":  {Started: "exec failed"},
+	}
+	sessions["running"].ID = "running"
+	sessions["exited"].ID = "exited"
+	sessions["failed"].ID = "failed"
+
+	store := map[string]string{}
+	hb := NewHeartbeat(extraconfig.MapSink(store), time.Hour)
+	require.NoError(t, hb.Reload(&ExecutorConfig{Sessions: sessions}))
+
+	now := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	hb.beat(now)
+
+	assert.Equal(t, now, sessions["running"].Diagnostics.Heartbeat)
+	assert.True(t, sessions["exited"].Diagnostics.Heartbeat.IsZero())
+	assert.True(t, sessions["failed"].Diagnostics.Heartbeat.IsZero())
+	assert.Len(t, store, 1, "only the running session should publish a heartbeat")
+
+	// the published value must be readable as part of the session config
+	var cfg executor.ExecutorConfig
+	store["guestinfo.vice./sessions"] = "running"
+	extraconfig.Decode(extraconfig.MapSource(store), &cfg)
+	require.Contains(t, cfg.Sessions, "running")
+	assert.Equal(t, now, cfg.Sessions["running"].Diagnostics.Heartbeat.UTC())
+}
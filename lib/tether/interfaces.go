@@ -17,6 +17,7 @@ package tether
 import (
 	"context"
 	"io"
+	"net/url"
 
 	"github.com/vmware/vic/pkg/dio"
 )
@@ -34,12 +35,16 @@ type Operations interface {
 	SetHostname(hostname string, aliases ...string) error
 	Apply(endpoint *NetworkEndpoint) error
 	MountLabel(ctx context.Context, label, target string) error
+	// MountTarget mounts the network share at source, e.g. an NFS export, on target with the freeform mount mode
+	MountTarget(ctx context.Context, source url.URL, target string, mode string) error
 	Fork() error
 	// Returns two DynamicMultiWriters for stdout and stderr
 	SessionLog(session *SessionConfig) (dio.DynamicMultiWriter, dio.DynamicMultiWriter, error)
 	// Returns a function to invoke after the session state has been persisted
 	HandleSessionExit(config *ExecutorConfig, session *SessionConfig) func()
 	ProcessEnv(env []string) []string
+	// Properties returns the vApp properties from the OVF environment, if any
+	Properties() (map[string]string, error)
 }
 
 // Tether presents the consumption interface for code needing to run a tether
@@ -48,6 +53,8 @@ type Tether interface {
 	Stop() error
 	Reload()
 	Register(name string, ext Extension)
+	// Exec launches a session in the running executor
+	Exec(session *SessionConfig) error
 }
 
 // Extension is a very simple extension interface for supporting code that need to be
@@ -82,6 +82,16 @@ func (h MockResolvConf) Nameservers() []net.IP {
 	return nil
 }
 
+func (h MockResolvConf) AddSearch(...string) {
+}
+
+func (h MockResolvConf) RemoveSearch(...string) {
+}
+
+func (h MockResolvConf) Search() []string {
+	return nil
+}
+
 func (h MockResolvConf) Attempts() uint {
 	return etcconf.DefaultAttempts
 }
@@ -121,6 +121,7 @@ func TestSetIpAddress(t *testing.T) {
 					Common: executor.Common{
 						Name: "external",
 					},
+					MTU: 9000,
 				},
 				Static: true,
 				IP: &net.IPNet{
@@ -154,4 +155,81 @@ func TestSetIpAddress(t *testing.T) {
 	assert.NotNil(t, eIface)
 
 	assert.Equal(t, 1, len(eIface.Addrs), "Expected one address on external interface")
+	assert.Equal(t, 9000, eIface.MTU, "Expected MTU of external network on external interface")
+	assert.Equal(t, 0, bIface.MTU, "Expected default MTU on bridge interface")
+}
+
+func TestSetIpAddressVLAN(t *testing.T) {
+	_, mocker := testSetup(t)
+	defer testTeardown(t, mocker)
+
+	hFile, err := ioutil.TempFile("", "vic_set_ip_test_hosts")
+	if err != nil {
+		t.Errorf("Failed to create tmp hosts file: %s", err)
+	}
+	rFile, err := ioutil.TempFile("", "vic_set_ip_test_resolv")
+	if err != nil {
+		t.Errorf("Failed to create tmp resolv file: %s", err)
+	}
+
+	// give us a hosts file we can modify
+	defer func(hosts etcconf.Hosts, resolv etcconf.ResolvConf) {
+		Sys.Hosts = hosts
+		Sys.ResolvConf = resolv
+	}(Sys.Hosts, Sys.ResolvConf)
+
+	Sys.Hosts = etcconf.NewHosts(hFile.Name())
+	Sys.ResolvConf = etcconf.NewResolvConf(rFile.Name())
+
+	backend := AddInterface("eth1", mocker)
+
+	ip, _ := netlink.ParseIPNet("172.16.0.10/24")
+	cfg := executor.ExecutorConfig{
+		Common: executor.Common{
+			ID:   "ipconfig",
+			Name: "tether_test_executor",
+		},
+		Networks: map[string]*executor.NetworkEndpoint{
+			"backend": {
+				Common: executor.Common{
+					ID: backend,
+					// interface rename
+					Name: "backend",
+				},
+				Network: executor.ContainerNetwork{
+					Common: executor.Common{
+						Name: "backend",
+					},
+					MTU:  9000,
+					VLAN: 42,
+				},
+				Static: true,
+				IP:     ip,
+			},
+		},
+	}
+
+	tthr, _ := StartTether(t, &cfg, mocker)
+
+	defer func() {
+		// prevent indefinite wait in tether - normally session exit would trigger this
+		tthr.Stop()
+
+		// wait for tether to exit
+		<-mocker.Cleaned
+	}()
+
+	<-mocker.Started
+
+	nIface, _ := mocker.Interfaces["backend"].(*Interface)
+	assert.NotNil(t, nIface)
+	assert.Equal(t, 9000, nIface.MTU, "Expected MTU of backend network on the NIC")
+	assert.Empty(t, nIface.Addrs, "Expected no address on the NIC of a tagged network")
+
+	vIface, _ := mocker.Interfaces["backend.42"].(*Interface)
+	if assert.NotNil(t, vIface, "Expected VLAN link for tagged network") {
+		assert.Equal(t, nIface.Index, vIface.ParentIndex, "Expected VLAN link on the backend NIC")
+		assert.True(t, vIface.Up)
+		assert.Equal(t, 1, len(vIface.Addrs), "Expected one address on the VLAN link")
+	}
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/user"
 	"strconv"
@@ -53,6 +54,13 @@ func (t *BaseOperations) MountLabel(ctx context.Context, label, target string) e
 	return errors.New("not implemented on OSX")
 }
 
+// MountTarget mounts the network share at source on target
+func (t *BaseOperations) MountTarget(ctx context.Context, source url.URL, target string, mode string) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("Mounting %s on %s", source.String(), target)))
+
+	return errors.New("not implemented on OSX")
+}
+
 // ProcessEnv does OS specific checking and munging on the process environment prior to launch
 func (t *BaseOperations) ProcessEnv(env []string) []string {
 	// TODO: figure out how we're going to specify user and pass all the settings along
@@ -108,3 +116,8 @@ func getUserSysProcAttr(uname string) *syscall.SysProcAttr {
 		}
 	}
 }
+
+// Properties returns the vApp properties from the OVF environment
+func (t *BaseOperations) Properties() (map[string]string, error) {
+	return nil, nil
+}
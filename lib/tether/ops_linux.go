@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/url"
 	"os"
 	"os/user"
 	"path"
@@ -36,8 +37,10 @@ import (
 	"github.com/vmware/vic/lib/dhcp"
 	"github.com/vmware/vic/lib/dhcp/client"
 	"github.com/vmware/vic/pkg/ip"
+	"github.com/vmware/vic/pkg/nfs"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vmw-guestinfo/rpcout"
+	"github.com/vmware/vmw-guestinfo/rpcvmx"
 )
 
 var (
@@ -47,6 +50,9 @@ var (
 
 const (
 	pciDevPath = "/sys/bus/pci/devices"
+
+	// maxLinkNameLen is the longest interface name the kernel accepts
+	maxLinkNameLen = 15
 )
 
 type BaseOperations struct {
@@ -63,6 +69,8 @@ type Netlink interface {
 	LinkSetDown(netlink.Link) error
 	LinkSetUp(netlink.Link) error
 	LinkSetAlias(netlink.Link, string) error
+	LinkSetMTU(netlink.Link, int) error
+	LinkAdd(netlink.Link) error
 	AddrList(netlink.Link, int) ([]netlink.Addr, error)
 	AddrAdd(netlink.Link, *netlink.Addr) error
 	AddrDel(netlink.Link, *netlink.Addr) error
@@ -93,6 +101,14 @@ func (t *BaseOperations) LinkSetAlias(link netlink.Link, alias string) error {
 	return netlink.LinkSetAlias(link, alias)
 }
 
+func (t *BaseOperations) LinkSetMTU(link netlink.Link, mtu int) error {
+	return netlink.LinkSetMTU(link, mtu)
+}
+
+func (t *BaseOperations) LinkAdd(link netlink.Link) error {
+	return netlink.LinkAdd(link)
+}
+
 func (t *BaseOperations) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
 	return netlink.AddrList(link, family)
 }
@@ -267,6 +283,48 @@ func renameLink(t Netlink, link netlink.Link, slot int32, endpoint *NetworkEndpo
 	return link, nil
 }
 
+// vlanLinkName returns the name of the VLAN link for parent, truncating the parent name so it fits
+// within the kernel limit on interface names
+func vlanLinkName(parent string, vlan int) string {
+	suffix := fmt.Sprintf(".%d", vlan)
+	if max := maxLinkNameLen - len(suffix); len(parent) > max {
+		parent = parent[:max]
+	}
+	return parent + suffix
+}
+
+// vlanLink returns the link tagging traffic on parent with vlan, creating it and bringing it up if needed.
+// The link inherits the MTU of parent when created.
+func vlanLink(t Netlink, parent netlink.Link, vlan int) (netlink.Link, error) {
+	name := vlanLinkName(parent.Attrs().Name, vlan)
+
+	link, err := t.LinkByName(name)
+	if err != nil || link == nil {
+		log.Infof("Adding VLAN %d link %s on %s", vlan, name, parent.Attrs().Name)
+
+		err = t.LinkAdd(&netlink.Vlan{
+			LinkAttrs: netlink.LinkAttrs{
+				Name:        name,
+				ParentIndex: parent.Attrs().Index,
+			},
+			VlanId: vlan,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if link, err = t.LinkByName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = t.LinkSetUp(link); err != nil {
+		return nil, fmt.Errorf("failed to bring link %s up: %s", name, err)
+	}
+
+	return link, nil
+}
+
 func getDynamicIP(t Netlink, link netlink.Link, endpoint *NetworkEndpoint) (client.Client, error) {
 	var ack *dhcp.Packet
 	var err error
@@ -277,6 +335,11 @@ func getDynamicIP(t Netlink, link netlink.Link, endpoint *NetworkEndpoint) (clie
 		return nil, err
 	}
 
+	if endpoint.DHCPClientID != "" {
+		// type 0 marks an identifier other than a hardware address, per RFC 2132, section 9.14
+		dc.SetClientID(append([]byte{0}, endpoint.DHCPClientID...))
+	}
+
 	params := []byte{byte(dhcp4.OptionSubnetMask)}
 	if ip.IsUnspecifiedIP(endpoint.Network.Gateway.IP) {
 		params = append(params, byte(dhcp4.OptionRouter))
@@ -366,6 +429,9 @@ func updateDefaultRoute(t Netlink, link netlink.Link, endpoint *NetworkEndpoint)
 	}
 
 	_, defaultNet, _ := net.ParseCIDR("0.0.0.0/0")
+	if ip.IsIPv6(endpoint.Network.Gateway.IP) {
+		_, defaultNet, _ = net.ParseCIDR("::/0")
+	}
 	// delete default route first
 	if err := t.RouteDel(&netlink.Route{LinkIndex: link.Attrs().Index, Dst: defaultNet}); err != nil {
 		if errno, ok := err.(syscall.Errno); !ok || errno != syscall.ESRCH {
@@ -411,6 +477,11 @@ func (t *BaseOperations) updateNameservers(endpoint *NetworkEndpoint) error {
 		log.Infof("Added nameserver: %s", endpoint.Network.Gateway.IP)
 	}
 
+	if len(endpoint.Network.SearchDomains) > 0 {
+		Sys.ResolvConf.AddSearch(endpoint.Network.SearchDomains...)
+		log.Infof("Added search domains: %s", strings.Join(endpoint.Network.SearchDomains, " "))
+	}
+
 	if err := Sys.ResolvConf.Save(); err != nil {
 		return err
 	}
@@ -455,6 +526,27 @@ func apply(nl Netlink, t *BaseOperations, endpoint *NetworkEndpoint) error {
 		return fmt.Errorf("unable to reacquire link %s after rename pass: %s", endpoint.ID, err)
 	}
 
+	// endpoints sharing a NIC are expected to agree on the MTU, the last one applied wins
+	if mtu := endpoint.Network.MTU; mtu > 0 && link.Attrs().MTU != mtu {
+		log.Infof("Setting MTU of link %s to %d", link.Attrs().Name, mtu)
+		if err = nl.LinkSetMTU(link, mtu); err != nil {
+			return fmt.Errorf("unable to set MTU of link %s to %d: %s", endpoint.ID, mtu, err)
+		}
+	}
+
+	// addresses and routes of a tagged network belong on the VLAN link rather than the NIC
+	if vlan := endpoint.Network.VLAN; vlan > 0 {
+		link, err = vlanLink(nl, link, vlan)
+		if err != nil {
+			return fmt.Errorf("unable to acquire VLAN %d link on %s: %s", vlan, endpoint.ID, err)
+		}
+	}
+
+	// lock the link down before it is addressed
+	if err = applyFirewall(link.Attrs().Name, endpoint); err != nil {
+		return fmt.Errorf("unable to apply firewall policy to link %s: %s", endpoint.ID, err)
+	}
+
 	var dc client.Client
 	defer func() {
 		if err != nil && dc != nil {
@@ -515,6 +607,7 @@ func apply(nl Netlink, t *BaseOperations, endpoint *NetworkEndpoint) error {
 	}
 
 	Sys.ResolvConf.RemoveNameservers(endpoint.Network.Nameservers...)
+	Sys.ResolvConf.RemoveSearch(endpoint.Network.SearchDomains...)
 	if err = t.updateNameservers(endpoint); err != nil {
 		return err
 	}
@@ -638,6 +731,32 @@ func (t *BaseOperations) MountLabel(ctx context.Context, label, target string) e
 	return nil
 }
 
+// MountTarget mounts the NFS export at source on target, read-only if the mode says so
+func (t *BaseOperations) MountTarget(ctx context.Context, source url.URL, target string, mode string) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("Mounting %s on %s", source.String(), target)))
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return fmt.Errorf("unable to create mount point %s: %s", target, err)
+	}
+
+	readOnly := nfs.ReadOnly(mode)
+	device, data, err := nfs.MountArgs(&source, readOnly)
+	if err != nil {
+		return err
+	}
+
+	flags := uintptr(syscall.MS_NOATIME)
+	if readOnly {
+		flags |= syscall.MS_RDONLY
+	}
+
+	if err := Sys.Syscall.Mount(device, target, "nfs", flags, data); err != nil {
+		return fmt.Errorf("mounting %s on %s failed: %s", device, target, err)
+	}
+
+	return nil
+}
+
 // ProcessEnv does OS specific checking and munging on the process environment prior to launch
 func (t *BaseOperations) ProcessEnv(env []string) []string {
 	// TODO: figure out how we're going to specify user and pass all the settings along
@@ -751,3 +870,15 @@ func getUserSysProcAttr(uname string) *syscall.SysProcAttr {
 		}
 	}
 }
+
+// Properties returns the vApp properties from the OVF environment delivered via guestinfo
+func (t *BaseOperations) Properties() (map[string]string, error) {
+	defer trace.End(trace.Begin(""))
+
+	doc, err := rpcvmx.NewConfig().String("guestinfo.ovfEnv", "")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOvfEnvironment(doc)
+}
@@ -23,6 +23,7 @@ import (
 	"syscall"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/vishvananda/netlink"
 
 	"github.com/vmware/vic/pkg/trace"
@@ -91,6 +92,29 @@ func (t *Mocker) LinkSetAlias(link netlink.Link, alias string) error {
 	return nil
 }
 
+func (t *Mocker) LinkSetMTU(link netlink.Link, mtu int) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("Setting MTU of %s to %d", link.Attrs().Name, mtu)))
+
+	iface := link.(*Interface)
+	iface.MTU = mtu
+	return nil
+}
+
+func (t *Mocker) LinkAdd(link netlink.Link) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("Adding %s", link.Attrs().Name)))
+
+	name := link.Attrs().Name
+	if _, ok := t.Interfaces[name]; ok {
+		return syscall.EEXIST
+	}
+
+	t.maxSlot++
+	attrs := *link.Attrs()
+	attrs.Index = t.maxSlot
+	t.Interfaces[name] = &Interface{LinkAttrs: attrs}
+	return nil
+}
+
 func (t *Mocker) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
 	defer trace.End(trace.Begin(""))
 
@@ -153,6 +177,11 @@ func (t *Mocker) LinkBySlot(slot int32) (netlink.Link, error) {
 	return nil, errors.New("no such interface")
 }
 
+func TestVLANLinkName(t *testing.T) {
+	assert.Equal(t, "backend.42", vlanLinkName("backend", 42))
+	assert.Equal(t, "averylongn.4094", vlanLinkName("averylongnetwork", 4094))
+}
+
 func TestSlotToPciPath(t *testing.T) {
 	var tests = []struct {
 		slot int32
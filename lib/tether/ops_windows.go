@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"syscall"
 
@@ -50,6 +51,13 @@ func (t *BaseOperations) MountLabel(ctx context.Context, label, target string) e
 	return errors.New("not implemented on windows")
 }
 
+// MountTarget mounts the network share at source on target
+func (t *BaseOperations) MountTarget(ctx context.Context, source url.URL, target string, mode string) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("Mounting %s on %s", source.String(), target)))
+
+	return errors.New("not implemented on windows")
+}
+
 // processEnvOS does OS specific checking and munging on the process environment prior to launch
 func (t *BaseOperations) ProcessEnv(env []string) []string {
 	return env
@@ -76,3 +84,8 @@ func (t *BaseOperations) Cleanup() error {
 func getUserSysProcAttr(uname string) *syscall.SysProcAttr {
 	return nil
 }
+
+// Properties returns the vApp properties from the OVF environment
+func (t *BaseOperations) Properties() (map[string]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tether
+
+import (
+	"encoding/xml"
+	"net"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/lib/config/executor"
+	"github.com/vmware/vic/lib/portlayer/constants"
+)
+
+// ovfEnvironment is the part of the OVF environment document that holds the vApp properties
+type ovfEnvironment struct {
+	Properties []struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"PropertySection>Property"`
+}
+
+// parseOvfEnvironment returns the vApp properties in an OVF environment document, keyed by ID
+func parseOvfEnvironment(doc string) (map[string]string, error) {
+	if doc == "" {
+		return nil, nil
+	}
+
+	var env ovfEnvironment
+	if err := xml.Unmarshal([]byte(doc), &env); err != nil {
+		return nil, err
+	}
+
+	props := make(map[string]string)
+	for _, p := range env.Properties {
+		props[p.Key] = p.Value
+	}
+	return props, nil
+}
+
+// applyProperties overrides the configuration with the vApp properties that are set. Empty
+// properties leave the configuration unchanged.
+func applyProperties(config *ExecutorConfig, props map[string]string) {
+	for id, value := range props {
+		if value == "" {
+			continue
+		}
+
+		switch {
+		case id == executor.DebugProperty:
+			level, err := strconv.Atoi(value)
+			if err != nil {
+				log.Warnf("Ignoring invalid debug level %q from vApp property", value)
+				continue
+			}
+			config.DebugLevel = level
+
+		case id == executor.DNSProperty:
+			var nameservers []net.IP
+			for _, s := range strings.Split(value, ",") {
+				if ns := net.ParseIP(strings.TrimSpace(s)); ns != nil {
+					nameservers = append(nameservers, ns)
+					continue
+				}
+				log.Warnf("Ignoring invalid nameserver %q from vApp property", s)
+			}
+			if len(nameservers) == 0 {
+				continue
+			}
+			// as when set by vic-machine, the bridge network does not use the nameservers
+			for _, endpoint := range config.Networks {
+				if endpoint.Network.Type == constants.BridgeScopeType {
+					continue
+				}
+				endpoint.Network.Nameservers = nameservers
+			}
+
+		case strings.HasPrefix(id, executor.EnvPropertyPrefix):
+			name := strings.TrimPrefix(id, executor.EnvPropertyPrefix)
+			i := strings.LastIndex(name, ".")
+			if i < 1 {
+				continue
+			}
+			if session, ok := config.Sessions[name[:i]]; ok {
+				session.Cmd.Env = setEnv(session.Cmd.Env, name[i+1:], value)
+			}
+		}
+	}
+}
+
+// setEnv replaces the value of name in env, or adds it if not already present
+func setEnv(env []string, name, value string) []string {
+	entry := name + "=" + value
+
+	for i := range env {
+		if strings.HasPrefix(env[i], name+"=") {
+			env[i] = entry
+			return env
+		}
+	}
+	return append(env, entry)
+}
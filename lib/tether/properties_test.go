@@ -0,0 +1,97 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tether
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/vic/lib/config/executor"
+)
+
+const testOvfEnvironment = `<?xml version="1.0" encoding="UTF-8"?>
+<Environment
+     xmlns="http://schemas.dmtf.org/ovf/environment/1"
+     xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+     xmlns:oe="http://schemas.dmtf.org/ovf/environment/1"
+     xmlns:ve="http://www.vmware.com/schema/ovfenv"
+     oe:id="">
+   <PlatformSection>
+      <Kind>VMware ESXi</Kind>
+   </PlatformSection>
+   <PropertySection>
+         <Property oe:key="vic.debug" oe:value="2"/>
+         <Property oe:key="vic.dns" oe:value="10.0.0.1, 10.0.0.2"/>
+         <Property oe:key="vic.env.docker-personality.HTTP_PROXY" oe:value="http://proxy:3128"/>
+         <Property oe:key="vic.env.docker-personality.NO_PROXY" oe:value=""/>
+   </PropertySection>
+</Environment>`
+
+func TestParseOvfEnvironment(t *testing.T) {
+	props, err := parseOvfEnvironment(testOvfEnvironment)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		executor.DebugProperty: "2",
+		executor.DNSProperty:   "10.0.0.1, 10.0.0.2",
+		executor.EnvProperty("docker-personality", "HTTP_PROXY"): "http://proxy:3128",
+		executor.EnvProperty("docker-personality", "NO_PROXY"):   "",
+	}, props)
+
+	props, err = parseOvfEnvironment("")
+	assert.NoError(t, err)
+	assert.Nil(t, props)
+
+	_, err = parseOvfEnvironment("<Environment")
+	assert.Error(t, err)
+}
+
+func TestApplyProperties(t *testing.T) {
+	config := &ExecutorConfig{
+		DebugLevel: 1,
+		Sessions: map[string]*SessionConfig{
+			"docker-personality": &SessionConfig{},
+		},
+		Networks: map[string]*NetworkEndpoint{
+			"client": &NetworkEndpoint{},
+			"public": &NetworkEndpoint{},
+			"bridge": &NetworkEndpoint{},
+		},
+	}
+	config.Networks["bridge"].Network.Type = "bridge"
+	config.Sessions["docker-personality"].Cmd.Env = []string{"PATH=/sbin", "HTTP_PROXY=http://old:80"}
+
+	props, err := parseOvfEnvironment(testOvfEnvironment)
+	assert.NoError(t, err)
+
+	props[executor.EnvProperty("port-layer", "HTTP_PROXY")] = "http://unknown:80"
+	applyProperties(config, props)
+
+	assert.Equal(t, 2, config.DebugLevel)
+	for _, name := range []string{"client", "public"} {
+		assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, config.Networks[name].Network.Nameservers)
+	}
+	assert.Empty(t, config.Networks["bridge"].Network.Nameservers)
+	assert.Equal(t, []string{"PATH=/sbin", "HTTP_PROXY=http://proxy:3128"}, config.Sessions["docker-personality"].Cmd.Env)
+
+	// invalid values leave the configuration unchanged
+	applyProperties(config, map[string]string{
+		executor.DebugProperty: "verbose",
+		executor.DNSProperty:   "nameserver",
+	})
+	assert.Equal(t, 2, config.DebugLevel)
+	assert.Len(t, config.Networks["client"].Network.Nameservers, 2)
+}
@@ -32,8 +32,10 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/vmware/vic/lib/config/executor/validation"
 	"github.com/vmware/vic/lib/system"
 	"github.com/vmware/vic/pkg/dio"
+	"github.com/vmware/vic/pkg/nfs"
 	"github.com/vmware/vic/pkg/serial"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
@@ -45,6 +47,9 @@ const (
 
 	// the length of a truncated ID for use as hostname
 	shortLen = 12
+
+	// how long a process that has passed its deadline has to exit after the stop signal before it is killed
+	deadlineGracePeriod = 10 * time.Second
 )
 
 var Sys = system.New()
@@ -236,12 +241,18 @@ func (t *tether) setNetworks() error {
 
 func (t *tether) setMounts() error {
 	for k, v := range t.config.Mounts {
-		if v.Source.Scheme != "label" {
+		switch v.Source.Scheme {
+		case "label":
+			// this could block indefinitely while waiting for a volume to present
+			t.ops.MountLabel(context.Background(), v.Source.Path, v.Path)
+		case nfs.Scheme:
+			// the share is what the volume is, so the container must not run without it
+			if err := t.ops.MountTarget(context.Background(), v.Source, v.Path, v.Mode); err != nil {
+				return fmt.Errorf("failed to mount volume %s: %s", k, err)
+			}
+		default:
 			return fmt.Errorf("unsupported volume mount type for %s: %s", k, v.Source.Scheme)
 		}
-
-		// this could block indefinitely while waiting for a volume to present
-		t.ops.MountLabel(context.Background(), v.Source.Path, v.Path)
 	}
 	return nil
 }
@@ -373,8 +384,20 @@ func (t *tether) Start() error {
 		// load the config - this modifies the structure values in place
 		extraconfig.Decode(t.src, t.config)
 
+		// vApp properties take precedence over extraconfig
+		props, err := t.ops.Properties()
+		if err != nil {
+			log.Warnf("Unable to load vApp properties: %s", err)
+		}
+		applyProperties(t.config, props)
+
 		t.setLogLevel()
 
+		if err := t.validateConfig(); err != nil {
+			log.Error(err)
+			return err
+		}
+
 		if err := t.setHostname(); err != nil {
 			log.Error(err)
 			return err
@@ -414,6 +437,29 @@ func (t *tether) Start() error {
 	return nil
 }
 
+// validateConfig checks the decoded configuration. Problems are recorded against any sessions that
+// have not been launched so that they are reported in the same manner as a launch failure.
+func (t *tether) validateConfig() error {
+	err := validation.Executor(t.config.spec())
+	if err == nil {
+		return nil
+	}
+
+	for id, session := range t.config.Sessions {
+		session.Lock()
+		if session.Cmd.Process == nil {
+			session.Started = err.Error()
+
+			// FIXME: we cannot have this embedded knowledge of the extraconfig encoding pattern, but not
+			// currently sure how to expose it neatly via a utility function
+			extraconfig.EncodeWithPrefix(t.sink, session, fmt.Sprintf("guestinfo.vice..sessions|%s", id))
+		}
+		session.Unlock()
+	}
+
+	return err
+}
+
 func (t *tether) Stop() error {
 	defer trace.End(trace.Begin(""))
 
@@ -433,6 +479,51 @@ func (t *tether) Reload() {
 	t.reload <- true
 }
 
+// Exec launches session alongside the configured sessions, as docker exec does. Exec sessions are
+// never restarted and their exit does not stop the executor. A session that waits for attach is
+// launched in the background, with any failure recorded in its Started field.
+func (t *tether) Exec(session *SessionConfig) error {
+	defer trace.End(trace.Begin("exec session " + session.ID))
+
+	if err := func() error {
+		t.config.execMutex.Lock()
+		defer t.config.execMutex.Unlock()
+
+		if _, ok := t.config.Sessions[session.ID]; ok {
+			return fmt.Errorf("session %s already exists", session.ID)
+		}
+		if _, ok := t.config.Execs[session.ID]; ok {
+			return fmt.Errorf("session %s already exists", session.ID)
+		}
+
+		if t.config.Execs == nil {
+			t.config.Execs = make(map[string]*SessionConfig)
+		}
+		session.exec = true
+		t.config.Execs[session.ID] = session
+		return nil
+	}(); err != nil {
+		return err
+	}
+
+	// the output of exec sessions is only seen by those attached to them
+	session.Outwriter = dio.MultiWriter()
+	session.Errwriter = dio.MultiWriter()
+	session.Reader = dio.MultiReader()
+
+	if !session.RunBlock {
+		return t.launch(session)
+	}
+
+	session.ClearToLaunch = make(chan struct{})
+	go func() {
+		if err := t.launch(session); err != nil {
+			log.Errorf("Failed to launch exec session %s: %s", session.ID, err)
+		}
+	}()
+	return nil
+}
+
 func (t *tether) Register(name string, extension Extension) {
 	log.Infof("Registering tether extension " + name)
 
@@ -473,27 +564,40 @@ func (t *tether) handleSessionExit(session *SessionConfig) {
 	// set the stop time
 	session.StopTime = time.Now().UTC().Unix()
 
+	if session.deadline != nil {
+		session.deadline.Stop()
+		session.deadline = nil
+	}
+
 	// this returns an arbitrary closure for invocation after the session status update
 	f := t.ops.HandleSessionExit(t.config, session)
 
-	// FIXME: we cannot have this embedded knowledge of the extraconfig encoding pattern, but not
-	// currently sure how to expose it neatly via a utility function
-	extraconfig.EncodeWithPrefix(t.sink, session, fmt.Sprintf("guestinfo.vice..sessions|%s", session.ID))
+	t.encodeSession(session)
 
 	if f != nil {
 		f()
 	}
 }
 
+// encodeSession records the state of a configured session. Exec sessions are not part of the
+// configuration so their state is only available from the executor while it runs.
+func (t *tether) encodeSession(session *SessionConfig) {
+	if session.exec {
+		return
+	}
+
+	// FIXME: we cannot have this embedded knowledge of the extraconfig encoding pattern, but not
+	// currently sure how to expose it neatly via a utility function
+	extraconfig.EncodeWithPrefix(t.sink, session, fmt.Sprintf("guestinfo.vice..sessions|%s", session.ID))
+}
+
 // launch will launch the command defined in the session.
 // This will return an error if the session fails to launch
 func (t *tether) launch(session *SessionConfig) error {
 	defer trace.End(trace.Begin("launching session " + session.ID))
 
 	// encode the result whether success or error
-	defer func() {
-		extraconfig.EncodeWithPrefix(t.sink, session, fmt.Sprintf("guestinfo.vice..sessions|%s", session.ID))
-	}()
+	defer t.encodeSession(session)
 
 	session.Lock()
 	defer session.Unlock()
@@ -509,6 +613,12 @@ func (t *tether) launch(session *SessionConfig) error {
 	session.Cmd.Stderr = session.Errwriter
 	session.Cmd.Stdin = session.Reader
 
+	if err := workingDir(session.Cmd.Dir, session.CreateDir); err != nil {
+		log.Errorf("Working directory check failed for %s: %s", session.Cmd.Dir, err)
+		session.Started = err.Error()
+		return err
+	}
+
 	resolved, err := lookPath(session.Cmd.Path, session.Cmd.Env, session.Cmd.Dir)
 	if err != nil {
 		log.Errorf("Path lookup failed for %s: %s", session.Cmd.Path, err)
@@ -540,7 +650,7 @@ func (t *tether) launch(session *SessionConfig) error {
 		defer t.config.pidMutex.Unlock()
 
 		if !session.Tty {
-			err = session.Cmd.Start()
+			err = establishNonPty(session)
 		} else {
 			err = establishPty(session)
 		}
@@ -566,6 +676,11 @@ func (t *tether) launch(session *SessionConfig) error {
 
 	// Set the Started key to "true" - this indicates a successful launch
 	session.Started = "true"
+	session.OOMKilled = false
+
+	if session.Deadline > 0 {
+		session.deadline = t.enforceDeadline(session)
+	}
 
 	// Write the PID to the associated PID file
 	cmdname := path.Base(session.Cmd.Path)
@@ -580,6 +695,117 @@ func (t *tether) launch(session *SessionConfig) error {
 	return nil
 }
 
+// establishNonPty starts the process of a session without a tty. The output is copied by the tether
+// rather than by Cmd so that handleSessionExit can wait for all of it to be delivered before closing
+// the reader, which may share a connection with the writers.
+func establishNonPty(session *SessionConfig) error {
+	defer trace.End(trace.Begin("initializing output handling for session " + session.ID))
+
+	session.wait = &sync.WaitGroup{}
+
+	// the pipes cannot be created while the writers are set
+	session.Cmd.Stdout = nil
+	session.Cmd.Stderr = nil
+
+	stdout, err := session.Cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := session.Cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err = session.Cmd.Start(); err != nil {
+		return err
+	}
+
+	session.wait.Add(2)
+	go func() {
+		_, gerr := io.Copy(session.Outwriter, stdout)
+		log.Debugf("stdout copy: %s", gerr)
+
+		session.wait.Done()
+	}()
+	go func() {
+		_, gerr := io.Copy(session.Errwriter, stderr)
+		log.Debugf("stderr copy: %s", gerr)
+
+		session.wait.Done()
+	}()
+
+	return nil
+}
+
+// enforceDeadline stops the running process of the session once it has run for the session deadline,
+// sending it the stop signal and then killing it if it has not exited after the grace period. It must be
+// called with the session lock held, and the returned timer stopped when the process exits.
+func (t *tether) enforceDeadline(session *SessionConfig) *time.Timer {
+	process := session.Cmd.Process
+	deadline := time.Duration(session.Deadline) * time.Second
+
+	// running reports whether the process is still the one the deadline is for
+	running := func() bool {
+		return session.deadline != nil && session.Cmd.Process == process
+	}
+
+	return time.AfterFunc(deadline, func() {
+		session.Lock()
+		defer session.Unlock()
+
+		if !running() {
+			return
+		}
+
+		log.Warnf("Session %s has run for its deadline of %s, stopping it", session.ID, deadline)
+		if err := signalSession(session, session.StopSignal); err != nil {
+			log.Errorf("Failed to stop session %s at its deadline: %s", session.ID, err)
+		}
+
+		time.AfterFunc(deadlineGracePeriod, func() {
+			session.Lock()
+			defer session.Unlock()
+
+			if !running() {
+				return
+			}
+
+			log.Warnf("Killing session %s as it did not exit within %s of its deadline", session.ID, deadlineGracePeriod)
+			if err := process.Kill(); err != nil {
+				log.Errorf("Failed to kill session %s: %s", session.ID, err)
+			}
+		})
+	})
+}
+
+// workingDir checks that dir exists and is a directory, creating it if create is set.
+// The errors are those docker reports for a bad working directory, e.g.
+// "chdir /foo: no such file or directory".
+func workingDir(dir string, create bool) error {
+	if dir == "" {
+		return nil
+	}
+
+	fi, err := os.Stat(dir)
+	if os.IsNotExist(err) && create {
+		log.Infof("Creating working directory %s", dir)
+		if err = os.MkdirAll(dir, 0755); err == nil {
+			return nil
+		}
+	}
+	if err != nil {
+		if perr, ok := err.(*os.PathError); ok {
+			return &os.PathError{Op: "chdir", Path: dir, Err: perr.Err}
+		}
+		return err
+	}
+
+	if !fi.IsDir() {
+		return &os.PathError{Op: "chdir", Path: dir, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
 func logConfig(config *ExecutorConfig) {
 	// just pretty print the json for now
 	log.Info("Loaded executor config")
@@ -17,10 +17,12 @@ package tether
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -71,6 +73,8 @@ func (t *tether) childReaper() error {
 	go func() {
 		var status syscall.WaitStatus
 		flag := syscall.WNOHANG | syscall.WUNTRACED | syscall.WCONTINUED
+		// the number of OOM kills by the kernel, so a process killed by one can be told apart
+		oomKills := oomKillCount()
 
 		for range t.incoming {
 			func() {
@@ -117,6 +121,11 @@ func (t *tether) childReaper() error {
 					if ok {
 						session.Lock()
 						session.ExitStatus = status.ExitStatus()
+						if status.Signaled() && status.Signal() == syscall.SIGKILL {
+							count := oomKillCount()
+							session.OOMKilled = count > oomKills
+							oomKills = count
+						}
 						session.Unlock()
 
 						t.handleSessionExit(session)
@@ -133,6 +142,26 @@ func (t *tether) childReaper() error {
 	return nil
 }
 
+// oomKillCount returns the number of processes killed by the kernel for running out of memory since boot,
+// or zero if the kernel does not report it
+func oomKillCount() uint64 {
+	data, err := ioutil.ReadFile("/proc/vmstat")
+	if err != nil {
+		log.Debugf("Unable to read vmstat: %s", err)
+		return 0
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, _ := strconv.ParseUint(fields[1], 10, 64)
+			return count
+		}
+	}
+
+	return 0
+}
+
 func (t *tether) stopReaper() {
 	defer trace.End(trace.Begin("Shutting down child reaping"))
 
@@ -156,7 +185,7 @@ func findExecutable(file string) error {
 	if m := d.Mode(); !m.IsDir() && m&0111 != 0 {
 		return nil
 	}
-	return os.ErrPermission
+	return &os.PathError{Op: "exec", Path: file, Err: os.ErrPermission}
 }
 
 // lookPath searches for an executable binary named file in the directories
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"runtime"
 	"testing"
@@ -120,6 +121,11 @@ func (t *Mocker) ProcessEnv(env []string) []string {
 	return t.Base.ProcessEnv(env)
 }
 
+// Properties returns no vApp properties as there is no OVF environment in tests
+func (t *Mocker) Properties() (map[string]string, error) {
+	return nil, nil
+}
+
 // SetHostname sets both the kernel hostname and /etc/hostname to the specified string
 func (t *Mocker) SetHostname(hostname string, aliases ...string) error {
 	defer trace.End(trace.Begin("mocking hostname to " + hostname))
@@ -149,6 +155,18 @@ func (t *Mocker) MountLabel(ctx context.Context, label, target string) error {
 	return nil
 }
 
+// MountTarget records the mount of the network share at source on target
+func (t *Mocker) MountTarget(ctx context.Context, source url.URL, target string, mode string) error {
+	defer trace.End(trace.Begin(fmt.Sprintf("mocking mounting %s on %s", source.String(), target)))
+
+	if t.Mounts == nil {
+		t.Mounts = make(map[string]string)
+	}
+
+	t.Mounts[source.String()] = target
+	return nil
+}
+
 // Fork triggers vmfork and handles the necessary pre/post OS level operations
 func (t *Mocker) Fork() error {
 	defer trace.End(trace.Begin("mocking fork"))
@@ -251,3 +269,31 @@ func testTeardown(t *testing.T, mocker *Mocker) {
 
 	log.Infof("Finished test teardown for %s", name)
 }
+
+func TestSetMounts(t *testing.T) {
+	mocker := &Mocker{}
+	tthr := &tether{
+		ops: mocker,
+		config: &ExecutorConfig{
+			Mounts: map[string]executor.MountSpec{
+				"disk":  {Source: url.URL{Scheme: "label", Path: "b2d1e6f1c2a3b4c5"}, Path: "/data"},
+				"share": {Source: url.URL{Scheme: "nfs", Host: "10.0.0.5", Path: "/export/volumes/share"}, Path: "/shared", Mode: "ro"},
+			},
+		},
+	}
+
+	if err := tthr.setMounts(); err != nil {
+		t.Fatalf("failed to set mounts: %s", err)
+	}
+	if mocker.Mounts["b2d1e6f1c2a3b4c5"] != "/data" {
+		t.Errorf("disk volume not mounted by label: %#v", mocker.Mounts)
+	}
+	if mocker.Mounts["nfs://10.0.0.5/export/volumes/share"] != "/shared" {
+		t.Errorf("NFS volume not mounted: %#v", mocker.Mounts)
+	}
+
+	tthr.config.Mounts["smb"] = executor.MountSpec{Source: url.URL{Scheme: "smb", Host: "10.0.0.5", Path: "/share"}, Path: "/smb"}
+	if err := tthr.setMounts(); err == nil {
+		t.Errorf("expected an error for an unsupported mount type")
+	}
+}
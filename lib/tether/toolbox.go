@@ -133,6 +133,11 @@ func (t *Toolbox) kill(name string) error {
 }
 
 func (t *Toolbox) killHelper(session *SessionConfig, name string) error {
+	return signalSession(session, name)
+}
+
+// signalSession sends the named signal, SIGTERM if empty, to the process of the session
+func signalSession(session *SessionConfig, name string) error {
 	if name == "" {
 		name = string(ssh.SIGTERM)
 	}
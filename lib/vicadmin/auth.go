@@ -0,0 +1,164 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vicadmin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/vmware/vic/lib/config"
+	"github.com/vmware/vic/pkg/ldap"
+	"github.com/vmware/vic/pkg/trace"
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+// ldapTimeout bounds the connection to, and bind against, the LDAP server
+const ldapTimeout = 10 * time.Second
+
+// ErrInvalidCredentials is returned by Authenticators for an unknown user or a wrong password
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// Authenticator checks the credentials of vicadmin users
+type Authenticator interface {
+	// Authenticate returns ErrInvalidCredentials if the username and password are not valid,
+	// and other errors if they could not be checked
+	Authenticate(ctx context.Context, username, password string) error
+}
+
+// NewAuthenticator returns the Authenticator configured for the VCH: the LDAP server if one is
+// given, otherwise the vSphere target of the VCH
+func NewAuthenticator(vch *config.VirtualContainerHostConfigSpec) (Authenticator, error) {
+	if vch.LDAPURL.Host == "" {
+		target := vch.Target
+		target.User = nil
+
+		return &VSphereAuthenticator{
+			Service:    target,
+			Thumbprint: vch.TargetThumbprint,
+			Insecure:   vch.Insecure,
+		}, nil
+	}
+
+	if !strings.Contains(vch.LDAPUserDN, "%s") {
+		return nil, fmt.Errorf("LDAP user DN %q does not contain %%s for the username", vch.LDAPUserDN)
+	}
+
+	a := &LDAPAuthenticator{
+		URL:    vch.LDAPURL,
+		UserDN: vch.LDAPUserDN,
+	}
+
+	if len(vch.LDAPCertificateAuthorities) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Warnf("Unable to load system root certificates: %s", err)
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(vch.LDAPCertificateAuthorities) {
+			return nil, errors.New("unable to load the LDAP certificate authorities from config")
+		}
+		a.RootCAs = pool
+	}
+
+	return a, nil
+}
+
+// VSphereAuthenticator checks credentials by logging in to vSphere with them
+type VSphereAuthenticator struct {
+	// Service is the SDK URL, without user information
+	Service url.URL
+	// Thumbprint of the vSphere certificate, if it is not trusted otherwise
+	Thumbprint string
+	// Insecure skips the verification of the vSphere certificate
+	Insecure bool
+}
+
+func (a *VSphereAuthenticator) Authenticate(ctx context.Context, username, password string) error {
+	defer trace.End(trace.Begin(username))
+
+	if username == "" || password == "" {
+		return ErrInvalidCredentials
+	}
+
+	u := a.Service
+	u.User = url.UserPassword(username, password)
+
+	s := session.NewSession(&session.Config{
+		Service:    u.String(),
+		Thumbprint: a.Thumbprint,
+		Insecure:   a.Insecure,
+	})
+
+	if _, err := s.Connect(ctx); err != nil {
+		if strings.Contains(err.Error(), "incorrect user name or password") {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	s.Client.Logout(ctx)
+	return nil
+}
+
+// LDAPAuthenticator checks credentials with a simple bind to an LDAP or Active Directory server
+type LDAPAuthenticator struct {
+	// URL is the ldap:// or ldaps:// URL of the server
+	URL url.URL
+	// UserDN is the DN bound as, with %s replaced by the escaped username. For Active Directory
+	// the user principal name may be used, e.g. %s@example.com
+	UserDN string
+	// RootCAs verify the certificate of ldaps:// servers, the system roots if nil
+	RootCAs *x509.CertPool
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) error {
+	defer trace.End(trace.Begin(username))
+
+	if username == "" || password == "" {
+		return ErrInvalidCredentials
+	}
+
+	host, _, err := net.SplitHostPort(a.URL.Host)
+	if err != nil {
+		host = a.URL.Host
+	}
+
+	timeout := ldapTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = deadline.Sub(time.Now())
+	}
+
+	conn, err := ldap.Dial(&a.URL, &tls.Config{ServerName: host, RootCAs: a.RootCAs}, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.Bind(fmt.Sprintf(a.UserDN, ldap.EscapeDN(username)), password)
+	if ldap.IsInvalidCredentials(err) {
+		log.Debugf("LDAP bind failed for %s: %s", username, err)
+		return ErrInvalidCredentials
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vicadmin
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/vic/lib/config"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	vch := &config.VirtualContainerHostConfigSpec{}
+	vch.Target = url.URL{Scheme: "https", Host: "vc.example.com", Path: "/sdk", User: url.UserPassword("admin", "secret")}
+	vch.TargetThumbprint = "AB:CD"
+
+	a, err := NewAuthenticator(vch)
+	require.NoError(t, err)
+	if assert.IsType(t, &VSphereAuthenticator{}, a) {
+		vs := a.(*VSphereAuthenticator)
+		assert.Nil(t, vs.Service.User, "the VCH credentials must not be used")
+		assert.Equal(t, "https://vc.example.com/sdk", vs.Service.String())
+		assert.Equal(t, "AB:CD", vs.Thumbprint)
+	}
+
+	vch.LDAPURL = url.URL{Scheme: "ldaps", Host: "dc.example.com"}
+	vch.LDAPUserDN = "uid=jdoe,dc=example,dc=com"
+	_, err = NewAuthenticator(vch)
+	assert.Error(t, err, "user DN without the username")
+
+	vch.LDAPUserDN = "%s@example.com"
+	a, err = NewAuthenticator(vch)
+	require.NoError(t, err)
+	assert.Equal(t, &LDAPAuthenticator{URL: vch.LDAPURL, UserDN: vch.LDAPUserDN}, a)
+
+	vch.LDAPCertificateAuthorities = []byte("not a certificate")
+	_, err = NewAuthenticator(vch)
+	assert.Error(t, err)
+}
+
+func TestSessions(t *testing.T) {
+	now := time.Now()
+	s := NewSessions(time.Minute)
+	s.now = func() time.Time { return now }
+
+	req := &http.Request{Header: http.Header{}}
+	assert.Nil(t, s.Get(req))
+
+	cookie, err := s.Create("jdoe")
+	require.NoError(t, err)
+	assert.Equal(t, SessionCookie, cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, 60, cookie.MaxAge)
+
+	req.AddCookie(cookie)
+	if session := s.Get(req); assert.NotNil(t, session) {
+		assert.Equal(t, "jdoe", session.Username)
+	}
+
+	forged := &http.Request{Header: http.Header{}}
+	forged.AddCookie(&http.Cookie{Name: SessionCookie, Value: "forged"})
+	assert.Nil(t, s.Get(forged))
+
+	// sessions expire
+	now = now.Add(time.Minute)
+	assert.Nil(t, s.Get(req))
+
+	cookie, err = s.Create("jdoe")
+	require.NoError(t, err)
+	req = &http.Request{Header: http.Header{}}
+	req.AddCookie(cookie)
+	assert.NotNil(t, s.Get(req))
+
+	// and end on logout
+	cleared := s.Delete(req)
+	assert.Equal(t, -1, cleared.MaxAge)
+	assert.Nil(t, s.Get(req))
+}
@@ -0,0 +1,133 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vicadmin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// SessionCookie is the name of the cookie holding the vicadmin session token
+	SessionCookie = "vicadmin-session"
+	// DefaultSessionTimeout is how long a vicadmin session lasts after login
+	DefaultSessionTimeout = 30 * time.Minute
+
+	sessionTokenSize = 32
+)
+
+// Session is a logged in vicadmin user
+type Session struct {
+	Username string
+	Expires  time.Time
+}
+
+// Sessions holds the sessions of logged in vicadmin users, keyed by the token in their cookie.
+// Sessions are kept in memory, so users log in again after vicadmin restarts.
+type Sessions struct {
+	timeout time.Duration
+
+	m        sync.Mutex
+	sessions map[string]*Session
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewSessions returns a session store whose sessions expire after timeout
+func NewSessions(timeout time.Duration) *Sessions {
+	return &Sessions{
+		timeout:  timeout,
+		sessions: make(map[string]*Session),
+		now:      time.Now,
+	}
+}
+
+// Create starts a session for username and returns the cookie that identifies it
+func (s *Sessions) Create(username string) (*http.Cookie, error) {
+	b := make([]byte, sessionTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(b)
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	now := s.now()
+	for t, session := range s.sessions {
+		if !now.Before(session.Expires) {
+			delete(s.sessions, t)
+		}
+	}
+
+	expires := now.Add(s.timeout)
+	s.sessions[token] = &Session{Username: username, Expires: expires}
+
+	return &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(s.timeout.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+	}, nil
+}
+
+// Get returns the unexpired session of the request, or nil if it has none
+func (s *Sessions) Get(req *http.Request) *Session {
+	c, err := req.Cookie(SessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	session, ok := s.sessions[c.Value]
+	if !ok {
+		return nil
+	}
+
+	if !s.now().Before(session.Expires) {
+		delete(s.sessions, c.Value)
+		return nil
+	}
+
+	return session
+}
+
+// Delete ends the session of the request, if any, and returns a cookie that clears it
+func (s *Sessions) Delete(req *http.Request) *http.Cookie {
+	if c, err := req.Cookie(SessionCookie); err == nil {
+		s.m.Lock()
+		delete(s.sessions, c.Value)
+		s.m.Unlock()
+	}
+
+	return &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+	}
+}
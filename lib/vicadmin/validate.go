@@ -23,6 +23,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	// "github.com/vmware/govmomi/vim25/types"
@@ -35,10 +36,13 @@ import (
 	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/lib/config"
 	"github.com/vmware/vic/lib/install/validate"
+	"github.com/vmware/vic/lib/portlayer/scheduler"
 	"github.com/vmware/vic/lib/tether"
+	"github.com/vmware/vic/pkg/certificate"
 	"github.com/vmware/vic/pkg/trace"
 	"github.com/vmware/vic/pkg/version"
 	"github.com/vmware/vic/pkg/vsphere/session"
+	"github.com/vmware/vic/pkg/vsphere/vm"
 )
 
 type Validator struct {
@@ -48,6 +52,10 @@ type Validator struct {
 	FirewallIssues   template.HTML
 	LicenseStatus    template.HTML
 	LicenseIssues    template.HTML
+	CertStatus       template.HTML
+	CertIssues       template.HTML
+	ToolsStatus      template.HTML
+	ToolsIssues      template.HTML
 	NetworkStatus    template.HTML
 	NetworkIssues    template.HTML
 	StorageRemaining template.HTML
@@ -55,13 +63,24 @@ type Validator struct {
 	DockerPort       string
 	VCHStatus        template.HTML
 	VCHIssues        template.HTML
+
+	MaintenanceStatus template.HTML
+	MaintenanceIssues template.HTML
+
+	ReleaseStatus template.HTML
+	ReleaseIssues template.HTML
 }
 
 const (
 	GoodStatus = template.HTML(`<i class="icon-ok"></i>`)
 	BadStatus  = template.HTML(`<i class="icon-attention"></i>`)
+
+	releaseTimeout = 5 * time.Second
 )
 
+// releases caches the latest release published at the release URL across dashboard requests
+var releases version.ReleaseChecker
+
 func NewValidator(ctx context.Context, vch *config.VirtualContainerHostConfigSpec, sess *session.Session) *Validator {
 	defer trace.End(trace.Begin(""))
 	log.Infof("Creating new validator")
@@ -150,9 +169,131 @@ func NewValidator(ctx context.Context, vch *config.VirtualContainerHostConfigSpe
 
 	v.QueryDatastore(ctx, vch, sess)
 	v.QueryVCHStatus(vch)
+	v.QueryCertificateStatus(vch)
+	v.QueryToolsStatus(ctx, vch, sess)
+	v.QueryMaintenanceStatus(scheduler.DefaultStatusPath)
+	v.QueryReleaseStatus(ctx, vch)
 	return v
 }
 
+// QueryToolsStatus reports whether the guest tools of the appliance are running, without which
+// vSphere does not see the appliance addresses
+func (v *Validator) QueryToolsStatus(ctx context.Context, vch *config.VirtualContainerHostConfigSpec, sess *session.Session) {
+	defer trace.End(trace.Begin(""))
+	v.ToolsStatus = GoodStatus
+	v.ToolsIssues = template.HTML("")
+
+	var moref types.ManagedObjectReference
+	if !moref.FromString(vch.ID) {
+		log.Errorf("Unable to parse appliance reference %q", vch.ID)
+		return
+	}
+
+	tools, err := vm.NewVirtualMachine(ctx, sess, moref).ToolsStatus(ctx)
+	if err != nil {
+		v.ToolsStatus = BadStatus
+		v.ToolsIssues = template.HTML(fmt.Sprintf("<span class=\"error-message\">Unable to query guest tools: %s</span>\n", template.HTMLEscapeString(err.Error())))
+		return
+	}
+	log.Infof("Appliance tools status: %s", tools)
+
+	if !tools.Running() {
+		v.ToolsStatus = BadStatus
+		v.ToolsIssues = template.HTML("<span class=\"error-message\">Guest tools are not running, appliance addresses and guest operations are unavailable</span>\n")
+	}
+}
+
+// QueryCertificateStatus reports whether the host certificate has reached one of the configured expiry thresholds
+func (v *Validator) QueryCertificateStatus(vch *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(""))
+	v.CertStatus = GoodStatus
+	v.CertIssues = template.HTML("")
+
+	if vch.HostCertificate.IsNil() {
+		return
+	}
+
+	em, err := vch.HostCertificateMonitor()
+	if err != nil {
+		v.CertStatus = BadStatus
+		v.CertIssues = template.HTML(fmt.Sprintf("<span class=\"error-message\">Unable to load host certificate: %s</span>\n", err))
+		return
+	}
+
+	status := em.Status(time.Now())
+	log.Infof("Host certificate status: %s", status)
+
+	switch status.State {
+	case certificate.CertificateExpiring, certificate.CertificateExpired:
+		v.CertStatus = BadStatus
+		v.CertIssues = template.HTML(fmt.Sprintf("<span class=\"error-message\">%s</span>\n", template.HTMLEscapeString(status.String())))
+	}
+}
+
+// QueryMaintenanceStatus reports the last run of each port layer maintenance job from the status
+// written to path by the port layer, flagging the jobs whose last run failed
+func (v *Validator) QueryMaintenanceStatus(path string) {
+	defer trace.End(trace.Begin(path))
+	v.MaintenanceStatus = GoodStatus
+	v.MaintenanceIssues = template.HTML("")
+
+	jobs, err := scheduler.ReadStatus(path)
+	if err != nil {
+		// the status is only written once the port layer has started
+		log.Warnf("Unable to read maintenance job status: %s", err)
+		v.MaintenanceIssues = template.HTML("<span>Maintenance job status is not available yet</span>\n")
+		return
+	}
+
+	for _, job := range jobs {
+		var msg string
+		switch {
+		case !job.Enabled:
+			msg = fmt.Sprintf("%s is disabled", job.Name)
+		case job.Runs == 0:
+			msg = fmt.Sprintf("%s has not run yet", job.Name)
+		default:
+			msg = fmt.Sprintf("%s last ran %s, taking %s", job.Name, job.LastRun.Format(time.RFC1123), job.Duration)
+		}
+
+		if job.Error != "" {
+			v.MaintenanceStatus = BadStatus
+			v.MaintenanceIssues = template.HTML(fmt.Sprintf("%s<span class=\"error-message\">%s: %s</span>\n",
+				v.MaintenanceIssues, template.HTMLEscapeString(msg), template.HTMLEscapeString(job.Error)))
+			continue
+		}
+
+		v.MaintenanceIssues = template.HTML(fmt.Sprintf("%s<span>%s</span>\n", v.MaintenanceIssues, template.HTMLEscapeString(msg)))
+	}
+}
+
+// QueryReleaseStatus reports whether a newer release is published at the release URL of the VCH,
+// leaving the status empty if the VCH has no release URL
+func (v *Validator) QueryReleaseStatus(ctx context.Context, vch *config.VirtualContainerHostConfigSpec) {
+	defer trace.End(trace.Begin(vch.ReleaseURL.String()))
+	if vch.ReleaseURL.Host == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, releaseTimeout)
+	defer cancel()
+
+	v.ReleaseStatus = GoodStatus
+	v.ReleaseIssues = template.HTML("<span>Up to date</span>\n")
+
+	r, err := releases.Upgrade(ctx, &vch.ReleaseURL, version.GetBuild())
+	if err != nil {
+		log.Warnf("Unable to check for new releases: %s", err)
+		v.ReleaseStatus = BadStatus
+		v.ReleaseIssues = template.HTML(fmt.Sprintf("<span class=\"error-message\">Unable to check for new releases: %s</span>\n", template.HTMLEscapeString(err.Error())))
+		return
+	}
+	if r != nil {
+		v.ReleaseStatus = BadStatus
+		v.ReleaseIssues = template.HTML(fmt.Sprintf("<span class=\"error-message\">%s</span>\n", template.HTMLEscapeString(r.String())))
+	}
+}
+
 type dsList []mo.Datastore
 
 func (d dsList) Len() int           { return len(d) }
@@ -27,6 +27,7 @@ import (
 	"math/big"
 	"net"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/vmware/vic/pkg/errors"
@@ -111,32 +112,58 @@ func templateAsClientOnly(template *x509.Certificate) *x509.Certificate {
 	return template
 }
 
-// templateWithServer adds the capabilities of the certificate to be only used for server auth
-func templateWithServer(template *x509.Certificate, domain string) *x509.Certificate {
+// templateWithServer adds the capabilities of the certificate to be only used for server auth.
+// The domain and any alternates are added as subjectAltNames - IP addresses as IP entries, anything
+// else, including wildcard names of the form *.domain, as DNS entries.
+func templateWithServer(template *x509.Certificate, domain string, alternates []string) *x509.Certificate {
 	template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
 
 	// abide by the spec - if CN is an IP, put it in the subjectAltName as well
 	ip := net.ParseIP(domain)
 	if ip != nil {
-		template.IPAddresses = []net.IP{ip}
-
 		// try best guess at DNSNames entries
 		names, err := net.LookupAddr(domain)
 		if err == nil && len(names) > 0 {
-			template.DNSNames = names
+			alternates = append(alternates, names...)
 		}
-
-		return template
+	} else if domain != "" {
+		template.Subject.CommonName = domain
 	}
 
-	if domain != "" {
-		template.Subject.CommonName = domain
-		template.DNSNames = []string{domain}
+	for _, name := range append([]string{domain}, alternates...) {
+		addSubjectAltName(template, name)
 	}
 
 	return template
 }
 
+// addSubjectAltName adds name to the IP or DNS subjectAltNames of the template, ignoring
+// empty names and duplicates
+func addSubjectAltName(template *x509.Certificate, name string) {
+	// reverse lookups return fully qualified names
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return
+	}
+
+	if ip := net.ParseIP(name); ip != nil {
+		for _, existing := range template.IPAddresses {
+			if existing.Equal(ip) {
+				return
+			}
+		}
+		template.IPAddresses = append(template.IPAddresses, ip)
+		return
+	}
+
+	for _, existing := range template.DNSNames {
+		if strings.EqualFold(existing, name) {
+			return
+		}
+	}
+	template.DNSNames = append(template.DNSNames, name)
+}
+
 // createCertificate creates a certificate from the supplied template:
 // template: an x509 template describing the certificate to generate.
 // parent: either a CA certificate, or template (for self-signed). If nil, will use template.
@@ -247,10 +274,12 @@ func ParseCertificate(cb, kb []byte) (*x509.Certificate, *rsa.PrivateKey, error)
 	return cert, key, nil
 }
 
-func CreateSelfSigned(domain string, org []string, size int) (cert bytes.Buffer, key bytes.Buffer, err error) {
+// CreateSelfSigned creates a self-signed server certificate for domain. Any alternates, whether IP
+// addresses, FQDNs or wildcard names, are included as subjectAltNames.
+func CreateSelfSigned(domain string, org []string, size int, alternates ...string) (cert bytes.Buffer, key bytes.Buffer, err error) {
 	defer trace.End(trace.Begin(""))
 
-	template, pkey, err := templateWithKey(templateWithServer(template(org), domain), size)
+	template, pkey, err := templateWithKey(templateWithServer(template(org), domain, alternates), size)
 	if err != nil {
 		return cert, key, err
 	}
@@ -269,7 +298,9 @@ func CreateRootCA(domain string, org []string, size int) (cert bytes.Buffer, key
 	return createCertificate(template, nil, pkey, nil)
 }
 
-func CreateServerCertificate(domain string, org []string, size int, cb, kb []byte) (cert bytes.Buffer, key bytes.Buffer, err error) {
+// CreateServerCertificate creates a server certificate for domain signed by the supplied CA. Any
+// alternates, whether IP addresses, FQDNs or wildcard names, are included as subjectAltNames.
+func CreateServerCertificate(domain string, org []string, size int, cb, kb []byte, alternates ...string) (cert bytes.Buffer, key bytes.Buffer, err error) {
 	defer trace.End(trace.Begin(""))
 
 	// Load up the CA
@@ -279,7 +310,7 @@ func CreateServerCertificate(domain string, org []string, size int, cb, kb []byt
 	}
 
 	// Generate the new cert
-	template, pkey, err := templateWithKey(templateWithServer(template(org), domain), size)
+	template, pkey, err := templateWithKey(templateWithServer(template(org), domain, alternates), size)
 	if err != nil {
 		return cert, key, err
 	}
@@ -16,6 +16,7 @@ package certificate
 
 import (
 	"crypto/x509"
+	"net"
 	"testing"
 
 	log "github.com/Sirupsen/logrus"
@@ -92,3 +93,40 @@ func TestFailedValidation(t *testing.T) {
 	assert.Error(t, err, "Expected to pass second verify")
 
 }
+
+func TestServerCertificateAltNames(t *testing.T) {
+	log.SetLevel(log.DebugLevel)
+
+	cacert, cakey, err := CreateRootCA("somewhere.com", []string{"MyOrg"}, 2048)
+	assert.NoError(t, err, "Failed generating ca certificate")
+
+	alternates := []string{"10.0.0.2", "vch.somewhere.com", "*.dhcp.somewhere.com", "10.0.0.2", "fd00::2", "VCH.somewhere.com"}
+	cert, key, err := CreateServerCertificate("somewhere.com", []string{"MyOrg"}, 2048, cacert.Bytes(), cakey.Bytes(), alternates...)
+	assert.NoError(t, err, "Failed generating signed certificate")
+
+	tlsCert, _, err := ParseCertificate(cert.Bytes(), key.Bytes())
+	assert.NoError(t, err, "Failed loading signed certificate")
+
+	assert.Equal(t, "somewhere.com", tlsCert.Subject.CommonName)
+	assert.Equal(t, []string{"somewhere.com", "vch.somewhere.com", "*.dhcp.somewhere.com"}, tlsCert.DNSNames)
+	if assert.Len(t, tlsCert.IPAddresses, 2) {
+		assert.True(t, tlsCert.IPAddresses[0].Equal(net.ParseIP("10.0.0.2")))
+		assert.True(t, tlsCert.IPAddresses[1].Equal(net.ParseIP("fd00::2")))
+	}
+
+	for _, name := range []string{"somewhere.com", "vch.somewhere.com", "host-1.dhcp.somewhere.com", "10.0.0.2", "fd00::2"} {
+		assert.NoError(t, tlsCert.VerifyHostname(name), "Expected certificate to be valid for %s", name)
+	}
+	assert.Error(t, tlsCert.VerifyHostname("10.0.0.3"))
+}
+
+func TestSelfSignedIPAltNames(t *testing.T) {
+	cert, key, err := CreateSelfSigned("10.0.0.2", []string{"MyOrg"}, 2048, "vch.somewhere.com")
+	assert.NoError(t, err, "Failed generating self-signed certificate")
+
+	tlsCert, _, err := ParseCertificate(cert.Bytes(), key.Bytes())
+	assert.NoError(t, err, "Failed loading self-signed certificate")
+
+	assert.NoError(t, tlsCert.VerifyHostname("10.0.0.2"))
+	assert.NoError(t, tlsCert.VerifyHostname("vch.somewhere.com"))
+}
@@ -0,0 +1,374 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certificate
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/pkg/errors"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+const (
+	// DefaultRenewalOverlap is how long before expiry a self-signed certificate is renewed,
+	// and so how long the old and new certificates are both valid for
+	DefaultRenewalOverlap = 14 * 24 * time.Hour
+
+	// DefaultExpiryCheckInterval is how often a running ExpiryMonitor checks its certificate
+	DefaultExpiryCheckInterval = time.Hour
+)
+
+// DefaultExpiryThresholds are the times remaining before expiry at which a warning is raised
+var DefaultExpiryThresholds = []time.Duration{
+	30 * 24 * time.Hour,
+	7 * 24 * time.Hour,
+	24 * time.Hour,
+}
+
+// ExpiryState describes where a certificate is in its validity period
+type ExpiryState int
+
+const (
+	// CertificateValid indicates no warning threshold has been reached
+	CertificateValid ExpiryState = iota
+	// CertificateExpiring indicates a warning threshold has been reached
+	CertificateExpiring
+	// CertificateExpired indicates the certificate is no longer valid
+	CertificateExpired
+	// CertificateRenewed indicates the certificate was replaced with a renewed one
+	CertificateRenewed
+)
+
+func (s ExpiryState) String() string {
+	switch s {
+	case CertificateValid:
+		return "valid"
+	case CertificateExpiring:
+		return "expiring"
+	case CertificateExpired:
+		return "expired"
+	case CertificateRenewed:
+		return "renewed"
+	}
+	return "unknown"
+}
+
+// ExpiryStatus is the result of checking a certificate for expiry
+type ExpiryStatus struct {
+	Name      string
+	State     ExpiryState
+	NotAfter  time.Time
+	Remaining time.Duration
+	// Threshold is the smallest warning threshold reached, if any
+	Threshold time.Duration
+}
+
+func (s ExpiryStatus) String() string {
+	switch s.State {
+	case CertificateExpired:
+		return fmt.Sprintf("%s certificate expired on %s", s.Name, s.NotAfter.Format(time.RFC1123))
+	case CertificateExpiring:
+		return fmt.Sprintf("%s certificate expires in %s (on %s)", s.Name, s.Remaining.Truncate(time.Minute), s.NotAfter.Format(time.RFC1123))
+	case CertificateRenewed:
+		return fmt.Sprintf("%s certificate was renewed and is now valid until %s", s.Name, s.NotAfter.Format(time.RFC1123))
+	}
+	return fmt.Sprintf("%s certificate is valid until %s", s.Name, s.NotAfter.Format(time.RFC1123))
+}
+
+// IsSelfSigned returns true if the certificate was signed by its own key
+func IsSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+
+	// CheckSignatureFrom requires the parent to be a CA, which self-signed server certificates are not
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// ExpiryMonitor tracks a certificate's validity, reporting each warning threshold as it is reached
+// via Notify and, if AutoRenew is set, replacing self-signed certificates once they are within
+// RenewalOverlap of expiry. The certificate served via GetCertificate always reflects the latest
+// renewal so TLS listeners pick up the new certificate without restarting.
+type ExpiryMonitor struct {
+	// Name identifies the certificate in status messages
+	Name string
+	// Thresholds are the times remaining before expiry at which to warn
+	Thresholds []time.Duration
+	// AutoRenew enables renewal of self-signed certificates
+	AutoRenew bool
+	// RenewalOverlap is how long before expiry to renew
+	RenewalOverlap time.Duration
+	// Notify is called when a threshold is reached or the certificate is renewed
+	Notify func(ExpiryStatus)
+	// Persist, if set, stores a renewed key pair before it is served. If it fails the renewal
+	// is abandoned and attempted again at the next check.
+	Persist func(*KeyPair) error
+	// Source, if set, is consulted at each check for a certificate replaced outside this monitor,
+	// such as one renewed and persisted by another process. A changed certificate is served in
+	// place of the current one and reported as renewed.
+	Source func() (*KeyPair, error)
+
+	m sync.RWMutex
+
+	kp   *KeyPair
+	cert *tls.Certificate
+	x509 *x509.Certificate
+
+	// warned is the smallest threshold that has already been reported
+	warned time.Duration
+}
+
+// NewExpiryMonitor creates a monitor for the supplied key pair with the default thresholds
+func NewExpiryMonitor(name string, kp *KeyPair) (*ExpiryMonitor, error) {
+	em := &ExpiryMonitor{
+		Name:           name,
+		Thresholds:     DefaultExpiryThresholds,
+		RenewalOverlap: DefaultRenewalOverlap,
+	}
+
+	if err := em.load(kp); err != nil {
+		return nil, err
+	}
+
+	return em, nil
+}
+
+func (em *ExpiryMonitor) load(kp *KeyPair) error {
+	cert, err := kp.Certificate()
+	if err != nil {
+		return err
+	}
+
+	x, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return errors.Errorf("Failed to parse %s certificate: %s", em.Name, err)
+	}
+
+	em.m.Lock()
+	defer em.m.Unlock()
+
+	em.kp = kp
+	em.cert = cert
+	em.x509 = x
+	em.warned = 0
+
+	return nil
+}
+
+// KeyPair returns the current key pair, which will differ from that supplied to NewExpiryMonitor
+// once the certificate has been renewed
+func (em *ExpiryMonitor) KeyPair() *KeyPair {
+	em.m.RLock()
+	defer em.m.RUnlock()
+
+	return em.kp
+}
+
+// GetCertificate is suitable for use as tls.Config.GetCertificate
+func (em *ExpiryMonitor) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	em.m.RLock()
+	defer em.m.RUnlock()
+
+	return em.cert, nil
+}
+
+// Status returns the expiry status of the current certificate at the given time without
+// triggering notifications or renewal
+func (em *ExpiryMonitor) Status(now time.Time) ExpiryStatus {
+	em.m.RLock()
+	defer em.m.RUnlock()
+
+	return em.status(now)
+}
+
+func (em *ExpiryMonitor) status(now time.Time) ExpiryStatus {
+	s := ExpiryStatus{
+		Name:      em.Name,
+		State:     CertificateValid,
+		NotAfter:  em.x509.NotAfter,
+		Remaining: em.x509.NotAfter.Sub(now),
+	}
+
+	if s.Remaining <= 0 {
+		s.State = CertificateExpired
+		return s
+	}
+
+	for _, t := range em.Thresholds {
+		if s.Remaining <= t && (s.Threshold == 0 || t < s.Threshold) {
+			s.State = CertificateExpiring
+			s.Threshold = t
+		}
+	}
+
+	return s
+}
+
+// Check evaluates the certificate at the given time, renewing it if required and calling Notify
+// for any newly reached threshold or renewal. Each threshold is only reported once per certificate.
+func (em *ExpiryMonitor) Check(now time.Time) ExpiryStatus {
+	if reloaded, err := em.reload(); err != nil {
+		log.Errorf("Unable to reload %s certificate: %s", em.Name, err)
+	} else if reloaded {
+		s := em.Status(now)
+		s.State = CertificateRenewed
+		em.notify(s)
+		return s
+	}
+
+	s := em.Status(now)
+
+	if em.AutoRenew && s.Remaining <= em.RenewalOverlap {
+		if err := em.renew(); err != nil {
+			log.Errorf("Unable to renew %s certificate: %s", em.Name, err)
+		} else {
+			s = em.Status(now)
+			s.State = CertificateRenewed
+			em.notify(s)
+			return s
+		}
+	}
+
+	em.m.Lock()
+	report := s.State == CertificateExpired ||
+		(s.State == CertificateExpiring && (em.warned == 0 || s.Threshold < em.warned))
+	if report {
+		em.warned = s.Threshold
+	}
+	em.m.Unlock()
+
+	if report {
+		em.notify(s)
+	}
+
+	return s
+}
+
+func (em *ExpiryMonitor) notify(s ExpiryStatus) {
+	switch s.State {
+	case CertificateExpired:
+		log.Error(s.String())
+	case CertificateExpiring:
+		log.Warn(s.String())
+	default:
+		log.Info(s.String())
+	}
+
+	if em.Notify != nil {
+		em.Notify(s)
+	}
+}
+
+// renew replaces the current certificate with a self-signed one carrying the same subject, names and usages
+func (em *ExpiryMonitor) renew() error {
+	defer trace.End(trace.Begin(em.Name))
+
+	em.m.RLock()
+	old := em.x509
+	kp := em.kp
+	size := 2048
+	if key, ok := em.cert.PrivateKey.(*rsa.PrivateKey); ok {
+		size = key.N.BitLen()
+	}
+	em.m.RUnlock()
+
+	if !IsSelfSigned(old) {
+		return errors.Errorf("%s certificate is not self-signed", em.Name)
+	}
+
+	cert, key, err := renewSelfSigned(old, size)
+	if err != nil {
+		return err
+	}
+
+	renewed := NewKeyPair(kp.CertFile, kp.KeyFile, cert.Bytes(), key.Bytes())
+	if em.Persist != nil {
+		if err := em.Persist(renewed); err != nil {
+			return errors.Errorf("Failed to store renewed %s certificate: %s", em.Name, err)
+		}
+	}
+
+	return em.load(renewed)
+}
+
+// reload replaces the current certificate with the one from Source if it has changed
+func (em *ExpiryMonitor) reload() (bool, error) {
+	if em.Source == nil {
+		return false, nil
+	}
+
+	kp, err := em.Source()
+	if err != nil || kp == nil {
+		return false, err
+	}
+
+	em.m.RLock()
+	unchanged := bytes.Equal(kp.CertPEM, em.kp.CertPEM) && bytes.Equal(kp.KeyPEM, em.kp.KeyPEM)
+	em.m.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	return true, em.load(kp)
+}
+
+// Run checks the certificate at the given interval until the context is done
+func (em *ExpiryMonitor) Run(ctx context.Context, interval time.Duration) {
+	em.Check(time.Now())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			em.Check(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// renewSelfSigned generates a new self-signed certificate from the old one, with a new key of the given size
+func renewSelfSigned(old *x509.Certificate, size int) (cert bytes.Buffer, key bytes.Buffer, err error) {
+	t := template(old.Subject.Organization)
+	if t == nil {
+		return cert, key, errors.New("Failed to generate certificate template")
+	}
+
+	t.Subject = old.Subject
+	t.DNSNames = old.DNSNames
+	t.IPAddresses = old.IPAddresses
+	t.KeyUsage = old.KeyUsage
+	t.ExtKeyUsage = old.ExtKeyUsage
+	t.IsCA = old.IsCA
+
+	t, pkey, err := templateWithKey(t, size)
+	if err != nil {
+		return cert, key, err
+	}
+
+	return createCertificate(t, nil, pkey, nil)
+}
@@ -0,0 +1,193 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certificate
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryThresholds(t *testing.T) {
+	cert, key, err := CreateSelfSigned("somewhere.com", []string{"MyOrg"}, 1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	em, err := NewExpiryMonitor("test", NewKeyPair("", "", cert.Bytes(), key.Bytes()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notified []ExpiryStatus
+	em.Notify = func(s ExpiryStatus) {
+		notified = append(notified, s)
+	}
+
+	notAfter := em.Status(time.Now()).NotAfter
+	day := 24 * time.Hour
+
+	s := em.Check(notAfter.Add(-60 * day))
+	assert.Equal(t, CertificateValid, s.State)
+	assert.Len(t, notified, 0)
+
+	s = em.Check(notAfter.Add(-20 * day))
+	assert.Equal(t, CertificateExpiring, s.State)
+	assert.Equal(t, 30*day, s.Threshold)
+	assert.Len(t, notified, 1)
+
+	// same threshold is only reported once
+	em.Check(notAfter.Add(-19 * day))
+	assert.Len(t, notified, 1)
+
+	s = em.Check(notAfter.Add(-2 * day))
+	assert.Equal(t, 7*day, s.Threshold)
+	assert.Len(t, notified, 2)
+
+	s = em.Check(notAfter.Add(time.Hour))
+	assert.Equal(t, CertificateExpired, s.State)
+	assert.Len(t, notified, 3)
+}
+
+func TestExpiryAutoRenew(t *testing.T) {
+	cert, key, err := CreateSelfSigned("somewhere.com", []string{"MyOrg"}, 1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	em, err := NewExpiryMonitor("test", NewKeyPair("", "", cert.Bytes(), key.Bytes()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	em.AutoRenew = true
+
+	old, err := em.GetCertificate(nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	notAfter := em.Status(time.Now()).NotAfter
+	s := em.Check(notAfter.Add(-time.Hour))
+	assert.Equal(t, CertificateRenewed, s.State)
+
+	renewed, err := em.GetCertificate(nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotEqual(t, old.Certificate[0], renewed.Certificate[0])
+
+	x, err := x509.ParseCertificate(renewed.Certificate[0])
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, IsSelfSigned(x))
+	assert.Equal(t, []string{"somewhere.com"}, x.DNSNames)
+	assert.False(t, x.NotAfter.Before(notAfter))
+}
+
+func TestExpiryNoRenewSigned(t *testing.T) {
+	cacert, cakey, err := CreateRootCA("somewhere.com", []string{"MyOrg"}, 1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cert, key, err := CreateServerCertificate("somewhere.com", []string{"MyOrg"}, 1024, cacert.Bytes(), cakey.Bytes())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	em, err := NewExpiryMonitor("test", NewKeyPair("", "", cert.Bytes(), key.Bytes()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	em.AutoRenew = true
+
+	notAfter := em.Status(time.Now()).NotAfter
+	s := em.Check(notAfter.Add(-time.Hour))
+	assert.Equal(t, CertificateExpiring, s.State)
+}
+
+func TestExpiryPersist(t *testing.T) {
+	cert, key, err := CreateSelfSigned("somewhere.com", []string{"MyOrg"}, 1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	em, err := NewExpiryMonitor("test", NewKeyPair("", "", cert.Bytes(), key.Bytes()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	em.AutoRenew = true
+
+	var persisted *KeyPair
+	em.Persist = func(kp *KeyPair) error {
+		return errors.New("datastore unavailable")
+	}
+
+	notAfter := em.Status(time.Now()).NotAfter
+	s := em.Check(notAfter.Add(-time.Hour))
+	assert.Equal(t, CertificateExpiring, s.State)
+	assert.Equal(t, cert.Bytes(), em.KeyPair().CertPEM)
+
+	em.Persist = func(kp *KeyPair) error {
+		persisted = kp
+		return nil
+	}
+
+	s = em.Check(notAfter.Add(-time.Hour))
+	assert.Equal(t, CertificateRenewed, s.State)
+	if assert.NotNil(t, persisted) {
+		assert.Equal(t, persisted, em.KeyPair())
+	}
+}
+
+func TestExpirySource(t *testing.T) {
+	cert, key, err := CreateSelfSigned("somewhere.com", []string{"MyOrg"}, 1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	kp := NewKeyPair("", "", cert.Bytes(), key.Bytes())
+	em, err := NewExpiryMonitor("test", kp)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var notified []ExpiryStatus
+	em.Notify = func(s ExpiryStatus) {
+		notified = append(notified, s)
+	}
+	em.Source = func() (*KeyPair, error) {
+		return kp, nil
+	}
+
+	s := em.Check(time.Now())
+	assert.Equal(t, CertificateValid, s.State)
+	assert.Empty(t, notified)
+
+	cert, key, err = CreateSelfSigned("somewhere.com", []string{"MyOrg"}, 1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+	kp = NewKeyPair("", "", cert.Bytes(), key.Bytes())
+
+	s = em.Check(time.Now())
+	assert.Equal(t, CertificateRenewed, s.State)
+	assert.Len(t, notified, 1)
+	assert.Equal(t, kp, em.KeyPair())
+}
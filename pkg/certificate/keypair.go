@@ -62,8 +62,8 @@ func (kp *KeyPair) SaveCertificate() error {
 	return saveCertificate(kp.CertFile, kp.KeyFile, bytes.NewBuffer(kp.CertPEM), bytes.NewBuffer(kp.KeyPEM))
 }
 
-func (kp *KeyPair) CreateSelfSigned(domain string, org []string, size int) error {
-	c, k, err := CreateSelfSigned(domain, org, size)
+func (kp *KeyPair) CreateSelfSigned(domain string, org []string, size int, alternates ...string) error {
+	c, k, err := CreateSelfSigned(domain, org, size, alternates...)
 	if err != nil {
 		return err
 	}
@@ -86,8 +86,8 @@ func (kp *KeyPair) CreateRootCA(domain string, org []string, size int) error {
 	return nil
 }
 
-func (kp *KeyPair) CreateServerCertificate(domain string, org []string, size int, ca *KeyPair) error {
-	c, k, err := CreateServerCertificate(domain, org, size, ca.CertPEM, ca.KeyPEM)
+func (kp *KeyPair) CreateServerCertificate(domain string, org []string, size int, ca *KeyPair, alternates ...string) error {
+	c, k, err := CreateServerCertificate(domain, org, size, ca.CertPEM, ca.KeyPEM, alternates...)
 	if err != nil {
 		return err
 	}
@@ -110,6 +110,33 @@ func (kp *KeyPair) CreateClientCertificate(domain string, org []string, size int
 	return nil
 }
 
+// RenewSelfSigned replaces a self-signed certificate with a new one carrying the same subject, names
+// and usages, generated with a new key of the same size
+func (kp *KeyPair) RenewSelfSigned() error {
+	if kp.CertPEM == nil || kp.KeyPEM == nil {
+		return errors.New("KeyPair has no data")
+	}
+
+	old, key, err := ParseCertificate(kp.CertPEM, kp.KeyPEM)
+	if err != nil {
+		return err
+	}
+
+	if !IsSelfSigned(old) {
+		return errors.New("Certificate is not self-signed")
+	}
+
+	c, k, err := renewSelfSigned(old, key.N.BitLen())
+	if err != nil {
+		return err
+	}
+
+	kp.CertPEM = c.Bytes()
+	kp.KeyPEM = k.Bytes()
+
+	return nil
+}
+
 // Certificate turns the KeyPair back into useful TLS constructs
 func (kp *KeyPair) Certificate() (*tls.Certificate, error) {
 	if kp.CertPEM == nil || kp.KeyPEM == nil {
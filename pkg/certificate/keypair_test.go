@@ -119,3 +119,41 @@ func TestGetCertificate(t *testing.T) {
 
 	assert.Equal(t, pair, pair2, "Expected loads to be consistent")
 }
+
+func TestRenewSelfSigned(t *testing.T) {
+	pair := NewKeyPair("", "", nil, nil)
+	assert.Error(t, pair.RenewSelfSigned())
+
+	if !assert.NoError(t, pair.CreateSelfSigned("somewhere.com", []string{"MyOrg"}, 1024)) {
+		return
+	}
+	old := *pair
+
+	if !assert.NoError(t, pair.RenewSelfSigned()) {
+		return
+	}
+	assert.NotEqual(t, old.CertPEM, pair.CertPEM)
+	assert.NotEqual(t, old.KeyPEM, pair.KeyPEM)
+
+	x, key, err := ParseCertificate(pair.CertPEM, pair.KeyPEM)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, IsSelfSigned(x))
+	assert.Equal(t, []string{"somewhere.com"}, x.DNSNames)
+	assert.Equal(t, 1024, key.N.BitLen())
+}
+
+func TestRenewSelfSignedSigned(t *testing.T) {
+	ca := NewKeyPair("", "", nil, nil)
+	if !assert.NoError(t, ca.CreateRootCA("somewhere.com", []string{"MyOrg"}, 1024)) {
+		return
+	}
+
+	pair := NewKeyPair("", "", nil, nil)
+	if !assert.NoError(t, pair.CreateServerCertificate("somewhere.com", []string{"MyOrg"}, 1024, ca)) {
+		return
+	}
+
+	assert.Error(t, pair.RenewSelfSigned())
+}
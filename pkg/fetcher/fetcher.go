@@ -17,6 +17,7 @@ package fetcher
 import (
 	"bytes"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -81,8 +82,13 @@ type Options struct {
 	Password string
 
 	InsecureSkipVerify bool
+	// RootCAs are the certificate authorities trusted by the client, the system roots if nil
+	RootCAs *x509.CertPool
 
 	Token *Token
+
+	// Proxies overrides the proxies from the environment for some registries
+	Proxies RegistryProxies
 }
 
 // URLFetcher struct
@@ -100,9 +106,10 @@ type URLFetcher struct {
 func NewURLFetcher(options Options) Fetcher {
 	/* #nosec */
 	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
+		Proxy: options.Proxies.Proxy,
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: options.InsecureSkipVerify,
+			RootCAs:            options.RootCAs,
 		},
 	}
 	client := &http.Client{Transport: tr}
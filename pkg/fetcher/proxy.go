@@ -0,0 +1,42 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetcher
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// RegistryProxies maps registry hosts, with or without a port, to the proxy used to reach them
+// in place of the proxy from the environment. A nil proxy reaches the registry directly.
+type RegistryProxies map[string]*url.URL
+
+// Proxy returns the proxy for the registry the request is for if it has its own, and otherwise
+// the proxy chosen by HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+func (r RegistryProxies) Proxy(req *http.Request) (*url.URL, error) {
+	host := req.URL.Host
+	if proxy, ok := r[host]; ok {
+		return proxy, nil
+	}
+
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		if proxy, ok := r[name]; ok {
+			return proxy, nil
+		}
+	}
+
+	return http.ProxyFromEnvironment(req)
+}
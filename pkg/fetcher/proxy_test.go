@@ -0,0 +1,60 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetcher
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryProxies(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.example.com:3128")
+	local, _ := url.Parse("http://local-proxy:3128")
+
+	proxies := RegistryProxies{
+		"registry.example.com":      proxy,
+		"registry.example.com:5000": local,
+		"direct.example.com":        nil,
+	}
+
+	tests := []struct {
+		url   string
+		proxy *url.URL
+	}{
+		{"https://registry.example.com/v2/", proxy},
+		{"https://registry.example.com:443/v2/", proxy},
+		{"https://registry.example.com:5000/v2/", local},
+		{"https://direct.example.com/v2/", nil},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", test.url, nil)
+		require.NoError(t, err)
+
+		p, err := proxies.Proxy(req)
+		require.NoError(t, err)
+		assert.Equal(t, test.proxy, p, test.url)
+	}
+
+	// a nil map uses the environment
+	req, _ := http.NewRequest("GET", "http://localhost/v2/", nil)
+	p, err := RegistryProxies(nil).Proxy(req)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+}
@@ -16,6 +16,7 @@ package ip
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math"
 	"net"
@@ -56,16 +57,21 @@ func (i *Range) Equal(other *Range) bool {
 
 // Network returns the network that this range represents, if any
 func (i *Range) Network() *net.IPNet {
-	// only works for ipv4
-	first := i.FirstIP.To4()
-	last := i.LastIP.To4()
-	diff := net.IPv4(0, 0, 0, 0).To4()
-	for j := 0; j < net.IPv4len; j++ {
+	first, last := i.FirstIP.To4(), i.LastIP.To4()
+	if first == nil || last == nil {
+		first, last = i.FirstIP.To16(), i.LastIP.To16()
+	}
+	if first == nil || last == nil {
+		return nil
+	}
+
+	diff := make(net.IP, len(first))
+	for j := range diff {
 		diff[j] = first[j] ^ last[j]
 	}
 
 	var m uint
-	for j := net.IPv4len - 1; j >= 0; j-- {
+	for j := len(diff) - 1; j >= 0; j-- {
 		var k uint
 		for ; k < 8; k++ {
 			if diff[j]>>k == 0 {
@@ -83,7 +89,8 @@ func (i *Range) Network() *net.IPNet {
 		return nil
 	}
 
-	mask := net.CIDRMask(32-int(m), 32)
+	bits := len(first) * 8
+	mask := net.CIDRMask(bits-int(m), bits)
 	for j, f := range first {
 		l := f | ^mask[j]
 		if l != last[j] {
@@ -123,13 +130,24 @@ func ParseRange(r string) *Range {
 
 	last = net.ParseIP(comps[1])
 	if last == nil {
-		var end int
-		end, err := strconv.Atoi(comps[1])
-		if err != nil || end <= int(first[15]) || end > math.MaxUint8 {
-			return nil
+		last = make(net.IP, len(first))
+		copy(last, first)
+
+		if first.To4() != nil {
+			// the IPv4 short form replaces the last octet, in decimal
+			end, err := strconv.Atoi(comps[1])
+			if err != nil || end <= int(first[15]) || end > math.MaxUint8 {
+				return nil
+			}
+			last[15] = byte(end)
+		} else {
+			// the IPv6 short form replaces the last group, in hex as the group is written
+			end, err := strconv.ParseUint(comps[1], 16, 16)
+			if err != nil || end <= uint64(binary.BigEndian.Uint16(first[14:])) {
+				return nil
+			}
+			binary.BigEndian.PutUint16(last[14:], uint16(end))
 		}
-
-		last = net.IPv4(first[12], first[13], first[14], byte(end))
 	}
 
 	if bytes.Compare(first, last) > 0 {
@@ -177,6 +195,21 @@ func Empty(i net.IPNet) bool {
 	return i.IP == nil && i.Mask == nil
 }
 
+// IsIPv4 determines if ip is an IPv4 address, including IPv4-mapped IPv6 addresses
+func IsIPv4(ip net.IP) bool {
+	return ip.To4() != nil
+}
+
+// IsIPv6 determines if ip is an IPv6 address that is not IPv4-mapped
+func IsIPv6(ip net.IP) bool {
+	return ip.To4() == nil && ip.To16() != nil
+}
+
+// SameFamily determines if a and b are both IPv4 or both IPv6 addresses
+func SameFamily(a, b net.IP) bool {
+	return IsIPv4(a) == IsIPv4(b) && IsIPv6(a) == IsIPv6(b)
+}
+
 func IsUnspecifiedIP(ip net.IP) bool {
 	return len(ip) == 0 || ip.IsUnspecified()
 }
@@ -197,6 +230,16 @@ func AllZerosAddr(subnet *net.IPNet) net.IP {
 
 // AllOnesAddr returns the all-ones address for a subnet
 func AllOnesAddr(subnet *net.IPNet) net.IP {
+	if subnet.IP.To4() == nil || len(subnet.Mask) == net.IPv6len {
+		ip := subnet.IP.To16()
+		ones := make(net.IP, net.IPv6len)
+		for i := range ip {
+			ones[i] = ip[i] | ^subnet.Mask[i]
+		}
+
+		return ones
+	}
+
 	ones := net.IPv4(0, 0, 0, 0)
 	ip := subnet.IP.To16()
 	for i := range ip[12:] {
@@ -56,6 +56,13 @@ func TestRangeUnmarshalText(t *testing.T) {
 		{"10.10.10.10-24", &Range{net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24")}, nil},
 		{"10.10.10.10-10.10.10.24", &Range{net.ParseIP("10.10.10.10"), net.ParseIP("10.10.10.24")}, nil},
 		{"10.10.10.0/24", &Range{net.ParseIP("10.10.10.0"), net.ParseIP("10.10.10.255")}, nil},
+		{"fd00::10-24", &Range{net.ParseIP("fd00::10"), net.ParseIP("fd00::24")}, nil},
+		{"fd00::10-ff", &Range{net.ParseIP("fd00::10"), net.ParseIP("fd00::ff")}, nil},
+		{"fd00::1:10-1ff", &Range{net.ParseIP("fd00::1:10"), net.ParseIP("fd00::1:1ff")}, nil},
+		{"fd00::10-f", nil, fmt.Errorf("")},
+		{"fd00::10-10000", nil, fmt.Errorf("")},
+		{"fd00::10-fd00::24", &Range{net.ParseIP("fd00::10"), net.ParseIP("fd00::24")}, nil},
+		{"fd00::/120", &Range{net.ParseIP("fd00::"), net.ParseIP("fd00::ff")}, nil},
 	}
 
 	for _, te := range tests {
@@ -121,6 +128,7 @@ func TestAllOnesAddr(t *testing.T) {
 	}{
 		{&net.IPNet{IP: net.ParseIP("192.168.0.0"), Mask: net.CIDRMask(16, 32)}, net.ParseIP("192.168.255.255")},
 		{&net.IPNet{IP: net.ParseIP("192.168.100.0"), Mask: net.CIDRMask(24, 32)}, net.ParseIP("192.168.100.255")},
+		{&net.IPNet{IP: net.ParseIP("fd00::"), Mask: net.CIDRMask(112, 128)}, net.ParseIP("fd00::ffff")},
 	}
 
 	for _, te := range tests {
@@ -139,6 +147,8 @@ func TestRangeNetwork(t *testing.T) {
 		{ParseRange("10.10.10.10/24"), &net.IPNet{IP: net.ParseIP("10.10.10.0"), Mask: net.CIDRMask(24, 32)}},
 		{ParseRange("10.10.10.10-10.10.14.11"), nil},
 		{ParseRange("10.10.10.10-10.10.10.11"), &net.IPNet{IP: net.ParseIP("10.10.10.10"), Mask: net.CIDRMask(31, 32)}},
+		{ParseRange("fd00::10/120"), &net.IPNet{IP: net.ParseIP("fd00::"), Mask: net.CIDRMask(120, 128)}},
+		{ParseRange("fd00::10-fd00::1:11"), nil},
 	}
 
 	for _, te := range tests {
@@ -157,3 +167,24 @@ func TestRangeNetwork(t *testing.T) {
 		assert.EqualValues(t, n.Mask, te.n.Mask)
 	}
 }
+
+func TestAddressFamily(t *testing.T) {
+	v4 := net.ParseIP("10.10.10.10")
+	mapped := net.ParseIP("::ffff:10.10.10.10")
+	v6 := net.ParseIP("fd00::10")
+
+	assert.True(t, IsIPv4(v4))
+	assert.True(t, IsIPv4(mapped))
+	assert.False(t, IsIPv4(v6))
+	assert.False(t, IsIPv4(nil))
+
+	assert.False(t, IsIPv6(v4))
+	assert.False(t, IsIPv6(mapped))
+	assert.True(t, IsIPv6(v6))
+	assert.False(t, IsIPv6(nil))
+
+	assert.True(t, SameFamily(v4, mapped))
+	assert.True(t, SameFamily(v6, net.ParseIP("fd00::1")))
+	assert.False(t, SameFamily(v4, v6))
+	assert.False(t, SameFamily(v6, nil))
+}
@@ -0,0 +1,326 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package iso authors the bootstrap and appliance ISOs from the staged filesystem bundles
+// produced by the isos/*-staging.sh scripts.
+package iso
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/pkg/trace"
+)
+
+const (
+	// systemdDir is where unit files are installed in the root filesystem
+	systemdDir = "/etc/systemd/system"
+
+	isolinuxDir    = "boot/isolinux"
+	xorrisoOptions = "xorriso-options.cfg"
+)
+
+// rdinitPlaceholder matches the commented out kernel command line in the staged isolinux.cfg
+var rdinitPlaceholder = regexp.MustCompile(`(?m)^#(\s*append rdinit)=_INIT_BINARY_`)
+
+// Bundle is an unpacked ISO filesystem bundle - a root filesystem that becomes the initramfs,
+// a boot filesystem holding the kernel and isolinux, and the options used to author the image.
+type Bundle struct {
+	Dir string
+}
+
+// Unpack extracts the staged package into dir and returns the resulting bundle
+func Unpack(pkg, dir string) (*Bundle, error) {
+	defer trace.End(trace.Begin(pkg))
+
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("unable to preserve ownership or permissions when unpacking %s - run as root", pkg)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create target directory %s for unpacking: %s", dir, err)
+	}
+
+	if err := run("", "tar", "-C", dir, "-xf", pkg); err != nil {
+		return nil, fmt.Errorf("error extracting package archive %s: %s", pkg, err)
+	}
+
+	b := &Bundle{Dir: dir}
+
+	// restore attributes recorded by an unpack/pack cycle as non-root
+	if _, err := os.Stat(filepath.Join(dir, "tar-attr.cfg")); err == nil {
+		if err := run(b.RootFS(), "sh", "-c", ". ../tar-attr.cfg"); err != nil {
+			return nil, fmt.Errorf("failed to restore file permissions from manifest: %s", err)
+		}
+	}
+
+	return b, nil
+}
+
+// RootFS returns the path of the root filesystem
+func (b *Bundle) RootFS() string {
+	return filepath.Join(b.Dir, "rootfs")
+}
+
+// BootFS returns the path of the boot filesystem
+func (b *Bundle) BootFS() string {
+	return filepath.Join(b.Dir, "bootfs")
+}
+
+// Path returns the location of the root filesystem path p in the bundle
+func (b *Bundle) Path(p string) string {
+	return filepath.Join(b.RootFS(), p)
+}
+
+// Copy copies the file src into the root filesystem at dst with the specified mode
+func (b *Bundle) Copy(src, dst string, mode os.FileMode) error {
+	return copyFile(src, b.Path(dst), mode)
+}
+
+// CopyDir recursively copies the contents of the directory src into the root filesystem at dst
+func (b *Bundle) CopyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := b.Path(filepath.Join(dst, rel))
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+// WriteFile writes data to the root filesystem path p
+func (b *Bundle) WriteFile(p string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(b.Path(p)), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(b.Path(p), data, mode)
+}
+
+// Symlink creates link in the root filesystem pointing at target, replacing any existing link
+func (b *Bundle) Symlink(target, link string) error {
+	path := b.Path(link)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(target, path)
+}
+
+// Remove removes the root filesystem paths matching the supplied patterns
+func (b *Bundle) Remove(patterns ...string) error {
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(b.Path(pattern))
+		if err != nil {
+			return err
+		}
+
+		for _, m := range matches {
+			if err = os.RemoveAll(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Chown recursively changes the ownership of the root filesystem path p
+func (b *Bundle) Chown(p string, uid, gid int) error {
+	return filepath.Walk(b.Path(p), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// Chroot runs the command within the root filesystem
+func (b *Bundle) Chroot(name string, args ...string) error {
+	return run("", "chroot", append([]string{b.RootFS(), name}, args...)...)
+}
+
+// InstallUnit copies the systemd unit file src into the root filesystem
+func (b *Bundle) InstallUnit(src string) error {
+	return b.Copy(src, filepath.Join(systemdDir, filepath.Base(src)), 0644)
+}
+
+// EnableUnit makes the installed systemd unit a dependency of target
+func (b *Bundle) EnableUnit(unit, target string) error {
+	return b.Symlink(filepath.Join(systemdDir, unit), filepath.Join(systemdDir, target+".wants", unit))
+}
+
+// SetDefaultTarget selects the systemd target started at boot
+func (b *Bundle) SetDefaultTarget(target string) error {
+	return b.Symlink(filepath.Join(systemdDir, target), filepath.Join(systemdDir, "default.target"))
+}
+
+// isolinuxConfig returns the path of the isolinux configuration
+func (b *Bundle) isolinuxConfig() string {
+	return filepath.Join(b.BootFS(), isolinuxDir, "isolinux.cfg")
+}
+
+// editIsolinuxConfig replaces matches of re in the isolinux configuration with repl
+func (b *Bundle) editIsolinuxConfig(re *regexp.Regexp, repl string) error {
+	cfg := b.isolinuxConfig()
+
+	data, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return err
+	}
+
+	if !re.Match(data) {
+		return fmt.Errorf("no kernel command line found in %s", cfg)
+	}
+
+	return ioutil.WriteFile(cfg, re.ReplaceAll(data, []byte(repl)), 0644)
+}
+
+// Generate writes the ISO image to out, booting init from the root filesystem
+func (b *Bundle) Generate(out, init string) error {
+	defer trace.End(trace.Begin(out))
+
+	for _, tool := range []string{"cpio", "xorriso"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("cpio and xorriso must be installed for ISO authoring: %s", err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(b.BootFS(), isolinuxDir, "isolinux.bin")); err != nil {
+		return fmt.Errorf("isolinux files must exist in %s: %s", filepath.Join(b.BootFS(), isolinuxDir), err)
+	}
+
+	if info, err := os.Stat(b.Path(init)); err != nil || info.Mode()&0111 == 0 {
+		return fmt.Errorf("specified init (%s) does not exist or is not executable", init)
+	}
+
+	if err := b.editIsolinuxConfig(rdinitPlaceholder, "${1}="+init); err != nil {
+		return fmt.Errorf("unable to update rdinit entry in isolinux.cfg: %s", err)
+	}
+
+	log.Infof("Constructing initramfs archive")
+	if err := b.initramfs(filepath.Join(b.BootFS(), "boot", "core.gz")); err != nil {
+		return fmt.Errorf("failed to package root filesystem from %s: %s", b.RootFS(), err)
+	}
+
+	out, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+
+	// deleting the file first seems to be necessary in some cases
+	if err = os.Remove(out); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	log.Infof("Generating %s", out)
+	if err = run(b.Dir, "xorriso", "-dev", out, "-options_from_file", xorrisoOptions); err != nil {
+		return fmt.Errorf("failed to generate ISO file from package: %s", err)
+	}
+
+	return nil
+}
+
+// initramfs writes the root filesystem to out as a gzipped cpio archive
+func (b *Bundle) initramfs(out string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewWriterLevel(f, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+
+	find := exec.Command("find")
+	find.Dir = b.RootFS()
+
+	cpio := exec.Command("cpio", "-o", "-H", "newc")
+	cpio.Dir = b.RootFS()
+	cpio.Stdout = gz
+	cpio.Stderr = ioutil.Discard
+
+	if cpio.Stdin, err = find.StdoutPipe(); err != nil {
+		return err
+	}
+
+	if err = cpio.Start(); err != nil {
+		return err
+	}
+	if err = find.Run(); err != nil {
+		return err
+	}
+	if err = cpio.Wait(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err = io.Copy(out, in); err != nil {
+		return err
+	}
+
+	// the mode is subject to umask on creation
+	return out.Chmod(mode)
+}
+
+func run(dir, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Errorf("%s failed: %s", name, err)
+		log.Errorf("%s output: %s", name, string(output))
+		return err
+	}
+	return nil
+}
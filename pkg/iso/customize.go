@@ -0,0 +1,183 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iso
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/vic/pkg/trace"
+)
+
+const (
+	// CABundle is the system certificate authority bundle in the root filesystem
+	CABundle = "/etc/pki/tls/certs/ca-bundle.crt"
+
+	// PluginDir is where plugin binaries are installed in the root filesystem
+	PluginDir = "/usr/lib/vic/plugins"
+)
+
+// kernelCommandLine matches the kernel command line in isolinux.cfg, whether or not the init has been set
+var kernelCommandLine = regexp.MustCompile(`(?m)^(#?\s*append rdinit=.*)$`)
+
+var pluginUnit = template.Must(template.New("unit").Parse(`[Unit]
+Description=VIC plugin {{.Name}}
+After=basic.target
+
+[Service]
+ExecStart={{.Exec}}
+Restart=on-failure
+
+[Install]
+WantedBy=vic.target
+`))
+
+// Plugin is an additional binary started alongside the main components of an image
+type Plugin struct {
+	// Name of the plugin, used for the binary and unit names
+	Name string
+	// Path of the binary on the build host
+	Path string
+	// Args are passed to the binary when started
+	Args []string
+}
+
+// Customization holds downstream modifications applied to an image in addition to the standard content
+type Customization struct {
+	// KernelParams are appended to the kernel command line
+	KernelParams []string
+	// CACerts are PEM encoded certificate authorities added to the system trust store
+	CACerts [][]byte
+	// Plugins are installed and started at boot
+	Plugins []Plugin
+}
+
+// Customize applies the customizations to the bundle
+func (b *Bundle) Customize(c *Customization) error {
+	defer trace.End(trace.Begin(""))
+
+	if c == nil {
+		return nil
+	}
+
+	if len(c.KernelParams) > 0 {
+		if err := b.AddKernelParams(c.KernelParams...); err != nil {
+			return err
+		}
+	}
+
+	if len(c.CACerts) > 0 {
+		if err := b.AddCACerts(c.CACerts...); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range c.Plugins {
+		if err := b.AddPlugin(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddKernelParams appends params to the kernel command line
+func (b *Bundle) AddKernelParams(params ...string) error {
+	log.Infof("Adding kernel parameters: %s", strings.Join(params, " "))
+
+	// escape $ so the params aren't treated as references to submatches
+	repl := "${1} " + strings.Replace(strings.Join(params, " "), "$", "$$", -1)
+	if err := b.editIsolinuxConfig(kernelCommandLine, repl); err != nil {
+		return fmt.Errorf("unable to add kernel parameters: %s", err)
+	}
+	return nil
+}
+
+// AddCACerts appends the PEM encoded certificate authorities to the system trust store
+func (b *Bundle) AddCACerts(certs ...[]byte) error {
+	var bundle bytes.Buffer
+
+	for i, c := range certs {
+		rest := c
+		found := false
+		for {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				return fmt.Errorf("CA certificate %d contains unexpected PEM block of type %q", i, block.Type)
+			}
+
+			pem.Encode(&bundle, block)
+			found = true
+		}
+
+		if !found {
+			return fmt.Errorf("CA certificate %d contains no PEM encoded certificates", i)
+		}
+	}
+
+	log.Infof("Adding %d certificate authorities to %s", len(certs), CABundle)
+
+	f, err := os.OpenFile(b.Path(CABundle), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open CA bundle: %s", err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(bundle.Bytes()); err != nil {
+		return fmt.Errorf("unable to add certificate authorities: %s", err)
+	}
+	return nil
+}
+
+// AddPlugin installs the plugin binary along with a systemd unit that starts it with the image
+func (b *Bundle) AddPlugin(p Plugin) error {
+	if p.Name == "" || strings.ContainsAny(p.Name, "/ ") {
+		return fmt.Errorf("invalid plugin name %q", p.Name)
+	}
+
+	log.Infof("Adding plugin %s from %s", p.Name, p.Path)
+
+	bin := filepath.Join(PluginDir, p.Name)
+	if err := b.Copy(p.Path, bin, 0755); err != nil {
+		return fmt.Errorf("unable to install plugin %s: %s", p.Name, err)
+	}
+
+	var unit bytes.Buffer
+	err := pluginUnit.Execute(&unit, struct{ Name, Exec string }{
+		Name: p.Name,
+		Exec: strings.Join(append([]string{bin}, p.Args...), " "),
+	})
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("vic-plugin-%s.service", p.Name)
+	if err = b.WriteFile(filepath.Join(systemdDir, name), unit.Bytes(), 0644); err != nil {
+		return fmt.Errorf("unable to install unit for plugin %s: %s", p.Name, err)
+	}
+
+	return b.EnableUnit(name, "vic.target")
+}
@@ -0,0 +1,144 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iso
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const isolinuxCfg = `label microcore
+	kernel /boot/vmlinuz64 com1=115200,8n1
+	initrd /boot/core.gz
+# 	append rdinit=_INIT_BINARY_ loglevel=3 quiet
+implicit 0
+`
+
+const testCert = `-----BEGIN CERTIFICATE-----
+MIIBhTCCASugAwIBAgIQIRi6zePL6mKjOipn+dNuaTAKBggqhkjOPQQDAjASMRAw
+DgYDVQQKEwdBY21lIENvMB4XDTE3MTAyMDE5NDMwNloXDTE4MTAyMDE5NDMwNlow
+EjEQMA4GA1UEChMHQWNtZSBDbzBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABD0d
+7VNhbWvZLWPuj/RtHFjvtJBEwOkhbN/BnnE8rnZR8+sbwnc/KhCk3FhnpHZnQz7B
+5aETbbIgmuvewdjvSBSjYTBfMA4GA1UdDwEB/wQEAwICpDATBgNVHSUEDDAKBggr
+BgEFBQcDATAPBgNVHRMBAf8EBTADAQH/MCcGA1UdEQQgMB6CC2V4YW1wbGUuY29t
+hwR/AAABhxAAAAAAAAAAAAAAAAAAAAABMAoGCCqGSM49BAMCA0gAMEUCIEO/1qZb
+Ad/xkSQcOypy63TrWoIUx7Vs46RMxudGPjWEAiEAygbSUKcmYW6M6Od3DuYJjxbm
+cmLZWfyY1RD+w6vHzbY=
+-----END CERTIFICATE-----
+`
+
+func testBundle(t *testing.T) *Bundle {
+	dir, err := ioutil.TempDir("", "iso-test")
+	require.NoError(t, err)
+
+	b := &Bundle{Dir: dir}
+	require.NoError(t, os.MkdirAll(filepath.Join(b.BootFS(), isolinuxDir), 0755))
+	require.NoError(t, os.MkdirAll(b.RootFS(), 0755))
+	require.NoError(t, ioutil.WriteFile(b.isolinuxConfig(), []byte(isolinuxCfg), 0644))
+
+	return b
+}
+
+func TestAddKernelParams(t *testing.T) {
+	b := testBundle(t)
+	defer os.RemoveAll(b.Dir)
+
+	assert.NoError(t, b.AddKernelParams("vic.debug=1", "price=$1"))
+
+	// the init placeholder must still be set correctly after params are added
+	assert.NoError(t, b.editIsolinuxConfig(rdinitPlaceholder, "${1}="+Init))
+
+	data, err := ioutil.ReadFile(b.isolinuxConfig())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), " 	append rdinit=/lib/systemd/systemd loglevel=3 quiet vic.debug=1 price=$1\n")
+	assert.Contains(t, string(data), "implicit 0")
+}
+
+func TestAddKernelParamsNoCommandLine(t *testing.T) {
+	b := testBundle(t)
+	defer os.RemoveAll(b.Dir)
+
+	require.NoError(t, ioutil.WriteFile(b.isolinuxConfig(), []byte("label microcore\n"), 0644))
+	assert.Error(t, b.AddKernelParams("vic.debug=1"))
+}
+
+func TestAddCACerts(t *testing.T) {
+	b := testBundle(t)
+	defer os.RemoveAll(b.Dir)
+
+	require.NoError(t, b.WriteFile(CABundle, []byte(testCert), 0644))
+	assert.NoError(t, b.AddCACerts([]byte(testCert)))
+
+	data, err := ioutil.ReadFile(b.Path(CABundle))
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "BEGIN CERTIFICATE"))
+
+	// invalid content must not modify the bundle
+	assert.Error(t, b.AddCACerts([]byte("not a certificate")))
+	assert.Error(t, b.AddCACerts([]byte(strings.Replace(testCert, "CERTIFICATE", "RSA PRIVATE KEY", -1))))
+
+	after, err := ioutil.ReadFile(b.Path(CABundle))
+	require.NoError(t, err)
+	assert.Equal(t, data, after)
+}
+
+func TestAddPlugin(t *testing.T) {
+	b := testBundle(t)
+	defer os.RemoveAll(b.Dir)
+
+	src := filepath.Join(b.Dir, "plugin")
+	require.NoError(t, ioutil.WriteFile(src, []byte("#!/bin/sh\n"), 0600))
+
+	assert.NoError(t, b.AddPlugin(Plugin{Name: "audit", Path: src, Args: []string{"-v"}}))
+
+	info, err := os.Stat(b.Path(filepath.Join(PluginDir, "audit")))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	unit, err := ioutil.ReadFile(b.Path("/etc/systemd/system/vic-plugin-audit.service"))
+	require.NoError(t, err)
+	assert.Contains(t, string(unit), "ExecStart=/usr/lib/vic/plugins/audit -v\n")
+
+	link, err := os.Readlink(b.Path("/etc/systemd/system/vic.target.wants/vic-plugin-audit.service"))
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/systemd/system/vic-plugin-audit.service", link)
+
+	assert.Error(t, b.AddPlugin(Plugin{Name: "../audit", Path: src}))
+	assert.Error(t, b.AddPlugin(Plugin{Name: "missing", Path: filepath.Join(b.Dir, "missing")}))
+}
+
+func TestEnableUnit(t *testing.T) {
+	b := testBundle(t)
+	defer os.RemoveAll(b.Dir)
+
+	assert.NoError(t, b.SetDefaultTarget("multi-user.target"))
+	// replacing an existing link must succeed
+	assert.NoError(t, b.SetDefaultTarget("vic.target"))
+
+	link, err := os.Readlink(b.Path("/etc/systemd/system/default.target"))
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/systemd/system/vic.target", link)
+
+	assert.NoError(t, b.EnableUnit("tether.service", "vic.target"))
+	link, err = os.Readlink(b.Path("/etc/systemd/system/vic.target.wants/tether.service"))
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/systemd/system/tether.service", link)
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iso
+
+import (
+	"path/filepath"
+
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// Init is the init binary used by both images
+const Init = "/lib/systemd/systemd"
+
+// Sources identifies the inputs used to author an image
+type Sources struct {
+	// Assets is the directory holding the image content from the repository, i.e. isos/
+	Assets string
+	// Bin is the directory holding the built components
+	Bin string
+}
+
+func (s *Sources) asset(p ...string) string {
+	return filepath.Join(append([]string{s.Assets}, p...)...)
+}
+
+func (s *Sources) bin(name string) string {
+	return filepath.Join(s.Bin, name)
+}
+
+// disableNetworkd stops systemd managing links or running a dhcp client, as we manage the link state directly
+func (b *Bundle) disableNetworkd(src *Sources) error {
+	err := b.Remove(
+		"/etc/systemd/system/multi-user.target.wants/systemd-networkd.service",
+		"/etc/systemd/system/sockets.target.wants/systemd-networkd.socket",
+		"/etc/systemd/network/*",
+	)
+	if err != nil {
+		return err
+	}
+
+	return b.Copy(src.asset("base", "no-dhcp.network"), "/etc/systemd/network/no-dhcp.network", 0644)
+}
+
+// Bootstrap authors the bootstrap image booted by container VMs. The debug image includes
+// an interactive bootstrap and rpctool.
+func Bootstrap(b *Bundle, src *Sources, debug bool) error {
+	defer trace.End(trace.Begin(b.Dir))
+
+	// selecting the init script as our entry point
+	bootstrap := "bootstrap"
+	if debug {
+		bootstrap = "bootstrap.debug"
+		if err := b.Copy(src.bin("rpctool"), "/sbin/rpctool", 0755); err != nil {
+			return err
+		}
+	}
+	if err := b.Copy(src.asset("bootstrap", bootstrap), "/bin/bootstrap", 0755); err != nil {
+		return err
+	}
+
+	// copy in our components
+	if err := b.Copy(src.bin("tether-linux"), "/bin/tether", 0755); err != nil {
+		return err
+	}
+
+	// kick off our components at boot time
+	for _, unit := range []string{src.asset("bootstrap", "tether.service"), src.asset("appliance", "vic.target")} {
+		if err := b.InstallUnit(unit); err != nil {
+			return err
+		}
+	}
+	if err := b.EnableUnit("tether.service", "vic.target"); err != nil {
+		return err
+	}
+	if err := b.SetDefaultTarget("vic.target"); err != nil {
+		return err
+	}
+
+	return b.disableNetworkd(src)
+}
+
+// Appliance authors the image booted by the VCH appliance
+func Appliance(b *Bundle, src *Sources) error {
+	defer trace.End(trace.Begin(b.Dir))
+
+	// systemd configuration
+	units, err := filepath.Glob(src.asset("appliance", "*.service"))
+	if err != nil {
+		return err
+	}
+	for _, unit := range append(units, src.asset("appliance", "vic.target")) {
+		if err = b.InstallUnit(unit); err != nil {
+			return err
+		}
+	}
+
+	scripts, err := filepath.Glob(src.asset("appliance", "*-setup"))
+	if err != nil {
+		return err
+	}
+	for _, script := range scripts {
+		if err = b.Copy(script, filepath.Join("/etc/systemd/scripts", filepath.Base(script)), 0755); err != nil {
+			return err
+		}
+	}
+
+	for _, unit := range []string{"vic-init.service", "nat.service", "permissions.service", "multi-user.target"} {
+		if err = b.EnableUnit(unit, "vic.target"); err != nil {
+			return err
+		}
+	}
+
+	if err = b.disableNetworkd(src); err != nil {
+		return err
+	}
+
+	// disable time synching - we use toolbox for this
+	if err = b.Remove("/etc/systemd/system/sysinit.target.wants/systemd-timesyncd.service"); err != nil {
+		return err
+	}
+
+	// change the default systemd target to launch VIC
+	if err = b.SetDefaultTarget("vic.target"); err != nil {
+		return err
+	}
+
+	// set up component users
+	if err = b.Chroot("groupadd", "-g", "1000", "vicadmin"); err != nil {
+		return err
+	}
+	if err = b.Chroot("useradd", "-u", "1000", "-g", "1000", "-G", "systemd-journal", "-m", "-d", "/home/vicadmin", "-s", "/bin/false", "vicadmin"); err != nil {
+		return err
+	}
+	if err = b.CopyDir(src.asset("vicadmin"), "/home/vicadmin"); err != nil {
+		return err
+	}
+	if err = b.Chown("/home/vicadmin", 1000, 1000); err != nil {
+		return err
+	}
+
+	// so vicadmin can read the system journal via journalctl
+	tmpfiles := []byte("m  /var/log/journal/%m/system.journal 2755 root systemd-journal - -\n")
+	if err = b.WriteFile("/etc/tmpfiles.d/systemd.conf", tmpfiles, 0644); err != nil {
+		return err
+	}
+
+	// main VIC components
+	for _, component := range []string{"vic-init", "docker-engine-server", "port-layer-server", "vicadmin"} {
+		if err = b.Copy(src.bin(component), filepath.Join("/sbin", component), 0755); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,322 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ldap implements the part of the LDAP protocol (RFC 4511) needed to check user
+// credentials, a simple bind, against an LDAP or Active Directory server.
+package ldap
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Scheme is the URL scheme of plain LDAP servers
+	Scheme = "ldap"
+	// SecureScheme is the URL scheme of LDAP over TLS servers
+	SecureScheme = "ldaps"
+
+	defaultPort       = "389"
+	defaultSecurePort = "636"
+
+	// ResultSuccess is the result code of a successful operation
+	ResultSuccess = 0
+	// ResultInvalidCredentials is the result code of a bind with the wrong DN or password
+	ResultInvalidCredentials = 49
+
+	protocolVersion = 3
+
+	// maxResponseSize bounds the responses read from the server
+	maxResponseSize = 1 << 20
+)
+
+// ErrEmptyPassword is returned for binds without a password, which servers treat as anonymous
+// binds that succeed whatever the DN
+var ErrEmptyPassword = errors.New("password is empty")
+
+// ResultError is the result of an operation that was not successful
+type ResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap result code %d: %s", e.Code, e.Message)
+}
+
+// IsInvalidCredentials returns true if err is the result of a bind with the wrong DN or password
+func IsInvalidCredentials(err error) bool {
+	re, ok := err.(*ResultError)
+	return ok && re.Code == ResultInvalidCredentials
+}
+
+// Validate checks that u is an ldap:// or ldaps:// URL with a host
+func Validate(u *url.URL) error {
+	if u.Scheme != Scheme && u.Scheme != SecureScheme {
+		return fmt.Errorf("%s is not an %s:// or %s:// url", u, Scheme, SecureScheme)
+	}
+	if u.Host == "" {
+		return errors.New("the LDAP server is missing")
+	}
+	return nil
+}
+
+// Conn is a connection to an LDAP server
+type Conn struct {
+	conn      net.Conn
+	messageID int
+}
+
+// Dial connects to the server at u, using config for ldaps:// servers
+func Dial(u *url.URL, config *tls.Config, timeout time.Duration) (*Conn, error) {
+	if err := Validate(u); err != nil {
+		return nil, err
+	}
+
+	addr := u.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := defaultPort
+		if u.Scheme == SecureScheme {
+			port = defaultSecurePort
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if u.Scheme == SecureScheme {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, config)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	return &Conn{conn: conn}, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// bindRequest is the BindRequest of RFC 4511 4.2, with simple authentication
+type bindRequest struct {
+	Version  int
+	Name     []byte
+	Password []byte `asn1:"tag:0"`
+}
+
+// bindMessage is an LDAPMessage holding a BindRequest
+type bindMessage struct {
+	MessageID int
+	Request   bindRequest `asn1:"application,tag:0"`
+}
+
+// Bind authenticates the connection as dn with password. A *ResultError is returned if the
+// server refuses the bind.
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+
+	c.messageID++
+	req, err := asn1.Marshal(bindMessage{
+		MessageID: c.messageID,
+		Request: bindRequest{
+			Version:  protocolVersion,
+			Name:     []byte(dn),
+			Password: []byte(password),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.conn.Write(req); err != nil {
+		return err
+	}
+
+	res, err := readElement(c.conn)
+	if err != nil {
+		return err
+	}
+
+	return parseBindResponse(res, c.messageID)
+}
+
+// element is a BER encoded type-length-value
+type element struct {
+	class    int
+	tag      int
+	compound bool
+	content  []byte
+}
+
+// readElement reads a single BER element from r
+func readElement(r io.Reader) (*element, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return nil, fmt.Errorf("unsupported ldap message length encoding 0x%x", header[1])
+		}
+
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+
+		length = 0
+		for _, v := range b {
+			length = length<<8 | int(v)
+		}
+	}
+
+	if length > maxResponseSize {
+		return nil, fmt.Errorf("ldap message of %d bytes is too large", length)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+
+	return &element{
+		class:    int(header[0] >> 6),
+		tag:      int(header[0] & 0x1f),
+		compound: header[0]&0x20 != 0,
+		content:  content,
+	}, nil
+}
+
+// children parses the content of a constructed element into its elements. Servers may use
+// any valid BER length encoding, e.g. Active Directory always uses the long form, which the
+// DER only encoding/asn1 rejects.
+func (e *element) children() ([]*element, error) {
+	if !e.compound {
+		return nil, errors.New("ldap message element is not constructed")
+	}
+
+	var children []*element
+	r := bytes.NewReader(e.content)
+	for r.Len() > 0 {
+		child, err := readElement(r)
+		if err != nil {
+			return nil, fmt.Errorf("malformed ldap message: %s", err)
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// integer returns the value of an INTEGER or ENUMERATED element
+func (e *element) integer() (int, error) {
+	if len(e.content) == 0 || len(e.content) > 4 {
+		return 0, errors.New("malformed ldap integer")
+	}
+
+	// sign extend from the first byte
+	v := int(int8(e.content[0]))
+	for _, b := range e.content[1:] {
+		v = v<<8 | int(b)
+	}
+	return v, nil
+}
+
+// parseBindResponse returns the error of the BindResponse held by the LDAPMessage msg, if any
+func parseBindResponse(msg *element, messageID int) error {
+	fields, err := msg.children()
+	if err != nil {
+		return err
+	}
+
+	if len(fields) < 2 {
+		return errors.New("malformed ldap message")
+	}
+
+	id, err := fields[0].integer()
+	if err != nil {
+		return err
+	}
+	if id != messageID {
+		return fmt.Errorf("ldap response to message %d received for message %d", id, messageID)
+	}
+
+	op := fields[1]
+	if op.class != asn1.ClassApplication || op.tag != 1 {
+		return fmt.Errorf("unexpected ldap response [APPLICATION %d] to bind request", op.tag)
+	}
+
+	result, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(result) < 3 {
+		return errors.New("malformed ldap bind response")
+	}
+
+	code, err := result[0].integer()
+	if err != nil {
+		return err
+	}
+
+	if code != ResultSuccess {
+		return &ResultError{Code: code, Message: string(result[2].content)}
+	}
+	return nil
+}
+
+// EscapeDN escapes the special characters of s, so that it can be used as an attribute value of
+// a distinguished name (RFC 4514 2.4)
+func EscapeDN(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.IndexByte(`,+"\<>;=`, c) >= 0,
+			c == '#' && i == 0,
+			c == ' ' && (i == 0 || i == len(s)-1):
+			b = append(b, '\\', c)
+		case c == 0:
+			b = append(b, `\00`...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
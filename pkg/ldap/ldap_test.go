@@ -0,0 +1,110 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"encoding/asn1"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// longForm encodes an element with a four byte length, as Active Directory does
+func longForm(tag byte, content []byte) []byte {
+	n := len(content)
+	return append([]byte{tag, 0x84, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}, content...)
+}
+
+// bindResponse encodes the response to message id with the given result code and message
+func bindResponse(id int, code int, message string) []byte {
+	var result []byte
+	result = append(result, 0x0a, 0x01, byte(code))
+	result = append(result, longForm(0x04, nil)...)
+	result = append(result, longForm(0x04, []byte(message))...)
+
+	msg := []byte{0x02, 0x01, byte(id)}
+	msg = append(msg, longForm(0x61, result)...)
+	return longForm(0x30, msg)
+}
+
+// serve accepts a connection and answers its bind requests, accepting only dn with password
+func serve(t *testing.T, l net.Listener, dn, password string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		req, err := readElement(conn)
+		if err != nil {
+			return
+		}
+
+		var msg bindMessage
+		raw, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: req.content})
+		if _, err = asn1.Unmarshal(raw, &msg); !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, protocolVersion, msg.Request.Version)
+
+		code, message := ResultSuccess, ""
+		if string(msg.Request.Name) != dn || string(msg.Request.Password) != password {
+			code, message = ResultInvalidCredentials, "80090308: LdapErr: DSID-0C09042A, comment: AcceptSecurityContext error, data 52e, v3839"
+		}
+
+		if _, err = conn.Write(bindResponse(msg.MessageID, code, message)); err != nil {
+			return
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go serve(t, l, "CN=Jane Doe,OU=Users,DC=example,DC=com", "secret")
+
+	conn, err := Dial(&url.URL{Scheme: Scheme, Host: l.Addr().String()}, nil, 5*time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, ErrEmptyPassword, conn.Bind("CN=Jane Doe,OU=Users,DC=example,DC=com", ""))
+
+	err = conn.Bind("CN=Jane Doe,OU=Users,DC=example,DC=com", "wrong")
+	assert.True(t, IsInvalidCredentials(err), "unexpected error: %s", err)
+	assert.Contains(t, err.Error(), "data 52e")
+
+	assert.NoError(t, conn.Bind("CN=Jane Doe,OU=Users,DC=example,DC=com", "secret"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(&url.URL{Scheme: "ldaps", Host: "dc.example.com"}))
+	assert.NoError(t, Validate(&url.URL{Scheme: "ldap", Host: "10.0.0.5:3389"}))
+	assert.Error(t, Validate(&url.URL{Scheme: "https", Host: "dc.example.com"}))
+	assert.Error(t, Validate(&url.URL{Scheme: "ldaps"}))
+}
+
+func TestEscapeDN(t *testing.T) {
+	assert.Equal(t, "jdoe", EscapeDN("jdoe"))
+	assert.Equal(t, `Doe\, Jane`, EscapeDN("Doe, Jane"))
+	assert.Equal(t, `\#admin\=x\+y`, EscapeDN("#admin=x+y"))
+	assert.Equal(t, `\ padded\ `, EscapeDN(" padded "))
+}
@@ -0,0 +1,128 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nfs holds the handling of NFS export URLs shared by the port layer, which creates volumes
+// on NFS exports, and the tether, which mounts them in containerVMs.
+package nfs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Scheme is the URL scheme of NFS exports, e.g. nfs://fileserver/export/volumes
+const Scheme = "nfs"
+
+// defaultOptions are the mount options used unless the URL gives them. NFSv3 is used as the kernel
+// client mounts it without a helper, and locks are local as containerVMs run no statd.
+var defaultOptions = map[string]string{
+	"vers":   "3",
+	"nolock": "",
+}
+
+// IsExport returns whether u refers to an NFS export
+func IsExport(u *url.URL) bool {
+	return u != nil && u.Scheme == Scheme
+}
+
+// Validate checks that u is an NFS export URL with a host and an absolute export path
+func Validate(u *url.URL) error {
+	if !IsExport(u) {
+		return fmt.Errorf("%s is not an %s:// url", u, Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("the NFS server is missing")
+	}
+	if !path.IsAbs(u.Path) {
+		return errors.New("the export path must be absolute")
+	}
+	return nil
+}
+
+// MountArgs returns the source and data arguments of mount(2) for the NFS export at u. The kernel
+// client is used directly, without the mount helper, so the server is resolved to an address here.
+// Query parameters of u are passed as mount options, e.g. nfs://fileserver/export?vers=4.1
+func MountArgs(u *url.URL, readOnly bool) (string, string, error) {
+	if err := Validate(u); err != nil {
+		return "", "", err
+	}
+
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+		port = ""
+	}
+
+	addr := net.ParseIP(host)
+	if addr == nil {
+		addrs, err := net.LookupIP(host)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to resolve NFS server %s: %s", host, err)
+		}
+		addr = addrs[0]
+	}
+
+	opts := make(map[string]string)
+	for k, v := range defaultOptions {
+		opts[k] = v
+	}
+	for k, v := range u.Query() {
+		opts[k] = ""
+		if len(v) > 0 {
+			opts[k] = v[0]
+		}
+	}
+	// locking needs NFSv4, which has it in the protocol
+	if opts["vers"] != "3" {
+		delete(opts, "nolock")
+	}
+	opts["addr"] = addr.String()
+	if port != "" {
+		opts["port"] = port
+	}
+	if readOnly {
+		opts["ro"] = ""
+	}
+
+	return fmt.Sprintf("%s:%s", host, path.Clean(u.Path)), formatOptions(opts), nil
+}
+
+// formatOptions joins the mount options in a stable order
+func formatOptions(opts map[string]string) string {
+	var o []string
+	for k, v := range opts {
+		if v == "" {
+			o = append(o, k)
+			continue
+		}
+		o = append(o, k+"="+v)
+	}
+	sort.Strings(o)
+	return strings.Join(o, ",")
+}
+
+// ReadOnly returns whether the freeform mount mode, e.g. "ro" or "rw,z", makes the mount read-only
+func ReadOnly(mode string) bool {
+	for _, m := range strings.Split(mode, ",") {
+		if strings.TrimSpace(m) == "ro" {
+			return true
+		}
+	}
+	return false
+}
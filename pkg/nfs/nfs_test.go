@@ -0,0 +1,61 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nfs
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMountArgs(t *testing.T) {
+	var tests = []struct {
+		url      string
+		readOnly bool
+		source   string
+		data     string
+	}{
+		{"nfs://10.0.0.5/export/vols", false, "10.0.0.5:/export/vols", "addr=10.0.0.5,nolock,vers=3"},
+		{"nfs://10.0.0.5:2050/export/vols/", true, "10.0.0.5:/export/vols", "addr=10.0.0.5,nolock,port=2050,ro,vers=3"},
+		{"nfs://10.0.0.5/export?vers=4.1&proto=tcp", false, "10.0.0.5:/export", "addr=10.0.0.5,proto=tcp,vers=4.1"},
+	}
+
+	for _, te := range tests {
+		u, err := url.Parse(te.url)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		source, data, err := MountArgs(u, te.readOnly)
+		if assert.NoError(t, err, te.url) {
+			assert.Equal(t, te.source, source, te.url)
+			assert.Equal(t, te.data, data, te.url)
+		}
+	}
+
+	for _, bad := range []string{"ds://datastore1/vols", "nfs:///export", "nfs://10.0.0.5"} {
+		u, _ := url.Parse(bad)
+		_, _, err := MountArgs(u, false)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	assert.True(t, ReadOnly("ro"))
+	assert.True(t, ReadOnly("z, ro"))
+	assert.False(t, ReadOnly("rw"))
+	assert.False(t, ReadOnly(""))
+}
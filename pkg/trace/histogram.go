@@ -0,0 +1,204 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricName is the name of the histogram family exported for sampled traces
+const MetricName = "vic_trace_duration_seconds"
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histograms.
+// They span from fast in-process calls to slow vSphere tasks and appliance boot.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Metrics is the registry that sampled Begin/End timings are recorded in
+var Metrics = NewRegistry(DefaultBuckets)
+
+// sampleInterval records every nth trace in Metrics, zero disables sampling
+var sampleInterval uint64
+
+// monotonic counter which increments on every sampling decision
+var sampleCount uint64
+
+// EnableSampling records the duration of every nth trace in Metrics,
+// regardless of the log level.
+func EnableSampling(n uint64) {
+	atomic.StoreUint64(&sampleInterval, n)
+}
+
+// DisableSampling stops recording trace durations.
+func DisableSampling() {
+	atomic.StoreUint64(&sampleInterval, 0)
+}
+
+// sample determines whether the trace being started should be recorded
+func sample() bool {
+	n := atomic.LoadUint64(&sampleInterval)
+	if n == 0 {
+		return false
+	}
+	return atomic.AddUint64(&sampleCount, 1)%n == 0
+}
+
+// Histogram counts observed durations in cumulative buckets
+type Histogram struct {
+	mu sync.Mutex
+
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+// Observe adds d to the histogram
+func (h *Histogram) Observe(d time.Duration) {
+	s := d.Seconds()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the bucket counts are not cumulative until written out
+	if i := sort.SearchFloat64s(h.bounds, s); i < len(h.bounds) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += s
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.count
+}
+
+// writeTo writes the histogram in the Prometheus text format with the given labels
+func (h *Histogram) writeTo(w io.Writer, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n", MetricName, labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", MetricName, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", MetricName, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", MetricName, labels, h.count)
+}
+
+// Registry holds a latency histogram per traced function
+type Registry struct {
+	mu sync.RWMutex
+
+	bounds     []float64
+	histograms map[string]*Histogram
+}
+
+// NewRegistry returns an empty registry whose histograms use the given bucket bounds
+func NewRegistry(bounds []float64) *Registry {
+	b := make([]float64, len(bounds))
+	copy(b, bounds)
+	sort.Float64s(b)
+
+	return &Registry{
+		bounds:     b,
+		histograms: make(map[string]*Histogram),
+	}
+}
+
+// Histogram returns the histogram for name, creating it if necessary
+func (r *Registry) Histogram(name string) *Histogram {
+	r.mu.RLock()
+	h, ok := r.histograms[name]
+	r.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if h, ok = r.histograms[name]; !ok {
+		h = newHistogram(r.bounds)
+		r.histograms[name] = h
+	}
+	return h
+}
+
+// Observe adds d to the histogram for name
+func (r *Registry) Observe(name string, d time.Duration) {
+	r.Histogram(name).Observe(d)
+}
+
+// Reset discards all histograms
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.histograms = make(map[string]*Histogram)
+}
+
+// Write writes all histograms in the Prometheus text exposition format, ordered by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.histograms))
+	for name := range r.histograms {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# HELP %s Latency of sampled traced function calls.\n", MetricName)
+	fmt.Fprintf(bw, "# TYPE %s histogram\n", MetricName)
+	for _, name := range names {
+		r.Histogram(name).writeTo(bw, fmt.Sprintf("func=\"%s\"", escapeLabel(name)))
+	}
+
+	return bw.Flush()
+}
+
+// ServeHTTP exports the registry for scraping by Prometheus
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.Write(w)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value as required by the Prometheus text format
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry([]float64{1, 0.1})
+
+	r.Observe("pkg.Fast", 50*time.Millisecond)
+	r.Observe("pkg.Fast", 500*time.Millisecond)
+	r.Observe("pkg.Slow", 2*time.Second)
+
+	buf := new(bytes.Buffer)
+	if !assert.NoError(t, r.Write(buf)) {
+		return
+	}
+
+	expected := []string{
+		"# HELP vic_trace_duration_seconds Latency of sampled traced function calls.",
+		"# TYPE vic_trace_duration_seconds histogram",
+		`vic_trace_duration_seconds_bucket{func="pkg.Fast",le="0.1"} 1`,
+		`vic_trace_duration_seconds_bucket{func="pkg.Fast",le="1"} 2`,
+		`vic_trace_duration_seconds_bucket{func="pkg.Fast",le="+Inf"} 2`,
+		`vic_trace_duration_seconds_sum{func="pkg.Fast"} 0.55`,
+		`vic_trace_duration_seconds_count{func="pkg.Fast"} 2`,
+		`vic_trace_duration_seconds_bucket{func="pkg.Slow",le="0.1"} 0`,
+		`vic_trace_duration_seconds_bucket{func="pkg.Slow",le="1"} 0`,
+		`vic_trace_duration_seconds_bucket{func="pkg.Slow",le="+Inf"} 1`,
+		`vic_trace_duration_seconds_sum{func="pkg.Slow"} 2`,
+		`vic_trace_duration_seconds_count{func="pkg.Slow"} 1`,
+		"",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), buf.String())
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, escapeLabel("a\\b\"c\nd"))
+}
+
+func TestSampling(t *testing.T) {
+	Logger.Level = logrus.InfoLevel
+	defer DisableSampling()
+	defer Metrics.Reset()
+
+	sampled := func() {
+		defer End(Begin(""))
+	}
+
+	// without sampling or debug logging no trace is started
+	DisableSampling()
+	assert.Nil(t, Begin(""))
+
+	EnableSampling(2)
+	for i := 0; i < 10; i++ {
+		sampled()
+	}
+
+	name := "github.com/vmware/vic/pkg/trace.TestSampling.func1"
+	assert.Equal(t, uint64(5), Metrics.Histogram(name).Count())
+}
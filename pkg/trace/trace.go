@@ -54,6 +54,9 @@ type Message struct {
 	lineNo   int
 
 	startTime time.Time
+
+	// whether the duration is recorded in Metrics
+	sampled bool
 }
 
 func (t *Message) delta() time.Duration {
@@ -80,20 +83,32 @@ func newTrace(msg string, skip int) *Message {
 	}
 }
 
+func debugEnabled() bool {
+	return tracingEnabled && Logger.Level >= logrus.DebugLevel
+}
+
 // Begin starts the trace.  Msg is the msg to log.
 func Begin(msg string) *Message {
-	if tracingEnabled && Logger.Level >= logrus.DebugLevel {
-		if t := newTrace(msg, 2); t != nil {
-			if msg == "" {
-				Logger.Debugf("[BEGIN] [%s:%d]", t.funcName, t.lineNo)
-			} else {
-				Logger.Debugf("[BEGIN] [%s:%d] %s", t.funcName, t.lineNo, t.msg)
-			}
-			return t
+	debug := debugEnabled()
+	sampled := sample()
+	if !debug && !sampled {
+		return nil
+	}
+
+	t := newTrace(msg, 2)
+	if t == nil {
+		return nil
+	}
+	t.sampled = sampled
 
+	if debug {
+		if msg == "" {
+			Logger.Debugf("[BEGIN] [%s:%d]", t.funcName, t.lineNo)
+		} else {
+			Logger.Debugf("[BEGIN] [%s:%d] %s", t.funcName, t.lineNo, t.msg)
 		}
 	}
-	return nil
+	return t
 }
 
 // End ends the trace.
@@ -101,5 +116,12 @@ func End(t *Message) {
 	if t == nil {
 		return
 	}
-	Logger.Debugf("[ END ] [%s:%d] [%s] %s", t.funcName, t.lineNo, t.delta(), t.msg)
+
+	delta := t.delta()
+	if t.sampled {
+		Metrics.Observe(t.funcName, delta)
+	}
+	if debugEnabled() {
+		Logger.Debugf("[ END ] [%s:%d] [%s] %s", t.funcName, t.lineNo, delta, t.msg)
+	}
 }
@@ -0,0 +1,152 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+const (
+	// ReleaseCheckInterval is how long a fetched release is reused before the metadata is fetched again
+	ReleaseCheckInterval = 6 * time.Hour
+	// releaseRetryInterval is how long a failed fetch is reported before it is retried
+	releaseRetryInterval = time.Minute
+)
+
+// Release is the metadata published for the latest VIC release, as a JSON document of the form
+//
+//	{"version": "v1.1.0", "build_number": "9852", "git_commit": "7b7d7ba", "url": "https://..."}
+type Release struct {
+	Version     string `json:"version"`
+	BuildNumber string `json:"build_number"`
+	GitCommit   string `json:"git_commit,omitempty"`
+	// URL of the release downloads or notes, if published
+	URL string `json:"url,omitempty"`
+}
+
+// Build returns the release as a build for comparison with the running version
+func (r *Release) Build() *Build {
+	return &Build{
+		Version:     r.Version,
+		BuildNumber: r.BuildNumber,
+		GitCommit:   r.GitCommit,
+	}
+}
+
+// FetchRelease retrieves the release metadata published at u
+func FetchRelease(ctx context.Context, client *http.Client, u *url.URL) (*Release, error) {
+	res, err := ctxhttp.Get(ctx, client, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release metadata at %s returned %s", u, res.Status)
+	}
+
+	r := &Release{}
+	if err := json.NewDecoder(res.Body).Decode(r); err != nil {
+		return nil, fmt.Errorf("invalid release metadata at %s: %s", u, err)
+	}
+	if r.BuildNumber == "" {
+		return nil, fmt.Errorf("invalid release metadata at %s: no build number", u)
+	}
+	return r, nil
+}
+
+// ReleaseChecker reports whether newer releases are available, reusing the last fetched release
+// so frequent callers do not fetch the metadata on every request. The zero value is ready to use.
+type ReleaseChecker struct {
+	// Client used to fetch the metadata, http.DefaultClient if nil
+	Client *http.Client
+	// Interval a fetched release is reused for, ReleaseCheckInterval if zero
+	Interval time.Duration
+
+	m       sync.Mutex
+	url     string
+	expires time.Time
+	release *Release
+	err     error
+}
+
+// Latest returns the release published at u, fetching it again once the previous result has expired
+func (c *ReleaseChecker) Latest(ctx context.Context, u *url.URL) (*Release, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.url == u.String() && time.Now().Before(c.expires) {
+		return c.release, c.err
+	}
+
+	c.url = u.String()
+	c.release, c.err = FetchRelease(ctx, c.Client, u)
+
+	interval := c.Interval
+	if interval == 0 {
+		interval = ReleaseCheckInterval
+	}
+	if c.err != nil {
+		interval = releaseRetryInterval
+	}
+	c.expires = time.Now().Add(interval)
+
+	return c.release, c.err
+}
+
+// Upgrade returns the release published at u if it is newer than current, or nil if current is up to date
+func (c *ReleaseChecker) Upgrade(ctx context.Context, u *url.URL, current *Build) (*Release, error) {
+	r, err := c.Latest(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	older, err := current.IsOlder(r.Build())
+	if err != nil {
+		return nil, err
+	}
+	if !older {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// Status generates a user facing string reporting whether the release published at u is newer than current
+func (c *ReleaseChecker) Status(ctx context.Context, u *url.URL, current *Build) string {
+	r, err := c.Upgrade(ctx, u, current)
+	if err != nil {
+		return fmt.Sprintf("Unknown: %s", err)
+	}
+	if r == nil {
+		return "Up to date"
+	}
+	return r.String()
+}
+
+// String describes the release as an available upgrade
+func (r *Release) String() string {
+	if r.URL != "" {
+		return fmt.Sprintf("Upgrade available: %s (%s)", r.Build().ShortVersion(), r.URL)
+	}
+	return fmt.Sprintf("Upgrade available: %s", r.Build().ShortVersion())
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestReleaseChecker(t *testing.T) {
+	body := `{"version": "v1.2.4", "build_number": "11", "git_commit": "ccccccc", "url": "https://example.com/vic"}`
+	fetches := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fetches++
+		fmt.Fprint(w, body)
+	}))
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	ctx := context.Background()
+	c := &ReleaseChecker{}
+
+	assert.Equal(t, "Upgrade available: v1.2.4-11-ccccccc (https://example.com/vic)", c.Status(ctx, u, a))
+	assert.Equal(t, "Up to date", c.Status(ctx, u, d))
+	// the release is reused until the interval expires
+	assert.Equal(t, 1, fetches)
+
+	// an unparsable running build number cannot be compared
+	assert.True(t, strings.HasPrefix(c.Status(ctx, u, f), "Unknown: "))
+
+	body = `{"version": "v1.2.4"}`
+	_, err := FetchRelease(ctx, nil, u)
+	assert.Error(t, err, "metadata without a build number is invalid")
+
+	body = `not json`
+	_, err = FetchRelease(ctx, nil, u)
+	assert.Error(t, err)
+
+	missing, _ := url.Parse(s.URL + "/missing")
+	assert.True(t, strings.HasPrefix(c.Status(ctx, missing, a), "Unknown: "), "a different url is fetched again")
+}
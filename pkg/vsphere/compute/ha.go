@@ -0,0 +1,147 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// HASettings are the vSphere HA overrides of a VM. Empty settings keep the cluster defaults.
+type HASettings struct {
+	// RestartPriority is the order the VM is restarted in after a host failure
+	RestartPriority string
+	// IsolationResponse is the action taken when the host of the VM is isolated from the network
+	IsolationResponse string
+	// Monitoring is the sensitivity of VM monitoring, which resets the VM when its tools heartbeat stops
+	Monitoring string
+}
+
+// Values accepted for the HA settings
+var (
+	RestartPriorities  = []string{"disabled", "lowest", "low", "medium", "high", "highest"}
+	IsolationResponses = []string{
+		string(types.ClusterDasVmSettingsIsolationResponseNone),
+		string(types.ClusterDasVmSettingsIsolationResponsePowerOff),
+		string(types.ClusterDasVmSettingsIsolationResponseShutdown),
+	}
+)
+
+// monitoringSensitivity holds the VM monitoring presets of the vSphere client, keyed by sensitivity
+var monitoringSensitivity = map[string]types.ClusterVmToolsMonitoringSettings{
+	"low":    {FailureInterval: 120, MinUpTime: 480, MaxFailures: 3, MaxFailureWindow: 7 * 24 * 3600},
+	"medium": {FailureInterval: 60, MinUpTime: 240, MaxFailures: 3, MaxFailureWindow: 24 * 3600},
+	"high":   {FailureInterval: 30, MinUpTime: 120, MaxFailures: 3, MaxFailureWindow: 3600},
+}
+
+// MonitoringSensitivities returns the values accepted for the VM monitoring sensitivity
+func MonitoringSensitivities() []string {
+	values := []string{"disabled"}
+	for s := range monitoringSensitivity {
+		values = append(values, s)
+	}
+	sort.Strings(values[1:])
+	return values
+}
+
+// IsSet returns whether any of the settings override the cluster defaults
+func (s HASettings) IsSet() bool {
+	return s.RestartPriority != "" || s.IsolationResponse != "" || s.Monitoring != ""
+}
+
+// Validate checks that the settings hold accepted values
+func (s HASettings) Validate() error {
+	var bad []string
+	check := func(name, value string, accepted []string) {
+		if value == "" {
+			return
+		}
+		for _, a := range accepted {
+			if value == a {
+				return
+			}
+		}
+		bad = append(bad, fmt.Sprintf("%s %q must be one of %s", name, value, strings.Join(accepted, ", ")))
+	}
+
+	check("restart priority", s.RestartPriority, RestartPriorities)
+	check("isolation response", s.IsolationResponse, IsolationResponses)
+	check("monitoring sensitivity", s.Monitoring, MonitoringSensitivities())
+
+	if len(bad) > 0 {
+		return fmt.Errorf("invalid HA settings: %s", strings.Join(bad, "; "))
+	}
+	return nil
+}
+
+// HAEnabled returns whether vSphere HA is enabled on the cluster
+func HAEnabled(info *types.ClusterConfigInfoEx) bool {
+	return info.DasConfig.Enabled != nil && *info.DasConfig.Enabled
+}
+
+// VMMonitoringEnabled returns whether the cluster acts on the VM monitoring settings of its VMs
+func VMMonitoringEnabled(info *types.ClusterConfigInfoEx) bool {
+	return info.DasConfig.VmMonitoring != "" && info.DasConfig.VmMonitoring != string(types.ClusterDasConfigInfoVmMonitoringStateVmMonitoringDisabled)
+}
+
+// DasVMConfigSpec returns the spec applying the HA settings to the VM, editing its existing HA
+// overrides if it has any
+func DasVMConfigSpec(info *types.ClusterConfigInfoEx, vm types.ManagedObjectReference, s HASettings) types.ClusterDasVmConfigSpec {
+	op := types.ArrayUpdateOperationAdd
+	settings := &types.ClusterDasVmSettings{}
+	for _, c := range info.DasVmConfig {
+		if c.Key == vm {
+			op = types.ArrayUpdateOperationEdit
+			if c.DasSettings != nil {
+				existing := *c.DasSettings
+				settings = &existing
+			}
+			break
+		}
+	}
+
+	if s.RestartPriority != "" {
+		settings.RestartPriority = s.RestartPriority
+	}
+	if s.IsolationResponse != "" {
+		settings.IsolationResponse = s.IsolationResponse
+	}
+	switch s.Monitoring {
+	case "":
+	case "disabled":
+		settings.VmToolsMonitoringSettings = &types.ClusterVmToolsMonitoringSettings{
+			Enabled:         types.NewBool(false),
+			ClusterSettings: types.NewBool(false),
+			VmMonitoring:    string(types.ClusterDasConfigInfoVmMonitoringStateVmMonitoringDisabled),
+		}
+	default:
+		monitoring := monitoringSensitivity[s.Monitoring]
+		monitoring.Enabled = types.NewBool(true)
+		monitoring.ClusterSettings = types.NewBool(false)
+		monitoring.VmMonitoring = string(types.ClusterDasConfigInfoVmMonitoringStateVmMonitoringOnly)
+		settings.VmToolsMonitoringSettings = &monitoring
+	}
+
+	return types.ClusterDasVmConfigSpec{
+		ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: op},
+		Info: &types.ClusterDasVmConfigInfo{
+			Key:         vm,
+			DasSettings: settings,
+		},
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestHASettingsValidate(t *testing.T) {
+	assert.False(t, HASettings{}.IsSet())
+	assert.NoError(t, HASettings{}.Validate())
+
+	s := HASettings{RestartPriority: "highest", IsolationResponse: "powerOff", Monitoring: "high"}
+	assert.True(t, s.IsSet())
+	assert.NoError(t, s.Validate())
+
+	assert.Error(t, HASettings{RestartPriority: "urgent"}.Validate())
+	assert.Error(t, HASettings{IsolationResponse: "reboot"}.Validate())
+	assert.Error(t, HASettings{Monitoring: "extreme"}.Validate())
+
+	assert.Equal(t, []string{"disabled", "high", "low", "medium"}, MonitoringSensitivities())
+}
+
+func TestDasVMConfigSpec(t *testing.T) {
+	info := testClusterConfig()
+
+	spec := DasVMConfigSpec(info, vmRef("vm-1"), HASettings{RestartPriority: "high", Monitoring: "medium"})
+	assert.Equal(t, types.ArrayUpdateOperationAdd, spec.Operation)
+	require.NotNil(t, spec.Info.DasSettings)
+	assert.Equal(t, vmRef("vm-1"), spec.Info.Key)
+	assert.Equal(t, "high", spec.Info.DasSettings.RestartPriority)
+	assert.Empty(t, spec.Info.DasSettings.IsolationResponse)
+
+	monitoring := spec.Info.DasSettings.VmToolsMonitoringSettings
+	require.NotNil(t, monitoring)
+	assert.True(t, *monitoring.Enabled)
+	assert.False(t, *monitoring.ClusterSettings)
+	assert.Equal(t, int32(60), monitoring.FailureInterval)
+
+	// existing overrides are edited, keeping the settings not given
+	info.DasVmConfig = []types.ClusterDasVmConfigInfo{
+		{
+			Key:         vmRef("vm-1"),
+			DasSettings: &types.ClusterDasVmSettings{RestartPriority: "low", IsolationResponse: "shutdown"},
+		},
+	}
+	spec = DasVMConfigSpec(info, vmRef("vm-1"), HASettings{RestartPriority: "high", Monitoring: "disabled"})
+	assert.Equal(t, types.ArrayUpdateOperationEdit, spec.Operation)
+	assert.Equal(t, "high", spec.Info.DasSettings.RestartPriority)
+	assert.Equal(t, "shutdown", spec.Info.DasSettings.IsolationResponse)
+	assert.False(t, *spec.Info.DasSettings.VmToolsMonitoringSettings.Enabled)
+	assert.Equal(t, "low", info.DasVmConfig[0].DasSettings.RestartPriority)
+}
+
+func TestHAEnabled(t *testing.T) {
+	info := testClusterConfig()
+	assert.False(t, HAEnabled(info))
+	assert.False(t, VMMonitoringEnabled(info))
+
+	info.DasConfig.Enabled = types.NewBool(true)
+	info.DasConfig.VmMonitoring = string(types.ClusterDasConfigInfoVmMonitoringStateVmMonitoringOnly)
+	assert.True(t, HAEnabled(info))
+	assert.True(t, VMMonitoringEnabled(info))
+}
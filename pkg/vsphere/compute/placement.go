@@ -0,0 +1,90 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// DRSEnabled returns whether DRS places VMs in the cluster
+func DRSEnabled(ctx context.Context, cluster *object.ComputeResource) (bool, error) {
+	if cluster.Reference().Type != "ClusterComputeResource" {
+		return false, nil
+	}
+
+	info, err := ClusterConfig(ctx, cluster)
+	if err != nil {
+		return false, err
+	}
+
+	drs := info.DrsConfig
+	return drs.Enabled != nil && *drs.Enabled, nil
+}
+
+// PlacementHost returns the host of the compute resource to place a new VM on when DRS does not
+// choose one, which is the connected host outside maintenance mode running the fewest VMs
+func PlacementHost(ctx context.Context, cluster *object.ComputeResource) (*object.HostSystem, error) {
+	hosts, err := cluster.Hosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%q has no hosts", cluster.Name())
+	}
+
+	var refs []types.ManagedObjectReference
+	for _, h := range hosts {
+		refs = append(refs, h.Reference())
+	}
+
+	var mhs []mo.HostSystem
+	pc := property.DefaultCollector(cluster.Client())
+	if err = pc.Retrieve(ctx, refs, []string{"name", "runtime", "vm"}, &mhs); err != nil {
+		return nil, err
+	}
+
+	best := leastLoadedHost(mhs)
+	if best == nil {
+		return nil, fmt.Errorf("%q has no connected hosts outside maintenance mode", cluster.Name())
+	}
+
+	for _, h := range hosts {
+		if h.Reference() == best.Reference() {
+			return h, nil
+		}
+	}
+	return object.NewHostSystem(cluster.Client(), best.Reference()), nil
+}
+
+// leastLoadedHost returns the host that can run new VMs with the fewest VMs, or nil if none can
+func leastLoadedHost(hosts []mo.HostSystem) *mo.HostSystem {
+	var best *mo.HostSystem
+	for i := range hosts {
+		h := &hosts[i]
+		if h.Runtime.ConnectionState != types.HostSystemConnectionStateConnected || h.Runtime.InMaintenanceMode {
+			continue
+		}
+		if best == nil || len(h.Vm) < len(best.Vm) {
+			best = h
+		}
+	}
+	return best
+}
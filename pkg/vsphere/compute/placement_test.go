@@ -0,0 +1,53 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func testHost(name string, state types.HostSystemConnectionState, maintenance bool, vms int) mo.HostSystem {
+	h := mo.HostSystem{}
+	h.Self = types.ManagedObjectReference{Type: "HostSystem", Value: name}
+	h.Name = name
+	h.Runtime.ConnectionState = state
+	h.Runtime.InMaintenanceMode = maintenance
+	for i := 0; i < vms; i++ {
+		h.Vm = append(h.Vm, vmRef(name))
+	}
+	return h
+}
+
+func TestLeastLoadedHost(t *testing.T) {
+	connected := types.HostSystemConnectionStateConnected
+
+	hosts := []mo.HostSystem{
+		testHost("busy", connected, false, 5),
+		testHost("disconnected", types.HostSystemConnectionStateDisconnected, false, 0),
+		testHost("maintenance", connected, true, 0),
+		testHost("idle", connected, false, 1),
+	}
+	if h := leastLoadedHost(hosts); assert.NotNil(t, h) {
+		assert.Equal(t, "idle", h.Name)
+	}
+
+	assert.Nil(t, leastLoadedHost(hosts[1:3]))
+	assert.Nil(t, leastLoadedHost(nil))
+}
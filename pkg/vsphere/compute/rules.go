@@ -0,0 +1,226 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/pkg/vsphere/tasks"
+)
+
+// ApplianceVMGroupName returns the name of the DRS VM group holding the appliance of the VCH
+func ApplianceVMGroupName(vch string) string {
+	return vch + "-appliance"
+}
+
+// ContainerVMGroupName returns the name of the DRS VM group holding the containerVMs of the VCH
+func ContainerVMGroupName(vch string) string {
+	return vch + "-containers"
+}
+
+// HostRuleName returns the name of the rule keeping a VM group on a host group
+func HostRuleName(vmGroup string) string {
+	return vmGroup + "-hosts"
+}
+
+// AntiAffinityRulePrefix returns the prefix of the names of the rules keeping the containerVMs of
+// a service on different hosts, which is followed by the service name
+func AntiAffinityRulePrefix(vch string) string {
+	return vch + "-anti-affinity-"
+}
+
+// ClusterConfig returns the extended configuration of the cluster, which holds its DRS groups and rules
+func ClusterConfig(ctx context.Context, cluster *object.ComputeResource) (*types.ClusterConfigInfoEx, error) {
+	var ccr mo.ClusterComputeResource
+
+	if err := cluster.Properties(ctx, cluster.Reference(), []string{"configurationEx"}, &ccr); err != nil {
+		return nil, err
+	}
+
+	info, ok := ccr.ConfigurationEx.(*types.ClusterConfigInfoEx)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a cluster", cluster.Name())
+	}
+	return info, nil
+}
+
+// ReconfigureCluster applies the DRS group and rule changes in the spec to the cluster
+func ReconfigureCluster(ctx context.Context, cluster *object.ComputeResource, spec *types.ClusterConfigSpecEx) error {
+	return tasks.Wait(ctx, func(ctx context.Context) (tasks.Task, error) {
+		return cluster.Reconfigure(ctx, spec, true)
+	})
+}
+
+// FindGroup returns the named DRS group of the cluster, or nil if there is none
+func FindGroup(info *types.ClusterConfigInfoEx, name string) types.BaseClusterGroupInfo {
+	for _, g := range info.Group {
+		if g.GetClusterGroupInfo().Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// FindRule returns the named DRS rule of the cluster, or nil if there is none
+func FindRule(info *types.ClusterConfigInfoEx, name string) types.BaseClusterRuleInfo {
+	for _, r := range info.Rule {
+		if r.GetClusterRuleInfo().Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// HostGroupExists returns an error unless the cluster has a host group with the given name
+func HostGroupExists(info *types.ClusterConfigInfoEx, name string) error {
+	if _, ok := FindGroup(info, name).(*types.ClusterHostGroup); !ok {
+		return fmt.Errorf("host group %q not found in cluster", name)
+	}
+	return nil
+}
+
+// VMGroupSpec returns the spec adding the VMs to the named VM group, creating the group if
+// it does not exist
+func VMGroupSpec(info *types.ClusterConfigInfoEx, name string, vms ...types.ManagedObjectReference) (types.ClusterGroupSpec, error) {
+	g := FindGroup(info, name)
+	if g == nil {
+		return types.ClusterGroupSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationAdd},
+			Info: &types.ClusterVmGroup{
+				ClusterGroupInfo: types.ClusterGroupInfo{Name: name},
+				Vm:               vms,
+			},
+		}, nil
+	}
+
+	group, ok := g.(*types.ClusterVmGroup)
+	if !ok {
+		return types.ClusterGroupSpec{}, fmt.Errorf("group %q is not a VM group", name)
+	}
+
+	edit := *group
+	edit.Vm = union(group.Vm, vms)
+
+	return types.ClusterGroupSpec{
+		ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationEdit},
+		Info:            &edit,
+	}, nil
+}
+
+// VMHostRuleSpec returns the spec for a rule keeping the VMs of the VM group on the hosts of the
+// host group. A mandatory rule is never violated, otherwise DRS treats it as a preference.
+func VMHostRuleSpec(name, vmGroup, hostGroup string, mandatory bool) types.ClusterRuleSpec {
+	return types.ClusterRuleSpec{
+		ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationAdd},
+		Info: &types.ClusterVmHostRuleInfo{
+			ClusterRuleInfo: types.ClusterRuleInfo{
+				Name:      name,
+				Enabled:   types.NewBool(true),
+				Mandatory: types.NewBool(mandatory),
+			},
+			VmGroupName:         vmGroup,
+			AffineHostGroupName: hostGroup,
+		},
+	}
+}
+
+// AntiAffinityRuleSpec returns the spec for a rule keeping the VMs on different hosts, adding
+// them to the named rule if it already exists. As such a rule needs at least two VMs, nil is
+// returned if there are fewer.
+func AntiAffinityRuleSpec(info *types.ClusterConfigInfoEx, name string, vms ...types.ManagedObjectReference) (*types.ClusterRuleSpec, error) {
+	r := FindRule(info, name)
+	if r == nil {
+		if len(vms) < 2 {
+			return nil, nil
+		}
+
+		return &types.ClusterRuleSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationAdd},
+			Info: &types.ClusterAntiAffinityRuleSpec{
+				ClusterRuleInfo: types.ClusterRuleInfo{
+					Name:    name,
+					Enabled: types.NewBool(true),
+				},
+				Vm: vms,
+			},
+		}, nil
+	}
+
+	rule, ok := r.(*types.ClusterAntiAffinityRuleSpec)
+	if !ok {
+		return nil, fmt.Errorf("rule %q is not an anti-affinity rule", name)
+	}
+
+	edit := *rule
+	edit.Vm = union(rule.Vm, vms)
+
+	return &types.ClusterRuleSpec{
+		ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationEdit},
+		Info:            &edit,
+	}, nil
+}
+
+// RemoveSpec returns the spec removing the DRS rules and groups whose names match, or nil if
+// there is nothing to remove
+func RemoveSpec(info *types.ClusterConfigInfoEx, rule, group func(name string) bool) *types.ClusterConfigSpecEx {
+	spec := &types.ClusterConfigSpecEx{}
+
+	// rules are removed by key, groups by name
+	for _, r := range info.Rule {
+		ri := r.GetClusterRuleInfo()
+		if rule(ri.Name) {
+			spec.RulesSpec = append(spec.RulesSpec, types.ClusterRuleSpec{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationRemove, RemoveKey: ri.Key},
+			})
+		}
+	}
+
+	for _, g := range info.Group {
+		gi := g.GetClusterGroupInfo()
+		if group(gi.Name) {
+			spec.GroupSpec = append(spec.GroupSpec, types.ClusterGroupSpec{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationRemove, RemoveKey: gi.Name},
+			})
+		}
+	}
+
+	if len(spec.RulesSpec) == 0 && len(spec.GroupSpec) == 0 {
+		return nil
+	}
+	return spec
+}
+
+// union returns the references in a followed by those in b that are not in a
+func union(a, b []types.ManagedObjectReference) []types.ManagedObjectReference {
+	res := append([]types.ManagedObjectReference{}, a...)
+
+	seen := make(map[types.ManagedObjectReference]bool, len(a))
+	for _, ref := range a {
+		seen[ref] = true
+	}
+
+	for _, ref := range b {
+		if !seen[ref] {
+			res = append(res, ref)
+			seen[ref] = true
+		}
+	}
+	return res
+}
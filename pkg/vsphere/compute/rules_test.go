@@ -0,0 +1,119 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func vmRef(id string) types.ManagedObjectReference {
+	return types.ManagedObjectReference{Type: "VirtualMachine", Value: id}
+}
+
+func testClusterConfig() *types.ClusterConfigInfoEx {
+	return &types.ClusterConfigInfoEx{
+		Group: []types.BaseClusterGroupInfo{
+			&types.ClusterHostGroup{ClusterGroupInfo: types.ClusterGroupInfo{Name: "licensed"}},
+			&types.ClusterVmGroup{ClusterGroupInfo: types.ClusterGroupInfo{Name: "vch-containers"}, Vm: []types.ManagedObjectReference{vmRef("vm-1")}},
+		},
+		Rule: []types.BaseClusterRuleInfo{
+			&types.ClusterAntiAffinityRuleSpec{
+				ClusterRuleInfo: types.ClusterRuleInfo{Key: 1, Name: "vch-anti-affinity-web"},
+				Vm:              []types.ManagedObjectReference{vmRef("vm-1"), vmRef("vm-2")},
+			},
+			&types.ClusterVmHostRuleInfo{
+				ClusterRuleInfo: types.ClusterRuleInfo{Key: 2, Name: "vch-containers-hosts"},
+				VmGroupName:     "vch-containers",
+			},
+		},
+	}
+}
+
+func TestHostGroupExists(t *testing.T) {
+	info := testClusterConfig()
+
+	assert.NoError(t, HostGroupExists(info, "licensed"))
+	assert.Error(t, HostGroupExists(info, "vch-containers"))
+	assert.Error(t, HostGroupExists(info, "missing"))
+}
+
+func TestVMGroupSpec(t *testing.T) {
+	info := testClusterConfig()
+
+	spec, err := VMGroupSpec(info, "vch-appliance", vmRef("vm-0"))
+	require.NoError(t, err)
+	assert.Equal(t, types.ArrayUpdateOperationAdd, spec.Operation)
+	assert.Equal(t, []types.ManagedObjectReference{vmRef("vm-0")}, spec.Info.(*types.ClusterVmGroup).Vm)
+
+	spec, err = VMGroupSpec(info, "vch-containers", vmRef("vm-1"), vmRef("vm-3"))
+	require.NoError(t, err)
+	assert.Equal(t, types.ArrayUpdateOperationEdit, spec.Operation)
+	assert.Equal(t, []types.ManagedObjectReference{vmRef("vm-1"), vmRef("vm-3")}, spec.Info.(*types.ClusterVmGroup).Vm)
+
+	// the existing group is not modified until the spec is applied
+	assert.Len(t, info.Group[1].(*types.ClusterVmGroup).Vm, 1)
+
+	_, err = VMGroupSpec(info, "licensed", vmRef("vm-0"))
+	assert.Error(t, err)
+}
+
+func TestAntiAffinityRuleSpec(t *testing.T) {
+	info := testClusterConfig()
+
+	spec, err := AntiAffinityRuleSpec(info, "vch-anti-affinity-db", vmRef("vm-4"))
+	require.NoError(t, err)
+	assert.Nil(t, spec, "a rule needs at least two VMs")
+
+	spec, err = AntiAffinityRuleSpec(info, "vch-anti-affinity-db", vmRef("vm-4"), vmRef("vm-5"))
+	require.NoError(t, err)
+	require.NotNil(t, spec)
+	assert.Equal(t, types.ArrayUpdateOperationAdd, spec.Operation)
+
+	spec, err = AntiAffinityRuleSpec(info, "vch-anti-affinity-web", vmRef("vm-3"))
+	require.NoError(t, err)
+	require.NotNil(t, spec)
+	assert.Equal(t, types.ArrayUpdateOperationEdit, spec.Operation)
+
+	rule := spec.Info.(*types.ClusterAntiAffinityRuleSpec)
+	assert.Equal(t, int32(1), rule.Key)
+	assert.Equal(t, []types.ManagedObjectReference{vmRef("vm-1"), vmRef("vm-2"), vmRef("vm-3")}, rule.Vm)
+
+	_, err = AntiAffinityRuleSpec(info, "vch-containers-hosts", vmRef("vm-3"))
+	assert.Error(t, err)
+}
+
+func TestRemoveSpec(t *testing.T) {
+	info := testClusterConfig()
+
+	prefix := func(name string) bool { return strings.HasPrefix(name, "vch-") }
+	spec := RemoveSpec(info, prefix, prefix)
+	require.NotNil(t, spec)
+
+	require.Len(t, spec.RulesSpec, 2)
+	assert.Equal(t, int32(1), spec.RulesSpec[0].RemoveKey)
+	assert.Equal(t, int32(2), spec.RulesSpec[1].RemoveKey)
+
+	require.Len(t, spec.GroupSpec, 1)
+	assert.Equal(t, "vch-containers", spec.GroupSpec[0].RemoveKey)
+
+	none := func(name string) bool { return false }
+	assert.Nil(t, RemoveSpec(info, none, none))
+}
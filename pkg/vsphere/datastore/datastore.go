@@ -48,6 +48,9 @@ type Helper struct {
 
 	// The datastore url (including root) in "[dsname] /path" format.
 	RootURL string
+
+	// directory listings made by LsEach
+	listings *listingCache
 }
 
 // NewDatastore returns a Datastore.
@@ -59,9 +62,10 @@ type Helper struct {
 func NewHelper(ctx context.Context, s *session.Session, ds *object.Datastore, rootdir string) (*Helper, error) {
 
 	d := &Helper{
-		ds: ds,
-		s:  s,
-		fm: object.NewFileManager(s.Vim25()),
+		ds:       ds,
+		s:        s,
+		fm:       object.NewFileManager(s.Vim25()),
+		listings: newListingCache(),
 	}
 
 	if path.IsAbs(rootdir) {
@@ -94,10 +98,11 @@ func GetDatastores(ctx context.Context, s *session.Session, dsURLs map[string]*u
 		}
 
 		d := &Helper{
-			ds:      vsDs,
-			s:       s,
-			fm:      fm,
-			RootURL: dsURL.Path,
+			ds:       vsDs,
+			s:        s,
+			fm:       fm,
+			RootURL:  dsURL.Path,
+			listings: newListingCache(),
 		}
 
 		stores[name] = d
@@ -136,7 +141,10 @@ func mkdir(ctx context.Context, sess *session.Session, fm *object.FileManager, c
 
 // Mkdir creates directories.
 func (d *Helper) Mkdir(ctx context.Context, createParentDirectories bool, dirs ...string) (string, error) {
-	return mkdir(ctx, d.s, d.fm, createParentDirectories, path.Join(d.RootURL, path.Join(dirs...)))
+	p := path.Join(d.RootURL, path.Join(dirs...))
+	defer d.listings.invalidate(p)
+
+	return mkdir(ctx, d.s, d.fm, createParentDirectories, p)
 }
 
 // Ls returns a list of dirents at the given path (relative to root)
@@ -208,6 +216,8 @@ func (d *Helper) LsDirs(ctx context.Context, p string) (*types.ArrayOfHostDatast
 }
 
 func (d *Helper) Upload(ctx context.Context, r io.Reader, pth string) error {
+	defer d.listings.invalidate(path.Join(d.RootURL, pth))
+
 	return d.ds.Upload(ctx, r, path.Join(d.rootDir(), pth), &soap.DefaultUpload)
 }
 
@@ -234,6 +244,9 @@ func (d *Helper) Mv(ctx context.Context, fromPath, toPath string) error {
 	from := path.Join(d.RootURL, fromPath)
 	to := path.Join(d.RootURL, toPath)
 	log.Infof("Moving %s to %s", from, to)
+	defer d.listings.invalidate(to)
+	defer d.listings.invalidate(from)
+
 	err := tasks.Wait(ctx, func(context.Context) (tasks.Task, error) {
 		return d.fm.MoveDatastoreFile(ctx, from, d.s.Datacenter, to, d.s.Datacenter, true)
 	})
@@ -244,6 +257,8 @@ func (d *Helper) Mv(ctx context.Context, fromPath, toPath string) error {
 func (d *Helper) Rm(ctx context.Context, pth string) error {
 	f := path.Join(d.RootURL, pth)
 	log.Infof("Removing %s", pth)
+	defer d.listings.invalidate(f)
+
 	return tasks.Wait(context.TODO(), func(ctx context.Context) (tasks.Task, error) {
 		return d.fm.DeleteDatastoreFile(ctx, f, d.s.Datacenter)
 	})
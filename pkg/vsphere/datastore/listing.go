@@ -0,0 +1,150 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// ListingTTL is how long a directory listing made by LsEach is reused for. Writes made through the Helper
+// invalidate the listings they affect straight away, so this only bounds how stale listings can be with
+// respect to changes made by others.
+var ListingTTL = 30 * time.Second
+
+// DefaultPageSize is the number of dirents passed to each call of the LsEach callback when no page size is given
+const DefaultPageSize = 256
+
+type listing struct {
+	files   []types.BaseFileInfo
+	expires time.Time
+}
+
+// byPath sorts dirents by name
+type byPath []types.BaseFileInfo
+
+func (b byPath) Len() int           { return len(b) }
+func (b byPath) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byPath) Less(i, j int) bool { return b[i].GetFileInfo().Path < b[j].GetFileInfo().Path }
+
+// listingCache holds the directory listings of a Helper, keyed by datastore path
+type listingCache struct {
+	mu sync.Mutex
+
+	listings map[string]*listing
+}
+
+func newListingCache() *listingCache {
+	return &listingCache{
+		listings: make(map[string]*listing),
+	}
+}
+
+func (c *listingCache) get(dir string) []types.BaseFileInfo {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, ok := c.listings[dir]
+	if !ok {
+		return nil
+	}
+
+	if time.Now().After(l.expires) {
+		delete(c.listings, dir)
+		return nil
+	}
+
+	return l.files
+}
+
+func (c *listingCache) put(dir string, files []types.BaseFileInfo) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.listings[dir] = &listing{
+		files:   files,
+		expires: time.Now().Add(ListingTTL),
+	}
+}
+
+// invalidate drops the listings of p, of the directories above it and of those below it
+func (c *listingCache) invalidate(p string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for dir := range c.listings {
+		if dir == p || strings.HasPrefix(p, dir+"/") || strings.HasPrefix(dir, p+"/") {
+			delete(c.listings, dir)
+		}
+	}
+}
+
+// LsEach calls fn with successive pages of at most pageSize dirents of the directory p (relative to root),
+// in name order, stopping at the first error returned by fn. The datastore is searched once per
+// directory and the listing reused for ListingTTL, so that directories with many files, such as large
+// image stores, can be paged through and listed repeatedly without a search each time. fn must not modify
+// the dirents it is passed.
+func (d *Helper) LsEach(ctx context.Context, p string, pageSize int, fn func(files []types.BaseFileInfo) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	dir := path.Join(d.RootURL, p)
+	files := d.listings.get(dir)
+	if files == nil {
+		res, err := d.Ls(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		files = make([]types.BaseFileInfo, len(res.File))
+		copy(files, res.File)
+		sort.Sort(byPath(files))
+
+		d.listings.put(dir, files)
+	}
+
+	for len(files) > 0 {
+		n := pageSize
+		if n > len(files) {
+			n = len(files)
+		}
+
+		if err := fn(files[:n:n]); err != nil {
+			return err
+		}
+		files = files[n:]
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestListingCacheInvalidate(t *testing.T) {
+	c := newListingCache()
+	files := []types.BaseFileInfo{&types.FileInfo{Path: "a"}}
+
+	dirs := []string{"[ds] root", "[ds] root/images", "[ds] root/images/abc", "[ds] root/imagesx", "[ds] root/volumes"}
+	for _, dir := range dirs {
+		c.put(dir, files)
+	}
+
+	// writing root/images/abc/manifest affects the listings of the directories it is in
+	c.invalidate("[ds] root/images/abc/manifest")
+	for _, dir := range dirs[:3] {
+		assert.Nil(t, c.get(dir), "listing of %s was not invalidated", dir)
+	}
+	for _, dir := range dirs[3:] {
+		assert.NotNil(t, c.get(dir), "listing of %s was invalidated", dir)
+	}
+
+	// removing a directory affects the listings below it
+	c.put("[ds] root/volumes/vol1", files)
+	c.invalidate("[ds] root/volumes")
+	assert.Nil(t, c.get("[ds] root/volumes/vol1"))
+	assert.NotNil(t, c.get("[ds] root/imagesx"))
+
+	// listings expire
+	defer func(ttl time.Duration) { ListingTTL = ttl }(ListingTTL)
+	ListingTTL = -time.Second
+	c.put("[ds] root/volumes", files)
+	assert.Nil(t, c.get("[ds] root/volumes"))
+
+	// a Helper without a cache lists without caching
+	var none *listingCache
+	none.put("[ds] root", files)
+	none.invalidate("[ds] root")
+	assert.Nil(t, none.get("[ds] root"))
+}
+
+func TestDatastoreLsEach(t *testing.T) {
+	ctx, ds, cleanupfunc := DSsetup(t)
+	if t.Failed() {
+		return
+	}
+	defer cleanupfunc()
+
+	for i := 4; i >= 0; i-- {
+		_, err := ds.Mkdir(ctx, true, "parent", fmt.Sprintf("dir%d", i))
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+
+	list := func() ([]string, int) {
+		var names []string
+		pages := 0
+		err := ds.LsEach(ctx, "parent", 2, func(files []types.BaseFileInfo) error {
+			assert.True(t, len(files) <= 2)
+			pages++
+			for _, f := range files {
+				names = append(names, f.GetFileInfo().Path)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		return names, pages
+	}
+
+	names, pages := list()
+	assert.Equal(t, []string{"dir0", "dir1", "dir2", "dir3", "dir4"}, names)
+	assert.Equal(t, 3, pages)
+
+	// writes through the helper are seen straight away
+	_, err := ds.Mkdir(ctx, true, "parent", "dir5")
+	assert.NoError(t, err)
+	assert.NoError(t, ds.Rm(ctx, "parent/dir0"))
+
+	names, _ = list()
+	assert.Equal(t, []string{"dir1", "dir2", "dir3", "dir4", "dir5"}, names)
+
+	// errors from the callback stop the listing
+	calls := 0
+	err = ds.LsEach(ctx, "parent", 1, func([]types.BaseFileInfo) error {
+		calls++
+		return assert.AnError
+	})
+	assert.Equal(t, assert.AnError, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,143 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/vmware/vic/pkg/vsphere/session"
+)
+
+const (
+	// MostFreeSpace places on the datastore with the most free space
+	MostFreeSpace = "most-free-space"
+	// RoundRobin places on each datastore in turn
+	RoundRobin = "round-robin"
+)
+
+// PlacementPolicies are the supported placement policies, the first being the default
+var PlacementPolicies = []string{MostFreeSpace, RoundRobin}
+
+// FreeSpaceFunc returns the free space in bytes of the datastore identified by the URL
+type FreeSpaceFunc func(ctx context.Context, u *url.URL) (int64, error)
+
+// Placer chooses between datastores according to a placement policy
+type Placer struct {
+	policy string
+	stores []url.URL
+	free   FreeSpaceFunc
+
+	m    sync.Mutex
+	next int
+}
+
+// ValidPlacementPolicy returns an error if the policy is not one of PlacementPolicies
+func ValidPlacementPolicy(policy string) error {
+	for _, p := range PlacementPolicies {
+		if p == policy {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown placement policy %q, must be one of %s", policy, strings.Join(PlacementPolicies, ", "))
+}
+
+// NewPlacer returns a Placer for the stores. An empty policy selects the default.
+func NewPlacer(policy string, stores []url.URL, free FreeSpaceFunc) (*Placer, error) {
+	if policy == "" {
+		policy = PlacementPolicies[0]
+	}
+	if err := ValidPlacementPolicy(policy); err != nil {
+		return nil, err
+	}
+	if len(stores) == 0 {
+		return nil, fmt.Errorf("no datastores to place on")
+	}
+
+	return &Placer{
+		policy: policy,
+		stores: stores,
+		free:   free,
+	}, nil
+}
+
+// Place returns the datastore to use next
+func (p *Placer) Place(ctx context.Context) (*url.URL, error) {
+	if len(p.stores) == 1 {
+		return &p.stores[0], nil
+	}
+
+	switch p.policy {
+	case RoundRobin:
+		p.m.Lock()
+		defer p.m.Unlock()
+
+		u := &p.stores[p.next]
+		p.next = (p.next + 1) % len(p.stores)
+		return u, nil
+	default:
+		return p.mostFreeSpace(ctx)
+	}
+}
+
+// mostFreeSpace returns the store with the most free space, skipping any that cannot be queried
+func (p *Placer) mostFreeSpace(ctx context.Context) (*url.URL, error) {
+	var best *url.URL
+	var most int64 = -1
+
+	for i := range p.stores {
+		u := &p.stores[i]
+
+		free, err := p.free(ctx, u)
+		if err != nil {
+			log.Warnf("Unable to determine free space on %s: %s", u.String(), err)
+			continue
+		}
+
+		log.Debugf("Datastore %s has %d bytes free", u.String(), free)
+		if free > most {
+			best, most = u, free
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("unable to determine free space on any of %d datastores", len(p.stores))
+	}
+	return best, nil
+}
+
+// FreeSpace returns a FreeSpaceFunc that looks up datastores using the session
+func FreeSpace(s *session.Session) FreeSpaceFunc {
+	return func(ctx context.Context, u *url.URL) (int64, error) {
+		ds, err := s.Finder.Datastore(ctx, u.Host)
+		if err != nil {
+			return 0, err
+		}
+
+		var mds mo.Datastore
+		if err = ds.Properties(ctx, ds.Reference(), []string{"summary.freeSpace"}, &mds); err != nil {
+			return 0, err
+		}
+
+		return mds.Summary.FreeSpace, nil
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var placementStores = []url.URL{
+	{Scheme: "ds", Host: "ds1", Path: "vch"},
+	{Scheme: "ds", Host: "ds2", Path: "vch"},
+	{Scheme: "ds", Host: "ds3", Path: "vch"},
+}
+
+func freeSpace(free map[string]int64) FreeSpaceFunc {
+	return func(ctx context.Context, u *url.URL) (int64, error) {
+		f, ok := free[u.Host]
+		if !ok {
+			return 0, fmt.Errorf("%s is not accessible", u.Host)
+		}
+		return f, nil
+	}
+}
+
+func TestPlaceRoundRobin(t *testing.T) {
+	p, err := NewPlacer(RoundRobin, placementStores, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 2*len(placementStores); i++ {
+		u, err := p.Place(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, placementStores[i%len(placementStores)].Host, u.Host)
+	}
+}
+
+func TestPlaceMostFreeSpace(t *testing.T) {
+	// ds3 has the most space but cannot be queried
+	p, err := NewPlacer("", placementStores, freeSpace(map[string]int64{"ds1": 10, "ds2": 20}))
+	require.NoError(t, err)
+
+	u, err := p.Place(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ds2", u.Host)
+
+	p, err = NewPlacer(MostFreeSpace, placementStores, freeSpace(nil))
+	require.NoError(t, err)
+
+	_, err = p.Place(context.Background())
+	assert.Error(t, err)
+}
+
+func TestPlaceSingleStore(t *testing.T) {
+	p, err := NewPlacer(MostFreeSpace, placementStores[:1], freeSpace(nil))
+	require.NoError(t, err)
+
+	u, err := p.Place(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ds1", u.Host)
+}
+
+func TestNewPlacer(t *testing.T) {
+	_, err := NewPlacer("random", placementStores, nil)
+	assert.Error(t, err)
+
+	_, err = NewPlacer(RoundRobin, nil, nil)
+	assert.Error(t, err)
+
+	for _, policy := range PlacementPolicies {
+		assert.NoError(t, ValidPlacementPolicy(policy))
+	}
+}
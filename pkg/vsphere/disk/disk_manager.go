@@ -52,6 +52,10 @@ type Manager struct {
 	// The PCI + SCSI device /dev node string format the disks can be attached with
 	byPathFormat string
 
+	// Provisioning of the disks created without a parent, thin if unset. Disks with a
+	// parent are delta disks, which are always sparse.
+	Provisioning Provisioning
+
 	reconfig sync.Mutex
 }
 
@@ -83,6 +87,14 @@ func NewDiskManager(op trace.Operation, session *session.Session) (*Manager, err
 func (m *Manager) CreateAndAttach(op trace.Operation, newDiskURI,
 	parentURI string,
 	capacity int64, flags int) (*VirtualDisk, error) {
+	return m.CreateAndAttachWithPolicy(op, newDiskURI, parentURI, "", capacity, flags)
+}
+
+// CreateAndAttachWithPolicy is CreateAndAttach with the vSphere storage policy
+// of the given ID applied to the new disk, or none if policy is empty.
+func (m *Manager) CreateAndAttachWithPolicy(op trace.Operation, newDiskURI,
+	parentURI, policy string,
+	capacity int64, flags int) (*VirtualDisk, error) {
 	defer trace.End(trace.Begin(newDiskURI))
 
 	// ensure we abide by max attached disks limits
@@ -97,7 +109,7 @@ func (m *Manager) CreateAndAttach(op trace.Operation, newDiskURI,
 
 	op.Infof("Create/attach vmdk %s from parent %s", newDiskURI, parentURI)
 
-	err = m.Attach(op, spec)
+	err = m.attach(op, spec, policy)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -134,6 +146,10 @@ func (m *Manager) createDiskSpec(childURI, parentURI string, capacity int64, fla
 		},
 	}
 
+	if parentURI == "" && flags != os.O_RDONLY {
+		m.Provisioning.Backing(backing)
+	}
+
 	if flags == os.O_RDONLY {
 		backing.DiskMode = string(types.VirtualDiskModeIndependent_nonpersistent)
 		capacity = 0
@@ -178,7 +194,7 @@ func (m *Manager) Create(op trace.Operation, newDiskURI string,
 
 	spec := &types.FileBackedVirtualDiskSpec{
 		VirtualDiskSpec: types.VirtualDiskSpec{
-			DiskType:    string(types.VirtualDiskTypeThin),
+			DiskType:    string(m.Provisioning.DiskType()),
 			AdapterType: string(types.VirtualDiskAdapterTypeLsiLogic),
 		},
 
@@ -229,6 +245,10 @@ func (m *Manager) Create(op trace.Operation, newDiskURI string,
 // }
 
 func (m *Manager) Attach(op trace.Operation, disk *types.VirtualDisk) error {
+	return m.attach(op, disk, "")
+}
+
+func (m *Manager) attach(op trace.Operation, disk *types.VirtualDisk, policy string) error {
 	deviceList := object.VirtualDeviceList{}
 	deviceList = append(deviceList, disk)
 
@@ -237,6 +257,13 @@ func (m *Manager) Attach(op trace.Operation, disk *types.VirtualDisk) error {
 		return err
 	}
 
+	if policy != "" {
+		op.Infof("Applying storage policy %s to the new disk", policy)
+		for _, change := range changeSpec {
+			change.GetVirtualDeviceConfigSpec().Profile = StoragePolicy(policy)
+		}
+	}
+
 	machineSpec := types.VirtualMachineConfigSpec{}
 	machineSpec.DeviceChange = append(machineSpec.DeviceChange, changeSpec...)
 
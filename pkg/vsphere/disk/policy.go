@@ -0,0 +1,32 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// StoragePolicy returns the profile spec that applies the vSphere storage policy with the given ID to
+// a VM or to a new disk, or nil for an empty ID. Encryption policies also need a KMS to be configured
+// in vCenter, and an encrypted disk can only be attached to a VM whose home is encrypted.
+func StoragePolicy(id string) []types.BaseVirtualMachineProfileSpec {
+	if id == "" {
+		return nil
+	}
+
+	return []types.BaseVirtualMachineProfileSpec{
+		&types.VirtualMachineDefinedProfileSpec{ProfileId: id},
+	}
+}
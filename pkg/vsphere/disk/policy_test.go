@@ -0,0 +1,35 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestStoragePolicy(t *testing.T) {
+	assert.Nil(t, StoragePolicy(""))
+
+	profile := StoragePolicy("aa6d5a82-1c88-45da-85d3-3d74b91a5bad")
+	if assert.Len(t, profile, 1) {
+		spec, ok := profile[0].(*types.VirtualMachineDefinedProfileSpec)
+		if assert.True(t, ok) {
+			assert.Equal(t, "aa6d5a82-1c88-45da-85d3-3d74b91a5bad", spec.ProfileId)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Provisioning is how the space of a disk is allocated on its datastore
+type Provisioning string
+
+const (
+	// Thin disks only allocate space as it is written
+	Thin Provisioning = "thin"
+	// Thick disks allocate all their space on creation, and zero it on first write
+	Thick Provisioning = "thick"
+	// EagerZeroedThick disks allocate and zero all their space on creation
+	EagerZeroedThick Provisioning = "eagerZeroedThick"
+)
+
+// ProvisioningTypes are the supported disk provisioning types
+var ProvisioningTypes = []string{string(Thin), string(Thick), string(EagerZeroedThick)}
+
+// ParseProvisioning returns the provisioning type named by s, with an empty string being thin
+func ParseProvisioning(s string) (Provisioning, error) {
+	if s == "" {
+		return Thin, nil
+	}
+
+	for _, p := range ProvisioningTypes {
+		if s == p {
+			return Provisioning(p), nil
+		}
+	}
+	return "", fmt.Errorf("Invalid disk provisioning %q, must be one of %s", s, strings.Join(ProvisioningTypes, ", "))
+}
+
+// Backing sets the allocation of a new disk backing to the provisioning type
+func (p Provisioning) Backing(backing *types.VirtualDiskFlatVer2BackingInfo) {
+	backing.ThinProvisioned = types.NewBool(p != Thick && p != EagerZeroedThick)
+	backing.EagerlyScrub = types.NewBool(p == EagerZeroedThick)
+}
+
+// DiskType returns the virtual disk manager type of the provisioning type
+func (p Provisioning) DiskType() types.VirtualDiskType {
+	switch p {
+	case Thick:
+		return types.VirtualDiskTypePreallocated
+	case EagerZeroedThick:
+		return types.VirtualDiskTypeEagerZeroedThick
+	default:
+		return types.VirtualDiskTypeThin
+	}
+}
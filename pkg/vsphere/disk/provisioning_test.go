@@ -0,0 +1,76 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestParseProvisioning(t *testing.T) {
+	for s, expected := range map[string]Provisioning{
+		"":                 Thin,
+		"thin":             Thin,
+		"thick":            Thick,
+		"eagerZeroedThick": EagerZeroedThick,
+	} {
+		p, err := ParseProvisioning(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, p, s)
+		}
+	}
+
+	for _, s := range []string{"Thin", "lazy", "eagerzeroedthick"} {
+		_, err := ParseProvisioning(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestProvisioningDiskSpec(t *testing.T) {
+	m := &Manager{controller: &types.ParaVirtualSCSIController{}}
+
+	var tests = []struct {
+		provisioning Provisioning
+		parent       string
+		flags        int
+		thin         bool
+		eager        bool
+	}{
+		{"", "", os.O_RDWR, true, false},
+		{Thin, "", os.O_RDWR, true, false},
+		{Thick, "", os.O_RDWR, false, false},
+		{EagerZeroedThick, "", os.O_RDWR, false, true},
+		// delta disks and read only disks keep the default
+		{EagerZeroedThick, "[ds] parent.vmdk", os.O_RDWR, true, false},
+		{Thick, "", os.O_RDONLY, true, false},
+	}
+
+	for _, te := range tests {
+		m.Provisioning = te.provisioning
+		spec := m.createDiskSpec("[ds] child.vmdk", te.parent, 1024, te.flags)
+		backing := spec.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+
+		assert.Equal(t, te.thin, *backing.ThinProvisioned, "%+v", te)
+		assert.Equal(t, te.eager, backing.EagerlyScrub != nil && *backing.EagerlyScrub, "%+v", te)
+	}
+
+	assert.Equal(t, types.VirtualDiskTypeThin, Provisioning("").DiskType())
+	assert.Equal(t, types.VirtualDiskTypePreallocated, Thick.DiskType())
+	assert.Equal(t, types.VirtualDiskTypeEagerZeroedThick, EagerZeroedThick.DiskType())
+}
@@ -26,6 +26,12 @@ import (
 // OptionValueSource is a convenience method to generate a MapSource source from
 // and array of OptionValue's
 func OptionValueSource(src []types.BaseOptionValue) extraconfig.DataSource {
+	return extraconfig.MapSource(OptionValueMap(src))
+}
+
+// OptionValueMap is a convenience method to convert a BaseOptionValue array into a map,
+// the inverse of OptionValueFromMap
+func OptionValueMap(src []types.BaseOptionValue) map[string]string {
 	// create the key/value store from the extraconfig slice for lookups
 	kv := make(map[string]string)
 	for i := range src {
@@ -37,7 +43,7 @@ func OptionValueSource(src []types.BaseOptionValue) extraconfig.DataSource {
 		kv[k] = v
 	}
 
-	return extraconfig.MapSource(kv)
+	return kv
 }
 
 // OptionValueFromMap is a convenience method to convert a map into a BaseOptionValue array
@@ -0,0 +1,108 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package network provides the vSphere network types that govmomi does not back network cards for
+package network
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// OpaqueNetworkType is the managed object type of networks managed outside of vSphere, such as
+// NSX logical switches
+const OpaqueNetworkType = "OpaqueNetwork"
+
+// OpaqueNetwork is a network managed outside of vSphere, such as an NSX logical switch. Network
+// cards are attached to it by its ID in the external manager rather than by name.
+type OpaqueNetwork struct {
+	object.Common
+}
+
+// NewOpaqueNetwork returns the opaque network with the reference
+func NewOpaqueNetwork(c *vim25.Client, ref types.ManagedObjectReference) *OpaqueNetwork {
+	return &OpaqueNetwork{
+		Common: object.NewCommon(c, ref),
+	}
+}
+
+// EthernetCardBackingInfo returns the backing of a network card on the opaque network
+func (n OpaqueNetwork) EthernetCardBackingInfo(ctx context.Context) (types.BaseVirtualDeviceBackingInfo, error) {
+	var net mo.OpaqueNetwork
+	if err := n.Properties(ctx, n.Reference(), []string{"summary"}, &net); err != nil {
+		return nil, err
+	}
+
+	return opaqueBacking(n.Reference(), net.Summary)
+}
+
+// opaqueBacking returns the network card backing for the summary of an opaque network
+func opaqueBacking(ref types.ManagedObjectReference, summary types.BaseNetworkSummary) (types.BaseVirtualDeviceBackingInfo, error) {
+	s, ok := summary.(*types.OpaqueNetworkSummary)
+	if !ok {
+		return nil, fmt.Errorf("%s has no opaque network summary", ref)
+	}
+
+	return &types.VirtualEthernetCardOpaqueNetworkBackingInfo{
+		OpaqueNetworkId:   s.OpaqueNetworkId,
+		OpaqueNetworkType: s.OpaqueNetworkType,
+	}, nil
+}
+
+// Reference returns the network, replacing the plain network that govmomi returns for an opaque
+// network with one that backs network cards correctly
+func Reference(n object.NetworkReference) object.NetworkReference {
+	if n == nil || n.Reference().Type != OpaqueNetworkType {
+		return n
+	}
+
+	if _, ok := n.(*OpaqueNetwork); ok {
+		return n
+	}
+
+	var c *vim25.Client
+	var path string
+	if common, ok := n.(*object.Network); ok {
+		c = common.Client()
+		path = common.InventoryPath
+	}
+
+	opaque := NewOpaqueNetwork(c, n.Reference())
+	opaque.InventoryPath = path
+	return opaque
+}
+
+// IsOpaque returns whether the reference is of an opaque network
+func IsOpaque(ref types.ManagedObjectReference) bool {
+	return ref.Type == OpaqueNetworkType
+}
+
+// SelectByBackingInfo returns the devices of the list with the backing, comparing opaque network
+// backings by network ID, which govmomi does not
+func SelectByBackingInfo(l object.VirtualDeviceList, backing types.BaseVirtualDeviceBackingInfo) object.VirtualDeviceList {
+	b, ok := backing.(*types.VirtualEthernetCardOpaqueNetworkBackingInfo)
+	if !ok {
+		return l.SelectByBackingInfo(backing)
+	}
+
+	return l.Select(func(device types.BaseVirtualDevice) bool {
+		a, ok := device.GetVirtualDevice().Backing.(*types.VirtualEthernetCardOpaqueNetworkBackingInfo)
+		return ok && a.OpaqueNetworkId == b.OpaqueNetworkId && a.OpaqueNetworkType == b.OpaqueNetworkType
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestOpaqueBacking(t *testing.T) {
+	ref := types.ManagedObjectReference{Type: OpaqueNetworkType, Value: "network-o1"}
+
+	backing, err := opaqueBacking(ref, &types.OpaqueNetworkSummary{
+		OpaqueNetworkId:   "a7b2c3d4",
+		OpaqueNetworkType: "nsx.LogicalSwitch",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &types.VirtualEthernetCardOpaqueNetworkBackingInfo{
+		OpaqueNetworkId:   "a7b2c3d4",
+		OpaqueNetworkType: "nsx.LogicalSwitch",
+	}, backing)
+
+	_, err = opaqueBacking(ref, &types.NetworkSummary{})
+	assert.Error(t, err)
+}
+
+func TestReference(t *testing.T) {
+	opaqueRef := types.ManagedObjectReference{Type: OpaqueNetworkType, Value: "network-o1"}
+	n := object.NewNetwork(nil, opaqueRef)
+	n.InventoryPath = "/dc1/network/ls-web"
+
+	r := Reference(n)
+	opaque, ok := r.(*OpaqueNetwork)
+	require.True(t, ok)
+	assert.Equal(t, opaqueRef, opaque.Reference())
+	assert.Equal(t, "/dc1/network/ls-web", opaque.InventoryPath)
+	assert.True(t, IsOpaque(r.Reference()))
+
+	// other networks are returned as they are
+	std := object.NewNetwork(nil, types.ManagedObjectReference{Type: "Network", Value: "network-7"})
+	assert.Equal(t, std, Reference(std))
+
+	dpg := object.NewDistributedVirtualPortgroup(nil, types.ManagedObjectReference{Type: "DistributedVirtualPortgroup", Value: "dvportgroup-9"})
+	assert.Equal(t, dpg, Reference(dpg))
+	assert.False(t, IsOpaque(dpg.Reference()))
+}
+
+func TestSelectByBackingInfo(t *testing.T) {
+	var l object.VirtualDeviceList
+	for _, id := range []string{"ls-1", "ls-2"} {
+		card, err := l.CreateEthernetCard("vmxnet3", &types.VirtualEthernetCardOpaqueNetworkBackingInfo{
+			OpaqueNetworkId:   id,
+			OpaqueNetworkType: "nsx.LogicalSwitch",
+		})
+		require.NoError(t, err)
+		l = append(l, card)
+	}
+	std, err := l.CreateEthernetCard("vmxnet3", &types.VirtualEthernetCardNetworkBackingInfo{
+		VirtualDeviceDeviceBackingInfo: types.VirtualDeviceDeviceBackingInfo{DeviceName: "VM Network"},
+	})
+	require.NoError(t, err)
+	l = append(l, std)
+
+	selected := SelectByBackingInfo(l, &types.VirtualEthernetCardOpaqueNetworkBackingInfo{
+		OpaqueNetworkId:   "ls-2",
+		OpaqueNetworkType: "nsx.LogicalSwitch",
+	})
+	require.Len(t, selected, 1)
+	assert.Equal(t, l[1], selected[0])
+
+	selected = SelectByBackingInfo(l, &types.VirtualEthernetCardNetworkBackingInfo{
+		VirtualDeviceDeviceBackingInfo: types.VirtualDeviceDeviceBackingInfo{DeviceName: "VM Network"},
+	})
+	require.Len(t, selected, 1)
+	assert.Equal(t, std, selected[0])
+}
@@ -46,6 +46,21 @@ import (
 	"github.com/vmware/vic/pkg/vsphere/extraconfig"
 )
 
+// LoginError is returned by Connect when vSphere does not accept the credentials of the session
+type LoginError struct {
+	Host string
+	Err  error
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("Failed to log in to %s: %s", e.Host, e.Err)
+}
+
+// AuthFailure reports that the error is a rejection of the credentials
+func (e *LoginError) AuthFailure() bool {
+	return true
+}
+
 // Config contains the configuration used to create a Session.
 type Config struct {
 	// SDK URL or proxy
@@ -70,6 +85,13 @@ type Config struct {
 
 	// confusingly vSphere calls this the extension key
 	ExtensionName string
+
+	// SAML bearer token issued by the vCenter SSO service, used to log in instead of the
+	// credentials in Service
+	Token string
+	// session ticket acquired with SessionManager.AcquireCloneTicket, used to log in by
+	// cloning the session it was acquired from
+	Ticket string
 }
 
 // HasCertificate checks for presence of a certificate and keyfile
@@ -187,6 +209,25 @@ func (s *Session) Connect(ctx context.Context) (*Session, error) {
 		login = func(ctx context.Context) error {
 			return s.LoginExtensionByCertificate(ctx, s.ExtensionName, "")
 		}
+	} else if s.Token != "" {
+		log.Debugf("Using login by SAML token")
+
+		login = func(ctx context.Context) error {
+			return loginByToken(ctx, s.Vim25(), soapClient, s.Token)
+		}
+	} else if s.Ticket != "" {
+		log.Debugf("Using login by session ticket")
+
+		// a ticket can only be used once, so the session cannot be re-authenticated
+		ticket := s.Ticket
+		login = func(ctx context.Context) error {
+			if ticket == "" {
+				return errors.New("session ticket has already been used")
+			}
+			t := ticket
+			ticket = ""
+			return cloneSession(ctx, s.Vim25(), t)
+		}
 	} else {
 		log.Debugf("Using to login by username/password")
 
@@ -235,7 +276,7 @@ func (s *Session) Connect(ctx context.Context) (*Session, error) {
 
 	err = login(ctx)
 	if err != nil {
-		return nil, errors.Errorf("Failed to log in to %s: %s", soapURL.Host, err)
+		return nil, &LoginError{Host: soapURL.Host, Err: err}
 	}
 
 	s.Finder = find.NewFinder(s.Vim25(), false)
@@ -23,6 +23,8 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
 	"github.com/vmware/vic/pkg/vsphere/simulator"
 	"github.com/vmware/vic/pkg/vsphere/test/env"
 )
@@ -186,3 +188,85 @@ func TestConnect(t *testing.T) {
 		}
 	}
 }
+
+// bearerToken returns a minimal SAML assertion with the given confirmation method and expiry
+func bearerToken(method string, expiry time.Time) string {
+	return `<saml2:Assertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion" ID="_1" Version="2.0">` +
+		`<saml2:Subject><saml2:NameID>admin@vsphere.local</saml2:NameID>` +
+		`<saml2:SubjectConfirmation Method="` + method + `"/></saml2:Subject>` +
+		`<saml2:Conditions NotBefore="2016-01-01T00:00:00Z" NotOnOrAfter="` + expiry.UTC().Format(time.RFC3339) + `"/>` +
+		`</saml2:Assertion>`
+}
+
+func TestCheckToken(t *testing.T) {
+	now := time.Now()
+	bearer := "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+	if err := checkToken(bearerToken(bearer, now.Add(time.Hour)), now); err != nil {
+		t.Errorf("expected valid bearer token: %s", err)
+	}
+	if err := checkToken(bearerToken(bearer, now.Add(-time.Hour)), now); err == nil {
+		t.Error("expected expired token error")
+	}
+	if err := checkToken(bearerToken(holderOfKey, now.Add(time.Hour)), now); err == nil {
+		t.Error("expected holder-of-key token error")
+	}
+	if err := checkToken("not a token", now); err == nil {
+		t.Error("expected token parse error")
+	}
+}
+
+func TestConnectTokenAndTicket(t *testing.T) {
+	ctx := context.Background()
+
+	model := simulator.VPX()
+	defer model.Remove()
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := model.Service.NewServer()
+	defer s.Close()
+
+	config := &Config{
+		Service: s.URL.String(),
+	}
+	session, err := NewSession(config).Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := methods.AcquireCloneTicket(ctx, session.Vim25(), &types.AcquireCloneTicket{
+		This: *session.Vim25().ServiceContent.SessionManager,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket := res.Returnval
+
+	u := *s.URL
+	u.User = nil
+
+	config = &Config{
+		Service: u.String(),
+		Ticket:  ticket,
+	}
+	if _, err = NewSession(config).Connect(ctx); err != nil {
+		t.Errorf("expected login with ticket: %s", err)
+	}
+	if _, err = NewSession(config).Connect(ctx); err == nil {
+		t.Error("expected login error reusing ticket")
+	}
+
+	config = &Config{
+		Service: u.String(),
+		Token:   bearerToken("urn:oasis:names:tc:SAML:2.0:cm:bearer", time.Now().Add(time.Hour)),
+	}
+	if _, err = NewSession(config).Connect(ctx); err != nil {
+		t.Errorf("expected login with token: %s", err)
+	}
+
+	config.Token = bearerToken(holderOfKey, time.Now().Add(time.Hour))
+	if _, err = NewSession(config).Connect(ctx); err == nil {
+		t.Error("expected login error with holder-of-key token")
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"bytes"
+	"context"
+	stdxml "encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vim25/xml"
+	"github.com/vmware/vic/pkg/errors"
+)
+
+const (
+	// holderOfKey is the SAML subject confirmation method of tokens that must be presented in a request
+	// signed with the key of the subject
+	holderOfKey = "urn:oasis:names:tc:SAML:2.0:cm:holder-of-key"
+
+	// tokenRequestLifetime is the validity of the WS-Security timestamp sent with the token
+	tokenRequestLifetime = 5 * time.Minute
+)
+
+// samlAssertion holds the parts of a SAML token checked before it is presented to vSphere
+type samlAssertion struct {
+	XMLName stdxml.Name
+	Subject struct {
+		Confirmation struct {
+			Method string `xml:"Method,attr"`
+		} `xml:"SubjectConfirmation"`
+	} `xml:"Subject"`
+	Conditions struct {
+		NotOnOrAfter string `xml:"NotOnOrAfter,attr"`
+	} `xml:"Conditions"`
+}
+
+// checkToken checks that token is a SAML bearer token that has not expired
+func checkToken(token string, now time.Time) error {
+	var assertion samlAssertion
+	if err := stdxml.Unmarshal([]byte(token), &assertion); err != nil {
+		return errors.Errorf("Unable to parse SAML token: %s", err)
+	}
+	if assertion.XMLName.Local != "Assertion" {
+		return errors.Errorf("SAML token is a %q, not an Assertion", assertion.XMLName.Local)
+	}
+
+	// signing requests with the subject key is not supported
+	if assertion.Subject.Confirmation.Method == holderOfKey {
+		return errors.New("SAML holder-of-key tokens are not supported, a bearer token is required")
+	}
+
+	if expiry := assertion.Conditions.NotOnOrAfter; expiry != "" {
+		t, err := time.Parse(time.RFC3339, expiry)
+		if err != nil {
+			return errors.Errorf("Unable to parse SAML token expiry %q: %s", expiry, err)
+		}
+		if !now.Before(t) {
+			return errors.Errorf("SAML token expired at %s", expiry)
+		}
+	}
+
+	return nil
+}
+
+// tokenEnvelope is a SOAP envelope carrying a SAML token in a WS-Security header
+type tokenEnvelope struct {
+	XMLName xml.Name    `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	Header  tokenHeader `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
+	Body    interface{}
+}
+
+type tokenHeader struct {
+	Security struct {
+		XMLName   xml.Name `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+		Timestamp struct {
+			XMLName xml.Name `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Timestamp"`
+			Created string   `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+			Expires string   `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Expires"`
+		}
+		Token string `xml:",innerxml"`
+	}
+}
+
+// newTokenEnvelope returns the envelope for body, with a header presenting the token
+func newTokenEnvelope(token string, body interface{}, now time.Time) *tokenEnvelope {
+	env := &tokenEnvelope{Body: body}
+
+	security := &env.Header.Security
+	security.Timestamp.Created = now.UTC().Format(time.RFC3339)
+	security.Timestamp.Expires = now.Add(tokenRequestLifetime).UTC().Format(time.RFC3339)
+	security.Token = token
+
+	return env
+}
+
+// tokenRoundTripper sends requests with the SAML token in a WS-Security header, which the soap.Client
+// does not support. Responses are handled as by soap.Client, so that the session cookie is kept by the
+// client for later requests.
+type tokenRoundTripper struct {
+	client *soap.Client
+	token  string
+}
+
+// RoundTrip implements the soap.RoundTripper interface
+func (t *tokenRoundTripper) RoundTrip(ctx context.Context, reqBody, resBody soap.HasFault) error {
+	b, err := xml.Marshal(newTokenEnvelope(t.token, reqBody, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", t.client.URL().String(), bytes.NewReader(append([]byte(xml.Header), b...)))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(`Content-Type`, `text/xml; charset="utf-8"`)
+	req.Header.Set(`SOAPAction`, fmt.Sprintf("%s/%s", t.client.Namespace, t.client.Version))
+	if t.client.UserAgent != "" {
+		req.Header.Set(`User-Agent`, t.client.UserAgent)
+	}
+
+	res, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusInternalServerError:
+		// a fault is returned with an error status
+	default:
+		return errors.New(res.Status)
+	}
+
+	dec := xml.NewDecoder(res.Body)
+	dec.TypeFunc = types.TypeFunc()
+	if err = dec.Decode(&soap.Envelope{Body: resBody}); err != nil {
+		return err
+	}
+
+	if f := resBody.Fault(); f != nil {
+		return soap.WrapSoapFault(f)
+	}
+
+	return nil
+}
+
+// loginByToken logs in to vCenter with a SAML bearer token issued by its SSO service
+func loginByToken(ctx context.Context, client *vim25.Client, soapClient *soap.Client, token string) error {
+	if err := checkToken(token, time.Now()); err != nil {
+		return err
+	}
+
+	req := types.LoginByToken{
+		This: *client.ServiceContent.SessionManager,
+	}
+
+	_, err := methods.LoginByToken(ctx, &tokenRoundTripper{client: soapClient, token: token}, &req)
+	return err
+}
+
+// cloneSession logs in by cloning the session a ticket was acquired from with AcquireCloneTicket. A
+// ticket can only be used once.
+func cloneSession(ctx context.Context, client *vim25.Client, ticket string) error {
+	req := types.CloneSession{
+		This:        *client.ServiceContent.SessionManager,
+		CloneTicket: ticket,
+	}
+
+	_, err := methods.CloneSession(ctx, client, &req)
+	return err
+}
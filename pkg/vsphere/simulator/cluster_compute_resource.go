@@ -81,6 +81,12 @@ func CreateClusterComputeResource(f *Folder, name string, spec types.ClusterConf
 	cluster := &ClusterComputeResource{}
 	cluster.Name = name
 
+	config := &types.ClusterConfigInfoEx{}
+	if spec.DrsConfig != nil {
+		config.DrsConfig = *spec.DrsConfig
+	}
+	cluster.ConfigurationEx = config
+
 	pool := NewResourcePool()
 	Map.PutEntity(cluster, Map.NewEntity(pool))
 	cluster.ResourcePool = &pool.Self
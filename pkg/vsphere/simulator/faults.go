@@ -0,0 +1,138 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Faults simulates a slow or flaky network between clients and the SOAP endpoint of a Server, so that
+// session keepalive, retry and reconnect code paths can be exercised. Faults are only injected into
+// requests made over HTTP, not into those made with the in-process RoundTrip client.
+type Faults struct {
+	// Latency is added before every request is handled
+	Latency time.Duration
+	// Jitter adds a random latency of up to Jitter on top of Latency
+	Jitter time.Duration
+
+	// ResetRate is the fraction of requests, from 0 to 1, whose connection is closed without a response
+	ResetRate float64
+	// UnavailableRate is the fraction of requests, from 0 to 1, answered with 503 Service Unavailable
+	UnavailableRate float64
+
+	// Methods limits faults to the named SOAP methods, e.g. RetrieveProperties; all methods if empty
+	Methods []string
+
+	// Seed seeds the random source deciding which requests fail, for reproducible runs.
+	// The current time is used if zero.
+	Seed int64
+}
+
+// faultInjector applies Faults to the requests of a Service
+type faultInjector struct {
+	mu sync.Mutex
+
+	faults  Faults
+	methods map[string]bool
+	rand    *rand.Rand
+}
+
+func newFaultInjector(f Faults) *faultInjector {
+	seed := f.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	fi := &faultInjector{
+		faults: f,
+		rand:   rand.New(rand.NewSource(seed)),
+	}
+
+	if len(f.Methods) > 0 {
+		fi.methods = make(map[string]bool)
+		for _, m := range f.Methods {
+			fi.methods[m] = true
+		}
+	}
+
+	return fi
+}
+
+// SetFaults sets the faults injected into subsequent requests to the Service, nil to stop injecting faults.
+func (s *Service) SetFaults(f *Faults) {
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+
+	if f == nil {
+		s.faults = nil
+		return
+	}
+
+	s.faults = newFaultInjector(*f)
+}
+
+// inject applies the configured faults to the request for method, returning true if the request
+// must not be handled as a fault was injected in its place.
+func (s *Service) inject(w http.ResponseWriter, r *http.Request, method string) bool {
+	s.fmu.Lock()
+	fi := s.faults
+	s.fmu.Unlock()
+
+	if fi == nil || (fi.methods != nil && !fi.methods[method]) {
+		return false
+	}
+
+	fi.mu.Lock()
+	delay := fi.faults.Latency
+	if fi.faults.Jitter > 0 {
+		delay += time.Duration(fi.rand.Int63n(int64(fi.faults.Jitter)))
+	}
+	p := fi.rand.Float64()
+	fi.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+
+	switch {
+	case p < fi.faults.ResetRate:
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			break
+		}
+
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			log.Printf("error hijacking connection to reset %s: %s", method, err)
+			break
+		}
+
+		_ = conn.Close()
+		return true
+	case p < fi.faults.ResetRate+fi.faults.UnavailableRate:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/vic/pkg/vsphere/simulator/esx"
+)
+
+func TestFaults(t *testing.T) {
+	s := New(NewServiceInstance(esx.ServiceContent, esx.RootFolder))
+	ts := s.NewServer()
+	defer ts.Close()
+
+	ctx := context.Background()
+	client, err := govmomi.NewClient(ctx, ts.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// latency
+	s.SetFaults(&Faults{Latency: 50 * time.Millisecond})
+	start := time.Now()
+	if _, err = methods.GetCurrentTime(ctx, client); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Errorf("expected a latency of at least 50ms, got %s", time.Since(start))
+	}
+
+	// latency is cut short by the client giving up
+	s.SetFaults(&Faults{Latency: time.Minute})
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	_, err = methods.GetCurrentTime(tctx, client)
+	cancel()
+	if err == nil {
+		t.Error("expected timeout error")
+	}
+
+	// connection resets and 503s
+	for _, f := range []Faults{{ResetRate: 1}, {UnavailableRate: 1}} {
+		s.SetFaults(&f)
+		if _, err = methods.GetCurrentTime(ctx, client); err == nil {
+			t.Errorf("expected error with %+v", f)
+		}
+	}
+
+	// faults limited to other methods
+	s.SetFaults(&Faults{UnavailableRate: 1, Methods: []string{"RetrieveProperties"}})
+	if _, err = methods.GetCurrentTime(ctx, client); err != nil {
+		t.Error(err)
+	}
+
+	// a seeded source fails the same requests on every run
+	run := func() []bool {
+		s.SetFaults(&Faults{UnavailableRate: 0.5, Seed: 42})
+
+		var failed []bool
+		for i := 0; i < 20; i++ {
+			_, err := methods.GetCurrentTime(ctx, client)
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("request %d: failed=%t then failed=%t with the same seed", i, first[i], second[i])
+		}
+	}
+
+	// and the client recovers once faults are cleared
+	s.SetFaults(nil)
+	if _, err = methods.GetCurrentTime(ctx, client); err != nil {
+		t.Error(err)
+	}
+}
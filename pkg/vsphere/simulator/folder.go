@@ -40,6 +40,13 @@ func (f *Folder) putChild(o mo.Entity) {
 	f.ChildEntity = append(f.ChildEntity, o.Reference())
 }
 
+func (f *Folder) removeChild(ref types.ManagedObjectReference) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.ChildEntity = RemoveReference(ref, f.ChildEntity)
+}
+
 func (f *Folder) hasChildType(kind string) bool {
 	for _, t := range f.ChildType {
 		if t == kind {
@@ -72,6 +72,18 @@ func (dss *HostDatastoreSystem) add(ds *Datastore) *soap.Fault {
 	folder := Map.Get(Map.getEntityDatacenter(dss.Host).DatastoreFolder).(*Folder)
 	folder.putChild(ds)
 
+	// the datastore is available to the compute resource of the host
+	var crds *[]types.ManagedObjectReference
+	switch cr := Map.getEntityComputeResource(dss.Host).(type) {
+	case *mo.ComputeResource:
+		crds = &cr.Datastore
+	case *ClusterComputeResource:
+		crds = &cr.Datastore
+	}
+	if crds != nil && Map.FindByName(ds.Name, *crds) == nil {
+		*crds = append(*crds, ds.Self)
+	}
+
 	return nil
 }
 
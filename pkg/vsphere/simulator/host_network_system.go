@@ -0,0 +1,154 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+type HostNetworkSystem struct {
+	mo.HostNetworkSystem
+
+	Host *mo.HostSystem
+}
+
+func NewHostNetworkSystem(host *mo.HostSystem) *HostNetworkSystem {
+	return &HostNetworkSystem{
+		HostNetworkSystem: mo.HostNetworkSystem{
+			NetworkInfo: &types.HostNetworkInfo{},
+		},
+		Host: host,
+	}
+}
+
+func (s *HostNetworkSystem) findVirtualSwitch(name string) int {
+	for i, v := range s.NetworkInfo.Vswitch {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *HostNetworkSystem) findPortGroup(name string) int {
+	for i, p := range s.NetworkInfo.Portgroup {
+		if p.Spec.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *HostNetworkSystem) AddVirtualSwitch(c *types.AddVirtualSwitch) soap.HasFault {
+	r := &methods.AddVirtualSwitchBody{}
+
+	if s.findVirtualSwitch(c.VswitchName) >= 0 {
+		r.Fault_ = Fault("", &types.AlreadyExists{Name: c.VswitchName})
+		return r
+	}
+
+	sw := types.HostVirtualSwitch{
+		Name: c.VswitchName,
+		Key:  "key-vim.host.VirtualSwitch-" + c.VswitchName,
+	}
+	if c.Spec != nil {
+		sw.NumPorts = c.Spec.NumPorts
+		sw.Spec = *c.Spec
+	}
+
+	s.NetworkInfo.Vswitch = append(s.NetworkInfo.Vswitch, sw)
+
+	r.Res = &types.AddVirtualSwitchResponse{}
+	return r
+}
+
+func (s *HostNetworkSystem) RemoveVirtualSwitch(c *types.RemoveVirtualSwitch) soap.HasFault {
+	r := &methods.RemoveVirtualSwitchBody{}
+
+	i := s.findVirtualSwitch(c.VswitchName)
+	if i < 0 {
+		r.Fault_ = Fault("", &types.NotFound{})
+		return r
+	}
+
+	for _, p := range s.NetworkInfo.Portgroup {
+		if p.Spec.VswitchName == c.VswitchName {
+			r.Fault_ = Fault("", &types.ResourceInUse{Name: c.VswitchName})
+			return r
+		}
+	}
+
+	s.NetworkInfo.Vswitch = append(s.NetworkInfo.Vswitch[:i], s.NetworkInfo.Vswitch[i+1:]...)
+
+	r.Res = &types.RemoveVirtualSwitchResponse{}
+	return r
+}
+
+// AddPortGroup adds the port group to its virtual switch, and the Network of the port group to the inventory
+func (s *HostNetworkSystem) AddPortGroup(c *types.AddPortGroup) soap.HasFault {
+	r := &methods.AddPortGroupBody{}
+
+	if s.findPortGroup(c.Portgrp.Name) >= 0 {
+		r.Fault_ = Fault("", &types.AlreadyExists{Name: c.Portgrp.Name})
+		return r
+	}
+	if s.findVirtualSwitch(c.Portgrp.VswitchName) < 0 {
+		r.Fault_ = Fault("", &types.NotFound{})
+		return r
+	}
+
+	network := &mo.Network{}
+	network.Name = c.Portgrp.Name
+	network.Host = append(network.Host, s.Host.Self)
+
+	folder := Map.Get(Map.getEntityDatacenter(s.Host).NetworkFolder).(*Folder)
+	folder.putChild(network)
+	s.Host.Network = append(s.Host.Network, network.Self)
+
+	s.NetworkInfo.Portgroup = append(s.NetworkInfo.Portgroup, types.HostPortGroup{
+		Key:  "key-vim.host.PortGroup-" + c.Portgrp.Name,
+		Spec: c.Portgrp,
+	})
+
+	r.Res = &types.AddPortGroupResponse{}
+	return r
+}
+
+// RemovePortGroup removes the port group from its virtual switch, and the Network of the port group
+// from the inventory
+func (s *HostNetworkSystem) RemovePortGroup(c *types.RemovePortGroup) soap.HasFault {
+	r := &methods.RemovePortGroupBody{}
+
+	i := s.findPortGroup(c.PgName)
+	if i < 0 {
+		r.Fault_ = Fault("", &types.NotFound{})
+		return r
+	}
+
+	folder := Map.Get(Map.getEntityDatacenter(s.Host).NetworkFolder).(*Folder)
+	if e := Map.FindByName(c.PgName, folder.ChildEntity); e != nil {
+		folder.removeChild(e.Reference())
+		s.Host.Network = RemoveReference(e.Reference(), s.Host.Network)
+		Map.Remove(e.Reference())
+	}
+
+	s.NetworkInfo.Portgroup = append(s.NetworkInfo.Portgroup[:i], s.NetworkInfo.Portgroup[i+1:]...)
+
+	r.Res = &types.RemovePortGroupResponse{}
+	return r
+}
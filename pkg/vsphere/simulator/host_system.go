@@ -43,6 +43,7 @@ func NewHostSystem(host mo.HostSystem) *HostSystem {
 		obj mo.Reference
 	}{
 		{&hs.ConfigManager.DatastoreSystem, &HostDatastoreSystem{Host: &hs.HostSystem}},
+		{&hs.ConfigManager.NetworkSystem, NewHostNetworkSystem(&hs.HostSystem)},
 	}
 
 	for _, c := range config {
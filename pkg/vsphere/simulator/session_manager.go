@@ -30,10 +30,12 @@ type SessionManager struct {
 	mo.SessionManager
 
 	ServiceHostName string
+
+	tickets map[string]bool
 }
 
 func NewSessionManager(ref types.ManagedObjectReference) object.Reference {
-	s := &SessionManager{}
+	s := &SessionManager{tickets: make(map[string]bool)}
 	s.Self = ref
 	return s
 }
@@ -70,3 +72,47 @@ func (s *SessionManager) AcquireGenericServiceTicket(ticket *types.AcquireGeneri
 		},
 	}
 }
+
+// LoginByToken accepts any token, as the simulator does not see the WS-Security header carrying it
+func (s *SessionManager) LoginByToken(*types.LoginByToken) soap.HasFault {
+	return &methods.LoginByTokenBody{
+		Res: &types.LoginByTokenResponse{
+			Returnval: types.UserSession{
+				UserName:  "token",
+				FullName:  "token",
+				LoginTime: time.Now(),
+			},
+		},
+	}
+}
+
+func (s *SessionManager) AcquireCloneTicket(*types.AcquireCloneTicket) soap.HasFault {
+	ticket := uuid.New().String()
+	s.tickets[ticket] = true
+
+	return &methods.AcquireCloneTicketBody{
+		Res: &types.AcquireCloneTicketResponse{
+			Returnval: ticket,
+		},
+	}
+}
+
+// CloneSession accepts a ticket from AcquireCloneTicket once
+func (s *SessionManager) CloneSession(clone *types.CloneSession) soap.HasFault {
+	body := &methods.CloneSessionBody{}
+
+	if !s.tickets[clone.CloneTicket] {
+		body.Fault_ = Fault("Login failure", &types.InvalidLogin{})
+	} else {
+		delete(s.tickets, clone.CloneTicket)
+		body.Res = &types.CloneSessionResponse{
+			Returnval: types.UserSession{
+				UserName:  "clone",
+				FullName:  "clone",
+				LoginTime: time.Now(),
+			},
+		}
+	}
+
+	return body
+}
@@ -32,6 +32,7 @@ import (
 	"path"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
@@ -55,6 +56,9 @@ type Service struct {
 	readAll func(io.Reader) ([]byte, error)
 
 	TLS *tls.Config
+
+	fmu    sync.Mutex
+	faults *faultInjector
 }
 
 // Server provides a simulator Service over HTTP
@@ -176,6 +180,10 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		res = serverFault(err.Error())
 	} else {
+		if s.inject(w, r, method.Name) {
+			return
+		}
+
 		res = s.call(method)
 	}
 
@@ -39,6 +39,16 @@ type VirtualMachine struct {
 	log *log.Logger
 }
 
+// guestHook runs in place of the guest of a VM when it powers on
+var guestHook func(vm *VirtualMachine)
+
+// SetGuestHook sets the function run whenever a VM powers on, standing in for its guest. The hook can
+// report what a guest would through the guestinfo keys of the VM, using SetExtraConfig. A nil hook
+// removes the current one.
+func SetGuestHook(hook func(vm *VirtualMachine)) {
+	guestHook = hook
+}
+
 func NewVirtualMachine(spec *types.VirtualMachineConfigSpec) (*VirtualMachine, types.BaseMethodFault) {
 	vm := &VirtualMachine{}
 
@@ -134,11 +144,51 @@ func (vm *VirtualMachine) configure(spec *types.VirtualMachineConfigSpec) types.
 		vm.Summary.Config.NumCpu = vm.Config.Hardware.NumCPU
 	}
 
+	if spec.CpuAllocation != nil {
+		vm.Config.CpuAllocation = spec.CpuAllocation
+	}
+
+	if spec.MemoryAllocation != nil {
+		vm.Config.MemoryAllocation = spec.MemoryAllocation
+	}
+
+	if spec.CpuHotAddEnabled != nil {
+		vm.Config.CpuHotAddEnabled = spec.CpuHotAddEnabled
+	}
+
+	if spec.MemoryHotAddEnabled != nil {
+		vm.Config.MemoryHotAddEnabled = spec.MemoryHotAddEnabled
+	}
+
+	vm.SetExtraConfig(spec.ExtraConfig)
+
 	vm.Config.Modified = time.Now()
 
 	return nil
 }
 
+// SetExtraConfig updates the ExtraConfig of the VM with the given options. As with vSphere, an option with
+// an empty value removes the key.
+func (vm *VirtualMachine) SetExtraConfig(options []types.BaseOptionValue) {
+	for _, o := range options {
+		opt := o.GetOptionValue()
+		value, _ := opt.Value.(string)
+
+		var config []types.BaseOptionValue
+		for _, c := range vm.Config.ExtraConfig {
+			if c.GetOptionValue().Key != opt.Key {
+				config = append(config, c)
+			}
+		}
+
+		if value != "" {
+			config = append(config, &types.OptionValue{Key: opt.Key, Value: value})
+		}
+
+		vm.Config.ExtraConfig = config
+	}
+}
+
 func (vm *VirtualMachine) useDatastore(name string) *Datastore {
 	host := Map.Get(*vm.Runtime.Host).(*HostSystem)
 
@@ -228,17 +278,58 @@ func (vm *VirtualMachine) create(spec *types.VirtualMachineConfigSpec) types.Bas
 func (vm *VirtualMachine) configureDevices(spec *types.VirtualMachineConfigSpec) types.BaseMethodFault {
 	devices := object.VirtualDeviceList(vm.Config.Hardware.Device)
 
+	// as with vSphere, devices added with a negative key are given a new one, updating the devices
+	// of the spec that refer to them as their controller
+	keys := make(map[int32]int32)
+	next := int32(1000)
+	for _, d := range devices {
+		if key := d.GetVirtualDevice().Key; key >= next {
+			next = key + 1
+		}
+	}
+	for _, change := range spec.DeviceChange {
+		dspec := change.GetVirtualDeviceConfigSpec()
+		device := dspec.Device.GetVirtualDevice()
+		if dspec.Operation == types.VirtualDeviceConfigSpecOperationAdd && device.Key < 0 {
+			keys[device.Key] = next
+			device.Key = next
+			next++
+		}
+	}
+
 	for i, change := range spec.DeviceChange {
 		dspec := change.GetVirtualDeviceConfigSpec()
 		device := dspec.Device.GetVirtualDevice()
 		invalid := &types.InvalidDeviceSpec{DeviceIndex: int32(i)}
 
+		if key, ok := keys[device.ControllerKey]; ok {
+			device.ControllerKey = key
+		}
+		if c, ok := dspec.Device.(types.BaseVirtualController); ok {
+			children := c.GetVirtualController().Device
+			for j := range children {
+				if key, ok := keys[children[j]]; ok {
+					children[j] = key
+				}
+			}
+		}
+
 		switch dspec.Operation {
 		case types.VirtualDeviceConfigSpecOperationAdd:
 			if devices.FindByKey(device.Key) != nil {
 				return invalid
 			}
-			devices = append(devices, device)
+			devices = append(devices, dspec.Device)
+		case types.VirtualDeviceConfigSpecOperationEdit, types.VirtualDeviceConfigSpecOperationRemove:
+			if devices.FindByKey(device.Key) == nil {
+				return invalid
+			}
+			devices = devices.Select(func(d types.BaseVirtualDevice) bool {
+				return d.GetVirtualDevice().Key != device.Key
+			})
+			if dspec.Operation == types.VirtualDeviceConfigSpecOperationEdit {
+				devices = append(devices, dspec.Device)
+			}
 		}
 	}
 
@@ -271,6 +362,10 @@ func (c *powerVMTask) Run(task *Task) (types.AnyType, types.BaseMethodFault) {
 	if c.state == types.VirtualMachinePowerStatePoweredOn {
 		now := time.Now()
 		*bt = &now
+
+		if guestHook != nil {
+			guestHook(c.VirtualMachine)
+		}
 	} else {
 		*bt = nil
 	}
@@ -319,6 +414,16 @@ func (c *destroyVMTask) Run(task *Task) (types.AnyType, types.BaseMethodFault) {
 	}
 
 	// TODO: remove references from HostSystem and Datastore
+	if c.Parent != nil {
+		if f, ok := Map.Get(*c.Parent).(*Folder); ok {
+			f.removeChild(c.Reference())
+		}
+	}
+	if c.ResourcePool != nil {
+		if rp, ok := Map.Get(*c.ResourcePool).(*ResourcePool); ok {
+			rp.Vm = RemoveReference(c.Reference(), rp.Vm)
+		}
+	}
 	Map.Remove(c.Reference())
 
 	return nil, nil
@@ -337,3 +442,32 @@ func (vm *VirtualMachine) DestroyTask(c *types.Destroy_Task) soap.HasFault {
 
 	return r
 }
+
+type reconfigVMTask struct {
+	*VirtualMachine
+
+	req *types.ReconfigVM_Task
+}
+
+func (c *reconfigVMTask) Run(task *Task) (types.AnyType, types.BaseMethodFault) {
+	spec := c.req.Spec
+	if spec.Files == nil {
+		spec.Files = new(types.VirtualMachineFileInfo)
+	}
+
+	return nil, c.VirtualMachine.configure(&spec)
+}
+
+func (vm *VirtualMachine) ReconfigVMTask(c *types.ReconfigVM_Task) soap.HasFault {
+	r := &methods.ReconfigVM_TaskBody{}
+
+	task := NewTask(&reconfigVMTask{vm, c})
+
+	r.Res = &types.ReconfigVM_TaskResponse{
+		Returnval: task.Self,
+	}
+
+	task.Run()
+
+	return r
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vmware/govmomi/task"
+	"github.com/vmware/govmomi/vim25/progress"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// FakeResult is a single outcome reported by a FakeTask
+type FakeResult struct {
+	// State is the state of the task, defaulting to success if neither Fault nor Err is set
+	State types.TaskInfoState
+	// Fault is reported as the task error, in the same form as a failed vSphere task
+	Fault types.BaseMethodFault
+	// Result is the task result, e.g. the reference of a created object
+	Result types.AnyType
+	// Err is returned without task info, e.g. to simulate a connection failure
+	Err error
+}
+
+// FakeSuccess returns a result for a task that completed with the given result
+func FakeSuccess(result types.AnyType) FakeResult {
+	return FakeResult{State: types.TaskInfoStateSuccess, Result: result}
+}
+
+// FakeFault returns a result for a task that failed with the given fault
+func FakeFault(fault types.BaseMethodFault) FakeResult {
+	return FakeResult{State: types.TaskInfoStateError, Fault: fault}
+}
+
+// FakeError returns a result for a wait that failed without the task reporting
+func FakeError(err error) FakeResult {
+	return FakeResult{Err: err}
+}
+
+// FakeTask is a Task that reports a configured sequence of results, one per wait, so that
+// WaitForResult and its callers can be tested without vSphere or the simulator. The last
+// result is repeated once the sequence is exhausted.
+type FakeTask struct {
+	m sync.Mutex
+
+	results []FakeResult
+
+	// Invocations counts calls to Invoke
+	Invocations int
+	// Waits counts calls to Wait or WaitForResult
+	Waits int
+}
+
+// NewFakeTask returns a FakeTask that reports the given results in order
+func NewFakeTask(results ...FakeResult) *FakeTask {
+	if len(results) == 0 {
+		results = []FakeResult{FakeSuccess(nil)}
+	}
+
+	return &FakeTask{results: results}
+}
+
+// Invoke is suitable for passing to Wait and WaitForResult as the task creation function.
+// The same task is returned on each call so a retried operation continues the sequence.
+func (t *FakeTask) Invoke(ctx context.Context) (Task, error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.Invocations++
+	return t, nil
+}
+
+// Wait reports the next result in the sequence
+func (t *FakeTask) Wait(ctx context.Context) error {
+	_, err := t.WaitForResult(ctx, nil)
+	return err
+}
+
+// WaitForResult reports the next result in the sequence, in the same form as object.Task
+func (t *FakeTask) WaitForResult(ctx context.Context, s progress.Sinker) (*types.TaskInfo, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	t.m.Lock()
+	r := t.results[0]
+	if len(t.results) > 1 {
+		t.results = t.results[1:]
+	}
+	t.Waits++
+	t.m.Unlock()
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	info := &types.TaskInfo{
+		State:  r.State,
+		Result: r.Result,
+	}
+
+	if r.Fault != nil {
+		info.State = types.TaskInfoStateError
+		info.Error = &types.LocalizedMethodFault{
+			Fault:            r.Fault,
+			LocalizedMessage: "fake task fault",
+		}
+		return info, task.Error{LocalizedMethodFault: info.Error}
+	}
+
+	if info.State == "" {
+		info.State = types.TaskInfoStateSuccess
+	}
+
+	return info, TaskError(info)
+}
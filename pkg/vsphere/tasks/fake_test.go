@@ -0,0 +1,73 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestFakeTaskRetry(t *testing.T) {
+	ref := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"}
+	ft := NewFakeTask(
+		FakeFault(&types.TaskInProgress{}),
+		FakeFault(&types.TaskInProgress{}),
+		FakeSuccess(ref),
+	)
+
+	info, err := WaitForResult(context.Background(), ft.Invoke)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, ref, info.Result)
+	assert.Equal(t, 3, ft.Invocations)
+	assert.Equal(t, 3, ft.Waits)
+}
+
+func TestFakeTaskFault(t *testing.T) {
+	ft := NewFakeTask(FakeFault(&types.InvalidState{}))
+
+	info, err := WaitForResult(context.Background(), ft.Invoke)
+	assert.True(t, IsFault(err, &types.InvalidState{}))
+	assert.Equal(t, types.TaskInfoStateError, info.State)
+
+	// faults other than TaskInProgress are not retried
+	assert.Equal(t, 1, ft.Invocations)
+}
+
+func TestFakeTaskError(t *testing.T) {
+	ft := NewFakeTask(FakeError(errors.New("connection reset")))
+
+	err := Wait(context.Background(), ft.Invoke)
+	assert.EqualError(t, err, "connection reset")
+	assert.Nil(t, Fault(err))
+}
+
+func TestFakeTaskCancel(t *testing.T) {
+	ft := NewFakeTask(FakeFault(&types.TaskInProgress{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := WaitForResult(ctx, ft.Invoke)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, ft.Waits)
+}
@@ -0,0 +1,115 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"reflect"
+
+	"github.com/vmware/govmomi/task"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Fault returns the vSphere fault carried by err, or nil if err does not carry one.
+// The fault is normalized to its pointer form (e.g. *types.TaskInProgress) regardless
+// of whether it arrived as a SOAP fault, a VIM fault or a task error, so callers can
+// branch on the fault type instead of the message text, which vCenter localizes.
+func Fault(err error) types.BaseMethodFault {
+	if err == nil {
+		return nil
+	}
+
+	switch e := err.(type) {
+	case task.Error:
+		return toBaseMethodFault(e.Fault())
+	case *task.Error:
+		return toBaseMethodFault(e.Fault())
+	}
+
+	if soap.IsSoapFault(err) {
+		return toBaseMethodFault(soap.ToSoapFault(err).VimFault())
+	}
+
+	if soap.IsVimFault(err) {
+		return toBaseMethodFault(soap.ToVimFault(err))
+	}
+
+	if f, ok := err.(types.HasFault); ok {
+		return toBaseMethodFault(f.Fault())
+	}
+
+	return nil
+}
+
+// IsFault returns true if err carries a vSphere fault of the same type as target,
+// e.g. IsFault(err, &types.InvalidState{}).
+func IsFault(err error, target types.BaseMethodFault) bool {
+	f := Fault(err)
+	if f == nil || target == nil {
+		return false
+	}
+
+	return reflect.TypeOf(f) == reflect.TypeOf(toBaseMethodFault(target))
+}
+
+// TaskError returns an error for a task that did not complete successfully, or nil
+// if it did. The returned error preserves both the fault, for use with Fault and
+// IsFault, and the localized message reported by vSphere, for display.
+func TaskError(info *types.TaskInfo) error {
+	if info == nil {
+		return nil
+	}
+
+	if info.Error != nil {
+		return task.Error{LocalizedMethodFault: info.Error}
+	}
+
+	if info.State == types.TaskInfoStateError {
+		return task.Error{
+			LocalizedMethodFault: &types.LocalizedMethodFault{
+				Fault:            &types.RuntimeFault{},
+				LocalizedMessage: "task failed without reporting a fault",
+			},
+		}
+	}
+
+	return nil
+}
+
+// toBaseMethodFault converts a fault value, as decoded from a SOAP fault detail,
+// into its pointer form so that all faults can be compared by type.
+func toBaseMethodFault(f interface{}) types.BaseMethodFault {
+	if f == nil {
+		return nil
+	}
+
+	if bf, ok := f.(types.BaseMethodFault); ok {
+		return bf
+	}
+
+	v := reflect.ValueOf(f)
+	if v.Kind() == reflect.Ptr {
+		return nil
+	}
+
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+
+	if bf, ok := p.Interface().(types.BaseMethodFault); ok {
+		return bf
+	}
+
+	return nil
+}
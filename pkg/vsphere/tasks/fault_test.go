@@ -0,0 +1,87 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/task"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestFault(t *testing.T) {
+	// a localized message that does not match the english text
+	msg := "Der Vorgang ist im aktuellen Zustand nicht zulässig."
+
+	errs := []error{
+		task.Error{
+			LocalizedMethodFault: &types.LocalizedMethodFault{
+				Fault:            &types.InvalidState{},
+				LocalizedMessage: msg,
+			},
+		},
+		&task.Error{
+			LocalizedMethodFault: &types.LocalizedMethodFault{
+				Fault:            &types.InvalidState{},
+				LocalizedMessage: msg,
+			},
+		},
+		soap.WrapVimFault(&types.InvalidState{}),
+		soap.WrapSoapFault(&soap.Fault{
+			String: msg,
+			Detail: struct {
+				Fault types.AnyType `xml:",any,typeattr"`
+			}{
+				Fault: types.InvalidState{},
+			},
+		}),
+	}
+
+	for _, err := range errs {
+		assert.IsType(t, &types.InvalidState{}, Fault(err))
+		assert.True(t, IsFault(err, &types.InvalidState{}))
+		assert.False(t, IsFault(err, &types.TaskInProgress{}))
+	}
+
+	assert.Nil(t, Fault(nil))
+	assert.Nil(t, Fault(assert.AnError))
+	assert.False(t, IsFault(assert.AnError, &types.InvalidState{}))
+}
+
+func TestTaskError(t *testing.T) {
+	assert.NoError(t, TaskError(nil))
+	assert.NoError(t, TaskError(&types.TaskInfo{State: types.TaskInfoStateSuccess}))
+
+	msg := "Ressource ist ausgelastet"
+	err := TaskError(&types.TaskInfo{
+		State: types.TaskInfoStateError,
+		Error: &types.LocalizedMethodFault{
+			Fault:            &types.TaskInProgress{},
+			LocalizedMessage: msg,
+		},
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, msg, err.Error())
+	assert.True(t, IsFault(err, &types.TaskInProgress{}))
+	assert.True(t, isTaskInProgress(err))
+
+	// error state with no fault still surfaces as an error
+	err = TaskError(&types.TaskInfo{State: types.TaskInfoStateError})
+	assert.Error(t, err)
+}
@@ -22,10 +22,10 @@ import (
 	"time"
 
 	log "github.com/Sirupsen/logrus"
-	"github.com/vmware/govmomi/task"
 	"github.com/vmware/govmomi/vim25/progress"
-	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware/vic/pkg/trace"
 )
 
 const (
@@ -56,6 +56,8 @@ func Wait(ctx context.Context, f func(context.Context) (Task, error)) error {
 //       return vm, vm.Reconfigure(ctx, config)
 //    })
 func WaitForResult(ctx context.Context, f func(context.Context) (Task, error)) (*types.TaskInfo, error) {
+	defer trace.End(trace.Begin(""))
+
 	var err error
 	var info *types.TaskInfo
 	var backoffFactor int64 = 1
@@ -89,36 +91,13 @@ func WaitForResult(ctx context.Context, f func(context.Context) (Task, error)) (
 }
 
 func isTaskInProgress(err error) bool {
-	if soap.IsSoapFault(err) {
-		switch f := soap.ToSoapFault(err).VimFault().(type) {
-		case types.TaskInProgress:
-			return true
-		default:
-			logSoapFault(f)
-		}
-	}
-
-	if soap.IsVimFault(err) {
-		switch f := soap.ToVimFault(err).(type) {
-		case *types.TaskInProgress:
-			return true
-		default:
-			logFault(f)
-		}
-	}
-
-	switch err := err.(type) {
-	case task.Error:
-		if _, ok := err.Fault().(*types.TaskInProgress); ok {
-			return true
-		}
-		logFault(err.Fault())
+	switch f := Fault(err).(type) {
+	case *types.TaskInProgress:
+		return true
+	case nil:
+		logError(err)
 	default:
-		if f, ok := err.(types.HasFault); ok {
-			logFault(f.Fault())
-		} else {
-			logError(err)
-		}
+		logFault(f)
 	}
 	return false
 }
@@ -128,10 +107,6 @@ func logFault(fault types.BaseMethodFault) {
 	log.Debugf("unexpected fault on task retry : %#v", fault)
 }
 
-func logSoapFault(fault types.AnyType) {
-	log.Debugf("unexpected soap fault on task retry : %#v", fault)
-}
-
 func logError(err error) {
 	log.Debugf("unexpected error on task retry : %#v", err)
 }
@@ -0,0 +1,83 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// ToolsStatus describes the guest tools (VMware Tools or open-vm-tools) of a VM. The
+// addresses of a VM and guest operations are only available while its tools are running.
+type ToolsStatus struct {
+	RunningStatus string `json:"running_status"`
+	VersionStatus string `json:"version_status"`
+	Version       string `json:"version,omitempty"`
+}
+
+// NewToolsStatus returns the tools status reported in the guest info, which is nil if the
+// VM has never reported on its guest
+func NewToolsStatus(guest *types.GuestInfo) *ToolsStatus {
+	if guest == nil {
+		return &ToolsStatus{
+			RunningStatus: string(types.VirtualMachineToolsRunningStatusGuestToolsNotRunning),
+			VersionStatus: string(types.VirtualMachineToolsVersionStatusGuestToolsNotInstalled),
+		}
+	}
+
+	status := &ToolsStatus{
+		RunningStatus: guest.ToolsRunningStatus,
+		VersionStatus: guest.ToolsVersionStatus2,
+		Version:       guest.ToolsVersion,
+	}
+	if status.RunningStatus == "" {
+		status.RunningStatus = string(types.VirtualMachineToolsRunningStatusGuestToolsNotRunning)
+	}
+	if status.Version == "0" {
+		status.Version = ""
+	}
+	return status
+}
+
+// Running returns whether the tools are running in the guest
+func (t *ToolsStatus) Running() bool {
+	return t.RunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsNotRunning)
+}
+
+func (t *ToolsStatus) String() string {
+	running := "not running"
+	if t.Running() {
+		running = "running"
+	}
+
+	if t.Version == "" {
+		return fmt.Sprintf("%s (%s)", running, t.VersionStatus)
+	}
+	return fmt.Sprintf("%s, version %s (%s)", running, t.Version, t.VersionStatus)
+}
+
+// ToolsStatus returns the status of the guest tools of the VM
+func (vm *VirtualMachine) ToolsStatus(ctx context.Context) (*ToolsStatus, error) {
+	var mvm mo.VirtualMachine
+
+	if err := vm.Properties(ctx, vm.Reference(), []string{"guest"}, &mvm); err != nil {
+		return nil, err
+	}
+
+	return NewToolsStatus(mvm.Guest), nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestToolsStatus(t *testing.T) {
+	status := NewToolsStatus(nil)
+	assert.False(t, status.Running())
+	assert.Equal(t, "not running (guestToolsNotInstalled)", status.String())
+
+	status = NewToolsStatus(&types.GuestInfo{
+		ToolsRunningStatus:  string(types.VirtualMachineToolsRunningStatusGuestToolsRunning),
+		ToolsVersionStatus2: string(types.VirtualMachineToolsVersionStatusGuestToolsUnmanaged),
+		ToolsVersion:        "10272",
+	})
+	assert.True(t, status.Running())
+	assert.Equal(t, "running, version 10272 (guestToolsUnmanaged)", status.String())
+
+	status = NewToolsStatus(&types.GuestInfo{
+		ToolsVersionStatus2: string(types.VirtualMachineToolsVersionStatusGuestToolsUnmanaged),
+		ToolsVersion:        "0",
+	})
+	assert.False(t, status.Running())
+	assert.Empty(t, status.Version)
+}
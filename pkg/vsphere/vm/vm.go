@@ -466,7 +466,7 @@ func (vm *VirtualMachine) FixInvalidState(ctx context.Context) error {
 		return err
 	}
 
-	properties := []string{"config.files", "summary.config", "summary.runtime", "resourcePool", "parentVApp"}
+	properties := []string{"config.files", "summary.config", "summary.runtime", "resourcePool", "parentVApp", "parent"}
 	log.Debugf("Get vm properties %s", properties)
 	var mvm mo.VirtualMachine
 	if err = vm.Properties(ctx, vm.Reference(), properties, &mvm); err != nil {
@@ -481,7 +481,13 @@ func (vm *VirtualMachine) FixInvalidState(ctx context.Context) error {
 		return err
 	}
 
-	task, err := vm.registerVM(ctx, mvm.Config.Files.VmPathName, name, mvm.ParentVApp, mvm.ResourcePool, mvm.Summary.Runtime.Host, folders.VmFolder)
+	// register back in the same folder so that VMs placed in a specific folder stay there
+	folder := folders.VmFolder
+	if mvm.Parent != nil {
+		folder = object.NewFolder(vm.Vim25(), *mvm.Parent)
+	}
+
+	task, err := vm.registerVM(ctx, mvm.Config.Files.VmPathName, name, mvm.ParentVApp, mvm.ResourcePool, mvm.Summary.Runtime.Host, folder)
 	if err != nil {
 		log.Errorf("Unable to register VM %q back: %s", name, err)
 		return err
@@ -491,8 +497,8 @@ func (vm *VirtualMachine) FixInvalidState(ctx context.Context) error {
 		return err
 	}
 	// re-register vm will change vm reference, so reset the object reference here
-	if info.Error != nil {
-		return errors.New(info.Error.LocalizedMessage)
+	if err = tasks.TaskError(info); err != nil {
+		return err
 	}
 
 	// set new registered vm attribute back
@@ -504,17 +510,11 @@ func (vm *VirtualMachine) FixInvalidState(ctx context.Context) error {
 }
 
 func (vm *VirtualMachine) needsFix(err error) bool {
-	f, ok := err.(types.HasFault)
-	if !ok {
-		return false
-	}
-	switch f.Fault().(type) {
-	case *types.InvalidState:
+	if tasks.IsFault(err, &types.InvalidState{}) {
 		return true
-	default:
-		log.Debugf("Do not fix non invalid state error")
-		return false
 	}
+	log.Debugf("Do not fix non invalid state error")
+	return false
 }
 
 // WaitForResult is designed to handle VM invalid state error for any VM operations.